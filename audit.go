@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/urfave/cli"
+)
+
+var auditCommand = cli.Command{
+	Name:  "audit",
+	Usage: "check a container for foreign processes",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+	Description: `The audit command looks for foreign processes in the container cgroup, i.e.
+processes which are neither the container init nor the processes started by
+runc exec, nor their descendants. Such processes were moved into the
+container cgroup, or entered the container by other means than runc, which
+may indicate a process leak or injection.
+
+The foreign processes (and their descendants) are shown in the same format
+as by runc ps --tree, and the command fails if any are found.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format, f",
+			Value: "table",
+			Usage: `select one of: ` + formatOptions,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		foreign, err := container.ForeignProcesses()
+		if err != nil {
+			return err
+		}
+		switch context.String("format") {
+		case "table":
+			if len(foreign) > 0 {
+				if err := printProcessTree(os.Stdout, foreign); err != nil {
+					return err
+				}
+			}
+		case "json":
+			if foreign == nil {
+				foreign = []*libcontainer.ProcessInfo{}
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(foreign); err != nil {
+				return err
+			}
+		default:
+			return errors.New("invalid format option")
+		}
+		if len(foreign) > 0 {
+			return fmt.Errorf("container %s has %d foreign process(es)", container.ID(), len(foreign))
+		}
+		return nil
+	},
+}
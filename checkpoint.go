@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	criu "github.com/checkpoint-restore/go-criu/v6/rpc"
 	"github.com/opencontainers/runc/libcontainer"
@@ -41,6 +42,7 @@ checkpointed.`,
 		cli.StringFlag{Name: "manage-cgroups-mode", Value: "", Usage: "cgroups mode: soft|full|strict|ignore (default: soft)"},
 		cli.StringSliceFlag{Name: "empty-ns", Usage: "create a namespace, but don't restore its properties"},
 		cli.BoolFlag{Name: "auto-dedup", Usage: "enable auto deduplication of memory images"},
+		cli.StringFlag{Name: "compress", Value: "", Usage: "replace the image files by an archive compressed with this method: zstd|lz4|gzip"},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -51,6 +53,11 @@ checkpointed.`,
 			logrus.Warn("runc checkpoint is untested with rootless containers")
 		}
 
+		lock, err := lockContainer(context, "checkpoint")
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
 		container, err := getContainer(context)
 		if err != nil {
 			return err
@@ -66,6 +73,17 @@ checkpointed.`,
 		if err != nil {
 			return err
 		}
+		compress := context.String("compress")
+		if compress != "" {
+			if _, ok := compressors[compress]; !ok {
+				return fmt.Errorf("unsupported --compress value %q (supported: %s)", compress, strings.Join(compressorNames(), ", "))
+			}
+			// The images must all be local, and the pre-dump ones must
+			// stay usable as a parent.
+			if options.PreDump || options.LazyPages || options.PageServer.Address != "" {
+				return errors.New("--compress can not be used with --pre-dump, --lazy-pages or --page-server")
+			}
+		}
 
 		err = container.Checkpoint(options)
 		if err == nil && !(options.LeaveRunning || options.PreDump) {
@@ -74,6 +92,9 @@ checkpointed.`,
 				logrus.Warn(err)
 			}
 		}
+		if err == nil && compress != "" {
+			err = compressImages(options.ImagesDirectory, compress)
+		}
 		return err
 	},
 }
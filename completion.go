@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+var completionCommand = cli.Command{
+	Name:  "completion",
+	Usage: "output a shell completion script",
+	ArgsUsage: `bash|zsh|fish
+
+Where the argument is the shell to generate the completion script for.`,
+	Description: `The completion command outputs a completion script for the given shell,
+generated from the runc command line description (see runc cli-schema).
+The container IDs are completed by running runc list, with the --root and
+--tenant options of the command line being completed.
+
+EXAMPLES:
+
+       # runc completion bash > /usr/share/bash-completion/completions/runc
+       # runc completion zsh > /usr/share/zsh/site-functions/_runc
+       # runc completion fish > /usr/share/fish/vendor_completions.d/runc.fish
+`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		s := getCLISchema(context.App)
+		switch shell := context.Args().First(); shell {
+		case "bash":
+			writeBashCompletion(os.Stdout, s)
+		case "zsh":
+			writeZshCompletion(os.Stdout, s)
+		case "fish":
+			writeFishCompletion(os.Stdout, s)
+		default:
+			return fmt.Errorf("unsupported shell %q (supported: bash, zsh, fish)", shell)
+		}
+		return nil
+	},
+}
+
+// flagWords returns all the flags names with dashes, as used on the
+// command line.
+func flagWords(flags []flagSchema, withValue bool) []string {
+	var words []string
+	for _, f := range flags {
+		if f.TakesValue != withValue {
+			continue
+		}
+		for _, name := range f.Names {
+			words = append(words, dashed(name))
+		}
+	}
+	return words
+}
+
+func dashed(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+func commandNames(s *cliSchema) []string {
+	var names []string
+	for _, c := range s.Commands {
+		names = append(names, c.Name)
+		names = append(names, c.Aliases...)
+	}
+	return names
+}
+
+func writeBashCompletion(w io.Writer, s *cliSchema) {
+	allFlags := func(flags []flagSchema) string {
+		return strings.Join(append(flagWords(flags, false), flagWords(flags, true)...), " ")
+	}
+	// A case pattern which never matches, for when there are no flags
+	// taking a value.
+	valuePattern := func(flags []flagSchema) string {
+		words := flagWords(flags, true)
+		if len(words) == 0 {
+			return "--"
+		}
+		return strings.Join(words, "|")
+	}
+
+	fmt.Fprintf(w, "# bash completion for %s, generated by \"%s completion bash\".\n\n", s.Name, s.Name)
+	// The container IDs are listed with the same root directory (and
+	// tenant) as the completed command line, from rootargs.
+	fmt.Fprintf(w, "_%s_container_ids() {\n", s.Name)
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W \"$(\"${COMP_WORDS[0]}\" \"${rootargs[@]}\" list -q 2>/dev/null)\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "_%s() {\n", s.Name)
+	fmt.Fprintf(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "\tlocal cmd=\"\" cmdpos=0 i nargs flags\n")
+	fmt.Fprintf(w, "\tlocal -a rootargs=()\n\n")
+	fmt.Fprintf(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(w, "\t\tcase \"${COMP_WORDS[i]}\" in\n")
+	fmt.Fprintf(w, "\t\t--root | --tenant)\n\t\t\trootargs+=(\"${COMP_WORDS[i]}\" \"${COMP_WORDS[i+1]}\")\n\t\t\t((i++))\n\t\t\t;;\n")
+	fmt.Fprintf(w, "\t\t--root=* | --tenant=*) rootargs+=(\"${COMP_WORDS[i]}\") ;;\n")
+	fmt.Fprintf(w, "\t\t%s) ((i++)) ;;\n", valuePattern(s.GlobalFlags))
+	fmt.Fprintf(w, "\t\t-*) ;;\n")
+	fmt.Fprintf(w, "\t\t*)\n\t\t\tcmd=\"${COMP_WORDS[i]}\"\n\t\t\tcmdpos=$i\n\t\t\tbreak\n\t\t\t;;\n")
+	fmt.Fprintf(w, "\t\tesac\n\tdone\n\n")
+
+	fmt.Fprintf(w, "\tif [ -z \"$cmd\" ]; then\n")
+	fmt.Fprintf(w, "\t\tcase \"$prev\" in\n")
+	fmt.Fprintf(w, "\t\t%s)\n\t\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\t\treturn\n\t\t\t;;\n", valuePattern(s.GlobalFlags))
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\t\tif [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(w, "\t\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", allFlags(s.GlobalFlags))
+	fmt.Fprintf(w, "\t\telse\n")
+	fmt.Fprintf(w, "\t\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(s), " "))
+	fmt.Fprintf(w, "\t\tfi\n\t\treturn\n\tfi\n\n")
+
+	fmt.Fprintf(w, "\tcase \"$cmd\" in\n")
+	for _, c := range s.Commands {
+		fmt.Fprintf(w, "\t%s)\n", strings.Join(append([]string{c.Name}, c.Aliases...), "|"))
+		fmt.Fprintf(w, "\t\tcase \"$prev\" in\n")
+		fmt.Fprintf(w, "\t\t%s)\n\t\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\t\treturn\n\t\t\t;;\n", valuePattern(c.Flags))
+		fmt.Fprintf(w, "\t\tesac\n")
+		fmt.Fprintf(w, "\t\tflags=%q\n", allFlags(c.Flags))
+		if c.Args.ContainerID {
+			// Complete the first argument with the container IDs.
+			fmt.Fprintf(w, "\t\tif [[ \"$cur\" != -* ]]; then\n")
+			fmt.Fprintf(w, "\t\t\tnargs=0\n")
+			fmt.Fprintf(w, "\t\t\tfor ((i = cmdpos + 1; i < COMP_CWORD; i++)); do\n")
+			fmt.Fprintf(w, "\t\t\t\tcase \"${COMP_WORDS[i]}\" in\n")
+			fmt.Fprintf(w, "\t\t\t\t%s) ((i++)) ;;\n", valuePattern(c.Flags))
+			fmt.Fprintf(w, "\t\t\t\t-*) ;;\n")
+			fmt.Fprintf(w, "\t\t\t\t*) ((nargs++)) ;;\n")
+			fmt.Fprintf(w, "\t\t\t\tesac\n\t\t\tdone\n")
+			fmt.Fprintf(w, "\t\t\tif [ \"$nargs\" -eq 0 ]; then\n")
+			fmt.Fprintf(w, "\t\t\t\t_%s_container_ids\n\t\t\t\treturn\n\t\t\tfi\n", s.Name)
+			fmt.Fprintf(w, "\t\tfi\n")
+		}
+		fmt.Fprintf(w, "\t\t;;\n")
+	}
+	fmt.Fprintf(w, "\t*)\n\t\treturn\n\t\t;;\n")
+	fmt.Fprintf(w, "\tesac\n\n")
+
+	fmt.Fprintf(w, "\tif [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "\telse\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	fmt.Fprintf(w, "\tfi\n}\n\n")
+	fmt.Fprintf(w, "complete -F _%s %s\n", s.Name, s.Name)
+}
+
+// zshQuote quotes a string to be used in a single-quoted _arguments or
+// _describe spec.
+func zshQuote(s string) string {
+	r := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`)
+	return r.Replace(s)
+}
+
+func zshFlagSpecs(flags []flagSchema) []string {
+	var specs []string
+	for _, f := range flags {
+		usage := zshQuote(f.Usage)
+		for _, name := range f.Names {
+			if f.TakesValue {
+				specs = append(specs, fmt.Sprintf("'%s=[%s]:value:_files'", dashed(name), usage))
+			} else {
+				specs = append(specs, fmt.Sprintf("'%s[%s]'", dashed(name), usage))
+			}
+		}
+	}
+	return specs
+}
+
+func writeZshCompletion(w io.Writer, s *cliSchema) {
+	fmt.Fprintf(w, "#compdef %s\n\n", s.Name)
+	fmt.Fprintf(w, "# zsh completion for %s, generated by \"%s completion zsh\".\n\n", s.Name, s.Name)
+	// The container IDs are listed with the same root directory (and
+	// tenant) as the completed command line, from prog and rootargs.
+	fmt.Fprintf(w, "_%s_container_ids() {\n", s.Name)
+	fmt.Fprintf(w, "\tlocal -a ids\n")
+	fmt.Fprintf(w, "\tids=(${(f)\"$($prog $rootargs list -q 2>/dev/null)\"})\n")
+	fmt.Fprintf(w, "\t_describe -t containers 'container' ids\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "_%s() {\n", s.Name)
+	fmt.Fprintf(w, "\tlocal curcontext=\"$curcontext\" state line prog=$words[1]\n")
+	fmt.Fprintf(w, "\tlocal -a rootargs\n\ttypeset -A opt_args\n")
+	fmt.Fprintf(w, "\tlocal -a commands\n\tcommands=(\n")
+	for _, c := range s.Commands {
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			fmt.Fprintf(w, "\t\t'%s:%s'\n", name, zshQuote(c.Usage))
+		}
+	}
+	fmt.Fprintf(w, "\t)\n\n")
+
+	fmt.Fprintf(w, "\t_arguments -C \\\n")
+	for _, spec := range zshFlagSpecs(s.GlobalFlags) {
+		fmt.Fprintf(w, "\t\t%s \\\n", spec)
+	}
+	fmt.Fprintf(w, "\t\t'1: :->command' \\\n\t\t'*:: :->args'\n\n")
+
+	fmt.Fprintf(w, "\tcase $state in\n")
+	fmt.Fprintf(w, "\tcommand)\n\t\t_describe -t commands 'runc command' commands\n\t\t;;\n")
+	fmt.Fprintf(w, "\targs)\n")
+	fmt.Fprintf(w, "\t\t[[ -n ${opt_args[--root]} ]] && rootargs+=(--root ${opt_args[--root]})\n")
+	fmt.Fprintf(w, "\t\t[[ -n ${opt_args[--tenant]} ]] && rootargs+=(--tenant ${opt_args[--tenant]})\n")
+	fmt.Fprintf(w, "\t\tcase $words[1] in\n")
+	for _, c := range s.Commands {
+		fmt.Fprintf(w, "\t\t%s)\n", strings.Join(append([]string{c.Name}, c.Aliases...), "|"))
+		fmt.Fprintf(w, "\t\t\t_arguments \\\n")
+		for _, spec := range zshFlagSpecs(c.Flags) {
+			fmt.Fprintf(w, "\t\t\t\t%s \\\n", spec)
+		}
+		if c.Args.ContainerID {
+			fmt.Fprintf(w, "\t\t\t\t'1: :_%s_container_ids' \\\n", s.Name)
+		}
+		fmt.Fprintf(w, "\t\t\t\t'*: :_files'\n\t\t\t;;\n")
+	}
+	fmt.Fprintf(w, "\t\tesac\n\t\t;;\n\tesac\n}\n\n")
+	fmt.Fprintf(w, "_%s \"$@\"\n", s.Name)
+}
+
+// fishQuote quotes a string for use in a fish script.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func fishFlags(w io.Writer, prog, cond string, flags []flagSchema) {
+	for _, f := range flags {
+		fmt.Fprintf(w, "complete -c %s -n %s", prog, fishQuote(cond))
+		for _, name := range f.Names {
+			if len(name) == 1 {
+				fmt.Fprintf(w, " -s %s", name)
+			} else {
+				fmt.Fprintf(w, " -l %s", name)
+			}
+		}
+		if f.TakesValue {
+			fmt.Fprintf(w, " -r")
+		}
+		fmt.Fprintf(w, " -d %s\n", fishQuote(f.Usage))
+	}
+}
+
+func writeFishCompletion(w io.Writer, s *cliSchema) {
+	fmt.Fprintf(w, "# fish completion for %s, generated by \"%s completion fish\".\n\n", s.Name, s.Name)
+	// The container IDs are listed with the same root directory (and
+	// tenant) as the completed command line.
+	fmt.Fprintf(w, "function __%s_container_ids\n", s.Name)
+	fmt.Fprintf(w, "\tset -l tokens (commandline -opc)\n\tset -l args\n\tset -l i 2\n")
+	fmt.Fprintf(w, "\twhile test $i -lt (count $tokens)\n")
+	fmt.Fprintf(w, "\t\tswitch $tokens[$i]\n")
+	fmt.Fprintf(w, "\t\tcase --root --tenant\n")
+	fmt.Fprintf(w, "\t\t\tset i (math $i + 1)\n\t\t\tset args $args $tokens[(math $i - 1)] $tokens[$i]\n")
+	fmt.Fprintf(w, "\t\tcase '--root=*' '--tenant=*'\n")
+	fmt.Fprintf(w, "\t\t\tset args $args $tokens[$i]\n")
+	fmt.Fprintf(w, "\t\tend\n\t\tset i (math $i + 1)\n\tend\n")
+	fmt.Fprintf(w, "\t$tokens[1] $args list -q 2>/dev/null\nend\n\n")
+	fmt.Fprintf(w, "complete -c %s -f\n", s.Name)
+	fishFlags(w, s.Name, "__fish_use_subcommand", s.GlobalFlags)
+	for _, c := range s.Commands {
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", s.Name, name, fishQuote(c.Usage))
+		}
+	}
+	for _, c := range s.Commands {
+		cond := "__fish_seen_subcommand_from " + strings.Join(append([]string{c.Name}, c.Aliases...), " ")
+		fishFlags(w, s.Name, cond, c.Flags)
+		if c.Args.ContainerID {
+			fmt.Fprintf(w, "complete -c %s -n %s -a '(__%s_container_ids)' -d container\n", s.Name, fishQuote(cond), s.Name)
+		}
+	}
+}
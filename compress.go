@@ -0,0 +1,300 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/extcmd"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// imagesArchive is the name of the compressed archive of the criu images
+// in the images directory, without the extension of the compression method.
+const imagesArchive = "checkpoint.tar"
+
+// compressor is a compression method of the images archive, done by an
+// external binary (reading from its stdin and writing to its stdout).
+type compressor struct {
+	ext        string
+	compress   []string
+	decompress []string
+}
+
+var compressors = map[string]compressor{
+	"gzip": {".gz", []string{"gzip", "-c"}, []string{"gzip", "-d", "-c"}},
+	"lz4":  {".lz4", []string{"lz4", "-c", "-q"}, []string{"lz4", "-d", "-c", "-q"}},
+	"zstd": {".zst", []string{"zstd", "-c", "-q", "-T0"}, []string{"zstd", "-d", "-c", "-q"}},
+}
+
+func compressorNames() []string {
+	names := make([]string, 0, len(compressors))
+	for name := range compressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// imageFiles returns the names of the criu images (the regular files and
+// symlinks, such as the "parent" one) in dir, leaving out the logs and the
+// images archive.
+func imageFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, ".log") || strings.HasPrefix(name, imagesArchive) {
+			continue
+		}
+		if t := e.Type(); t.IsRegular() || t&os.ModeSymlink != 0 {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// compressImages replaces the criu images in dir by an archive of them,
+// compressed with the given method. The images are left as they are if
+// this fails.
+func compressImages(dir, method string) (Err error) {
+	c, ok := compressors[method]
+	if !ok {
+		return fmt.Errorf("unsupported compression %q (supported: %s)", method, strings.Join(compressorNames(), ", "))
+	}
+	names, err := imageFiles(dir)
+	if err != nil {
+		return err
+	}
+	archive := filepath.Join(dir, imagesArchive+c.ext)
+	tmp := archive + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		if Err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	// Stream the tar archive through the compressor.
+	pr, pw := io.Pipe()
+	tarErr := make(chan error, 1)
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := func() error {
+			for _, name := range names {
+				p := filepath.Join(dir, name)
+				fi, err := os.Lstat(p)
+				if err != nil {
+					return err
+				}
+				if err := writeTarEntry(tw, p, name, fi); err != nil {
+					return err
+				}
+			}
+			return tw.Close()
+		}()
+		_ = pw.CloseWithError(err)
+		tarErr <- err
+	}()
+	cmd := extcmd.Command(c.compress[0], c.compress[1:]...)
+	cmd.Stdin = pr
+	cmd.Stdout = f
+	err = cmd.Run()
+	// Unblock the writer, if the compressor failed early.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	if err2 := <-tarErr; err2 != nil && err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("unable to compress the images: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, archive); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			logrus.Warnf("unable to remove the compressed image: %v", err)
+		}
+	}
+	return nil
+}
+
+// decompressImages extracts the criu images in dir from their compressed
+// archive, if there is one, for them to be restored. The returned function
+// removes the extracted images.
+func decompressImages(dir string) (func(), error) {
+	var archive string
+	for _, name := range compressorNames() {
+		p := filepath.Join(dir, imagesArchive+compressors[name].ext)
+		if _, err := os.Stat(p); err == nil {
+			archive = p
+			break
+		}
+	}
+	if archive == "" {
+		return func() {}, nil
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	extracted, err := extractImages(f, dir)
+	cleanup := func() {
+		for _, name := range extracted {
+			_ = os.Remove(filepath.Join(dir, name))
+		}
+	}
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("unable to decompress %s: %w", archive, err)
+	}
+	return cleanup, nil
+}
+
+// compressorMagic returns the compressor of the data starting with magic,
+// if it is compressed with one of the supported methods.
+func compressorMagic(magic []byte) (compressor, bool) {
+	for _, m := range []struct {
+		magic  []byte
+		method string
+	}{
+		{[]byte{0x1f, 0x8b}, "gzip"},
+		{[]byte{0x04, 0x22, 0x4d, 0x18}, "lz4"},
+		{[]byte{0x28, 0xb5, 0x2f, 0xfd}, "zstd"},
+	} {
+		if bytes.HasPrefix(magic, m.magic) {
+			return compressors[m.method], true
+		}
+	}
+	return compressor{}, false
+}
+
+// extractImages extracts the criu images from the tar archive read from r,
+// which can be compressed with one of the supported methods, into dir. It
+// returns the names of the extracted files, even on error. The archive can
+// only have regular files and symlinks at its top level, which must not
+// exist in dir.
+func extractImages(r io.Reader, dir string) (extracted []string, _ error) {
+	br := bufio.NewReader(r)
+	// A short archive is handled by the tar reader.
+	magic, _ := br.Peek(4)
+	c, ok := compressorMagic(magic)
+	if !ok {
+		err := extractTar(tar.NewReader(br), dir, &extracted)
+		return extracted, err
+	}
+
+	pr, pw := io.Pipe()
+	cmd := extcmd.Command(c.decompress[0], c.decompress[1:]...)
+	cmd.Stdin = br
+	cmd.Stdout = pw
+	cmdErr := make(chan error, 1)
+	go func() {
+		err := cmd.Run()
+		_ = pw.CloseWithError(err)
+		cmdErr <- err
+	}()
+	err := extractTar(tar.NewReader(pr), dir, &extracted)
+	// Unblock the decompressor, if the extraction failed early.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	if err2 := <-cmdErr; err2 != nil && err == nil {
+		err = err2
+	}
+	return extracted, err
+}
+
+// extractTar extracts the files from tr into dir, adding their names to
+// extracted (see extractImages).
+func extractTar(tr *tar.Reader, dir string, extracted *[]string) error {
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// As in an archive created by tar -C dir -c .
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if hdr.Typeflag == tar.TypeDir && (name == "" || name == ".") {
+			continue
+		}
+		if name == "" || name == "." || name == ".." || strings.ContainsRune(name, '/') {
+			return fmt.Errorf("invalid file name %q in archive", hdr.Name)
+		}
+		p := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, p); err != nil {
+				return err
+			}
+			*extracted = append(*extracted, name)
+		case tar.TypeReg:
+			f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL|unix.O_CLOEXEC, 0o600)
+			if err != nil {
+				return err
+			}
+			*extracted = append(*extracted, name)
+			_, err = io.Copy(f, tr)
+			if err1 := f.Close(); err == nil {
+				err = err1
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported file type of %q in archive", hdr.Name)
+		}
+	}
+}
+
+// extractImageStream extracts the criu images from the tar archive read
+// from stream ("-" for the standard input, or a file path, such as a named
+// pipe) into a new directory, in workDir if set (or in os.TempDir), as criu
+// can only read the images from a directory. It returns the directory, which
+// is to be removed once the images are restored.
+func extractImageStream(stream, workDir string) (string, error) {
+	r := io.Reader(os.Stdin)
+	if stream != "-" {
+		f, err := os.Open(stream)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		r = f
+	}
+	if workDir != "" {
+		if err := os.MkdirAll(workDir, 0o700); err != nil {
+			return "", err
+		}
+	}
+	dir, err := os.MkdirTemp(workDir, "runc-restore-images-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := extractImages(r, dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("unable to extract the images from %s: %w", stream, err)
+	}
+	return dir, nil
+}
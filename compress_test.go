@@ -0,0 +1,128 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressImages(t *testing.T) {
+	for _, method := range compressorNames() {
+		t.Run(method, func(t *testing.T) {
+			if _, err := exec.LookPath(compressors[method].compress[0]); err != nil {
+				t.Skip(err)
+			}
+			dir := t.TempDir()
+			files := map[string]string{
+				"pages-1.img":   "pages",
+				"inventory.img": "inventory",
+				"dump.log":      "log",
+			}
+			for name, data := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.Symlink("../parent", filepath.Join(dir, "parent")); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := compressImages(dir, method); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			archive := imagesArchive + compressors[method].ext
+			if len(names) != 2 || names[0] != archive || names[1] != "dump.log" {
+				t.Fatalf("expected only %s and dump.log to be left, got %v", archive, names)
+			}
+
+			cleanup, err := decompressImages(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, data := range files {
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != data {
+					t.Errorf("%s: expected %q, got %q", name, data, got)
+				}
+			}
+			if link, err := os.Readlink(filepath.Join(dir, "parent")); err != nil || link != "../parent" {
+				t.Errorf("parent: expected a symlink to ../parent, got %q (%v)", link, err)
+			}
+			cleanup()
+			if _, err := os.Stat(filepath.Join(dir, "inventory.img")); !os.IsNotExist(err) {
+				t.Errorf("expected the extracted images to be removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCompressImagesUnsupported(t *testing.T) {
+	if err := compressImages(t.TempDir(), "bzip2"); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
+
+func TestExtractImagesPlainTar(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files []string
+		err   bool
+	}{
+		{name: "flat", files: []string{"pages-1.img", "inventory.img"}},
+		{name: "dot prefix", files: []string{"./", "./pages-1.img", "./inventory.img"}},
+		{name: "subdirectory", files: []string{"sub/pages-1.img"}, err: true},
+		{name: "parent", files: []string{"../pages-1.img"}, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, name := range tc.files {
+				hdr := &tar.Header{Name: name, Mode: 0o600, Typeflag: tar.TypeReg, Size: int64(len(name))}
+				if name == "./" {
+					hdr = &tar.Header{Name: name, Mode: 0o700, Typeflag: tar.TypeDir}
+				}
+				if err := tw.WriteHeader(hdr); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := tw.Write([]byte(name[:hdr.Size])); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			extracted, err := extractImages(&buf, dir)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got nil (extracted %v)", extracted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(extracted) != 2 || extracted[0] != "pages-1.img" || extracted[1] != "inventory.img" {
+				t.Fatalf("unexpected extracted files %v", extracted)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "inventory.img")); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
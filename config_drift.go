@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// configHash returns the digest ("sha256:<hex>") of the file at path.
+func configHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// bundleConfigHash returns the digest of the config.json currently in the
+// bundle of the container with the given config, or "" if there is none.
+func bundleConfigHash(config *configs.Config) (string, error) {
+	bundle, ok := utils.SearchLabels(config.Labels, "bundle")
+	if !ok {
+		return "", nil
+	}
+	hash, err := configHash(filepath.Join(bundle, specConfig))
+	if errors.Is(err, os.ErrNotExist) {
+		// The bundle may be removed once the container is started.
+		return "", nil
+	}
+	return hash, err
+}
+
+// checkConfigDrift checks that the bundle config.json was not changed since
+// the container was created, as the container would silently diverge from
+// it. Depending on the ConfigDriftCheck of the container, a change is
+// ignored, logged, or an error.
+func checkConfigDrift(container *libcontainer.Container) error {
+	config := container.Config()
+	if config.ConfigHash == "" || config.ConfigDriftCheck == configs.ConfigDriftCheckIgnore {
+		return nil
+	}
+	hash, err := bundleConfigHash(&config)
+	if err == nil && (hash == "" || hash == config.ConfigHash) {
+		return nil
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to check the bundle config.json: %w", err)
+	} else {
+		err = fmt.Errorf("the bundle config.json was changed since the container was created (%s, now %s), the changes are not applied", config.ConfigHash, hash)
+	}
+	if config.ConfigDriftCheck == configs.ConfigDriftCheckStrict {
+		return err
+	}
+	logrus.Warn(err)
+	return nil
+}
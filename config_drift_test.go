@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestBundleConfigHash(t *testing.T) {
+	bundle := t.TempDir()
+	config := &configs.Config{Labels: []string{"bundle=" + bundle}}
+	if hash, err := bundleConfigHash(config); err != nil || hash != "" {
+		t.Fatalf("expected no digest without config.json, got %q (%v)", hash, err)
+	}
+
+	p := filepath.Join(bundle, specConfig)
+	if err := os.WriteFile(p, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bundleConfigHash(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	if err := os.WriteFile(p, []byte("{ }"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if hash, err = bundleConfigHash(config); err != nil || hash == expected {
+		t.Fatalf("expected the digest to change, got %s (%v)", hash, err)
+	}
+}
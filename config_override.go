@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+)
+
+// configOverrideFlag and configPatchFlag are the create and run options to
+// modify the bundle config.json, without changing the file.
+var (
+	configOverrideFlag = cli.StringSliceFlag{
+		Name:  "config-override",
+		Usage: "override a config.json field, as path.to.field=value (or /json/pointer=value), the value being JSON or else a string (can be specified multiple times)",
+	}
+	configPatchFlag = cli.StringSliceFlag{
+		Name:  "config-patch",
+		Usage: "apply the RFC 6902 JSON patch from the given `file` to config.json, before the overrides (can be specified multiple times)",
+	}
+)
+
+// configOverrides are the modifications of config.json requested by the
+// --config-patch and --config-override options.
+type configOverrides struct {
+	// patches are the contents of the --config-patch files.
+	patches   []configPatch
+	overrides []string
+}
+
+type configPatch struct {
+	name string
+	data []byte
+}
+
+// readConfigOverrides reads the --config-patch and --config-override options, including the
+// patch files (so, it must be called before changing to the bundle
+// directory).
+func readConfigOverrides(context *cli.Context) (*configOverrides, error) {
+	o := &configOverrides{overrides: context.StringSlice("config-override")}
+	for _, path := range context.StringSlice("config-patch") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read config patch: %w", err)
+		}
+		o.patches = append(o.patches, configPatch{name: path, data: data})
+	}
+	if len(o.patches) == 0 && len(o.overrides) == 0 {
+		return nil, nil
+	}
+	return o, nil
+}
+
+// apply applies the patches, then the overrides, to the config.json
+// contents, and returns the resulting spec.
+func (o *configOverrides) apply(data []byte) (*specs.Spec, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range o.patches {
+		if doc, err = applyJSONPatch(doc, p.data); err != nil {
+			return nil, fmt.Errorf("config patch %s: %w", p.name, err)
+		}
+	}
+	for _, val := range o.overrides {
+		if doc, err = applyConfigOverride(doc, val); err != nil {
+			return nil, fmt.Errorf("invalid --config-override %q: %w", val, err)
+		}
+	}
+	if data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	var spec *specs.Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid overridden config: %w", err)
+	}
+	if spec == nil {
+		return nil, errors.New("config cannot be null")
+	}
+	return spec, validateProcessSpec(spec.Process)
+}
+
+// decodeJSON decodes a JSON value, keeping the numbers as is (so that large
+// integers do not lose precision).
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+// applyConfigOverride applies a --config-override value to doc. The path is
+// either a JSON pointer, or a list of keys separated by dots, array items
+// being selected by their index (or "-" to append an item). Missing objects
+// along the path are created.
+func applyConfigOverride(doc interface{}, val string) (interface{}, error) {
+	path, raw, ok := strings.Cut(val, "=")
+	if !ok || path == "" {
+		return nil, errors.New("must be path=value")
+	}
+	var tokens []string
+	if strings.HasPrefix(path, "/") {
+		var err error
+		if tokens, err = parseJSONPointer(path); err != nil {
+			return nil, err
+		}
+	} else {
+		tokens = strings.Split(path, ".")
+	}
+	value, err := decodeJSON([]byte(raw))
+	if err != nil {
+		// Not JSON, so a string.
+		value = raw
+	}
+	return modifyJSON(doc, tokens, true, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(p, key, true)
+			if err != nil {
+				return nil, err
+			}
+			if i == len(p) {
+				return append(p, value), nil
+			}
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("%s: not an object or an array", key)
+	})
+}
+
+// parseJSONPointer parses a (non-empty) RFC 6901 JSON pointer.
+func parseJSONPointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses the index of an item of a (for end, "-" being the
+// end of a, where an item can be added).
+func arrayIndex(a []interface{}, token string, end bool) (int, error) {
+	max := len(a) - 1
+	if end {
+		if token == "-" {
+			return len(a), nil
+		}
+		max = len(a)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+// modifyJSON calls fn with the parent of the value the tokens point to, and
+// its key in the parent, and replaces the parent with the value fn returns.
+// If create is true, the missing objects along the path are created.
+func modifyJSON(node interface{}, tokens []string, create bool, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		if node == nil && create {
+			node = map[string]interface{}{}
+		}
+		return fn(node, tokens[0])
+	}
+	key, rest := tokens[0], tokens[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[key]
+		if !ok && !create {
+			return nil, fmt.Errorf("%s: not found", key)
+		}
+		if child, err := modifyJSON(child, rest, create, fn); err != nil {
+			return nil, err
+		} else {
+			n[key] = child
+		}
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(n, key, false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := modifyJSON(n[i], rest, create, fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	case nil:
+		if create {
+			return modifyJSON(map[string]interface{}{}, tokens, create, fn)
+		}
+	}
+	return nil, fmt.Errorf("%s: not an object or an array", key)
+}
+
+// getJSON returns the value the tokens point to in doc.
+func getJSON(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch n := doc.(type) {
+		case map[string]interface{}:
+			v, ok := n[t]
+			if !ok {
+				return nil, fmt.Errorf("%s: not found", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(n, t, false)
+			if err != nil {
+				return nil, err
+			}
+			doc = n[i]
+		default:
+			return nil, fmt.Errorf("%s: not an object or an array", t)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPatchOp is an operation of an RFC 6902 JSON patch.
+type jsonPatchOp struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies an RFC 6902 JSON patch to doc.
+func applyJSONPatch(doc interface{}, patch []byte) (interface{}, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+	for i, op := range ops {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return doc, nil
+}
+
+func (op *jsonPatchOp) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, errors.New("missing path")
+	}
+	path, err := parseJSONPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value, from interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		if value, err = decodeJSON(*op.Value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, errors.New("missing from")
+		}
+		fromPath, err := parseJSONPointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if from, err = getJSON(doc, fromPath); err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if *op.Path == *op.From {
+				return doc, nil
+			}
+			if strings.HasPrefix(*op.Path, *op.From+"/") {
+				return nil, errors.New("unable to move a value into one of its children")
+			}
+			if doc, err = removeJSON(doc, fromPath); err != nil {
+				return nil, err
+			}
+		} else {
+			// Do not share the value between the two locations.
+			data, err := json.Marshal(from)
+			if err != nil {
+				return nil, err
+			}
+			if from, err = decodeJSON(data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	switch op.Op {
+	case "add":
+		return addJSON(doc, path, value)
+	case "remove":
+		return removeJSON(doc, path)
+	case "replace":
+		if doc, err = removeJSON(doc, path); err != nil {
+			return nil, err
+		}
+		return addJSON(doc, path, value)
+	case "move", "copy":
+		return addJSON(doc, path, from)
+	case "test":
+		v, err := getJSON(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(v, value) {
+			return nil, fmt.Errorf("%s: test failed", *op.Path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+func addJSON(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modifyJSON(doc, path, false, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(p, key, true)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("%s: not an object or an array", key)
+	})
+}
+
+func removeJSON(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return modifyJSON(doc, path, false, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return nil, fmt.Errorf("%s: not found", key)
+			}
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(p, key, false)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%s: not an object or an array", key)
+	})
+}
+
+// jsonEqual compares two decoded JSON values, the numbers being compared
+// by value.
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if bv, ok := b[k]; !ok || !jsonEqual(v, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if a == b {
+			return true
+		}
+		af, err1 := a.Float64()
+		bf, err2 := b.Float64()
+		return err1 == nil && err2 == nil && af == bf
+	}
+	return a == b
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testOverrideConfig = `{
+	"ociVersion": "1.0.2",
+	"process": {"cwd": "/", "args": ["sh"], "env": ["A=1"]},
+	"root": {"path": "rootfs"},
+	"linux": {"resources": {"memory": {"limit": 9223372036854775807}}}
+}`
+
+func TestApplyConfigOverride(t *testing.T) {
+	for _, tc := range []struct {
+		override string
+		path     string
+		want     interface{}
+	}{
+		{"process.args=[\"echo\",\"hi\"]", "/process/args", []interface{}{"echo", "hi"}},
+		{"process.args.0=true", "/process/args/0", true},
+		{"process.env.-=B=2", "/process/env/1", "B=2"},
+		{"hostname=ctr", "/hostname", "ctr"},
+		{"linux.resources.memory.limit=1048576", "/linux/resources/memory/limit", json.Number("1048576")},
+		{"linux.resources.pids.limit=10", "/linux/resources/pids/limit", json.Number("10")},
+		{"/annotations/a~1b=x", "/annotations/a~1b", "x"},
+	} {
+		doc, err := decodeJSON([]byte(testOverrideConfig))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err = applyConfigOverride(doc, tc.override)
+		if err != nil {
+			t.Errorf("%s: %v", tc.override, err)
+			continue
+		}
+		tokens, _ := parseJSONPointer(tc.path)
+		got, err := getJSON(doc, tokens)
+		if err != nil {
+			t.Errorf("%s: %v", tc.override, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.override, got, tc.want)
+		}
+	}
+
+	for _, override := range []string{
+		"process.args",
+		"=1",
+		"process.args.5=x",
+		"process.cwd.x=1",
+		"root.path.0=x",
+	} {
+		doc, err := decodeJSON([]byte(testOverrideConfig))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := applyConfigOverride(doc, override); err == nil {
+			t.Errorf("%s: expected an error", override)
+		}
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	for _, tc := range []struct {
+		doc, patch, want string
+		fail             bool
+	}{
+		{
+			doc:   `{"a": [1, 2]}`,
+			patch: `[{"op": "add", "path": "/a/1", "value": 3}, {"op": "add", "path": "/b", "value": {}}]`,
+			want:  `{"a": [1, 3, 2], "b": {}}`,
+		},
+		{
+			doc:   `{"a": [1, 2], "b": 1}`,
+			patch: `[{"op": "remove", "path": "/a/0"}, {"op": "replace", "path": "/b", "value": "x"}]`,
+			want:  `{"a": [2], "b": "x"}`,
+		},
+		{
+			doc:   `{"a": {"b": 1}}`,
+			patch: `[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "move", "from": "/a/b", "path": "/d"}]`,
+			want:  `{"a": {}, "c": {"b": 1}, "d": 1}`,
+		},
+		{
+			doc:   `{"a": 1.0}`,
+			patch: `[{"op": "test", "path": "/a", "value": 1}]`,
+			want:  `{"a": 1.0}`,
+		},
+		{
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "test", "path": "/a", "value": 2}]`,
+			fail:  true,
+		},
+		{
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "replace", "path": "/b", "value": 2}]`,
+			fail:  true,
+		},
+		{
+			doc:   `{"a": {"b": 1}}`,
+			patch: `[{"op": "move", "from": "/a", "path": "/a/c"}]`,
+			fail:  true,
+		},
+		{
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "frob", "path": "/a"}]`,
+			fail:  true,
+		},
+	} {
+		doc, err := decodeJSON([]byte(tc.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := applyJSONPatch(doc, []byte(tc.patch))
+		if tc.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.patch)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.patch, err)
+			continue
+		}
+		want, _ := decodeJSON([]byte(tc.want))
+		if !jsonEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tc.patch, got, want)
+		}
+	}
+}
+
+func TestConfigOverridesApply(t *testing.T) {
+	o := &configOverrides{
+		patches: []configPatch{{
+			name: "patch.json",
+			data: []byte(`[{"op": "replace", "path": "/process/args", "value": ["true"]}]`),
+		}},
+		overrides: []string{"process.args.-=x", "linux.resources.memory.limit=1048576"},
+	}
+	spec, err := o.apply([]byte(testOverrideConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spec.Process.Args, []string{"true", "x"}) {
+		t.Errorf("unexpected args %q", spec.Process.Args)
+	}
+	if l := *spec.Linux.Resources.Memory.Limit; l != 1048576 {
+		t.Errorf("unexpected memory limit %d", l)
+	}
+
+	o = &configOverrides{overrides: []string{"process.cwd=relative"}}
+	if _, err := o.apply([]byte(testOverrideConfig)); err == nil {
+		t.Error("expected an invalid process error")
+	}
+}
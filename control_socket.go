@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/containerd/console"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// controlSocketName is the name of the control socket (see
+// startControlSocket), in the container state directory.
+const controlSocketName = "control.sock"
+
+// controlRequest is a request sent to the control socket, as a single
+// message (with the file descriptor to pass, for "pass-fd", sent along with
+// it as SCM_RIGHTS).
+type controlRequest struct {
+	// Op is "signal", "resize", "stats" or "pass-fd".
+	Op string `json:"op"`
+	// Signal is the signal to send to the container init, for "signal"
+	// (such as "TERM" or "15", as for runc kill).
+	Signal string `json:"signal,omitempty"`
+	// Width and Height are the terminal size, for "resize".
+	Width  uint16 `json:"width,omitempty"`
+	Height uint16 `json:"height,omitempty"`
+	// Path is the path (in the container) of the unix socket to pass the
+	// file descriptor to, for "pass-fd".
+	Path string `json:"path,omitempty"`
+	// Name is sent as the payload of the message carrying the file
+	// descriptor, for "pass-fd" (default "fd").
+	Name string `json:"name,omitempty"`
+}
+
+// controlResponse is the response to a controlRequest.
+type controlResponse struct {
+	// Error is the error message, if the request failed.
+	Error string `json:"error,omitempty"`
+	// Data is the result of the request (the stats, for "stats").
+	Data interface{} `json:"data,omitempty"`
+}
+
+// controlSocket is the per container control socket of runc run: while the
+// container runs, the clients of the same user as runc (or root) can get
+// its stats, signal it, resize its terminal, or pass a file descriptor to a
+// process in it, without running runc again.
+type controlSocket struct {
+	listener  *net.UnixListener
+	container *libcontainer.Container
+	tty       *tty
+}
+
+// startControlSocket starts serving the control socket of container, whose
+// state is in root. t is the container terminal, or nil.
+func startControlSocket(root string, container *libcontainer.Container, t *tty) (*controlSocket, error) {
+	path := filepath.Join(root, container.ID(), controlSocketName)
+	// Left over by a runc which was killed.
+	_ = os.Remove(path)
+	l, err := net.ListenUnix("unixpacket", &net.UnixAddr{Name: path, Net: "unixpacket"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the control socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	s := &controlSocket{listener: l, container: container, tty: t}
+	go s.serve()
+	return s, nil
+}
+
+func (s *controlSocket) serve() {
+	for {
+		conn, err := s.listener.AcceptUnix()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// close stops serving the control socket, and removes it.
+func (s *controlSocket) close() {
+	_ = s.listener.Close()
+}
+
+func (s *controlSocket) handle(conn *net.UnixConn) {
+	defer conn.Close()
+	if err := checkControlPeer(conn); err != nil {
+		logrus.Warnf("control socket: %v", err)
+		return
+	}
+	buf := make([]byte, 64*1024)
+	oob := make([]byte, unix.CmsgSpace(4))
+	for {
+		n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+		if err != nil || n == 0 {
+			return
+		}
+		fds, err := parseControlFds(oob[:oobn])
+		var resp controlResponse
+		if err == nil {
+			resp.Data, err = s.do(buf[:n], fds)
+		}
+		for _, fd := range fds {
+			unix.Close(fd)
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			logrus.Warnf("control socket: %v", err)
+			return
+		}
+		if _, err := conn.Write(data); err != nil {
+			return
+		}
+	}
+}
+
+// checkControlPeer checks that the peer of conn is either root, or of the
+// same user as runc.
+func checkControlPeer(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *unix.Ucred
+	err2 := raw.Control(func(fd uintptr) {
+		cred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err2 != nil {
+		return err2
+	}
+	if err != nil {
+		return &os.SyscallError{Syscall: "getsockopt SO_PEERCRED", Err: err}
+	}
+	if cred.Uid != 0 && int(cred.Uid) != os.Geteuid() {
+		return fmt.Errorf("rejected a client with uid %d (pid %d)", cred.Uid, cred.Pid)
+	}
+	return nil
+}
+
+func parseControlFds(oob []byte) ([]int, error) {
+	scms, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	var fds []int
+	for _, scm := range scms {
+		f, err := unix.ParseUnixRights(&scm)
+		if err != nil {
+			continue
+		}
+		fds = append(fds, f...)
+	}
+	return fds, nil
+}
+
+// do runs a request, and returns its result.
+func (s *controlSocket) do(data []byte, fds []int) (interface{}, error) {
+	var req controlRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Op != "pass-fd" && len(fds) != 0 {
+		return nil, fmt.Errorf("unexpected file descriptor for %q", req.Op)
+	}
+	switch req.Op {
+	case "signal":
+		sig, err := parseSignal(req.Signal)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.container.Signal(sig)
+	case "resize":
+		if s.tty == nil || s.tty.console == nil {
+			return nil, errors.New("the container has no terminal")
+		}
+		return nil, s.tty.console.Resize(console.WinSize{Width: req.Width, Height: req.Height})
+	case "stats":
+		stats, err := s.container.Stats()
+		if err != nil {
+			return nil, err
+		}
+		return convertLibcontainerStats(stats), nil
+	case "pass-fd":
+		if len(fds) != 1 {
+			return nil, errors.New("pass-fd requires exactly one file descriptor")
+		}
+		return nil, passFd(s.container, req.Path, fds[0], req.Name)
+	}
+	return nil, fmt.Errorf("unknown op %q", req.Op)
+}
+
+// passFd sends fd, with name as the payload, to the unix (stream) socket at
+// path in the container.
+func passFd(container *libcontainer.Container, path string, fd int, name string) error {
+	if path == "" {
+		return errors.New("pass-fd requires a path")
+	}
+	if name == "" {
+		name = "fd"
+	}
+	root, err := container.RootFS()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	// The socket is connected to through an O_PATH file descriptor, so
+	// that path is resolved in the container root.
+	f, err := root.OpenFile(path, unix.O_PATH, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: "/proc/self/fd/" + strconv.Itoa(int(f.Fd())), Net: "unix"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, _, err = conn.WriteMsgUnix([]byte(name), unix.UnixRights(fd), nil)
+	return err
+}
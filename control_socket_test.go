@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+func TestControlSocket(t *testing.T) {
+	// The container is not actually used by the requests below.
+	s := &controlSocket{container: &libcontainer.Container{}}
+	path := filepath.Join(t.TempDir(), controlSocketName)
+	l, err := net.ListenUnix("unixpacket", &net.UnixAddr{Name: path, Net: "unixpacket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.listener = l
+	go s.serve()
+	defer s.close()
+
+	conn, err := net.DialUnix("unixpacket", nil, &net.UnixAddr{Name: path, Net: "unixpacket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	request := func(req string, fd int) controlResponse {
+		t.Helper()
+		var oob []byte
+		if fd >= 0 {
+			oob = unix.UnixRights(fd)
+		}
+		if _, _, err := conn.WriteMsgUnix([]byte(req), oob, nil); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp controlResponse
+		if err := json.Unmarshal(buf[:n], &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, tc := range []struct {
+		req, err string
+		fd       bool
+	}{
+		{req: `{`, err: "invalid request: unexpected end of JSON input"},
+		{req: `{"op":"reboot"}`, err: `unknown op "reboot"`},
+		{req: `{"op":"resize","width":80,"height":24}`, err: "the container has no terminal"},
+		{req: `{"op":"signal","signal":"SIGNOPE"}`, err: "unknown signal \"SIGNOPE\""},
+		{req: `{"op":"pass-fd","path":"/run/sock"}`, err: "pass-fd requires exactly one file descriptor"},
+		{req: `{"op":"stats"}`, err: `unexpected file descriptor for "stats"`, fd: true},
+	} {
+		fd := -1
+		if tc.fd {
+			fd = int(os.Stdin.Fd())
+		}
+		resp := request(tc.req, fd)
+		if resp.Error != tc.err {
+			t.Errorf("%s: expected error %q, got %q", tc.req, tc.err, resp.Error)
+		}
+	}
+}
@@ -35,6 +35,10 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Value: "",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal",
 		},
+		cli.IntFlag{
+			Name:  "console-socket-version",
+			Usage: "the `version` of the protocol used over the console socket (1 or 2, the default being 1)",
+		},
 		cli.StringFlag{
 			Name:  "pidfd-socket",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the init process",
@@ -56,6 +60,17 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Name:  "preserve-fds",
 			Usage: "Pass N additional file descriptors to the container (stdio + $LISTEN_FDS + N in total)",
 		},
+		secretFlag,
+		configOverrideFlag,
+		configPatchFlag,
+		cli.IntFlag{
+			Name:  "cgroup-fd",
+			Usage: "use the existing cgroup v2 directory opened as file descriptor `N` as the container cgroup",
+		},
+		cli.IntFlag{
+			Name:  "userns-fd",
+			Usage: "join the user namespace opened as file descriptor `N`",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		/*执行参数校验，只能有一个参数*/
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// timeoutExitStatus is the exit status of runc run when the container is
+// stopped for exceeding its --timeout deadline (the same as timeout(1)).
+const timeoutExitStatus = 124
+
+// deadline stops a container once its runc run --timeout deadline is
+// exceeded: it is sent SIGTERM, then SIGKILL if it is still running after
+// the grace period.
+type deadline struct {
+	container *libcontainer.Container
+	grace     time.Duration
+	timer     *time.Timer
+	done      chan struct{}
+	// stopped is closed once expire has returned.
+	stopped chan struct{}
+}
+
+func startDeadline(container *libcontainer.Container, timeout, grace time.Duration) *deadline {
+	d := &deadline{
+		container: container,
+		grace:     grace,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	d.timer = time.AfterFunc(timeout, d.expire)
+	return d
+}
+
+func (d *deadline) expire() {
+	defer close(d.stopped)
+	logrus.Warnf("container %s exceeded its deadline, stopping it", d.container.ID())
+	if err := d.container.SetExitReason(libcontainer.ExitReasonTimeout); err != nil {
+		logrus.Warnf("unable to record the exit reason: %v", err)
+	}
+	if err := d.container.Signal(unix.SIGTERM); err != nil {
+		logrus.Debugf("unable to send SIGTERM: %v", err)
+	}
+	select {
+	case <-d.done:
+		return
+	case <-time.After(d.grace):
+	}
+	if err := d.container.Signal(unix.SIGKILL); err != nil {
+		logrus.Debugf("unable to send SIGKILL: %v", err)
+	}
+}
+
+// stop stops enforcing the deadline, once the container has exited, and
+// returns whether it was exceeded.
+func (d *deadline) stop() bool {
+	if d.timer.Stop() {
+		return false
+	}
+	close(d.done)
+	<-d.stopped
+	return true
+}
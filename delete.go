@@ -1,13 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/types"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 
 	"golang.org/x/sys/unix"
@@ -24,6 +26,36 @@ func killContainer(container *libcontainer.Container) error {
 	return errors.New("container init still running")
 }
 
+// gracefulStop is how a running container is stopped before being deleted
+// (see runc delete --stop-signal and --stop-timeout).
+type gracefulStop struct {
+	signal  unix.Signal
+	timeout time.Duration
+}
+
+// stopContainer sends the stop signal to the container init, waits for it
+// to exit for up to the stop timeout, and then kills and destroys the
+// container (see killContainer). A paused container is resumed first, so
+// that it can handle the signal.
+func stopContainer(container *libcontainer.Container, status libcontainer.Status, stop *gracefulStop) error {
+	if status == libcontainer.Paused {
+		if err := container.Resume(); err != nil {
+			return err
+		}
+	}
+	if err := container.Signal(stop.signal); err != nil {
+		logrus.Warnf("unable to send the stop signal: %v", err)
+	} else {
+		for deadline := time.Now().Add(stop.timeout); time.Now().Before(deadline); {
+			time.Sleep(100 * time.Millisecond)
+			if err := container.Signal(unix.Signal(0)); err != nil {
+				break
+			}
+		}
+	}
+	return killContainer(container)
+}
+
 var deleteCommand = cli.Command{
 	Name:  "delete",
 	Usage: "delete any resources held by the container often used with detached container",
@@ -42,6 +74,19 @@ status of "ubuntu01" as "stopped" the following will delete resources held for
 			Name:  "force, f",
 			Usage: "Forcibly deletes the container if it is still running (uses SIGKILL)",
 		},
+		cli.StringFlag{
+			Name:  "stop-signal",
+			Usage: "stop the container if it is still running, by sending the given `signal` (SIGTERM by default), and SIGKILL after --stop-timeout",
+		},
+		cli.DurationFlag{
+			Name:  "stop-timeout",
+			Value: 10 * time.Second,
+			Usage: "stop the container if it is still running, waiting for the given `duration` after the --stop-signal before sending SIGKILL",
+		},
+		cli.BoolFlag{
+			Name:  "summary",
+			Usage: "display the container resource usage peaks (as a summary event) once deleted",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -50,40 +95,104 @@ status of "ubuntu01" as "stopped" the following will delete resources held for
 
 		id := context.Args().First()
 		force := context.Bool("force")
-		container, err := getContainer(context)
-		if err != nil {
-			if errors.Is(err, libcontainer.ErrNotExist) {
-				// if there was an aborted start or something of the sort then the container's directory could exist but
-				// libcontainer does not see it because the state.json file inside that directory was never created.
-				path := filepath.Join(context.GlobalString("root"), id)
-				if e := os.RemoveAll(path); e != nil {
-					fmt.Fprintf(os.Stderr, "remove %s: %v\n", path, e)
-				}
-				if force {
-					return nil
+		var stop *gracefulStop
+		if context.IsSet("stop-signal") || context.IsSet("stop-timeout") {
+			stop = &gracefulStop{signal: unix.SIGTERM, timeout: context.Duration("stop-timeout")}
+			if context.IsSet("stop-signal") {
+				sig, err := parseSignal(context.String("stop-signal"))
+				if err != nil {
+					return err
 				}
+				stop.signal = sig
 			}
+			if stop.timeout < 0 {
+				return errors.New("--stop-timeout must not be negative")
+			}
+		}
+		lock, err := lockContainer(context, "delete")
+		if err != nil {
 			return err
 		}
-		// When --force is given, we kill all container processes and
-		// then destroy the container. This is done even for a stopped
-		// container, because (in case it does not have its own PID
-		// namespace) there may be some leftover processes in the
-		// container's cgroup.
-		if force {
-			return killContainer(container)
+		defer lock.Unlock()
+		err = deleteContainer(context, id, force, stop)
+		if err == nil || errors.Is(err, libcontainer.ErrNotExist) {
+			// The container is gone, and so should be its lock.
+			if rerr := lock.Remove(); rerr != nil && err == nil {
+				err = rerr
+			}
 		}
+		return err
+	},
+}
+
+func deleteContainer(context *cli.Context, id string, force bool, stop *gracefulStop) error {
+	container, err := getContainer(context)
+	if err != nil {
+		if errors.Is(err, libcontainer.ErrCorruptState) {
+			if !force {
+				return fmt.Errorf("%w (use --force to remove the container)", err)
+			}
+			return libcontainer.DestroyCorruptState(context.GlobalString("root"), id)
+		}
+		if errors.Is(err, libcontainer.ErrNotExist) {
+			// if there was an aborted start or something of the sort then the container's directory could exist but
+			// libcontainer does not see it because the state.json file inside that directory was never created.
+			// Undo whatever was done by the aborted create, and remove the directory.
+			if e := libcontainer.RollbackCreate(context.GlobalString("root"), id); e != nil {
+				fmt.Fprintf(os.Stderr, "rollback %s: %v\n", id, e)
+			}
+			if force {
+				return nil
+			}
+		}
+		return err
+	}
+	// Get the final values before the cgroup is removed.
+	var peaks *libcontainer.ResourcePeaks
+	if context.Bool("summary") {
+		if peaks, err = container.UpdatePeaks(); err != nil {
+			logrus.Warnf("unable to get resource peaks: %v", err)
+		}
+	}
+	if err := destroyContainer(container, id, force, stop); err != nil {
+		return err
+	}
+	if context.Bool("summary") {
+		return json.NewEncoder(os.Stdout).Encode(&types.Event{Type: "summary", ID: id, Data: peaks})
+	}
+	return nil
+}
+
+func destroyContainer(container *libcontainer.Container, id string, force bool, stop *gracefulStop) error {
+	// When --stop-signal or --stop-timeout is given, a running (or
+	// paused) container is stopped gracefully first.
+	if stop != nil {
 		s, err := container.Status()
 		if err != nil {
 			return err
 		}
-		switch s {
-		case libcontainer.Stopped:
-			return container.Destroy()
-		case libcontainer.Created:
-			return killContainer(container)
-		default:
-			return fmt.Errorf("cannot delete container %s that is not stopped: %s", id, s)
+		if s == libcontainer.Running || s == libcontainer.Paused {
+			return stopContainer(container, s, stop)
 		}
-	},
+	}
+	// When --force is given, we kill all container processes and
+	// then destroy the container. This is done even for a stopped
+	// container, because (in case it does not have its own PID
+	// namespace) there may be some leftover processes in the
+	// container's cgroup.
+	if force {
+		return killContainer(container)
+	}
+	s, err := container.Status()
+	if err != nil {
+		return err
+	}
+	switch s {
+	case libcontainer.Stopped:
+		return container.Destroy()
+	case libcontainer.Created:
+		return killContainer(container)
+	default:
+		return fmt.Errorf("cannot delete container %s that is not stopped: %s", id, s)
+	}
 }
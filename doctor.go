@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containerd/console"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/extcmd"
+	"github.com/opencontainers/runc/libcontainer/preflight"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+const (
+	doctorPass = "pass"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+
+	// doctorPidsLimit is the pids limit set for the throwaway containers,
+	// and checked to be applied.
+	doctorPidsLimit = 64
+	// doctorTimeout is how long the container process may run.
+	doctorTimeout = 30 * time.Second
+)
+
+var doctorCommand = cli.Command{
+	Name:  "doctor",
+	Usage: "check that containers can be run on this host",
+	Description: `The doctor command checks that runc is able to run containers on this host,
+by running the runc binary itself (runc --version) in a few throwaway
+containers with an otherwise empty root filesystem. It checks:
+
+ * kernel   the kernel settings the containers rely upon;
+ * run      running a container (with the namespaces of runc spec);
+ * cgroup   setting a resource limit (pids) for the container cgroup;
+ * userns   running a container in a new user namespace;
+ * seccomp  loading a seccomp filter;
+ * console  allocating a console (pseudo-terminal) for the container;
+ * criu     the CRIU kernel support, if criu is installed.
+
+The global --systemd-cgroup and --rootless options are honored, while the
+containers state is kept in a temporary directory rather than in --root.
+The command fails if any of the checks fails.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format, f",
+			Value: "table",
+			Usage: `select one of: ` + formatOptions,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		format := context.String("format")
+		if format != "table" && format != "json" {
+			return errors.New("invalid format option")
+		}
+		d, err := newDoctor(context)
+		if err != nil {
+			return err
+		}
+		defer d.cleanup()
+		checks := d.run()
+
+		if format == "json" {
+			if err := json.NewEncoder(os.Stdout).Encode(checks); err != nil {
+				return err
+			}
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', 0)
+			fmt.Fprint(w, "CHECK\tSTATUS\tDETAIL\n")
+			for _, c := range checks {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Status, c.Detail)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		var failed []string
+		for _, c := range checks {
+			if c.Status == doctorFail {
+				failed = append(failed, c.Name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed checks: %s", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// doctorCheck is the result of a runc doctor check.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type doctor struct {
+	context         *cli.Context
+	dir             string
+	rootfs          string
+	exe             string
+	rootless        bool
+	rootlessCgroups bool
+	count           int
+}
+
+func newDoctor(context *cli.Context) (*doctor, error) {
+	rootlessCg, err := shouldUseRootlessCgroupManager(context)
+	if err != nil {
+		return nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "runc-doctor-")
+	if err != nil {
+		return nil, err
+	}
+	d := &doctor{
+		context:         context,
+		dir:             dir,
+		rootfs:          filepath.Join(dir, "rootfs"),
+		exe:             exe,
+		rootless:        os.Geteuid() != 0,
+		rootlessCgroups: rootlessCg,
+	}
+	if err := os.Mkdir(d.rootfs, 0o755); err != nil {
+		d.cleanup()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *doctor) cleanup() {
+	_ = os.RemoveAll(d.dir)
+}
+
+// run runs all the checks.
+func (d *doctor) run() []doctorCheck {
+	var checks []doctorCheck
+	add := func(name, status, detail string) {
+		checks = append(checks, doctorCheck{Name: name, Status: status, Detail: detail})
+	}
+
+	// The kernel settings are checked for the most demanding configuration.
+	if spec, err := d.spec(); err != nil {
+		add("kernel", doctorFail, err.Error())
+	} else {
+		addUserns(spec)
+		d.kernelCheck(spec, add)
+	}
+
+	var pidsLimit uint64
+	_, err := d.runContainer(nil, func(c *libcontainer.Container) error {
+		stats, err := c.Stats()
+		if err != nil {
+			return err
+		}
+		pidsLimit = stats.CgroupStats.PidsStats.Limit
+		return nil
+	})
+	if err != nil {
+		add("run", doctorFail, err.Error())
+		add("cgroup", doctorSkip, "no container was run")
+	} else {
+		add("run", doctorPass, "")
+		switch {
+		case d.rootless && d.rootlessCgroups && !d.context.GlobalBool("systemd-cgroup"):
+			add("cgroup", doctorSkip, "resource limits of rootless containers need --systemd-cgroup")
+		case pidsLimit != doctorPidsLimit:
+			add("cgroup", doctorFail, fmt.Sprintf("pids limit is %d, expected %d", pidsLimit, doctorPidsLimit))
+		default:
+			add("cgroup", doctorPass, "")
+		}
+	}
+
+	if d.rootless {
+		// Rootless containers always use a user namespace.
+		if err != nil {
+			add("userns", doctorFail, err.Error())
+		} else {
+			add("userns", doctorPass, "")
+		}
+	} else {
+		_, err := d.runContainer(addUserns, nil)
+		addResult(add, "userns", err)
+	}
+
+	if !seccomp.Enabled {
+		add("seccomp", doctorSkip, "runc is built without seccomp support")
+	} else {
+		_, err := d.runContainer(func(spec *specs.Spec) {
+			spec.Linux.Seccomp = &specs.LinuxSeccomp{
+				DefaultAction: specs.ActAllow,
+				Syscalls: []specs.LinuxSyscall{{
+					Names:  []string{"sethostname"},
+					Action: specs.ActErrno,
+				}},
+			}
+		}, nil)
+		addResult(add, "seccomp", err)
+	}
+
+	out, err := d.runContainer(func(spec *specs.Spec) {
+		spec.Process.Terminal = true
+	}, nil)
+	if err == nil && !strings.Contains(out, "runc version") {
+		err = fmt.Errorf("unexpected console output %q", out)
+	}
+	addResult(add, "console", err)
+
+	d.criuCheck(add)
+	return checks
+}
+
+func addResult(add func(name, status, detail string), name string, err error) {
+	if err != nil {
+		add(name, doctorFail, err.Error())
+	} else {
+		add(name, doctorPass, "")
+	}
+}
+
+func addUserns(spec *specs.Spec) {
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == specs.UserNamespace {
+			return
+		}
+	}
+	spec.Linux.Namespaces = append(spec.Linux.Namespaces, specs.LinuxNamespace{Type: specs.UserNamespace})
+	spec.Linux.UIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: 0, Size: 65536}}
+	spec.Linux.GIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: 0, Size: 65536}}
+}
+
+func (d *doctor) kernelCheck(spec *specs.Spec, add func(name, status, detail string)) {
+	config, err := d.config("kernel", spec)
+	if err != nil {
+		add("kernel", doctorFail, err.Error())
+		return
+	}
+	results := preflight.Run(config)
+	failed := preflight.Failed(results)
+	if len(failed) == 0 {
+		add("kernel", doctorPass, strconv.Itoa(len(results))+" settings checked")
+		return
+	}
+	for _, r := range failed {
+		detail := r.Name
+		if r.Value != "" {
+			detail += " = " + r.Value
+		}
+		detail += ": " + r.Message
+		if r.Remediation != "" {
+			detail += "; to fix: " + r.Remediation
+		}
+		add("kernel", doctorFail, detail)
+	}
+}
+
+func (d *doctor) criuCheck(add func(name, status, detail string)) {
+	criu, err := exec.LookPath("criu")
+	if err != nil {
+		add("criu", doctorSkip, "criu not found in $PATH (only needed by checkpoint and restore)")
+		return
+	}
+	out, err := extcmd.Command(criu, "check").CombinedOutput()
+	if err != nil {
+		detail := strings.TrimSpace(string(out))
+		if i := strings.LastIndexByte(detail, '\n'); i >= 0 {
+			detail = detail[i+1:]
+		}
+		if detail == "" {
+			detail = err.Error()
+		}
+		add("criu", doctorFail, detail)
+		return
+	}
+	add("criu", doctorPass, "")
+}
+
+// spec returns the spec of a throwaway container, which runs the runc
+// binary, bind mounted into the otherwise empty root filesystem.
+func (d *doctor) spec() (*specs.Spec, error) {
+	spec := specconv.Example()
+	spec.Root.Path = d.rootfs
+	spec.Hostname = "runc-doctor"
+	spec.Process.Terminal = false
+	spec.Process.Args = []string{"/runc", "--version"}
+	spec.Mounts = append(spec.Mounts, specs.Mount{
+		Destination: "/runc",
+		Type:        "bind",
+		Source:      d.exe,
+		Options:     []string{"bind", "ro", "nosuid", "nodev"},
+	})
+	dynamic, err := isDynamicELF(d.exe)
+	if err != nil {
+		return nil, err
+	}
+	if dynamic {
+		// The dynamic loader and the libraries are needed as well.
+		for _, dir := range []string{"/lib", "/lib32", "/lib64", "/usr"} {
+			if _, err := os.Stat(dir); err == nil {
+				spec.Mounts = append(spec.Mounts, specs.Mount{
+					Destination: dir,
+					Type:        "bind",
+					Source:      dir,
+					Options:     []string{"rbind", "ro", "nosuid", "nodev"},
+				})
+			}
+		}
+	}
+	limit := int64(doctorPidsLimit)
+	spec.Linux.Resources.Pids = &specs.LinuxPids{Limit: limit}
+	if d.rootless {
+		specconv.ToRootless(spec)
+		if d.context.GlobalBool("systemd-cgroup") {
+			spec.Linux.Resources = &specs.LinuxResources{Pids: &specs.LinuxPids{Limit: limit}}
+		}
+	}
+	return spec, nil
+}
+
+func isDynamicELF(path string) (bool, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_INTERP {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *doctor) config(id string, spec *specs.Spec) (*configs.Config, error) {
+	return specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
+		CgroupName:       id,
+		UseSystemdCgroup: d.context.GlobalBool("systemd-cgroup"),
+		Spec:             spec,
+		RootlessEUID:     d.rootless,
+		RootlessCgroups:  d.rootlessCgroups,
+	})
+}
+
+// runContainer runs a throwaway container, with the spec modified by
+// modify, waits for its process, and calls check before destroying it. The
+// output of the container process is returned.
+func (d *doctor) runContainer(modify func(*specs.Spec), check func(*libcontainer.Container) error) (_ string, Err error) {
+	spec, err := d.spec()
+	if err != nil {
+		return "", err
+	}
+	if modify != nil {
+		modify(spec)
+	}
+	d.count++
+	id := "runc-doctor-" + strconv.Itoa(os.Getpid()) + "-" + strconv.Itoa(d.count)
+	config, err := d.config(id, spec)
+	if err != nil {
+		return "", err
+	}
+	container, err := libcontainer.Create(filepath.Join(d.dir, "state"), id, config)
+	if err != nil {
+		return "", annotatePreflight(err, config)
+	}
+	defer func() {
+		if err := container.Destroy(); err != nil && Err == nil {
+			Err = err
+		}
+	}()
+
+	process, err := newProcess(*spec.Process)
+	if err != nil {
+		return "", err
+	}
+	process.Init = true
+	var (
+		out     bytes.Buffer
+		outDone chan error
+	)
+	if spec.Process.Terminal {
+		parent, child, err := utils.NewSockPair("console")
+		if err != nil {
+			return "", err
+		}
+		defer parent.Close()
+		defer child.Close()
+		process.ConsoleSocket = child
+		outDone = make(chan error, 1)
+		go func() {
+			f, err := utils.RecvFile(parent)
+			if err != nil {
+				outDone <- fmt.Errorf("unable to receive console: %w", err)
+				return
+			}
+			c, err := console.ConsoleFromFile(f)
+			if err != nil {
+				outDone <- err
+				return
+			}
+			defer c.Close()
+			// An error is expected once the terminal is gone.
+			_, _ = io.Copy(&out, c)
+			outDone <- nil
+		}()
+	} else {
+		process.Stdout = &out
+		process.Stderr = &out
+	}
+
+	if err := container.Run(process); err != nil {
+		return "", annotatePreflight(err, config)
+	}
+	waitDone := make(chan error, 1)
+	go func() {
+		_, err := process.Wait()
+		waitDone <- err
+	}()
+	select {
+	case err = <-waitDone:
+	case <-time.After(doctorTimeout):
+		_ = container.Signal(unix.SIGKILL)
+		<-waitDone
+		return "", fmt.Errorf("container process did not exit in %s", doctorTimeout)
+	}
+	if outDone != nil {
+		select {
+		case err := <-outDone:
+			if err != nil {
+				return "", err
+			}
+		case <-time.After(doctorTimeout):
+			return "", errors.New("timed out reading the container console")
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("container process failed: %w (output: %q)", err, strings.TrimSpace(out.String()))
+	}
+	if check != nil {
+		if err := check(container); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// errorCode is a stable, machine-readable code of a runc error, for the
+// container engines to tell the user errors from the runtime ones without
+// parsing the error messages. It is logged (as the "code" field, with the
+// JSON log format) along with the error and, with --error-exit-codes, used
+// as the runc exit status.
+type errorCode struct {
+	name string
+	exit int
+}
+
+// The error codes. Their names and exit statuses must not be changed. The
+// exit statuses are in the range reserved by errorExitBase, so that they
+// can be told from the common exit statuses of the container processes
+// (which runc run and runc exec exit with, when not detached), such as the
+// ones of the shells (up to 128+64, for the signals).
+var (
+	// errCodeInternal is any other error, such as a runtime bug or a host
+	// configuration issue.
+	errCodeInternal = errorCode{"internal", 240}
+	// errCodeUsage is an invalid command line.
+	errCodeUsage = errorCode{"usage", 241}
+	// errCodeBundleInvalid is an invalid bundle (config.json).
+	errCodeBundleInvalid = errorCode{"bundle-invalid", 242}
+	// errCodeNotFound is a container which does not exist.
+	errCodeNotFound = errorCode{"container-not-found", 243}
+	// errCodeExists is a container which already exists.
+	errCodeExists = errorCode{"container-exists", 244}
+	// errCodeState is an operation not possible in the container state,
+	// such as starting a running container.
+	errCodeState = errorCode{"container-state", 245}
+	// errCodeCgroup is a failure to create or join the container cgroup.
+	errCodeCgroup = errorCode{"cgroup-create-failed", 246}
+	// errCodeExec is a failure to find or execute the container process.
+	errCodeExec = errorCode{"exec-failed", 247}
+)
+
+// errorExitBase is the first exit status reserved for the error codes.
+const errorExitBase = 240
+
+// errorCodes are all the error codes, for the documentation and the tests.
+var errorCodes = []errorCode{
+	errCodeInternal,
+	errCodeUsage,
+	errCodeBundleInvalid,
+	errCodeNotFound,
+	errCodeExists,
+	errCodeState,
+	errCodeCgroup,
+	errCodeExec,
+}
+
+// errorExitCodes is set by --error-exit-codes.
+var errorExitCodes bool
+
+// codedError is an error with an explicit error code, and the message of
+// the error alone.
+type codedError struct {
+	code errorCode
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode returns err with the given error code, or nil if err is nil.
+func withCode(code errorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// errorCodeOf returns the error code of err.
+func errorCodeOf(err error) errorCode {
+	var ce *codedError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	for _, c := range []struct {
+		err  error
+		code errorCode
+	}{
+		{libcontainer.ErrInvalidID, errCodeUsage},
+		{errEmptyID, errCodeUsage},
+		{libcontainer.ErrNotExist, errCodeNotFound},
+		{libcontainer.ErrExist, errCodeExists},
+		{libcontainer.ErrPaused, errCodeState},
+		{libcontainer.ErrRunning, errCodeState},
+		{libcontainer.ErrNotRunning, errCodeState},
+		{libcontainer.ErrNotPaused, errCodeState},
+		{libcontainer.ErrCorruptState, errCodeState},
+		{libcontainer.ErrAdoptedCgroup, errCodeState},
+		{libcontainer.ErrInvalidConfig, errCodeBundleInvalid},
+		{libcontainer.ErrCgroupApply, errCodeCgroup},
+		{libcontainer.ErrExec, errCodeExec},
+	} {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return errCodeInternal
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code errorCode
+	}{
+		{errors.New("something"), errCodeInternal},
+		{withCode(errCodeUsage, errors.New("bad usage")), errCodeUsage},
+		{fmt.Errorf("wrapped: %w", withCode(errCodeBundleInvalid, errors.New("bad spec"))), errCodeBundleInvalid},
+		{fmt.Errorf("loading: %w", libcontainer.ErrNotExist), errCodeNotFound},
+		{libcontainer.ErrExist, errCodeExists},
+		{libcontainer.ErrPaused, errCodeState},
+		{fmt.Errorf("loading: %w", libcontainer.ErrCorruptState), errCodeState},
+		{fmt.Errorf("pausing: %w", libcontainer.ErrAdoptedCgroup), errCodeState},
+		{fmt.Errorf("invalid: %w", libcontainer.ErrInvalidID), errCodeUsage},
+		{fmt.Errorf("%w: %w", libcontainer.ErrInvalidConfig, errors.New("bad rootfs")), errCodeBundleInvalid},
+		{fmt.Errorf("start: %w", libcontainer.ErrCgroupApply), errCodeCgroup},
+		{fmt.Errorf("start: %w", libcontainer.ErrExec), errCodeExec},
+	} {
+		if code := errorCodeOf(tc.err); code != tc.code {
+			t.Errorf("%v: expected %s, got %s", tc.err, tc.code.name, code.name)
+		}
+	}
+}
+
+func TestErrorCodesUnique(t *testing.T) {
+	names := make(map[string]bool)
+	exits := make(map[int]bool)
+	for _, c := range errorCodes {
+		if names[c.name] || exits[c.exit] {
+			t.Errorf("duplicate error code %s (%d)", c.name, c.exit)
+		}
+		names[c.name] = true
+		exits[c.exit] = true
+		// Not to collide with the exit statuses of the container
+		// processes, nor with 255 (the default one of runc exec).
+		if c.exit < errorExitBase || c.exit >= 255 {
+			t.Errorf("error code %s: exit status %d out of the reserved range", c.name, c.exit)
+		}
+	}
+}
+
+func TestCodedErrorMessage(t *testing.T) {
+	err := withCode(errCodeUsage, errors.New("bad usage"))
+	if err.Error() != "bad usage" {
+		t.Errorf("expected the message of the error alone, got %q", err.Error())
+	}
+	if withCode(errCodeUsage, nil) != nil {
+		t.Error("expected nil")
+	}
+}
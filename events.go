@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"sync"
 	"time"
 
@@ -24,12 +23,40 @@ var eventsCommand = cli.Command{
 
 Where "<container-id>" is the name for the instance of the container.`,
 	Description: `The events command displays information about the container. By default the
-information is displayed once every 5 seconds.`,
+information is displayed once every 5 seconds.
+
+With --stats, several container IDs (or --all) can be given, to get the stats
+of several containers at once, as a JSON array of events.
+
+With --output, the events are written to a file (rotated once it reaches
+--output-max-size, if set) instead of the standard output.`,
 	Flags: []cli.Flag{
 		cli.DurationFlag{Name: "interval", Value: 5 * time.Second, Usage: "set the stats collection interval"},
 		cli.BoolFlag{Name: "stats", Usage: "display the container's stats then exit"},
+		cli.BoolFlag{Name: "all, a", Usage: "with --stats, display the stats of all the running containers"},
+		cli.BoolFlag{Name: "oom-report", Usage: "upon an OOM kill, save a report on the container memory usage, and display it as an oom-report event"},
+		cli.IntFlag{Name: "oom-report-top", Value: 10, Usage: "number of processes (with the largest RSS) to include in OOM reports (0 for all)"},
+		cli.BoolFlag{Name: "fds", Usage: "also collect the totals of the file descriptors opened by the container processes (implied by --fd-threshold and --socket-threshold)"},
+		cli.IntFlag{Name: "fds-max-processes", Value: 1024, Usage: "with --fds, maximum number of container processes whose file descriptors are accounted for"},
+		cli.Uint64Flag{Name: "fd-threshold", Usage: "send an fd-threshold event when the container processes have more open file descriptors than this (0 to disable)"},
+		cli.Uint64Flag{Name: "socket-threshold", Usage: "send an fd-threshold event when the container processes have more open sockets than this (0 to disable)"},
+		cli.StringFlag{Name: "output, o", Usage: "write the events to this file (appending to it) instead of the standard output"},
+		cli.StringFlag{Name: "output-max-size", Usage: "with --output, rotate the file once it would grow over this size (such as 10M)"},
+		cli.IntFlag{Name: "output-max-files", Value: 3, Usage: "with --output-max-size, number of rotated files to keep (FILE.1 being the most recent)"},
 	},
 	Action: func(context *cli.Context) error {
+		if context.Bool("all") {
+			if !context.Bool("stats") {
+				return errors.New("--all requires --stats")
+			}
+			if err := checkArgs(context, 0, exactArgs); err != nil {
+				return err
+			}
+			return printMultiStats(context, nil)
+		}
+		if context.Bool("stats") && context.NArg() > 1 {
+			return printMultiStats(context, context.Args())
+		}
 		if err := checkArgs(context, 1, exactArgs); err != nil {
 			return err
 		}
@@ -48,6 +75,11 @@ information is displayed once every 5 seconds.`,
 		if status == libcontainer.Stopped {
 			return fmt.Errorf("container with id %s is not running", container.ID())
 		}
+		out, err := eventsOutput(context)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
 		var (
 			stats  = make(chan *libcontainer.Stats, 1)
 			events = make(chan *types.Event, 1024)
@@ -56,7 +88,7 @@ information is displayed once every 5 seconds.`,
 		group.Add(1)
 		go func() {
 			defer group.Done()
-			enc := json.NewEncoder(os.Stdout)
+			enc := json.NewEncoder(out)
 			for e := range events {
 				if err := enc.Encode(e); err != nil {
 					logrus.Error(err)
@@ -64,7 +96,7 @@ information is displayed once every 5 seconds.`,
 			}
 		}()
 		if context.Bool("stats") {
-			s, err := container.Stats()
+			s, err := containerStats(context, container)
 			if err != nil {
 				return err
 			}
@@ -73,14 +105,49 @@ information is displayed once every 5 seconds.`,
 			group.Wait()
 			return nil
 		}
+		// Other events found while collecting the stats.
+		other := make(chan *types.Event, 2)
 		go func() {
+			reported := make(map[int]uint64)
+			pidsMax := int64(-1)
+			thresholds := []*fdThreshold{
+				{resource: "fds", threshold: context.Uint64("fd-threshold")},
+				{resource: "sockets", threshold: context.Uint64("socket-threshold")},
+			}
 			for range time.Tick(context.Duration("interval")) {
-				s, err := container.Stats()
+				s, err := containerStats(context, container)
 				if err != nil {
 					logrus.Error(err)
 					continue
 				}
 				stats <- s
+				if cg := s.CgroupStats; cg != nil {
+					// Report the pids limit hits since the previous interval.
+					if n := int64(cg.PidsStats.MaxEvents); pidsMax >= 0 && n > pidsMax {
+						other <- &types.Event{Type: "pids-max", ID: container.ID(), Data: types.Pids{Current: cg.PidsStats.Current, Limit: cg.PidsStats.Limit, MaxEvents: cg.PidsStats.MaxEvents}}
+					}
+					pidsMax = int64(cg.PidsStats.MaxEvents)
+				}
+				if fds := s.FDs; fds != nil {
+					for _, t := range thresholds {
+						current := fds.FDs
+						if t.resource == "sockets" {
+							current = fds.Sockets
+						}
+						if t.exceeded(current) {
+							other <- &types.Event{Type: "fd-threshold", ID: container.ID(), Data: types.FDThreshold{Resource: t.resource, Current: current, Threshold: t.threshold}}
+						}
+					}
+				}
+				_, _ = container.UpdatePeaks()
+				if f := newForeignProcesses(container, reported); len(f) > 0 {
+					other <- &types.Event{Type: "foreign-process", ID: container.ID(), Data: f}
+				}
+				if cpus, err := container.SyncCpuset(); err != nil {
+					logrus.Warnf("unable to update cpuset: %v", err)
+				} else if cpus != "" {
+					other <- &types.Event{Type: "cpuset", ID: container.ID(), Data: map[string]string{"cpus": cpus}}
+				}
 			}
 		}()
 		n, err := container.NotifyOOM()
@@ -95,11 +162,21 @@ information is displayed once every 5 seconds.`,
 					// the channel was closed because the container stopped and
 					// the cgroups no longer exist.
 					events <- &types.Event{Type: "oom", ID: container.ID()}
+					if context.Bool("oom-report") {
+						r, err := container.CaptureOOMReport(context.Int("oom-report-top"))
+						if err != nil {
+							logrus.Error(err)
+						} else {
+							events <- &types.Event{Type: "oom-report", ID: container.ID(), Data: r}
+						}
+					}
 				} else {
 					n = nil
 				}
 			case s := <-stats:
 				events <- &types.Event{Type: "stats", ID: container.ID(), Data: convertLibcontainerStats(s)}
+			case e := <-other:
+				events <- e
 			}
 			if n == nil {
 				close(events)
@@ -111,6 +188,102 @@ information is displayed once every 5 seconds.`,
 	},
 }
 
+// printMultiStats prints the stats of the containers with the given IDs (or
+// of all the running containers, if ids is nil) as a JSON array of events.
+// A container whose stats can not be collected gets an error event.
+func printMultiStats(context *cli.Context, ids []string) error {
+	out, err := eventsOutput(context)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if ids == nil {
+		containers, err := getContainers(context)
+		if err != nil {
+			return err
+		}
+		for _, c := range containers {
+			if c.Status != libcontainer.Stopped.String() {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	root := context.GlobalString("root")
+	events := make([]*types.Event, 0, len(ids))
+	for _, id := range ids {
+		s, err := loadContainerStats(context, root, id)
+		if err != nil {
+			events = append(events, &types.Event{Type: "error", ID: id, Data: err.Error()})
+			continue
+		}
+		events = append(events, &types.Event{Type: "stats", ID: id, Data: convertLibcontainerStats(s)})
+	}
+	return json.NewEncoder(out).Encode(events)
+}
+
+func loadContainerStats(context *cli.Context, root, id string) (*libcontainer.Stats, error) {
+	container, err := libcontainer.Load(root, id)
+	if err != nil {
+		return nil, err
+	}
+	status, err := container.Status()
+	if err != nil {
+		return nil, err
+	}
+	if status == libcontainer.Stopped {
+		return nil, fmt.Errorf("container with id %s is not running", id)
+	}
+	return containerStats(context, container)
+}
+
+// containerStats returns the stats of container, including the file
+// descriptor ones if requested by --fds (or by a threshold on them).
+func containerStats(context *cli.Context, container *libcontainer.Container) (*libcontainer.Stats, error) {
+	if context.Bool("fds") || context.Uint64("fd-threshold") > 0 || context.Uint64("socket-threshold") > 0 {
+		return container.StatsWithFDs(context.Int("fds-max-processes"))
+	}
+	return container.Stats()
+}
+
+// fdThreshold is an --fd-threshold or --socket-threshold limit.
+type fdThreshold struct {
+	resource  string
+	threshold uint64
+	over      bool
+}
+
+// exceeded reports whether current went over the threshold since the
+// previous call, so that an event is only sent when the threshold is
+// crossed.
+func (t *fdThreshold) exceeded(current uint64) bool {
+	if t.threshold == 0 {
+		return false
+	}
+	wasOver := t.over
+	t.over = current > t.threshold
+	return t.over && !wasOver
+}
+
+// newForeignProcesses returns the foreign processes of the container which
+// are not in reported yet (a map of PIDs to their start times), adding them
+// to it.
+func newForeignProcesses(container *libcontainer.Container, reported map[int]uint64) []*libcontainer.ProcessInfo {
+	foreign, err := container.ForeignProcesses()
+	if err != nil {
+		logrus.Debugf("unable to check for foreign processes: %v", err)
+		return nil
+	}
+	var ret []*libcontainer.ProcessInfo
+	for _, p := range foreign {
+		if st, ok := reported[p.Pid]; ok && st == p.StartTime {
+			continue
+		}
+		reported[p.Pid] = p.StartTime
+		ret = append(ret, p)
+	}
+	return ret
+}
+
 func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	cg := ls.CgroupStats
 	if cg == nil {
@@ -119,6 +292,7 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	var s types.Stats
 	s.Pids.Current = cg.PidsStats.Current
 	s.Pids.Limit = cg.PidsStats.Limit
+	s.Pids.MaxEvents = cg.PidsStats.MaxEvents
 
 	s.CPU.Usage.Kernel = cg.CpuStats.CpuUsage.UsageInKernelmode
 	s.CPU.Usage.User = cg.CpuStats.CpuUsage.UsageInUsermode
@@ -150,6 +324,7 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	s.Blkio.IoTimeRecursive = convertBlkioEntry(cg.BlkioStats.IoTimeRecursive)
 	s.Blkio.SectorsRecursive = convertBlkioEntry(cg.BlkioStats.SectorsRecursive)
 	s.Blkio.PSI = cg.BlkioStats.PSI
+	s.Blkio.Devices = convertBlkioDevices(&cg.BlkioStats)
 
 	s.Hugetlb = make(map[string]types.Hugetlb)
 	for k, v := range cg.HugetlbStats {
@@ -175,7 +350,12 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 		}
 	}
 
-	s.NetworkInterfaces = ls.Interfaces
+	for _, i := range ls.Interfaces {
+		s.NetworkInterfaces = append(s.NetworkInterfaces, (*types.NetworkInterface)(i))
+	}
+	s.DiskQuota = (*types.DiskQuota)(ls.DiskQuota)
+	s.FDs = (*types.FDs)(ls.FDs)
+
 	return &s
 }
 
@@ -204,6 +384,45 @@ func convertBlkioEntry(c []cgroups.BlkioStatEntry) []types.BlkioEntry {
 	return out
 }
 
+// convertBlkioDevices sums up the read and write stats of each device.
+func convertBlkioDevices(b *cgroups.BlkioStats) map[string]types.BlkioDevice {
+	devs := make(map[[2]uint64]*types.BlkioDevice)
+	add := func(entries []cgroups.BlkioStatEntry, ios bool) {
+		for _, e := range entries {
+			key := [2]uint64{e.Major, e.Minor}
+			d := devs[key]
+			if d == nil {
+				d = &types.BlkioDevice{Major: e.Major, Minor: e.Minor}
+				devs[key] = d
+			}
+			switch {
+			case e.Op == "Read" && ios:
+				d.ReadIOs += e.Value
+			case e.Op == "Write" && ios:
+				d.WriteIOs += e.Value
+			case e.Op == "Read":
+				d.ReadBytes += e.Value
+			case e.Op == "Write":
+				d.WriteBytes += e.Value
+			}
+		}
+	}
+	add(b.IoServiceBytesRecursive, false)
+	add(b.IoServicedRecursive, true)
+	if len(devs) == 0 {
+		return nil
+	}
+	out := make(map[string]types.BlkioDevice, len(devs))
+	for _, d := range devs {
+		name := cgroups.BlockDeviceName(d.Major, d.Minor)
+		if name == "" {
+			name = fmt.Sprintf("%d:%d", d.Major, d.Minor)
+		}
+		out[name] = *d
+	}
+	return out
+}
+
 func convertL3CacheInfo(i *intelrdt.L3CacheInfo) *types.L3CacheInfo {
 	ci := types.L3CacheInfo(*i)
 	return &ci
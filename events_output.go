@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/docker/go-units"
+	"github.com/urfave/cli"
+)
+
+// rotatingFile is a file which is rotated once it would grow over maxSize:
+// path is renamed to path.1 (path.1 to path.2, and so on, up to maxFiles),
+// and a new, empty, path is created. Each write is kept in a single file, so
+// that an event (written at once) is never split.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+}
+
+// openRotatingFile opens (or creates) path to append to it, with the given
+// maximum size (0 for no rotation) and number of rotated files to keep.
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, fi.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, fmt.Errorf("unable to rotate %s: %w", r.path, err)
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if r.maxFiles == 0 {
+		if err := os.Remove(r.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return r.open()
+	}
+	for i := r.maxFiles - 1; i > 0; i-- {
+		err := os.Rename(r.rotatedPath(i), r.rotatedPath(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(r.path, r.rotatedPath(1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) rotatedPath(i int) string {
+	return r.path + "." + strconv.Itoa(i)
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
+
+// eventsOutput returns where runc events writes the events to: the
+// --output file, or the standard output.
+func eventsOutput(context *cli.Context) (io.WriteCloser, error) {
+	path := context.String("output")
+	if path == "" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	var maxSize int64
+	if s := context.String("output-max-size"); s != "" {
+		var err error
+		if maxSize, err = units.RAMInBytes(s); err != nil {
+			return nil, fmt.Errorf("invalid --output-max-size value %q: %w", s, err)
+		}
+		if maxSize < 0 {
+			return nil, fmt.Errorf("invalid --output-max-size value %q", s)
+		}
+	}
+	maxFiles := context.Int("output-max-files")
+	if maxFiles < 0 {
+		return nil, errors.New("--output-max-files must not be negative")
+	}
+	return openRotatingFile(path, maxSize, maxFiles)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	r, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffffffffffff\n", "gggg\n"} {
+		if _, err := r.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for p, expected := range map[string]string{
+		path:        "gggg\n",
+		path + ".1": "ffffffffffff\n", // A write larger than the maximum size is not split.
+		path + ".2": "eeee\n",
+	} {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("%s: expected %q, got %q", p, expected, data)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("more rotated files than expected")
+	}
+
+	// The size of an existing file is accounted for.
+	r, err = openRotatingFile(path, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Write([]byte("hhhhhh\n")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != "hhhhhh" {
+		t.Errorf("expected the file to be rotated, got %q", data)
+	}
+}
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/docker/go-units"
 	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
@@ -33,10 +40,18 @@ following will output a list of processes running in the container:
 			Name:  "console-socket",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal",
 		},
+		cli.IntFlag{
+			Name:  "console-socket-version",
+			Usage: "the `version` of the protocol used over the console socket (1 or 2, the default being 1)",
+		},
 		cli.StringFlag{
 			Name:  "pidfd-socket",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the exec process",
 		},
+		cli.StringFlag{
+			Name:  "stdio-socket",
+			Usage: "path to an AF_UNIX socket which will receive the file descriptors of the process stdio (stdin and stdout, then stderr) and, once it exits, its exit status",
+		},
 		cli.StringFlag{
 			Name:  "cwd",
 			Usage: "current working directory in the container",
@@ -45,6 +60,14 @@ following will output a list of processes running in the container:
 			Name:  "env, e",
 			Usage: "set environment variables",
 		},
+		cli.StringSliceFlag{
+			Name:  "env-file",
+			Usage: "read environment variables from a file, one KEY=VALUE per line (can be specified multiple times)",
+		},
+		cli.BoolFlag{
+			Name:  "expand-state-vars",
+			Usage: "expand the container state variables, such as ${CONTAINER_ID}, in the environment variables values",
+		},
 		cli.BoolFlag{
 			Name:  "tty, t",
 			Usage: "allocate a pseudo-TTY",
@@ -59,7 +82,7 @@ following will output a list of processes running in the container:
 		},
 		cli.StringFlag{
 			Name:  "process, p",
-			Usage: "path to the process.json",
+			Usage: "path to the process.json (\"-\" to read it from the standard input)",
 		},
 		cli.BoolFlag{
 			Name:  "detach,d",
@@ -91,10 +114,27 @@ following will output a list of processes running in the container:
 			Name:  "preserve-fds",
 			Usage: "Pass N additional file descriptors to the container (stdio + $LISTEN_FDS + N in total)",
 		},
+		secretFlag,
 		cli.StringSliceFlag{
 			Name:  "cgroup",
 			Usage: "run the process in an (existing) sub-cgroup(s). Format is [<controller>:]<cgroup>.",
 		},
+		cli.BoolFlag{
+			Name:  "cgroup-create",
+			Usage: "create the --cgroup sub-cgroup(s) if they do not exist",
+		},
+		cli.StringSliceFlag{
+			Name:  "cgroup-set",
+			Usage: "set a cgroup file of the --cgroup sub-cgroup(s), as FILE=VALUE, such as memory.max=64M (can be specified multiple times)",
+		},
+		cli.BoolFlag{
+			Name:  "cgroup-remove",
+			Usage: "once the process exits, kill the processes left in the --cgroup sub-cgroup(s), and remove them",
+		},
+		cli.StringFlag{
+			Name:  "cgroup-of",
+			Usage: "run the process in the cgroup(s) of another running container (of the same owner), for its resources to be accounted against that container",
+		},
 		cli.BoolFlag{
 			Name:  "ignore-paused",
 			Usage: "allow exec in a paused container",
@@ -143,7 +183,60 @@ func getSubCgroupPaths(args []string) (map[string]string, error) {
 	return paths, nil
 }
 
+// parseCgroupSet parses the --cgroup-set values (FILE=VALUE). The values of
+// the memory controller files can be sizes with a unit suffix (such as 64M),
+// which are expanded.
+func parseCgroupSet(vals []string) (map[string]string, error) {
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	set := make(map[string]string, len(vals))
+	for _, v := range vals {
+		file, value, ok := strings.Cut(v, "=")
+		if !ok || file == "" || strings.ContainsRune(file, '/') {
+			return nil, fmt.Errorf("invalid --cgroup-set %q: must be FILE=VALUE", v)
+		}
+		if strings.HasPrefix(file, "memory.") {
+			if size, err := units.RAMInBytes(value); err == nil {
+				value = strconv.FormatInt(size, 10)
+			}
+		}
+		set[file] = value
+	}
+	return set, nil
+}
+
+// lockExec takes the lock on the container, and on the --cgroup-of one
+// (if any), so that the latter can not be deleted while the process is
+// being put into its cgroup.
+func lockExec(context *cli.Context) (lock, cgroupOfLock *libcontainer.ContainerLock, err error) {
+	id, other := context.Args().First(), context.String("cgroup-of")
+	if other == "" || other == id {
+		lock, err = lockContainer(context, "exec")
+		return lock, nil, err
+	}
+	if id == "" {
+		return nil, nil, errEmptyID
+	}
+	// Take the locks in the same order as LockContainers does, so that
+	// two exec --cgroup-of the other way around can not deadlock.
+	locks, err := libcontainer.LockContainers(context.GlobalString("root"), []string{id, other}, "exec")
+	if err != nil {
+		return nil, nil, err
+	}
+	if id < other {
+		return locks[0], locks[1], nil
+	}
+	return locks[1], locks[0], nil
+}
+
 func execProcess(context *cli.Context) (int, error) {
+	lock, cgroupOfLock, err := lockExec(context)
+	if err != nil {
+		return -1, err
+	}
+	defer lock.Unlock()
+	defer cgroupOfLock.Unlock()
 	container, err := getContainer(context)
 	if err != nil {
 		return -1, err
@@ -153,10 +246,13 @@ func execProcess(context *cli.Context) (int, error) {
 		return -1, err
 	}
 	if status == libcontainer.Stopped {
-		return -1, errors.New("cannot exec in a stopped container")
+		return -1, withCode(errCodeState, errors.New("cannot exec in a stopped container"))
 	}
 	if status == libcontainer.Paused && !context.Bool("ignore-paused") {
-		return -1, errors.New("cannot exec in a paused container (use --ignore-paused to override)")
+		return -1, withCode(errCodeState, errors.New("cannot exec in a paused container (use --ignore-paused to override)"))
+	}
+	if err := checkConfigDrift(container); err != nil {
+		return -1, err
 	}
 	path := context.String("process")
 	if path == "" && len(context.Args()) == 1 {
@@ -174,40 +270,342 @@ func execProcess(context *cli.Context) (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	if context.Bool("expand-state-vars") {
+		p.Env = expandStateVars(p.Env, stateVars(state, bundle))
+	}
 
 	cgPaths, err := getSubCgroupPaths(context.StringSlice("cgroup"))
 	if err != nil {
 		return -1, err
 	}
+	cgSet, err := parseCgroupSet(context.StringSlice("cgroup-set"))
+	if err != nil {
+		return -1, err
+	}
+	cgCreate, cgRemove := context.Bool("cgroup-create"), context.Bool("cgroup-remove")
+	if cgPaths == nil && (cgCreate || cgRemove || cgSet != nil) {
+		return -1, errors.New("--cgroup-create, --cgroup-set and --cgroup-remove require --cgroup")
+	}
+	if cgRemove && context.Bool("detach") {
+		return -1, errors.New("--cgroup-remove can not be used with --detach")
+	}
+	if cgRemove {
+		for _, p := range cgPaths {
+			if filepath.Clean("/"+p) == "/" {
+				return -1, errors.New("--cgroup-remove can not remove the container cgroup")
+			}
+		}
+	}
+	var cgroupOfPaths map[string]string
+	if id := context.String("cgroup-of"); id != "" {
+		if cgPaths != nil {
+			return -1, errors.New("--cgroup-of can not be used with --cgroup")
+		}
+		if cgroupOfPaths, err = cgroupOf(context, container, id); err != nil {
+			return -1, err
+		}
+	}
+	secrets, err := parseSecrets(context)
+	if err != nil {
+		return -1, err
+	}
+	defer closeSecrets(secrets)
 
 	r := &runner{
 		enableSubreaper: false,
 		shouldDestroy:   false,
 		container:       container,
 		consoleSocket:   context.String("console-socket"),
+		consoleVersion:  context.Int("console-socket-version"),
 		pidfdSocket:     context.String("pidfd-socket"),
+		stdioSocket:     context.String("stdio-socket"),
 		detach:          context.Bool("detach"),
 		pidFile:         context.String("pid-file"),
 		action:          CT_ACT_RUN,
 		init:            false,
 		preserveFDs:     context.Int("preserve-fds"),
 		subCgroupPaths:  cgPaths,
+		subCgroupCreate: cgCreate,
+		subCgroupSet:    cgSet,
+		cgroupPaths:     cgroupOfPaths,
+		secrets:         secrets,
+		lock:            lock,
+		cgroupOfLock:    cgroupOfLock,
 	}
-	return r.run(p)
+	exitStatus, err := r.run(p)
+	if cgRemove {
+		if err := container.DestroySubCgroups(cgPaths); err != nil {
+			logrus.Warnf("unable to remove the sub-cgroup: %v", err)
+		}
+	}
+	return exitStatus, err
 }
 
-func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
-	if path := context.String("process"); path != "" {
-		f, err := os.Open(path)
+// cgroupOf returns the cgroup paths of the container with the given ID, for
+// exec --cgroup-of. That container must be running, and have its state in
+// the same root, with the same owner, as container. The owner is the host
+// user the container root is mapped to, so a container can not be used to
+// account (or limit) the processes of another user.
+//
+// The caller must hold the lock of that container (see lockExec).
+func cgroupOf(context *cli.Context, container *libcontainer.Container, id string) (map[string]string, error) {
+	root := context.GlobalString("root")
+	other, err := libcontainer.Load(root, id)
+	if err != nil {
+		return nil, fmt.Errorf("--cgroup-of %s: %w", id, err)
+	}
+	status, err := other.Status()
+	if err != nil {
+		return nil, err
+	}
+	if status != libcontainer.Running && status != libcontainer.Created {
+		return nil, withCode(errCodeState, fmt.Errorf("--cgroup-of: container %s is %s", id, status))
+	}
+	var owners [2]int
+	for i, c := range []*libcontainer.Container{container, other} {
+		config := c.Config()
+		if owners[i], err = config.HostRootUID(); err != nil {
+			return nil, fmt.Errorf("--cgroup-of: container %s: %w", c.ID(), err)
+		}
+	}
+	if owners[0] != owners[1] {
+		return nil, fmt.Errorf("--cgroup-of: container %s is not owned by the owner of container %s", id, container.ID())
+	}
+	paths := other.CgroupPaths()
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("--cgroup-of: container %s has no cgroup", id)
+	}
+	return paths, nil
+}
+
+// stateVars returns the container state variables which can be referenced
+// from the environment of a process being executed, with
+// --expand-state-vars (see expandStateVars).
+func stateVars(state *libcontainer.State, bundle string) map[string]string {
+	cgroupPath := state.CgroupPaths[""]
+	if cgroupPath == "" {
+		// cgroup v1: there is no single cgroup, use the devices one
+		// (same as libcontainer does to check if the cgroup exists).
+		cgroupPath = state.CgroupPaths["devices"]
+	}
+	return map[string]string{
+		"CONTAINER_ID": state.ID,
+		"INIT_PID":     strconv.Itoa(state.InitProcessPid),
+		"CGROUP_PATH":  cgroupPath,
+		"BUNDLE":       bundle,
+		"ROOTFS":       state.Config.Rootfs,
+	}
+}
+
+// expandStateVars replaces references to the container state variables
+// in the form of ${NAME} in the environment variables values. Other
+// references (including $NAME without braces) are left as is.
+func expandStateVars(env []string, vars map[string]string) []string {
+	pairs := make([]string, 0, 2*len(vars))
+	for name, value := range vars {
+		pairs = append(pairs, "${"+name+"}", value)
+	}
+	r := strings.NewReplacer(pairs...)
+	for i, e := range env {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok || !strings.Contains(v, "${") {
+			continue
+		}
+		env[i] = k + "=" + r.Replace(v)
+	}
+	return env
+}
+
+// readEnvFile reads the environment variables from an --env-file file, which
+// has one KEY=VALUE per line. Empty lines, and lines starting with #, are
+// ignored. A line with only KEY passes the variable of that name of the
+// runc environment, if it is set.
+func readEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var env []string
+	sc := bufio.NewScanner(f)
+	// Allow long values.
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimLeft(sc.Text(), " \t")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, _, ok := strings.Cut(line, "=")
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: invalid variable name %q", path, n, key)
+		}
+		if !ok {
+			val, found := os.LookupEnv(key)
+			if !found {
+				continue
+			}
+			line = key + "=" + val
+		}
+		env = append(env, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return env, nil
+}
+
+// processFile is the format of the --process file: an OCI process, along
+// with the optional version of the runtime spec it follows.
+type processFile struct {
+	specs.Process
+	Version string `json:"ociVersion,omitempty"`
+}
+
+// readProcessFile reads a --process file. Its fields unknown to runc are
+// ignored (with a warning), unless the file follows a version of the runtime
+// spec newer than the one runc supports, in which case they are likely to be
+// needed, and an error is returned. So, a newer engine can use an older runc,
+// as long as it does not use the newer process fields.
+//
+// If path is "-", the process is read from the standard input instead, so
+// that it (and its environment, which may contain secrets) does not have to
+// be written to disk.
+func readProcessFile(path string) (*specs.Process, error) {
+	var (
+		data []byte
+		err  error
+	)
+	fromStdin := path == "-"
+	if fromStdin {
+		path = "<stdin>"
+		data, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the process from the standard input: %w", err)
+		}
+	} else {
+		data, err = os.ReadFile(path)
 		if err != nil {
 			return nil, err
 		}
-		defer f.Close()
-		var p specs.Process
-		if err := json.NewDecoder(f).Decode(&p); err != nil {
+	}
+	var p processFile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid process file %s: %w", path, err)
+	}
+	newer := false
+	if p.Version != "" {
+		cmp, err := compareSpecVersions(p.Version, specs.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process file %s: %w", path, err)
+		}
+		if major, _, _ := strings.Cut(p.Version, "."); major != strconv.Itoa(specs.VersionMajor) {
+			return nil, fmt.Errorf("process file %s: unsupported ociVersion %s (supported: %s)", path, p.Version, specs.Version)
+		}
+		newer = cmp > 0
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid process file %s: %w", path, err)
+	}
+	if unknown := unknownJSONFields(fields, p); len(unknown) > 0 {
+		if newer {
+			return nil, fmt.Errorf("process file %s: fields %s, from ociVersion %s, are not supported (supported: %s)", path, strings.Join(unknown, ", "), p.Version, specs.Version)
+		}
+		logrus.Warnf("process file %s: ignoring unknown fields %s", path, strings.Join(unknown, ", "))
+	}
+	if fromStdin && p.Terminal {
+		// The standard input has been consumed, and can not be the
+		// terminal input.
+		return nil, fmt.Errorf("process file %s: terminal can not be used when the process is read from the standard input", path)
+	}
+	return &p.Process, nil
+}
+
+// unknownJSONFields returns the sorted keys of fields not matching any field
+// of v, a struct (JSON keys being matched case insensitively, like
+// json.Unmarshal does).
+func unknownJSONFields(fields map[string]json.RawMessage, v interface{}) []string {
+	var known []string
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if f.Anonymous && name == "" {
+				add(f.Type)
+				continue
+			}
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			known = append(known, name)
+		}
+	}
+	add(reflect.TypeOf(v))
+
+	var unknown []string
+next:
+	for key := range fields {
+		for _, k := range known {
+			if strings.EqualFold(key, k) {
+				continue next
+			}
+		}
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// compareSpecVersions compares two runtime spec versions (such as "1.0.2",
+// "1.1.0-rc.1" or "1.2.0+dev"), ignoring the pre-release and build suffixes.
+func compareSpecVersions(a, b string) (int, error) {
+	parse := func(v string) ([3]int, error) {
+		var parsed [3]int
+		v, _, _ = strings.Cut(v, "+")
+		v, _, _ = strings.Cut(v, "-")
+		parts := strings.Split(v, ".")
+		if len(parts) != 3 {
+			return parsed, fmt.Errorf("invalid version %q", v)
+		}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return parsed, fmt.Errorf("invalid version %q", v)
+			}
+			parsed[i] = n
+		}
+		return parsed, nil
+	}
+	va, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
+	if path := context.String("process"); path != "" {
+		p, err := readProcessFile(path)
+		if err != nil {
 			return nil, err
 		}
-		return &p, validateProcessSpec(&p)
+		return p, validateProcessSpec(p)
 	}
 	// process via cli flags
 	if err := os.Chdir(bundle); err != nil {
@@ -238,6 +636,13 @@ func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
 		}
 	}
 	// append the passed env variables
+	for _, path := range context.StringSlice("env-file") {
+		env, err := readEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p.Env = append(p.Env, env...)
+	}
 	p.Env = append(p.Env, context.StringSlice("env")...)
 
 	// set the tty
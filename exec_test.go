@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	t.Setenv("RUNC_ENV_FILE_TEST", "from runc")
+	for _, tc := range []struct {
+		data     string
+		expected []string
+		isErr    bool
+	}{
+		{
+			data:     "A=1\n\n# comment\n  B=x y=z \nC=\n",
+			expected: []string{"A=1", "B=x y=z ", "C="},
+		},
+		{
+			data:     "RUNC_ENV_FILE_TEST\nRUNC_ENV_FILE_UNSET\n",
+			expected: []string{"RUNC_ENV_FILE_TEST=from runc"},
+		},
+		{
+			data: "",
+		},
+		{
+			data:  "=1\n",
+			isErr: true,
+		},
+		{
+			data:  "A B=1\n",
+			isErr: true,
+		},
+	} {
+		path := filepath.Join(t.TempDir(), "env")
+		if err := os.WriteFile(path, []byte(tc.data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		env, err := readEnvFile(path)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", tc.data, env)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.data, err)
+		} else if !reflect.DeepEqual(env, tc.expected) {
+			t.Errorf("%q: expected %q, got %q", tc.data, tc.expected, env)
+		}
+	}
+
+	if _, err := readEnvFile(filepath.Join(t.TempDir(), "nonexistent")); err == nil {
+		t.Error("expected error for a nonexistent file")
+	}
+}
+
+func TestParseCgroupSet(t *testing.T) {
+	for _, tc := range []struct {
+		in       []string
+		expected map[string]string
+		isErr    bool
+	}{
+		{in: nil},
+		{
+			in:       []string{"memory.max=64M", "pids.max=10", "memory.high=max", "cpu.max=50000 100000"},
+			expected: map[string]string{"memory.max": "67108864", "pids.max": "10", "memory.high": "max", "cpu.max": "50000 100000"},
+		},
+		{in: []string{"memory.max"}, isErr: true},
+		{in: []string{"=1"}, isErr: true},
+		{in: []string{"../memory.max=1"}, isErr: true},
+	} {
+		set, err := parseCgroupSet(tc.in)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tc.in, set)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if !reflect.DeepEqual(set, tc.expected) {
+			t.Errorf("%q: expected %v, got %v", tc.in, tc.expected, set)
+		}
+	}
+}
+
+func TestExpandStateVars(t *testing.T) {
+	vars := map[string]string{"CONTAINER_ID": "ctr", "INIT_PID": "42"}
+	for _, tc := range []struct {
+		in       string
+		expected string
+	}{
+		{in: "A=${CONTAINER_ID}", expected: "A=ctr"},
+		{in: "A=${CONTAINER_ID}:${INIT_PID}:${CONTAINER_ID}", expected: "A=ctr:42:ctr"},
+		{in: "A=$CONTAINER_ID", expected: "A=$CONTAINER_ID"},
+		{in: "A=${HOME}", expected: "A=${HOME}"},
+		{in: "A=${CONTAINER_ID", expected: "A=${CONTAINER_ID"},
+		{in: "${CONTAINER_ID}=x", expected: "${CONTAINER_ID}=x"},
+		{in: "A=", expected: "A="},
+		{in: "NOVALUE", expected: "NOVALUE"},
+	} {
+		env := expandStateVars([]string{tc.in}, vars)
+		if env[0] != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.expected, env[0])
+		}
+	}
+}
+
+func TestReadProcessFile(t *testing.T) {
+	for _, tc := range []struct {
+		data  string
+		isErr bool
+	}{
+		{data: `{"cwd": "/", "args": ["sh"]}`},
+		{data: `{"ociVersion": "1.0.0", "cwd": "/", "Args": ["sh"]}`},
+		// Unknown fields are ignored, unless from a newer version.
+		{data: `{"cwd": "/", "args": ["sh"], "newField": 1}`},
+		{data: `{"ociVersion": "1.0.0", "cwd": "/", "args": ["sh"], "newField": 1}`},
+		{data: `{"ociVersion": "1.99.0", "cwd": "/", "args": ["sh"]}`},
+		{data: `{"ociVersion": "1.99.0", "cwd": "/", "args": ["sh"], "newField": 1}`, isErr: true},
+		{data: `{"ociVersion": "2.0.0", "cwd": "/", "args": ["sh"]}`, isErr: true},
+		{data: `{"ociVersion": "1.0", "cwd": "/", "args": ["sh"]}`, isErr: true},
+		{data: `{"cwd": "/", "args": "sh"}`, isErr: true},
+	} {
+		path := filepath.Join(t.TempDir(), "process.json")
+		if err := os.WriteFile(path, []byte(tc.data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		p, err := readProcessFile(path)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", tc.data, p)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.data, err)
+		} else if p.Cwd != "/" || !reflect.DeepEqual(p.Args, []string{"sh"}) {
+			t.Errorf("%s: unexpected process %+v", tc.data, p)
+		}
+	}
+}
+
+func TestReadProcessFileStdin(t *testing.T) {
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	readStdin := func(data string) (*specs.Process, error) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		os.Stdin = r
+		if _, err := w.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		return readProcessFile("-")
+	}
+
+	p, err := readStdin(`{"cwd": "/", "args": ["sh"], "env": ["SECRET=x"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Cwd != "/" || !reflect.DeepEqual(p.Args, []string{"sh"}) || !reflect.DeepEqual(p.Env, []string{"SECRET=x"}) {
+		t.Errorf("unexpected process %+v", p)
+	}
+
+	// The standard input is consumed, so it can not be a terminal.
+	if p, err := readStdin(`{"terminal": true, "cwd": "/", "args": ["sh"]}`); err == nil {
+		t.Errorf("expected an error with terminal, got %+v", p)
+	}
+}
+
+func TestCompareSpecVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.2", "1.2.0+dev", -1},
+		{"1.10.0", "1.9.1", 1},
+		{"1.1.0-rc.1", "1.1.0", 0},
+	} {
+		got, err := compareSpecVersions(tc.a, tc.b)
+		if err != nil {
+			t.Errorf("%s, %s: unexpected error: %v", tc.a, tc.b, err)
+		} else if got != tc.expected {
+			t.Errorf("%s, %s: expected %d, got %d", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
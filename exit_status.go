@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/system"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+)
+
+// exitStatus is the content (in JSON) of the runc run --exit-status-file.
+type exitStatus struct {
+	// ExitCode is the exit status of the container init process, or 128
+	// plus the signal number if it was killed by a signal.
+	ExitCode int `json:"exit_code"`
+	// OOMKilled tells whether a container process was killed by the OOM
+	// killer.
+	OOMKilled bool `json:"oom_killed"`
+}
+
+// writeExitStatus atomically writes the exit status of the init process of
+// container to path.
+func writeExitStatus(path string, container *libcontainer.Container, code int) error {
+	s := exitStatus{ExitCode: code}
+	if n, err := container.OOMKillCount(); err != nil {
+		logrus.Warnf("unable to get the OOM kill count: %v", err)
+	} else {
+		s.OOMKilled = n > 0
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmpName := filepath.Join(filepath.Dir(path), "."+filepath.Base(path))
+	f, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|unix.O_CLOEXEC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("unable to write the exit status file: %w", err)
+	}
+	return nil
+}
+
+// exitMonitorEnv marks the processes of runc run --detach with
+// --exit-status-file or --control-socket (see runExitMonitored):
+// "monitor:<fd>" for the exit monitor, writing the runc run exit status to
+// fd, and "run" for the runc run it runs.
+const exitMonitorEnv = "_RUNC_EXIT_MONITOR"
+
+// exitMonitored tells whether the container init process of runc run is to
+// be watched by an exit monitor, rather than by runc run itself.
+func exitMonitored(context *cli.Context) bool {
+	return context.Bool("detach") && (context.String("exit-status-file") != "" || context.Bool("control-socket")) && os.Getenv(exitMonitorEnv) == ""
+}
+
+// preservedFiles returns the extra files runc run passes to the container.
+func preservedFiles(context *cli.Context) []*os.File {
+	files := make([]*os.File, context.Int("preserve-fds"))
+	for i := range files {
+		files[i] = os.NewFile(uintptr(3+i), "preserved-fd-"+strconv.Itoa(3+i))
+	}
+	return files
+}
+
+// reexecRun returns a command running runc with the current command line,
+// and exitMonitorEnv set to role.
+func reexecRun(context *cli.Context, role string) *exec.Cmd {
+	cmd := exec.Command("/proc/self/exe", os.Args[1:]...)
+	cmd.Args[0] = os.Args[0]
+	cmd.Env = append(os.Environ(), exitMonitorEnv+"="+role)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = preservedFiles(context)
+	return cmd
+}
+
+// runExitMonitored runs runc run --detach with an exit monitor: as the
+// container init process is reparented once runc run exits, only an
+// ancestor subreaper can get its exit status. The monitor, in a new
+// session, is such a subreaper for the runc run it runs, and writes the
+// exit status file once the container init exits (serving the control
+// socket until then, with --control-socket). runExitMonitored returns
+// the runc run exit status, once it is done.
+func runExitMonitored(context *cli.Context) (int, error) {
+	if os.Getenv("LISTEN_FDS") != "" {
+		return -1, errors.New("--exit-status-file and --control-socket can not be used with --detach and socket activation")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return -1, err
+	}
+	defer r.Close()
+	fd := 3 + context.Int("preserve-fds")
+	cmd := reexecRun(context, "monitor:"+strconv.Itoa(fd))
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+	cmd.SysProcAttr = &unix.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return -1, fmt.Errorf("unable to start the exit monitor: %w", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return -1, err
+	}
+	status, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return -1, errors.New("the exit monitor failed")
+	}
+	return status, nil
+}
+
+// exitMonitor is the exit monitor of runExitMonitored, which never returns.
+// Once runc run returns, its errors are only logged with --log, as its
+// stdio is then /dev/null.
+func exitMonitor(context *cli.Context) {
+	if err := runExitMonitor(context); err != nil {
+		logrus.Errorf("exit monitor: %v", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func runExitMonitor(context *cli.Context) error {
+	fd, err := strconv.Atoi(strings.TrimPrefix(os.Getenv(exitMonitorEnv), "monitor:"))
+	if err != nil {
+		return fmt.Errorf("invalid %s value", exitMonitorEnv)
+	}
+	unix.CloseOnExec(fd)
+	statusPipe := os.NewFile(uintptr(fd), "exit-monitor-status")
+	if err := system.SetSubreaper(1); err != nil {
+		return err
+	}
+
+	cmd := reexecRun(context, "run")
+	status := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		status = exitErr.ExitCode()
+	}
+	var (
+		container *libcontainer.Container
+		pid       int
+	)
+	if status == 0 {
+		container, err = libcontainer.Load(context.GlobalString("root"), context.Args().First())
+		if err != nil {
+			return err
+		}
+		state, err := container.State()
+		if err != nil {
+			return err
+		}
+		pid = state.InitProcessPid
+		if context.Bool("control-socket") {
+			// Before runc run returns, so that the socket is there
+			// once it does.
+			cs, err := startControlSocket(context.GlobalString("root"), container, nil)
+			if err != nil {
+				logrus.Error(err)
+				_ = container.Signal(unix.SIGKILL)
+				status = 1
+			} else {
+				defer cs.close()
+			}
+		}
+	}
+	// Let runc run return, and stop holding its stdio.
+	_, _ = fmt.Fprintln(statusPipe, status)
+	statusPipe.Close()
+	if err := detachStdio(cmd.ExtraFiles); err != nil {
+		return err
+	}
+	if status != 0 {
+		return nil
+	}
+
+	code, err := waitInit(pid)
+	if err != nil {
+		return fmt.Errorf("unable to wait for the container init process %d: %w", pid, err)
+	}
+	if path := context.String("exit-status-file"); path != "" {
+		return writeExitStatus(path, container, code)
+	}
+	return nil
+}
+
+// waitInit waits for the container init process pid, reparented to this
+// subreaper process, to exit, and returns its exit status (see
+// exitStatus.ExitCode). Its exit is told by a process file descriptor, if
+// the kernel supports it (Linux 5.3 or later), and by SIGCHLD otherwise.
+// The other processes reparented to this one (the orphaned container
+// processes, if the container shares the host PID namespace) are reaped as
+// they exit.
+func waitInit(pid int) (int, error) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, unix.SIGCHLD)
+	defer signal.Stop(sigc)
+	// Reap the processes which exited before signal.Notify.
+	sigc <- unix.SIGCHLD
+
+	exited := make(chan error, 1)
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err == nil {
+		defer unix.Close(pidfd)
+		go func() {
+			fds := []unix.PollFd{{Fd: int32(pidfd), Events: unix.POLLIN}}
+			for {
+				if _, err := unix.Poll(fds, -1); err != unix.EINTR {
+					exited <- err
+					return
+				}
+			}
+		}()
+	} else if !errors.Is(err, unix.ENOSYS) {
+		return -1, os.NewSyscallError("pidfd_open", err)
+	}
+
+	for {
+		// The init process PID can not be reused until it is reaped here.
+		flags := unix.WNOHANG
+		wpid := -1
+		select {
+		case err := <-exited:
+			if err != nil {
+				return -1, os.NewSyscallError("poll", err)
+			}
+			flags, wpid = 0, pid
+		case <-sigc:
+		}
+		for {
+			var ws unix.WaitStatus
+			p, err := unix.Wait4(wpid, &ws, flags, nil)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				return -1, err
+			}
+			if p == pid {
+				if ws.Signaled() {
+					return 128 + int(ws.Signal()), nil
+				}
+				return ws.ExitStatus(), nil
+			}
+			if p == 0 {
+				break
+			}
+		}
+	}
+}
+
+// detachStdio replaces the standard input and outputs by /dev/null, and
+// closes the preserved files.
+func detachStdio(preserved []*os.File) error {
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer null.Close()
+	for fd := 0; fd < 3; fd++ {
+		if err := unix.Dup3(int(null.Fd()), fd, 0); err != nil {
+			return err
+		}
+	}
+	for _, f := range preserved {
+		f.Close()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestWaitInit(t *testing.T) {
+	// Another child, reaped along the way.
+	other := exec.Command("true")
+	if err := other.Start(); err != nil {
+		t.Fatal(err)
+	}
+	initCmd := exec.Command("sh", "-c", "sleep 0.2; exit 3")
+	if err := initCmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	code, err := waitInit(initCmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("expected exit status 3, got %d", code)
+	}
+	if _, err := unix.Wait4(other.Process.Pid, nil, unix.WNOHANG, nil); err != unix.ECHILD {
+		t.Errorf("expected the other child to be reaped, got %v", err)
+	}
+
+	killed := exec.Command("sleep", "10")
+	if err := killed.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_ = killed.Process.Kill()
+	if code, err := waitInit(killed.Process.Pid); err != nil || code != 128+int(unix.SIGKILL) {
+		t.Errorf("expected exit status %d, got %d (%v)", 128+int(unix.SIGKILL), code, err)
+	}
+}
@@ -3,15 +3,24 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
+	gocriu "github.com/checkpoint-restore/go-criu/v6"
+	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/capabilities"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/lsm"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/specconv"
 	runcfeatures "github.com/opencontainers/runc/types/features"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
 )
 
 var featuresCommand = cli.Command{
@@ -36,6 +45,9 @@ var featuresCommand = cli.Command{
 				runcfeatures.AnnotationRuncVersion:           version,
 				runcfeatures.AnnotationRuncCommit:            gitCommit,
 				runcfeatures.AnnotationRuncCheckpointEnabled: "true",
+				runcfeatures.AnnotationStateVersion:          strconv.Itoa(libcontainer.StateVersion),
+				runcfeatures.AnnotationConsoleSocketVersions: consoleSocketVersions(),
+				runcfeatures.AnnotationMountSetattr:          strconv.FormatBool(mountSetattrSupported()),
 			},
 			Hooks:        configs.KnownHookNames(),
 			MountOptions: specconv.KnownMountOptions(),
@@ -71,7 +83,7 @@ var featuresCommand = cli.Command{
 				Enabled:        &tru,
 				Actions:        seccomp.KnownActions(),
 				Operators:      seccomp.KnownOperators(),
-				Archs:          seccomp.KnownArchs(),
+				Archs:          seccomp.SupportedArchs(),
 				KnownFlags:     seccomp.KnownFlags(),
 				SupportedFlags: seccomp.SupportedFlags(),
 			}
@@ -79,8 +91,67 @@ var featuresCommand = cli.Command{
 			feat.Annotations[runcfeatures.AnnotationLibseccompVersion] = fmt.Sprintf("%d.%d.%d", major, minor, patch)
 		}
 
+		if rootless, err := shouldUseRootlessCgroupManager(context); err == nil {
+			if caps, err := systemd.DetectCapabilities(rootless); err == nil {
+				feat.Annotations[runcfeatures.AnnotationSystemdVersion] = strconv.Itoa(caps.Version)
+				feat.Annotations[runcfeatures.AnnotationSystemdFeatures] = strings.Join(caps.Features, ",")
+			}
+		}
+
+		if stack, err := lsm.Stack(); err == nil {
+			feat.Annotations[runcfeatures.AnnotationLSMStack] = strings.Join(stack, ",")
+		}
+
+		if controllers, err := cgroups.GetAllSubsystems(); err == nil {
+			sort.Strings(controllers)
+			feat.Annotations[runcfeatures.AnnotationCgroupControllers] = strings.Join(controllers, ",")
+		}
+		var unavailable []string
+		for _, f := range cgroups.UnavailableV2Features(nil) {
+			unavailable = append(unavailable, f.Name)
+		}
+		feat.Annotations[runcfeatures.AnnotationCgroupV2Unavailable] = strings.Join(unavailable, ",")
+
+		if v, err := gocriu.MakeCriu().GetCriuVersion(); err == nil {
+			feat.Annotations[runcfeatures.AnnotationCriuVersion] = criuVersionString(v)
+		}
+
+		feat.Annotations[runcfeatures.AnnotationHelperGOMAXPROCS] = helperGOMAXPROCS()
+		feat.Annotations[runcfeatures.AnnotationHelperMemoryLimit] = helperMemoryLimit()
+		if nice, err := libcontainer.Nice(0); err == nil {
+			feat.Annotations[runcfeatures.AnnotationHelperNice] = strconv.Itoa(nice)
+		}
+		feat.Annotations[runcfeatures.AnnotationHelperMaxParallel] = strconv.Itoa(libcontainer.MaxParallel())
+
 		enc := json.NewEncoder(context.App.Writer)
 		enc.SetIndent("", "    ")
 		return enc.Encode(feat)
 	},
 }
+
+// consoleSocketVersions returns the supported console socket protocol
+// versions, as a comma-separated list.
+func consoleSocketVersions() string {
+	versions := make([]string, 0, libcontainer.ConsoleSocketVersion)
+	for v := libcontainer.ConsoleSocketV1; v <= libcontainer.ConsoleSocketVersion; v++ {
+		versions = append(versions, strconv.Itoa(v))
+	}
+	return strings.Join(versions, ",")
+}
+
+// mountSetattrSupported returns whether the kernel supports mount_setattr(2).
+func mountSetattrSupported() bool {
+	// An invalid fd gives EBADF if the syscall is implemented.
+	err := unix.MountSetattr(-1, "", unix.AT_EMPTY_PATH, &unix.MountAttr{})
+	return err != unix.ENOSYS
+}
+
+// criuVersionString formats a CRIU version as returned by GetCriuVersion
+// (major*10000 + minor*100 + sublevel), omitting a zero sublevel.
+func criuVersionString(v int) string {
+	major, minor, sublevel := v/10000, v/100%100, v%100
+	if sublevel == 0 {
+		return fmt.Sprintf("%d.%d", major, minor)
+	}
+	return fmt.Sprintf("%d.%d.%d", major, minor, sublevel)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/docker/go-units"
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// applyHelperLimits caps the resource usage of runc itself, as requested by
+// the --helper-gomaxprocs, --helper-memory-limit, --helper-nice and
+// --helper-max-parallel options, so that runc competes less with the
+// workloads, for example on a small device.
+func applyHelperLimits(context *cli.Context) error {
+	if context.GlobalIsSet("helper-gomaxprocs") {
+		n := context.GlobalInt("helper-gomaxprocs")
+		if n < 1 {
+			return fmt.Errorf("invalid --helper-gomaxprocs value %d: must be at least 1", n)
+		}
+		runtime.GOMAXPROCS(n)
+	}
+	if s := context.GlobalString("helper-memory-limit"); s != "" {
+		limit, err := units.RAMInBytes(s)
+		if err != nil {
+			return fmt.Errorf("invalid --helper-memory-limit value %q: %w", s, err)
+		}
+		if limit <= 0 {
+			return fmt.Errorf("invalid --helper-memory-limit value %q", s)
+		}
+		debug.SetMemoryLimit(limit)
+	}
+	if context.GlobalIsSet("helper-nice") {
+		if err := libcontainer.SetHelperNice(context.GlobalInt("helper-nice")); err != nil {
+			return fmt.Errorf("unable to set the helper niceness: %w", err)
+		}
+	}
+	if context.GlobalIsSet("helper-max-parallel") {
+		n := context.GlobalInt("helper-max-parallel")
+		if n < 1 {
+			return fmt.Errorf("invalid --helper-max-parallel value %d: must be at least 1", n)
+		}
+		_ = libcontainer.SetMaxParallel(n)
+	}
+	return nil
+}
+
+// helperGOMAXPROCS returns the effective GOMAXPROCS of runc, for runc
+// features.
+func helperGOMAXPROCS() string {
+	return strconv.Itoa(runtime.GOMAXPROCS(0))
+}
+
+// helperMemoryLimit returns the effective soft memory limit of runc, in
+// bytes, or "max", for runc features.
+func helperMemoryLimit() string {
+	// A negative value only reads the limit.
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return "max"
+	}
+	return strconv.FormatInt(limit, 10)
+}
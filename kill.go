@@ -17,7 +17,10 @@ var killCommand = cli.Command{
 	ArgsUsage: `<container-id> [signal]
 
 Where "<container-id>" is the name for the instance of the container and
-"[signal]" is the signal to be sent to the init process.
+"[signal]" is the signal to be sent to the init process. If the signal is not
+specified, SIGTERM is used, unless the container runs systemd as its init (see
+the "org.opencontainers.runc.systemd-init" annotation), in which case the
+signal which makes systemd shut down (SIGRTMIN+3) is used.
 
 EXAMPLE:
 For example, if the container id is "ubuntu01" the following will send a "KILL"
@@ -30,6 +33,14 @@ signal to the init process of the "ubuntu01" container:
 			Usage:  "(obsoleted, do not use)",
 			Hidden: true,
 		},
+		cli.BoolFlag{
+			Name:  "all-exec",
+			Usage: "send the signal (default: SIGTERM) to the processes of all the exec sessions, rather than to the init process",
+		},
+		cli.StringFlag{
+			Name:  "exec-session",
+			Usage: "send the signal (default: SIGTERM) to the process of the exec session `id` (see runc ps --exec), rather than to the init process",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, minArgs); err != nil {
@@ -43,15 +54,27 @@ signal to the init process of the "ubuntu01" container:
 			return err
 		}
 
+		allExec, session := context.Bool("all-exec"), context.String("exec-session")
+		if allExec && session != "" {
+			return errors.New("--all-exec and --exec-session can not be used together")
+		}
+
 		sigstr := context.Args().Get(1)
 		if sigstr == "" {
-			sigstr = "SIGTERM"
+			sigstr = defaultStopSignal(container)
+			if allExec || session != "" {
+				sigstr = "SIGTERM"
+			}
 		}
 
 		signal, err := parseSignal(sigstr)
 		if err != nil {
 			return err
 		}
+		if allExec || session != "" {
+			_, err := container.SignalExecSessions(signal, session)
+			return err
+		}
 		err = container.Signal(signal)
 		if errors.Is(err, libcontainer.ErrNotRunning) && context.Bool("all") {
 			err = nil
@@ -60,6 +83,16 @@ signal to the init process of the "ubuntu01" container:
 	},
 }
 
+// defaultStopSignal returns the signal to be used to stop the container
+// when no signal is specified explicitly.
+func defaultStopSignal(container *libcontainer.Container) string {
+	if container.Config().SystemdInit {
+		// This is what systemd handles as a "halt" request.
+		return "SIGRTMIN+3"
+	}
+	return "SIGTERM"
+}
+
 func parseSignal(rawSignal string) (unix.Signal, error) {
 	s, err := strconv.Atoi(rawSignal)
 	if err == nil {
@@ -69,9 +102,46 @@ func parseSignal(rawSignal string) (unix.Signal, error) {
 	if !strings.HasPrefix(sig, "SIG") {
 		sig = "SIG" + sig
 	}
+	if rt, ok := parseRealtimeSignal(sig); ok {
+		return rt, nil
+	}
 	signal := unix.SignalNum(sig)
 	if signal == 0 {
 		return -1, fmt.Errorf("unknown signal %q", rawSignal)
 	}
 	return signal, nil
 }
+
+// parseRealtimeSignal parses SIGRTMIN+n and SIGRTMAX-n signal names, which
+// are not known to unix.SignalNum.
+func parseRealtimeSignal(sig string) (unix.Signal, bool) {
+	// These are the values seen by the userspace (glibc and musl reserve
+	// a few realtime signals for internal use).
+	const (
+		sigrtmin = 34
+		sigrtmax = 64
+	)
+	var (
+		base, n int
+		err     error
+	)
+	switch {
+	case sig == "SIGRTMIN":
+		return sigrtmin, true
+	case sig == "SIGRTMAX":
+		return sigrtmax, true
+	case strings.HasPrefix(sig, "SIGRTMIN+"):
+		base = sigrtmin
+		n, err = strconv.Atoi(strings.TrimPrefix(sig, "SIGRTMIN+"))
+	case strings.HasPrefix(sig, "SIGRTMAX-"):
+		base = sigrtmax
+		n, err = strconv.Atoi(strings.TrimPrefix(sig, "SIGRTMAX-"))
+		n = -n
+	default:
+		return 0, false
+	}
+	if err != nil || base+n < sigrtmin || base+n > sigrtmax {
+		return 0, false
+	}
+	return unix.Signal(base + n), true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+var labelCommand = cli.Command{
+	Name:  "label",
+	Usage: "set or remove metadata labels of a container",
+	ArgsUsage: `<container-id> <key>=<value>|<key>- ...
+
+Where "<container-id>" is the name for the instance of the container,
+"<key>=<value>" sets the label <key> to <value>, and "<key>-" removes
+the label <key>.`,
+	Description: `The label command updates the metadata labels of a container.
+
+Labels are arbitrary key/value pairs kept in the container state. Unlike
+annotations, which come from the bundle's config.json and can not be
+changed, labels can be set and removed at any time. They are shown by
+runc state and runc list, and can be used to filter the output of the
+latter (see runc list --label).
+
+EXAMPLE:
+
+       # runc label ubuntu01 owner=alice tenant-
+`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 2, minArgs); err != nil {
+			return err
+		}
+		update, err := parseLabels(context.Args().Tail())
+		if err != nil {
+			return err
+		}
+		lock, err := lockContainer(context, "label")
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		return container.SetMetadata(update)
+	},
+}
+
+// parseLabels parses the label arguments in the form of key=value (set the
+// label) or key- (remove the label). A removed label has an empty value in
+// the returned map.
+func parseLabels(args []string) (map[string]string, error) {
+	labels := make(map[string]string, len(args))
+	for _, arg := range args {
+		var key, value string
+		if k, v, ok := strings.Cut(arg, "="); ok {
+			if v == "" {
+				return nil, fmt.Errorf("invalid label %q: empty value (use %s- to remove the label)", arg, k)
+			}
+			key, value = k, v
+		} else if strings.HasSuffix(arg, "-") {
+			key = strings.TrimSuffix(arg, "-")
+		} else {
+			return nil, fmt.Errorf("invalid label %q: must be key=value or key-", arg)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid label %q: empty key", arg)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/opencontainers/runc/libcontainer/extcmd"
+	"github.com/sirupsen/logrus"
+)
+
+// lazyPagesLog is the name of the log of the criu lazy-pages daemon, in the
+// criu work directory.
+const lazyPagesLog = "lazy-pages.log"
+
+// startLazyPages starts the criu lazy-pages daemon, which fetches the memory
+// pages of a lazy restore from the page server at addr (as started by runc
+// checkpoint --lazy-pages --page-server on the source host), and waits for
+// it to be ready. The daemon serves the restore through a socket in workDir,
+// and exits once all the pages are transferred.
+func startLazyPages(addr, imagesDir, workDir string) (*extcmd.Cmd, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || port == "" {
+		return nil, errors.New("use --page-client ADDRESS:PORT to specify the page server")
+	}
+	if workDir == "" {
+		workDir = imagesDir
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	cmd := extcmd.Command("criu", "lazy-pages",
+		"--page-server", "--address", host, "--port", port,
+		"--images-dir", imagesDir, "--work-dir", workDir,
+		"--log-file", lazyPagesLog, "--status-fd", "3")
+	cmd.ExtraFiles = []*os.File{w}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// criu writes \0 to the status fd once it is ready, and closes it.
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		err = cmd.Wait()
+		if err == nil {
+			err = errors.New("exited before being ready")
+		}
+		return nil, fmt.Errorf("criu lazy-pages: %w (see %s)", err, filepath.Join(workDir, lazyPagesLog))
+	}
+	logrus.Debugf("criu lazy-pages (pid %d) fetching the pages from %s", cmd.Process.Pid, addr)
+	return cmd, nil
+}
+
+// stopLazyPages waits for the criu lazy-pages daemon to transfer all the
+// pages, or kills it if the restore failed.
+func stopLazyPages(cmd *extcmd.Cmd, restored bool) {
+	if !restored {
+		_ = cmd.Process.Kill()
+	}
+	if err := cmd.Wait(); err != nil && restored {
+		logrus.Warnf("criu lazy-pages: %v", err)
+	}
+}
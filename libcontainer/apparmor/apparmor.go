@@ -11,6 +11,10 @@ var (
 	// on other platforms.
 	ApplyProfile = applyProfile
 
+	// ProcessProfile returns the name of the profile confining the process
+	// with the given pid ("unconfined" if there is none).
+	ProcessProfile = processProfile
+
 	// ErrApparmorNotEnabled indicates that AppArmor is not enabled or not supported.
 	ErrApparmorNotEnabled = errors.New("apparmor: config provided but apparmor not supported")
 )
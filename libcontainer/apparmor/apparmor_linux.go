@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/opencontainers/runc/libcontainer/lsm"
 	"github.com/opencontainers/runc/libcontainer/utils"
 )
 
@@ -21,6 +24,10 @@ func isEnabled() bool {
 			buf, err := os.ReadFile("/sys/module/apparmor/parameters/enabled")
 			appArmorEnabled = err == nil && len(buf) > 1 && buf[0] == 'Y'
 		}
+		// The module may be enabled but not in the LSM stack.
+		if active, known := lsm.IsActive("apparmor"); known && !active {
+			appArmorEnabled = false
+		}
 	})
 	return appArmorEnabled
 }
@@ -31,6 +38,9 @@ func setProcAttr(attr, value string) error {
 	attrPath := "/proc/self/attr/apparmor/" + attr
 	if _, err := os.Stat(attrPath); errors.Is(err, os.ErrNotExist) {
 		// fall back to the old convention
+		if err := checkLegacyAttr(); err != nil {
+			return err
+		}
 		attrPath = "/proc/self/attr/" + attr
 	}
 
@@ -48,6 +58,16 @@ func setProcAttr(attr, value string) error {
 	return err
 }
 
+// checkLegacyAttr checks that the legacy process attribute files, used by
+// the kernels without /proc/<pid>/attr/apparmor, belong to AppArmor (and
+// not to another LSM of the stack, such as SELinux or Smack).
+func checkLegacyAttr() error {
+	if owner := lsm.AttrOwner(); owner != "" && owner != "apparmor" {
+		return fmt.Errorf("apparmor: the process attributes belong to %s, and the kernel has no AppArmor specific ones", owner)
+	}
+	return nil
+}
+
 // changeOnExec reimplements aa_change_onexec from libapparmor in Go
 func changeOnExec(name string) error {
 	if err := setProcAttr("exec", "exec "+name); err != nil {
@@ -66,3 +86,24 @@ func applyProfile(name string) error {
 
 	return changeOnExec(name)
 }
+
+func processProfile(pid int) (string, error) {
+	dir := "/proc/" + strconv.Itoa(pid) + "/attr/"
+	data, err := os.ReadFile(dir + "apparmor/current")
+	if errors.Is(err, os.ErrNotExist) {
+		// fall back to the old convention
+		if err := checkLegacyAttr(); err != nil {
+			return "", err
+		}
+		data, err = os.ReadFile(dir + "current")
+	}
+	if err != nil {
+		return "", err
+	}
+	// The profile mode, if any, follows the name, as in "name (enforce)".
+	label := strings.TrimSpace(strings.TrimRight(string(data), "\x00\n"))
+	if name, _, ok := strings.Cut(label, " ("); ok {
+		return name, nil
+	}
+	return label, nil
+}
@@ -13,3 +13,7 @@ func applyProfile(name string) error {
 	}
 	return nil
 }
+
+func processProfile(pid int) (string, error) {
+	return "", ErrApparmorNotEnabled
+}
@@ -0,0 +1,181 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+)
+
+// maxParallel is the default number of containers created at once by
+// CreateAll, or 0 for runtime.GOMAXPROCS(0). See SetMaxParallel.
+var maxParallel atomic.Int64
+
+// SetMaxParallel sets the number of containers created at once by CreateAll
+// (and by CreateAllLimit with a non-positive limit), which is
+// runtime.GOMAXPROCS(0) by default.
+func SetMaxParallel(n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid number of parallel creations %d: must be at least 1", n)
+	}
+	maxParallel.Store(int64(n))
+	return nil
+}
+
+// MaxParallel returns the number of containers created at once by CreateAll.
+func MaxParallel() int {
+	if n := maxParallel.Load(); n > 0 {
+		return int(n)
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// CreateRequest describes a single container to be created by CreateAll.
+type CreateRequest struct {
+	// ID is the container ID, see Create.
+	ID string
+	// Config is the container configuration.
+	Config *configs.Config
+	// Process, if set, is the container's init process, which is started
+	// right after the container is created (as by Container.Start).
+	Process *Process
+}
+
+// CreateResult is the result of a single container creation by CreateAll.
+type CreateResult struct {
+	// Container is the created container, or nil if Err is set.
+	Container *Container
+	// Err is the error which prevented the container from being created
+	// (or its init process from being started).
+	Err error
+}
+
+// CreateAll creates a number of containers concurrently, which is faster
+// than creating them one by one (for example, for a pod sandbox and its
+// application containers). The host features (cgroup version, whether
+// systemd is running, etc.) are probed once, and each distinct seccomp
+// profile is compiled once, and shared by all creations.
+//
+// The result for each request is returned at the same index as the request.
+// Failure to create one container does not affect the others; a container
+// which has been created but whose init process has failed to start is
+// destroyed.
+//
+// At most MaxParallel() containers are created at once; use SetMaxParallel
+// or CreateAllLimit to change that.
+func CreateAll(root string, reqs []CreateRequest) []CreateResult {
+	return CreateAllLimit(root, reqs, 0)
+}
+
+// CreateAllLimit is like CreateAll, but creates at most limit containers at
+// once, so that a burst of creations competes less with the running
+// workloads, for example on a small device. If limit is not positive,
+// MaxParallel() is used.
+func CreateAllLimit(root string, reqs []CreateRequest, limit int) []CreateResult {
+	results := make([]CreateResult, len(reqs))
+	if limit <= 0 {
+		limit = MaxParallel()
+	}
+
+	// Probe the host once before doing anything concurrently, so all
+	// the creations use cached results.
+	_ = cgroups.IsCgroup2UnifiedMode()
+	_ = systemd.IsRunningSystemd()
+
+	seen := make(map[string]bool, len(reqs))
+	todo := make([]int, 0, len(reqs))
+	for i := range reqs {
+		if seen[reqs[i].ID] {
+			results[i].Err = ErrExist
+			continue
+		}
+		seen[reqs[i].ID] = true
+		todo = append(todo, i)
+	}
+	progs := compileSeccomp(reqs, todo)
+
+	runLimited(todo, limit, func(i int) {
+		results[i].Container, results[i].Err = createAndStart(root, &reqs[i], progs[i])
+	})
+
+	return results
+}
+
+// runLimited calls f for each of the indexes in todo, running at most limit
+// calls at once, and returns once they are all done.
+func runLimited(todo []int, limit int, f func(i int)) {
+	if limit > len(todo) {
+		limit = len(todo)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < limit; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				f(i)
+			}
+		}()
+	}
+	for _, i := range todo {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// compileSeccomp compiles the seccomp profiles of the requests at the
+// indexes in todo, compiling each distinct profile once. The programs are
+// returned at the same indexes as the requests. A program is nil if the
+// request has no seccomp profile or it fails to compile, in which case
+// runc init compiles it (and reports the error, if any).
+func compileSeccomp(reqs []CreateRequest, todo []int) []*seccomp.Program {
+	progs := make([]*seccomp.Program, len(reqs))
+	cache := make(map[string]*seccomp.Program)
+	for _, i := range todo {
+		config := reqs[i].Config
+		if config == nil || config.Seccomp == nil {
+			continue
+		}
+		key, err := json.Marshal(config.Seccomp)
+		if err != nil {
+			continue
+		}
+		prog, ok := cache[string(key)]
+		if !ok {
+			prog, err = seccomp.Compile(config.Seccomp)
+			if err != nil {
+				logrus.Debugf("unable to precompile seccomp profile for %s: %v", reqs[i].ID, err)
+				prog = nil
+			}
+			cache[string(key)] = prog
+		}
+		progs[i] = prog
+	}
+	return progs
+}
+
+func createAndStart(root string, req *CreateRequest, prog *seccomp.Program) (*Container, error) {
+	c, err := Create(root, req.ID, req.Config)
+	if err != nil {
+		return nil, err
+	}
+	c.seccompProgram = prog
+	if req.Process == nil {
+		return c, nil
+	}
+	if err := c.Start(req.Process); err != nil {
+		_ = c.Destroy()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,123 @@
+package libcontainer
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+)
+
+func TestCreateAllPerRequestErrors(t *testing.T) {
+	root := t.TempDir()
+	config := &configs.Config{}
+	res := CreateAll(root, []CreateRequest{
+		{ID: "../bad", Config: config},
+		{ID: "dup", Config: config},
+		{ID: "dup", Config: config},
+	})
+	if len(res) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(res))
+	}
+	if !errors.Is(res[0].Err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID, got %v", res[0].Err)
+	}
+	// The first "dup" request fails on config validation,
+	// the second one is rejected as a duplicate.
+	if res[1].Err == nil || errors.Is(res[1].Err, ErrExist) {
+		t.Errorf("expected a validation error, got %v", res[1].Err)
+	}
+	if !errors.Is(res[2].Err, ErrExist) {
+		t.Errorf("expected ErrExist, got %v", res[2].Err)
+	}
+	for i, r := range res {
+		if r.Container != nil {
+			t.Errorf("result %d: unexpected container", i)
+		}
+	}
+}
+
+func TestRunLimited(t *testing.T) {
+	for _, limit := range []int{1, 3, 10} {
+		todo := []int{0, 1, 2, 3, 4, 5, 6, 7}
+		var (
+			running, peak atomic.Int32
+			done          [8]atomic.Bool
+		)
+		runLimited(todo, limit, func(i int) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			// Give the other workers a chance to run concurrently.
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			done[i].Store(true)
+		})
+		want := limit
+		if want > len(todo) {
+			want = len(todo)
+		}
+		if p := int(peak.Load()); p > want {
+			t.Errorf("limit %d: %d calls ran at once, want at most %d", limit, p, want)
+		}
+		for i := range done {
+			if !done[i].Load() {
+				t.Errorf("limit %d: index %d was not done", limit, i)
+			}
+		}
+	}
+}
+
+func TestMaxParallel(t *testing.T) {
+	defer maxParallel.Store(maxParallel.Load())
+
+	maxParallel.Store(0)
+	if n := MaxParallel(); n != runtime.GOMAXPROCS(0) {
+		t.Errorf("expected GOMAXPROCS by default, got %d", n)
+	}
+	if err := SetMaxParallel(0); err == nil {
+		t.Error("expected an error for 0")
+	}
+	if err := SetMaxParallel(2); err != nil {
+		t.Fatal(err)
+	}
+	if n := MaxParallel(); n != 2 {
+		t.Errorf("expected 2, got %d", n)
+	}
+}
+
+func TestCreateAllCompileSeccompOnce(t *testing.T) {
+	if !seccomp.Enabled {
+		t.Skip("seccomp support is not compiled in")
+	}
+	profile := func() *configs.Seccomp {
+		return &configs.Seccomp{
+			DefaultAction: configs.Allow,
+			Syscalls: []*configs.Syscall{
+				{Name: "kexec_load", Action: configs.Errno},
+			},
+		}
+	}
+	reqs := []CreateRequest{
+		{ID: "a", Config: &configs.Config{Seccomp: profile()}},
+		{ID: "b", Config: &configs.Config{Seccomp: profile()}},
+		{ID: "c", Config: &configs.Config{}},
+	}
+	progs := compileSeccomp(reqs, []int{0, 1, 2})
+	if progs[0] == nil {
+		t.Fatal("expected the profile to be compiled")
+	}
+	if progs[0] != progs[1] {
+		t.Error("expected identical profiles to share a program")
+	}
+	if progs[2] != nil {
+		t.Error("expected no program without a profile")
+	}
+}
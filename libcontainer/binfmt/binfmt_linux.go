@@ -0,0 +1,151 @@
+// Package binfmt handles the binfmt_misc entries (such as the emulators of
+// other architectures) a container relies upon.
+package binfmt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// Dir is where binfmt_misc is mounted.
+const Dir = "/proc/sys/fs/binfmt_misc"
+
+// Entry is a binfmt_misc registration.
+type Entry struct {
+	Name string
+	// Type is M (magic) or E (extension).
+	Type        string
+	Interpreter string
+	Flags       string
+}
+
+// ParseRegister parses a registration string
+// (":name:type:offset:magic:mask:interpreter:flags", where the first
+// character is the delimiter).
+func ParseRegister(s string) (*Entry, error) {
+	if s == "" {
+		return nil, errors.New("empty registration string")
+	}
+	fields := strings.Split(s[1:], s[:1])
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("invalid registration string %q: must have 7 fields", s)
+	}
+	e := &Entry{
+		Name:        fields[0],
+		Type:        fields[1],
+		Interpreter: fields[5],
+		Flags:       fields[6],
+	}
+	if err := CheckName(e.Name); err != nil {
+		return nil, fmt.Errorf("invalid registration string %q: %w", s, err)
+	}
+	if e.Type != "M" && e.Type != "E" {
+		return nil, fmt.Errorf("invalid registration string %q: type must be M or E", s)
+	}
+	if e.Interpreter == "" {
+		return nil, fmt.Errorf("invalid registration string %q: no interpreter", s)
+	}
+	if strings.Trim(e.Flags, "POCF") != "" {
+		return nil, fmt.Errorf("invalid registration string %q: unknown flags %q", s, e.Flags)
+	}
+	return e, nil
+}
+
+// CheckName checks the name of a binfmt_misc entry.
+func CheckName(name string) error {
+	switch name {
+	case "", ".", "..", "register", "status":
+		return fmt.Errorf("invalid entry name %q", name)
+	}
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("invalid entry name %q", name)
+	}
+	return nil
+}
+
+// CheckRequired checks that the entries with the given names are registered
+// in the binfmt_misc instance mounted at dir, and enabled. For the entries
+// without the F flag, whose interpreter is opened when a program is run,
+// the interpreter must also exist under rootfs.
+func CheckRequired(dir string, names []string, rootfs string) error {
+	for _, name := range names {
+		e, enabled, err := readEntry(filepath.Join(dir, name))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("required binfmt_misc entry %s is not registered", name)
+			}
+			return err
+		}
+		if !enabled {
+			return fmt.Errorf("required binfmt_misc entry %s is disabled", name)
+		}
+		if !strings.Contains(e.Flags, "F") {
+			path, err := securejoin.SecureJoin(rootfs, e.Interpreter)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("interpreter %s of the required binfmt_misc entry %s is not in the container (register it with the F flag, or add it to the rootfs): %w", e.Interpreter, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// readEntry reads a binfmt_misc entry file, such as:
+//
+//	enabled
+//	interpreter /usr/bin/qemu-aarch64-static
+//	flags: OCF
+//	offset 0
+//	magic 7f454c460201010000000000000000000200b700
+func readEntry(path string) (*Entry, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	e := &Entry{Name: filepath.Base(path)}
+	var enabled bool
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "enabled":
+			enabled = true
+		case strings.HasPrefix(line, "interpreter "):
+			e.Interpreter = strings.TrimPrefix(line, "interpreter ")
+		case strings.HasPrefix(line, "flags:"):
+			e.Flags = strings.TrimSpace(strings.TrimPrefix(line, "flags:"))
+		}
+	}
+	return e, enabled, sc.Err()
+}
+
+// Setup mounts a binfmt_misc instance private to the user namespace of the
+// calling process (which must be the container init, after pivot_root) at
+// Dir, registers the b.Register entries, and checks the b.Require ones.
+func Setup(b *configs.Binfmt) error {
+	if err := unix.Mount("binfmt_misc", Dir, "binfmt_misc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		err = &os.PathError{Op: "mount binfmt_misc", Path: Dir, Err: err}
+		if errors.Is(err, unix.EPERM) {
+			return fmt.Errorf("%w (private binfmt_misc instances require Linux 6.7 or later)", err)
+		}
+		return err
+	}
+	for _, r := range b.Register {
+		if err := os.WriteFile(filepath.Join(Dir, "register"), []byte(r), 0); err != nil {
+			return fmt.Errorf("unable to register binfmt_misc entry %q: %w", r, err)
+		}
+	}
+	return CheckRequired(Dir, b.Require, "/")
+}
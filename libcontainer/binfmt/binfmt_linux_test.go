@@ -0,0 +1,68 @@
+package binfmt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRegister(t *testing.T) {
+	e, err := ParseRegister(`,qemu-riscv64,M,,\x7fELF\x02\x01\x01,\xff\xff\xff\xff\xff\xff\xff,/usr/bin/qemu-riscv64,OCF`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "qemu-riscv64" || e.Type != "M" || e.Interpreter != "/usr/bin/qemu-riscv64" || e.Flags != "OCF" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	for _, r := range []string{
+		"",
+		":name:M:0:magic",
+		":name:X::magic::/bin/sh:",
+		"::E::ext::/bin/sh:",
+		":register:E::ext::/bin/sh:",
+		":a/b:E::ext::/bin/sh:",
+		":name:E::ext:::",
+		":name:E::ext::/bin/sh:Z",
+	} {
+		if _, err := ParseRegister(r); err == nil {
+			t.Errorf("%q: expected error, got nil", r)
+		}
+	}
+}
+
+func TestCheckRequired(t *testing.T) {
+	dir := t.TempDir()
+	rootfs := t.TempDir()
+	for name, content := range map[string]string{
+		"fixed":    "enabled\ninterpreter /usr/bin/qemu-fixed\nflags: OCF\noffset 0\nmagic 7f454c46\n",
+		"inrootfs": "enabled\ninterpreter /usr/bin/qemu-inrootfs\nflags: \nextension .x\n",
+		"missing":  "enabled\ninterpreter /usr/bin/qemu-missing\nflags: P\noffset 0\nmagic 7f454c46\n",
+		"disabled": "disabled\ninterpreter /usr/bin/qemu-fixed\nflags: F\noffset 0\nmagic 7f454c46\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(rootfs, "usr/bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rootfs, "usr/bin/qemu-inrootfs"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckRequired(dir, []string{"fixed", "inrootfs"}, rootfs); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for name, msg := range map[string]string{
+		"missing":      "is not in the container",
+		"disabled":     "is disabled",
+		"unregistered": "is not registered",
+	} {
+		err := CheckRequired(dir, []string{"fixed", name}, rootfs)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: expected error containing %q, got %v", name, msg, err)
+		}
+	}
+}
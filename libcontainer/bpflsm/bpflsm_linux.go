@@ -0,0 +1,110 @@
+// Package bpflsm attaches pinned BPF LSM programs to a container cgroup, so
+// that they only apply to the container processes.
+package bpflsm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+)
+
+// attachLSMCgroup is BPF_LSM_CGROUP, not in cilium/ebpf yet.
+const attachLSMCgroup = ebpf.AttachType(43)
+
+// Attach attaches the pinned programs at the given paths to the cgroup v2
+// directory cgroupPath. In case of an error, the programs already attached
+// are detached.
+func Attach(cgroupPath string, programs []string) error {
+	cg, err := openCgroup(cgroupPath)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(cg)
+
+	for i, path := range programs {
+		if err := attach(cg, path); err != nil {
+			if detachErr := detach(cg, programs[:i]); detachErr != nil {
+				return fmt.Errorf("%w (and unable to detach the attached programs: %v)", err, detachErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func attach(cg int, path string) error {
+	prog, err := ebpf.LoadPinnedProgram(path, nil)
+	if err != nil {
+		return fmt.Errorf("unable to load BPF LSM program %s: %w", path, err)
+	}
+	defer prog.Close()
+	if prog.Type() != ebpf.LSM {
+		return fmt.Errorf("unable to attach BPF LSM program %s: not a BPF LSM program (type %s)", path, prog.Type())
+	}
+	err = link.RawAttachProgram(link.RawAttachProgramOptions{
+		Target:  cg,
+		Program: prog,
+		Attach:  attachLSMCgroup,
+		Flags:   unix.BPF_F_ALLOW_MULTI,
+	})
+	if err != nil {
+		if errors.Is(err, unix.EINVAL) {
+			// Such as a program not loaded with the BPF_LSM_CGROUP
+			// expected attach type, or a kernel older than 6.0.
+			return fmt.Errorf("unable to attach BPF LSM program %s: %w (it must be loaded with the BPF_LSM_CGROUP attach type, which requires Linux 6.0 or later)", path, err)
+		}
+		return fmt.Errorf("unable to attach BPF LSM program %s: %w", path, err)
+	}
+	return nil
+}
+
+// Detach detaches the pinned programs at the given paths from the cgroup v2
+// directory cgroupPath. The programs which are not attached, or no longer
+// pinned, and a cgroup which no longer exists (the programs are detached
+// along with it), are not an error.
+func Detach(cgroupPath string, programs []string) error {
+	cg, err := openCgroup(cgroupPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer unix.Close(cg)
+	return detach(cg, programs)
+}
+
+func detach(cg int, programs []string) error {
+	var firstErr error
+	for _, path := range programs {
+		prog, err := ebpf.LoadPinnedProgram(path, nil)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) && firstErr == nil {
+				firstErr = fmt.Errorf("unable to load BPF LSM program %s: %w", path, err)
+			}
+			continue
+		}
+		err = link.RawDetachProgram(link.RawDetachProgramOptions{
+			Target:  cg,
+			Program: prog,
+			Attach:  attachLSMCgroup,
+		})
+		prog.Close()
+		if err != nil && !errors.Is(err, unix.ENOENT) && firstErr == nil {
+			firstErr = fmt.Errorf("unable to detach BPF LSM program %s: %w", path, err)
+		}
+	}
+	return firstErr
+}
+
+func openCgroup(path string) (int, error) {
+	fd, err := unix.Open(path, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return fd, nil
+}
@@ -75,6 +75,52 @@ func New(capConfig *configs.Capabilities) (*Caps, error) {
 	return &c, nil
 }
 
+// Current returns the capabilities of the process with the given pid (0 for
+// the current process), as capability names. Only the capabilities known to
+// both runc and the kernel are reported.
+func Current(pid int) (*configs.Capabilities, error) {
+	p, err := capability.NewPid2(pid)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Load(); err != nil {
+		return nil, err
+	}
+	names := func(which capability.CapType) []string {
+		out := []string{}
+		for _, c := range capability.List() {
+			if c <= capability.CAP_LAST_CAP && p.Get(which, c) {
+				out = append(out, "CAP_"+strings.ToUpper(c.String()))
+			}
+		}
+		return out
+	}
+	return &configs.Capabilities{
+		Bounding:    names(capability.BOUNDING),
+		Effective:   names(capability.EFFECTIVE),
+		Inheritable: names(capability.INHERITABLE),
+		Permitted:   names(capability.PERMITTED),
+		Ambient:     names(capability.AMBIENT),
+	}, nil
+}
+
+// Unavailable returns the capabilities of capConfig which are unknown, or
+// unavailable in the current environment, in sorted order. These are the
+// ones ignored by New.
+func Unavailable(capConfig *configs.Capabilities) []string {
+	unknownCaps := make(map[string]struct{})
+	for _, caps := range [][]string{
+		capConfig.Bounding,
+		capConfig.Effective,
+		capConfig.Inheritable,
+		capConfig.Permitted,
+		capConfig.Ambient,
+	} {
+		capSlice(caps, unknownCaps)
+	}
+	return mapKeys(unknownCaps)
+}
+
 // capSlice converts the slice of capability names in caps, to their numeric
 // equivalent, and returns them as a slice. Unknown or unavailable capabilities
 // are not returned, but appended to unknownCaps.
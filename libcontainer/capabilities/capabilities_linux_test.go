@@ -3,6 +3,7 @@ package capabilities
 import (
 	"io"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
@@ -69,3 +70,34 @@ func TestNew(t *testing.T) {
 
 	hook.Reset()
 }
+
+func TestCurrent(t *testing.T) {
+	caps, err := Current(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unless runc is run by a process with a reduced bounding set, which
+	// is unlikely for the tests, the bounding set has all the capabilities.
+	if len(caps.Bounding) == 0 {
+		t.Error("expected a non-empty bounding set")
+	}
+	for _, c := range caps.Bounding {
+		if _, ok := capabilityMap[c]; !ok {
+			t.Errorf("unknown capability %q in the bounding set", c)
+		}
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	caps := Unavailable(&configs.Capabilities{
+		Bounding:  []string{"CAP_CHOWN", "CAP_NET_ADMN"},
+		Effective: []string{"CAP_CHOWN", "CAP_NET_ADMN"},
+		Ambient:   []string{"cap_kill"},
+	})
+	if !reflect.DeepEqual(caps, []string{"CAP_NET_ADMN", "cap_kill"}) {
+		t.Errorf("unexpected unavailable capabilities: %v", caps)
+	}
+	if caps := Unavailable(&configs.Capabilities{Bounding: []string{"CAP_CHOWN"}}); caps != nil {
+		t.Errorf("expected no unavailable capabilities, got %v", caps)
+	}
+}
@@ -0,0 +1,107 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+// The methods below let the monitoring agents embedding libcontainer find
+// the container cgroups, rather than reconstructing their paths from
+// /proc/<pid>/cgroup. They are part of the stable libcontainer API (see
+// README.md), and work for any container loaded with Load, whatever the
+// cgroup driver and version.
+
+// CgroupPath returns the path of the container cgroup v2 directory, on a
+// cgroup v2 host (or in the unified hierarchy of a hybrid one), or "" if
+// there is none. The directory may not exist (yet, or any more).
+func (c *Container) CgroupPath() string {
+	return c.cgroupManager.Path("")
+}
+
+// CgroupPaths returns the paths of the container cgroup directories, by
+// controller (or by hierarchy name, such as "name=systemd") for cgroup v1,
+// the cgroup v2 directory having an empty key.
+func (c *Container) CgroupPaths() map[string]string {
+	return c.cgroupManager.GetPaths()
+}
+
+// CgroupControllers returns the availability of the cgroup controllers of
+// the host for the container: a controller is available if the resources
+// of the container can be set and read with it, that is, if it is enabled
+// in the container cgroup (cgroup v2, see cgroup2Controllers for the devices
+// and freezer ones), or if the container cgroup exists in its hierarchy
+// (cgroup v1). It fails if the container cgroup does not exist.
+func (c *Container) CgroupControllers() (map[string]bool, error) {
+	all, err := cgroups.GetAllSubsystems()
+	if err != nil {
+		return nil, err
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		path := c.CgroupPath()
+		data, err := cgroups.ReadFile(path, "cgroup.controllers")
+		if err != nil {
+			return nil, err
+		}
+		canFreeze := cgroups.PathExists(filepath.Join(path, "cgroup.freeze"))
+		return cgroup2Controllers(all, data, canFreeze), nil
+	}
+
+	available := make(map[string]bool, len(all))
+
+	paths := c.CgroupPaths()
+	exists := false
+	for _, name := range all {
+		p, ok := paths[name]
+		available[name] = ok && cgroups.PathExists(p)
+		exists = exists || available[name]
+	}
+	if !exists {
+		return nil, &os.PathError{Op: "stat", Path: "container cgroup", Err: os.ErrNotExist}
+	}
+	return available, nil
+}
+
+// cgroup2Controllers returns the availability of the controllers all in a
+// cgroup v2 directory, whose cgroup.controllers file has the contents
+// controllers. The devices and freezer "pseudo" controllers are not listed
+// there: devices (eBPF) is always available, and freezer is if canFreeze
+// (the cgroup has a cgroup.freeze file, since Linux 5.2).
+func cgroup2Controllers(all []string, controllers string, canFreeze bool) map[string]bool {
+	available := make(map[string]bool, len(all))
+	for _, name := range all {
+		available[name] = false
+	}
+	for _, name := range strings.Fields(controllers) {
+		available[name] = true
+	}
+	if _, ok := available["devices"]; ok {
+		available["devices"] = true
+	}
+	if _, ok := available["freezer"]; ok {
+		available["freezer"] = canFreeze
+	}
+	return available
+}
+
+// OpenCgroup opens the container cgroup v2 directory (see CgroupPath), for
+// reading its files relative to the returned directory: unlike a path, it
+// keeps referring to this cgroup once it is removed, rather than to one
+// later created with the same path. The error wraps os.ErrNotExist if the
+// cgroup does not exist. The caller must close the returned file.
+func (c *Container) OpenCgroup() (*os.File, error) {
+	path := c.CgroupPath()
+	if path == "" {
+		return nil, errors.New("no cgroup v2 directory")
+	}
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
@@ -0,0 +1,76 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCgroup2Controllers(t *testing.T) {
+	all := []string{"devices", "freezer", "cpu", "io", "memory", "pids"}
+	for _, tc := range []struct {
+		controllers string
+		canFreeze   bool
+		expected    map[string]bool
+	}{
+		{
+			controllers: "cpu memory pids\n",
+			canFreeze:   true,
+			expected:    map[string]bool{"devices": true, "freezer": true, "cpu": true, "io": false, "memory": true, "pids": true},
+		},
+		{
+			controllers: "",
+			canFreeze:   false,
+			expected:    map[string]bool{"devices": true, "freezer": false, "cpu": false, "io": false, "memory": false, "pids": false},
+		},
+	} {
+		if got := cgroup2Controllers(all, tc.controllers, tc.canFreeze); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%q (canFreeze %v): expected %v, got %v", tc.controllers, tc.canFreeze, tc.expected, got)
+		}
+	}
+}
+
+func TestOpenCgroup(t *testing.T) {
+	dir := t.TempDir()
+	container := &Container{
+		cgroupManager: &mockCgroupManager{paths: map[string]string{"": dir}},
+	}
+	if path := container.CgroupPath(); path != dir {
+		t.Fatalf("expected cgroup path %q, got %q", dir, path)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte("1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := container.OpenCgroup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// The directory is still readable through the opened file once it
+	// is replaced by another one.
+	if err := os.Rename(dir, dir+".old"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir + ".old")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "cgroup.procs" {
+		t.Fatalf("expected the old cgroup files, got %v", names)
+	}
+
+	container.cgroupManager = &mockCgroupManager{paths: map[string]string{"": filepath.Join(dir, "gone")}}
+	if _, err := container.OpenCgroup(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+	container.cgroupManager = &mockCgroupManager{}
+	if _, err := container.OpenCgroup(); err == nil {
+		t.Fatal("expected an error with no cgroup v2 directory")
+	}
+}
@@ -0,0 +1,69 @@
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// ResolveBlockIODevices sets the device numbers of the block IO devices of r
+// which are given by path (see configs.BlockIODevice.Path).
+func ResolveBlockIODevices(r *configs.Resources) error {
+	for _, wd := range r.BlkioWeightDevice {
+		if err := resolveBlockIODevice(&wd.BlockIODevice); err != nil {
+			return err
+		}
+	}
+	for _, list := range [][]*configs.ThrottleDevice{
+		r.BlkioThrottleReadBpsDevice,
+		r.BlkioThrottleWriteBpsDevice,
+		r.BlkioThrottleReadIOPSDevice,
+		r.BlkioThrottleWriteIOPSDevice,
+	} {
+		for _, td := range list {
+			if err := resolveBlockIODevice(&td.BlockIODevice); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resolveBlockIODevice(d *configs.BlockIODevice) error {
+	if d.Path == "" {
+		return nil
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(d.Path, &st); err != nil {
+		return &os.PathError{Op: "stat", Path: d.Path, Err: err}
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return fmt.Errorf("%s is not a block device", d.Path)
+	}
+	devNumber := uint64(st.Rdev) //nolint:unconvert // Rdev is uint32 on e.g. MIPS.
+	d.Major = int64(unix.Major(devNumber))
+	d.Minor = int64(unix.Minor(devNumber))
+	return nil
+}
+
+// BlockDeviceName returns the kernel name (such as nvme0n1) of the block
+// device major:minor, or an empty string if it is not known.
+func BlockDeviceName(major, minor uint64) string {
+	f, err := os.Open(fmt.Sprintf("/sys/dev/block/%d:%d/uevent", major, minor))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if name, ok := strings.CutPrefix(sc.Text(), "DEVNAME="); ok {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package cgroups
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestResolveBlockIODevices(t *testing.T) {
+	// Find a block device node, if any.
+	var (
+		path         string
+		major, minor int64
+	)
+	if entries, err := os.ReadDir("/sys/dev/block"); err == nil {
+		for _, e := range entries {
+			var mj, mn int64
+			if _, err := fmt.Sscanf(e.Name(), "%d:%d", &mj, &mn); err != nil {
+				continue
+			}
+			n := BlockDeviceName(uint64(mj), uint64(mn))
+			if n == "" {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join("/dev", n)); err == nil {
+				path, major, minor = filepath.Join("/dev", n), mj, mn
+				break
+			}
+		}
+	}
+
+	r := &configs.Resources{
+		BlkioWeightDevice: []*configs.WeightDevice{configs.NewWeightDevice(8, 0, 100, 0)},
+	}
+	if path != "" {
+		td := configs.NewThrottleDevice(0, 0, 1024)
+		td.Path = path
+		r.BlkioThrottleReadBpsDevice = []*configs.ThrottleDevice{td}
+	}
+	if err := ResolveBlockIODevices(r); err != nil {
+		t.Fatal(err)
+	}
+	// The devices given by numbers are left as is.
+	if wd := r.BlkioWeightDevice[0]; wd.Major != 8 || wd.Minor != 0 {
+		t.Errorf("unexpected weight device: %+v", wd)
+	}
+	if path != "" {
+		if td := r.BlkioThrottleReadBpsDevice[0]; td.Major != major || td.Minor != minor {
+			t.Errorf("%s: expected %d:%d, got %d:%d", path, major, minor, td.Major, td.Minor)
+		}
+	}
+
+	for _, p := range []string{"/dev/null", "/nonexistent"} {
+		wd := configs.NewWeightDevice(0, 0, 100, 0)
+		wd.Path = p
+		r := &configs.Resources{BlkioWeightDevice: []*configs.WeightDevice{wd}}
+		if err := ResolveBlockIODevices(r); err == nil {
+			t.Errorf("%s: expected error, got nil", p)
+		}
+	}
+}
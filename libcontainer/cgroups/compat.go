@@ -0,0 +1,79 @@
+package cgroups
+
+import (
+	"path/filepath"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system/kernelversion"
+)
+
+// V2Feature is a cgroup v2 only feature, which may be unavailable on a
+// cgroup v1 host (or with an older kernel).
+type V2Feature struct {
+	// Name is the name of the feature, such as "psi".
+	Name string `json:"name"`
+	// Fallback is what is done instead when the feature is unavailable,
+	// or "" if nothing is.
+	Fallback string `json:"fallback,omitempty"`
+
+	// requested tells whether the feature is requested by resources
+	// (nil if it is always used).
+	requested func(r *configs.Resources) bool
+	available func() bool
+}
+
+// v2Features are the cgroup v2 only features used by runc.
+var v2Features = []V2Feature{
+	{
+		// Used by runc state, events and top, which then report no
+		// pressure metrics.
+		Name:      "psi",
+		available: func() bool { return IsCgroup2UnifiedMode() && PathExists("/proc/pressure/cpu") },
+	},
+	{
+		Name:      "memory.low",
+		Fallback:  "memory.soft_limit_in_bytes, only enforced under global memory pressure",
+		requested: func(r *configs.Resources) bool { return r.MemoryReservation != 0 },
+		available: func() bool { return IsCgroup2UnifiedMode() },
+	},
+	{
+		// The io.weight file, enforced by the I/O cost model controller
+		// (io.cost.qos) whatever the I/O scheduler, which runc writes the
+		// (converted) weight to unless io.bfq.weight is there. The
+		// weights by device are only set with BFQ.
+		Name:      "io.weight",
+		Fallback:  "the BFQ (or, on cgroup v1, CFQ) I/O scheduler weight, only enforced on the devices using it",
+		requested: func(r *configs.Resources) bool { return r.BlkioWeight != 0 },
+		available: func() bool {
+			return IsCgroup2UnifiedMode() && PathExists(filepath.Join(unifiedMountpoint, "io.cost.qos"))
+		},
+	},
+	{
+		// Used to kill all the container processes.
+		Name:     "cgroup.kill",
+		Fallback: "the container is frozen, and its processes are killed one by one",
+		available: func() bool {
+			if !IsCgroup2UnifiedMode() && !IsCgroup2HybridMode() {
+				return false
+			}
+			ok, _ := kernelversion.GreaterEqualThan(kernelversion.KernelVersion{Kernel: 5, Major: 14})
+			return ok
+		},
+	},
+}
+
+// UnavailableV2Features returns the cgroup v2 only features which are not
+// available on the host, with the fallback used instead. If r is not nil,
+// only the features requested by r (or always used) are returned.
+func UnavailableV2Features(r *configs.Resources) []V2Feature {
+	var unavailable []V2Feature
+	for _, f := range v2Features {
+		if r != nil && f.requested != nil && !f.requested(r) {
+			continue
+		}
+		if !f.available() {
+			unavailable = append(unavailable, f)
+		}
+	}
+	return unavailable
+}
@@ -0,0 +1,34 @@
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestUnavailableV2Features(t *testing.T) {
+	names := func(r *configs.Resources) map[string]bool {
+		m := make(map[string]bool)
+		for _, f := range UnavailableV2Features(r) {
+			m[f.Name] = true
+		}
+		return m
+	}
+
+	host := names(nil)
+	if !IsCgroup2UnifiedMode() && !host["memory.low"] {
+		t.Errorf("expected memory.low to be unavailable on a cgroup v1 host, got %v", host)
+	}
+
+	// The features a container does not request are left out.
+	unrequested := names(&configs.Resources{})
+	if unrequested["memory.low"] || unrequested["io.weight"] {
+		t.Errorf("expected only the features used by runc, got %v", unrequested)
+	}
+	requested := names(&configs.Resources{MemoryReservation: 1 << 20, BlkioWeight: 500})
+	for name := range host {
+		if !requested[name] {
+			t.Errorf("expected %s to be reported as unavailable, got %v", name, requested)
+		}
+	}
+}
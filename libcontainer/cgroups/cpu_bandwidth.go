@@ -0,0 +1,58 @@
+package cgroups
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// The CFS bandwidth control bounds, in microseconds, as enforced by the
+// kernel for both cgroup v1 (cpu.cfs_period_us and cpu.cfs_quota_us) and
+// cgroup v2 (cpu.max).
+const (
+	CPUPeriodMin = 1000    // 1ms
+	CPUPeriodMax = 1000000 // 1s
+	CPUQuotaMin  = 1000    // 1ms
+
+	// CPUPeriodDefault is the kernel default period.
+	CPUPeriodDefault = 100000
+
+	// CPUPeriodShort is the period below which the container is likely to
+	// be throttled (and unthrottled) very often, with a significant
+	// scheduling overhead.
+	CPUPeriodShort = 10000
+)
+
+// CheckCPUBandwidth checks the CFS period, quota and burst of r against the
+// kernel bounds. A zero period or quota means it is not set, and a negative
+// quota means no limit.
+func CheckCPUBandwidth(r *configs.Resources) error {
+	if p := r.CpuPeriod; p != 0 && (p < CPUPeriodMin || p > CPUPeriodMax) {
+		return fmt.Errorf("invalid cpu period %d: must be between %d and %d (in microseconds)", p, CPUPeriodMin, CPUPeriodMax)
+	}
+	if q := r.CpuQuota; q > 0 && q < CPUQuotaMin {
+		return fmt.Errorf("invalid cpu quota %d: must be at least %d (in microseconds)", q, CPUQuotaMin)
+	}
+	if b := r.CpuBurst; b != nil && *b != 0 && r.CpuQuota > 0 && *b > uint64(r.CpuQuota) {
+		return fmt.Errorf("invalid cpu burst %d: must not be greater than the cpu quota %d", *b, r.CpuQuota)
+	}
+	return nil
+}
+
+// RescaleCPUQuota returns the CFS quota to use with newPeriod for the CPU
+// limit (the quota to period ratio) to stay the same as with quota and
+// oldPeriod. A zero period means the default one. A zero or negative quota
+// (i.e. no limit) is returned as is.
+func RescaleCPUQuota(quota int64, oldPeriod, newPeriod uint64) int64 {
+	if quota <= 0 {
+		return quota
+	}
+	if oldPeriod == 0 {
+		oldPeriod = CPUPeriodDefault
+	}
+	if newPeriod == 0 {
+		newPeriod = CPUPeriodDefault
+	}
+	return int64(math.Round(float64(quota) * float64(newPeriod) / float64(oldPeriod)))
+}
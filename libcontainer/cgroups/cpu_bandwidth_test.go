@@ -0,0 +1,51 @@
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestCheckCPUBandwidth(t *testing.T) {
+	burst := func(b uint64) *uint64 { return &b }
+	for _, tc := range []struct {
+		r     configs.Resources
+		isErr bool
+	}{
+		{r: configs.Resources{}},
+		{r: configs.Resources{CpuPeriod: 100000, CpuQuota: 50000}},
+		{r: configs.Resources{CpuPeriod: 1000, CpuQuota: -1}},
+		{r: configs.Resources{CpuPeriod: 1000000, CpuQuota: 1000, CpuBurst: burst(1000)}},
+		{r: configs.Resources{CpuQuota: 200000, CpuBurst: burst(0)}},
+		{r: configs.Resources{CpuPeriod: 999}, isErr: true},
+		{r: configs.Resources{CpuPeriod: 1000001}, isErr: true},
+		{r: configs.Resources{CpuQuota: 999}, isErr: true},
+		{r: configs.Resources{CpuQuota: 50000, CpuBurst: burst(50001)}, isErr: true},
+	} {
+		err := CheckCPUBandwidth(&tc.r)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.r)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tc.r, err)
+		}
+	}
+}
+
+func TestRescaleCPUQuota(t *testing.T) {
+	for _, tc := range []struct {
+		quota                int64
+		oldPeriod, newPeriod uint64
+		expected             int64
+	}{
+		{quota: 50000, oldPeriod: 100000, newPeriod: 10000, expected: 5000},
+		{quota: 50000, oldPeriod: 0, newPeriod: 200000, expected: 100000},
+		{quota: 150000, oldPeriod: 100000, newPeriod: 0, expected: 150000},
+		{quota: 33333, oldPeriod: 100000, newPeriod: 30000, expected: 10000},
+		{quota: -1, oldPeriod: 100000, newPeriod: 10000, expected: -1},
+		{quota: 0, oldPeriod: 100000, newPeriod: 10000, expected: 0},
+	} {
+		if q := RescaleCPUQuota(tc.quota, tc.oldPeriod, tc.newPeriod); q != tc.expected {
+			t.Errorf("quota %d, period %d -> %d: expected %d, got %d", tc.quota, tc.oldPeriod, tc.newPeriod, tc.expected, q)
+		}
+	}
+}
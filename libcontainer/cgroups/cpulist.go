@@ -0,0 +1,97 @@
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// onlineCPUsFile can be changed by unit tests.
+var onlineCPUsFile = "/sys/devices/system/cpu/online"
+
+// ParseCPUList parses a list of CPUs (or memory nodes) in the format used by
+// cpuset.cpus and cpuset.mems (e.g. "0-3,7"), see cpuset(7). The returned
+// list is sorted and has no duplicates.
+func ParseCPUList(list string) ([]uint16, error) {
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return nil, nil
+	}
+	seen := make(map[uint16]struct{})
+	for _, r := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(r, "-")
+		min, err := strconv.ParseUint(first, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %w", list, err)
+		}
+		max := min
+		if isRange {
+			if max, err = strconv.ParseUint(last, 10, 16); err != nil {
+				return nil, fmt.Errorf("invalid cpu list %q: %w", list, err)
+			}
+			if min > max {
+				return nil, fmt.Errorf("invalid cpu list %q: %w", list, errors.New("invalid range"))
+			}
+		}
+		for i := min; i <= max; i++ {
+			seen[uint16(i)] = struct{}{}
+		}
+	}
+	cpus := make([]uint16, 0, len(seen))
+	for c := range seen {
+		cpus = append(cpus, c)
+	}
+	sort.Slice(cpus, func(i, j int) bool { return cpus[i] < cpus[j] })
+	return cpus, nil
+}
+
+// FormatCPUList is the opposite of ParseCPUList. The cpus must be sorted.
+func FormatCPUList(cpus []uint16) string {
+	var b strings.Builder
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(int(cpus[i])))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(int(cpus[j])))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// OnlineCPUs returns the list of the online CPUs.
+func OnlineCPUs() ([]uint16, error) {
+	data, err := os.ReadFile(onlineCPUsFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCPUList(string(data))
+}
+
+// IntersectCPUList returns the CPUs present in both (sorted) lists.
+func IntersectCPUList(a, b []uint16) []uint16 {
+	var res []uint16
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+	return res
+}
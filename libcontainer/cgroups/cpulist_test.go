@@ -0,0 +1,78 @@
+package cgroups
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		cpus []uint16
+		fail bool
+	}{
+		{in: "", cpus: nil},
+		{in: "0\n", cpus: []uint16{0}},
+		{in: "0-3,7", cpus: []uint16{0, 1, 2, 3, 7}},
+		{in: "7,2-3,3", cpus: []uint16{2, 3, 7}},
+		{in: "3-1", fail: true},
+		{in: "1-2-3", fail: true},
+		{in: "a", fail: true},
+		{in: "1,", fail: true},
+		{in: "70000", fail: true},
+	} {
+		cpus, err := ParseCPUList(tc.in)
+		if tc.fail {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tc.in, cpus)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if !reflect.DeepEqual(cpus, tc.cpus) {
+			t.Errorf("%q: expected %v, got %v", tc.in, tc.cpus, cpus)
+		}
+	}
+}
+
+func TestFormatCPUList(t *testing.T) {
+	for _, tc := range []struct {
+		cpus []uint16
+		out  string
+	}{
+		{nil, ""},
+		{[]uint16{5}, "5"},
+		{[]uint16{0, 1, 2, 3, 7}, "0-3,7"},
+		{[]uint16{0, 2, 4, 5}, "0,2,4-5"},
+	} {
+		if out := FormatCPUList(tc.cpus); out != tc.out {
+			t.Errorf("%v: expected %q, got %q", tc.cpus, tc.out, out)
+		}
+	}
+}
+
+func TestIntersectCPUList(t *testing.T) {
+	res := IntersectCPUList([]uint16{0, 1, 2, 5, 8}, []uint16{1, 2, 3, 8, 9})
+	if !reflect.DeepEqual(res, []uint16{1, 2, 8}) {
+		t.Errorf("unexpected intersection %v", res)
+	}
+}
+
+func TestOnlineCPUs(t *testing.T) {
+	old := onlineCPUsFile
+	defer func() { onlineCPUsFile = old }()
+	onlineCPUsFile = filepath.Join(t.TempDir(), "online")
+	if err := os.WriteFile(onlineCPUsFile, []byte("0-1,4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cpus, err := OnlineCPUs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cpus, []uint16{0, 1, 4}) {
+		t.Errorf("unexpected online cpus %v", cpus)
+	}
+}
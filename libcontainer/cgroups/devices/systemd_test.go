@@ -8,11 +8,11 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/opencontainers/runc/internal/testutil"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/opencontainers/runc/libcontainer/internal/testutil"
 )
 
 // TestPodSkipDevicesUpdate checks that updating a pod having SkipDevices: true
@@ -114,6 +114,9 @@ func openFile(dir, file string, flags int) (*os.File, error) {
 		mode = 0o600
 	}
 	path := path.Join(dir, utils.CleanPath(file))
+	if dirFd, relPath, ok := pinnedDir(path); ok {
+		return openPinned(dirFd, relPath, path, flags, mode)
+	}
 	if prepareOpenat2() != nil {
 		return openFallback(path, flags, mode)
 	}
@@ -151,6 +154,61 @@ func openFile(dir, file string, flags int) (*os.File, error) {
 	return os.NewFile(uintptr(fd), path), nil
 }
 
+var (
+	pinnedMu sync.RWMutex
+	// pinned maps cgroup directories to their file descriptors.
+	pinned map[string]int
+)
+
+// PinDir makes the file operations in this package on the cgroup v2
+// directory dir (and its subdirectories) use the directory file descriptor
+// fd rather than the path. This way, they are not affected by the directory
+// being moved or replaced, and work even if dir is not visible in the
+// current mount namespace. The fd must be kept open while dir is used.
+func PinDir(dir string, fd int) {
+	pinnedMu.Lock()
+	defer pinnedMu.Unlock()
+	if pinned == nil {
+		pinned = make(map[string]int)
+	}
+	pinned[path.Clean(dir)] = fd
+}
+
+// IsPinned returns whether dir was pinned using PinDir.
+func IsPinned(dir string) bool {
+	_, _, ok := pinnedDir(dir)
+	return ok
+}
+
+// pinnedDir returns the file descriptor of the pinned directory p is in,
+// and the path of p relative to it.
+func pinnedDir(p string) (int, string, bool) {
+	pinnedMu.RLock()
+	defer pinnedMu.RUnlock()
+	for dir, fd := range pinned {
+		if p == dir {
+			return fd, ".", true
+		}
+		if rel := strings.TrimPrefix(p, dir+"/"); len(rel) != len(p) {
+			return fd, rel, true
+		}
+	}
+	return -1, "", false
+}
+
+func openPinned(dirFd int, relPath, path string, flags int, mode os.FileMode) (*os.File, error) {
+	fd, err := unix.Openat2(dirFd, relPath,
+		&unix.OpenHow{
+			Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS | unix.RESOLVE_NO_XDEV | unix.RESOLVE_NO_SYMLINKS,
+			Flags:   uint64(flags) | unix.O_CLOEXEC,
+			Mode:    uint64(mode),
+		})
+	if err != nil {
+		return nil, &os.PathError{Op: "openat2", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
 var errNotCgroupfs = errors.New("not a cgroup file")
 
 // Can be changed by unit tests.
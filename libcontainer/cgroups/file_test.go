@@ -9,7 +9,9 @@ import (
 	"testing"
 	"time"
 
-	"github.com/opencontainers/runc/internal/testutil"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/internal/testutil"
 )
 
 func TestWriteCgroupFileHandlesInterrupt(t *testing.T) {
@@ -73,3 +75,47 @@ func TestOpenat2(t *testing.T) {
 		fd.Close()
 	}
 }
+
+func TestPinDir(t *testing.T) {
+	TestMode = true
+	defer func() { TestMode = false }()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := unix.Open(dir, unix.O_DIRECTORY|unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fd)
+
+	// A path which does not exist, resolved using the fd.
+	const pinnedPath = "/sys/fs/cgroup/pinned-test"
+	PinDir(pinnedPath, fd)
+	defer func() {
+		pinnedMu.Lock()
+		delete(pinned, pinnedPath)
+		pinnedMu.Unlock()
+	}()
+
+	if !IsPinned(pinnedPath+"/sub") || IsPinned(pinnedPath+"-other") {
+		t.Fatal("unexpected IsPinned result")
+	}
+	if !PathExists(pinnedPath) || !PathExists(pinnedPath+"/sub") || PathExists(pinnedPath+"/missing") {
+		t.Fatal("unexpected PathExists result")
+	}
+	if err := WriteFile(pinnedPath+"/sub", "pids.max", "10"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "sub", "pids.max"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "10" {
+		t.Fatalf("expected 10, got %q", data)
+	}
+	if val, err := ReadFile(pinnedPath, "sub/pids.max"); err != nil || val != "10" {
+		t.Fatalf("expected 10, got %q (err: %v)", val, err)
+	}
+}
@@ -25,6 +25,9 @@ func (s *BlkioGroup) Apply(path string, _ *configs.Resources, pid int) error {
 }
 
 func (s *BlkioGroup) Set(path string, r *configs.Resources) error {
+	if err := cgroups.ResolveBlockIODevices(r); err != nil {
+		return err
+	}
 	s.detectWeightFilenames(path)
 	if r.BlkioWeight != 0 {
 		if err := cgroups.WriteFile(path, s.weightFilename, strconv.FormatUint(uint64(r.BlkioWeight), 10)); err != nil {
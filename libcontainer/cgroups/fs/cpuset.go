@@ -138,6 +138,17 @@ func (s *CpusetGroup) GetStats(path string, stats *cgroups.Stats) error {
 		return err
 	}
 
+	// Since Linux 3.16.
+	stats.CPUSetStats.EffectiveCPUs, err = getCpusetStat(path, "cpuset.effective_cpus")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	stats.CPUSetStats.EffectiveMems, err = getCpusetStat(path, "cpuset.effective_mems")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	return nil
 }
 
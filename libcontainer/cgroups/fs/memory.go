@@ -109,6 +109,17 @@ func setMemoryAndSwap(path string, r *configs.Resources) error {
 }
 
 func (s *MemoryGroup) Set(path string, r *configs.Resources) error {
+	// If the memory limit is not set (i.e. not changed by runc update),
+	// neither is the memory+swap limit.
+	if r.SwapMax != nil && r.Memory != 0 {
+		memorySwap, err := cgroups.ConvertSwapMaxToCgroupV1Value(*r.SwapMax, r.Memory)
+		if err != nil {
+			return err
+		}
+		converted := *r
+		converted.MemorySwap = memorySwap
+		r = &converted
+	}
 	if err := setMemoryAndSwap(path, r); err != nil {
 		return err
 	}
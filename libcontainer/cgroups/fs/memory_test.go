@@ -108,6 +108,40 @@ func TestMemorySetMemoryswap(t *testing.T) {
 	}
 }
 
+func TestMemorySetSwapMax(t *testing.T) {
+	path := tempDir(t, "memory")
+
+	const (
+		memory  = 314572800 // 300M
+		swapMax = 209715200 // 200M
+	)
+
+	writeFileContents(t, path, map[string]string{
+		"memory.limit_in_bytes":       "0",
+		"memory.memsw.limit_in_bytes": "0",
+	})
+
+	swap := int64(swapMax)
+	r := &configs.Resources{
+		Memory:  memory,
+		SwapMax: &swap,
+	}
+	if err := (&MemoryGroup{}).Set(path, r); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := fscommon.GetCgroupParamUint(path, "memory.memsw.limit_in_bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != memory+swapMax {
+		t.Fatalf("expected memory.memsw.limit_in_bytes %d, got %d", memory+swapMax, value)
+	}
+	if r.MemorySwap != 0 {
+		t.Fatal("resources should not be modified")
+	}
+}
+
 func TestMemorySetMemoryLargerThanSwap(t *testing.T) {
 	path := tempDir(t, "memory")
 
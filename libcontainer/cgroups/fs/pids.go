@@ -1,7 +1,9 @@
 package fs
 
 import (
+	"errors"
 	"math"
+	"os"
 	"strconv"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -56,7 +58,15 @@ func (s *PidsGroup) GetStats(path string, stats *cgroups.Stats) error {
 		max = 0
 	}
 
+	// pids.events is available since Linux 4.3 (cgroup v1) and
+	// Linux 4.5 (cgroup v2).
+	events, err := fscommon.GetValueByKey(path, "pids.events", "max")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	stats.PidsStats.Current = current
 	stats.PidsStats.Limit = max
+	stats.PidsStats.MaxEvents = events
 	return nil
 }
@@ -85,6 +85,26 @@ func TestPidsStats(t *testing.T) {
 	}
 }
 
+func TestPidsStatsMaxEvents(t *testing.T) {
+	path := tempDir(t, "pids")
+
+	writeFileContents(t, path, map[string]string{
+		"pids.current": strconv.Itoa(maxLimited),
+		"pids.max":     strconv.Itoa(maxLimited),
+		"pids.events":  "max 42\n",
+	})
+
+	pids := &PidsGroup{}
+	stats := *cgroups.NewStats()
+	if err := pids.GetStats(path, &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.PidsStats.MaxEvents != 42 {
+		t.Fatalf("Expected %d, got %d for pids.events", 42, stats.PidsStats.MaxEvents)
+	}
+}
+
 func TestPidsStatsUnlimited(t *testing.T) {
 	path := tempDir(t, "pids")
 
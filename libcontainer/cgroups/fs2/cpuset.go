@@ -1,6 +1,9 @@
 package fs2
 
 import (
+	"errors"
+	"os"
+
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
 )
@@ -26,3 +29,27 @@ func setCpuset(dirPath string, r *configs.Resources) error {
 	}
 	return nil
 }
+
+func statCpuset(dirPath string, stats *cgroups.Stats) error {
+	for _, f := range []struct {
+		name string
+		dst  *[]uint16
+	}{
+		{"cpuset.cpus", &stats.CPUSetStats.CPUs},
+		{"cpuset.cpus.effective", &stats.CPUSetStats.EffectiveCPUs},
+		{"cpuset.mems", &stats.CPUSetStats.Mems},
+		{"cpuset.mems.effective", &stats.CPUSetStats.EffectiveMems},
+	} {
+		data, err := cgroups.ReadFile(dirPath, f.name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if *f.dst, err = cgroups.ParseCPUList(data); err != nil {
+			return &parseError{Path: dirPath, File: f.name, Err: err}
+		}
+	}
+	return nil
+}
@@ -65,6 +65,11 @@ func (m *Manager) getControllers() error {
 }
 
 func (m *Manager) Apply(pid int) error {
+	// A cgroup given by its directory fd already exists (and may not be
+	// accessible by path).
+	if cgroups.IsPinned(m.dirPath) {
+		return cgroups.WriteCgroupProc(m.dirPath, pid)
+	}
 	if err := CreateCgroupPath(m.dirPath, m.config); err != nil {
 		// Related tests:
 		// - "runc create (no limits + no cgrouppath + no permission) succeeds"
@@ -116,6 +121,10 @@ func (m *Manager) GetStats() (*cgroups.Stats, error) {
 	if err := statCpu(m.dirPath, st); err != nil && !os.IsNotExist(err) {
 		errs = append(errs, err)
 	}
+	// cpuset (since kernel 5.0)
+	if err := statCpuset(m.dirPath, st); err != nil {
+		errs = append(errs, err)
+	}
 	// PSI (since kernel 4.20).
 	var err error
 	if st.CpuStats.PSI, err = statPSI(m.dirPath, "cpu.pressure"); err != nil {
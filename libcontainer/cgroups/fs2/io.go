@@ -41,6 +41,9 @@ func setIo(dirPath string, r *configs.Resources) error {
 	if !isIoSet(r) {
 		return nil
 	}
+	if err := cgroups.ResolveBlockIODevices(r); err != nil {
+		return err
+	}
 
 	// If BFQ IO scheduler is available, use it.
 	var bfq *os.File
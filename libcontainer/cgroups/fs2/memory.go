@@ -33,7 +33,7 @@ func numToStr(value int64) (ret string) {
 }
 
 func isMemorySet(r *configs.Resources) bool {
-	return r.MemoryReservation != 0 || r.Memory != 0 || r.MemorySwap != 0
+	return r.MemoryReservation != 0 || r.Memory != 0 || r.MemorySwap != 0 || r.SwapMax != nil
 }
 
 func setMemory(dirPath string, r *configs.Resources) error {
@@ -45,13 +45,14 @@ func setMemory(dirPath string, r *configs.Resources) error {
 		return err
 	}
 
-	swap, err := cgroups.ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory)
+	swap, err := cgroups.SwapLimitV2(r.MemorySwap, r.Memory, r.SwapMax)
 	if err != nil {
 		return err
 	}
 	swapStr := numToStr(swap)
-	if swapStr == "" && swap == 0 && r.MemorySwap > 0 {
-		// memory and memorySwap set to the same value -- disable swap
+	if swapStr == "" && swap == 0 && (r.MemorySwap > 0 || r.SwapMax != nil) {
+		// memory and memorySwap set to the same value, or swap limit
+		// explicitly set to 0 -- disable swap
 		swapStr = "0"
 	}
 	// never write empty string to `memory.swap.max`, it means set to 0.
@@ -66,7 +66,15 @@ func statPids(dirPath string, stats *cgroups.Stats) error {
 		max = 0
 	}
 
+	// pids.events is available since Linux 4.3 (cgroup v1) and
+	// Linux 4.5 (cgroup v2).
+	events, err := fscommon.GetValueByKey(dirPath, "pids.events", "max")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	stats.PidsStats.Current = current
 	stats.PidsStats.Limit = max
+	stats.PidsStats.MaxEvents = events
 	return nil
 }
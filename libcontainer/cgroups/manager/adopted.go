@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// adoptedManager is a cgroup manager for a cgroup owned by an external
+// manager (see configs.Cgroup.Adopted). It never creates, configures or
+// removes the cgroup, it only adds processes to it, and reads its stats
+// and state.
+type adoptedManager struct {
+	cgroups.Manager
+}
+
+// Apply adds the process to the (existing) cgroup.
+func (m *adoptedManager) Apply(pid int) error {
+	if !m.Exists() {
+		return fmt.Errorf("adopted cgroup %s does not exist", m.Path(""))
+	}
+	for _, path := range m.GetPaths() {
+		if !cgroups.PathExists(path) {
+			continue
+		}
+		if err := cgroups.WriteCgroupProc(path, pid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set fails, as the cgroup resources are set by its owner.
+func (m *adoptedManager) Set(_ *configs.Resources) error {
+	return fmt.Errorf("unable to set resources of adopted cgroup %s: the cgroup is configured by its owner", m.Path(""))
+}
+
+// Freeze fails, as the cgroup may contain processes of its owner.
+func (m *adoptedManager) Freeze(_ configs.FreezerState) error {
+	return fmt.Errorf("unable to freeze adopted cgroup %s: the cgroup may contain processes of its owner", m.Path(""))
+}
+
+// Destroy is a no-op, as the cgroup is removed by its owner.
+func (m *adoptedManager) Destroy() error {
+	return nil
+}
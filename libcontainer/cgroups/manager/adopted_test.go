@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// fakeManager is a cgroups.Manager for a single directory, which fails the
+// operations not to be called on an adopted cgroup.
+type fakeManager struct {
+	cgroups.Manager
+	dir string
+	t   *testing.T
+}
+
+func (m *fakeManager) Path(string) string { return m.dir }
+
+func (m *fakeManager) GetPaths() map[string]string { return map[string]string{"": m.dir} }
+
+func (m *fakeManager) Exists() bool { return cgroups.PathExists(m.dir) }
+
+func (m *fakeManager) Set(*configs.Resources) error {
+	m.t.Error("unexpected Set")
+	return nil
+}
+
+func (m *fakeManager) Freeze(configs.FreezerState) error {
+	m.t.Error("unexpected Freeze")
+	return nil
+}
+
+func (m *fakeManager) Destroy() error {
+	m.t.Error("unexpected Destroy")
+	return nil
+}
+
+func TestAdoptedManager(t *testing.T) {
+	cgroups.TestMode = true
+	defer func() { cgroups.TestMode = false }()
+
+	dir := t.TempDir()
+	procs := filepath.Join(dir, cgroups.CgroupProcesses)
+	if err := os.WriteFile(procs, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := &adoptedManager{Manager: &fakeManager{dir: dir, t: t}}
+
+	if err := m.Apply(1234); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(procs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != "1234" {
+		t.Errorf("expected pid 1234 in %s, got %q", cgroups.CgroupProcesses, data)
+	}
+	// The operations affecting the owner's processes, or overriding its
+	// configuration, fail.
+	if err := m.Set(&configs.Resources{PidsLimit: 10}); err == nil {
+		t.Error("expected Set to fail, got nil")
+	}
+	if err := m.Freeze(configs.Frozen); err == nil {
+		t.Error("expected Freeze to fail, got nil")
+	}
+	if err := m.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Exists() {
+		t.Fatal("adopted cgroup was removed")
+	}
+
+	// A non-existent cgroup can not be adopted.
+	m = &adoptedManager{Manager: &fakeManager{dir: filepath.Join(dir, "missing"), t: t}}
+	if err := m.Apply(1234); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestNewAdopted(t *testing.T) {
+	mgr, err := New(&configs.Cgroup{
+		Path:      "/adopted-test",
+		Systemd:   true, // ignored
+		Adopted:   true,
+		Resources: &configs.Resources{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mgr.(*adoptedManager); !ok {
+		t.Fatalf("expected adopted manager, got %T", mgr)
+	}
+}
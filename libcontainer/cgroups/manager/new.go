@@ -28,10 +28,34 @@ func New(config *configs.Cgroup) (cgroups.Manager, error) {
 // For cgroup v2, the only key allowed is "" (empty string), and the value
 // is the unified cgroup path.
 func NewWithPaths(config *configs.Cgroup, paths map[string]string) (cgroups.Manager, error) {
+	m, err := newWithPaths(config, paths)
+	if err != nil {
+		return nil, err
+	}
+	if config.DirFd != 0 {
+		if !cgroups.IsCgroup2UnifiedMode() {
+			return nil, errors.New("cgroup directory fd requires cgroup v2")
+		}
+		cgroups.PinDir(m.Path(""), config.DirFd)
+	}
+	return m, nil
+}
+
+func newWithPaths(config *configs.Cgroup, paths map[string]string) (cgroups.Manager, error) {
 	if config == nil {
 		/*config不能为nil*/
 		return nil, errors.New("cgroups/manager.New: config must not be nil")
 	}
+
+	if config.Adopted {
+		// The cgroup is owned by someone else, so systemd is never
+		// asked to create a unit for it.
+		m, err := newFsManager(config, paths)
+		if err != nil {
+			return nil, err
+		}
+		return &adoptedManager{Manager: m}, nil
+	}
 	
 	/*指明了systemd,但本机的systemd未运行*/
 	if config.Systemd && !systemd.IsRunningSystemd() {
@@ -64,6 +88,18 @@ func NewWithPaths(config *configs.Cgroup, paths map[string]string) (cgroups.Mana
 	return fs.NewManager(config, paths)
 }
 
+// newFsManager returns a (non-systemd) fs or fs2 cgroup manager.
+func newFsManager(config *configs.Cgroup, paths map[string]string) (cgroups.Manager, error) {
+	if cgroups.IsCgroup2UnifiedMode() {
+		path, err := getUnifiedPath(paths)
+		if err != nil {
+			return nil, fmt.Errorf("manager.NewWithPaths: inconsistent paths: %w", err)
+		}
+		return fs2.NewManager(config, path)
+	}
+	return fs.NewManager(config, paths)
+}
+
 // getUnifiedPath is an implementation detail of libcontainer.
 // Historically, libcontainer.Create saves cgroup paths as per-subsystem path
 // map (as returned by cm.GetPaths(""), but with v2 we only have one single
@@ -75,6 +75,12 @@ type CPUSetStats struct {
 	SchedLoadBalance uint64 `json:"sched_load_balance"`
 	// sched_relax_domain_level
 	SchedRelaxDomainLevel int64 `json:"sched_relax_domain_level"`
+	// List of the CPUs which processes in that cpuset can actually
+	// use, which can differ from CPUs (the requested ones) if some
+	// of these are offline, or not available in the parent cpuset.
+	EffectiveCPUs []uint16 `json:"effective_cpus,omitempty"`
+	// List of the memory nodes which can actually be used.
+	EffectiveMems []uint16 `json:"effective_mems,omitempty"`
 }
 
 type MemoryData struct {
@@ -130,6 +136,9 @@ type PidsStats struct {
 	Current uint64 `json:"current,omitempty"`
 	// active pids hard limit
 	Limit uint64 `json:"limit,omitempty"`
+	// number of times a fork failed due to the pids limit (in this cgroup
+	// or in one of its ancestors)
+	MaxEvents uint64 `json:"max_events,omitempty"`
 }
 
 type BlkioStatEntry struct {
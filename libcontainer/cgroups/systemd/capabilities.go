@@ -0,0 +1,148 @@
+package systemd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Capabilities describe the systemd instance runc talks to.
+type Capabilities struct {
+	// Version is the systemd version.
+	Version int `json:"version"`
+	// Features are the version-dependent unit properties and property
+	// values runc uses, which are supported by this systemd version.
+	Features []string `json:"features"`
+}
+
+// versionedFeatures are the unit properties and property values runc only
+// uses if systemd is recent enough.
+var versionedFeatures = []struct {
+	name       string
+	minVersion int
+}{
+	{"DeviceAllow={block,char}-MAJOR", 240},
+	{"CPUQuotaPeriodUSec", 242},
+	{"AllowedCPUs", 244},
+	{"AllowedMemoryNodes", 244},
+	{"CPUWeight=idle", cpuIdleSupportedVersion},
+}
+
+func capabilitiesFor(version int) *Capabilities {
+	c := &Capabilities{Version: version, Features: []string{}}
+	for _, f := range versionedFeatures {
+		if version >= f.minVersion {
+			c.Features = append(c.Features, f.name)
+		}
+	}
+	return c
+}
+
+// DetectCapabilities returns the capabilities of the system (or, if
+// rootless is set, the user) instance of systemd.
+func DetectCapabilities(rootless bool) (*Capabilities, error) {
+	if !IsRunningSystemd() {
+		return nil, errors.New("systemd is not running")
+	}
+	v := systemdVersion(newDbusConnManager(rootless))
+	if v < 0 {
+		return nil, errors.New("unable to get systemd version")
+	}
+	return capabilitiesFor(v), nil
+}
+
+// capsCacheFilename is the file the detected capabilities are cached in,
+// so that every runc invocation does not need to ask systemd. The file is
+// in /run (or $XDG_RUNTIME_DIR), which is emptied on boot; the boot ID is
+// saved to the file as well, and checked, for the systems where it is not.
+const capsCacheFilename = "systemd-capabilities.json"
+
+// These can be changed by unit tests.
+var (
+	capsCacheDir = "/run/runc"
+	bootIDFile   = "/proc/sys/kernel/random/boot_id"
+)
+
+type capsCache struct {
+	BootID string `json:"boot_id"`
+	Capabilities
+}
+
+func capsCachePath(rootless bool) string {
+	if !rootless {
+		return filepath.Join(capsCacheDir, capsCacheFilename)
+	}
+	if xdr := os.Getenv("XDG_RUNTIME_DIR"); xdr != "" {
+		return filepath.Join(xdr, "runc", capsCacheFilename)
+	}
+	return ""
+}
+
+func bootID() string {
+	data, err := os.ReadFile(bootIDFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readCapsCache returns the cached capabilities, or nil if there are none
+// for the current boot.
+func readCapsCache(rootless bool) *Capabilities {
+	path := capsCachePath(rootless)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var c capsCache
+	if err := json.Unmarshal(data, &c); err != nil {
+		logrus.Debugf("ignoring invalid %s: %v", path, err)
+		return nil
+	}
+	if id := bootID(); id == "" || c.BootID != id || c.Version <= 0 {
+		return nil
+	}
+	return &c.Capabilities
+}
+
+// writeCapsCache saves the capabilities for the current boot. The errors
+// are ignored, as the cache is merely an optimization.
+func writeCapsCache(rootless bool, caps *Capabilities) {
+	path := capsCachePath(rootless)
+	id := bootID()
+	if path == "" || id == "" {
+		return
+	}
+	data, err := json.Marshal(&capsCache{BootID: id, Capabilities: *caps})
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		logrus.Debugf("unable to cache systemd capabilities: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(dir, "."+capsCacheFilename+"-")
+	if err != nil {
+		logrus.Debugf("unable to cache systemd capabilities: %v", err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		logrus.Debugf("unable to cache systemd capabilities: %v", err)
+	}
+}
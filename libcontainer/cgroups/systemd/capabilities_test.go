@@ -0,0 +1,67 @@
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCapabilitiesFor(t *testing.T) {
+	for _, tc := range []struct {
+		version  int
+		features []string
+	}{
+		{version: 219, features: []string{}},
+		{version: 242, features: []string{"DeviceAllow={block,char}-MAJOR", "CPUQuotaPeriodUSec"}},
+		{version: 244, features: []string{"DeviceAllow={block,char}-MAJOR", "CPUQuotaPeriodUSec", "AllowedCPUs", "AllowedMemoryNodes"}},
+		{version: 255, features: []string{"DeviceAllow={block,char}-MAJOR", "CPUQuotaPeriodUSec", "AllowedCPUs", "AllowedMemoryNodes", "CPUWeight=idle"}},
+	} {
+		c := capabilitiesFor(tc.version)
+		if c.Version != tc.version || !reflect.DeepEqual(c.Features, tc.features) {
+			t.Errorf("version %d: expected features %v, got %+v", tc.version, tc.features, c)
+		}
+	}
+}
+
+func TestCapsCache(t *testing.T) {
+	dir := t.TempDir()
+	oldDir, oldBootID := capsCacheDir, bootIDFile
+	t.Cleanup(func() { capsCacheDir, bootIDFile = oldDir, oldBootID })
+	capsCacheDir = filepath.Join(dir, "run")
+	bootIDFile = filepath.Join(dir, "boot_id")
+	setBootID := func(id string) {
+		if err := os.WriteFile(bootIDFile, []byte(id+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setBootID("boot-1")
+	if c := readCapsCache(false); c != nil {
+		t.Fatalf("expected no cached capabilities, got %+v", c)
+	}
+	caps := capabilitiesFor(250)
+	writeCapsCache(false, caps)
+	if c := readCapsCache(false); !reflect.DeepEqual(c, caps) {
+		t.Fatalf("expected %+v, got %+v", caps, c)
+	}
+
+	// The cache is per boot.
+	setBootID("boot-2")
+	if c := readCapsCache(false); c != nil {
+		t.Fatalf("expected no cached capabilities after reboot, got %+v", c)
+	}
+
+	// The user instance has its own cache, in $XDG_RUNTIME_DIR.
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(dir, "user"))
+	if c := readCapsCache(true); c != nil {
+		t.Fatalf("expected no cached capabilities, got %+v", c)
+	}
+	writeCapsCache(true, caps)
+	if _, err := os.Stat(filepath.Join(dir, "user", "runc", capsCacheFilename)); err != nil {
+		t.Fatal(err)
+	}
+	if c := readCapsCache(true); !reflect.DeepEqual(c, caps) {
+		t.Fatalf("expected %+v, got %+v", caps, c)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,6 +18,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
 	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
@@ -127,7 +130,7 @@ func isUnitExists(err error) bool {
 
 func startUnit(cm *dbusConnManager, unitName string, properties []systemdDbus.Property, ignoreExist bool) error {
 	statusChan := make(chan string, 1)
-	retry := true
+	retry, stopStale := true, true
 
 retry:
 	err := cm.retryOnDisconnect(func(c *systemdDbus.Conn) error {
@@ -155,6 +158,22 @@ retry:
 			retry = false
 			goto retry
 		}
+		if stopStale && getUnitType(unitName) == "Scope" {
+			// The unit may also be a leftover from a crash (of the
+			// node, or of runc) which is still active, although no
+			// process is left in it. Stop it, and retry once more.
+			stopStale = false
+			stale, serr := isStaleUnit(cm, unitName)
+			if serr != nil {
+				logrus.Warnf("unable to check whether unit %s is stale: %v", unitName, serr)
+			} else if stale {
+				logrus.Warnf("stopping stale unit %s (it has no process left)", unitName)
+				if serr := stopUnit(cm, unitName); serr != nil {
+					logrus.Warnf("unable to stop stale unit %s: %v", unitName, serr)
+				}
+				goto retry
+			}
+		}
 		return err
 	}
 
@@ -205,6 +224,50 @@ func stopUnit(cm *dbusConnManager, unitName string) error {
 	return nil
 }
 
+// isStaleUnit returns true if the scope unitName has no process left in its
+// cgroup (or has no cgroup at all).
+func isStaleUnit(cm *dbusConnManager, unitName string) (bool, error) {
+	prop, err := getUnitTypeProperty(cm, unitName, "Scope", "ControlGroup")
+	if err != nil {
+		return false, err
+	}
+	cg, _ := prop.Value.Value().(string)
+	if cg == "" {
+		return true, nil
+	}
+	path, err := systemdCgroupPath(cg)
+	if err != nil {
+		return false, err
+	}
+	return isEmptyCgroup(path)
+}
+
+// systemdCgroupPath returns the path of cg, a cgroup of the hierarchy
+// managed by systemd (such as the ControlGroup unit property).
+func systemdCgroupPath(cg string) (string, error) {
+	if cgroups.IsCgroup2UnifiedMode() {
+		return filepath.Join(fs2.UnifiedMountpoint, cg), nil
+	}
+	root, err := cgroups.FindCgroupMountpoint("", "name=systemd")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, cg), nil
+}
+
+// isEmptyCgroup returns true if there is no process in the cgroup at path,
+// nor in its sub-cgroups, or if it does not exist.
+func isEmptyCgroup(path string) (bool, error) {
+	pids, err := cgroups.GetAllPids(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	return len(pids) == 0, nil
+}
+
 func resetFailedUnit(cm *dbusConnManager, name string) error {
 	return cm.retryOnDisconnect(func(c *systemdDbus.Conn) error {
 		return c.ResetFailedUnitContext(context.TODO(), name)
@@ -220,10 +283,88 @@ func getUnitTypeProperty(cm *dbusConnManager, unitName string, unitType string,
 	return prop, err
 }
 
-func setUnitProperties(cm *dbusConnManager, name string, properties ...systemdDbus.Property) error {
+// setUnitProperties sets all the properties of the unit name at once, either
+// at runtime only, or persistently (see SetUnitProperties in
+// org.freedesktop.systemd1(5)).
+func setUnitProperties(cm *dbusConnManager, name string, runtime bool, properties ...systemdDbus.Property) error {
 	return cm.retryOnDisconnect(func(c *systemdDbus.Conn) error {
-		return c.SetUnitPropertiesContext(context.TODO(), name, true, properties...)
+		return c.SetUnitPropertiesContext(context.TODO(), name, runtime, properties...)
+	})
+}
+
+// persistentDropInDir returns the directory of the drop-ins written by
+// systemd when the properties of the unit name are set persistently.
+func persistentDropInDir(rootless bool, name string) (string, error) {
+	dir := "/etc/systemd/system.control"
+	if rootless {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "systemd", "user.control")
+	}
+	return filepath.Join(dir, name+".d"), nil
+}
+
+// removePersistentDropIns removes the drop-ins written by systemd when the
+// properties of the unit name were set persistently (see
+// configs.Resources.SystemdPersistent). Unlike the runtime ones, these are
+// not removed with the unit, and would apply to a later unit with the same
+// name.
+func removePersistentDropIns(rootless bool, name string) error {
+	dir, err := persistentDropInDir(rootless, name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// verifiedProperties are the unit properties which verifyUnitProperties
+// checks. Others (such as DeviceAllow, CPUQuotaPerSecUSec or AllowedCPUs)
+// may legitimately be read back from systemd with a different value than
+// the one set.
+var verifiedProperties = map[string]bool{
+	"BlockIOWeight": true,
+	"CPUShares":     true,
+	"CPUWeight":     true,
+	"IOWeight":      true,
+	"MemoryHigh":    true,
+	"MemoryLimit":   true,
+	"MemoryLow":     true,
+	"MemoryMax":     true,
+	"MemorySwapMax": true,
+	"TasksMax":      true,
+}
+
+// verifyUnitProperties reads the properties of the unit name back from
+// systemd, in a single call, and checks that they have the values set.
+func verifyUnitProperties(cm *dbusConnManager, name string, properties []systemdDbus.Property) error {
+	var values map[string]interface{}
+	err := cm.retryOnDisconnect(func(c *systemdDbus.Conn) (err error) {
+		values, err = c.GetUnitTypePropertiesContext(context.TODO(), name, getUnitType(name))
+		return err
 	})
+	if err != nil {
+		return fmt.Errorf("unable to read back the properties of unit %s: %w", name, err)
+	}
+	return checkUnitProperties(name, properties, values)
+}
+
+func checkUnitProperties(name string, properties []systemdDbus.Property, values map[string]interface{}) error {
+	for _, p := range properties {
+		if !verifiedProperties[p.Name] {
+			continue
+		}
+		// Not known to this systemd version.
+		v, ok := values[p.Name]
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(v, p.Value.Value()) {
+			return fmt.Errorf("systemd unit %s: %s is %v after setting it to %v", name, p.Name, v, p.Value.Value())
+		}
+	}
+	return nil
 }
 
 func getManagerProperty(cm *dbusConnManager, name string) (string, error) {
@@ -241,6 +382,11 @@ func getManagerProperty(cm *dbusConnManager, name string) (string, error) {
 
 func systemdVersion(cm *dbusConnManager) int {
 	versionOnce.Do(func() {
+		rootless := cm.isRootless()
+		if c := readCapsCache(rootless); c != nil {
+			version = c.Version
+			return
+		}
 		version = -1
 		verStr, err := getManagerProperty(cm, "Version")
 		if err == nil {
@@ -249,7 +395,9 @@ func systemdVersion(cm *dbusConnManager) int {
 
 		if err != nil {
 			logrus.WithError(err).Error("unable to get systemd version")
+			return
 		}
+		writeCapsCache(rootless, capabilitiesFor(version))
 	})
 
 	return version
@@ -31,6 +31,14 @@ func newDbusConnManager(rootless bool) *dbusConnManager {
 	return &dbusConnManager{}
 }
 
+// isRootless returns whether the connection is to the user instance of
+// systemd.
+func (d *dbusConnManager) isRootless() bool {
+	dbusMu.RLock()
+	defer dbusMu.RUnlock()
+	return dbusRootless
+}
+
 // getConnection lazily initializes and returns systemd dbus connection.
 func (d *dbusConnManager) getConnection() (*systemdDbus.Conn, error) {
 	// In the case where dbusC != nil
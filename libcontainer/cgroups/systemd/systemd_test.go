@@ -2,6 +2,7 @@ package systemd
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -98,6 +99,53 @@ func TestUnitExistsIgnored(t *testing.T) {
 	}
 }
 
+func TestIsEmptyCgroup(t *testing.T) {
+	cgs, err := cgroups.ParseCgroupFile("/proc/self/cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, ok := cgs["name=systemd"]
+	if cgroups.IsCgroup2UnifiedMode() {
+		own, ok = cgs[""]
+	}
+	if !ok {
+		t.Skip("Test requires the systemd cgroup hierarchy.")
+	}
+	path, err := systemdCgroupPath(own)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// The cgroup of this process.
+	if empty, err := isEmptyCgroup(path); err != nil || empty {
+		t.Fatalf("expected %s to be non-empty, got %v (err: %v)", path, empty, err)
+	}
+	if empty, err := isEmptyCgroup(filepath.Join(path, "nonexistent")); err != nil || !empty {
+		t.Fatalf("expected a nonexistent cgroup to be empty, got %v (err: %v)", empty, err)
+	}
+}
+
+func TestCheckUnitProperties(t *testing.T) {
+	props := []systemdDbus.Property{
+		newProp("MemoryMax", uint64(1<<30)),
+		newProp("TasksMax", uint64(100)),
+		// Not verified.
+		newProp("CPUQuotaPerSecUSec", uint64(150000)),
+	}
+	values := map[string]interface{}{
+		"MemoryMax":          uint64(1 << 30),
+		"CPUQuotaPerSecUSec": uint64(160000),
+		// TasksMax is unknown to this systemd.
+	}
+	if err := checkUnitProperties("test.scope", props, values); err != nil {
+		t.Fatal(err)
+	}
+	values["MemoryMax"] = uint64(1 << 29)
+	if err := checkUnitProperties("test.scope", props, values); err == nil {
+		t.Fatal("expected an error for a MemoryMax mismatch")
+	}
+}
+
 func TestUnifiedResToSystemdProps(t *testing.T) {
 	if !IsRunningSystemd() {
 		t.Skip("Test requires systemd.")
@@ -179,3 +227,25 @@ func TestUnifiedResToSystemdProps(t *testing.T) {
 		})
 	}
 }
+
+func TestRemovePersistentDropIns(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	dir := filepath.Join(config, "systemd", "user.control", "runc-test.scope.d")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "50-TasksMax.conf"), []byte("[Scope]\nTasksMax=30\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := removePersistentDropIns(true, "runc-test.scope"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", dir, err)
+	}
+	// Nothing to remove.
+	if err := removePersistentDropIns(true, "runc-test.scope"); err != nil {
+		t.Error(err)
+	}
+}
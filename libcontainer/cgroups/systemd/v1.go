@@ -220,7 +220,11 @@ func (m *LegacyManager) Destroy() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	stopErr := stopUnit(m.dbus, getUnitName(m.cgroups))
+	unitName := getUnitName(m.cgroups)
+	stopErr := stopUnit(m.dbus, unitName)
+	if stopErr == nil {
+		stopErr = removePersistentDropIns(m.cgroups.Rootless, unitName)
+	}
 
 	// Both on success and on error, cleanup all the cgroups
 	// we are aware of, as some of them were created directly
@@ -361,7 +365,7 @@ func (m *LegacyManager) Set(r *configs.Resources) error {
 			}
 		}
 	}
-	setErr := setUnitProperties(m.dbus, unitName, properties...)
+	setErr := setUnitProperties(m.dbus, unitName, !r.SystemdPersistent, properties...)
 	if needsThaw {
 		if err := m.doFreeze(configs.Thawed); err != nil {
 			logrus.Infof("thaw container after SetUnitProperties failed: %v", err)
@@ -370,6 +374,9 @@ func (m *LegacyManager) Set(r *configs.Resources) error {
 	if setErr != nil {
 		return setErr
 	}
+	if err := verifyUnitProperties(m.dbus, unitName, properties); err != nil {
+		return err
+	}
 
 	for _, sys := range legacySubsystems {
 		// Get the subsystem path, but don't error out for not found cgroups.
@@ -230,11 +230,11 @@ func genV2ResourcesProperties(dirPath string, r *configs.Resources, cm *dbusConn
 			newProp("MemoryLow", uint64(r.MemoryReservation)))
 	}
 
-	swap, err := cgroups.ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory)
+	swap, err := cgroups.SwapLimitV2(r.MemorySwap, r.Memory, r.SwapMax)
 	if err != nil {
 		return nil, err
 	}
-	if swap != 0 {
+	if swap != 0 || r.SwapMax != nil {
 		properties = append(properties,
 			newProp("MemorySwapMax", uint64(swap)))
 	}
@@ -393,6 +393,9 @@ func (m *UnifiedManager) Destroy() error {
 	if err := stopUnit(m.dbus, unitName); err != nil {
 		return err
 	}
+	if err := removePersistentDropIns(m.cgroups.Rootless, unitName); err != nil {
+		return err
+	}
 
 	// systemd 239 do not remove sub-cgroups.
 	err := m.fsMgr.Destroy()
@@ -489,9 +492,13 @@ func (m *UnifiedManager) Set(r *configs.Resources) error {
 		return err
 	}
 
-	if err := setUnitProperties(m.dbus, getUnitName(m.cgroups), properties...); err != nil {
+	unitName := getUnitName(m.cgroups)
+	if err := setUnitProperties(m.dbus, unitName, !r.SystemdPersistent, properties...); err != nil {
 		return fmt.Errorf("unable to set unit properties: %w", err)
 	}
+	if err := verifyUnitProperties(m.dbus, unitName, properties); err != nil {
+		return err
+	}
 
 	return m.fsMgr.Set(r)
 }
@@ -215,6 +215,10 @@ func parseCgroupFromReader(r io.Reader) (map[string]string, error) {
 
 /*检查path是否存在*/
 func PathExists(path string) bool {
+	if fd, rel, ok := pinnedDir(path); ok {
+		var st unix.Stat_t
+		return unix.Fstatat(fd, rel, &st, unix.AT_SYMLINK_NOFOLLOW) == nil
+	}
 	if _, err := os.Stat(path); err != nil {
 		return false
 	}
@@ -440,6 +444,42 @@ func ConvertMemorySwapToCgroupV2Value(memorySwap, memory int64) (int64, error) {
 	return memorySwap - memory, nil
 }
 
+// SwapLimitV2 returns the cgroup v2 swap limit (the memory.swap.max value)
+// from either swapMax, the swap limit on its own, or memorySwap, the
+// memory+swap limit (see ConvertMemorySwapToCgroupV2Value). Only one of
+// them can be set.
+func SwapLimitV2(memorySwap, memory int64, swapMax *int64) (int64, error) {
+	if swapMax == nil {
+		return ConvertMemorySwapToCgroupV2Value(memorySwap, memory)
+	}
+	if memorySwap != 0 {
+		return 0, errors.New("memory+swap limit and swap limit can not be set together")
+	}
+	if *swapMax < -1 {
+		return 0, fmt.Errorf("invalid swap limit: %d", *swapMax)
+	}
+	return *swapMax, nil
+}
+
+// ConvertSwapMaxToCgroupV1Value converts the swap limit (swapMax) to the
+// memory+swap limit used by cgroup v1, which is only possible if the memory
+// limit is set (unless swap is unlimited).
+func ConvertSwapMaxToCgroupV1Value(swapMax, memory int64) (int64, error) {
+	if swapMax == -1 {
+		return -1, nil
+	}
+	if swapMax < -1 {
+		return 0, fmt.Errorf("invalid swap limit: %d", swapMax)
+	}
+	if memory == 0 || memory == -1 {
+		return 0, errors.New("unable to set swap limit without memory limit")
+	}
+	if memory < 0 {
+		return 0, fmt.Errorf("invalid memory value: %d", memory)
+	}
+	return memory + swapMax, nil
+}
+
 // Since the OCI spec is designed for cgroup v1, in some cases
 // there is need to convert from the cgroup v1 configuration to cgroup v2
 // the formula for BlkIOWeight to IOWeight is y = (1 + (x - 10) * 9999 / 990)
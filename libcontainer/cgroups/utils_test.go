@@ -633,6 +633,71 @@ func TestConvertMemorySwapToCgroupV2Value(t *testing.T) {
 	}
 }
 
+func TestSwapLimitV2(t *testing.T) {
+	i64 := func(v int64) *int64 { return &v }
+	cases := []struct {
+		memswap, memory int64
+		swapMax         *int64
+		expected        int64
+		expErr          bool
+	}{
+		{memswap: 500, memory: 200, expected: 300},
+		{memory: 200, swapMax: i64(300), expected: 300},
+		{memory: 0, swapMax: i64(300), expected: 300},
+		{memory: -1, swapMax: i64(0), expected: 0},
+		{memory: 200, swapMax: i64(-1), expected: -1},
+		{memory: 200, swapMax: i64(-2), expErr: true},
+		{memswap: 500, memory: 200, swapMax: i64(300), expErr: true},
+	}
+	for _, c := range cases {
+		swap, err := SwapLimitV2(c.memswap, c.memory, c.swapMax)
+		if c.expErr {
+			if err == nil {
+				t.Errorf("%+v: expected error, got %d, nil", c, swap)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: expected success, got error %s", c, err)
+		}
+		if swap != c.expected {
+			t.Errorf("%+v: expected %d, got %d", c, c.expected, swap)
+		}
+	}
+}
+
+func TestConvertSwapMaxToCgroupV1Value(t *testing.T) {
+	cases := []struct {
+		swapMax, memory int64
+		expected        int64
+		expErr          bool
+	}{
+		{swapMax: 300, memory: 200, expected: 500},
+		{swapMax: 0, memory: 200, expected: 200},
+		{swapMax: -1, memory: 0, expected: -1},
+		{swapMax: -1, memory: 200, expected: -1},
+		{swapMax: 300, memory: 0, expErr: true},
+		{swapMax: 300, memory: -1, expErr: true},
+		{swapMax: 300, memory: -200, expErr: true},
+		{swapMax: -2, memory: 200, expErr: true},
+	}
+	for _, c := range cases {
+		memswap, err := ConvertSwapMaxToCgroupV1Value(c.swapMax, c.memory)
+		if c.expErr {
+			if err == nil {
+				t.Errorf("%+v: expected error, got %d, nil", c, memswap)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: expected success, got error %s", c, err)
+		}
+		if memswap != c.expected {
+			t.Errorf("%+v: expected %d, got %d", c, c.expected, memswap)
+		}
+	}
+}
+
 func TestConvertBlkIOToIOWeightValue(t *testing.T) {
 	cases := map[uint16]uint64{
 		0:    0,
@@ -0,0 +1,255 @@
+// Package cni implements a minimal CNI (Container Network Interface)
+// runtime, able to run the plugins of a network configuration list to add
+// a container to a network, and to remove it.
+//
+// See https://github.com/containernetworking/cni/blob/main/SPEC.md.
+package cni
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/extcmd"
+)
+
+// Defaults for the configs.CNI fields.
+const (
+	DefaultConfDir = "/etc/cni/net.d"
+	DefaultBinDir  = "/opt/cni/bin"
+	DefaultIfName  = "eth0"
+)
+
+// Network is a CNI network configuration list.
+type Network struct {
+	Name       string
+	CNIVersion string
+	// Plugins are the raw plugin configurations.
+	Plugins []map[string]interface{}
+}
+
+// Error is an error returned by a CNI plugin.
+type Error struct {
+	Plugin  string `json:"-"`
+	Code    uint   `json:"code"`
+	Msg     string `json:"msg"`
+	Details string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf("cni plugin %s failed (code %d): %s", e.Plugin, e.Code, e.Msg)
+	if e.Details != "" {
+		s += "; " + e.Details
+	}
+	return s
+}
+
+func withDefaults(c *configs.CNI) configs.CNI {
+	conf := *c
+	if conf.ConfDir == "" {
+		conf.ConfDir = DefaultConfDir
+	}
+	if conf.BinDir == "" {
+		conf.BinDir = DefaultBinDir
+	}
+	if conf.IfName == "" {
+		conf.IfName = DefaultIfName
+	}
+	return conf
+}
+
+// LoadNetwork finds the configuration of the named network in confDir.
+// Both network configuration lists (*.conflist) and single plugin
+// configurations (*.conf, *.json) are supported.
+func LoadNetwork(confDir, name string) (*Network, error) {
+	files, err := os.ReadDir(confDir)
+	if err != nil {
+		return nil, fmt.Errorf("cni: %w", err)
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		ext := filepath.Ext(n)
+		if ext != ".conflist" && ext != ".conf" && ext != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(confDir, n))
+		if err != nil {
+			return nil, fmt.Errorf("cni: %w", err)
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("cni: unable to parse %s: %w", n, err)
+		}
+		if raw["name"] != name {
+			continue
+		}
+		network := &Network{Name: name}
+		network.CNIVersion, _ = raw["cniVersion"].(string)
+		if ext != ".conflist" {
+			network.Plugins = []map[string]interface{}{raw}
+			return network, nil
+		}
+		plugins, _ := raw["plugins"].([]interface{})
+		for _, p := range plugins {
+			plugin, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cni: %s: invalid plugin configuration", n)
+			}
+			network.Plugins = append(network.Plugins, plugin)
+		}
+		if len(network.Plugins) == 0 {
+			return nil, fmt.Errorf("cni: %s: no plugins", n)
+		}
+		return network, nil
+	}
+	return nil, fmt.Errorf("cni: network %q not found in %s", name, confDir)
+}
+
+// Add adds the container with the given ID and network namespace to the
+// network, and returns the CNI result.
+func Add(c *configs.CNI, id, netns string) (json.RawMessage, error) {
+	conf := withDefaults(c)
+	network, err := LoadNetwork(conf.ConfDir, conf.Network)
+	if err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	for _, plugin := range network.Plugins {
+		result, err = invoke(&conf, network, plugin, "ADD", id, netns, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Del removes the container from the network. The network namespace can
+// be empty if it no longer exists, and prevResult is the result of Add,
+// if known. All plugins are run even if some of them fail, and the first
+// error is returned.
+func Del(c *configs.CNI, id, netns string, prevResult json.RawMessage) error {
+	conf := withDefaults(c)
+	network, err := LoadNetwork(conf.ConfDir, conf.Network)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for i := len(network.Plugins) - 1; i >= 0; i-- {
+		if _, err := invoke(&conf, network, network.Plugins[i], "DEL", id, netns, prevResult); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func invoke(conf *configs.CNI, network *Network, plugin map[string]interface{}, command, id, netns string, prevResult json.RawMessage) (json.RawMessage, error) {
+	typ, _ := plugin["type"].(string)
+	if typ == "" || strings.ContainsRune(typ, '/') {
+		return nil, fmt.Errorf("cni: network %s: invalid plugin type %q", network.Name, typ)
+	}
+	path, err := findPlugin(conf.BinDir, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin := make(map[string]interface{}, len(plugin)+3)
+	for k, v := range plugin {
+		stdin[k] = v
+	}
+	stdin["name"] = network.Name
+	stdin["cniVersion"] = network.CNIVersion
+	if prevResult != nil {
+		stdin["prevResult"] = prevResult
+	}
+	data, err := json.Marshal(stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := extcmd.Command(path)
+	cmd.Env = append(os.Environ(),
+		"CNI_COMMAND="+command,
+		"CNI_CONTAINERID="+id,
+		"CNI_NETNS="+netns,
+		"CNI_IFNAME="+conf.IfName,
+		"CNI_PATH="+conf.BinDir,
+	)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			perr := &Error{}
+			if json.Unmarshal(stdout.Bytes(), perr) == nil && perr.Msg != "" {
+				perr.Plugin = typ
+				return nil, perr
+			}
+		}
+		return nil, fmt.Errorf("cni plugin %s %s failed: %w (stderr: %s)", typ, command, err, strings.TrimSpace(stderr.String()))
+	}
+	if command != "ADD" {
+		return nil, nil
+	}
+	result := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(result) {
+		return nil, fmt.Errorf("cni plugin %s returned an invalid result: %q", typ, result)
+	}
+	return json.RawMessage(result), nil
+}
+
+func findPlugin(binDir, typ string) (string, error) {
+	for _, dir := range filepath.SplitList(binDir) {
+		path := filepath.Join(dir, typ)
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("cni: plugin %q not found in %s", typ, binDir)
+}
+
+// IPs returns the IP addresses (in the CIDR form) from a CNI result.
+func IPs(result json.RawMessage) []string {
+	if len(result) == 0 {
+		return nil
+	}
+	var r struct {
+		// CNI spec 0.3.0 and later.
+		IPs []struct {
+			Address string `json:"address"`
+		} `json:"ips"`
+		// CNI spec 0.1.0 and 0.2.0.
+		IP4 *struct {
+			IP string `json:"ip"`
+		} `json:"ip4"`
+		IP6 *struct {
+			IP string `json:"ip"`
+		} `json:"ip6"`
+	}
+	if err := json.Unmarshal(result, &r); err != nil {
+		return nil
+	}
+	var ips []string
+	for _, ip := range r.IPs {
+		ips = append(ips, ip.Address)
+	}
+	if r.IP4 != nil && r.IP4.IP != "" {
+		ips = append(ips, r.IP4.IP)
+	}
+	if r.IP6 != nil && r.IP6.IP != "" {
+		ips = append(ips, r.IP6.IP)
+	}
+	return ips
+}
@@ -0,0 +1,151 @@
+package cni
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// fakePlugin logs the CNI environment and its stdin to dir/log, and prints
+// a result with the given address on ADD.
+const fakePlugin = `#!/bin/sh
+dir=$(dirname "$0")
+{
+	echo "$CNI_COMMAND $CNI_CONTAINERID $CNI_NETNS $CNI_IFNAME $(basename "$0")"
+	cat
+	echo
+} >>"$dir/log"
+if [ "$CNI_COMMAND" = ADD ]; then
+	echo '{"cniVersion": "1.0.0", "ips": [{"address": "%ADDR%"}]}'
+fi
+`
+
+const failPlugin = `#!/bin/sh
+echo '{"code": 11, "msg": "no luck"}'
+exit 1
+`
+
+func writeFile(t *testing.T, path, data string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setup(t *testing.T) (conf *configs.CNI, binDir string) {
+	t.Helper()
+	confDir := t.TempDir()
+	binDir = t.TempDir()
+	writeFile(t, filepath.Join(binDir, "first"), strings.Replace(fakePlugin, "%ADDR%", "10.0.0.2/24", 1), 0o755)
+	writeFile(t, filepath.Join(binDir, "second"), strings.Replace(fakePlugin, "%ADDR%", "10.0.0.3/24", 1), 0o755)
+	writeFile(t, filepath.Join(binDir, "fail"), failPlugin, 0o755)
+	writeFile(t, filepath.Join(confDir, "10-other.conf"), `{"cniVersion": "0.4.0", "name": "other", "type": "fail"}`, 0o644)
+	writeFile(t, filepath.Join(confDir, "20-test.conflist"), `{
+		"cniVersion": "1.0.0",
+		"name": "test",
+		"plugins": [{"type": "first", "foo": "bar"}, {"type": "second"}]
+	}`, 0o644)
+	writeFile(t, filepath.Join(confDir, "README"), "not a config", 0o644)
+	return &configs.CNI{Network: "test", ConfDir: confDir, BinDir: binDir}, binDir
+}
+
+func TestAddDel(t *testing.T) {
+	conf, binDir := setup(t)
+
+	res, err := Add(conf, "ctr", "/proc/1/ns/net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ips := IPs(res); !reflect.DeepEqual(ips, []string{"10.0.0.3/24"}) {
+		t.Fatalf("expected the result of the last plugin, got %v", ips)
+	}
+	if err := Del(conf, "ctr", "", res); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(binDir, "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("expected 4 plugin invocations, got %q", lines)
+	}
+	for i, expected := range []string{
+		"ADD ctr /proc/1/ns/net eth0 first",
+		"ADD ctr /proc/1/ns/net eth0 second",
+		"DEL ctr  eth0 second",
+		"DEL ctr  eth0 first",
+	} {
+		if lines[2*i] != expected {
+			t.Errorf("invocation #%d: expected %q, got %q", i, expected, lines[2*i])
+		}
+	}
+
+	var stdin map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &stdin); err != nil {
+		t.Fatal(err)
+	}
+	if stdin["name"] != "test" || stdin["cniVersion"] != "1.0.0" || stdin["foo"] != "bar" || stdin["prevResult"] != nil {
+		t.Errorf("unexpected stdin of the first ADD: %v", stdin)
+	}
+	if err := json.Unmarshal([]byte(lines[3]), &stdin); err != nil {
+		t.Fatal(err)
+	}
+	if prev := IPs(mustMarshal(t, stdin["prevResult"])); !reflect.DeepEqual(prev, []string{"10.0.0.2/24"}) {
+		t.Errorf("unexpected prevResult of the second ADD: %v", stdin["prevResult"])
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestAddErrors(t *testing.T) {
+	conf, _ := setup(t)
+
+	conf.Network = "other"
+	_, err := Add(conf, "ctr", "/proc/1/ns/net")
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Code != 11 || perr.Plugin != "fail" {
+		t.Errorf("expected plugin error, got %v", err)
+	}
+
+	conf.Network = "missing"
+	if _, err := Add(conf, "ctr", "/proc/1/ns/net"); err == nil {
+		t.Error("expected error for a missing network, got nil")
+	}
+
+	conf.Network = "test"
+	conf.BinDir = t.TempDir()
+	if _, err := Add(conf, "ctr", "/proc/1/ns/net"); err == nil {
+		t.Error("expected error for missing plugins, got nil")
+	}
+}
+
+func TestIPs(t *testing.T) {
+	for _, tc := range []struct {
+		result string
+		ips    []string
+	}{
+		{``, nil},
+		{`garbage`, nil},
+		{`{"ips": [{"address": "10.0.0.2/24"}, {"address": "fd00::2/64"}]}`, []string{"10.0.0.2/24", "fd00::2/64"}},
+		{`{"ip4": {"ip": "10.0.0.2/24"}, "ip6": {"ip": "fd00::2/64"}}`, []string{"10.0.0.2/24", "fd00::2/64"}},
+	} {
+		if ips := IPs(json.RawMessage(tc.result)); !reflect.DeepEqual(ips, tc.ips) {
+			t.Errorf("%s: expected %v, got %v", tc.result, tc.ips, ips)
+		}
+	}
+}
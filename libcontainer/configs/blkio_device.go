@@ -8,6 +8,11 @@ type BlockIODevice struct {
 	Major int64 `json:"major"`
 	// Minor is the device's minor number
 	Minor int64 `json:"minor"`
+	// Path is the host path of the device, such as /dev/disk/by-id/...
+	// If set, Major and Minor are resolved from it each time the cgroup
+	// limits are set, as the device numbers may change (such as when an
+	// NVMe disk is replaced).
+	Path string `json:"path,omitempty"`
 }
 
 // WeightDevice struct holds a `major:minor weight`|`major:minor leaf_weight` pair
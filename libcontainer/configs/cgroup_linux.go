@@ -42,6 +42,19 @@ type Cgroup struct {
 	// Rootless tells if rootless cgroups should be used.
 	Rootless bool
 
+	// Adopted tells that the cgroup (specified by Path) is created and
+	// owned by an external manager. runc only adds the container processes
+	// to it and reads its stats; it never creates the cgroup, sets its
+	// resources, or removes it.
+	Adopted bool `json:"adopted,omitempty"`
+
+	// DirFd, if non-zero, is an open file descriptor of the cgroup v2
+	// directory (specified by Path), which is used for all the operations
+	// on the cgroup by the runc instance creating the container, including
+	// starting the container init in it (CLONE_INTO_CGROUP). It is not
+	// saved in the container state.
+	DirFd int `json:"-"`
+
 	// The host UID that should own the cgroup, or nil to accept
 	// the default ownership.  This should only be set when the
 	// cgroupfs is to be mounted read/write.
@@ -63,6 +76,13 @@ type Resources struct {
 	// Total memory usage (memory + swap); set `-1` to enable unlimited swap
 	MemorySwap int64 `json:"memory_swap"`
 
+	// Swap usage limit (in bytes), independent of Memory; set `-1` to
+	// enable unlimited swap, and `0` to disable swap. This is the value
+	// of memory.swap.max for cgroup v2; for cgroup v1, it is converted to
+	// the memory+swap limit, which requires Memory to be set. Can not be
+	// used together with MemorySwap.
+	SwapMax *int64 `json:"swap_max,omitempty"`
+
 	// CPU shares (relative weight vs. other containers)
 	CpuShares uint64 `json:"cpu_shares"`
 
@@ -162,6 +182,12 @@ type Resources struct {
 	// methods may be relatively slow, thus this flag.
 	SkipFreezeOnSet bool `json:"-"`
 
+	// SystemdPersistent makes the systemd cgroup managers set the unit
+	// properties persistently (in /etc/systemd/system.control) rather than
+	// at runtime only (in /run/systemd/system.control). These drop-ins are
+	// removed when the cgroup is destroyed. Used by runc update.
+	SystemdPersistent bool `json:"-"`
+
 	// MemoryCheckBeforeUpdate is a flag for cgroup v2 managers to check
 	// if the new memory limits (Memory and MemorySwap) being set are lower
 	// than the current memory usage, and reject if so.
@@ -3,6 +3,7 @@ package configs
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"time"
@@ -108,6 +109,42 @@ type Config struct {
 	// Specifies the mount propagation flags to be applied to /.
 	RootPropagation int `json:"rootPropagation"`
 
+	// PropagationCheck sets what to do if the propagation of / or of a
+	// mount is not the one requested (by RootPropagation or by the mount
+	// PropagationFlags) once the rootfs is set up. It is one of the
+	// PropagationCheck* values, and defaults to PropagationCheckWarn.
+	PropagationCheck string `json:"propagation_check,omitempty"`
+
+	// KeepSysBind disables mounting a fresh sysfs, showing the network
+	// devices of the container network namespace, in place of a bind mount
+	// of the host /sys on /sys when the container has its own network
+	// namespace.
+	KeepSysBind bool `json:"keep_sys_bind,omitempty"`
+
+	// AllowedMountSources, if not empty, restricts the bind mount sources
+	// to these host directories (and their subdirectories), after the
+	// symbolic links are resolved. It is a host policy, set by runc rather
+	// than from the bundle.
+	AllowedMountSources []string `json:"allowed_mount_sources,omitempty"`
+
+	// ConfigHash is the digest ("sha256:<hex>") of the bundle config.json
+	// the container was created from, set by runc.
+	ConfigHash string `json:"config_hash,omitempty"`
+
+	// ConfigDriftCheck sets what to do if the bundle config.json no longer
+	// matches ConfigHash when the container is started or a process is
+	// executed in it. It is one of the ConfigDriftCheck* values, and
+	// defaults to ConfigDriftCheckWarn.
+	ConfigDriftCheck string `json:"config_drift_check,omitempty"`
+
+	// InitPath is the absolute path of the binary run as "<InitPath> init"
+	// to set up the container processes, instead of the current binary
+	// (which must then handle "init" by calling libcontainer.Init). The
+	// binary must implement the init contract described in
+	// docs/init-contract.md, and be built from the same libcontainer
+	// version. It is cloned before being run, as the current binary is.
+	InitPath string `json:"init_path,omitempty"`
+
 	// Mounts specify additional source and destination paths that will be mounted inside the container's
 	// rootfs and mount namespace if specified
 	Mounts []*Mount `json:"mounts"`
@@ -222,6 +259,163 @@ type Config struct {
 
 	// Personality contains configuration for the Linux personality syscall.
 	Personality *LinuxPersonality `json:"personality,omitempty"`
+
+	// SystemdInit is set when the container runs systemd as its init
+	// process (see specconv.SystemdInitAnnotation).
+	SystemdInit bool `json:"systemd_init,omitempty"`
+
+	// UnconfinedStartHooks makes the StartContainer hooks run before the
+	// container's seccomp filter, capabilities and user are applied to
+	// the init process, rather than after.
+	UnconfinedStartHooks bool `json:"unconfined_start_hooks,omitempty"`
+
+	// CNI configures the container network with CNI plugins. The plugins
+	// are run (ADD) when the container is created, and again (DEL) when
+	// it is destroyed.
+	CNI *CNI `json:"cni,omitempty"`
+
+	// StaticNetwork is a static configuration of the addresses, routes
+	// and DNS servers of the container network namespace.
+	StaticNetwork *StaticNetwork `json:"static_network,omitempty"`
+
+	// EtcFiles configures the /etc/hostname, /etc/hosts and
+	// /etc/resolv.conf files generated by runc for the container.
+	EtcFiles *EtcFiles `json:"etc_files,omitempty"`
+
+	// CpusetAdjust allows the requested CPUs (Cgroups.Resources.CpusetCpus)
+	// to be partially offline. With cgroup v1, only the online ones are
+	// set, and the cpuset can be updated after a CPU hotplug (see
+	// Container.SyncCpuset).
+	CpusetAdjust bool `json:"cpuset_adjust,omitempty"`
+
+	// CpusetAlloc, if set, makes runc allocate exclusive CPUs for the
+	// container (setting Cgroups.Resources.CpusetCpus) when it is created.
+	CpusetAlloc *CpusetAlloc `json:"cpuset_alloc,omitempty"`
+
+	// HousekeepingCgroup, if set, is the path (relative to the cgroup v2
+	// mount point) of the cgroup for runc to do the container setup in, so
+	// that the setup cost (runc init) is charged neither to the container
+	// cgroup nor to the cgroup of the runc caller. A transient sub-cgroup
+	// is created in it for each runc create, run, and exec. It requires
+	// cgroup v2 and the cgroupfs driver.
+	HousekeepingCgroup string `json:"housekeeping_cgroup,omitempty"`
+
+	// PidsStartLimit, if set, is a lower pids limit for the container to
+	// start with, to contain a fork bomb in the container entrypoint.
+	PidsStartLimit *PidsStartLimit `json:"pids_start_limit,omitempty"`
+
+	// ShmSize, if set, is the size (in bytes) of the /dev/shm tmpfs, and
+	// TmpSize and TmpInodes, if set, are the size and the maximum number of
+	// inodes of the /tmp tmpfs. The tmpfs mounts themselves are in Mounts,
+	// with the corresponding options. The limits can be changed while the
+	// container is running.
+	ShmSize   int64  `json:"shm_size,omitempty"`
+	TmpSize   int64  `json:"tmp_size,omitempty"`
+	TmpInodes uint64 `json:"tmp_inodes,omitempty"`
+
+	// DiskQuota, if set, is a project quota limiting the disk usage of the
+	// container writable layer.
+	DiskQuota *DiskQuota `json:"disk_quota,omitempty"`
+
+	// Binfmt, if set, is the binfmt_misc handling of the container.
+	Binfmt *Binfmt `json:"binfmt,omitempty"`
+
+	// BPFLSM, if set, are the BPF LSM programs scoped to the container
+	// cgroup.
+	BPFLSM *BPFLSM `json:"bpf_lsm,omitempty"`
+}
+
+// The values of Config.PropagationCheck.
+const (
+	// PropagationCheckIgnore disables the check.
+	PropagationCheckIgnore = "ignore"
+	// PropagationCheckWarn logs a warning.
+	PropagationCheckWarn = "warn"
+	// PropagationCheckRepair applies the requested propagation again,
+	// and logs a warning if it is still not the requested one.
+	PropagationCheckRepair = "repair"
+	// PropagationCheckStrict is like PropagationCheckRepair, but fails
+	// the container creation instead of logging a warning.
+	PropagationCheckStrict = "strict"
+)
+
+// The values of Config.ConfigDriftCheck.
+const (
+	// ConfigDriftCheckIgnore disables the check.
+	ConfigDriftCheckIgnore = "ignore"
+	// ConfigDriftCheckWarn logs a warning.
+	ConfigDriftCheckWarn = "warn"
+	// ConfigDriftCheckStrict fails the start or exec.
+	ConfigDriftCheckStrict = "strict"
+)
+
+// CpusetAlloc describes an exclusive CPU allocation for a container.
+type CpusetAlloc struct {
+	// CPUs is the number of CPUs to allocate.
+	CPUs int `json:"cpus"`
+	// Pool is the path to a file with the list of CPUs (in the cpuset
+	// format, e.g. "2-15") to allocate from. If empty, all the online
+	// CPUs are used.
+	Pool string `json:"pool,omitempty"`
+}
+
+// PidsStartLimit describes a temporary pids limit for a container start.
+// The limit is set along with the other cgroup resources when the container
+// is created. Once the container process is started, runc waits for Settle,
+// and then raises the limit to Cgroups.Resources.PidsLimit, unless the
+// limit was hit, in which case the start fails (and the limit is kept).
+type PidsStartLimit struct {
+	// Limit is the pids limit to start with. Note the threads of runc init
+	// are counted, too.
+	Limit int64 `json:"limit"`
+	// Settle is the time for the container to reach a steady state.
+	Settle time.Duration `json:"settle"`
+}
+
+// DiskQuota describes a project quota (supported by ext4 and xfs) limiting
+// the disk usage of a directory tree, such as the container rootfs, or the
+// upper directory of an overlayfs rootfs. The project ID is set on the
+// directory and the files in it when the container is created, and is
+// inherited by the files created later. The limits are removed when the
+// container is destroyed.
+type DiskQuota struct {
+	// Path is the (host) path of the directory.
+	Path string `json:"path"`
+	// ProjectID is the quota project ID, which must not be used by other
+	// directories.
+	ProjectID uint32 `json:"project_id"`
+	// Size is the disk space limit, in bytes (0 means no limit).
+	Size int64 `json:"size,omitempty"`
+	// Inodes is the maximum number of inodes (0 means no limit).
+	Inodes uint64 `json:"inodes,omitempty"`
+}
+
+// Binfmt describes the binfmt_misc handling of a container, such as the
+// emulators of other architectures it relies upon.
+type Binfmt struct {
+	// Private mounts a binfmt_misc instance private to the container user
+	// namespace (Linux 6.7 or later), rather than relying on the host
+	// registrations.
+	Private bool `json:"private,omitempty"`
+	// Register are the registration strings
+	// (":name:type:offset:magic:mask:interpreter:flags", see the kernel
+	// binfmt-misc documentation) to write to the private instance. The
+	// interpreter paths are container paths.
+	Register []string `json:"register,omitempty"`
+	// Require are the names of the binfmt_misc entries which must be
+	// registered, and enabled, for the container to be created: entries of
+	// the private instance, or of the host one. For a host entry without
+	// the F flag, the interpreter must also exist in the container rootfs.
+	Require []string `json:"require,omitempty"`
+}
+
+// BPFLSM describes the BPF LSM programs attached to the container cgroup
+// (cgroup v2 only) while it exists, confining the container processes.
+type BPFLSM struct {
+	// Programs are the paths of the pinned programs, in a bpffs. They must
+	// be BPF_PROG_TYPE_LSM programs, loaded with the BPF_LSM_CGROUP
+	// expected attach type (Linux 6.0 or later).
+	Programs []string `json:"programs"`
 }
 
 // Scheduler is based on the Linux sched_setattr(2) syscall.
@@ -309,7 +503,8 @@ const (
 
 	// StartContainer commands MUST be called as part of the start operation and before
 	// the container process is started.
-	// StartContainer commands are called in the Container namespace.
+	// StartContainer commands are called in the Container namespace, with the
+	// container's root filesystem as the root directory.
 	StartContainer HookName = "startContainer"
 
 	// Poststart commands are executed after the container init process starts.
@@ -319,6 +514,20 @@ const (
 	// Poststop commands are executed after the container init process exits.
 	// Poststop commands are called in the Runtime Namespace.
 	Poststop HookName = "poststop"
+
+	// PreFreeze commands are executed before the container is frozen, when
+	// it is paused or checkpointed, so that the applications can flush
+	// their state. If one fails, the container is not frozen.
+	// PreFreeze commands are called in the Runtime Namespace, or in the
+	// container for the ones with InContainer set.
+	PreFreeze HookName = "preFreeze"
+
+	// PostThaw commands are executed after the container is thawed, when it
+	// is resumed, restored, or left running after a checkpoint, so that the
+	// applications can re-establish their connections.
+	// PostThaw commands are called in the Runtime Namespace, or in the
+	// container for the ones with InContainer set.
+	PostThaw HookName = "postThaw"
 )
 
 // KnownHookNames returns the known hook names.
@@ -394,14 +603,21 @@ func (hooks *Hooks) MarshalJSON() ([]byte, error) {
 		return serializableHooks
 	}
 
-	return json.Marshal(map[string]interface{}{
+	m := map[string]interface{}{
 		"prestart":        serialize((*hooks)[Prestart]),
 		"createRuntime":   serialize((*hooks)[CreateRuntime]),
 		"createContainer": serialize((*hooks)[CreateContainer]),
 		"startContainer":  serialize((*hooks)[StartContainer]),
 		"poststart":       serialize((*hooks)[Poststart]),
 		"poststop":        serialize((*hooks)[Poststop]),
-	})
+	}
+	// The runc specific hooks are only serialized if set.
+	for _, name := range []HookName{PreFreeze, PostThaw} {
+		if h := serialize((*hooks)[name]); len(h) > 0 {
+			m[string(name)] = h
+		}
+	}
+	return json.Marshal(m)
 }
 
 // Run executes all hooks for the given hook name.
@@ -416,11 +632,55 @@ func (hooks Hooks) Run(name HookName, state *specs.State) error {
 	return nil
 }
 
+// RunWithResult executes all hooks for the given hook name, and returns
+// the merged results of the hooks which printed one (see HookResult).
+// The annotations from the result of a hook are also added to the state
+// passed to the following hooks.
+func (hooks Hooks) RunWithResult(name HookName, state *specs.State) (*HookResult, error) {
+	var res *HookResult
+	for i, h := range hooks[name] {
+		rh, ok := h.(ResultHook)
+		if !ok {
+			if err := h.Run(state); err != nil {
+				return nil, fmt.Errorf("error running %s hook #%d: %w", name, i, err)
+			}
+			continue
+		}
+		r, err := rh.RunWithResult(state)
+		if err != nil {
+			return nil, fmt.Errorf("error running %s hook #%d: %w", name, i, err)
+		}
+		if r == nil {
+			continue
+		}
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid result of %s hook #%d: %w", name, i, err)
+		}
+		if len(r.Annotations) > 0 && state.Annotations == nil {
+			state.Annotations = make(map[string]string, len(r.Annotations))
+		}
+		for k, v := range r.Annotations {
+			state.Annotations[k] = v
+		}
+		res = res.merge(r)
+	}
+
+	return res, nil
+}
+
 type Hook interface {
 	// Run executes the hook with the provided state.
 	Run(*specs.State) error
 }
 
+// ResultHook is a hook which can return a result.
+type ResultHook interface {
+	Hook
+	// RunWithResult executes the hook with the provided state, and
+	// returns its result, or nil if the hook did not return one.
+	RunWithResult(*specs.State) (*HookResult, error)
+}
+
 // NewFunctionHook will call the provided function when the hook is run.
 func NewFunctionHook(f func(*specs.State) error) FuncHook {
 	return FuncHook{
@@ -442,6 +702,10 @@ type Command struct {
 	Env     []string       `json:"env"`
 	Dir     string         `json:"dir"`
 	Timeout *time.Duration `json:"timeout"`
+	// InContainer makes the command executed in the container, by
+	// libcontainer, rather than on the host (only for the PreFreeze and
+	// PostThaw hooks).
+	InContainer bool `json:"in_container,omitempty"`
 }
 
 // NewCommandHook will execute the provided command when the hook is run.
@@ -456,9 +720,29 @@ type CommandHook struct {
 }
 
 func (c Command) Run(s *specs.State) error {
+	_, err := c.run(s)
+	return err
+}
+
+// RunWithResult executes the command, and parses its standard output as a
+// hook result (see HookResult). If the command did not print a result, the
+// returned result is nil.
+func (c Command) RunWithResult(s *specs.State) (*HookResult, error) {
+	stdout, err := c.run(s)
+	if err != nil {
+		return nil, err
+	}
+	return parseHookResult(stdout)
+}
+
+// run executes the command, returning its standard output.
+func (c Command) run(s *specs.State) ([]byte, error) {
+	if c.InContainer {
+		return nil, errors.New("the hook must be executed in the container")
+	}
 	b, err := json.Marshal(s)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var stdout, stderr bytes.Buffer
 	cmd := exec.Cmd{
@@ -470,7 +754,7 @@ func (c Command) Run(s *specs.State) error {
 		Stderr: &stderr,
 	}
 	if err := cmd.Start(); err != nil {
-		return err
+		return nil, err
 	}
 	errC := make(chan error, 1)
 	go func() {
@@ -488,10 +772,13 @@ func (c Command) Run(s *specs.State) error {
 	}
 	select {
 	case err := <-errC:
-		return err
+		if err != nil {
+			return nil, err
+		}
+		return stdout.Bytes(), nil
 	case <-timerCh:
 		_ = cmd.Process.Kill()
 		<-errC
-		return fmt.Errorf("hook ran past specified timeout of %.1fs", c.Timeout.Seconds())
+		return nil, fmt.Errorf("hook ran past specified timeout of %.1fs", c.Timeout.Seconds())
 	}
 }
@@ -4,7 +4,7 @@ import (
 	"testing"
 )
 
-var HookNameList = []HookName{Prestart, CreateRuntime, CreateContainer, StartContainer, Poststart, Poststop}
+var HookNameList = []HookName{Prestart, CreateRuntime, CreateContainer, StartContainer, Poststart, Poststop, PreFreeze, PostThaw}
 
 func TestRemoveNamespace(t *testing.T) {
 	ns := Namespaces{
@@ -83,3 +83,51 @@ func TestHostRootGIDWithUSERNS(t *testing.T) {
 		t.Fatalf("expected gid 1000 with no USERNS but received %d", uid)
 	}
 }
+
+func TestHostIDWithManyMappings(t *testing.T) {
+	// More than 5 ranges (the limit before Linux 4.15).
+	mappings := []IDMap{
+		{ContainerID: 0, HostID: 100000, Size: 1000},
+		{ContainerID: 1000, HostID: 1000, Size: 1},
+		{ContainerID: 1002, HostID: 201002, Size: 1},
+		{ContainerID: 2000, HostID: 202000, Size: 100},
+		{ContainerID: 3000, HostID: 203000, Size: 1},
+		{ContainerID: 4000, HostID: 204000, Size: 10},
+		{ContainerID: 65534, HostID: 265534, Size: 1},
+	}
+	config := &Config{
+		Namespaces:  Namespaces{{Type: NEWUSER}},
+		UIDMappings: mappings,
+		GIDMappings: mappings,
+	}
+	for _, tc := range []struct {
+		id, host int
+	}{
+		{0, 100000},
+		{999, 100999},
+		{1000, 1000},
+		{1001, -1},
+		{1002, 201002},
+		{2099, 202099},
+		{2100, -1},
+		{4009, 204009},
+		{65534, 265534},
+		{65535, -1},
+	} {
+		uid, err := config.HostUID(tc.id)
+		gid, gerr := config.HostGID(tc.id)
+		if tc.host == -1 {
+			if err == nil || gerr == nil {
+				t.Errorf("id %d: expected error, got %d, %d", tc.id, uid, gid)
+			}
+			continue
+		}
+		if err != nil || gerr != nil {
+			t.Errorf("id %d: unexpected error: %v, %v", tc.id, err, gerr)
+			continue
+		}
+		if uid != tc.host || gid != tc.host {
+			t.Errorf("id %d: expected %d, got uid %d, gid %d", tc.id, tc.host, uid, gid)
+		}
+	}
+}
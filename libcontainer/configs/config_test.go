@@ -10,6 +10,7 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 )
 
 func TestUnmarshalHooks(t *testing.T) {
@@ -99,6 +100,11 @@ func TestMarshalUnmarshalHooks(t *testing.T) {
 		configs.StartContainer:  configs.HookList{hookCmd},
 		configs.Poststart:       configs.HookList{hookCmd},
 		configs.Poststop:        configs.HookList{hookCmd},
+		configs.PreFreeze:       configs.HookList{hookCmd},
+		configs.PostThaw: configs.HookList{configs.NewCommandHook(configs.Command{
+			Path:        "/bin/reconnect",
+			InContainer: true,
+		})},
 	}
 	hooks, err := hook.MarshalJSON()
 	if err != nil {
@@ -226,3 +232,81 @@ func TestCommandHookRunTimeout(t *testing.T) {
 		t.Error("Expected error to occur but it was nil")
 	}
 }
+
+func TestHooksRunWithResult(t *testing.T) {
+	state := &specs.State{
+		Version: "1",
+		ID:      "1",
+		Status:  "creating",
+		Pid:     1,
+		Bundle:  "/bundle",
+	}
+	echoHook := func(out string) configs.Hook {
+		return configs.NewCommandHook(configs.Command{
+			Path: "/bin/sh",
+			Args: []string{"/bin/sh", "-c", "cat >/dev/null; echo '" + out + "'"},
+		})
+	}
+	hooks := configs.Hooks{
+		configs.CreateRuntime: configs.HookList{
+			// Not a result.
+			echoHook("hello"),
+			// Not a result either (no version).
+			echoHook(`{"env": ["IGNORED=1"]}`),
+			echoHook(`{"hookResultVersion": "1.0.0", "env": ["A=1"], "annotations": {"x": "1", "y": "1"}}`),
+			configs.NewFunctionHook(func(s *specs.State) error {
+				if s.Annotations["x"] != "1" {
+					return fmt.Errorf("annotations from previous hook not in state: %v", s.Annotations)
+				}
+				return nil
+			}),
+			echoHook(`{"hookResultVersion": "1.0.0", "mounts": [{"source": "/src", "destination": "/dst", "options": ["rbind", "ro"]}], "env": ["B=2"], "annotations": {"y": "2"}}`),
+		},
+	}
+	res, err := hooks.RunWithResult(configs.CreateRuntime, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.HookResult{
+		Version:     configs.HookResultVersion,
+		Mounts:      []configs.HookMount{{Source: "/src", Destination: "/dst", Options: []string{"rbind", "ro"}}},
+		Env:         []string{"A=1", "B=2"},
+		Annotations: map[string]string{"x": "1", "y": "2"},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, res)
+	}
+	mounts := res.ToMounts()
+	if len(mounts) != 1 || mounts[0].Device != "bind" || mounts[0].Flags != unix.MS_BIND|unix.MS_REC|unix.MS_RDONLY {
+		t.Fatalf("unexpected mounts: %+v", mounts)
+	}
+
+	// No hooks printing a result.
+	res, err = configs.Hooks{configs.CreateRuntime: configs.HookList{echoHook("")}}.RunWithResult(configs.CreateRuntime, state)
+	if err != nil || res != nil {
+		t.Fatalf("expected nil result and error, got %+v, %v", res, err)
+	}
+}
+
+func TestHooksRunWithResultInvalid(t *testing.T) {
+	for _, out := range []string{
+		`{"hookResultVersion": "0.1"}`,
+		`{"hookResultVersion": "1.0.0", "env": ["NOVALUE"]}`,
+		`{"hookResultVersion": "1.0.0", "mounts": [{"source": "rel", "destination": "/dst"}]}`,
+		`{"hookResultVersion": "1.0.0", "mounts": [{"source": "/src", "destination": "dst"}]}`,
+		`{"hookResultVersion": "1.0.0", "mounts": [{"source": "/src", "destination": "/dst", "options": ["suid"]}]}`,
+		`{"hookResultVersion": "1.0.0", "annotations": {"bundle": "/x"}}`,
+	} {
+		hooks := configs.Hooks{
+			configs.CreateRuntime: configs.HookList{
+				configs.NewCommandHook(configs.Command{
+					Path: "/bin/sh",
+					Args: []string{"/bin/sh", "-c", "cat >/dev/null; echo '" + out + "'"},
+				}),
+			},
+		}
+		if _, err := hooks.RunWithResult(configs.CreateRuntime, &specs.State{}); err == nil {
+			t.Errorf("%s: expected error, got nil", out)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package configs
+
+// EtcFiles configures the /etc/hostname, /etc/hosts and /etc/resolv.conf
+// files generated by runc for the container. They are written to the
+// container state directory, and bind mounted over the ones of the root
+// filesystem, which is left untouched (and can be read-only).
+type EtcFiles struct {
+	// Hostname, Hosts and ResolvConf select the files to generate.
+	Hostname   bool `json:"hostname,omitempty"`
+	Hosts      bool `json:"hosts,omitempty"`
+	ResolvConf bool `json:"resolv_conf,omitempty"`
+
+	// ExtraHosts are added to /etc/hosts.
+	ExtraHosts []*EtcHost `json:"extra_hosts,omitempty"`
+
+	// Nameservers, Search and Options are the DNS servers, search domains
+	// and resolver options of /etc/resolv.conf. If no nameservers are
+	// set, the ones of the static network configuration are used, and
+	// if there are none either, the host /etc/resolv.conf is used as a
+	// base.
+	Nameservers []string `json:"nameservers,omitempty"`
+	Search      []string `json:"search,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// EtcHost is an entry of /etc/hosts.
+type EtcHost struct {
+	Address string   `json:"address"`
+	Names   []string `json:"names"`
+}
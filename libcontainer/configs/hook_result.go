@@ -0,0 +1,158 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// HookResultVersion is the version of the hook result format supported.
+const HookResultVersion = "1.0.0"
+
+// HookResult is the result a createRuntime hook can print to its standard
+// output, as a JSON object, to modify the container being created (similar
+// to the result of a CNI plugin).
+//
+// For backward compatibility with hooks which print arbitrary data, the
+// standard output of a hook is only treated as a result if it is a JSON
+// object with the hookResultVersion field set.
+type HookResult struct {
+	// Version is the version of the result format (HookResultVersion).
+	Version string `json:"hookResultVersion"`
+
+	// Mounts are the additional bind mounts to set up in the container.
+	Mounts []HookMount `json:"mounts,omitempty"`
+
+	// Env are the environment variables, in the KEY=value form, to add to
+	// the environment of the container process.
+	Env []string `json:"env,omitempty"`
+
+	// Annotations are the annotations to add to the container state.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// HookMount is a bind mount requested by a hook.
+type HookMount struct {
+	// Source is the absolute path of the mount source on the host.
+	Source string `json:"source"`
+	// Destination is the absolute path of the mount point in the container.
+	Destination string `json:"destination"`
+	// Options are the mount options. Supported options are "rbind",
+	// "ro", "rw", "nosuid", "nodev" and "noexec".
+	Options []string `json:"options,omitempty"`
+}
+
+var hookMountFlags = map[string]struct {
+	clear bool
+	flag  int
+}{
+	"rbind":  {false, unix.MS_REC},
+	"ro":     {false, unix.MS_RDONLY},
+	"rw":     {true, unix.MS_RDONLY},
+	"nosuid": {false, unix.MS_NOSUID},
+	"nodev":  {false, unix.MS_NODEV},
+	"noexec": {false, unix.MS_NOEXEC},
+}
+
+// parseHookResult parses the standard output of a hook. It returns nil if
+// the output is not a hook result.
+func parseHookResult(stdout []byte) (*HookResult, error) {
+	stdout = bytes.TrimSpace(stdout)
+	if len(stdout) == 0 || stdout[0] != '{' {
+		return nil, nil
+	}
+	var r HookResult
+	if err := json.Unmarshal(stdout, &r); err != nil {
+		logrus.Debugf("hook output is not a hook result: %v", err)
+		return nil, nil
+	}
+	if r.Version == "" {
+		return nil, nil
+	}
+	if r.Version != HookResultVersion {
+		return nil, fmt.Errorf("unsupported hook result version %q (supported: %q)", r.Version, HookResultVersion)
+	}
+	return &r, nil
+}
+
+// Validate checks the result is well-formed.
+func (r *HookResult) Validate() error {
+	for _, m := range r.Mounts {
+		if !filepath.IsAbs(m.Source) {
+			return fmt.Errorf("mount source %q is not an absolute path", m.Source)
+		}
+		if !filepath.IsAbs(m.Destination) {
+			return fmt.Errorf("mount destination %q is not an absolute path", m.Destination)
+		}
+		if strings.IndexByte(m.Source, 0) >= 0 || strings.IndexByte(m.Destination, 0) >= 0 {
+			return errors.New("mount field contains null byte")
+		}
+		for _, o := range m.Options {
+			if _, ok := hookMountFlags[o]; !ok {
+				return fmt.Errorf("mount %q: unsupported option %q", m.Destination, o)
+			}
+		}
+	}
+	for _, e := range r.Env {
+		k, _, ok := strings.Cut(e, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("invalid environment variable %q", e)
+		}
+	}
+	for k := range r.Annotations {
+		if k == "" {
+			return errors.New("empty annotation key")
+		}
+		if k == "bundle" {
+			return errors.New("annotation \"bundle\" is reserved")
+		}
+	}
+	return nil
+}
+
+// merge returns the result of appending the mounts and environment of
+// other to r, and adding the annotations of other to the ones of r.
+// r can be nil.
+func (r *HookResult) merge(other *HookResult) *HookResult {
+	if r == nil {
+		r = &HookResult{Version: HookResultVersion}
+	}
+	r.Mounts = append(r.Mounts, other.Mounts...)
+	r.Env = append(r.Env, other.Env...)
+	if len(other.Annotations) > 0 && r.Annotations == nil {
+		r.Annotations = make(map[string]string, len(other.Annotations))
+	}
+	for k, v := range other.Annotations {
+		r.Annotations[k] = v
+	}
+	return r
+}
+
+// ToMounts converts the mounts of a validated result to container mounts.
+func (r *HookResult) ToMounts() []*Mount {
+	mounts := make([]*Mount, 0, len(r.Mounts))
+	for _, m := range r.Mounts {
+		mnt := &Mount{
+			Source:      m.Source,
+			Destination: m.Destination,
+			Device:      "bind",
+			Flags:       unix.MS_BIND,
+		}
+		for _, o := range m.Options {
+			f := hookMountFlags[o]
+			if f.clear {
+				mnt.Flags &= ^f.flag
+			} else {
+				mnt.Flags |= f.flag
+			}
+		}
+		mounts = append(mounts, mnt)
+	}
+	return mounts
+}
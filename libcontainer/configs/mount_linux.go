@@ -1,9 +1,21 @@
 package configs
 
-import "golang.org/x/sys/unix"
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// SourceFdPrefix is the prefix of a bind mount source which is a file
+// descriptor (see [Mount.SourceFd]) rather than a path.
+const SourceFdPrefix = "fd:"
 
 type Mount struct {
-	// Source path for the mount.
+	// Source path for the mount. For a bind mount, this can also be a file
+	// descriptor passed to the container, in the "fd:N" form (see
+	// [Mount.SourceFd]).
 	Source string `json:"source"`
 
 	// Destination path for the mount inside the container.
@@ -54,3 +66,42 @@ func (m *Mount) IsBind() bool {
 func (m *Mount) IsIDMapped() bool {
 	return len(m.UIDMappings) > 0 || len(m.GIDMappings) > 0
 }
+
+// IsSourceFd reports whether the mount source is a file descriptor, in the
+// "fd:N" form.
+func (m *Mount) IsSourceFd() bool {
+	return strings.HasPrefix(m.Source, SourceFdPrefix)
+}
+
+// SourceFd returns the file descriptor the mount source refers to. This is
+// one of the file descriptors passed to the container init process (its
+// ExtraFiles, or runc --preserve-fds), which is either a detached mount tree
+// (as returned by open_tree(2) with OPEN_TREE_CLONE, or by fsmount(2)),
+// attached as is, or any other file or directory, which is bind mounted.
+// This allows the caller to open the mount sources beforehand, in a
+// different mount namespace for example. The file descriptor is closed once
+// mounted, so that it does not leak into the container.
+func (m *Mount) SourceFd() (int, error) {
+	if !m.IsSourceFd() {
+		return -1, errors.New("mount source is not a file descriptor")
+	}
+	fd, err := strconv.Atoi(strings.TrimPrefix(m.Source, SourceFdPrefix))
+	if err != nil || fd < 0 {
+		return -1, errors.New("invalid mount source " + strconv.Quote(m.Source) + ": must be fd:N, N being a file descriptor number")
+	}
+	return fd, nil
+}
+
+// SetDataOption sets the key option of the mount data to value, replacing
+// the current value, if any.
+func (m *Mount) SetDataOption(key, value string) {
+	var opts []string
+	if m.Data != "" {
+		for _, o := range strings.Split(m.Data, ",") {
+			if k, _, _ := strings.Cut(o, "="); k != key {
+				opts = append(opts, o)
+			}
+		}
+	}
+	m.Data = strings.Join(append(opts, key+"="+value), ",")
+}
@@ -0,0 +1,21 @@
+package configs
+
+import "testing"
+
+func TestMountSetDataOption(t *testing.T) {
+	for _, tc := range []struct {
+		data, key, value, expected string
+	}{
+		{data: "", key: "size", value: "1024", expected: "size=1024"},
+		{data: "mode=1777", key: "size", value: "1024", expected: "mode=1777,size=1024"},
+		{data: "size=64k,mode=1777", key: "size", value: "1024", expected: "mode=1777,size=1024"},
+		{data: "mode=1777,nr_inodes=10", key: "size", value: "1m", expected: "mode=1777,nr_inodes=10,size=1m"},
+		{data: "sizex=1,size", key: "size", value: "2", expected: "sizex=1,size=2"},
+	} {
+		m := &Mount{Data: tc.data}
+		m.SetDataOption(tc.key, tc.value)
+		if m.Data != tc.expected {
+			t.Errorf("%q, set %s=%s: expected %q, got %q", tc.data, tc.key, tc.value, tc.expected, m.Data)
+		}
+	}
+}
@@ -48,6 +48,14 @@ type Network struct {
 	// Note: This is unsupported on some systems.
 	// Note: This does not apply to loopback interfaces.
 	HairpinMode bool `json:"hairpin_mode"`
+
+	// Parent is the name of the host interface to create the interface on,
+	// for the macvlan and ipvlan types.
+	Parent string `json:"parent,omitempty"`
+
+	// Mode is the macvlan mode (bridge, private, vepa or passthru), or the
+	// ipvlan mode (l2, l3 or l3s).
+	Mode string `json:"mode,omitempty"`
 }
 
 // Route defines a routing table entry.
@@ -73,3 +81,59 @@ type Route struct {
 	// InterfaceName specifies the device to set this route up for, for example eth0.
 	InterfaceName string `json:"interface_name"`
 }
+
+// CNI defines the CNI network the container is attached to.
+type CNI struct {
+	// Network is the name of the CNI network (network configuration list).
+	Network string `json:"network"`
+
+	// ConfDir is the directory the network configuration is read from.
+	ConfDir string `json:"conf_dir,omitempty"`
+
+	// BinDir is the list of directories (separated by colons) the CNI
+	// plugins are searched in.
+	BinDir string `json:"bin_dir,omitempty"`
+
+	// IfName is the name of the network interface created in the container.
+	IfName string `json:"ifname,omitempty"`
+}
+
+// StaticNetwork is a static IP configuration of the container network
+// namespace, applied by runc before the container process is started.
+type StaticNetwork struct {
+	// Addresses are the IP addresses to assign to the interfaces.
+	Addresses []*StaticAddress `json:"addresses,omitempty"`
+
+	// Routes are the routes to add.
+	Routes []*StaticRoute `json:"routes,omitempty"`
+
+	// Nameservers and Search are the DNS servers and search domains to
+	// write to /etc/resolv.conf in the container, if ResolvConf is set.
+	Nameservers []string `json:"nameservers,omitempty"`
+	Search      []string `json:"search,omitempty"`
+	ResolvConf  bool     `json:"resolv_conf,omitempty"`
+}
+
+// StaticAddress is an IP address assigned to an interface.
+type StaticAddress struct {
+	// InterfaceName is the name of the interface, for example eth0.
+	InterfaceName string `json:"interface_name"`
+
+	// Address is the IP address and mask in the CIDR form.
+	Address string `json:"address"`
+}
+
+// StaticRoute is a route in the main routing table.
+type StaticRoute struct {
+	// Destination is the destination in the CIDR form, or empty for the
+	// default route.
+	Destination string `json:"destination,omitempty"`
+
+	// Gateway is the gateway IP address. It can be empty for a route
+	// to a directly connected network.
+	Gateway string `json:"gateway,omitempty"`
+
+	// InterfaceName is the name of the interface to route through. It can
+	// be empty if the gateway is set.
+	InterfaceName string `json:"interface_name,omitempty"`
+}
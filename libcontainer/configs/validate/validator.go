@@ -3,14 +3,18 @@ package validate
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 
+	"github.com/opencontainers/runc/libcontainer/binfmt"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/lsm"
+	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	selinux "github.com/opencontainers/selinux/go-selinux"
 	"github.com/sirupsen/logrus"
@@ -23,8 +27,19 @@ type check func(config *configs.Config) error
 func Validate(config *configs.Config) error {
 	checks := []check{
 		cgroupsCheck,
+		cpusetAlloc,
+		housekeepingCgroup,
+		pidsStartLimit,
+		propagationCheck,
+		configDriftCheck,
+		initPath,
+		tmpfsSizes,
+		diskQuota,
+		binfmtCheck,
+		bpfLSMCheck,
 		rootfs,
 		network,
+		etcFiles,
 		uts,
 		security,
 		namespaces,
@@ -32,6 +47,7 @@ func Validate(config *configs.Config) error {
 		intelrdtCheck,
 		rootlessEUIDCheck,
 		mountsStrict,
+		allowedMountSources,
 		scheduler,
 	}
 	
@@ -46,6 +62,7 @@ func Validate(config *configs.Config) error {
 	// Relaxed validation rules for backward compatibility
 	warns := []check{
 		mountsWarn,
+		cpuPeriodWarn,
 	}
 	for _, c := range warns {
 		if err := c(config); err != nil {
@@ -80,6 +97,198 @@ func network(config *configs.Config) error {
 			return errors.New("unable to apply network settings without a private NET namespace")
 		}
 	}
+	if config.CNI != nil {
+		if config.CNI.Network == "" {
+			return errors.New("cni: network name not specified")
+		}
+		// The CNI DEL run at container destroy removes the network
+		// configuration, so the network namespace must be the container's.
+		if !config.Namespaces.Contains(configs.NEWNET) || config.Namespaces.PathOf(configs.NEWNET) != "" {
+			return errors.New("cni: a new network namespace is required")
+		}
+	}
+	names := make(map[string]bool)
+	for _, n := range config.Networks {
+		if n.Type != "netdev" && n.Type != "macvlan" && n.Type != "ipvlan" {
+			continue
+		}
+		name := n.Name
+		if n.Type == "netdev" {
+			if n.HostInterfaceName == "" {
+				return errors.New("netdev: host interface name not specified")
+			}
+			if name == "" {
+				name = n.HostInterfaceName
+			}
+		} else if err := subInterface(n); err != nil {
+			return fmt.Errorf("%s: %w", n.Type, err)
+		}
+		if names[name] {
+			return fmt.Errorf("%s: duplicate interface name %q", n.Type, name)
+		}
+		names[name] = true
+		// The interfaces are cleaned up (or returned to the host) from the
+		// container network namespace, which has to be the container's own.
+		if config.Namespaces.PathOf(configs.NEWNET) != "" {
+			return fmt.Errorf("%s: a new network namespace is required", n.Type)
+		}
+		if config.RootlessEUID {
+			return fmt.Errorf("%s: not supported for rootless containers", n.Type)
+		}
+	}
+	if config.StaticNetwork != nil {
+		if err := staticNetwork(config); err != nil {
+			return fmt.Errorf("static network: %w", err)
+		}
+	}
+	return nil
+}
+
+// subInterface validates the configuration of a macvlan or ipvlan interface.
+func subInterface(n *configs.Network) error {
+	if n.Parent == "" {
+		return errors.New("parent interface not specified")
+	}
+	if n.Name == "" {
+		return errors.New("interface name not specified")
+	}
+	modes := map[string][]string{
+		"macvlan": {"", "bridge", "private", "vepa", "passthru"},
+		"ipvlan":  {"", "l2", "l3", "l3s"},
+	}
+	validMode := false
+	for _, m := range modes[n.Type] {
+		if n.Mode == m {
+			validMode = true
+			break
+		}
+	}
+	if !validMode {
+		return fmt.Errorf("invalid mode %q", n.Mode)
+	}
+	if n.MacAddress != "" {
+		if n.Type == "ipvlan" {
+			return errors.New("MAC address can not be set (ipvlan interfaces use the parent one)")
+		}
+		if _, err := net.ParseMAC(n.MacAddress); err != nil {
+			return err
+		}
+	}
+	for _, a := range []string{n.Address, n.IPv6Address} {
+		if a == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(a); err != nil {
+			return err
+		}
+	}
+	for _, gw := range []string{n.Gateway, n.IPv6Gateway} {
+		if gw != "" && net.ParseIP(gw) == nil {
+			return fmt.Errorf("invalid gateway %q", gw)
+		}
+	}
+	return nil
+}
+
+func staticNetwork(config *configs.Config) error {
+	if !config.Namespaces.Contains(configs.NEWNET) || config.Namespaces.PathOf(configs.NEWNET) != "" {
+		return errors.New("a new network namespace is required")
+	}
+	n := config.StaticNetwork
+	for _, a := range n.Addresses {
+		if a.InterfaceName == "" {
+			return fmt.Errorf("address %s: interface not specified", a.Address)
+		}
+		if _, _, err := net.ParseCIDR(a.Address); err != nil {
+			return err
+		}
+	}
+	for _, r := range n.Routes {
+		if r.Destination != "" {
+			if _, _, err := net.ParseCIDR(r.Destination); err != nil {
+				return err
+			}
+		}
+		if r.Gateway == "" {
+			if r.InterfaceName == "" {
+				return fmt.Errorf("route to %q: neither gateway nor interface specified", r.Destination)
+			}
+		} else if net.ParseIP(r.Gateway) == nil {
+			return fmt.Errorf("route to %q: invalid gateway %q", r.Destination, r.Gateway)
+		}
+	}
+	for _, ns := range n.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("invalid nameserver %q", ns)
+		}
+	}
+	if n.ResolvConf {
+		if !config.Namespaces.Contains(configs.NEWNS) {
+			return errors.New("unable to write resolv.conf without a private MNT namespace")
+		}
+		// Writing to a bind-mounted file would change the mount source.
+		for _, m := range config.Mounts {
+			if filepath.Clean(m.Destination) == "/etc/resolv.conf" {
+				return errors.New("unable to write resolv.conf, as it is a mount")
+			}
+		}
+	} else if len(n.Nameservers) > 0 || len(n.Search) > 0 {
+		logrus.Warn("static network: nameservers are ignored, as resolvConf is not set")
+	}
+	return nil
+}
+
+// etcFiles validates the configuration of the generated /etc files.
+func etcFiles(config *configs.Config) error {
+	e := config.EtcFiles
+	if e == nil {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNS) {
+		return errors.New("etc files: a private MNT namespace is required")
+	}
+	files := map[string]bool{
+		"/etc/hostname":    e.Hostname,
+		"/etc/hosts":       e.Hosts,
+		"/etc/resolv.conf": e.ResolvConf,
+	}
+	for _, m := range config.Mounts {
+		if files[filepath.Clean(m.Destination)] {
+			return fmt.Errorf("etc files: %s is already a mount", m.Destination)
+		}
+	}
+	if e.ResolvConf && config.StaticNetwork != nil && config.StaticNetwork.ResolvConf {
+		return errors.New("etc files: resolv.conf is also written by the static network configuration")
+	}
+	for _, h := range e.ExtraHosts {
+		if net.ParseIP(h.Address) == nil {
+			return fmt.Errorf("etc files: invalid host address %q", h.Address)
+		}
+		if len(h.Names) == 0 {
+			return fmt.Errorf("etc files: no names for host address %s", h.Address)
+		}
+		for _, name := range h.Names {
+			if name == "" || strings.ContainsAny(name, " \t\n#") {
+				return fmt.Errorf("etc files: invalid host name %q", name)
+			}
+		}
+	}
+	for _, ns := range e.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("etc files: invalid nameserver %q", ns)
+		}
+	}
+	for _, v := range append(e.Search, e.Options...) {
+		if v == "" || strings.ContainsAny(v, " \t\n#") {
+			return fmt.Errorf("etc files: invalid resolv.conf search domain or option %q", v)
+		}
+	}
+	if !e.Hosts && len(e.ExtraHosts) > 0 {
+		logrus.Warn("etc files: extra hosts are ignored, as hosts is not set")
+	}
+	if !e.ResolvConf && len(e.Nameservers)+len(e.Search)+len(e.Options) > 0 {
+		logrus.Warn("etc files: nameservers, search domains and options are ignored, as resolvConf is not set")
+	}
 	return nil
 }
 
@@ -102,6 +311,27 @@ func security(config *configs.Config) error {
 	if config.ProcessLabel != "" && !selinux.GetEnabled() {
 		return errors.New("selinux label is specified in config, but selinux is disabled or not supported")
 	}
+	if config.ProcessLabel != "" {
+		// go-selinux uses the legacy process attributes.
+		if owner := lsm.AttrOwner(); owner != "" && owner != "selinux" {
+			return fmt.Errorf("selinux label is specified in config, but the process attributes belong to %s in the LSM stack", owner)
+		}
+	}
+	if config.AppArmorProfile != "" {
+		if active, known := lsm.IsActive("apparmor"); known && !active {
+			stack, _ := lsm.Stack()
+			return fmt.Errorf("apparmor profile is specified in config, but apparmor is not in the LSM stack (%s)", strings.Join(stack, ","))
+		}
+	}
+	if config.UnconfinedStartHooks && config.Seccomp != nil {
+		// The seccomp filter is loaded after the start hooks are run,
+		// when there is no way to pass the listener fd to runc.
+		for _, call := range config.Seccomp.Syscalls {
+			if call.Action == configs.Notify {
+				return errors.New("unconfined start hooks can not be used with seccomp notify actions")
+			}
+		}
+	}
 
 	return nil
 }
@@ -274,6 +504,24 @@ func cgroupsCheck(config *configs.Config) error {
 		return fmt.Errorf("cgroup: either Path or Name and Parent should be used, got %+v", c)
 	}
 
+	if c.Adopted {
+		if c.Path == "" {
+			return errors.New("cgroup: adopted cgroup requires Path")
+		}
+		if c.Systemd {
+			return errors.New("cgroup: adopted cgroup can not be managed by systemd")
+		}
+	}
+
+	if c.DirFd != 0 {
+		if !cgroups.IsCgroup2UnifiedMode() {
+			return errors.New("cgroup: directory fd requires cgroup v2")
+		}
+		if c.Systemd || c.Path == "" {
+			return errors.New("cgroup: directory fd requires Path and no systemd")
+		}
+	}
+
 	r := c.Resources
 	if r == nil {
 		return nil
@@ -284,12 +532,318 @@ func cgroupsCheck(config *configs.Config) error {
 	}
 
 	if cgroups.IsCgroup2UnifiedMode() {
-		_, err := cgroups.ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory)
+		_, err := cgroups.SwapLimitV2(r.MemorySwap, r.Memory, r.SwapMax)
+		if err != nil {
+			return err
+		}
+	}
+	if r.SwapMax != nil {
+		if err := swapMax(r); err != nil {
+			return err
+		}
+	}
+
+	if r.CpusetCpus != "" {
+		if err := cpusetCpus(r.CpusetCpus, config.CpusetAdjust); err != nil {
+			return err
+		}
+	}
+
+	if err := blkioDevicePaths(r); err != nil {
+		return err
+	}
+
+	return cgroups.CheckCPUBandwidth(r)
+}
+
+// cpuPeriodWarn warns about a CFS period short enough to cause a lot of
+// throttling overhead.
+func cpuPeriodWarn(config *configs.Config) error {
+	if config.Cgroups == nil || config.Cgroups.Resources == nil {
+		return nil
+	}
+	if p := config.Cgroups.Resources.CpuPeriod; p != 0 && p < cgroups.CPUPeriodShort {
+		return fmt.Errorf("cpu period %dus is short: the container may be throttled very often, with a high scheduling overhead", p)
+	}
+	return nil
+}
+
+// swapMax checks the combination of the swap limit with the other memory
+// settings (the cgroup v2 conversion is checked by cgroups.SwapLimitV2).
+func swapMax(r *configs.Resources) error {
+	if r.MemorySwap != 0 {
+		return errors.New("swap limit can not be set together with memory+swap limit")
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		if _, err := cgroups.ConvertSwapMaxToCgroupV1Value(*r.SwapMax, r.Memory); err != nil {
+			return err
+		}
+	}
+	// Swappiness of 0 means the container memory is not to be swapped out.
+	if r.MemorySwappiness != nil && *r.MemorySwappiness == 0 && *r.SwapMax != 0 {
+		return fmt.Errorf("swap limit %d contradicts memory swappiness 0", *r.SwapMax)
+	}
+	return nil
+}
+
+// blkioDevicePaths checks the paths of the block IO devices given by path
+// (the devices themselves are resolved when the limits are set).
+func blkioDevicePaths(r *configs.Resources) error {
+	devs := make([]*configs.BlockIODevice, 0, len(r.BlkioWeightDevice))
+	for _, wd := range r.BlkioWeightDevice {
+		devs = append(devs, &wd.BlockIODevice)
+	}
+	for _, list := range [][]*configs.ThrottleDevice{
+		r.BlkioThrottleReadBpsDevice,
+		r.BlkioThrottleWriteBpsDevice,
+		r.BlkioThrottleReadIOPSDevice,
+		r.BlkioThrottleWriteIOPSDevice,
+	} {
+		for _, td := range list {
+			devs = append(devs, &td.BlockIODevice)
+		}
+	}
+	for _, d := range devs {
+		if d.Path != "" && !filepath.IsAbs(d.Path) {
+			return fmt.Errorf("block IO device path %q is not absolute", d.Path)
+		}
+	}
+	return nil
+}
+
+func cpusetAlloc(config *configs.Config) error {
+	a := config.CpusetAlloc
+	if a == nil {
+		return nil
+	}
+	if config.Cgroups == nil {
+		return errors.New("cpuset allocation: cgroups not configured")
+	}
+	if a.CPUs <= 0 {
+		return fmt.Errorf("cpuset allocation: invalid number of CPUs %d", a.CPUs)
+	}
+	if r := config.Cgroups.Resources; r != nil && r.CpusetCpus != "" {
+		return errors.New("cpuset allocation: cpuset.cpus can not be set")
+	}
+	if a.Pool != "" && !filepath.IsAbs(a.Pool) {
+		return fmt.Errorf("cpuset allocation: pool %q is not an absolute path", a.Pool)
+	}
+	return nil
+}
+
+func housekeepingCgroup(config *configs.Config) error {
+	path := config.HousekeepingCgroup
+	if path == "" {
+		return nil
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("housekeeping cgroup requires cgroup v2")
+	}
+	if config.Cgroups == nil || config.Cgroups.Systemd {
+		return errors.New("housekeeping cgroup requires the cgroupfs driver")
+	}
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path || path == "/" {
+		return fmt.Errorf("housekeeping cgroup %q is not a clean absolute path other than /", path)
+	}
+	return nil
+}
+
+func pidsStartLimit(config *configs.Config) error {
+	l := config.PidsStartLimit
+	if l == nil {
+		return nil
+	}
+	if config.Cgroups == nil {
+		return errors.New("pids start limit: cgroups not configured")
+	}
+	if l.Limit <= 0 {
+		return fmt.Errorf("pids start limit: invalid limit %d", l.Limit)
+	}
+	if l.Settle <= 0 {
+		return fmt.Errorf("pids start limit: invalid settle time %s", l.Settle)
+	}
+	if r := config.Cgroups.Resources; r != nil && r.PidsLimit > 0 && r.PidsLimit <= l.Limit {
+		return fmt.Errorf("pids start limit %d is not lower than the pids limit %d", l.Limit, r.PidsLimit)
+	}
+	return nil
+}
+
+func tmpfsSizes(config *configs.Config) error {
+	if config.ShmSize < 0 {
+		return fmt.Errorf("invalid /dev/shm size %d", config.ShmSize)
+	}
+	if config.TmpSize < 0 {
+		return fmt.Errorf("invalid /tmp size %d", config.TmpSize)
+	}
+	for _, t := range []struct {
+		dest string
+		set  bool
+	}{
+		{"/dev/shm", config.ShmSize != 0},
+		{"/tmp", config.TmpSize != 0 || config.TmpInodes != 0},
+	} {
+		if !t.set {
+			continue
+		}
+		var found bool
+		for _, m := range config.Mounts {
+			if m.Device == "tmpfs" && utils.CleanPath(m.Destination) == t.dest {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s size is set, but there is no %s tmpfs mount", t.dest, t.dest)
+		}
+	}
+	return nil
+}
+
+func diskQuota(config *configs.Config) error {
+	q := config.DiskQuota
+	if q == nil {
+		return nil
+	}
+	if !filepath.IsAbs(q.Path) {
+		return fmt.Errorf("disk quota path %q is not absolute", q.Path)
+	}
+	if q.ProjectID == 0 {
+		return errors.New("disk quota project ID must not be 0")
+	}
+	if q.Size < 0 {
+		return fmt.Errorf("invalid disk quota size %d", q.Size)
+	}
+	if q.Size == 0 && q.Inodes == 0 {
+		return errors.New("disk quota requires a size or an inodes limit")
+	}
+	if config.RootlessEUID {
+		return errors.New("disk quota requires root")
+	}
+	return nil
+}
+
+func binfmtCheck(config *configs.Config) error {
+	b := config.Binfmt
+	if b == nil {
+		return nil
+	}
+	if b.Private {
+		if !config.Namespaces.Contains(configs.NEWUSER) || !config.Namespaces.Contains(configs.NEWNS) {
+			return errors.New("private binfmt_misc instance requires user and mount namespaces")
+		}
+	} else if len(b.Register) > 0 {
+		return errors.New("binfmt_misc registrations require a private binfmt_misc instance")
+	}
+	for _, r := range b.Register {
+		e, err := binfmt.ParseRegister(r)
 		if err != nil {
 			return err
 		}
+		if !filepath.IsAbs(e.Interpreter) {
+			return fmt.Errorf("binfmt_misc entry %s: interpreter %q is not absolute", e.Name, e.Interpreter)
+		}
 	}
+	for _, name := range b.Require {
+		if err := binfmt.CheckName(name); err != nil {
+			return fmt.Errorf("required binfmt_misc entry: %w", err)
+		}
+	}
+	return nil
+}
 
+func bpfLSMCheck(config *configs.Config) error {
+	b := config.BPFLSM
+	if b == nil {
+		return nil
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("bpf lsm programs require cgroup v2")
+	}
+	if active, known := lsm.IsActive("bpf"); known && !active {
+		return errors.New("bpf lsm programs are specified in config, but the bpf lsm is not active (add it to the lsm= boot parameter)")
+	}
+	if len(b.Programs) == 0 {
+		return errors.New("bpf lsm: no programs")
+	}
+	seen := make(map[string]bool, len(b.Programs))
+	for _, path := range b.Programs {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("bpf lsm program path %q is not absolute", path)
+		}
+		if seen[path] {
+			return fmt.Errorf("bpf lsm program %s is specified twice", path)
+		}
+		seen[path] = true
+	}
+	return nil
+}
+
+func propagationCheck(config *configs.Config) error {
+	switch config.PropagationCheck {
+	case "", configs.PropagationCheckIgnore, configs.PropagationCheckWarn, configs.PropagationCheckRepair, configs.PropagationCheckStrict:
+		return nil
+	}
+	return fmt.Errorf("invalid propagation check %q", config.PropagationCheck)
+}
+
+func configDriftCheck(config *configs.Config) error {
+	switch config.ConfigDriftCheck {
+	case "", configs.ConfigDriftCheckIgnore, configs.ConfigDriftCheckWarn, configs.ConfigDriftCheckStrict:
+		return nil
+	}
+	return fmt.Errorf("invalid config drift check %q", config.ConfigDriftCheck)
+}
+
+func initPath(config *configs.Config) error {
+	if config.InitPath == "" {
+		return nil
+	}
+	if !filepath.IsAbs(config.InitPath) {
+		return fmt.Errorf("init path %q is not absolute", config.InitPath)
+	}
+	fi, err := os.Stat(config.InitPath)
+	if err != nil {
+		return fmt.Errorf("invalid init path: %w", err)
+	}
+	if !fi.Mode().IsRegular() || fi.Mode()&0o111 == 0 {
+		return fmt.Errorf("init path %q is not an executable file", config.InitPath)
+	}
+	return nil
+}
+
+// cpusetCpus checks that the requested CPUs are online or, if adjust is
+// set, that at least one of them is.
+func cpusetCpus(cpus string, adjust bool) error {
+	requested, err := cgroups.ParseCPUList(cpus)
+	if err != nil {
+		return fmt.Errorf("invalid cpuset.cpus: %w", err)
+	}
+	online, err := cgroups.OnlineCPUs()
+	if err != nil {
+		logrus.Debugf("unable to check cpuset.cpus against online CPUs: %v", err)
+		return nil
+	}
+	avail := cgroups.IntersectCPUList(requested, online)
+	if len(avail) == len(requested) {
+		return nil
+	}
+	if !adjust {
+		isOnline := make(map[uint16]bool, len(avail))
+		for _, c := range avail {
+			isOnline[c] = true
+		}
+		var offline []uint16
+		for _, c := range requested {
+			if !isOnline[c] {
+				offline = append(offline, c)
+			}
+		}
+		return fmt.Errorf("cpuset.cpus: CPU(s) %s are not online (online CPUs: %s)",
+			cgroups.FormatCPUList(offline), cgroups.FormatCPUList(online))
+	}
+	if len(avail) == 0 {
+		return fmt.Errorf("cpuset.cpus: none of the requested CPUs (%s) are online (online CPUs: %s)",
+			cpus, cgroups.FormatCPUList(online))
+	}
 	return nil
 }
 
@@ -340,6 +894,68 @@ func checkIDMapMounts(config *configs.Config, m *configs.Mount) error {
 	return nil
 }
 
+// checkSourceFdMounts checks the mounts with a file descriptor as a source
+// (see [configs.Mount.SourceFd]).
+func checkSourceFdMounts(config *configs.Config) error {
+	seen := make(map[int]bool)
+	for _, m := range config.Mounts {
+		if !m.IsSourceFd() {
+			continue
+		}
+		fd, err := m.SourceFd()
+		if err != nil {
+			return err
+		}
+		if !m.IsBind() {
+			return fmt.Errorf("mount source %s: file descriptors are only supported as bind mount sources", m.Source)
+		}
+		if m.IsIDMapped() {
+			return fmt.Errorf("mount source %s: file descriptors are not supported as idmapped mount sources", m.Source)
+		}
+		if fd < 3 {
+			return fmt.Errorf("mount source %s: stdio can not be a mount source (preserved file descriptors start from 3)", m.Source)
+		}
+		// Attaching a detached mount tree moves it, so it can only be
+		// done once, and moving an attached mount must never happen
+		// outside of the container mount namespace.
+		if seen[fd] {
+			return fmt.Errorf("mount source %s: file descriptor used by several mounts", m.Source)
+		}
+		seen[fd] = true
+		if !config.Namespaces.Contains(configs.NEWNS) {
+			return fmt.Errorf("mount source %s: file descriptors as mount sources require a new mount namespace", m.Source)
+		}
+	}
+	return nil
+}
+
+// checkVirtualFsMount checks a virtiofs or 9p mount of a directory shared by
+// the VM host, the source of which is a tag rather than a path.
+func checkVirtualFsMount(config *configs.Config, m *configs.Mount) error {
+	if m.Device != "virtiofs" && m.Device != "9p" {
+		return nil
+	}
+	if m.Device == "9p" {
+		for _, o := range strings.Split(m.Data, ",") {
+			if strings.HasPrefix(o, "trans=") && o != "trans=virtio" {
+				// Not a tag (but a host address, or a path).
+				return nil
+			}
+		}
+	}
+	if m.Source == "" {
+		return fmt.Errorf("%s mount source must be the tag of the shared directory", m.Device)
+	}
+	if filepath.IsAbs(m.Source) {
+		return fmt.Errorf("%s mount source %q is a path: must be the tag of the shared directory", m.Device, m.Source)
+	}
+	// Neither filesystem can be mounted from a user namespace.
+	if config.Namespaces.Contains(configs.NEWUSER) {
+		return fmt.Errorf("%s can not be mounted in a user namespace (bind mount it from the host instead)", m.Device)
+	}
+	return nil
+}
+
 func mountsWarn(config *configs.Config) error {
 	for _, m := range config.Mounts {
 		if !filepath.IsAbs(m.Destination) {
@@ -357,10 +973,85 @@ func mountsStrict(config *configs.Config) error {
 		if err := checkIDMapMounts(config, m); err != nil {
 			return fmt.Errorf("invalid mount %+v: %w", m, err)
 		}
+		if err := checkVirtualFsMount(config, m); err != nil {
+			return fmt.Errorf("invalid mount %+v: %w", m, err)
+		}
+	}
+	return checkSourceFdMounts(config)
+}
+
+// allowedMountSources checks that the bind mount sources are in one of the
+// config.AllowedMountSources directories, if set. This is checked again,
+// on the opened source, when the mounts are done (see CheckMountSource).
+func allowedMountSources(config *configs.Config) error {
+	if len(config.AllowedMountSources) == 0 {
+		return nil
+	}
+	for _, dir := range config.AllowedMountSources {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("allowed mount source %q is not an absolute path", dir)
+		}
+	}
+	for _, m := range config.Mounts {
+		if !m.IsBind() {
+			continue
+		}
+		// The file a descriptor refers to can not be checked, nor the
+		// source of an idmapped mount, which is a detached mount tree
+		// once opened.
+		if m.IsSourceFd() {
+			return fmt.Errorf("mount source %s: file descriptors are not allowed as mount sources with allowed mount sources set", m.Source)
+		}
+		if m.IsIDMapped() {
+			return fmt.Errorf("mount source %s: idmapped mounts are not allowed with allowed mount sources set", m.Source)
+		}
+		source, err := filepath.EvalSymlinks(m.Source)
+		if err != nil {
+			return fmt.Errorf("mount source %s: %w", m.Source, err)
+		}
+		if err := CheckMountSource(config.AllowedMountSources, source); err != nil {
+			if source != m.Source {
+				return fmt.Errorf("mount source %s (resolved to %s): %w", m.Source, source, err)
+			}
+			return fmt.Errorf("mount source %s: %w", m.Source, err)
+		}
 	}
 	return nil
 }
 
+// CheckMountSource returns an error if source, a path without symbolic
+// links, is not in one of the allowed directories (see
+// configs.Config.AllowedMountSources). Any source is allowed if allowed is
+// empty.
+func CheckMountSource(allowedSources []string, source string) error {
+	if len(allowedSources) == 0 {
+		return nil
+	}
+	allowed := make([]string, 0, len(allowedSources))
+	for _, dir := range allowedSources {
+		// The directory may not exist (yet), in which case it can not
+		// match any existing source.
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		allowed = append(allowed, filepath.Clean(dir))
+	}
+	if !isInDirs(source, allowed) {
+		return fmt.Errorf("not in the allowed mount sources %v", allowed)
+	}
+	return nil
+}
+
+// isInDirs returns whether path is one of dirs, or in one of them.
+func isInDirs(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || dir == "/" || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // sameMapping checks if the mappings are the same. If the mappings are the same
 // but in different order, it returns false.
 func sameMapping(a, b []configs.IDMap) bool {
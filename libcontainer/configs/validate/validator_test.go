@@ -4,8 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/lsm"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
@@ -66,6 +69,278 @@ func TestValidateNetworkRoutesWithoutNETNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateCNI(t *testing.T) {
+	testCases := []struct {
+		cni        *configs.CNI
+		namespaces configs.Namespaces
+		isErr      bool
+	}{
+		{cni: &configs.CNI{Network: "net"}, namespaces: configs.Namespaces{{Type: configs.NEWNET}}},
+		{cni: &configs.CNI{}, namespaces: configs.Namespaces{{Type: configs.NEWNET}}, isErr: true},
+		{cni: &configs.CNI{Network: "net"}, isErr: true},
+		{cni: &configs.CNI{Network: "net"}, namespaces: configs.Namespaces{{Type: configs.NEWNET, Path: "/proc/1/ns/net"}}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: tc.namespaces,
+			CNI:        tc.cni,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("cni %+v, namespaces %+v: expected error, got nil", tc.cni, tc.namespaces)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("cni %+v, namespaces %+v: unexpected error: %v", tc.cni, tc.namespaces, err)
+		}
+	}
+}
+
+func TestValidateNetdev(t *testing.T) {
+	testCases := []struct {
+		name       string
+		network    *configs.Network
+		namespaces configs.Namespaces
+		rootless   bool
+		isErr      bool
+	}{
+		{
+			name:       "valid",
+			network:    &configs.Network{Type: "netdev", HostInterfaceName: "ens1f0v1", Name: "eth1"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+		},
+		{
+			name:       "no host name",
+			network:    &configs.Network{Type: "netdev", Name: "eth1"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+			isErr:      true,
+		},
+		{
+			name:       "existing netns",
+			network:    &configs.Network{Type: "netdev", HostInterfaceName: "ens1f0v1"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET, Path: "/proc/1/ns/net"}},
+			isErr:      true,
+		},
+		{
+			name:       "macvlan",
+			network:    &configs.Network{Type: "macvlan", Parent: "eth0", Name: "eth0", Mode: "bridge", MacAddress: "02:42:ac:11:00:02", Address: "192.168.1.10/24"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+		},
+		{
+			name:       "ipvlan",
+			network:    &configs.Network{Type: "ipvlan", Parent: "eth0", Name: "eth0", Mode: "l3", IPv6Address: "fd00::10/64"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+		},
+		{
+			name:       "macvlan without parent",
+			network:    &configs.Network{Type: "macvlan", Name: "eth0"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+			isErr:      true,
+		},
+		{
+			name:       "ipvlan with macvlan mode",
+			network:    &configs.Network{Type: "ipvlan", Parent: "eth0", Name: "eth0", Mode: "bridge"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+			isErr:      true,
+		},
+		{
+			name:       "ipvlan with MAC address",
+			network:    &configs.Network{Type: "ipvlan", Parent: "eth0", Name: "eth0", MacAddress: "02:42:ac:11:00:02"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+			isErr:      true,
+		},
+		{
+			name:       "macvlan with bad address",
+			network:    &configs.Network{Type: "macvlan", Parent: "eth0", Name: "eth0", Address: "192.168.1.10"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+			isErr:      true,
+		},
+		{
+			name:       "rootless",
+			network:    &configs.Network{Type: "netdev", HostInterfaceName: "ens1f0v1"},
+			namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+			rootless:   true,
+			isErr:      true,
+		},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:       "/var",
+			Namespaces:   tc.namespaces,
+			Networks:     []*configs.Network{tc.network},
+			RootlessEUID: tc.rootless,
+		}
+		if tc.rootless {
+			config.Namespaces = append(config.Namespaces, configs.Namespace{Type: configs.NEWUSER})
+			config.UIDMappings = []configs.IDMap{{HostID: 1000, Size: 1}}
+			config.GIDMappings = []configs.IDMap{{HostID: 1000, Size: 1}}
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateStaticNetwork(t *testing.T) {
+	netns := configs.Namespaces{{Type: configs.NEWNET}, {Type: configs.NEWNS}}
+	testCases := []struct {
+		name       string
+		network    *configs.StaticNetwork
+		namespaces configs.Namespaces
+		mounts     []*configs.Mount
+		isErr      bool
+	}{
+		{
+			name: "valid",
+			network: &configs.StaticNetwork{
+				Addresses:   []*configs.StaticAddress{{InterfaceName: "eth0", Address: "192.168.1.10/24"}},
+				Routes:      []*configs.StaticRoute{{Gateway: "192.168.1.1"}, {Destination: "10.0.0.0/8", InterfaceName: "eth0"}},
+				Nameservers: []string{"192.168.1.1", "fd00::1"},
+				ResolvConf:  true,
+			},
+			namespaces: netns,
+		},
+		{
+			name:    "no netns",
+			network: &configs.StaticNetwork{},
+			isErr:   true,
+		},
+		{
+			name:       "bad address",
+			network:    &configs.StaticNetwork{Addresses: []*configs.StaticAddress{{InterfaceName: "eth0", Address: "192.168.1.10"}}},
+			namespaces: netns,
+			isErr:      true,
+		},
+		{
+			name:       "no interface",
+			network:    &configs.StaticNetwork{Addresses: []*configs.StaticAddress{{Address: "192.168.1.10/24"}}},
+			namespaces: netns,
+			isErr:      true,
+		},
+		{
+			name:       "route without gateway nor interface",
+			network:    &configs.StaticNetwork{Routes: []*configs.StaticRoute{{Destination: "10.0.0.0/8"}}},
+			namespaces: netns,
+			isErr:      true,
+		},
+		{
+			name:       "bad nameserver",
+			network:    &configs.StaticNetwork{Nameservers: []string{"dns.example.com"}},
+			namespaces: netns,
+			isErr:      true,
+		},
+		{
+			name:       "resolv.conf mounted",
+			network:    &configs.StaticNetwork{Nameservers: []string{"192.168.1.1"}, ResolvConf: true},
+			namespaces: netns,
+			mounts:     []*configs.Mount{{Destination: "/etc/resolv.conf", Device: "bind"}},
+			isErr:      true,
+		},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:        "/var",
+			Namespaces:    tc.namespaces,
+			Mounts:        tc.mounts,
+			StaticNetwork: tc.network,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateEtcFiles(t *testing.T) {
+	mntns := configs.Namespaces{{Type: configs.NEWNS}}
+	testCases := []struct {
+		name       string
+		files      *configs.EtcFiles
+		network    *configs.StaticNetwork
+		namespaces configs.Namespaces
+		mounts     []*configs.Mount
+		isErr      bool
+	}{
+		{
+			name: "valid",
+			files: &configs.EtcFiles{
+				Hostname:    true,
+				Hosts:       true,
+				ResolvConf:  true,
+				ExtraHosts:  []*configs.EtcHost{{Address: "fd00::2", Names: []string{"db", "db.example.com"}}},
+				Nameservers: []string{"192.168.1.1"},
+				Search:      []string{"example.com"},
+				Options:     []string{"ndots:2"},
+			},
+			namespaces: mntns,
+			// Only the generated files can't be mounts.
+			mounts: []*configs.Mount{{Destination: "/etc/localtime", Device: "bind"}},
+		},
+		{
+			name:  "no mntns",
+			files: &configs.EtcFiles{Hosts: true},
+			isErr: true,
+		},
+		{
+			name:       "hosts mounted",
+			files:      &configs.EtcFiles{Hosts: true},
+			namespaces: mntns,
+			mounts:     []*configs.Mount{{Destination: "/etc//hosts", Device: "bind"}},
+			isErr:      true,
+		},
+		{
+			name:       "resolv.conf also from the static network",
+			files:      &configs.EtcFiles{ResolvConf: true},
+			network:    &configs.StaticNetwork{ResolvConf: true},
+			namespaces: configs.Namespaces{{Type: configs.NEWNS}, {Type: configs.NEWNET}},
+			isErr:      true,
+		},
+		{
+			name:       "bad host address",
+			files:      &configs.EtcFiles{Hosts: true, ExtraHosts: []*configs.EtcHost{{Address: "db", Names: []string{"db"}}}},
+			namespaces: mntns,
+			isErr:      true,
+		},
+		{
+			name:       "bad host name",
+			files:      &configs.EtcFiles{Hosts: true, ExtraHosts: []*configs.EtcHost{{Address: "10.0.0.1", Names: []string{"a b"}}}},
+			namespaces: mntns,
+			isErr:      true,
+		},
+		{
+			name:       "bad nameserver",
+			files:      &configs.EtcFiles{ResolvConf: true, Nameservers: []string{"dns.example.com"}},
+			namespaces: mntns,
+			isErr:      true,
+		},
+		{
+			name:       "bad option",
+			files:      &configs.EtcFiles{ResolvConf: true, Options: []string{""}},
+			namespaces: mntns,
+			isErr:      true,
+		},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:        "/var",
+			Namespaces:    tc.namespaces,
+			Mounts:        tc.mounts,
+			StaticNetwork: tc.network,
+			EtcFiles:      tc.files,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
 func TestValidateHostname(t *testing.T) {
 	config := &configs.Config{
 		Rootfs:   "/var",
@@ -157,6 +432,35 @@ func TestValidateSecurityWithROPaths(t *testing.T) {
 	}
 }
 
+func TestValidateUnconfinedStartHooksWithSeccompNotify(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:               "/var",
+		UnconfinedStartHooks: true,
+		Seccomp: &configs.Seccomp{
+			DefaultAction: configs.Allow,
+			Syscalls: []*configs.Syscall{
+				{Name: "mkdir", Action: configs.Notify},
+			},
+		},
+	}
+
+	err := Validate(config)
+	if err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+
+	config.UnconfinedStartHooks = false
+	if err := Validate(config); err != nil {
+		t.Errorf("Expected error to not occur: %+v", err)
+	}
+
+	config.UnconfinedStartHooks = true
+	config.Seccomp.Syscalls[0].Action = configs.Errno
+	if err := Validate(config); err != nil {
+		t.Errorf("Expected error to not occur: %+v", err)
+	}
+}
+
 func TestValidateSecurityWithoutNEWNS(t *testing.T) {
 	config := &configs.Config{
 		Rootfs:        "/var",
@@ -489,6 +793,141 @@ func TestValidateBindMounts(t *testing.T) {
 	}
 }
 
+func TestValidateSourceFdMounts(t *testing.T) {
+	bind := func(source, dest string) *configs.Mount {
+		return &configs.Mount{Source: source, Destination: dest, Device: "bind", Flags: unix.MS_BIND}
+	}
+	testCases := []struct {
+		name   string
+		mounts []*configs.Mount
+		isErr  bool
+	}{
+		{name: "valid", mounts: []*configs.Mount{bind("fd:3", "/a"), bind("fd:4", "/b")}},
+		{name: "path", mounts: []*configs.Mount{bind("/fd:3", "/a")}},
+		{name: "not a number", mounts: []*configs.Mount{bind("fd:three", "/a")}, isErr: true},
+		{name: "negative", mounts: []*configs.Mount{bind("fd:-1", "/a")}, isErr: true},
+		{name: "stdio", mounts: []*configs.Mount{bind("fd:2", "/a")}, isErr: true},
+		{name: "used twice", mounts: []*configs.Mount{bind("fd:3", "/a"), bind("fd:3", "/b")}, isErr: true},
+		{name: "not bind", mounts: []*configs.Mount{{Source: "fd:3", Destination: "/a", Device: "tmpfs"}}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: configs.Namespaces{{Type: configs.NEWNS}},
+			Mounts:     tc.mounts,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%s: expected nil, got error %v", tc.name, err)
+		}
+	}
+
+	// A new mount namespace is required.
+	config := &configs.Config{
+		Rootfs: "/var",
+		Mounts: []*configs.Mount{bind("fd:3", "/a")},
+	}
+	if err := Validate(config); err == nil {
+		t.Error("expected error without a mount namespace, got nil")
+	}
+}
+
+func TestValidateVirtualFsMounts(t *testing.T) {
+	testCases := []struct {
+		device, source, data string
+		userns               bool
+		isErr                bool
+	}{
+		{device: "virtiofs", source: "myfs"},
+		{device: "9p", source: "share", data: "msize=262144"},
+		{device: "9p", source: "/run/9p.sock", data: "trans=unix"},
+		{device: "virtiofs", source: "", isErr: true},
+		{device: "virtiofs", source: "/srv/share", isErr: true},
+		{device: "9p", source: "/srv/share", data: "trans=virtio", isErr: true},
+		{device: "virtiofs", source: "myfs", userns: true, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs: "/var",
+			Mounts: []*configs.Mount{
+				{Device: tc.device, Source: tc.source, Destination: "/mnt", Data: tc.data},
+			},
+		}
+		if tc.userns {
+			config.Namespaces = configs.Namespaces{{Type: configs.NEWUSER}}
+			config.UIDMappings = []configs.IDMap{{HostID: 1000, Size: 1}}
+			config.GIDMappings = []configs.IDMap{{HostID: 1000, Size: 1}}
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s mount of %q (%s): expected error, got nil", tc.device, tc.source, tc.data)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%s mount of %q (%s): expected nil, got error %v", tc.device, tc.source, tc.data, err)
+		}
+	}
+}
+
+func TestValidateAllowedMountSources(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	other := filepath.Join(dir, "other")
+	for _, d := range []string{allowed + "/sub", allowed + "-not", other} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(other, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	bind := func(source string) *configs.Mount {
+		return &configs.Mount{Source: source, Destination: "/mnt", Device: "bind", Flags: unix.MS_BIND}
+	}
+	testCases := []struct {
+		name    string
+		allowed []string
+		mount   *configs.Mount
+		isErr   bool
+	}{
+		{name: "no policy", mount: bind(other)},
+		{name: "allowed", allowed: []string{allowed}, mount: bind(allowed)},
+		{name: "subdirectory", allowed: []string{other, allowed}, mount: bind(allowed + "/sub")},
+		{name: "not allowed", allowed: []string{allowed}, mount: bind(other), isErr: true},
+		{name: "prefix", allowed: []string{allowed}, mount: bind(allowed + "-not"), isErr: true},
+		{name: "dotdot", allowed: []string{allowed}, mount: bind(allowed + "/../other"), isErr: true},
+		{name: "symlink", allowed: []string{allowed}, mount: bind(allowed + "/escape"), isErr: true},
+		{name: "fd", allowed: []string{allowed}, mount: bind("fd:3"), isErr: true},
+		{name: "idmapped", allowed: []string{allowed}, mount: &configs.Mount{
+			Source: allowed, Destination: "/mnt", Device: "bind", Flags: unix.MS_BIND,
+			UIDMappings: []configs.IDMap{{Size: 1}}, GIDMappings: []configs.IDMap{{Size: 1}},
+		}, isErr: true},
+		{name: "relative policy", allowed: []string{"allowed"}, mount: bind(allowed), isErr: true},
+		{name: "not bind", allowed: []string{allowed}, mount: &configs.Mount{Source: "tmpfs", Destination: "/tmp", Device: "tmpfs"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:              "/var",
+				Mounts:              []*configs.Mount{tc.mount},
+				AllowedMountSources: tc.allowed,
+			}
+			if tc.mount.IsSourceFd() {
+				config.Namespaces = configs.Namespaces{{Type: configs.NEWNS}}
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Errorf("expected nil, got error %v", err)
+			}
+		})
+	}
+}
+
 func TestValidateIDMapMounts(t *testing.T) {
 	mapping := []configs.IDMap{
 		{
@@ -732,3 +1171,411 @@ func TestValidateScheduler(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDuplicateInterfaceNames(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:     "/var",
+		Namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+		Networks: []*configs.Network{
+			{Type: "netdev", HostInterfaceName: "ens1f0v1", Name: "eth0"},
+			{Type: "macvlan", Parent: "eth0", Name: "eth0"},
+		},
+	}
+	if err := Validate(config); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestValidateCpusetCpus(t *testing.T) {
+	if _, err := cgroups.OnlineCPUs(); err != nil {
+		t.Skipf("unable to get online CPUs: %v", err)
+	}
+	testCases := []struct {
+		cpus   string
+		adjust bool
+		isErr  bool
+	}{
+		{cpus: "0"},
+		{cpus: "0,65000", isErr: true},
+		{cpus: "0,65000", adjust: true},
+		{cpus: "65000", adjust: true, isErr: true},
+		{cpus: "1-0", isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs: "/var",
+			Cgroups: &configs.Cgroup{
+				Resources: &configs.Resources{CpusetCpus: tc.cpus},
+			},
+			CpusetAdjust: tc.adjust,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("cpus %q, adjust %v: expected error, got nil", tc.cpus, tc.adjust)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("cpus %q, adjust %v: unexpected error: %v", tc.cpus, tc.adjust, err)
+		}
+	}
+}
+
+func TestValidateCpusetAlloc(t *testing.T) {
+	testCases := []struct {
+		alloc *configs.CpusetAlloc
+		cpus  string
+		isErr bool
+	}{
+		{alloc: &configs.CpusetAlloc{CPUs: 1}},
+		{alloc: &configs.CpusetAlloc{CPUs: 2, Pool: "/etc/runc/cpuset-pool"}},
+		{alloc: &configs.CpusetAlloc{CPUs: 0}, isErr: true},
+		{alloc: &configs.CpusetAlloc{CPUs: 1, Pool: "pool"}, isErr: true},
+		{alloc: &configs.CpusetAlloc{CPUs: 1}, cpus: "0", isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs: "/var",
+			Cgroups: &configs.Cgroup{
+				Resources: &configs.Resources{CpusetCpus: tc.cpus},
+			},
+			CpusetAlloc: tc.alloc,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v, cpus %q: expected error, got nil", tc.alloc, tc.cpus)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v, cpus %q: unexpected error: %v", tc.alloc, tc.cpus, err)
+		}
+	}
+}
+
+func TestValidateSwapMax(t *testing.T) {
+	i64 := func(v int64) *int64 { return &v }
+	u64 := func(v uint64) *uint64 { return &v }
+	testCases := []struct {
+		name      string
+		resources configs.Resources
+		isErr     bool
+	}{
+		{name: "swap max", resources: configs.Resources{Memory: 1 << 20, SwapMax: i64(1 << 20)}},
+		{name: "no swap", resources: configs.Resources{Memory: 1 << 20, SwapMax: i64(0), MemorySwappiness: u64(0)}},
+		{name: "unlimited", resources: configs.Resources{SwapMax: i64(-1)}},
+		{name: "with memory swap", resources: configs.Resources{Memory: 1 << 20, MemorySwap: 2 << 20, SwapMax: i64(1 << 20)}, isErr: true},
+		{name: "invalid", resources: configs.Resources{Memory: 1 << 20, SwapMax: i64(-2)}, isErr: true},
+		{name: "swappiness 0", resources: configs.Resources{Memory: 1 << 20, SwapMax: i64(1 << 20), MemorySwappiness: u64(0)}, isErr: true},
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		testCases = append(testCases, struct {
+			name      string
+			resources configs.Resources
+			isErr     bool
+		}{name: "v1 without memory", resources: configs.Resources{SwapMax: i64(1 << 20)}, isErr: true})
+	}
+	for _, tc := range testCases {
+		r := tc.resources
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: &configs.Cgroup{Resources: &r},
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateAdoptedCgroup(t *testing.T) {
+	testCases := []struct {
+		cgroup *configs.Cgroup
+		isErr  bool
+	}{
+		{cgroup: &configs.Cgroup{Path: "/foo", Adopted: true}},
+		{cgroup: &configs.Cgroup{Name: "foo", Parent: "bar", Adopted: true}, isErr: true},
+		{cgroup: &configs.Cgroup{Path: "/foo", Systemd: true, Adopted: true}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: tc.cgroup,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.cgroup)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tc.cgroup, err)
+		}
+	}
+}
+
+func TestValidateCPUBandwidth(t *testing.T) {
+	testCases := []struct {
+		r     configs.Resources
+		isErr bool
+	}{
+		{r: configs.Resources{}},
+		{r: configs.Resources{CpuPeriod: 100000, CpuQuota: 50000}},
+		{r: configs.Resources{CpuPeriod: 100000, CpuQuota: -1}},
+		{r: configs.Resources{CpuPeriod: 5000, CpuQuota: 2500}},
+		{r: configs.Resources{CpuPeriod: 100000, CpuQuota: 50000, CpuBurst: &[]uint64{50000}[0]}},
+		{r: configs.Resources{CpuPeriod: 100}, isErr: true},
+		{r: configs.Resources{CpuPeriod: 2000000}, isErr: true},
+		{r: configs.Resources{CpuPeriod: 100000, CpuQuota: 500}, isErr: true},
+		{r: configs.Resources{CpuPeriod: 100000, CpuQuota: 50000, CpuBurst: &[]uint64{60000}[0]}, isErr: true},
+	}
+	for _, tc := range testCases {
+		r := tc.r
+		config := &configs.Config{
+			Rootfs:  "/var",
+			Cgroups: &configs.Cgroup{Resources: &r},
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.r)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tc.r, err)
+		}
+	}
+}
+
+func TestValidateHousekeepingCgroup(t *testing.T) {
+	testCases := []struct {
+		path    string
+		systemd bool
+		isErr   bool
+	}{
+		{path: "/runc-housekeeping"},
+		{path: "/a/b"},
+		{path: "/runc-housekeeping", systemd: true, isErr: true},
+		{path: "runc-housekeeping", isErr: true},
+		{path: "/a/../b", isErr: true},
+		{path: "/", isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:             "/var",
+			Cgroups:            &configs.Cgroup{Systemd: tc.systemd},
+			HousekeepingCgroup: tc.path,
+		}
+		err := Validate(config)
+		if !cgroups.IsCgroup2UnifiedMode() {
+			if err == nil {
+				t.Errorf("path %q: expected error on cgroup v1, got nil", tc.path)
+			}
+			continue
+		}
+		if tc.isErr && err == nil {
+			t.Errorf("path %q, systemd %v: expected error, got nil", tc.path, tc.systemd)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("path %q, systemd %v: unexpected error: %v", tc.path, tc.systemd, err)
+		}
+	}
+}
+
+func TestValidatePidsStartLimit(t *testing.T) {
+	testCases := []struct {
+		limit     *configs.PidsStartLimit
+		pidsLimit int64
+		isErr     bool
+	}{
+		{limit: &configs.PidsStartLimit{Limit: 32, Settle: time.Second}},
+		{limit: &configs.PidsStartLimit{Limit: 32, Settle: time.Second}, pidsLimit: 1024},
+		{limit: &configs.PidsStartLimit{Limit: 32, Settle: time.Second}, pidsLimit: -1},
+		{limit: &configs.PidsStartLimit{Limit: 32, Settle: time.Second}, pidsLimit: 32, isErr: true},
+		{limit: &configs.PidsStartLimit{Limit: 0, Settle: time.Second}, isErr: true},
+		{limit: &configs.PidsStartLimit{Limit: 32}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs: "/var",
+			Cgroups: &configs.Cgroup{
+				Resources: &configs.Resources{PidsLimit: tc.pidsLimit},
+			},
+			PidsStartLimit: tc.limit,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v, pids limit %d: expected error, got nil", tc.limit, tc.pidsLimit)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v, pids limit %d: unexpected error: %v", tc.limit, tc.pidsLimit, err)
+		}
+	}
+}
+
+func TestValidateTmpfsSizes(t *testing.T) {
+	shm := &configs.Mount{Source: "shm", Destination: "/dev/shm", Device: "tmpfs"}
+	tmp := &configs.Mount{Source: "tmpfs", Destination: "/tmp/", Device: "tmpfs"}
+	bindTmp := &configs.Mount{Source: "/var/tmp", Destination: "/tmp", Device: "bind", Flags: unix.MS_BIND}
+	testCases := []struct {
+		name             string
+		mounts           []*configs.Mount
+		shmSize, tmpSize int64
+		tmpInodes        uint64
+		isErr            bool
+	}{
+		{name: "none"},
+		{name: "shm", mounts: []*configs.Mount{shm}, shmSize: 1 << 20},
+		{name: "tmp", mounts: []*configs.Mount{tmp}, tmpSize: 1 << 20, tmpInodes: 1024},
+		{name: "tmp inodes", mounts: []*configs.Mount{tmp}, tmpInodes: 1024},
+		{name: "negative shm", mounts: []*configs.Mount{shm}, shmSize: -1, isErr: true},
+		{name: "negative tmp", mounts: []*configs.Mount{tmp}, tmpSize: -1, isErr: true},
+		{name: "no shm mount", mounts: []*configs.Mount{tmp}, shmSize: 1 << 20, isErr: true},
+		{name: "tmp not tmpfs", mounts: []*configs.Mount{bindTmp}, tmpInodes: 1024, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:    "/var",
+			Mounts:    tc.mounts,
+			ShmSize:   tc.shmSize,
+			TmpSize:   tc.tmpSize,
+			TmpInodes: tc.tmpInodes,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidatePropagationCheck(t *testing.T) {
+	for _, check := range []string{"", "ignore", "warn", "repair", "strict"} {
+		config := &configs.Config{Rootfs: "/var", PropagationCheck: check}
+		if err := Validate(config); err != nil {
+			t.Errorf("%q: unexpected error: %v", check, err)
+		}
+	}
+	config := &configs.Config{Rootfs: "/var", PropagationCheck: "fix"}
+	if err := Validate(config); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestValidateConfigDriftCheck(t *testing.T) {
+	for _, check := range []string{"", "ignore", "warn", "strict"} {
+		config := &configs.Config{Rootfs: "/var", ConfigDriftCheck: check}
+		if err := Validate(config); err != nil {
+			t.Errorf("%q: unexpected error: %v", check, err)
+		}
+	}
+	config := &configs.Config{Rootfs: "/var", ConfigDriftCheck: "repair"}
+	if err := Validate(config); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestValidateInitPath(t *testing.T) {
+	testCases := []struct {
+		path  string
+		isErr bool
+	}{
+		{path: ""},
+		{path: "/bin/sh"},
+		{path: "bin/sh", isErr: true},
+		{path: "/bin", isErr: true},
+		{path: "/etc/passwd", isErr: true},
+		{path: "/nonexistent/init", isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{Rootfs: "/var", InitPath: tc.path}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%q: expected error, got nil", tc.path)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.path, err)
+		}
+	}
+}
+
+func TestValidateDiskQuota(t *testing.T) {
+	testCases := []struct {
+		name  string
+		quota configs.DiskQuota
+		isErr bool
+	}{
+		{name: "size", quota: configs.DiskQuota{Path: "/var", ProjectID: 1000, Size: 1 << 30}},
+		{name: "inodes", quota: configs.DiskQuota{Path: "/var", ProjectID: 1000, Inodes: 1000}},
+		{name: "relative path", quota: configs.DiskQuota{Path: "var", ProjectID: 1000, Size: 1 << 30}, isErr: true},
+		{name: "no project", quota: configs.DiskQuota{Path: "/var", Size: 1 << 30}, isErr: true},
+		{name: "negative size", quota: configs.DiskQuota{Path: "/var", ProjectID: 1000, Size: -1}, isErr: true},
+		{name: "no limit", quota: configs.DiskQuota{Path: "/var", ProjectID: 1000}, isErr: true},
+	}
+	for _, tc := range testCases {
+		q := tc.quota
+		config := &configs.Config{
+			Rootfs:    "/var",
+			DiskQuota: &q,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateBinfmt(t *testing.T) {
+	const aarch64 = `:qemu-aarch64:M::\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\xb7\x00:\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff:/usr/bin/qemu-aarch64-static:F`
+	userns := configs.Namespaces([]configs.Namespace{{Type: configs.NEWNS}, {Type: configs.NEWUSER}})
+	testCases := []struct {
+		name       string
+		binfmt     configs.Binfmt
+		namespaces configs.Namespaces
+		isErr      bool
+	}{
+		{name: "require", binfmt: configs.Binfmt{Require: []string{"qemu-aarch64"}}},
+		{name: "private", binfmt: configs.Binfmt{Private: true, Register: []string{aarch64}, Require: []string{"qemu-aarch64"}}, namespaces: userns},
+		{name: "private without userns", binfmt: configs.Binfmt{Private: true}, isErr: true},
+		{name: "register without private", binfmt: configs.Binfmt{Register: []string{aarch64}}, isErr: true},
+		{name: "bad registration", binfmt: configs.Binfmt{Private: true, Register: []string{":x:M:0"}}, namespaces: userns, isErr: true},
+		{name: "relative interpreter", binfmt: configs.Binfmt{Private: true, Register: []string{":x:E::x::qemu:"}}, namespaces: userns, isErr: true},
+		{name: "bad name", binfmt: configs.Binfmt{Require: []string{"../status"}}, isErr: true},
+	}
+	for _, tc := range testCases {
+		b := tc.binfmt
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: tc.namespaces,
+			Binfmt:     &b,
+		}
+		err := binfmtCheck(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateBPFLSM(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("cgroup v2 is required")
+	}
+	if active, known := lsm.IsActive("bpf"); known && !active {
+		t.Skip("bpf lsm is not active")
+	}
+	testCases := []struct {
+		name     string
+		programs []string
+		isErr    bool
+	}{
+		{name: "valid", programs: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/b"}},
+		{name: "no programs", isErr: true},
+		{name: "relative", programs: []string{"a"}, isErr: true},
+		{name: "twice", programs: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/a"}, isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs: "/var",
+			BPFLSM: &configs.BPFLSM{Programs: tc.programs},
+		}
+		err := bpfLSMCheck(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
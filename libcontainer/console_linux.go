@@ -1,11 +1,49 @@
 package libcontainer
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 
 	"golang.org/x/sys/unix"
 )
 
+const (
+	// ConsoleSocketV1 is the original console socket protocol: the pty
+	// master is sent with the path of the pty slave as its name.
+	ConsoleSocketV1 = 1
+
+	// ConsoleSocketV2 is the console socket protocol where the pty master
+	// is sent with a JSON-encoded ConsoleSocketMessage as its name.
+	ConsoleSocketV2 = 2
+
+	// ConsoleSocketVersion is the latest console socket protocol version.
+	ConsoleSocketVersion = ConsoleSocketV2
+)
+
+// ConsoleSocketMessage is the name the pty master is sent with over the
+// console socket, with the version 2 of the protocol.
+type ConsoleSocketMessage struct {
+	Version     int    `json:"version"`
+	ContainerID string `json:"container_id"`
+	// PtyPath is the path of the pty slave in the container, if known (it
+	// is not for a restored container).
+	PtyPath string `json:"pty_path,omitempty"`
+}
+
+// consoleSocketName returns the name to send the pty master with over the
+// console socket, for the given protocol version (0 meaning ConsoleSocketV1).
+func consoleSocketName(version int, id, ptyPath string) (string, error) {
+	switch version {
+	case 0, ConsoleSocketV1:
+		return ptyPath, nil
+	case ConsoleSocketV2:
+		b, err := json.Marshal(ConsoleSocketMessage{Version: version, ContainerID: id, PtyPath: ptyPath})
+		return string(b), err
+	}
+	return "", fmt.Errorf("unsupported console socket version %d", version)
+}
+
 // mount initializes the console inside the rootfs mounting with the specified mount label
 // and applying the correct ownership of the console.
 func mountConsole(slavePath string) error {
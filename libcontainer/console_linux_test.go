@@ -0,0 +1,34 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConsoleSocketName(t *testing.T) {
+	for _, v := range []int{0, ConsoleSocketV1} {
+		name, err := consoleSocketName(v, "test", "/dev/pts/3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "/dev/pts/3" {
+			t.Errorf("version %d: expected the pty path, got %q", v, name)
+		}
+	}
+
+	name, err := consoleSocketName(ConsoleSocketV2, "test", "/dev/pts/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg ConsoleSocketMessage
+	if err := json.Unmarshal([]byte(name), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg != (ConsoleSocketMessage{Version: ConsoleSocketV2, ContainerID: "test", PtyPath: "/dev/pts/3"}) {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	if _, err := consoleSocketName(ConsoleSocketVersion+1, "test", "/dev/pts/3"); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
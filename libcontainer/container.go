@@ -56,4 +56,9 @@ type BaseState struct {
 
 	// Config is the container's configuration.
 	Config configs.Config `json:"config"`
+
+	// Metadata is the user-defined key/value metadata of the container.
+	// Unlike the annotations in Config.Labels, it can be changed at any
+	// time (see Container.SetMetadata).
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
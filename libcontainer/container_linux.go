@@ -8,7 +8,6 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"reflect"
 	"strconv"
@@ -25,7 +24,10 @@ import (
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/dmz"
+	"github.com/opencontainers/runc/libcontainer/extcmd"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/quota"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/system/kernelversion"
 	"github.com/opencontainers/runc/libcontainer/utils"
@@ -50,6 +52,18 @@ type Container struct {
 	state                containerState
 	created              time.Time
 	fifo                 *os.File
+	metadata             map[string]string
+	cniResult            json.RawMessage
+	setupCost            *SetupCost
+	helperOomScoreAdj    *int
+	runtimeMaskPaths     []string
+	exitReason           string
+	// journal records the side effects of the container creation,
+	// so they can be undone if it fails. Only set by Create.
+	journal *journal
+	// seccompProgram is the container's seccomp filter, precompiled by
+	// CreateAll. If nil, runc init compiles the filter itself.
+	seccompProgram *seccomp.Program
 }
 
 // State represents a running container's state
@@ -74,13 +88,62 @@ type State struct {
 	// with the value as the path.
 	NamespacePaths map[configs.NamespaceType]string `json:"namespace_paths"`
 
+	// Namespaces tells, for each namespace of the container, whether it
+	// was created by runc, joined, or inherited from runc, with its inode.
+	Namespaces []NamespaceState `json:"namespaces,omitempty"`
+
+	// CgroupV2Unavailable lists the cgroup v2 only features used by runc,
+	// or requested by the container resources, which are not available on
+	// the host, with the fallback used instead.
+	CgroupV2Unavailable []cgroups.V2Feature `json:"cgroup_v2_unavailable,omitempty"`
+
 	// Container's standard descriptors (std{in,out,err}), needed for checkpoint and restore
 	ExternalDescriptors []string `json:"external_descriptors,omitempty"`
 
 	// Intel RDT "resource control" filesystem path
 	IntelRdtPath string `json:"intel_rdt_path"`
+
+	// CNIResult is the result of adding the container to its CNI network,
+	// if configured (see configs.Config.CNI).
+	CNIResult json.RawMessage `json:"cni_result,omitempty"`
+
+	// SetupCost is the cost of the container setup by runc.
+	SetupCost *SetupCost `json:"setup_cost,omitempty"`
+
+	// HelperOomScoreAdj is the oom_score_adj runc init had during the
+	// container setup.
+	HelperOomScoreAdj *int `json:"helper_oom_score_adj,omitempty"`
+
+	// RuntimeMaskPaths are the paths masked in the running container (see
+	// Container.MaskPaths), in addition to the ones masked at its start.
+	RuntimeMaskPaths []string `json:"runtime_mask_paths,omitempty"`
+
+	// ExitReason is why the container was stopped by runc, if it was
+	// (see Container.SetExitReason), such as ExitReasonTimeout.
+	ExitReason string `json:"exit_reason,omitempty"`
+
+	// Version is the version of the state format (see StateVersion), or 0
+	// for a state written before the format was versioned.
+	Version int `json:"state_version,omitempty"`
+
+	// MinReaderVersion is the lowest state format version able to use
+	// this state (see StateMinReaderVersion).
+	MinReaderVersion int `json:"state_min_reader_version,omitempty"`
 }
 
+const (
+	// StateVersion is the version of the state format written by this
+	// runc. It is increased on every change of the format.
+	StateVersion = 2
+
+	// StateMinReaderVersion is the lowest state format version able to
+	// use the state written by this runc. It is only increased on
+	// incompatible changes, so that an older runc can still manage the
+	// containers created by a newer one (during a rolling upgrade, or a
+	// downgrade), as long as the format changes are additive.
+	StateMinReaderVersion = 1
+)
+
 // ID returns the container's unique ID
 func (c *Container) ID() string {
 	/*取container id*/
@@ -140,6 +203,12 @@ func (c *Container) Processes() ([]int, error) {
 	return pids, nil
 }
 
+// OOMKillCount returns the number of the container processes killed by
+// the OOM killer.
+func (c *Container) OOMKillCount() (uint64, error) {
+	return c.cgroupManager.OOMKillCount()
+}
+
 // Stats returns statistics for the container.
 func (c *Container) Stats() (*Stats, error) {
 	var (
@@ -154,6 +223,11 @@ func (c *Container) Stats() (*Stats, error) {
 			return stats, fmt.Errorf("unable to get container Intel RDT stats: %w", err)
 		}
 	}
+	if c.config.DiskQuota != nil {
+		if stats.DiskQuota, err = quota.GetUsage(c.config.DiskQuota); err != nil {
+			return stats, fmt.Errorf("unable to get container disk quota usage: %w", err)
+		}
+	}
 	for _, iface := range c.config.Networks {
 		switch iface.Type {
 		case "veth":
@@ -167,6 +241,26 @@ func (c *Container) Stats() (*Stats, error) {
 	return stats, nil
 }
 
+// StatsWithFDs is like Stats, but also accounts for the file descriptors
+// opened by the container processes (see FDStats), which walks their
+// /proc/<pid>/fd directories. To bound the cost of this, at most
+// maxProcesses processes are accounted for (the other ones are skipped, and
+// FDStats.Sampled is set).
+func (c *Container) StatsWithFDs(maxProcesses int) (*Stats, error) {
+	stats, err := c.Stats()
+	if err != nil {
+		return stats, err
+	}
+	pids, err := c.Processes()
+	if err != nil {
+		return stats, err
+	}
+	if stats.FDs, err = fdStats(pids, maxProcesses); err != nil {
+		return stats, fmt.Errorf("unable to get container file descriptor stats: %w", err)
+	}
+	return stats, nil
+}
+
 // Set resources of container as configured. Can be used to change resources
 // when the container is running.
 func (c *Container) Set(config configs.Config) error {
@@ -179,7 +273,14 @@ func (c *Container) Set(config configs.Config) error {
 	if status == Stopped {
 		return ErrNotRunning
 	}
-	if err := c.cgroupManager.Set(config.Cgroups.Resources); err != nil {
+	if c.config.Cgroups.Adopted {
+		return fmt.Errorf("unable to update container resources: %w", ErrAdoptedCgroup)
+	}
+	resources, err := cpusetResources(&config, config.Cgroups.Resources)
+	if err != nil {
+		return err
+	}
+	if err := c.cgroupManager.Set(resources); err != nil {
 		// Set configs back
 		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
 			logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
@@ -204,6 +305,72 @@ func (c *Container) Set(config configs.Config) error {
 	return err
 }
 
+// Metadata returns the container's metadata (see SetMetadata).
+func (c *Container) Metadata() map[string]string {
+	c.m.Lock()
+	defer c.m.Unlock()
+	m := make(map[string]string, len(c.metadata))
+	for k, v := range c.metadata {
+		m[k] = v
+	}
+	return m
+}
+
+// SetMetadata updates the container's metadata, which is a set of arbitrary
+// key/value pairs kept in the container state. Each key from update is set
+// to its value, or removed if the value is empty.
+//
+// Unlike the annotations, which come from the bundle and can not be changed,
+// the metadata is meant to be used by higher level tools to store
+// information such as ownership of the container.
+func (c *Container) SetMetadata(update map[string]string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if _, err := os.Stat(filepath.Join(c.stateDir, stateFilename)); err != nil {
+		// Nowhere to save the metadata to yet.
+		return fmt.Errorf("container state not found: %w", err)
+	}
+	old := c.metadata
+	m := make(map[string]string, len(old)+len(update))
+	for k, v := range old {
+		m[k] = v
+	}
+	for k, v := range update {
+		if v == "" {
+			delete(m, k)
+		} else {
+			m[k] = v
+		}
+	}
+	if len(m) == 0 {
+		m = nil
+	}
+	c.metadata = m
+	if _, err := c.updateState(nil); err != nil {
+		c.metadata = old
+		return err
+	}
+	return nil
+}
+
+// ExitReasonTimeout is the exit reason of a container stopped for exceeding
+// its deadline (see runc run --timeout).
+const ExitReasonTimeout = "timeout"
+
+// SetExitReason records why the container is being stopped by the runtime,
+// before stopping it, so that it is reported in its state (State.ExitReason).
+func (c *Container) SetExitReason(reason string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	old := c.exitReason
+	c.exitReason = reason
+	if _, err := c.updateState(nil); err != nil {
+		c.exitReason = old
+		return err
+	}
+	return nil
+}
+
 // Start starts a process inside the container. Returns error if process fails
 // to start. You can track process lifecycle with passed Process structure.
 func (c *Container) Start(process *Process) error {
@@ -212,6 +379,9 @@ func (c *Container) Start(process *Process) error {
 	if c.config.Cgroups.Resources.SkipDevices {
 		return errors.New("can't start container with SkipDevices set")
 	}
+	if err := c.checkSecrets(process.Secrets); err != nil {
+		return err
+	}
 	if process.Init {
 		if err := c.createExecFifo(); err != nil {
 			return err
@@ -256,7 +426,10 @@ func (c *Container) exec() error {
 	for {
 		select {
 		case result := <-blockingFifoOpenCh:
-			return handleFifoResult(result)
+			if err := handleFifoResult(result); err != nil {
+				return err
+			}
+			return c.settlePids()
 
 		case <-time.After(time.Millisecond * 100):
 			stat, err := system.Stat(pid)
@@ -345,6 +518,12 @@ func (c *Container) start(process *Process) (retErr error) {
 		return fmt.Errorf("unable to start container process: %w", err)
 	}
 
+	if !process.Init && process.maskPaths == nil {
+		if err := c.recordExecSession(parent.pid(), process.Args); err != nil {
+			logrus.Warnf("unable to record exec session: %v", err)
+		}
+	}
+
 	if process.Init {
 		c.fifo.Close()
 		if c.config.Hooks != nil {
@@ -368,7 +547,9 @@ func (c *Container) start(process *Process) (retErr error) {
 //
 // When s is SIGKILL and the container does not have its own PID namespace, all
 // the container's processes are killed. In this scenario, the libcontainer
-// user may be required to implement a proper child reaper.
+// user may be required to implement a proper child reaper. The exception is
+// a container in an adopted cgroup, which may also contain processes of its
+// owner, so only the container's init is killed.
 func (c *Container) Signal(s os.Signal) error {
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -380,7 +561,7 @@ func (c *Container) Signal(s os.Signal) error {
 	//
 	// OTOH, if PID namespace is shared, we should kill all pids to avoid
 	// leftover processes. Handle this special case here.
-	if s == unix.SIGKILL && !c.config.Namespaces.IsPrivate(configs.NEWPID) {
+	if s == unix.SIGKILL && !c.config.Namespaces.IsPrivate(configs.NEWPID) && !c.config.Cgroups.Adopted {
 		if err := signalAllProcesses(c.cgroupManager, unix.SIGKILL); err != nil {
 			return fmt.Errorf("unable to kill all processes: %w", err)
 		}
@@ -398,7 +579,7 @@ func (c *Container) Signal(s os.Signal) error {
 		// For cgroup v1, killing a process in a frozen cgroup
 		// does nothing until it's thawed. Only thaw the cgroup
 		// for SIGKILL.
-		if paused, _ := c.isPaused(); paused {
+		if paused, _ := c.isPaused(); paused && !c.config.Cgroups.Adopted {
 			_ = c.cgroupManager.Freeze(configs.Thawed)
 		}
 	}
@@ -515,7 +696,18 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 		// only one of dmzExe or safeExe are used at a time
 		dmzExe, safeExe *os.File
 	)
-	if dmz.IsSelfExeCloned() {
+	argv0 := os.Args[0]
+	if c.config.InitPath != "" {
+		// An alternate init binary is cloned just like our own.
+		safeExe, err = dmz.CloneExe(c.config.InitPath, c.stateDir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create safe %s clone for init: %w", c.config.InitPath, err)
+		}
+		exePath = "/proc/self/fd/" + strconv.Itoa(int(safeExe.Fd()))
+		argv0 = c.config.InitPath
+		p.clonedExes = append(p.clonedExes, safeExe)
+		logrus.Debugf("using init binary %s", c.config.InitPath)
+	} else if dmz.IsSelfExeCloned() {
 		// /proc/self/exe is already a cloned binary -- no need to do anything
 		logrus.Debug("skipping binary cloning -- /proc/self/exe is already cloned!")
 		exePath = "/proc/self/exe"
@@ -557,7 +749,7 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 	}
 
 	cmd := exec.Command(exePath, "init")
-	cmd.Args[0] = os.Args[0]
+	cmd.Args[0] = argv0
 	cmd.Stdin = p.Stdin
 	cmd.Stdout = p.Stdout
 	cmd.Stderr = p.Stderr
@@ -567,6 +759,18 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 	}
 	cmd.Env = append(cmd.Env, "GOMAXPROCS="+os.Getenv("GOMAXPROCS"))
 	cmd.ExtraFiles = append(cmd.ExtraFiles, p.ExtraFiles...)
+	if len(p.Secrets) > 0 {
+		fds := make([]int, len(p.Secrets))
+		for i, s := range p.Secrets {
+			cmd.ExtraFiles = append(cmd.ExtraFiles, s.File)
+			fds[i] = stdioFdCount + len(cmd.ExtraFiles) - 1
+		}
+		fdsJSON, err := json.Marshal(fds)
+		if err != nil {
+			return nil, fmt.Errorf("error creating _LIBCONTAINER_SECRET_FDS: %w", err)
+		}
+		cmd.Env = append(cmd.Env, "_LIBCONTAINER_SECRET_FDS="+string(fdsJSON))
+	}
 	if p.ConsoleSocket != nil {
 		cmd.ExtraFiles = append(cmd.ExtraFiles, p.ConsoleSocket)
 		cmd.Env = append(cmd.Env,
@@ -623,6 +827,12 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 		cmd.SysProcAttr.Pdeathsig = unix.Signal(c.config.ParentDeathSignal)
 	}
 
+	if p.Init && c.config.Cgroups.DirFd != 0 {
+		// Start runc init right in the container cgroup.
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = c.config.Cgroups.DirFd
+	}
+
 	if p.Init {
 		// We only set up fifoFd if we're not doing a `runc exec`. The historic
 		// reason for this is that previously we would pass a dirfd that allowed
@@ -654,9 +864,10 @@ func (c *Container) shouldSendMountSources() bool {
 		return false
 	}
 
-	// We need to send sources if there are non-idmap bind-mounts.
+	// We need to send sources if there are non-idmap bind-mounts (other
+	// than the ones of an already opened source).
 	for _, m := range c.config.Mounts {
-		if m.IsBind() && !m.IsIDMapped() {
+		if m.IsBind() && !m.IsIDMapped() && !m.IsSourceFd() {
 			return true
 		}
 	}
@@ -702,7 +913,7 @@ func (c *Container) sendMountSources(cmd *exec.Cmd, comm *processComm) error {
 	}
 
 	return c.sendFdsSources(cmd, comm, "_LIBCONTAINER_MOUNT_FDS", func(m *configs.Mount) bool {
-		return m.IsBind() && !m.IsIDMapped()
+		return m.IsBind() && !m.IsIDMapped() && !m.IsSourceFd()
 	})
 }
 
@@ -744,6 +955,9 @@ func (c *Container) sendFdsSources(cmd *exec.Cmd, comm *processComm, envVar stri
 }
 
 func (c *Container) newInitProcess(p *Process, cmd *exec.Cmd, comm *processComm) (*initProcess, error) {
+	if len(p.CgroupPaths) > 0 {
+		return nil, errors.New("CgroupPaths can not be used for the init process")
+	}
 	cmd.Env = append(cmd.Env, "_LIBCONTAINER_INITTYPE="+string(initStandard))
 	nsMaps := make(map[configs.NamespaceType]string)
 	for _, ns := range c.config.Namespaces {
@@ -803,33 +1017,26 @@ func (c *Container) newSetnsProcess(p *Process, cmd *exec.Cmd, comm *processComm
 		initProcessPid:  state.InitProcessPid,
 	}
 	if len(p.SubCgroupPaths) > 0 {
-		if add, ok := p.SubCgroupPaths[""]; ok {
-			// cgroup v1: using the same path for all controllers.
-			// cgroup v2: the only possible way.
-			for k := range proc.cgroupPaths {
-				subPath := path.Join(proc.cgroupPaths[k], add)
-				if !strings.HasPrefix(subPath, proc.cgroupPaths[k]) {
-					return nil, fmt.Errorf("%s is not a sub cgroup path", add)
-				}
-				proc.cgroupPaths[k] = subPath
-			}
+		proc.cgroupPaths, err = subCgroupPaths(proc.cgroupPaths, p.SubCgroupPaths)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.SubCgroupPaths[""]; ok {
 			// cgroup v2: do not try to join init process's cgroup
 			// as a fallback (see (*setnsProcess).start).
 			proc.initProcessPid = 0
-		} else {
-			// Per-controller paths.
-			for ctrl, add := range p.SubCgroupPaths {
-				if val, ok := proc.cgroupPaths[ctrl]; ok {
-					subPath := path.Join(val, add)
-					if !strings.HasPrefix(subPath, val) {
-						return nil, fmt.Errorf("%s is not a sub cgroup path", add)
-					}
-					proc.cgroupPaths[ctrl] = subPath
-				} else {
-					return nil, fmt.Errorf("unknown controller %s in SubCgroupPaths", ctrl)
-				}
-			}
 		}
+	} else if p.SubCgroupCreate || len(p.SubCgroupSettings) > 0 {
+		return nil, errors.New("sub-cgroup creation and settings require SubCgroupPaths")
+	}
+	if len(p.CgroupPaths) > 0 {
+		if len(p.SubCgroupPaths) > 0 {
+			return nil, errors.New("CgroupPaths can not be used with SubCgroupPaths")
+		}
+		proc.cgroupPaths = p.CgroupPaths
+		// Do not join the init process's cgroup as a fallback either
+		// (see (*setnsProcess).start).
+		proc.initProcessPid = 0
 	}
 	return proc, nil
 }
@@ -854,6 +1061,11 @@ func (c *Container) newInitConfig(process *Process) *initConfig {
 		CreateConsole:    process.ConsoleSocket != nil,
 		ConsoleWidth:     process.ConsoleWidth,
 		ConsoleHeight:    process.ConsoleHeight,
+		ConsoleVersion:   process.ConsoleSocketVersion,
+		Secrets:          process.Secrets,
+		MaskPaths:        process.maskPaths,
+		Nice:             getHelperNice(),
+		SeccompProgram:   c.seccompProgram,
 	}
 	if process.NoNewPrivileges != nil {
 		cfg.NoNewPrivileges = *process.NoNewPrivileges
@@ -865,7 +1077,10 @@ func (c *Container) newInitConfig(process *Process) *initConfig {
 		cfg.ProcessLabel = process.Label
 	}
 	if len(process.Rlimits) > 0 {
-		cfg.Rlimits = process.Rlimits
+		// The container limits, which may have been updated by SetRlimits,
+		// take precedence over the process ones of the same types (runc exec
+		// passes the ones of config.json).
+		cfg.Rlimits = mergeRlimits(process.Rlimits, c.config.Rlimits)
 	}
 	if cgroups.IsCgroup2UnifiedMode() {
 		cfg.Cgroup2Path = c.cgroupManager.Path("")
@@ -895,13 +1110,20 @@ func (c *Container) Destroy() error {
 func (c *Container) Pause() error {
 	c.m.Lock()
 	defer c.m.Unlock()
+	if c.config.Cgroups.Adopted {
+		return fmt.Errorf("unable to pause container: %w", ErrAdoptedCgroup)
+	}
 	status, err := c.currentStatus()
 	if err != nil {
 		return err
 	}
 	switch status {
 	case Running, Created:
+		if err := c.runFreezeHooks(configs.PreFreeze); err != nil {
+			return err
+		}
 		if err := c.cgroupManager.Freeze(configs.Frozen); err != nil {
+			c.runPostThawHooks()
 			return err
 		}
 		return c.state.transition(&pausedState{
@@ -928,9 +1150,13 @@ func (c *Container) Resume() error {
 	if err := c.cgroupManager.Freeze(configs.Thawed); err != nil {
 		return err
 	}
-	return c.state.transition(&runningState{
+	if err := c.state.transition(&runningState{
 		c: c,
-	})
+	}); err != nil {
+		return err
+	}
+	c.runPostThawHooks()
+	return nil
 }
 
 // NotifyOOM returns a read-only channel signaling when the container receives
@@ -972,8 +1198,16 @@ func (c *Container) updateState(process parentProcess) (*State, error) {
 	return state, nil
 }
 
-func (c *Container) saveState(s *State) (retErr error) {
-	tmpFile, err := os.CreateTemp(c.stateDir, "state-")
+func (c *Container) saveState(s *State) error {
+	return writeJSONAtomic(c.stateDir, stateFilename, stateTmpPrefix, s)
+}
+
+// writeJSONAtomic writes v as JSON to dir/name, so that the file is either
+// the old or the new one, even in case of a crash or a power loss. This is
+// achieved by writing to a temporary file (with the given prefix), which is
+// synced to disk and then renamed, and finally syncing the directory.
+func writeJSONAtomic(dir, name, tmpPrefix string, v interface{}) (retErr error) {
+	tmpFile, err := os.CreateTemp(dir, tmpPrefix)
 	if err != nil {
 		return err
 	}
@@ -985,17 +1219,27 @@ func (c *Container) saveState(s *State) (retErr error) {
 		}
 	}()
 
-	err = utils.WriteJSON(tmpFile, s)
+	err = utils.WriteJSON(tmpFile, v)
 	if err != nil {
 		return err
 	}
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
 	err = tmpFile.Close()
 	if err != nil {
 		return err
 	}
 
-	stateFilePath := filepath.Join(c.stateDir, stateFilename)
-	return os.Rename(tmpFile.Name(), stateFilePath)
+	if err := os.Rename(tmpFile.Name(), filepath.Join(dir, name)); err != nil {
+		return err
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
 }
 
 func (c *Container) currentStatus() (Status, error) {
@@ -1075,12 +1319,20 @@ func (c *Container) currentState() (*State, error) {
 			InitProcessPid:       pid,
 			InitProcessStartTime: startTime,
 			Created:              c.created,
+			Metadata:             c.metadata,
 		},
 		Rootless:            c.config.RootlessEUID && c.config.RootlessCgroups,
 		CgroupPaths:         c.cgroupManager.GetPaths(),
 		IntelRdtPath:        intelRdtPath,
+		CNIResult:           c.cniResult,
+		SetupCost:           c.setupCost,
+		HelperOomScoreAdj:   c.helperOomScoreAdj,
+		RuntimeMaskPaths:    c.runtimeMaskPaths,
+		ExitReason:          c.exitReason,
 		NamespacePaths:      make(map[configs.NamespaceType]string),
 		ExternalDescriptors: externalDescriptors,
+		Version:             StateVersion,
+		MinReaderVersion:    StateMinReaderVersion,
 	}
 	if pid > 0 {
 		for _, ns := range c.config.Namespaces {
@@ -1095,6 +1347,14 @@ func (c *Container) currentState() (*State, error) {
 				state.NamespacePaths[ns.Type] = ns.GetPath(pid)
 			}
 		}
+		state.Namespaces = c.namespaceStates(pid)
+	}
+	if c.config.Cgroups != nil {
+		r := c.config.Cgroups.Resources
+		if r == nil {
+			r = &configs.Resources{}
+		}
+		state.CgroupV2Unavailable = cgroups.UnavailableV2Features(r)
 	}
 	return state, nil
 }
@@ -1224,6 +1484,10 @@ func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Namespa
 						Type:  UidmapPathAttr,
 						Value: []byte(path),
 					})
+					r.AddData(&Int32msg{
+						Type:  UidmapTimeoutAttr,
+						Value: timeoutMsec(extcmd.Timeout(path)),
+					})
 				}
 			}
 			b, err := encodeIDMapping(c.config.UIDMappings)
@@ -1252,6 +1516,10 @@ func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Namespa
 						Type:  GidmapPathAttr,
 						Value: []byte(path),
 					})
+					r.AddData(&Int32msg{
+						Type:  GidmapTimeoutAttr,
+						Value: timeoutMsec(extcmd.Timeout(path)),
+					})
 				}
 			}
 			if requiresRootOrMappingTool(c.config) {
@@ -1263,7 +1531,7 @@ func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Namespa
 		}
 	}
 
-	if c.config.OomScoreAdj != nil {
+	if c.config.OomScoreAdj != nil && !deferOomScoreAdj(c.config, it) {
 		// write oom_score_adj
 		r.AddData(&Bytemsg{
 			Type:  OomScoreAdjAttr,
@@ -1281,7 +1549,7 @@ func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Namespa
 	if it == initStandard && c.shouldSendMountSources() {
 		var mounts []byte
 		for _, m := range c.config.Mounts {
-			if m.IsBind() && !m.IsIDMapped() {
+			if m.IsBind() && !m.IsIDMapped() && !m.IsSourceFd() {
 				if strings.IndexByte(m.Source, 0) >= 0 {
 					return nil, fmt.Errorf("mount source string contains null byte: %q", m.Source)
 				}
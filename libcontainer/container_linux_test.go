@@ -3,6 +3,8 @@ package libcontainer
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -223,6 +225,30 @@ func TestGetContainerState(t *testing.T) {
 			}
 		}
 	}
+	origins := map[configs.NamespaceType]string{
+		configs.NEWPID: NamespaceCreated,
+		configs.NEWNET: NamespaceJoined,
+		configs.NEWIPC: NamespaceInherited,
+	}
+	for _, ns := range state.Namespaces {
+		if ns.Inode == 0 {
+			t.Errorf("expected an inode for %s", ns.Type)
+		}
+		origin, ok := origins[ns.Type]
+		if !ok {
+			continue
+		}
+		delete(origins, ns.Type)
+		if ns.Origin != origin {
+			t.Errorf("expected %s origin %q but received %q", ns.Type, origin, ns.Origin)
+		}
+		if (ns.Path != "") != (origin == NamespaceJoined) {
+			t.Errorf("unexpected %s path %q", ns.Type, ns.Path)
+		}
+	}
+	if len(origins) != 0 {
+		t.Errorf("namespaces missing from the state: %v", origins)
+	}
 }
 
 func TestGetContainerStateAfterUpdate(t *testing.T) {
@@ -286,3 +312,59 @@ func TestGetContainerStateAfterUpdate(t *testing.T) {
 		t.Fatalf("expected Memory to be 2048 but received %q", state.Config.Cgroups.Memory)
 	}
 }
+
+func TestContainerSetMetadata(t *testing.T) {
+	pid := os.Getpid()
+	stat, err := system.Stat(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	container := &Container{
+		stateDir: filepath.Join(root, "myid"),
+		id:       "myid",
+		config: &configs.Config{
+			Cgroups: &configs.Cgroup{
+				Resources: &configs.Resources{},
+			},
+		},
+		initProcess: &mockProcess{
+			_pid:    pid,
+			started: stat.StartTime,
+		},
+		initProcessStartTime: stat.StartTime,
+		cgroupManager:        &mockCgroupManager{},
+	}
+	container.state = &runningState{c: container}
+	if err := os.Mkdir(container.stateDir, 0o711); err != nil {
+		t.Fatal(err)
+	}
+	// No state saved yet.
+	if err := container.SetMetadata(map[string]string{"a": "1"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := container.updateState(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := container.SetMetadata(map[string]string{"a": "1", "b": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := container.SetMetadata(map[string]string{"a": "", "c": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"b": "2", "c": "3"}
+	if m := container.Metadata(); !reflect.DeepEqual(m, expected) {
+		t.Fatalf("expected metadata %v, got %v", expected, m)
+	}
+
+	// The metadata is persisted in the state.
+	state, err := loadState(container.stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.Metadata, expected) {
+		t.Fatalf("expected saved metadata %v, got %v", expected, state.Metadata)
+	}
+}
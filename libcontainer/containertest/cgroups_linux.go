@@ -0,0 +1,103 @@
+package containertest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
+)
+
+// CgroupVersion returns the cgroup version (1 or 2) used by the host.
+// The hybrid hierarchy counts as cgroup v1.
+func CgroupVersion() int {
+	if cgroups.IsCgroup2UnifiedMode() {
+		return 2
+	}
+	return 1
+}
+
+// CgroupPath returns the path of the container cgroup for the given
+// controller (such as "memory"), which is ignored for cgroup v2. The
+// test fails if the path is not known.
+func CgroupPath(t testing.TB, c *libcontainer.Container, controller string) string {
+	t.Helper()
+	state, err := c.State()
+	Ok(t, err)
+	if cgroups.IsCgroup2UnifiedMode() {
+		controller = ""
+	}
+	path := state.CgroupPaths[controller]
+	if path == "" {
+		t.Fatalf("no cgroup path for controller %q in %v", controller, state.CgroupPaths)
+	}
+	return path
+}
+
+// ReadCgroupFile returns the trimmed content of the file (such as
+// "pids.max") from the container cgroup for the given controller.
+func ReadCgroupFile(t testing.TB, c *libcontainer.Container, controller, file string) string {
+	t.Helper()
+	value, err := cgroups.ReadFile(CgroupPath(t, c, controller), file)
+	Ok(t, err)
+	return strings.TrimSpace(value)
+}
+
+// ExpectCgroupValue fails the test if the content of the file from the
+// container cgroup for the given controller is not the expected one.
+func ExpectCgroupValue(t testing.TB, c *libcontainer.Container, controller, file, expected string) {
+	t.Helper()
+	if value := ReadCgroupFile(t, c, controller, file); value != expected {
+		t.Fatalf("expected %s to be %q, got %q", file, expected, value)
+	}
+}
+
+// RequireCgroupV1 skips the test unless the host uses cgroup v1.
+func RequireCgroupV1(t testing.TB) {
+	t.Helper()
+	if CgroupVersion() != 1 {
+		t.Skip("Test requires cgroup v1.")
+	}
+}
+
+// RequireCgroupV2 skips the test unless the host uses cgroup v2.
+func RequireCgroupV2(t testing.TB) {
+	t.Helper()
+	if CgroupVersion() != 2 {
+		t.Skip("Test requires cgroup v2.")
+	}
+}
+
+// RequireSystemd skips the test unless the host is running systemd.
+func RequireSystemd(t testing.TB) {
+	t.Helper()
+	if !systemd.IsRunningSystemd() {
+		t.Skip("Test requires systemd.")
+	}
+}
+
+// RequireUserns skips the test if user namespaces are not supported.
+func RequireUserns(t testing.TB) {
+	t.Helper()
+	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
+		t.Skip("Test requires userns.")
+	}
+}
+
+// RequireRoot skips the test unless it is run by root.
+func RequireRoot(t testing.TB) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+}
+
+// RequireRootless skips the test unless it is run by a non-root user.
+func RequireRootless(t testing.TB) {
+	t.Helper()
+	if os.Geteuid() == 0 {
+		t.Skip("Test requires a non-root user.")
+	}
+}
@@ -0,0 +1,230 @@
+package containertest
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+// StandardEnvironment is the environment of the processes started by
+// [Run].
+var StandardEnvironment = []string{
+	"HOME=/root",
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	"HOSTNAME=integration",
+	"TERM=xterm",
+}
+
+// DefaultMountFlags are the flags of the proc, /dev/shm, sysfs, and cgroup
+// mounts of the configuration returned by [NewConfig].
+const DefaultMountFlags = unix.MS_NOEXEC | unix.MS_NOSUID | unix.MS_NODEV
+
+// Param are the parameters of the configuration returned by [NewConfig].
+type Param struct {
+	// Userns adds a new user namespace, with the first 1000 IDs mapped
+	// to the same IDs on the host.
+	Userns bool
+	// Systemd makes the systemd cgroup manager be used.
+	Systemd bool
+	// Rootless makes the container rootless: it runs in a new user
+	// namespace, with root mapped to the current (non-root) user, and
+	// the cgroup errors are ignored. Implies Userns.
+	Rootless bool
+	// CgroupVersion, if non-zero, is the cgroup version (1 or 2) the
+	// host must be using. It is only used by [RunMatrix], to skip the
+	// variants which do not apply to the host.
+	CgroupVersion int
+}
+
+// String returns a short name of the parameters, such as "systemd+userns",
+// used as the subtest name by [RunMatrix].
+func (p Param) String() string {
+	name := "fs"
+	if p.Systemd {
+		name = "systemd"
+	}
+	if p.Rootless {
+		name += "+rootless"
+	} else if p.Userns {
+		name += "+userns"
+	}
+	if p.CgroupVersion != 0 {
+		name += "+v" + strconv.Itoa(p.CgroupVersion)
+	}
+	return name
+}
+
+// NewConfig returns a base template for running a container with the
+// given rootfs (see [NewRootfs]).
+//
+// It uses a network strategy of just setting a loopback interface
+// and the default setup for devices.
+//
+// If p is nil, a default container is created.
+func NewConfig(t testing.TB, rootfs string, p *Param) *configs.Config {
+	t.Helper()
+	var allowedDevices []*devices.Rule
+	for _, device := range specconv.AllowedDevices {
+		allowedDevices = append(allowedDevices, &device.Rule)
+	}
+	if p == nil {
+		p = &Param{}
+	}
+	caps := []string{
+		"CAP_CHOWN",
+		"CAP_DAC_OVERRIDE",
+		"CAP_FSETID",
+		"CAP_FOWNER",
+		"CAP_MKNOD",
+		"CAP_NET_RAW",
+		"CAP_SETGID",
+		"CAP_SETUID",
+		"CAP_SETFCAP",
+		"CAP_SETPCAP",
+		"CAP_NET_BIND_SERVICE",
+		"CAP_SYS_CHROOT",
+		"CAP_KILL",
+		"CAP_AUDIT_WRITE",
+	}
+	config := &configs.Config{
+		Rootfs: rootfs,
+		Capabilities: &configs.Capabilities{
+			Bounding:  caps,
+			Permitted: append([]string(nil), caps...),
+			Ambient:   append([]string(nil), caps...),
+			Effective: append([]string(nil), caps...),
+		},
+		Namespaces: configs.Namespaces([]configs.Namespace{
+			{Type: configs.NEWNS},
+			{Type: configs.NEWUTS},
+			{Type: configs.NEWIPC},
+			{Type: configs.NEWPID},
+			{Type: configs.NEWNET},
+		}),
+		Cgroups: &configs.Cgroup{
+			Systemd: p.Systemd,
+			Resources: &configs.Resources{
+				MemorySwappiness: nil,
+				Devices:          allowedDevices,
+			},
+		},
+		MaskPaths: []string{
+			"/proc/kcore",
+			"/sys/firmware",
+		},
+		ReadonlyPaths: []string{
+			"/proc/sys", "/proc/sysrq-trigger", "/proc/irq", "/proc/bus",
+		},
+		Devices:    specconv.AllowedDevices,
+		Hostname:   "integration",
+		Domainname: "integration",
+		Mounts: []*configs.Mount{
+			{
+				Source:      "proc",
+				Destination: "/proc",
+				Device:      "proc",
+				Flags:       DefaultMountFlags,
+			},
+			{
+				Source:      "tmpfs",
+				Destination: "/dev",
+				Device:      "tmpfs",
+				Flags:       unix.MS_NOSUID | unix.MS_STRICTATIME,
+				Data:        "mode=755",
+			},
+			{
+				Source:      "devpts",
+				Destination: "/dev/pts",
+				Device:      "devpts",
+				Flags:       unix.MS_NOSUID | unix.MS_NOEXEC,
+				Data:        "newinstance,ptmxmode=0666,mode=0620,gid=5",
+			},
+			{
+				Device:      "tmpfs",
+				Source:      "shm",
+				Destination: "/dev/shm",
+				Data:        "mode=1777,size=65536k",
+				Flags:       DefaultMountFlags,
+			},
+			/*
+				            CI is broken on the debian based kernels with this
+							{
+								Source:      "mqueue",
+								Destination: "/dev/mqueue",
+								Device:      "mqueue",
+								Flags:       DefaultMountFlags,
+							},
+			*/
+			{
+				Source:      "sysfs",
+				Destination: "/sys",
+				Device:      "sysfs",
+				Flags:       DefaultMountFlags | unix.MS_RDONLY,
+			},
+		},
+		Networks: []*configs.Network{
+			{
+				Type:    "loopback",
+				Address: "127.0.0.1/0",
+				Gateway: "localhost",
+			},
+		},
+		Rlimits: []configs.Rlimit{
+			{
+				Type: unix.RLIMIT_NOFILE,
+				Hard: uint64(1025),
+				Soft: uint64(1025),
+			},
+		},
+	}
+
+	switch {
+	case p.Rootless:
+		uid, gid := os.Geteuid(), os.Getegid()
+		config.UIDMappings = []configs.IDMap{{HostID: int64(uid), ContainerID: 0, Size: 1}}
+		config.GIDMappings = []configs.IDMap{{HostID: int64(gid), ContainerID: 0, Size: 1}}
+		config.Namespaces = append(config.Namespaces, configs.Namespace{Type: configs.NEWUSER})
+		config.RootlessEUID = uid != 0
+		config.RootlessCgroups = true
+		config.Cgroups.Rootless = true
+		// Only one group is mapped, so gid=5 can't be used for devpts.
+		for _, m := range config.Mounts {
+			if m.Device == "devpts" {
+				m.Data = strings.TrimSuffix(m.Data, ",gid=5")
+			}
+		}
+	case p.Userns:
+		config.UIDMappings = []configs.IDMap{{HostID: 0, ContainerID: 0, Size: 1000}}
+		config.GIDMappings = []configs.IDMap{{HostID: 0, ContainerID: 0, Size: 1000}}
+		config.Namespaces = append(config.Namespaces, configs.Namespace{Type: configs.NEWUSER})
+	default:
+		config.Mounts = append(config.Mounts, &configs.Mount{
+			Destination: "/sys/fs/cgroup",
+			Device:      "cgroup",
+			Flags:       DefaultMountFlags | unix.MS_RDONLY,
+		})
+	}
+
+	if p.Systemd {
+		id := strconv.FormatInt(-int64(time.Now().Nanosecond()), 36)
+		config.Cgroups.Name = strings.ReplaceAll(t.Name(), "/", "_") + id
+		config.Cgroups.ScopePrefix = "runc-test"
+		if !p.Rootless {
+			// Rootless containers go into the user manager's
+			// default slice.
+			config.Cgroups.Parent = "system.slice"
+		}
+	} else {
+		config.Cgroups.Path = "/test/integration"
+	}
+
+	return config
+}
@@ -0,0 +1,85 @@
+package containertest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestParamString(t *testing.T) {
+	for _, tc := range []struct {
+		p    Param
+		name string
+	}{
+		{Param{}, "fs"},
+		{Param{Userns: true}, "fs+userns"},
+		{Param{Systemd: true, Userns: true}, "systemd+userns"},
+		{Param{Rootless: true, Userns: true}, "fs+rootless"},
+		{Param{Systemd: true, CgroupVersion: 2}, "systemd+v2"},
+	} {
+		if name := tc.p.String(); name != tc.name {
+			t.Errorf("%+v: expected %q, got %q", tc.p, tc.name, name)
+		}
+	}
+}
+
+func hasCgroupMount(config *configs.Config) bool {
+	for _, m := range config.Mounts {
+		if m.Device == "cgroup" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNewConfig(t *testing.T) {
+	config := NewConfig(t, "/rootfs", nil)
+	if config.Rootfs != "/rootfs" {
+		t.Errorf("unexpected rootfs %q", config.Rootfs)
+	}
+	if config.Namespaces.Contains(configs.NEWUSER) || len(config.UIDMappings) != 0 {
+		t.Error("unexpected user namespace")
+	}
+	if !hasCgroupMount(config) {
+		t.Error("expected a cgroup mount")
+	}
+	if config.Cgroups.Path == "" || config.Cgroups.Systemd {
+		t.Errorf("unexpected cgroup config %+v", config.Cgroups)
+	}
+
+	config = NewConfig(t, "/rootfs", &Param{Userns: true, Systemd: true})
+	if !config.Namespaces.Contains(configs.NEWUSER) {
+		t.Error("expected a user namespace")
+	}
+	if m := config.UIDMappings; len(m) != 1 || m[0].HostID != 0 || m[0].Size != 1000 {
+		t.Errorf("unexpected uid mappings %+v", m)
+	}
+	if hasCgroupMount(config) {
+		t.Error("unexpected cgroup mount")
+	}
+	if !config.Cgroups.Systemd || config.Cgroups.Name == "" || config.Cgroups.Parent != "system.slice" {
+		t.Errorf("unexpected cgroup config %+v", config.Cgroups)
+	}
+}
+
+func TestNewConfigRootless(t *testing.T) {
+	config := NewConfig(t, "/rootfs", &Param{Rootless: true, Systemd: true})
+	if !config.Namespaces.Contains(configs.NEWUSER) {
+		t.Error("expected a user namespace")
+	}
+	if m := config.UIDMappings; len(m) != 1 || m[0].HostID != int64(os.Geteuid()) || m[0].Size != 1 {
+		t.Errorf("unexpected uid mappings %+v", m)
+	}
+	if m := config.GIDMappings; len(m) != 1 || m[0].HostID != int64(os.Getegid()) || m[0].Size != 1 {
+		t.Errorf("unexpected gid mappings %+v", m)
+	}
+	if !config.RootlessCgroups || !config.Cgroups.Rootless || config.Cgroups.Parent != "" {
+		t.Errorf("unexpected cgroup config %+v", config.Cgroups)
+	}
+	for _, m := range config.Mounts {
+		if m.Device == "devpts" && m.Data != "newinstance,ptmxmode=0666,mode=0620" {
+			t.Errorf("unexpected devpts options %q", m.Data)
+		}
+	}
+}
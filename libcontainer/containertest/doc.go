@@ -0,0 +1,27 @@
+// Package containertest provides helpers to write Go tests running
+// containers in-process via libcontainer, without the bats suite.
+//
+// It is used by the libcontainer integration tests, and can be used by
+// the projects embedding libcontainer to test their own containers.
+//
+// As libcontainer re-executes the current binary (i.e. the test binary) as
+// the container init, the test package must call [Init] from an init
+// function:
+//
+//	func init() {
+//		containertest.Init()
+//	}
+//
+//	func TestHello(t *testing.T) {
+//		containertest.RunMatrix(t, containertest.DefaultMatrix, func(t *testing.T, p containertest.Param) {
+//			config := containertest.NewConfig(t, containertest.NewRootfs(t, busyboxTar), &p)
+//			out := containertest.RunOK(t, config, "echo", "hello")
+//			if out.Stdout.String() != "hello\n" {
+//				t.Fatalf("unexpected output: %q", out)
+//			}
+//		})
+//	}
+//
+// Most of the helpers require root, except for the rootless variant
+// (see [Param.Rootless]) which requires a non-root user.
+package containertest
@@ -0,0 +1,20 @@
+package containertest
+
+import (
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer"
+	//nolint:revive // Enable cgroup manager to manage devices
+	_ "github.com/opencontainers/runc/libcontainer/cgroups/devices"
+	_ "github.com/opencontainers/runc/libcontainer/nsenter"
+)
+
+// Init runs the container init if the current process (the test binary)
+// was executed by libcontainer as such, and returns otherwise. It must be
+// called from an init function of the test package, same as runc does in
+// its main package.
+func Init() {
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		libcontainer.Init()
+	}
+}
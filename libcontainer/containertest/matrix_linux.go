@@ -0,0 +1,56 @@
+package containertest
+
+import (
+	"strconv"
+	"testing"
+)
+
+// DefaultMatrix are the variants run by [RunMatrix] to test a container
+// with both cgroup managers, with and without a user namespace, and
+// rootless.
+var DefaultMatrix = []Param{
+	{},
+	{Userns: true},
+	{Systemd: true},
+	{Systemd: true, Userns: true},
+	{Rootless: true},
+	{Rootless: true, Systemd: true},
+}
+
+// RunMatrix runs fn as a subtest for each variant, skipping the variants
+// which can not be run on this host or by this user:
+//
+//   - the systemd variants, if systemd is not running;
+//   - the user namespace variants, if user namespaces are not supported;
+//   - the variants with a CgroupVersion other than the host one;
+//   - the rootless variants when run by root, and the other ones when
+//     run by a non-root user.
+//
+// The subtests are grouped by the host cgroup version ("cgroupv1" or
+// "cgroupv2"), so that the results of the runs on different hosts can be
+// told apart. The variant must be passed to [NewConfig].
+func RunMatrix(t *testing.T, variants []Param, fn func(t *testing.T, p Param)) {
+	t.Helper()
+	t.Run("cgroupv"+strconv.Itoa(CgroupVersion()), func(t *testing.T) {
+		for _, p := range variants {
+			p := p
+			t.Run(p.String(), func(t *testing.T) {
+				if p.CgroupVersion != 0 && p.CgroupVersion != CgroupVersion() {
+					t.Skipf("Test requires cgroup v%d.", p.CgroupVersion)
+				}
+				if p.Systemd {
+					RequireSystemd(t)
+				}
+				if p.Userns || p.Rootless {
+					RequireUserns(t)
+				}
+				if p.Rootless {
+					RequireRootless(t)
+				} else {
+					RequireRoot(t)
+				}
+				fn(t, p)
+			})
+		}
+	})
+}
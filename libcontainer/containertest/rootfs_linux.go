@@ -0,0 +1,73 @@
+package containertest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// NewRootfs creates a new temporary directory and extracts the root
+// filesystem from the tarball (such as a busybox image) to it.
+func NewRootfs(t testing.TB, tarball string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := CopyRootfs(dir, tarball); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make sure others can read+exec, so all tests (inside userns too) can
+	// read the rootfs.
+	if err := TraversePath(dir); err != nil {
+		t.Fatalf("Error making newRootfs path traversable by others: %v", err)
+	}
+
+	return dir
+}
+
+// CopyRootfs extracts the root filesystem from the tarball into dest,
+// skipping the device nodes (the container /dev is a tmpfs).
+func CopyRootfs(dest, tarball string) error {
+	out, err := exec.Command("sh", "-c", fmt.Sprintf("tar --exclude './dev/*' -C %q -xf %q", dest, tarball)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("untar error %w: %q", err, out)
+	}
+	return nil
+}
+
+// TraversePath gives read+execute permissions to others for all elements in tPath below
+// os.TempDir() and errors out if elements above it don't have read+exec permissions for others.
+// tPath MUST be a descendant of os.TempDir(). The path returned by testing.TempDir() usually is.
+func TraversePath(tPath string) error {
+	// Check the assumption that the argument is under os.TempDir().
+	tempBase := os.TempDir()
+	if !strings.HasPrefix(tPath, tempBase) {
+		return fmt.Errorf("traversePath: %q is not a descendant of %q", tPath, tempBase)
+	}
+
+	var path string
+	for _, p := range strings.SplitAfter(tPath, "/") {
+		path = path + p
+		stats, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		perm := stats.Mode().Perm()
+
+		if perm&0o5 == 0o5 {
+			continue
+		}
+
+		if strings.HasPrefix(tempBase, path) {
+			return fmt.Errorf("traversePath: directory %q MUST have read+exec permissions for others", path)
+		}
+
+		if err := os.Chmod(path, perm|0o5); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
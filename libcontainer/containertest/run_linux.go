@@ -0,0 +1,133 @@
+package containertest
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// Buffers are the stdio buffers of a process run by [Run].
+type Buffers struct {
+	Stdin  *bytes.Buffer
+	Stdout *bytes.Buffer
+	Stderr *bytes.Buffer
+}
+
+// NewBuffers returns new empty stdio buffers.
+func NewBuffers() *Buffers {
+	return &Buffers{
+		Stdin:  bytes.NewBuffer(nil),
+		Stdout: bytes.NewBuffer(nil),
+		Stderr: bytes.NewBuffer(nil),
+	}
+}
+
+func (b *Buffers) String() string {
+	s := []string{}
+	if b.Stderr != nil {
+		s = append(s, b.Stderr.String())
+	}
+	if b.Stdout != nil {
+		s = append(s, b.Stdout.String())
+	}
+	return strings.Join(s, "|")
+}
+
+// Ok fails the test if an err is not nil.
+func Ok(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// WaitProcess waits for the process, and fails the test unless the
+// process exited with 0.
+func WaitProcess(t testing.TB, p *libcontainer.Process) {
+	t.Helper()
+	status, err := p.Wait()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !status.Success() {
+		t.Fatalf("unexpected status: %v", status)
+	}
+}
+
+// NewContainer creates a container with the given config, using a
+// temporary state directory, and a container ID derived from the test
+// name. The container is not destroyed automatically, see [Destroy].
+func NewContainer(t testing.TB, config *configs.Config) (*libcontainer.Container, error) {
+	name := strings.ReplaceAll(t.Name(), "/", "_") + strconv.FormatInt(-int64(time.Now().Nanosecond()), 35)
+	root := t.TempDir()
+
+	return libcontainer.Create(root, name, config)
+}
+
+// Destroy destroys the container, ignoring any error.
+func Destroy(container *libcontainer.Container) {
+	_ = container.Destroy()
+}
+
+// Run runs the container with the specific config and arguments
+//
+// buffers are returned containing the STDOUT and STDERR output for the run
+// along with the exit code and any go error. If the process is killed by
+// a signal, the exit code is the negated signal number.
+func Run(t testing.TB, config *configs.Config, args ...string) (buffers *Buffers, exitCode int, err error) {
+	container, err := NewContainer(t, config)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer Destroy(container)
+	buffers = NewBuffers()
+	process := &libcontainer.Process{
+		Cwd:    "/",
+		Args:   args,
+		Env:    StandardEnvironment,
+		Stdin:  buffers.Stdin,
+		Stdout: buffers.Stdout,
+		Stderr: buffers.Stderr,
+		Init:   true,
+	}
+
+	err = container.Run(process)
+	if err != nil {
+		return buffers, -1, err
+	}
+	ps, err := process.Wait()
+	if err != nil {
+		return buffers, -1, err
+	}
+	status := ps.Sys().(syscall.WaitStatus)
+	if status.Exited() {
+		exitCode = status.ExitStatus()
+	} else if status.Signaled() {
+		exitCode = -int(status.Signal())
+	} else {
+		return buffers, -1, err
+	}
+	return
+}
+
+// RunOK is a wrapper for Run, simplifying its use for cases
+// when the run is expected to succeed and return exit code of 0.
+func RunOK(t testing.TB, config *configs.Config, args ...string) *Buffers {
+	t.Helper()
+	buffers, exitCode, err := Run(t, config, args...)
+	if err != nil {
+		t.Fatalf("%s: %s", buffers, err)
+	}
+	if exitCode != 0 {
+		t.Fatalf("exit code not 0. code %d stderr %q", exitCode, buffers.Stderr)
+	}
+
+	return buffers
+}
@@ -0,0 +1,221 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+const (
+	// cpusetAllocFilename is the file in the container state directory
+	// which records the CPUs allocated to the container. As it is removed
+	// along with the state directory, the CPUs are released once the
+	// container is deleted.
+	cpusetAllocFilename = "cpuset-alloc.json"
+	// cpusetAllocLockFilename is the file in the root directory locked
+	// while allocating CPUs.
+	cpusetAllocLockFilename = ".cpuset-alloc.lock"
+)
+
+// nodeSysDir can be changed by unit tests.
+var nodeSysDir = "/sys/devices/system/node"
+
+type cpusetAllocation struct {
+	CPUs string `json:"cpus"`
+	Mems string `json:"mems,omitempty"`
+}
+
+// allocateCpuset picks config.CpusetAlloc.CPUs CPUs from the pool which are
+// not allocated to other containers (in root), preferring the CPUs from a
+// single NUMA node, records the allocation in stateDir, and sets the
+// container cpuset accordingly. The memory nodes are set to the NUMA nodes
+// of the allocated CPUs, unless they are set in the configuration.
+func allocateCpuset(root, stateDir string, config *configs.Config) error {
+	alloc := config.CpusetAlloc
+	lock, err := os.OpenFile(filepath.Join(root, cpusetAllocLockFilename), os.O_RDWR|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := unix.Flock(int(lock.Fd()), unix.LOCK_EX); err != nil {
+		return &os.PathError{Op: "flock", Path: lock.Name(), Err: err}
+	}
+
+	pool, err := cpusetPool(alloc.Pool)
+	if err != nil {
+		return err
+	}
+	used, err := allocatedCPUs(root)
+	if err != nil {
+		return err
+	}
+	var free []uint16
+	for _, c := range pool {
+		if !used[c] {
+			free = append(free, c)
+		}
+	}
+	cpus, nodes, err := pickCPUs(free, numaNodes(), alloc.CPUs)
+	if err != nil {
+		return fmt.Errorf("unable to allocate %d CPUs from %s: %w", alloc.CPUs, cgroups.FormatCPUList(pool), err)
+	}
+
+	a := cpusetAllocation{CPUs: cgroups.FormatCPUList(cpus)}
+	if len(nodes) > 0 {
+		a.Mems = cgroups.FormatCPUList(nodes)
+	}
+	if err := writeJSONAtomic(stateDir, cpusetAllocFilename, ".cpuset-alloc-", &a); err != nil {
+		return err
+	}
+	if config.Cgroups.Resources == nil {
+		config.Cgroups.Resources = &configs.Resources{}
+	}
+	config.Cgroups.Resources.CpusetCpus = a.CPUs
+	if config.Cgroups.Resources.CpusetMems == "" {
+		config.Cgroups.Resources.CpusetMems = a.Mems
+	}
+	return nil
+}
+
+// cpusetPool returns the online CPUs from the pool file, or all online CPUs
+// if the pool file is not set.
+func cpusetPool(file string) ([]uint16, error) {
+	online, err := cgroups.OnlineCPUs()
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return online, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cpuset pool: %w", err)
+	}
+	pool, err := cgroups.ParseCPUList(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cpuset pool %s: %w", file, err)
+	}
+	return cgroups.IntersectCPUList(pool, online), nil
+}
+
+// allocatedCPUs returns the CPUs allocated to the containers in root.
+func allocatedCPUs(root string) (map[uint16]bool, error) {
+	files, err := filepath.Glob(filepath.Join(root, "*", cpusetAllocFilename))
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[uint16]bool)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		var a cpusetAllocation
+		if err := json.Unmarshal(data, &a); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", f, err)
+		}
+		cpus, err := cgroups.ParseCPUList(a.CPUs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		for _, c := range cpus {
+			used[c] = true
+		}
+	}
+	return used, nil
+}
+
+// numaNodes returns the CPUs of each NUMA node, or nil if the NUMA topology
+// is not available.
+func numaNodes() map[int][]uint16 {
+	dirs, _ := filepath.Glob(filepath.Join(nodeSysDir, "node[0-9]*"))
+	nodes := make(map[int][]uint16, len(dirs))
+	for _, d := range dirs {
+		n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(d), "node"))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d, "cpulist"))
+		if err != nil {
+			continue
+		}
+		if cpus, err := cgroups.ParseCPUList(string(data)); err == nil {
+			nodes[n] = cpus
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes
+}
+
+// pickCPUs picks n CPUs from free (a sorted list). If possible, the CPUs
+// are taken from a single NUMA node, choosing the node with the fewest free
+// CPUs that fit (to keep the larger free sets for larger allocations).
+// Otherwise, the nodes with the most free CPUs are used. The chosen NUMA
+// nodes are returned as well (nil if nodes is nil).
+func pickCPUs(free []uint16, nodes map[int][]uint16, n int) ([]uint16, []uint16, error) {
+	if n <= 0 {
+		return nil, nil, errors.New("invalid number of CPUs")
+	}
+	if len(free) < n {
+		return nil, nil, fmt.Errorf("only %d CPUs are available", len(free))
+	}
+	if nodes == nil {
+		return free[:n], nil, nil
+	}
+
+	type nodeFree struct {
+		node uint16
+		cpus []uint16
+	}
+	var perNode []nodeFree
+	for node, cpus := range nodes {
+		if f := cgroups.IntersectCPUList(free, cpus); len(f) > 0 {
+			perNode = append(perNode, nodeFree{node: uint16(node), cpus: f})
+		}
+	}
+	// Fewest free CPUs first, then by node number.
+	sort.Slice(perNode, func(i, j int) bool {
+		if len(perNode[i].cpus) != len(perNode[j].cpus) {
+			return len(perNode[i].cpus) < len(perNode[j].cpus)
+		}
+		return perNode[i].node < perNode[j].node
+	})
+	for _, nf := range perNode {
+		if len(nf.cpus) >= n {
+			return nf.cpus[:n], []uint16{nf.node}, nil
+		}
+	}
+
+	// Spread over the nodes with the most free CPUs.
+	var cpus, used []uint16
+	for i := len(perNode) - 1; i >= 0 && len(cpus) < n; i-- {
+		take := perNode[i].cpus
+		if rest := n - len(cpus); len(take) > rest {
+			take = take[:rest]
+		}
+		cpus = append(cpus, take...)
+		used = append(used, perNode[i].node)
+	}
+	if len(cpus) < n {
+		// Some free CPUs are not in any node; should not happen.
+		return free[:n], nil, nil
+	}
+	sort.Slice(cpus, func(i, j int) bool { return cpus[i] < cpus[j] })
+	sort.Slice(used, func(i, j int) bool { return used[i] < used[j] })
+	return cpus, used, nil
+}
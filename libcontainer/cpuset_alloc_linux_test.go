@@ -0,0 +1,103 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestPickCPUs(t *testing.T) {
+	nodes := map[int][]uint16{
+		0: {0, 1, 2, 3},
+		1: {4, 5, 6, 7},
+	}
+	for _, tc := range []struct {
+		name  string
+		free  []uint16
+		nodes map[int][]uint16
+		n     int
+		cpus  []uint16
+		mems  []uint16
+		isErr bool
+	}{
+		{name: "no numa", free: []uint16{1, 2, 3}, n: 2, cpus: []uint16{1, 2}},
+		{name: "single node", free: []uint16{0, 1, 2, 3, 4, 5, 6, 7}, nodes: nodes, n: 3, cpus: []uint16{0, 1, 2}, mems: []uint16{0}},
+		// Node 1 is the best fit.
+		{name: "best fit", free: []uint16{0, 1, 2, 3, 6, 7}, nodes: nodes, n: 2, cpus: []uint16{6, 7}, mems: []uint16{1}},
+		{name: "first fit", free: []uint16{0, 1, 2, 3, 6, 7}, nodes: nodes, n: 3, cpus: []uint16{0, 1, 2}, mems: []uint16{0}},
+		{name: "spread", free: []uint16{1, 2, 3, 6, 7}, nodes: nodes, n: 4, cpus: []uint16{1, 2, 3, 6}, mems: []uint16{0, 1}},
+		{name: "not enough", free: []uint16{1, 2}, nodes: nodes, n: 3, isErr: true},
+		{name: "zero", free: []uint16{1, 2}, n: 0, isErr: true},
+	} {
+		cpus, mems, err := pickCPUs(tc.free, tc.nodes, tc.n)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %v", tc.name, cpus)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cpus, tc.cpus) || !reflect.DeepEqual(mems, tc.mems) {
+			t.Errorf("%s: expected %v %v, got %v %v", tc.name, tc.cpus, tc.mems, cpus, mems)
+		}
+	}
+}
+
+func TestAllocateCpuset(t *testing.T) {
+	root := t.TempDir()
+	oldNodeSysDir := nodeSysDir
+	defer func() { nodeSysDir = oldNodeSysDir }()
+	nodeSysDir = t.TempDir() // no NUMA information
+
+	// Use a pool of the first online CPU only.
+	online, err := os.ReadFile("/sys/devices/system/cpu/online")
+	if err != nil {
+		t.Skip(err)
+	}
+	first := string(online[:1])
+	pool := filepath.Join(root, "pool")
+	if err := os.WriteFile(pool, []byte(first+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	newConfig := func() *configs.Config {
+		return &configs.Config{
+			Cgroups:     &configs.Cgroup{},
+			CpusetAlloc: &configs.CpusetAlloc{CPUs: 1, Pool: pool},
+		}
+	}
+	stateDir := filepath.Join(root, "c1")
+	if err := os.Mkdir(stateDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	config := newConfig()
+	if err := allocateCpuset(root, stateDir, config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Cgroups.Resources.CpusetCpus != first {
+		t.Errorf("expected cpus %q, got %q", first, config.Cgroups.Resources.CpusetCpus)
+	}
+
+	// The only CPU is taken.
+	stateDir2 := filepath.Join(root, "c2")
+	if err := os.Mkdir(stateDir2, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := allocateCpuset(root, stateDir2, newConfig()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Once the first container is gone, the CPU is available again.
+	if err := os.RemoveAll(stateDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := allocateCpuset(root, stateDir2, newConfig()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package libcontainer
+
+import (
+	"fmt"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// cpusetResources returns the resources to set for the container cgroup.
+// With cgroup v1, if config.CpusetAdjust is set, this is a copy of r with
+// the requested CPUs which are offline removed, as cgroup v1 does not allow
+// to set offline CPUs. Otherwise, r is returned.
+//
+// With cgroup v2, the requested CPUs (cpuset.cpus) can be offline, and the
+// kernel takes care of the CPU hotplug (cpuset.cpus.effective).
+func cpusetResources(config *configs.Config, r *configs.Resources) (*configs.Resources, error) {
+	if !config.CpusetAdjust || r == nil || r.CpusetCpus == "" || cgroups.IsCgroup2UnifiedMode() {
+		return r, nil
+	}
+	cpus, err := onlineCpusetCpus(r.CpusetCpus)
+	if err != nil {
+		return nil, err
+	}
+	if cpus == r.CpusetCpus {
+		return r, nil
+	}
+	adjusted := *r
+	adjusted.CpusetCpus = cpus
+	return &adjusted, nil
+}
+
+// onlineCpusetCpus returns the online CPUs from the requested list.
+func onlineCpusetCpus(requested string) (string, error) {
+	cpus, err := cgroups.ParseCPUList(requested)
+	if err != nil {
+		return "", err
+	}
+	online, err := cgroups.OnlineCPUs()
+	if err != nil {
+		return "", err
+	}
+	cpus = cgroups.IntersectCPUList(cpus, online)
+	if len(cpus) == 0 {
+		return "", fmt.Errorf("none of the requested CPUs (%s) are online", requested)
+	}
+	return cgroups.FormatCPUList(cpus), nil
+}
+
+// SyncCpuset updates the container cpuset according to the online CPUs, if
+// the cpuset adjustment is enabled (see [configs.Config.CpusetAdjust]), and
+// returns the new cpuset.cpus value, or an empty string if it was not
+// changed. This is needed for cgroup v1 only, as the offlined CPUs are
+// removed from cpuset.cpus, and are not added back once online again.
+func (c *Container) SyncCpuset() (string, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	r := c.config.Cgroups.Resources
+	if !c.config.CpusetAdjust || r == nil || r.CpusetCpus == "" || cgroups.IsCgroup2UnifiedMode() {
+		return "", nil
+	}
+	want, err := onlineCpusetCpus(r.CpusetCpus)
+	if err != nil {
+		return "", err
+	}
+	path := c.cgroupManager.Path("cpuset")
+	current, err := cgroups.ReadFile(path, "cpuset.cpus")
+	if err != nil {
+		return "", err
+	}
+	cur, err := cgroups.ParseCPUList(current)
+	if err != nil {
+		return "", err
+	}
+	if cgroups.FormatCPUList(cur) == want {
+		return "", nil
+	}
+	if err := cgroups.WriteFile(path, "cpuset.cpus", want); err != nil {
+		return "", err
+	}
+	return want, nil
+}
@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"time"
 
@@ -23,6 +24,7 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/extcmd"
 	"github.com/opencontainers/runc/libcontainer/utils"
 )
 
@@ -106,6 +108,25 @@ func (c *Container) checkCriuVersion(minVersion int) error {
 	return compareCriuVersion(c.criuVersion, minVersion)
 }
 
+// criuArchVersions are the first CRIU versions supporting the architectures
+// added after CRIU 3.0.
+var criuArchVersions = map[string]int{
+	"riscv64": 40000, // CRIU 4.0
+}
+
+// checkCriuArch checks that the CRIU version supports the architecture runc
+// runs on. Older versions fail with obscure errors otherwise.
+func (c *Container) checkCriuArch() error {
+	minVersion, ok := criuArchVersions[runtime.GOARCH]
+	if !ok {
+		return nil
+	}
+	if err := c.checkCriuVersion(minVersion); err != nil {
+		return fmt.Errorf("checkpoint/restore on %s: %w", runtime.GOARCH, err)
+	}
+	return nil
+}
+
 const descriptorsFilename = "descriptors.json"
 
 func (c *Container) addCriuDumpMount(req *criurpc.CriuReq, m *configs.Mount) {
@@ -293,6 +314,9 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 	if err := c.checkCriuVersion(30000); err != nil {
 		return err
 	}
+	if err := c.checkCriuArch(); err != nil {
+		return err
+	}
 
 	if criuOpts.ImagesDirectory == "" {
 		return errors.New("invalid directory to save checkpoint")
@@ -364,7 +388,11 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 	// CRIU can use cgroup freezer; when rpcOpts.FreezeCgroup
 	// is not set, CRIU uses ptrace() to pause the processes.
 	// Note cgroup v2 freezer is only supported since CRIU release 3.14.
-	if !cgroups.IsCgroup2UnifiedMode() || c.checkCriuVersion(31400) == nil {
+	// An adopted cgroup may contain processes of its owner, which are not
+	// to be frozen.
+	if c.config.Cgroups.Adopted {
+		// Use ptrace.
+	} else if !cgroups.IsCgroup2UnifiedMode() || c.checkCriuVersion(31400) == nil {
 		if fcg := c.cgroupManager.Path("freezer"); fcg != "" {
 			rpcOpts.FreezeCgroup = proto.String(fcg)
 		}
@@ -482,7 +510,19 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 		}
 	}
 
+	// The pre-dumps are not meant to be consistent.
+	quiesce := !criuOpts.PreDump
+	if quiesce {
+		if err := c.runFreezeHooks(configs.PreFreeze); err != nil {
+			return err
+		}
+	}
 	err = c.criuSwrk(nil, req, criuOpts, nil)
+	// The container is still running if the dump failed, or if it was
+	// asked to be left running.
+	if quiesce && (err != nil || criuOpts.LeaveRunning) {
+		c.runPostThawHooks()
+	}
 	if err != nil {
 		logCriuErrors(logDir, logFile)
 		return err
@@ -537,6 +577,9 @@ func (c *Container) makeCriuRestoreMountpoints(m *configs.Mount) error {
 		//   the mountpoint appears as soon as /sys is mounted
 		return nil
 	case "bind":
+		if m.IsSourceFd() {
+			return fmt.Errorf("mount source %s: file descriptors as mount sources are not supported by restore", m.Source)
+		}
 		// The prepareBindMount() function checks if source
 		// exists. So it cannot be used for other filesystem types.
 		// TODO: pass srcFD? Not sure if criu is impacted by issue #2484.
@@ -648,6 +691,9 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 	if err := c.checkCriuVersion(30000); err != nil {
 		return err
 	}
+	if err := c.checkCriuArch(); err != nil {
+		return err
+	}
 	if criuOpts.ImagesDirectory == "" {
 		return errors.New("invalid directory to restore checkpoint")
 	}
@@ -802,6 +848,8 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 	err = c.criuSwrk(process, req, criuOpts, extraFiles)
 	if err != nil {
 		logCriuErrors(logDir, logFile)
+	} else {
+		c.runPostThawHooks()
 	}
 
 	// Now that CRIU is done let's close all opened FDs CRIU needed.
@@ -873,7 +921,11 @@ func (c *Container) criuApplyCgroups(pid int, req *criurpc.CriuReq) error {
 		return err
 	}
 
-	if err := c.cgroupManager.Set(c.config.Cgroups.Resources); err != nil {
+	resources, err := cpusetResources(c.config, c.config.Cgroups.Resources)
+	if err != nil {
+		return err
+	}
+	if err := c.cgroupManager.Set(resources); err != nil {
 		return err
 	}
 
@@ -896,7 +948,7 @@ func (c *Container) criuApplyCgroups(pid int, req *criurpc.CriuReq) error {
 	return nil
 }
 
-func (c *Container) criuSwrk(process *Process, req *criurpc.CriuReq, opts *CriuOpts, extraFiles []*os.File) error {
+func (c *Container) criuSwrk(process *Process, req *criurpc.CriuReq, opts *CriuOpts, extraFiles []*os.File) (retErr error) {
 	fds, err := unix.Socketpair(unix.AF_LOCAL, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
 	if err != nil {
 		return err
@@ -920,7 +972,7 @@ func (c *Container) criuSwrk(process *Process, req *criurpc.CriuReq, opts *CriuO
 		// the initial CRIU run to detect the version. Skip it.
 		logrus.Debugf("Using CRIU %d", c.criuVersion)
 	}
-	cmd := exec.Command("criu", "swrk", "3")
+	cmd := extcmd.Command("criu", "swrk", "3")
 	if process != nil {
 		cmd.Stdin = process.Stdin
 		cmd.Stdout = process.Stdout
@@ -934,6 +986,11 @@ func (c *Container) criuSwrk(process *Process, req *criurpc.CriuReq, opts *CriuO
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	// If criu is killed due to the timeout, report it as the cause of
+	// whatever error it results in.
+	defer func() {
+		retErr = cmd.Finish(retErr)
+	}()
 	// we close criuServer so that even if CRIU crashes or unexpectedly exits, runc will not hang.
 	criuServer.Close()
 	// cmd.Process will be replaced by a restored init.
@@ -1029,7 +1086,7 @@ func (c *Container) criuSwrk(process *Process, req *criurpc.CriuReq, opts *CriuO
 			logrus.Debugf("Feature check says: %s", resp)
 			criuFeatures = resp.GetFeatures()
 		case criurpc.CriuReqType_NOTIFY:
-			if err := c.criuNotifications(resp, process, cmd, opts, extFds, oob[:oobn]); err != nil {
+			if err := c.criuNotifications(resp, process, cmd.Cmd, opts, extFds, oob[:oobn]); err != nil {
 				return err
 			}
 			req = &criurpc.CriuReq{
@@ -1184,8 +1241,18 @@ func (c *Container) criuNotifications(resp *criurpc.CriuResp, process *Process,
 		master := os.NewFile(uintptr(fds[0]), "orphan-pts-master")
 		defer master.Close()
 
-		// While we can access console.master, using the API is a good idea.
-		if err := utils.SendFile(process.ConsoleSocket, master); err != nil {
+		if process.ConsoleSocketVersion < ConsoleSocketV2 {
+			// While we can access console.master, using the API is a good idea.
+			if err := utils.SendFile(process.ConsoleSocket, master); err != nil {
+				return err
+			}
+			break
+		}
+		name, err := consoleSocketName(process.ConsoleSocketVersion, c.id, "")
+		if err != nil {
+			return err
+		}
+		if err := utils.SendRawFd(process.ConsoleSocket, name, master.Fd()); err != nil {
 			return err
 		}
 	case "status-ready":
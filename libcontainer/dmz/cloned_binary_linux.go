@@ -212,19 +212,24 @@ func IsCloned(exe *os.File) bool {
 // make sure the container process can never resolve the original runc binary.
 // For more details on why this is necessary, see CVE-2019-5736.
 func CloneSelfExe(tmpDir string) (*os.File, error) {
-	selfExe, err := os.Open("/proc/self/exe")
+	return CloneExe("/proc/self/exe", tmpDir)
+}
+
+// CloneExe is like CloneSelfExe, for the binary at path.
+func CloneExe(path, tmpDir string) (*os.File, error) {
+	exe, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("opening current binary: %w", err)
+		return nil, fmt.Errorf("opening binary: %w", err)
 	}
-	defer selfExe.Close()
+	defer exe.Close()
 
-	stat, err := selfExe.Stat()
+	stat, err := exe.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("checking /proc/self/exe size: %w", err)
+		return nil, fmt.Errorf("checking %s size: %w", path, err)
 	}
 	size := stat.Size()
 
-	return CloneBinary(selfExe, size, "/proc/self/exe", tmpDir)
+	return CloneBinary(exe, size, path, tmpDir)
 }
 
 // IsSelfExeCloned returns whether /proc/self/exe is a cloned binary that can
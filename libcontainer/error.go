@@ -10,4 +10,50 @@ var (
 	ErrRunning    = errors.New("container still running")
 	ErrNotRunning = errors.New("container not running")
 	ErrNotPaused  = errors.New("container not paused")
+
+	ErrPidsStartLimit = errors.New("pids start limit reached")
+	ErrStateVersion   = errors.New("unsupported state format version")
+	// ErrCorruptState is a container whose state file is corrupt, and
+	// could not be recovered. Such a container can only be removed, by
+	// DestroyCorruptState.
+	ErrCorruptState = errors.New("corrupt container state")
+	// ErrAdoptedCgroup is an operation on the whole container cgroup (such
+	// as freezing it, or setting its resources) refused because the cgroup
+	// is adopted (see configs.Cgroup.Adopted), so it may contain processes
+	// which are not the container's, and it is configured by its owner.
+	ErrAdoptedCgroup = errors.New("not supported for an adopted cgroup")
+)
+
+// The classes of the errors of the container creation and of the process
+// start, to be checked with errors.Is. They do not show in the messages.
+var (
+	// ErrInvalidConfig is an invalid container configuration.
+	ErrInvalidConfig = errors.New("invalid container configuration")
+	// ErrCgroupApply is a failure to create or join the container cgroup.
+	ErrCgroupApply = errors.New("unable to apply cgroup configuration")
+	// ErrExec is a failure to find or execute the container process.
+	ErrExec = errors.New("unable to execute the container process")
 )
+
+// classifiedError is an error in a class (see ErrInvalidConfig), with the
+// message of the error alone.
+type classifiedError struct {
+	err   error
+	class error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.err, e.class}
+}
+
+// classify returns err in the class, or nil if err is nil.
+func classify(err, class error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, class: class}
+}
@@ -0,0 +1,216 @@
+package libcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// etcFilesDir is the directory of the generated /etc files, in the
+// container state directory.
+const etcFilesDir = "etc"
+
+// generateEtcFiles writes the /etc files selected by config.EtcFiles to the
+// container state directory, and adds the bind mounts of these files over
+// the ones of the root filesystem to config.Mounts.
+func generateEtcFiles(stateDir string, config *configs.Config) error {
+	e := config.EtcFiles
+	if e == nil {
+		return nil
+	}
+	// Without a hostname, the container one is inherited from the host.
+	hostname := config.Hostname
+	if hostname == "" {
+		var err error
+		if hostname, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+	type etcFile struct {
+		name string
+		data []byte
+	}
+	var files []etcFile
+	if e.Hostname {
+		files = append(files, etcFile{"hostname", []byte(hostname + "\n")})
+	}
+	if e.Hosts {
+		data, err := etcHosts(config, hostname, os.ReadFile)
+		if err != nil {
+			return err
+		}
+		files = append(files, etcFile{"hosts", data})
+	}
+	if e.ResolvConf {
+		data, err := etcResolvConf(config, os.ReadFile)
+		if err != nil {
+			return err
+		}
+		files = append(files, etcFile{"resolv.conf", data})
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	uid, err := config.HostRootUID()
+	if err != nil {
+		return err
+	}
+	gid, err := config.HostRootGID()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(stateDir, etcFilesDir)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, f.data, 0o644); err != nil {
+			return err
+		}
+		// Let the container root update the file.
+		if uid != os.Geteuid() || gid != os.Getegid() {
+			if err := os.Chown(path, uid, gid); err != nil {
+				return err
+			}
+		}
+		config.Mounts = append(config.Mounts, &configs.Mount{
+			Source:      path,
+			Destination: "/etc/" + f.name,
+			Device:      "bind",
+			Flags:       unix.MS_BIND | unix.MS_REC,
+		})
+	}
+	return nil
+}
+
+// etcHosts returns the contents of the container /etc/hosts: the host one if
+// the container shares the host network namespace, or the localhost entries
+// otherwise, followed by the container hostname entry and the extra hosts.
+func etcHosts(config *configs.Config, hostname string, readFile func(string) ([]byte, error)) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("# Generated by runc.\n")
+	if !config.Namespaces.IsPrivate(configs.NEWNET) {
+		data, err := readFile("/etc/hosts")
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		b.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	} else {
+		b.WriteString("127.0.0.1\tlocalhost\n")
+		b.WriteString("::1\tlocalhost ip6-localhost ip6-loopback\n")
+		if hostname != "localhost" {
+			// Use the container address, if known.
+			addr := "127.0.1.1"
+			if n := config.StaticNetwork; n != nil && len(n.Addresses) > 0 {
+				if ip, _, err := net.ParseCIDR(n.Addresses[0].Address); err == nil {
+					addr = ip.String()
+				}
+			}
+			fmt.Fprintf(&b, "%s\t%s\n", addr, hostname)
+		}
+	}
+	for _, h := range config.EtcFiles.ExtraHosts {
+		fmt.Fprintf(&b, "%s\t%s\n", h.Address, strings.Join(h.Names, " "))
+	}
+	return b.Bytes(), nil
+}
+
+// etcResolvConf returns the contents of the container /etc/resolv.conf. The
+// nameservers are the ones of the configuration, or of the static network
+// configuration, or else of the host. In the latter case, the loopback
+// nameservers are unusable from a private network namespace, and are
+// skipped; if there are only such nameservers (as with systemd-resolved),
+// the upstream ones of systemd-resolved are used instead.
+func etcResolvConf(config *configs.Config, readFile func(string) ([]byte, error)) ([]byte, error) {
+	e := config.EtcFiles
+	nameservers, search, options := e.Nameservers, e.Search, e.Options
+	if len(nameservers) == 0 && config.StaticNetwork != nil {
+		nameservers = config.StaticNetwork.Nameservers
+	}
+	if len(nameservers) == 0 {
+		private := config.Namespaces.IsPrivate(configs.NEWNET)
+		for _, path := range []string{"/etc/resolv.conf", "/run/systemd/resolve/resolv.conf"} {
+			data, err := readFile(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			hostNs, hostSearch, hostOptions := parseResolvConf(data)
+			for _, ns := range hostNs {
+				if ip := net.ParseIP(ns); private && (ip == nil || ip.IsLoopback()) {
+					continue
+				}
+				nameservers = append(nameservers, ns)
+			}
+			if len(nameservers) == 0 {
+				continue
+			}
+			if len(search) == 0 {
+				search = hostSearch
+			}
+			if len(options) == 0 {
+				options = hostOptions
+			}
+			break
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("# Generated by runc.\n")
+	for _, ns := range nameservers {
+		b.WriteString("nameserver " + ns + "\n")
+	}
+	if len(search) > 0 {
+		b.WriteString("search " + strings.Join(search, " ") + "\n")
+	}
+	if len(options) > 0 {
+		b.WriteString("options " + strings.Join(options, " ") + "\n")
+	}
+	return b.Bytes(), nil
+}
+
+// parseResolvConf returns the nameservers, search domains and options of a
+// resolv.conf file (see resolv.conf(5)).
+func parseResolvConf(data []byte) (nameservers, search, options []string) {
+	var domain []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			nameservers = append(nameservers, fields[1])
+		case "search":
+			search = fields[1:]
+		case "domain":
+			domain = fields[1:2]
+		case "options":
+			options = append(options, fields[1:]...)
+		}
+	}
+	if len(search) == 0 {
+		search = domain
+	}
+	return nameservers, search, options
+}
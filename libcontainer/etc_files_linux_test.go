@@ -0,0 +1,147 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func fakeReadFile(files map[string]string) func(string) ([]byte, error) {
+	return func(path string) ([]byte, error) {
+		data, ok := files[path]
+		if !ok {
+			return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+		}
+		return []byte(data), nil
+	}
+}
+
+func TestEtcHosts(t *testing.T) {
+	config := &configs.Config{
+		Namespaces: configs.Namespaces{{Type: configs.NEWNET}},
+		EtcFiles: &configs.EtcFiles{
+			Hosts:      true,
+			ExtraHosts: []*configs.EtcHost{{Address: "192.168.1.20", Names: []string{"db", "db.example.com"}}},
+		},
+	}
+	host := fakeReadFile(map[string]string{"/etc/hosts": "127.0.0.1 localhost\n10.0.0.1 host"})
+
+	data, err := etcHosts(config, "test", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Generated by runc.\n127.0.0.1\tlocalhost\n::1\tlocalhost ip6-localhost ip6-loopback\n127.0.1.1\ttest\n192.168.1.20\tdb db.example.com\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+
+	config.StaticNetwork = &configs.StaticNetwork{
+		Addresses: []*configs.StaticAddress{{InterfaceName: "eth0", Address: "192.168.1.10/24"}},
+	}
+	if data, err = etcHosts(config, "test", host); err != nil {
+		t.Fatal(err)
+	}
+	expected = "# Generated by runc.\n127.0.0.1\tlocalhost\n::1\tlocalhost ip6-localhost ip6-loopback\n192.168.1.10\ttest\n192.168.1.20\tdb db.example.com\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+
+	// With the host network namespace, the host entries are used.
+	config.Namespaces = nil
+	if data, err = etcHosts(config, "test", host); err != nil {
+		t.Fatal(err)
+	}
+	expected = "# Generated by runc.\n127.0.0.1 localhost\n10.0.0.1 host\n192.168.1.20\tdb db.example.com\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
+
+func TestEtcResolvConf(t *testing.T) {
+	resolved := fakeReadFile(map[string]string{
+		"/etc/resolv.conf":                 "nameserver 127.0.0.53\noptions edns0 trust-ad\nsearch lan\n",
+		"/run/systemd/resolve/resolv.conf": "# upstream\nnameserver 192.168.1.1\nnameserver 192.168.1.2 # second\ndomain example.com\n",
+	})
+	for _, tc := range []struct {
+		name     string
+		config   *configs.Config
+		files    func(string) ([]byte, error)
+		expected string
+	}{
+		{
+			name:     "configured",
+			config:   &configs.Config{EtcFiles: &configs.EtcFiles{Nameservers: []string{"10.0.0.1"}, Search: []string{"a", "b"}, Options: []string{"ndots:2"}}},
+			files:    resolved,
+			expected: "nameserver 10.0.0.1\nsearch a b\noptions ndots:2\n",
+		},
+		{
+			name: "static network",
+			config: &configs.Config{
+				EtcFiles:      &configs.EtcFiles{},
+				StaticNetwork: &configs.StaticNetwork{Nameservers: []string{"10.0.0.2"}},
+			},
+			files:    resolved,
+			expected: "nameserver 10.0.0.2\n",
+		},
+		{
+			name:     "host network",
+			config:   &configs.Config{EtcFiles: &configs.EtcFiles{}},
+			files:    resolved,
+			expected: "nameserver 127.0.0.53\nsearch lan\noptions edns0 trust-ad\n",
+		},
+		{
+			name:     "systemd-resolved",
+			config:   &configs.Config{Namespaces: configs.Namespaces{{Type: configs.NEWNET}}, EtcFiles: &configs.EtcFiles{Options: []string{"ndots:1"}}},
+			files:    resolved,
+			expected: "nameserver 192.168.1.1\nnameserver 192.168.1.2\nsearch example.com\noptions ndots:1\n",
+		},
+		{
+			name:     "no host resolv.conf",
+			config:   &configs.Config{Namespaces: configs.Namespaces{{Type: configs.NEWNET}}, EtcFiles: &configs.EtcFiles{}},
+			files:    fakeReadFile(nil),
+			expected: "",
+		},
+	} {
+		data, err := etcResolvConf(tc.config, tc.files)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if expected := "# Generated by runc.\n" + tc.expected; string(data) != expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, expected, data)
+		}
+	}
+}
+
+func TestGenerateEtcFiles(t *testing.T) {
+	stateDir := t.TempDir()
+	config := &configs.Config{
+		Hostname:   "test",
+		Namespaces: configs.Namespaces{{Type: configs.NEWNET}, {Type: configs.NEWUTS}},
+		EtcFiles:   &configs.EtcFiles{Hostname: true, ResolvConf: true, Nameservers: []string{"10.0.0.1"}},
+	}
+	if err := generateEtcFiles(stateDir, config); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(stateDir, etcFilesDir, "hostname"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "test\n" {
+		t.Errorf("unexpected hostname %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, etcFilesDir, "hosts")); !os.IsNotExist(err) {
+		t.Errorf("expected no hosts file, got %v", err)
+	}
+	if len(config.Mounts) != 2 {
+		t.Fatalf("expected 2 mounts, got %d", len(config.Mounts))
+	}
+	for i, name := range []string{"hostname", "resolv.conf"} {
+		m := config.Mounts[i]
+		if m.Destination != "/etc/"+name || m.Source != filepath.Join(stateDir, etcFilesDir, name) || !m.IsBind() {
+			t.Errorf("unexpected mount %+v", m)
+		}
+	}
+}
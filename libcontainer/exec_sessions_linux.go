@@ -0,0 +1,179 @@
+package libcontainer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+const (
+	// execSessionsDir is the directory in the container state directory
+	// where the exec sessions are recorded, one file per process.
+	execSessionsDir    = "exec"
+	execSessionsSuffix = ".json"
+)
+
+// ExecSession describes a process started in an existing container (as
+// by "runc exec").
+type ExecSession struct {
+	// ID is a random identifier of the session (or, for the sessions
+	// recorded by older versions, the process ID).
+	ID string `json:"id"`
+	// Pid is the process ID, as seen from the host.
+	Pid int `json:"pid"`
+	// StartTime is the process start time, in clock ticks after boot,
+	// used to detect PID reuse.
+	StartTime uint64    `json:"start_time"`
+	Args      []string  `json:"args"`
+	Created   time.Time `json:"created"`
+}
+
+// recordExecSession records a newly started non-init process, so that it
+// is known to belong to the container.
+func (c *Container) recordExecSession(pid int, args []string) error {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(c.stateDir, execSessionsDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	s := &ExecSession{
+		ID:        hex.EncodeToString(id),
+		Pid:       pid,
+		StartTime: stat.StartTime,
+		Args:      args,
+		Created:   time.Now().UTC(),
+	}
+	return writeJSONAtomic(dir, strconv.Itoa(pid)+execSessionsSuffix, ".tmp-", s)
+}
+
+// ExecSessions returns the exec sessions which are still running, sorted
+// by their creation time. Records of the exited ones are removed.
+func (c *Container) ExecSessions() ([]*ExecSession, error) {
+	dir := filepath.Join(c.stateDir, execSessionsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sessions []*ExecSession
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, execSessionsSuffix) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		var s ExecSession
+		if err := json.Unmarshal(data, &s); err != nil {
+			logrus.Warnf("removing invalid exec session record %s: %v", path, err)
+			_ = os.Remove(path)
+			continue
+		}
+		stat, err := system.Stat(s.Pid)
+		if err != nil || stat.StartTime != s.StartTime || stat.State == system.Zombie || stat.State == system.Dead {
+			_ = os.Remove(path)
+			continue
+		}
+		if s.ID == "" {
+			s.ID = strconv.Itoa(s.Pid)
+		}
+		sessions = append(sessions, &s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Created.Before(sessions[j].Created)
+	})
+	return sessions, nil
+}
+
+// SignalExecSessions sends a signal to the processes of the exec session
+// id, or, if id is empty, to the processes of all the exec sessions, leaving
+// the container init (and the other processes) alone. The processes of a
+// session are the one started by "runc exec" and its descendants in the
+// container (as shown by "runc ps --exec"). It returns the number of
+// processes signaled.
+func (c *Container) SignalExecSessions(sig os.Signal, id string) (int, error) {
+	s, ok := sig.(unix.Signal)
+	if !ok {
+		return 0, errors.New("os: unsupported signal type")
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	sessions, err := c.ExecSessions()
+	if err != nil {
+		return 0, err
+	}
+	tree, err := c.ProcessTree()
+	if err != nil {
+		return 0, err
+	}
+	n, found := 0, false
+	for _, session := range sessions {
+		if id != "" && session.ID != id {
+			continue
+		}
+		found = true
+		for _, p := range sessionProcesses(tree, session) {
+			err := signalPid(p.Pid, p.StartTime, s)
+			if errors.Is(err, ErrNotRunning) {
+				continue
+			}
+			if err != nil {
+				return n, fmt.Errorf("unable to signal exec session %s (pid %d): %w", session.ID, p.Pid, err)
+			}
+			n++
+		}
+	}
+	if id != "" && !found {
+		return 0, fmt.Errorf("exec session %s not found", id)
+	}
+	return n, nil
+}
+
+// sessionProcesses returns the processes of the exec session s, from tree
+// (see ProcessTree): its process and the descendants of it, parents first.
+func sessionProcesses(tree []*ProcessInfo, s *ExecSession) []*ProcessInfo {
+	var procs []*ProcessInfo
+	var add func(p *ProcessInfo)
+	add = func(p *ProcessInfo) {
+		procs = append(procs, p)
+		for _, c := range p.Children {
+			add(c)
+		}
+	}
+	for _, p := range tree {
+		if p.Pid == s.Pid && p.StartTime == s.StartTime {
+			add(p)
+			break
+		}
+	}
+	return procs
+}
@@ -0,0 +1,91 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+func TestExecSessions(t *testing.T) {
+	c := &Container{stateDir: t.TempDir()}
+	if sessions, err := c.ExecSessions(); err != nil || len(sessions) != 0 {
+		t.Fatalf("expected no sessions, got %v (err: %v)", sessions, err)
+	}
+
+	if err := c.recordExecSession(os.Getpid(), []string{"sh"}); err != nil {
+		t.Fatal(err)
+	}
+	// A record of a process which is gone (PID 1 start time does not match).
+	if err := writeJSONAtomic(filepath.Join(c.stateDir, execSessionsDir), "1.json", ".tmp-",
+		&ExecSession{Pid: 1, StartTime: 1 << 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := c.ExecSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Pid != os.Getpid() || sessions[0].Args[0] != "sh" || len(sessions[0].ID) != 12 {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	if _, err := os.Stat(filepath.Join(c.stateDir, execSessionsDir, "1.json")); !os.IsNotExist(err) {
+		t.Errorf("expected stale record to be removed, got %v", err)
+	}
+}
+
+func TestSignalExecSessions(t *testing.T) {
+	// A session with a child process.
+	cmd := exec.Command("sh", "-c", "sleep 100 & echo $!; wait")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill() //nolint:errcheck
+	var child int
+	if _, err := fmt.Fscan(out, &child); err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Kill(child, unix.SIGKILL) //nolint:errcheck
+
+	c := &Container{
+		stateDir:      t.TempDir(),
+		cgroupManager: &mockCgroupManager{allPids: []int{cmd.Process.Pid, child}},
+	}
+	if err := c.recordExecSession(cmd.Process.Pid, cmd.Args); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.SignalExecSessions(unix.SIGKILL, "nonexistent"); err == nil {
+		t.Fatal("expected an error for a nonexistent session")
+	}
+	n, err := c.SignalExecSessions(unix.SIGKILL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 processes to be signaled, got %d", n)
+	}
+	_ = cmd.Wait()
+	if ws := cmd.ProcessState.Sys().(syscall.WaitStatus); ws.Signal() != unix.SIGKILL {
+		t.Fatalf("expected the process to be killed, got %v", cmd.ProcessState)
+	}
+	// The child is reparented once killed, and may not be reaped.
+	for i := 0; i < 100; i++ {
+		if stat, err := system.Stat(child); err != nil || stat.State == system.Zombie {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected the child process to be killed")
+}
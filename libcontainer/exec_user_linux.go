@@ -0,0 +1,156 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/moby/sys/user"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// ExecUser is a user of the container, as resolved by
+// [Container.LookupExecUser].
+type ExecUser struct {
+	// Uid, Gid and Sgids (supplementary groups) are the IDs in the
+	// container (user namespace).
+	Uid   int
+	Gid   int
+	Sgids []int
+	// UnmappedSgids are the supplementary groups of the user (from the
+	// container /etc/group) which are not mapped in the container user
+	// namespace, and thus are not set for the process.
+	UnmappedSgids []int `json:",omitempty"`
+	Home          string
+	// HostUid and HostGid are the IDs on the host, which differ from the
+	// container ones if the container has a user namespace.
+	HostUid int
+	HostGid int
+}
+
+// LookupExecUser resolves the user (in the "user[:group]" format, with
+// either names or numeric IDs) and additional groups of a process to be
+// executed in the container, the same way runc init does, using the
+// /etc/passwd and /etc/group files from the container root filesystem,
+// and taking the user namespace mappings into account.
+func (c *Container) LookupExecUser(userSpec string, additionalGroups []string) (*ExecUser, error) {
+	rootfs, err := c.RootFS()
+	if err != nil {
+		return nil, err
+	}
+	defer rootfs.Close()
+	return lookupExecUser(rootfs, c.config, userSpec, additionalGroups)
+}
+
+func lookupExecUser(rootfs *RootFS, config *configs.Config, userSpec string, additionalGroups []string) (*ExecUser, error) {
+	openOptional := func(name string) (io.ReadCloser, error) {
+		f, err := rootfs.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return f, nil
+	}
+	var passwd, group io.Reader
+	passwdFile, err := openOptional("/etc/passwd")
+	if err != nil {
+		return nil, err
+	}
+	if passwdFile != nil {
+		defer passwdFile.Close()
+		passwd = passwdFile
+	}
+	groupFile, err := openOptional("/etc/group")
+	if err != nil {
+		return nil, err
+	}
+	if groupFile != nil {
+		defer groupFile.Close()
+		group = groupFile
+	}
+
+	execUser, err := user.GetExecUser(userSpec, &user.ExecUser{Home: "/"}, passwd, group)
+	if err != nil {
+		return nil, err
+	}
+	var addGroups []int
+	if len(additionalGroups) > 0 {
+		if groupFile != nil {
+			if _, err := groupFile.(io.Seeker).Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		addGroups, err = user.GetAdditionalGroups(additionalGroups, group)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u := &ExecUser{Uid: execUser.Uid, Gid: execUser.Gid, Home: execUser.Home}
+	if u.HostUid, err = config.HostUID(u.Uid); err != nil {
+		return nil, err
+	}
+	if u.HostGid, err = config.HostGID(u.Gid); err != nil {
+		return nil, err
+	}
+	idMap := toUserIDMap(config.GIDMappings)
+	if !config.Namespaces.Contains(configs.NEWUSER) {
+		idMap = nil
+	}
+	u.Sgids, u.UnmappedSgids = splitMappedIDs(execUser.Sgids, idMap)
+	mapped, unmapped := splitMappedIDs(addGroups, idMap)
+	if len(unmapped) > 0 {
+		return nil, fmt.Errorf("additional groups %v are not mapped in the container user namespace", unmapped)
+	}
+	u.Sgids = append(u.Sgids, mapped...)
+	return u, nil
+}
+
+func toUserIDMap(m []configs.IDMap) []user.IDMap {
+	res := make([]user.IDMap, len(m))
+	for i, e := range m {
+		res[i] = user.IDMap{ID: e.ContainerID, ParentID: e.HostID, Count: e.Size}
+	}
+	return res
+}
+
+// readGIDMap returns the gid mappings read from path (normally
+// /proc/self/gid_map), or nil (meaning all gids are mapped) if the container
+// has no user namespace, or path does not exist (on a kernel without user
+// namespace support).
+func readGIDMap(config *configs.Config, path string) ([]user.IDMap, error) {
+	if !config.Namespaces.Contains(configs.NEWUSER) {
+		return nil, nil
+	}
+	gidMap, err := user.ParseIDMapFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return gidMap, nil
+}
+
+// splitMappedIDs splits ids into the ones which are mapped in idMap (in any
+// of its ranges), and the ones which are not. A nil idMap maps all IDs.
+func splitMappedIDs(ids []int, idMap []user.IDMap) (mapped, unmapped []int) {
+	if idMap == nil {
+		return ids, nil
+	}
+next:
+	for _, id := range ids {
+		for _, m := range idMap {
+			if int64(id) >= m.ID && int64(id)-m.ID < m.Count {
+				mapped = append(mapped, id)
+				continue next
+			}
+		}
+		unmapped = append(unmapped, id)
+	}
+	return mapped, unmapped
+}
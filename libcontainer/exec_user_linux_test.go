@@ -0,0 +1,155 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/moby/sys/user"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// manyIDMappings returns a mapping with more than 5 ranges (the limit
+// before Linux 4.15), with holes: container IDs 0-999, 1000, 1002, 2000-2099,
+// 3000, 4000-4009, and 65534.
+func manyIDMappings() []configs.IDMap {
+	return []configs.IDMap{
+		{ContainerID: 0, HostID: 100000, Size: 1000},
+		{ContainerID: 1000, HostID: 1000, Size: 1},
+		{ContainerID: 1002, HostID: 201002, Size: 1},
+		{ContainerID: 2000, HostID: 202000, Size: 100},
+		{ContainerID: 3000, HostID: 203000, Size: 1},
+		{ContainerID: 4000, HostID: 204000, Size: 10},
+		{ContainerID: 65534, HostID: 265534, Size: 1},
+	}
+}
+
+func TestSplitMappedIDs(t *testing.T) {
+	idMap := toUserIDMap(manyIDMappings())
+	ids := []int{0, 999, 1000, 1001, 1002, 2099, 2100, 3000, 4009, 4010, 65534, 65535}
+	mapped, unmapped := splitMappedIDs(ids, idMap)
+	if expected := []int{0, 999, 1000, 1002, 2099, 3000, 4009, 65534}; !reflect.DeepEqual(mapped, expected) {
+		t.Errorf("expected mapped %v, got %v", expected, mapped)
+	}
+	if expected := []int{1001, 2100, 4010, 65535}; !reflect.DeepEqual(unmapped, expected) {
+		t.Errorf("expected unmapped %v, got %v", expected, unmapped)
+	}
+
+	// No mappings means all IDs are mapped.
+	mapped, unmapped = splitMappedIDs(ids, nil)
+	if !reflect.DeepEqual(mapped, ids) || unmapped != nil {
+		t.Errorf("expected all mapped, got %v, %v", mapped, unmapped)
+	}
+	// Empty (but non-nil) mappings map nothing.
+	if mapped, _ := splitMappedIDs(ids, []user.IDMap{}); mapped != nil {
+		t.Errorf("expected nothing mapped, got %v", mapped)
+	}
+}
+
+func TestReadGIDMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gid_map")
+	if err := os.WriteFile(path, []byte("0 100000 65536\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := &configs.Config{}
+
+	// Without a user namespace, the file is not read.
+	if m, err := readGIDMap(config, "/nonexistent/dir/gid_map"); err != nil || m != nil {
+		t.Errorf("expected no mappings without a user namespace, got %v, %v", m, err)
+	}
+
+	config.Namespaces = configs.Namespaces{{Type: configs.NEWUSER}}
+	m, err := readGIDMap(config, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []user.IDMap{{ID: 0, ParentID: 100000, Count: 65536}}; !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %v, got %v", expected, m)
+	}
+	// A missing file means no mappings.
+	if m, err := readGIDMap(config, path+".missing"); err != nil || m != nil {
+		t.Errorf("expected no mappings for a missing file, got %v, %v", m, err)
+	}
+}
+
+func TestLookupExecUser(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"passwd": "root:x:0:0:root:/root:/bin/sh\n" +
+			"alice:x:1000:1000::/home/alice:/bin/sh\n" +
+			"bob:x:2050:2050::/home/bob:/bin/sh\n",
+		"group": "root:x:0:\n" +
+			"alice:x:1000:\n" +
+			"wheel:x:10:alice\n" +
+			"unmapped:x:1001:alice,bob\n" +
+			"dev:x:2001:alice,bob\n" +
+			"ops:x:4005:alice\n" +
+			"bob:x:2050:\n" +
+			"nomap:x:5000:\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, "etc", name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rootfs, err := OpenRootFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rootfs.Close()
+
+	config := &configs.Config{
+		Namespaces:  configs.Namespaces{{Type: configs.NEWUSER}},
+		UIDMappings: manyIDMappings(),
+		GIDMappings: manyIDMappings(),
+	}
+
+	u, err := lookupExecUser(rootfs, config, "alice", []string{"bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ExecUser{
+		Uid:           1000,
+		Gid:           1000,
+		Sgids:         []int{10, 2001, 4005, 2050},
+		UnmappedSgids: []int{1001},
+		Home:          "/home/alice",
+		HostUid:       1000,
+		HostGid:       1000,
+	}
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("expected %+v, got %+v", expected, u)
+	}
+
+	u, err = lookupExecUser(rootfs, config, "bob:ops", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.HostUid != 202050 || u.HostGid != 204005 {
+		t.Errorf("expected host ids 202050:204005, got %d:%d", u.HostUid, u.HostGid)
+	}
+
+	// Unmapped additional group.
+	if _, err := lookupExecUser(rootfs, config, "alice", []string{"nomap"}); err == nil {
+		t.Error("expected error for unmapped additional group, got nil")
+	}
+	// Unmapped user.
+	if _, err := lookupExecUser(rootfs, config, "1001", nil); err == nil {
+		t.Error("expected error for unmapped user, got nil")
+	}
+
+	// Without a user namespace, all groups are set.
+	config = &configs.Config{}
+	u, err = lookupExecUser(rootfs, config, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{10, 1001, 2001, 4005}; !reflect.DeepEqual(u.Sgids, expected) || u.UnmappedSgids != nil {
+		t.Errorf("expected groups %v, got %v (unmapped %v)", expected, u.Sgids, u.UnmappedSgids)
+	}
+}
@@ -0,0 +1,209 @@
+// Package extcmd runs the external binaries libcontainer (and runc) depend
+// on, such as newuidmap or criu, with timeouts, so that a hung binary does
+// not hang runc, and with errors telling which binary failed and how.
+package extcmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the timeout for the binaries with no timeout set.
+const DefaultTimeout = time.Minute
+
+var (
+	mu sync.RWMutex
+	// timeouts are the timeouts of the binaries, by name. Zero means no
+	// timeout.
+	timeouts = map[string]time.Duration{
+		"busctl":    10 * time.Second,
+		"newgidmap": 10 * time.Second,
+		"newuidmap": 10 * time.Second,
+		"ps":        30 * time.Second,
+		// criu swrk runs for the whole checkpoint or restore, which can
+		// take a long time for a large container.
+		"criu": 0,
+		// Likewise for the compression of the checkpoint images.
+		"gzip": 0,
+		"lz4":  0,
+		"zstd": 0,
+	}
+	defaultTimeout = DefaultTimeout
+)
+
+// SetTimeout sets the timeout for the binary with the given name (its base
+// name, such as "newuidmap"), or the default timeout if name is empty. Zero
+// means no timeout.
+func SetTimeout(name string, timeout time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	if name == "" {
+		defaultTimeout = timeout
+		return
+	}
+	timeouts[name] = timeout
+}
+
+// Timeout returns the timeout for the binary with the given name or path.
+func Timeout(name string) time.Duration {
+	mu.RLock()
+	defer mu.RUnlock()
+	if t, ok := timeouts[filepath.Base(name)]; ok {
+		return t
+	}
+	return defaultTimeout
+}
+
+// ParseTimeout parses a timeout setting in the name=duration form (or just
+// duration, for the default timeout), and sets it.
+func ParseTimeout(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		name, value = "", s
+	}
+	if strings.ContainsRune(name, '/') {
+		return fmt.Errorf("invalid timeout %q: binary name must not be a path", s)
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return fmt.Errorf("invalid timeout %q: must be [name=]duration, such as newuidmap=5s", s)
+	}
+	SetTimeout(name, timeout)
+	return nil
+}
+
+// Error is an error running an external binary.
+type Error struct {
+	// Path is the binary run.
+	Path string
+	// Timeout is set if the binary was killed as it did not finish in time.
+	Timeout time.Duration
+	// Output is the (trimmed) standard error or combined output of the
+	// binary, if captured.
+	Output string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	var msg string
+	if e.Timeout != 0 {
+		msg = fmt.Sprintf("%s did not finish in %s (hung?)", e.Path, e.Timeout)
+	} else {
+		msg = fmt.Sprintf("%s failed: %v", e.Path, e.Err)
+	}
+	if e.Output != "" {
+		msg += " (output: " + e.Output + ")"
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cmd is an exec.Cmd which is killed if it does not finish within the
+// timeout set for the binary (see SetTimeout), counting from the command
+// creation. The errors returned by its methods are of the *Error type.
+type Cmd struct {
+	*exec.Cmd
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+}
+
+// Command is like exec.Command, with a timeout (see Cmd).
+func Command(name string, arg ...string) *Cmd {
+	c := &Cmd{timeout: Timeout(name)}
+	if c.timeout == 0 {
+		c.Cmd = exec.Command(name, arg...)
+		return c
+	}
+	c.ctx, c.cancel = context.WithTimeout(context.Background(), c.timeout)
+	c.Cmd = exec.CommandContext(c.ctx, name, arg...)
+	// Do not wait forever for the I/O to complete if the binary leaves
+	// some children holding its stdout or stderr.
+	c.Cmd.WaitDelay = time.Second
+	return c
+}
+
+func (c *Cmd) wrap(err error, output []byte) error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{Path: c.Path, Err: err, Output: string(bytes.TrimSpace(output))}
+	if c.ctx != nil && errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+		e.Timeout = c.timeout
+	}
+	return e
+}
+
+func (c *Cmd) done() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// Start is like exec.Cmd.Start. Wait must be called if it succeeds.
+func (c *Cmd) Start() error {
+	err := c.Cmd.Start()
+	if err != nil {
+		c.done()
+	}
+	return c.wrap(err, nil)
+}
+
+// Wait is like exec.Cmd.Wait.
+func (c *Cmd) Wait() error {
+	defer c.done()
+	return c.wrap(c.Cmd.Wait(), nil)
+}
+
+// Finish is to be called (instead of Wait) by the callers which wait for the
+// process by other means, such as os.Process.Wait, once done with it. It
+// releases the timeout, and converts err to *Error if the binary was killed
+// due to the timeout (err is returned as is otherwise).
+func (c *Cmd) Finish(err error) error {
+	defer c.done()
+	if err == nil || c.ctx == nil || !errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return c.wrap(err, nil)
+}
+
+// Run is like exec.Cmd.Run. If the standard error is not set, it is captured
+// for the error.
+func (c *Cmd) Run() error {
+	defer c.done()
+	if c.Stderr != nil {
+		return c.wrap(c.Cmd.Run(), nil)
+	}
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	return c.wrap(c.Cmd.Run(), stderr.Bytes())
+}
+
+// Output is like exec.Cmd.Output.
+func (c *Cmd) Output() ([]byte, error) {
+	defer c.done()
+	out, err := c.Cmd.Output()
+	var stderr []byte
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		stderr = exitErr.Stderr
+	}
+	return out, c.wrap(err, stderr)
+}
+
+// CombinedOutput is like exec.Cmd.CombinedOutput.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	defer c.done()
+	out, err := c.Cmd.CombinedOutput()
+	return out, c.wrap(err, out)
+}
@@ -0,0 +1,83 @@
+package extcmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeout(t *testing.T) {
+	t.Cleanup(func() {
+		SetTimeout("", DefaultTimeout)
+		SetTimeout("newuidmap", 10*time.Second)
+	})
+	for _, tc := range []struct {
+		in    string
+		name  string
+		want  time.Duration
+		isErr bool
+	}{
+		{in: "newuidmap=5s", name: "newuidmap", want: 5 * time.Second},
+		{in: "newuidmap=0", name: "/usr/bin/newuidmap", want: 0},
+		{in: "2m", name: "unknown", want: 2 * time.Minute},
+		{in: "newuidmap=-1s", isErr: true},
+		{in: "newuidmap=5", isErr: true},
+		{in: "/usr/bin/newuidmap=5s", isErr: true},
+	} {
+		err := ParseTimeout(tc.in)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if got := Timeout(tc.name); got != tc.want {
+			t.Errorf("%q: expected timeout %s for %s, got %s", tc.in, tc.want, tc.name, got)
+		}
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	SetTimeout("sleep", 100*time.Millisecond)
+	t.Cleanup(func() { SetTimeout("sleep", DefaultTimeout) })
+
+	start := time.Now()
+	err := Command("sleep", "10").Run()
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("the command was not killed in time")
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if e.Timeout != 100*time.Millisecond {
+		t.Errorf("expected timeout to be set, got %+v", e)
+	}
+	if !strings.Contains(err.Error(), "did not finish in 100ms") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	err := Command("sh", "-c", "echo oops >&2; exit 3").Run()
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if e.Timeout != 0 || e.Output != "oops" {
+		t.Errorf("unexpected error: %+v", e)
+	}
+	if err := Command("true").Run(); err != nil {
+		t.Fatal(err)
+	}
+	// The callers rely on the output being included in the error message.
+	_, err = Command("sh", "-c", "echo oops; exit 3").CombinedOutput()
+	if err == nil || !strings.Contains(err.Error(), "(output: oops)") {
+		t.Errorf("expected the output in the error, got %v", err)
+	}
+}
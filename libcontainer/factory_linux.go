@@ -4,9 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"time"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 
 	//nolint:revive // Enable cgroup manager to manage devices
@@ -15,12 +20,15 @@ import (
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
 )
 
 const (
-	stateFilename    = "state.json"
-	execFifoFilename = "exec.fifo"
+	stateFilename      = "state.json"
+	stateTmpPrefix     = "state-"
+	corruptStateSuffix = ".corrupt"
+	execFifoFilename   = "exec.fifo"
 )
 
 // Create creates a new container with the given id inside a given state
@@ -30,9 +38,8 @@ const (
 // used later to get the list of containers, or to get information about a
 // particular container (see Load).
 //
-// The id must not be empty and consist of only the following characters:
-// ASCII letters, digits, underscore, plus, minus, period. The id must be
-// unique and non-existent for the given root path.
+// The id must be valid (see ValidateID), unique and non-existent for the
+// given root path.
 func Create(root, id string, config *configs.Config) (*Container, error) {
 	/*root不能为空*/
 	if root == "" {
@@ -40,13 +47,13 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 	}
 	
 	/*container id格式校验*/
-	if err := validateID(id); err != nil {
+	if err := ValidateID(id); err != nil {
 		return nil, err
 	}
 	
 	/*config校验*/
 	if err := validate.Validate(config); err != nil {
-		return nil, err
+		return nil, classify(err, ErrInvalidConfig)
 	}
 	
 	/*创建root对应的目录,例如/run/runc*/
@@ -77,8 +84,9 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 	// separate hierarchies, while both Exists() and GetAllPids() only use
 	// one for "devices" controller (assuming others are the same, which is
 	// probably true in almost all scenarios). Checking all the hierarchies
-	// would be too expensive.
-	if cm.Exists() {
+	// would be too expensive. An adopted cgroup may have other processes
+	// (put there by its owner), so it is not checked.
+	if cm.Exists() && !config.Cgroups.Adopted {
 		pids, err := cm.GetAllPids()
 		// Reading PIDs can race with cgroups removal, so ignore ENOENT and ENODEV.
 		if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, unix.ENODEV) {
@@ -104,6 +112,16 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 	if err := os.Mkdir(stateDir, 0o711); err != nil {
 		return nil, err
 	}
+	if config.CpusetAlloc != nil {
+		if err := allocateCpuset(root, stateDir, config); err != nil {
+			_ = os.RemoveAll(stateDir)
+			return nil, err
+		}
+	}
+	if err := generateEtcFiles(stateDir, config); err != nil {
+		_ = os.RemoveAll(stateDir)
+		return nil, fmt.Errorf("unable to generate etc files: %w", err)
+	}
 	
 	/*创建container对象*/
 	c := &Container{
@@ -112,6 +130,7 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 		config:          config,
 		cgroupManager:   cm,
 		intelRdtManager: intelrdt.NewManager(config, id, ""),
+		journal:         newJournal(stateDir),
 	}
 	c.state = &stoppedState{c: c}
 	return c, nil
@@ -125,7 +144,7 @@ func Load(root, id string) (*Container, error) {
 		return nil, errors.New("root not set")
 	}
 	// when load, we need to check id is valid or not.
-	if err := validateID(id); err != nil {
+	if err := ValidateID(id); err != nil {
 		return nil, err
 	}
 	stateDir, err := securejoin.SecureJoin(root, id)
@@ -154,6 +173,12 @@ func Load(root, id string) (*Container, error) {
 		intelRdtManager:      intelrdt.NewManager(&state.Config, id, state.IntelRdtPath),
 		stateDir:             stateDir,
 		created:              state.Created,
+		metadata:             state.Metadata,
+		cniResult:            state.CNIResult,
+		setupCost:            state.SetupCost,
+		helperOomScoreAdj:    state.HelperOomScoreAdj,
+		runtimeMaskPaths:     state.RuntimeMaskPaths,
+		exitReason:           state.ExitReason,
 	}
 	c.state = &loadedState{c: c}
 	if err := c.refreshState(); err != nil {
@@ -167,11 +192,36 @@ func loadState(root string) (*State, error) {
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Open(stateFilePath)
+	state, err := readStateFile(stateFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			// A corrupt state file which could not be recovered is
+			// moved aside (see recoverState).
+			if _, err := os.Stat(stateFilePath + corruptStateSuffix); err == nil {
+				return nil, fmt.Errorf("%w: state file moved to %s", ErrCorruptState, stateFilePath+corruptStateSuffix)
+			}
 			return nil, ErrNotExist
 		}
+		var syntaxErr *json.SyntaxError
+		if !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) && !errors.As(err, &syntaxErr) && !errors.Is(err, errNullState) {
+			return nil, err
+		}
+		if state, err = recoverState(root, stateFilePath, err); err != nil {
+			return nil, err
+		}
+	}
+	if state.MinReaderVersion > StateVersion {
+		return nil, fmt.Errorf("%w: the state of container %s requires version %d, this runc supports version %d",
+			ErrStateVersion, state.ID, state.MinReaderVersion, StateVersion)
+	}
+	return state, nil
+}
+
+var errNullState = errors.New("no state")
+
+func readStateFile(path string) (*State, error) {
+	f, err := os.Open(path)
+	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
@@ -179,17 +229,156 @@ func loadState(root string) (*State, error) {
 	if err := json.NewDecoder(f).Decode(&state); err != nil {
 		return nil, err
 	}
+	if state == nil {
+		return nil, errNullState
+	}
 	return state, nil
 }
 
-// validateID checks if the supplied container ID is valid, returning
-// the ErrInvalidID in case it is not.
-//
-// The format of valid ID was never formally defined, instead the code
-// was modified to allow or disallow specific characters.
+// recoverState is used when the state file is corrupt (truncated or
+// otherwise unparsable), which may happen if the host crashed while the state
+// was being written. If the writer has left a complete temporary state file,
+// the most recent one is used as the state. Otherwise, the corrupt state file
+// is moved aside, and ErrCorruptState is returned, so that the container can
+// be removed by DestroyCorruptState.
+func recoverState(root, path string, parseErr error) (*State, error) {
+	tmps, _ := filepath.Glob(filepath.Join(root, stateTmpPrefix+"*"))
+	mtimes := make(map[string]time.Time, len(tmps))
+	for _, tmp := range tmps {
+		if fi, err := os.Stat(tmp); err == nil {
+			mtimes[tmp] = fi.ModTime()
+		}
+	}
+	sort.SliceStable(tmps, func(i, j int) bool {
+		return mtimes[tmps[i]].After(mtimes[tmps[j]])
+	})
+	for _, tmp := range tmps {
+		state, err := readStateFile(tmp)
+		if err != nil {
+			continue
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			break
+		}
+		logrus.Warnf("corrupt state file %s (%v) replaced by %s", path, parseErr, tmp)
+		return state, nil
+	}
+
+	quarantine := path + corruptStateSuffix
+	if err := os.Rename(path, quarantine); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", path, parseErr)
+	}
+	logrus.Warnf("corrupt state file %s (%v) moved to %s", path, parseErr, quarantine)
+	return nil, fmt.Errorf("%w: state file moved to %s", ErrCorruptState, quarantine)
+}
+
+// salvageState returns the complete top-level fields of a corrupt (usually
+// truncated) state file, which hopefully include the container configuration,
+// the cgroup paths and the init process. It returns nil if nothing can be
+// read.
+func salvageState(path string) *State {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil
+	}
+	fields := make(map[string]json.RawMessage)
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			break
+		}
+		key, ok := t.(string)
+		if !ok {
+			break
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			break
+		}
+		fields[key] = value
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+	return &state
+}
+
+// DestroyCorruptState removes a container whose state file is corrupt (see
+// ErrCorruptState). As far as they can be found in what is left of the state
+// file, the container processes are killed and its cgroup is removed. The
+// side effects recorded in the creation journal (if any) are undone, and the
+// container state directory is removed.
+func DestroyCorruptState(root, id string) error {
+	if root == "" {
+		return errors.New("root not set")
+	}
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	stateDir, err := securejoin.SecureJoin(root, id)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	state := salvageState(filepath.Join(stateDir, stateFilename+corruptStateSuffix))
+	if state != nil && state.InitProcessPid > 0 {
+		// Make sure the pid is not reused by another process.
+		if st, err := system.Stat(state.InitProcessPid); err == nil && st.StartTime == state.InitProcessStartTime {
+			_ = unix.Kill(state.InitProcessPid, unix.SIGKILL)
+		}
+	}
+	switch {
+	case state == nil || state.Config.Cgroups == nil:
+		logrus.Warnf("unable to find the cgroup of container %s in its corrupt state, the cgroup (and any processes in it) is left in place", id)
+	case state.Config.Cgroups.Adopted:
+		// The cgroup, and the processes in it, belong to its owner.
+	default:
+		cm, err := manager.NewWithPaths(state.Config.Cgroups, state.CgroupPaths)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if cm.Exists() {
+			_ = signalAllProcesses(cm, unix.SIGKILL)
+		}
+		if err := cm.Destroy(); err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove container's cgroup: %w", err))
+		}
+	}
+	if j, err := loadJournal(stateDir); err != nil {
+		errs = append(errs, err)
+	} else if err := j.rollback(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := os.RemoveAll(stateDir); err != nil {
+		errs = append(errs, fmt.Errorf("unable to remove container state dir: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// MaxIDLength is the maximum length of a container ID. As the ID is used
+// as the name of the container state directory, it can't be longer than
+// NAME_MAX.
+const MaxIDLength = 255
+
+// ValidateID checks if the supplied container ID is valid, returning
+// an error wrapping ErrInvalidID (and telling why) in case it is not.
 //
-// Currently, a valid ID is a non-empty string consisting only of
-// the following characters:
+// A valid ID is a non-empty string of at most [MaxIDLength] bytes,
+// consisting only of the following characters:
 // - uppercase (A-Z) and lowercase (a-z) Latin letters;
 // - digits (0-9);
 // - underscore (_);
@@ -199,10 +388,14 @@ func loadState(root string) (*State, error) {
 //
 // In addition, IDs that can't be used to represent a file name
 // (such as . or ..) are rejected.
-
-func validateID(id string) error {
+//
+// The same rules apply to the tenant names (see runc --tenant).
+func ValidateID(id string) error {
 	if len(id) < 1 {
-		return ErrInvalidID
+		return fmt.Errorf("%w: empty ID", ErrInvalidID)
+	}
+	if len(id) > MaxIDLength {
+		return fmt.Errorf("%w: ID is longer than %d characters", ErrInvalidID, MaxIDLength)
 	}
 
 	// Allowed characters: 0-9 A-Z a-z _ + - .
@@ -217,14 +410,17 @@ func validateID(id string) error {
 		case c == '-':
 		case c == '.':
 		default:
-			return ErrInvalidID
+			return fmt.Errorf("%w: %q: character %q is not allowed (only A-Z a-z 0-9 _ + - . are)", ErrInvalidID, id, c)
 		}
 
 	}
 
 	/*id由字每，'.','-'组成，防路径理解不一致*/
 	if string(os.PathSeparator)+id != utils.CleanPath(string(os.PathSeparator)+id) {
-		return ErrInvalidID
+		return fmt.Errorf("%w: %q is not a valid file name", ErrInvalidID, id)
+	}
+	if id == LockDir {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidID, id)
 	}
 
 	return nil
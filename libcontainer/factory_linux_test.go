@@ -1,10 +1,13 @@
 package libcontainer
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
@@ -80,6 +83,110 @@ func TestFactoryLoadContainer(t *testing.T) {
 	}
 }
 
+func TestLoadStateCorrupt(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, stateFilename)
+	// A truncated state file, as left by a crash.
+	if err := os.WriteFile(path, []byte(`{"id":"tes`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadState(root)
+	if !errors.Is(err, ErrCorruptState) {
+		t.Fatalf("expected ErrCorruptState, got %v", err)
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Fatalf("expected corrupt state to be quarantined: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected corrupt state to be moved, got %v", err)
+	}
+	// The container is still reported as corrupt, not as nonexistent.
+	if _, err := loadState(root); !errors.Is(err, ErrCorruptState) {
+		t.Fatalf("expected ErrCorruptState, got %v", err)
+	}
+}
+
+func TestDestroyCorruptState(t *testing.T) {
+	root := t.TempDir()
+	stateDir := filepath.Join(root, "test")
+	if err := os.Mkdir(stateDir, 0o711); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(&State{
+		BaseState: BaseState{
+			ID:     "test",
+			Config: configs.Config{Rootfs: "/", Cgroups: &configs.Cgroup{Path: "/runc-test-nonexistent", Resources: &configs.Resources{}}},
+		},
+		CgroupPaths: map[string]string{"": "/sys/fs/cgroup/runc-test-nonexistent"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Truncate the state in the middle of the fields following the config.
+	data = data[:bytes.Index(data, []byte(`"cgroup_paths"`))+5]
+	if err := os.WriteFile(filepath.Join(stateDir, stateFilename+corruptStateSuffix), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	state := salvageState(filepath.Join(stateDir, stateFilename+corruptStateSuffix))
+	if state == nil || state.ID != "test" || state.Config.Cgroups == nil || state.Config.Cgroups.Path != "/runc-test-nonexistent" {
+		t.Fatalf("expected the id and config to be salvaged, got %+v", state)
+	}
+
+	if err := DestroyCorruptState(root, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stateDir); !os.IsNotExist(err) {
+		t.Fatalf("expected state directory to be removed, got %v", err)
+	}
+}
+
+func TestLoadStateRecoverFromTmp(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, stateFilename)
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// A complete temporary file, and an incomplete one.
+	if err := marshal(filepath.Join(root, stateTmpPrefix+"1"), &State{BaseState: BaseState{ID: "test"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, stateTmpPrefix+"2"), []byte(`{"id":`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.ID != "test" {
+		t.Fatalf("expected state to be recovered, got %+v", state.BaseState)
+	}
+	// The recovered state is now in place.
+	if _, err := loadState(root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteJSONAtomic(t *testing.T) {
+	dir := t.TempDir()
+	for _, id := range []string{"a", "b"} {
+		if err := writeJSONAtomic(dir, stateFilename, stateTmpPrefix, &State{BaseState: BaseState{ID: id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, err := loadState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.ID != "b" {
+		t.Fatalf("expected id b, got %q", state.ID)
+	}
+	tmps, _ := filepath.Glob(filepath.Join(dir, stateTmpPrefix+"*"))
+	if len(tmps) != 0 {
+		t.Fatalf("unexpected leftover files: %v", tmps)
+	}
+}
+
 func marshal(path string, v interface{}) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -94,3 +201,60 @@ type unserializableHook struct{}
 func (unserializableHook) Run(*specs.State) error {
 	return nil
 }
+
+func TestValidateID(t *testing.T) {
+	for _, tc := range []struct {
+		id    string
+		valid bool
+	}{
+		{"abc", true},
+		{"a-b_c+d.e", true},
+		{"ABC123", true},
+		{"..a", true},
+		{strings.Repeat("x", MaxIDLength), true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{LockDir, false},
+		{"a/b", false},
+		{"a:b", false},
+		{"a b", false},
+		{"ä", false},
+		{strings.Repeat("x", MaxIDLength+1), false},
+	} {
+		err := ValidateID(tc.id)
+		if tc.valid && err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.id, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidID) {
+			t.Errorf("%q: expected ErrInvalidID, got %v", tc.id, err)
+		}
+	}
+}
+
+func TestLoadStateVersion(t *testing.T) {
+	root := t.TempDir()
+	// A state written by a newer runc, compatible with this one.
+	state := &State{BaseState: BaseState{ID: "test"}, Version: StateVersion + 1, MinReaderVersion: StateVersion}
+	if err := marshal(filepath.Join(root, stateFilename), state); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(root); err != nil {
+		t.Fatal(err)
+	}
+	// An incompatible one.
+	state.MinReaderVersion = StateVersion + 1
+	if err := marshal(filepath.Join(root, stateFilename), state); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(root); !errors.Is(err, ErrStateVersion) {
+		t.Fatalf("expected ErrStateVersion, got %v", err)
+	}
+	// A state written before the format was versioned.
+	if err := marshal(filepath.Join(root, stateFilename), &State{BaseState: BaseState{ID: "test"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(root); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,192 @@
+package libcontainer
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// FDStats are the totals of the file descriptors opened by the container
+// processes. A socket opened by several processes (or several times) is
+// only counted once in the socket counters, while each descriptor counts
+// in FDs.
+type FDStats struct {
+	// FDs is the number of open file descriptors.
+	FDs uint64 `json:"fds"`
+	// Sockets is the number of open sockets, of which Unix are unix
+	// sockets, TCP are TCP (over IPv4 or IPv6) sockets, and UDP are UDP
+	// sockets.
+	Sockets uint64 `json:"sockets"`
+	Unix    uint64 `json:"unix"`
+	TCP     uint64 `json:"tcp"`
+	UDP     uint64 `json:"udp"`
+	// Processes is the number of processes accounted for.
+	Processes int `json:"processes"`
+	// Sampled is set if only some of the container processes were
+	// accounted for (see Container.StatsWithFDs), or if some processes
+	// could not be accessed.
+	Sampled bool `json:"sampled,omitempty"`
+}
+
+// socketTypes are the socket types (unix, tcp or udp), by socket inode.
+type socketTypes map[uint64]string
+
+// fdStats returns the totals of the file descriptors opened by the
+// processes with the given PIDs, accounting for at most maxProcs of them.
+// The processes which exit in the meantime are skipped.
+func fdStats(pids []int, maxProcs int) (*FDStats, error) {
+	s := &FDStats{}
+	if len(pids) > maxProcs {
+		pids = pids[:maxProcs]
+		s.Sampled = true
+	}
+	sockets := make(map[uint64]struct{})
+	// The socket types, by network namespace inode.
+	types := make(map[uint64]socketTypes)
+	for _, pid := range pids {
+		dir := "/proc/" + strconv.Itoa(pid)
+		fds, err := procSockets(dir, sockets)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ESRCH) {
+				continue
+			}
+			if errors.Is(err, os.ErrPermission) {
+				// Such as a process of another user in a rootless
+				// container.
+				s.Sampled = true
+				continue
+			}
+			return nil, err
+		}
+		s.FDs += fds
+		s.Processes++
+
+		var st unix.Stat_t
+		if err := unix.Stat(dir+"/ns/net", &st); err != nil {
+			continue
+		}
+		if _, ok := types[st.Ino]; !ok {
+			t, err := readSocketTypes(dir)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			types[st.Ino] = t
+		}
+	}
+	s.Sockets = uint64(len(sockets))
+	for inode := range sockets {
+		for _, t := range types {
+			typ, ok := t[inode]
+			if !ok {
+				continue
+			}
+			switch typ {
+			case "unix":
+				s.Unix++
+			case "tcp":
+				s.TCP++
+			case "udp":
+				s.UDP++
+			}
+			break
+		}
+	}
+	return s, nil
+}
+
+// procSockets returns the number of file descriptors of the process whose
+// /proc directory is dir, adding the inodes of its sockets to sockets.
+func procSockets(dir string, sockets map[uint64]struct{}) (uint64, error) {
+	fdDir := dir + "/fd"
+	d, err := os.Open(fdDir)
+	if err != nil {
+		return 0, err
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range names {
+		link, err := os.Readlink(fdDir + "/" + name)
+		if err != nil {
+			// The descriptor was closed in the meantime.
+			continue
+		}
+		if inode, ok := socketInode(link); ok {
+			sockets[inode] = struct{}{}
+		}
+	}
+	return uint64(len(names)), nil
+}
+
+// socketInode returns the inode of a socket from the target of its
+// /proc/<pid>/fd link ("socket:[<inode>]").
+func socketInode(link string) (uint64, bool) {
+	val, ok := strings.CutPrefix(link, "socket:[")
+	if !ok {
+		return 0, false
+	}
+	val, ok = strings.CutSuffix(val, "]")
+	if !ok {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(val, 10, 64)
+	return inode, err == nil
+}
+
+// readSocketTypes reads the types of the sockets of the network namespace
+// of the process whose /proc directory is dir.
+func readSocketTypes(dir string) (socketTypes, error) {
+	t := make(socketTypes)
+	for _, f := range []struct {
+		name, typ string
+		// inodeField is the index of the inode field in the lines.
+		inodeField int
+	}{
+		{"unix", "unix", 6},
+		{"tcp", "tcp", 9},
+		{"tcp6", "tcp", 9},
+		{"udp", "udp", 9},
+		{"udp6", "udp", 9},
+	} {
+		file, err := os.Open(dir + "/net/" + f.name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && f.name != "unix" {
+				// No IPv6 (or no IPv4) support.
+				continue
+			}
+			return nil, err
+		}
+		err = parseSocketTypes(bufio.NewScanner(file), f.typ, f.inodeField, t)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// parseSocketTypes parses a /proc/net/{unix,tcp,tcp6,udp,udp6} file,
+// setting the type of its sockets to typ in t.
+func parseSocketTypes(sc *bufio.Scanner, typ string, inodeField int, t socketTypes) error {
+	// Skip the header.
+	sc.Scan()
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) <= inodeField {
+			continue
+		}
+		if inode, err := strconv.ParseUint(fields[inodeField], 10, 64); err == nil && inode != 0 {
+			t[inode] = typ
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,101 @@
+package libcontainer
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSocketInode(t *testing.T) {
+	for _, tc := range []struct {
+		link  string
+		inode uint64
+		ok    bool
+	}{
+		{link: "socket:[12345]", inode: 12345, ok: true},
+		{link: "pipe:[12345]"},
+		{link: "socket:[12345"},
+		{link: "socket:[]"},
+		{link: "/dev/null"},
+	} {
+		inode, ok := socketInode(tc.link)
+		if inode != tc.inode || ok != tc.ok {
+			t.Errorf("%q: expected %d, %v, got %d, %v", tc.link, tc.inode, tc.ok, inode, ok)
+		}
+	}
+}
+
+func TestParseSocketTypes(t *testing.T) {
+	const tcp = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21412 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0277 0100007F:9C40 01 00000000:00000000 00:00000000 00000000     0        0 0 1 0000000000000000 20 4 30 10 -1
+`
+	const unixSockets = `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 18042 /run/systemd/notify
+0000000000000000: 00000003 00000000 00000000 0001 03 23140
+`
+	types := make(socketTypes)
+	if err := parseSocketTypes(bufio.NewScanner(strings.NewReader(tcp)), "tcp", 9, types); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseSocketTypes(bufio.NewScanner(strings.NewReader(unixSockets)), "unix", 6, types); err != nil {
+		t.Fatal(err)
+	}
+	expected := socketTypes{21412: "tcp", 18042: "unix", 23140: "unix"}
+	if len(types) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, types)
+	}
+	for inode, typ := range expected {
+		if types[inode] != typ {
+			t.Errorf("expected %d to be %s, got %q", inode, typ, types[inode])
+		}
+	}
+}
+
+func TestFDStats(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	defer l.Close()
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fds[0])
+	defer unix.Close(fds[1])
+	// A socket opened twice is counted once.
+	dup, err := unix.Dup(fds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(dup)
+
+	s, err := fdStats([]int{os.Getpid(), os.Getpid()}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Sampled || s.Processes != 1 {
+		t.Errorf("expected 1 sampled process, got %+v", s)
+	}
+	if s.FDs < 4 || s.TCP < 1 || s.Unix < 2 || s.Sockets < s.TCP+s.Unix+s.UDP {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	before := s.Sockets
+	dup2, err := unix.Dup(fds[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(dup2)
+	if s, err = fdStats([]int{os.Getpid()}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if s.Sampled || s.Sockets != before {
+		t.Errorf("expected %d unsampled sockets, got %+v", before, s)
+	}
+}
@@ -0,0 +1,102 @@
+package libcontainer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// runFreezeHooks runs the PreFreeze or PostThaw hooks, the ones with
+// InContainer set being executed in the container. c.m must be held.
+func (c *Container) runFreezeHooks(name configs.HookName) error {
+	hooks := c.config.Hooks[name]
+	if len(hooks) == 0 {
+		return nil
+	}
+	s, err := c.currentOCIState()
+	if err != nil {
+		return err
+	}
+	for i, h := range hooks {
+		if ch, ok := h.(configs.CommandHook); ok && ch.InContainer {
+			err = c.runInContainerHook(ch.Command, s)
+		} else {
+			err = h.Run(s)
+		}
+		if err != nil {
+			return fmt.Errorf("error running %s hook #%d: %w", name, i, err)
+		}
+	}
+	return nil
+}
+
+// runPostThawHooks runs the PostThaw hooks, only logging their errors, as
+// the container is already thawed. c.m must be held.
+func (c *Container) runPostThawHooks() {
+	if err := c.runFreezeHooks(configs.PostThaw); err != nil {
+		logrus.Warn(err)
+	}
+}
+
+// runInContainerHook executes the hook command in the container, as root
+// and with the capabilities of the container init, the state being passed
+// on its stdin as for the other hooks. c.m must be held.
+func (c *Container) runInContainerHook(cmd configs.Command, s *specs.State) error {
+	state, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// The executable is always Args[0] in the container.
+	args := []string{cmd.Path}
+	if len(cmd.Args) > 1 {
+		args = append(args, cmd.Args[1:]...)
+	}
+	cwd := cmd.Dir
+	if cwd == "" {
+		cwd = "/"
+	}
+	var stdout, stderr bytes.Buffer
+	p := &Process{
+		Args:         args,
+		Env:          cmd.Env,
+		Cwd:          cwd,
+		Capabilities: c.config.Capabilities,
+		Stdin:        bytes.NewReader(state),
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		LogLevel:     strconv.Itoa(int(logrus.GetLevel())),
+	}
+	if err := c.start(p); err != nil {
+		return err
+	}
+	errC := make(chan error, 1)
+	go func() {
+		_, err := p.Wait()
+		if err != nil {
+			err = fmt.Errorf("%w, stdout: %s, stderr: %s", err, stdout.String(), stderr.String())
+		}
+		errC <- err
+	}()
+	var timerCh <-chan time.Time
+	if cmd.Timeout != nil {
+		timer := time.NewTimer(*cmd.Timeout)
+		defer timer.Stop()
+		timerCh = timer.C
+	}
+	select {
+	case err := <-errC:
+		return err
+	case <-timerCh:
+		_ = p.Signal(unix.SIGKILL)
+		<-errC
+		return fmt.Errorf("hook ran past specified timeout of %.1fs", cmd.Timeout.Seconds())
+	}
+}
@@ -0,0 +1,114 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fscommon"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// SetupCost is the cost of the container setup by runc (i.e. of runc
+// init, from its start till the container process is ready).
+type SetupCost struct {
+	// WallTimeUsec is the elapsed time, in microseconds.
+	WallTimeUsec uint64 `json:"wall_time_usec"`
+	// CPUUsec is the CPU time used, in microseconds, and MemoryPeak is
+	// the peak memory usage, in bytes. These are only known if a
+	// housekeeping cgroup is used (see
+	// [configs.Config.HousekeepingCgroup]); MemoryPeak also requires
+	// memory.peak (Linux 5.19).
+	CPUUsec    uint64 `json:"cpu_usec,omitempty"`
+	MemoryPeak uint64 `json:"memory_peak,omitempty"`
+}
+
+// housekeeping is a transient cgroup (under the housekeeping cgroup) for
+// runc init to do the container setup in.
+type housekeeping struct {
+	path  string
+	dir   *os.File
+	start time.Time
+}
+
+// newHousekeeping starts measuring the setup cost and, if a housekeeping
+// cgroup is configured, creates a transient sub-cgroup of it for a runc
+// init started by this runc to run in.
+func newHousekeeping(config *configs.Config, id string) (*housekeeping, error) {
+	h := &housekeeping{start: time.Now()}
+	if config.HousekeepingCgroup == "" {
+		return h, nil
+	}
+	path := filepath.Join(fs2.UnifiedMountpoint, config.HousekeepingCgroup, "runc-"+id+"-"+strconv.Itoa(os.Getpid()))
+	// Enable the controllers (memory, in particular) for the accounting.
+	if err := fs2.CreateCgroupPath(path, &configs.Cgroup{Resources: &configs.Resources{}}); err != nil {
+		return nil, fmt.Errorf("unable to create housekeeping cgroup: %w", err)
+	}
+	dir, err := os.OpenFile(path, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		_ = cgroups.RemovePath(path)
+		return nil, fmt.Errorf("unable to open housekeeping cgroup: %w", err)
+	}
+	h.path = path
+	h.dir = dir
+	return h, nil
+}
+
+// enabled reports whether a housekeeping cgroup is used.
+func (h *housekeeping) enabled() bool {
+	return h.path != ""
+}
+
+// join moves pid to the housekeeping cgroup.
+func (h *housekeeping) join(pid int) error {
+	return cgroups.WriteCgroupProc(h.path, pid)
+}
+
+// leave moves all the processes from the housekeeping cgroup to the
+// (cgroup v2) container cgroup dir, and returns the setup cost.
+func (h *housekeeping) leave(dir string) (*SetupCost, error) {
+	if h.enabled() {
+		pids, err := cgroups.GetPids(h.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, pid := range pids {
+			if err := cgroups.WriteCgroupProc(dir, pid); err != nil {
+				return nil, fmt.Errorf("unable to move pid %d from housekeeping cgroup: %w", pid, err)
+			}
+		}
+	}
+	return h.cost()
+}
+
+// cost returns the setup cost so far.
+func (h *housekeeping) cost() (*SetupCost, error) {
+	cost := &SetupCost{WallTimeUsec: uint64(time.Since(h.start).Microseconds())}
+	if !h.enabled() {
+		return cost, nil
+	}
+	var err error
+	if cost.CPUUsec, err = fscommon.GetValueByKey(h.path, "cpu.stat", "usage_usec"); err != nil {
+		return nil, err
+	}
+	if cost.MemoryPeak, err = fscommon.GetCgroupParamUint(h.path, "memory.peak"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return cost, nil
+}
+
+// remove removes the housekeeping sub-cgroup.
+func (h *housekeeping) remove() {
+	if !h.enabled() {
+		return
+	}
+	_ = h.dir.Close()
+	_ = cgroups.RemovePath(h.path)
+}
@@ -0,0 +1,110 @@
+package libcontainer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestHousekeepingDisabled(t *testing.T) {
+	hk, err := newHousekeeping(&configs.Config{}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hk.remove()
+	if hk.enabled() {
+		t.Fatal("expected housekeeping to be disabled")
+	}
+	time.Sleep(time.Millisecond)
+	cost, err := hk.leave("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost.WallTimeUsec < 1000 {
+		t.Errorf("expected wall time >= 1000us, got %d", cost.WallTimeUsec)
+	}
+	if cost.CPUUsec != 0 || cost.MemoryPeak != 0 {
+		t.Errorf("unexpected cost %+v", *cost)
+	}
+}
+
+func TestHousekeeping(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("requires cgroup v2")
+	}
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	base := "/runc-test-housekeeping-" + strconv.Itoa(os.Getpid())
+	config := &configs.Config{HousekeepingCgroup: base + "/hk"}
+	dest := filepath.Join(fs2.UnifiedMountpoint, base, "ct")
+	if err := fs2.CreateCgroupPath(dest, &configs.Cgroup{Resources: &configs.Resources{}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cgroups.RemovePath(dest)
+		_ = cgroups.RemovePath(filepath.Join(fs2.UnifiedMountpoint, base, "hk"))
+		_ = cgroups.RemovePath(filepath.Join(fs2.UnifiedMountpoint, base))
+	}()
+
+	hk, err := newHousekeeping(config, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hk.enabled() {
+		t.Fatal("expected housekeeping to be enabled")
+	}
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	if err := hk.join(cmd.Process.Pid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hk.leave(dest); err != nil {
+		t.Fatal(err)
+	}
+	pids, err := cgroups.GetPids(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pids) != 1 || pids[0] != cmd.Process.Pid {
+		t.Fatalf("expected pid %d in %s, got %v", cmd.Process.Pid, dest, pids)
+	}
+	hk.remove()
+	if _, err := os.Stat(hk.path); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed, got %v", hk.path, err)
+	}
+}
+
+func TestStartCmdRetryWithoutCgroupFD(t *testing.T) {
+	// An invalid cgroup fd makes the start with CLONE_INTO_CGROUP fail,
+	// the same as on a kernel not supporting it.
+	cmd := exec.Command("true")
+	cmd.SysProcAttr = &syscall.SysProcAttr{UseCgroupFD: true, CgroupFD: -1}
+	started, err := startCmd(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started == cmd {
+		t.Fatal("expected the start to be retried with a new command")
+	}
+	if started.SysProcAttr.UseCgroupFD {
+		t.Fatal("expected the retry not to use CLONE_INTO_CGROUP")
+	}
+	if err := started.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
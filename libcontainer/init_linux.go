@@ -22,6 +22,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/capabilities"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
 )
@@ -78,10 +79,15 @@ type initConfig struct {
 	CreateConsole    bool                  `json:"create_console"`
 	ConsoleWidth     uint16                `json:"console_width"`
 	ConsoleHeight    uint16                `json:"console_height"`
+	ConsoleVersion   int                   `json:"console_socket_version,omitempty"`
 	RootlessEUID     bool                  `json:"rootless_euid,omitempty"`
 	RootlessCgroups  bool                  `json:"rootless_cgroups,omitempty"`
 	SpecState        *specs.State          `json:"spec_state,omitempty"`
 	Cgroup2Path      string                `json:"cgroup2_path,omitempty"`
+	Secrets          []*Secret             `json:"secrets,omitempty"`
+	MaskPaths        []string              `json:"mask_paths,omitempty"`
+	Nice             *int                  `json:"nice,omitempty"`
+	SeccompProgram   *seccomp.Program      `json:"seccomp_program,omitempty"`
 }
 
 // Init is part of "runc init" implementation.
@@ -120,7 +126,7 @@ func startInitialization() (retErr error) {
 	defer func() {
 		// If this defer is ever called, this means initialization has failed.
 		// Send the error back to the parent process in the form of an initError.
-		ierr := initError{Message: retErr.Error()}
+		ierr := initError{Message: retErr.Error(), Exec: errors.Is(retErr, ErrExec)}
 		if err := writeSyncArg(syncPipe, procError, ierr); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			return
@@ -201,6 +207,12 @@ func startInitialization() (retErr error) {
 		return err
 	}
 
+	// Get secret fds.
+	secretFds, err := parseFdsFromEnv("_LIBCONTAINER_SECRET_FDS")
+	if err != nil {
+		return err
+	}
+
 	// Get runc-dmz fds.
 	var dmzExe *os.File
 	if dmzFdStr := os.Getenv("_LIBCONTAINER_DMZEXEFD"); dmzFdStr != "" {
@@ -230,12 +242,19 @@ func startInitialization() (retErr error) {
 	if err := json.NewDecoder(initPipe).Decode(&config); err != nil {
 		return err
 	}
+	if len(secretFds) != len(config.Secrets) {
+		return fmt.Errorf("malformed secret fds: expected %d, got %d", len(config.Secrets), len(secretFds))
+	}
+	for i, s := range config.Secrets {
+		s.File = os.NewFile(uintptr(secretFds[i]), "secret:"+s.Name)
+	}
 
 	// If init succeeds, it will not return, hence none of the defers will be called.
 	return containerInit(it, &config, syncPipe, consoleSocket, pidfdSocket, fifofd, logFD, dmzExe, mountFds{sourceFds: mountSrcFds, idmapFds: idmapFds})
 }
 
 func containerInit(t initType, config *initConfig, pipe *syncSocket, consoleSocket, pidfdSocket *os.File, fifoFd, logFd int, dmzExe *os.File, mountFds mountFds) error {
+	restoreNice(config.Nice)
 	if err := populateProcessEnvironment(config.Env); err != nil {
 		return err
 	}
@@ -308,6 +327,9 @@ func finalizeNamespace(config *initConfig) error {
 	if err := utils.CloseExecFrom(config.PassedFilesCount + 3); err != nil {
 		return fmt.Errorf("error closing exec fds: %w", err)
 	}
+	if err := inheritSecrets(config.Secrets); err != nil {
+		return err
+	}
 
 	// we only do chdir if it's specified
 	doChdir := config.Cwd != ""
@@ -405,7 +427,11 @@ func setupConsole(socket *os.File, config *initConfig, mount bool) error {
 		}
 	}
 	// While we can access console.master, using the API is a good idea.
-	if err := utils.SendRawFd(socket, pty.Name(), pty.Fd()); err != nil {
+	name, err := consoleSocketName(config.ConsoleVersion, config.ContainerID, pty.Name())
+	if err != nil {
+		return err
+	}
+	if err := utils.SendRawFd(socket, name, pty.Fd()); err != nil {
 		return err
 	}
 	runtime.KeepAlive(pty)
@@ -429,13 +455,38 @@ func syncParentReady(pipe *syncSocket) error {
 // syncParentHooks sends to the given pipe a JSON payload which indicates that
 // the parent should execute pre-start hooks. It then waits for the parent to
 // indicate that it is cleared to resume.
-func syncParentHooks(pipe *syncSocket) error {
+func syncParentHooks(pipe *syncSocket) (*configs.HookResult, error) {
 	// Tell parent.
 	if err := writeSync(pipe, procHooks); err != nil {
-		return err
+		return nil, err
 	}
-	// Wait for parent to give the all-clear.
-	return readSync(pipe, procHooksDone)
+	// Wait for parent to give the all-clear, along with the result
+	// of the createRuntime hooks, if any.
+	sync, err := readSyncFull(pipe, procHooksDone)
+	if err != nil {
+		return nil, err
+	}
+	if sync.File != nil {
+		_ = sync.File.Close()
+		return nil, fmt.Errorf("sync %v had unexpected file passed", sync.Type)
+	}
+	if sync.Arg == nil {
+		return nil, nil
+	}
+	var res configs.HookResult
+	if err := json.Unmarshal(*sync.Arg, &res); err != nil {
+		return nil, fmt.Errorf("sync %q passed invalid hook result: %w", sync.Type, err)
+	}
+	return &res, nil
+}
+
+// initSeccomp installs the container's seccomp filter, which is precompiled
+// by the runtime if config.SeccompProgram is set (see CreateAll).
+func initSeccomp(config *initConfig) (*os.File, error) {
+	if config.SeccompProgram != nil {
+		return seccomp.LoadProgram(config.SeccompProgram)
+	}
+	return seccomp.InitSeccomp(config.Config.Seccomp)
 }
 
 // syncParentSeccomp sends the fd associated with the seccomp file descriptor
@@ -522,7 +573,22 @@ func setupUser(config *initConfig) error {
 	allowSupGroups := !config.RootlessEUID && string(bytes.TrimSpace(setgroups)) != "deny"
 
 	if allowSupGroups {
-		suppGroups := append(execUser.Sgids, addGroups...)
+		// The groups the user is a member of (according to /etc/group)
+		// may not all be mapped in the user namespace, in which case
+		// setgroups(2) fails with EINVAL. Skip those, but not the ones
+		// explicitly requested.
+		gidMap, err := readGIDMap(config.Config, "/proc/self/gid_map")
+		if err != nil {
+			return err
+		}
+		sgids, unmapped := splitMappedIDs(execUser.Sgids, gidMap)
+		if len(unmapped) > 0 {
+			logrus.Debugf("skipping supplementary groups %v not mapped in the user namespace", unmapped)
+		}
+		if _, unmapped := splitMappedIDs(addGroups, gidMap); len(unmapped) > 0 {
+			return fmt.Errorf("cannot set additional groups %v not mapped in the user namespace", unmapped)
+		}
+		suppGroups := append(sgids, addGroups...)
 		if err := unix.Setgroups(suppGroups); err != nil {
 			return &os.SyscallError{Syscall: "setgroups", Err: err}
 		}
@@ -641,6 +707,57 @@ func setupRoute(config *configs.Config) error {
 	return nil
 }
 
+// setupStaticNetwork assigns the addresses and adds the routes from the
+// static network configuration, if any.
+func setupStaticNetwork(config *configs.Config) error {
+	n := config.StaticNetwork
+	if n == nil {
+		return nil
+	}
+	for _, a := range n.Addresses {
+		l, err := netlink.LinkByName(a.InterfaceName)
+		if err != nil {
+			return fmt.Errorf("static network: %w", err)
+		}
+		addr, err := netlink.ParseAddr(a.Address)
+		if err != nil {
+			return fmt.Errorf("static network: %w", err)
+		}
+		if err := netlink.AddrAdd(l, addr); err != nil {
+			return fmt.Errorf("static network: unable to add address %s to %s: %w", a.Address, a.InterfaceName, err)
+		}
+		if err := netlink.LinkSetUp(l); err != nil {
+			return fmt.Errorf("static network: unable to set %s up: %w", a.InterfaceName, err)
+		}
+	}
+	for _, r := range n.Routes {
+		route := &netlink.Route{Scope: netlink.SCOPE_UNIVERSE}
+		if r.Destination != "" {
+			_, dst, err := net.ParseCIDR(r.Destination)
+			if err != nil {
+				return fmt.Errorf("static network: %w", err)
+			}
+			route.Dst = dst
+		}
+		if r.Gateway != "" {
+			route.Gw = net.ParseIP(r.Gateway)
+		} else {
+			route.Scope = netlink.SCOPE_LINK
+		}
+		if r.InterfaceName != "" {
+			l, err := netlink.LinkByName(r.InterfaceName)
+			if err != nil {
+				return fmt.Errorf("static network: %w", err)
+			}
+			route.LinkIndex = l.Attrs().Index
+		}
+		if err := netlink.RouteAdd(route); err != nil {
+			return fmt.Errorf("static network: unable to add route %+v: %w", r, err)
+		}
+	}
+	return nil
+}
+
 func setupRlimits(limits []configs.Rlimit, pid int) error {
 	for _, rlimit := range limits {
 		if err := unix.Prlimit(pid, rlimit.Type, &unix.Rlimit{Max: rlimit.Hard, Cur: rlimit.Soft}, nil); err != nil {
@@ -68,7 +68,7 @@ func testCheckpoint(t *testing.T, userns bool) {
 		t.Skip("Test requires criu >= 3.17-4 on CentOS Stream 9.")
 	}
 
-	config := newTemplateConfig(t, &tParam{userns: userns})
+	config := newTemplateConfig(t, &tParam{Userns: userns})
 	stateDir := t.TempDir()
 
 	container, err := libcontainer.Create(stateDir, "test", config)
@@ -39,7 +39,7 @@ func testExecPS(t *testing.T, userns bool) {
 	if testing.Short() {
 		return
 	}
-	config := newTemplateConfig(t, &tParam{userns: userns})
+	config := newTemplateConfig(t, &tParam{Userns: userns})
 
 	buffers := runContainerOk(t, config, "ps", "-o", "pid,user,comm")
 	lines := strings.Split(buffers.Stdout.String(), "\n")
@@ -133,7 +133,7 @@ func testRlimit(t *testing.T, userns bool) {
 		return
 	}
 
-	config := newTemplateConfig(t, &tParam{userns: userns})
+	config := newTemplateConfig(t, &tParam{Userns: userns})
 
 	// ensure limit is lower than what the config requests to test that in a user namespace
 	// the Setrlimit call happens early enough that we still have permissions to raise the limit.
@@ -446,7 +446,7 @@ func testFreeze(t *testing.T, withSystemd bool, useSet bool) {
 		t.Skip("Test requires systemd.")
 	}
 
-	config := newTemplateConfig(t, &tParam{systemd: withSystemd})
+	config := newTemplateConfig(t, &tParam{Systemd: withSystemd})
 	container, err := newContainer(t, config)
 	ok(t, err)
 	defer destroyContainer(container)
@@ -511,7 +511,7 @@ func testCpuShares(t *testing.T, systemd bool) {
 		t.Skip("cgroup v2 does not support CpuShares")
 	}
 
-	config := newTemplateConfig(t, &tParam{systemd: systemd})
+	config := newTemplateConfig(t, &tParam{Systemd: systemd})
 	config.Cgroups.Resources.CpuShares = 1
 
 	if _, _, err := runContainer(t, config, "ps"); err == nil {
@@ -535,7 +535,7 @@ func testPids(t *testing.T, systemd bool) {
 		return
 	}
 
-	config := newTemplateConfig(t, &tParam{systemd: systemd})
+	config := newTemplateConfig(t, &tParam{Systemd: systemd})
 	config.Cgroups.Resources.PidsLimit = -1
 
 	// Running multiple processes, expecting it to succeed with no pids limit.
@@ -595,7 +595,7 @@ func testCgroupResourcesUnifiedErrorOnV1(t *testing.T, systemd bool) {
 		t.Skip("requires cgroup v1")
 	}
 
-	config := newTemplateConfig(t, &tParam{systemd: systemd})
+	config := newTemplateConfig(t, &tParam{Systemd: systemd})
 	config.Cgroups.Resources.Unified = map[string]string{
 		"memory.min": "10240",
 	}
@@ -624,7 +624,7 @@ func testCgroupResourcesUnified(t *testing.T, systemd bool) {
 		t.Skip("requires cgroup v2")
 	}
 
-	config := newTemplateConfig(t, &tParam{systemd: systemd})
+	config := newTemplateConfig(t, &tParam{Systemd: systemd})
 	config.Cgroups.Resources.Memory = 536870912     // 512M
 	config.Cgroups.Resources.MemorySwap = 536870912 // 512M, i.e. no swap
 	config.Namespaces.Add(configs.NEWCGROUP, "")
@@ -1522,7 +1522,7 @@ func TestInitJoinNetworkAndUser(t *testing.T) {
 	}
 
 	// Execute a long-running container
-	config1 := newTemplateConfig(t, &tParam{userns: true})
+	config1 := newTemplateConfig(t, &tParam{Userns: true})
 	container1, err := newContainer(t, config1)
 	ok(t, err)
 	defer destroyContainer(container1)
@@ -1548,7 +1548,7 @@ func TestInitJoinNetworkAndUser(t *testing.T) {
 	userns1 := state1.NamespacePaths[configs.NEWUSER]
 
 	// Run a container inside the existing pidns but with different cgroups.
-	config2 := newTemplateConfig(t, &tParam{userns: true})
+	config2 := newTemplateConfig(t, &tParam{Userns: true})
 	config2.Namespaces.Add(configs.NEWNET, netns1)
 	config2.Namespaces.Add(configs.NEWUSER, userns1)
 	// Emulate specconv.setupUserNamespace().
@@ -1696,7 +1696,7 @@ func testFdLeaks(t *testing.T, systemd bool) {
 		return
 	}
 
-	config := newTemplateConfig(t, &tParam{systemd: systemd})
+	config := newTemplateConfig(t, &tParam{Systemd: systemd})
 	// Run a container once to exclude file descriptors that are only
 	// opened once during the process lifetime by the library and are
 	// never closed. Those are not considered leaks.
@@ -1776,7 +1776,7 @@ func TestBindMountAndUser(t *testing.T) {
 	ok(t, err)
 
 	config := newTemplateConfig(t, &tParam{
-		userns: true,
+		Userns: true,
 	})
 
 	// Set HostID to 1000 to avoid DAC_OVERRIDE bypassing the purpose of this test.
@@ -1813,3 +1813,20 @@ func TestBindMountAndUser(t *testing.T) {
 
 	waitProcess(&pconfig, t)
 }
+
+func TestInitPath(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	// The test binary handles "init" (see init_test.go), just as an
+	// alternate init binary would.
+	initPath, err := os.Executable()
+	ok(t, err)
+	config := newTemplateConfig(t, nil)
+	config.InitPath = initPath
+
+	buffers := runContainerOk(t, config, "sh", "-c", "echo $0 && cat /proc/1/comm")
+	if out := buffers.Stdout.String(); out != "sh\nsh\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
@@ -84,7 +84,7 @@ func testExecInRlimit(t *testing.T, userns bool) {
 		return
 	}
 
-	config := newTemplateConfig(t, &tParam{userns: userns})
+	config := newTemplateConfig(t, &tParam{Userns: userns})
 	container, err := newContainer(t, config)
 	ok(t, err)
 	defer destroyContainer(container)
@@ -497,7 +497,7 @@ func TestExecInUserns(t *testing.T) {
 	if testing.Short() {
 		return
 	}
-	config := newTemplateConfig(t, &tParam{userns: true})
+	config := newTemplateConfig(t, &tParam{Userns: true})
 	container, err := newContainer(t, config)
 	ok(t, err)
 	defer destroyContainer(container)
@@ -4,17 +4,12 @@ import (
 	"os"
 	"testing"
 
-	"github.com/opencontainers/runc/libcontainer"
-	//nolint:revive // Enable cgroup manager to manage devices
-	_ "github.com/opencontainers/runc/libcontainer/cgroups/devices"
-	_ "github.com/opencontainers/runc/libcontainer/nsenter"
+	"github.com/opencontainers/runc/libcontainer/containertest"
 )
 
 // Same as ../../init.go but for libcontainer/integration.
 func init() {
-	if len(os.Args) > 1 && os.Args[1] == "init" {
-		libcontainer.Init()
-	}
+	containertest.Init()
 }
 
 func TestMain(m *testing.M) {
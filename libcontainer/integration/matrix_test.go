@@ -0,0 +1,51 @@
+package integration
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/containertest"
+)
+
+func TestPidsLimitMatrix(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	containertest.RunMatrix(t, containertest.DefaultMatrix, func(t *testing.T, p containertest.Param) {
+		config := newTemplateConfig(t, &p)
+		config.Cgroups.Resources.PidsLimit = 42
+
+		container, err := newContainer(t, config)
+		ok(t, err)
+		defer destroyContainer(container)
+
+		stdinR, stdinW, err := os.Pipe()
+		ok(t, err)
+		defer stdinW.Close() //nolint: errcheck
+		var stdout bytes.Buffer
+		process := &libcontainer.Process{
+			Cwd:    "/",
+			Args:   []string{"sh", "-c", "cat; echo ok"},
+			Env:    standardEnvironment,
+			Stdin:  stdinR,
+			Stdout: &stdout,
+			Init:   true,
+		}
+		err = container.Run(process)
+		_ = stdinR.Close()
+		ok(t, err)
+		// The rootless fs cgroup manager ignores the errors, so the
+		// limit may not be set.
+		if !p.Rootless || p.Systemd {
+			containertest.ExpectCgroupValue(t, container, "pids", "pids.max", "42")
+		}
+		_ = stdinW.Close()
+		waitProcess(process, t)
+		if out := strings.TrimSpace(stdout.String()); out != "ok" {
+			t.Fatalf("unexpected output: %q", out)
+		}
+	})
+}
@@ -15,7 +15,7 @@ func testUpdateDevices(t *testing.T, systemd bool) {
 	if testing.Short() {
 		return
 	}
-	config := newTemplateConfig(t, &tParam{systemd: systemd})
+	config := newTemplateConfig(t, &tParam{Systemd: systemd})
 	container, err := newContainer(t, config)
 	ok(t, err)
 	defer destroyContainer(container)
@@ -0,0 +1,228 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/bpflsm"
+	"github.com/opencontainers/runc/libcontainer/cgroups/manager"
+	"github.com/opencontainers/runc/libcontainer/cni"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/quota"
+)
+
+const journalFilename = "journal.json"
+
+type journalEntryType string
+
+const (
+	// journalCgroup is a cgroup created for the container. With the systemd
+	// cgroup driver, this includes the transient unit, which is started by
+	// the same cgroup manager Apply call, and stopped by its Destroy.
+	journalCgroup journalEntryType = "cgroup"
+	// journalIntelRdt is an Intel RDT group created for the container.
+	journalIntelRdt journalEntryType = "intelrdt"
+	// journalNetwork is a network interface set up by a network strategy
+	// (including, for veth, its host end).
+	journalNetwork journalEntryType = "network"
+	// journalNetnsPin is the bind mount pinning the container network
+	// namespace in the state directory, which is the only mount made in
+	// the runtime (host) mount namespace.
+	journalNetnsPin journalEntryType = "netns-pin"
+	// journalCNI is the container being added to a CNI network.
+	journalCNI journalEntryType = "cni"
+	// journalDiskQuota is a disk quota set for the container.
+	journalDiskQuota journalEntryType = "disk-quota"
+	// journalBPFLSM are BPF LSM programs attached to the container cgroup.
+	journalBPFLSM journalEntryType = "bpf-lsm"
+)
+
+// journalEntry describes a single side effect of a container creation,
+// with enough information to undo it without the container state.
+type journalEntry struct {
+	Type journalEntryType `json:"type"`
+	// Path is a path to an Intel RDT group directory (for journalIntelRdt),
+	// the pinned container network namespace (for journalNetwork and
+	// journalNetnsPin), or the container cgroup (for journalBPFLSM).
+	Path string `json:"path,omitempty"`
+	// Cgroup is the container's cgroup configuration (for journalCgroup).
+	Cgroup *configs.Cgroup `json:"cgroup,omitempty"`
+	// Name is the container ID (for journalCNI).
+	Name string `json:"name,omitempty"`
+	// CNI is the container's CNI configuration (for journalCNI).
+	CNI *configs.CNI `json:"cni,omitempty"`
+	// Network is the network configuration (for journalNetwork).
+	Network *configs.Network `json:"network,omitempty"`
+	// DiskQuota is the disk quota configuration (for journalDiskQuota).
+	DiskQuota *configs.DiskQuota `json:"disk_quota,omitempty"`
+	// BPFLSM is the BPF LSM configuration (for journalBPFLSM).
+	BPFLSM *configs.BPFLSM `json:"bpf_lsm,omitempty"`
+}
+
+// journal is a record of side effects made while creating a container
+// (cgroups and systemd units created, mounts made, etc.), persisted in the
+// container state directory. The entries are recorded before the
+// corresponding action is performed, so in case the creation fails (or runc
+// is killed in the middle of it), the journal can be replayed in reverse to
+// clean everything up, either right away or later by "runc delete".
+//
+// Once the container is successfully created, the journal is committed
+// (removed), as its state is then handled by the regular container lifecycle.
+type journal struct {
+	path    string
+	Entries []journalEntry `json:"entries"`
+}
+
+func newJournal(stateDir string) *journal {
+	return &journal{path: filepath.Join(stateDir, journalFilename)}
+}
+
+// loadJournal reads the journal from the container state directory.
+// If there is no journal, an empty one is returned.
+func loadJournal(stateDir string) (*journal, error) {
+	j := newJournal(stateDir)
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return j, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", j.path, err)
+	}
+	return j, nil
+}
+
+// record adds an entry to the journal and saves it to disk.
+func (j *journal) record(e journalEntry) error {
+	if j == nil {
+		return nil
+	}
+	j.Entries = append(j.Entries, e)
+	return j.save()
+}
+
+func (j *journal) save() error {
+	return writeJSONAtomic(filepath.Dir(j.path), journalFilename, "journal-", j)
+}
+
+// commit discards the journal, meaning the recorded side effects are no
+// longer to be undone by the journal.
+func (j *journal) commit() error {
+	if j == nil {
+		return nil
+	}
+	j.Entries = nil
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// rollback undoes all the recorded side effects in the reverse order, and
+// discards the journal. It tries to undo as much as possible, returning the
+// first error encountered.
+func (j *journal) rollback() error {
+	if j == nil {
+		return nil
+	}
+	var firstErr error
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		e := j.Entries[i]
+		if err := e.undo(); err != nil {
+			logrus.WithError(err).Warnf("unable to undo %s", e.Type)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unable to undo %s: %w", e.Type, err)
+			}
+		}
+	}
+	if err := j.commit(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+func (e *journalEntry) undo() error {
+	switch e.Type {
+	case journalCgroup:
+		// An adopted cgroup belongs to its owner, which also owns the
+		// processes in it, so there is nothing to undo. Such entries are
+		// no longer recorded, but may be found in an older journal.
+		if e.Cgroup == nil || e.Cgroup.Adopted {
+			return nil
+		}
+		m, err := manager.New(e.Cgroup)
+		if err != nil {
+			return err
+		}
+		if m.Exists() {
+			// Processes left in the cgroup prevent its removal.
+			_ = signalAllProcesses(m, unix.SIGKILL)
+		}
+		return m.Destroy()
+	case journalIntelRdt:
+		if err := os.Remove(e.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	case journalNetwork:
+		if e.Network == nil {
+			return nil
+		}
+		strategy, err := getStrategy(e.Network.Type)
+		if err != nil {
+			return err
+		}
+		return strategy.destroy(e.Network, e.Path)
+	case journalCNI:
+		if e.CNI == nil {
+			return nil
+		}
+		// The network namespace is gone by now.
+		return cni.Del(e.CNI, e.Name, "", nil)
+	case journalDiskQuota:
+		if e.DiskQuota == nil {
+			return nil
+		}
+		return quota.Remove(e.DiskQuota)
+	case journalBPFLSM:
+		if e.BPFLSM == nil {
+			return nil
+		}
+		return bpflsm.Detach(e.Path, e.BPFLSM.Programs)
+	case journalNetnsPin:
+		return unpinNetns(e.Path)
+	default:
+		return fmt.Errorf("unknown journal entry type %q", e.Type)
+	}
+	return nil
+}
+
+// RollbackCreate cleans up after a container creation which was interrupted
+// before the container state was saved (so the container can not be loaded),
+// by replaying the creation journal, and removes the container state
+// directory.
+func RollbackCreate(root, id string) error {
+	if root == "" {
+		return errors.New("root not set")
+	}
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	stateDir := filepath.Join(root, id)
+	j, err := loadJournal(stateDir)
+	if err != nil {
+		return err
+	}
+	err = j.rollback()
+	if rerr := os.RemoveAll(stateDir); rerr != nil && err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package libcontainer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups/manager"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestJournalSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	j := newJournal(dir)
+	for _, e := range []journalEntry{
+		{Type: journalNetnsPin, Path: "/some/netns"},
+		{Type: journalCNI, Name: "test"},
+	} {
+		if err := j.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := loadJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", loaded.Entries)
+	}
+	if loaded.Entries[0].Path != "/some/netns" || loaded.Entries[1].Name != "test" {
+		t.Fatalf("unexpected entries: %+v", loaded.Entries)
+	}
+
+	if err := loaded.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, journalFilename)); !os.IsNotExist(err) {
+		t.Fatalf("expected journal to be removed, got %v", err)
+	}
+	// No journal is the same as an empty journal.
+	loaded, err = loadJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 0 {
+		t.Fatalf("expected no entries, got %+v", loaded.Entries)
+	}
+}
+
+func TestRollbackCreate(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	root := t.TempDir()
+	stateDir := filepath.Join(root, "test")
+	if err := os.Mkdir(stateDir, 0o711); err != nil {
+		t.Fatal(err)
+	}
+	nsPath := filepath.Join(stateDir, netnsPinFilename)
+	j := newJournal(stateDir)
+	if err := j.record(journalEntry{Type: journalNetnsPin, Path: nsPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinNetns(os.Getpid(), nsPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RollbackCreate(root, "test"); err != nil {
+		t.Fatal(err)
+	}
+	// The state directory can only be removed once the pin is unmounted.
+	if _, err := os.Stat(stateDir); !os.IsNotExist(err) {
+		t.Fatalf("expected state directory to be removed, got %v", err)
+	}
+}
+
+func TestJournalUndoAdoptedCgroup(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	// The cgroup, and the process in it, are owned by someone else.
+	config := &configs.Cgroup{
+		Path:      "/runc-test-adopted-" + filepath.Base(t.TempDir()),
+		Resources: &configs.Resources{},
+	}
+	owner, err := manager.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sleep", "100")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		<-exited
+		_ = owner.Destroy()
+	})
+	if err := owner.Apply(cmd.Process.Pid); err != nil {
+		t.Skipf("unable to create cgroup: %v", err)
+	}
+
+	adopted := *config
+	adopted.Adopted = true
+	e := journalEntry{Type: journalCgroup, Cgroup: &adopted}
+	if err := e.undo(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-exited:
+		exited <- err
+		t.Fatalf("expected the process in the adopted cgroup to be left alone, it exited: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !owner.Exists() {
+		t.Fatal("expected the adopted cgroup to be left in place")
+	}
+}
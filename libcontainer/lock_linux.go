@@ -0,0 +1,273 @@
+package libcontainer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// LockDir is the directory, in the root directory, holding the container
+// lock files. It is a reserved container ID.
+const LockDir = ".locks"
+
+// How long to wait for a container lock before complaining about it.
+const lockWarnTimeout = 5 * time.Second
+
+// LockHolder describes the process holding a container lock.
+type LockHolder struct {
+	// Pid is the PID of the lock holder.
+	Pid int `json:"pid"`
+	// Operation is what the holder is doing (e.g. "delete").
+	Operation string `json:"operation"`
+	// Since is the time the lock was taken.
+	Since time.Time `json:"since"`
+}
+
+// ContainerLock is an exclusive advisory lock on a container, preventing
+// other runc instances from modifying the same container concurrently.
+//
+// The lock is a flock(2) on the <root>/.locks/<id> file, so it is released
+// automatically if the holder dies. The lock file also contains
+// the information about the holder, see LockHolder.
+type ContainerLock struct {
+	f *os.File
+}
+
+func lockPath(root, id string) string {
+	return filepath.Join(root, LockDir, id)
+}
+
+// LockContainer takes an exclusive lock on the container with the given ID,
+// waiting for it if it is held by someone else. The operation is a short
+// description of what the caller is going to do with the container, shown
+// to other lock users.
+//
+// The lock should be taken before loading the container state, and held
+// for as long as the container state is being modified. Taking the lock
+// for a container which is already locked by the current process is an
+// error (rather than a deadlock), and so is taking it from a descendant of
+// the lock holder (for example, from a hook which runs runc).
+func LockContainer(root, id, operation string) (*ContainerLock, error) {
+	if root == "" {
+		return nil, errors.New("root not set")
+	}
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+	path := lockPath(root, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		if err := waitLock(f, root, id); err != nil {
+			f.Close()
+			return nil, err
+		}
+		// The lock file might have been removed (by runc delete)
+		// while we were waiting for it; make sure we locked the file
+		// which is still in place.
+		if !sameFile(f, path) {
+			f.Close()
+			continue
+		}
+		l := &ContainerLock{f: f}
+		if err := l.writeHolder(operation); err != nil {
+			l.Unlock()
+			return nil, err
+		}
+		return l, nil
+	}
+}
+
+// waitLock takes an exclusive flock on the lock file f, waiting for it if it
+// is held by someone else, unless the holder is this process or one of its
+// ancestors.
+func waitLock(f *os.File, root, id string) error {
+	err := flock(f, unix.LOCK_EX|unix.LOCK_NB)
+	if err != unix.EWOULDBLOCK {
+		return err
+	}
+	holder, _ := ContainerLockHolder(root, id)
+	if holder != nil && lockedByAncestor(holder.Pid) {
+		return fmt.Errorf("deadlock: container %s is locked by pid %d (%s), which is this process or its parent", id, holder.Pid, holder.Operation)
+	}
+	warn := time.AfterFunc(lockWarnTimeout, func() {
+		if holder, _ := ContainerLockHolder(root, id); holder != nil && holder.Pid != 0 {
+			logrus.Warnf("waiting for container %s lock held by pid %d (%s) since %s", id, holder.Pid, holder.Operation, holder.Since.Format(time.RFC3339))
+		} else {
+			logrus.Warnf("waiting for container %s lock", id)
+		}
+	})
+	defer warn.Stop()
+	return flock(f, unix.LOCK_EX)
+}
+
+func flock(f *os.File, how int) error {
+	for {
+		err := unix.Flock(int(f.Fd()), how)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil && err != unix.EWOULDBLOCK {
+			return &os.PathError{Op: "flock", Path: f.Name(), Err: err}
+		}
+		return err
+	}
+}
+
+// LockContainers locks a number of containers, always in the same order
+// (sorted by ID), so that concurrent multi-container operations can not
+// deadlock each other. Duplicate IDs are ignored. In case of an error,
+// no locks are held.
+func LockContainers(root string, ids []string, operation string) ([]*ContainerLock, error) {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	locks := make([]*ContainerLock, 0, len(sorted))
+	for i, id := range sorted {
+		if i > 0 && sorted[i-1] == id {
+			continue
+		}
+		l, err := LockContainer(root, id, operation)
+		if err != nil {
+			UnlockContainers(locks)
+			return nil, err
+		}
+		locks = append(locks, l)
+	}
+	return locks, nil
+}
+
+// UnlockContainers releases the locks taken by LockContainers,
+// in the reverse order.
+func UnlockContainers(locks []*ContainerLock) {
+	for i := len(locks) - 1; i >= 0; i-- {
+		locks[i].Unlock()
+	}
+}
+
+// Unlock releases the lock.
+func (l *ContainerLock) Unlock() {
+	if l == nil || l.f == nil {
+		return
+	}
+	// The holder information is removed so it is not mistaken
+	// for the current one; closing the file releases the lock.
+	_ = l.f.Truncate(0)
+	l.f.Close()
+	l.f = nil
+}
+
+// Remove removes the lock file while the lock is still held. It is used
+// once the container is destroyed, so that the lock files do not pile up.
+func (l *ContainerLock) Remove() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	if err := os.Remove(l.f.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *ContainerLock) writeHolder(operation string) error {
+	data, err := json.Marshal(LockHolder{
+		Pid:       os.Getpid(),
+		Operation: operation,
+		Since:     time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = l.f.WriteAt(data, 0)
+	return err
+}
+
+// ContainerLockHolder returns the information about the current holder
+// of the container lock, or nil if the lock is not held.
+func ContainerLockHolder(root, id string) (*LockHolder, error) {
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+	path := lockPath(root, id)
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_SH|unix.LOCK_NB); err == nil {
+		// Nobody holds the lock.
+		return nil, nil
+	} else if err != unix.EWOULDBLOCK {
+		return nil, &os.PathError{Op: "flock", Path: path, Err: err}
+	}
+	var holder LockHolder
+	if err := json.NewDecoder(f).Decode(&holder); err != nil {
+		// The holder has not written its information yet.
+		return &LockHolder{}, nil //nolint:nilerr // Not an error.
+	}
+	return &holder, nil
+}
+
+// lockedByAncestor tells whether pid is the current process or one of
+// its ancestors, in which case waiting for the lock would never end.
+func lockedByAncestor(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	for p := os.Getpid(); p > 1; p = parentPid(p) {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
+
+func parentPid(pid int) int {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0
+	}
+	// The command name may contain spaces and parentheses,
+	// so look for the fields after the last ')'.
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 || i+2 >= len(data) {
+		return 0
+	}
+	var state string
+	var ppid int
+	if _, err := fmt.Sscan(string(data[i+2:]), &state, &ppid); err != nil {
+		return 0
+	}
+	return ppid
+}
+
+func sameFile(f *os.File, path string) bool {
+	fi1, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	fi2, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fi1, fi2)
+}
@@ -0,0 +1,146 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestContainerLock(t *testing.T) {
+	root := t.TempDir()
+
+	holder, err := ContainerLockHolder(root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder != nil {
+		t.Fatalf("expected no lock holder, got %+v", holder)
+	}
+
+	l, err := LockContainer(root, "test", "update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err = ContainerLockHolder(root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder == nil || holder.Pid != os.Getpid() || holder.Operation != "update" {
+		t.Fatalf("unexpected lock holder: %+v", holder)
+	}
+
+	// Taking the same lock again must not hang.
+	_, err = LockContainer(root, "test", "delete")
+	if err == nil || !strings.Contains(err.Error(), "deadlock") {
+		t.Fatalf("expected deadlock error, got %v", err)
+	}
+
+	l.Unlock()
+	holder, err = ContainerLockHolder(root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder != nil {
+		t.Fatalf("expected no lock holder after unlock, got %+v", holder)
+	}
+
+	l, err = LockContainer(root, "test", "delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	l.Unlock()
+	if _, err := os.Stat(lockPath(root, "test")); !os.IsNotExist(err) {
+		t.Fatalf("expected lock file to be removed, got %v", err)
+	}
+}
+
+func TestLockContainers(t *testing.T) {
+	root := t.TempDir()
+
+	locks, err := LockContainers(root, []string{"b", "a", "b"}, "pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 2 {
+		t.Fatalf("expected 2 locks, got %d", len(locks))
+	}
+	for _, id := range []string{"a", "b"} {
+		holder, err := ContainerLockHolder(root, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if holder == nil {
+			t.Errorf("expected %s to be locked", id)
+		}
+	}
+
+	// A failure to lock any container releases all the locks taken.
+	if _, err := LockContainers(root, []string{"0", "a"}, "resume"); err == nil {
+		t.Fatal("expected an error")
+	}
+	holder, err := ContainerLockHolder(root, "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder != nil {
+		t.Fatalf("expected 0 to be unlocked, got %+v", holder)
+	}
+
+	UnlockContainers(locks)
+}
+
+func TestContainerLockWait(t *testing.T) {
+	root := t.TempDir()
+
+	// The state directory of a container named "test.lock" does not get in
+	// the way of the lock of the "test" container.
+	if err := os.Mkdir(filepath.Join(root, "test.lock"), 0o711); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LockContainer(root, "test", "create")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Unlock()
+
+	// Someone else (without the holder information) holds the lock.
+	f, err := os.Open(lockPath(root, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan error, 1)
+	go func() {
+		l, err := LockContainer(root, "test", "delete")
+		if err == nil {
+			l.Unlock()
+		}
+		locked <- err
+	}()
+	select {
+	case err := <-locked:
+		t.Fatalf("expected to wait for the lock, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_UN); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the lock")
+	}
+}
@@ -0,0 +1,89 @@
+// Package lsm reads the stack of the active Linux Security Modules.
+//
+// With LSM stacking, several major LSMs (such as AppArmor and the BPF LSM, or
+// SELinux and Landlock) are active at once. Only one of them owns the legacy
+// process attribute files (/proc/<pid>/attr/current, exec, ...): the first
+// one of the stack providing process attributes.
+package lsm
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// listPath is the list of the active LSMs, in the order they are called.
+const listPath = "/sys/kernel/security/lsm"
+
+// attrLSMs are the LSMs providing process attributes.
+var attrLSMs = map[string]bool{
+	"selinux":  true,
+	"smack":    true,
+	"apparmor": true,
+}
+
+var (
+	stack     []string
+	stackErr  error
+	readStack sync.Once
+)
+
+// Stack returns the names of the active LSMs, in the order they are called.
+// It returns an error if the list can not be read (such as when securityfs
+// is not mounted).
+func Stack() ([]string, error) {
+	readStack.Do(func() {
+		var data []byte
+		data, stackErr = os.ReadFile(listPath)
+		if stackErr == nil {
+			stack = parseList(string(data))
+		}
+	})
+	return stack, stackErr
+}
+
+// parseList parses the contents of /sys/kernel/security/lsm, such as
+// "lockdown,capability,landlock,yama,apparmor,bpf".
+func parseList(data string) []string {
+	var names []string
+	for _, name := range strings.Split(strings.TrimSpace(data), ",") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IsActive returns whether the named LSM is active. known is false if the
+// stack can not be read, in which case active is meaningless.
+func IsActive(name string) (active, known bool) {
+	names, err := Stack()
+	if err != nil {
+		return false, false
+	}
+	for _, n := range names {
+		if n == name {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// AttrOwner returns the name of the LSM owning the legacy process attribute
+// files, or "" if there is none, or if the stack can not be read.
+func AttrOwner() string {
+	names, err := Stack()
+	if err != nil {
+		return ""
+	}
+	return attrOwner(names)
+}
+
+func attrOwner(names []string) string {
+	for _, n := range names {
+		if attrLSMs[n] {
+			return n
+		}
+	}
+	return ""
+}
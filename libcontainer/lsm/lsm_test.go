@@ -0,0 +1,45 @@
+package lsm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	for _, tc := range []struct {
+		data  string
+		names []string
+		owner string
+	}{
+		{
+			data:  "lockdown,capability,landlock,yama,apparmor,bpf\n",
+			names: []string{"lockdown", "capability", "landlock", "yama", "apparmor", "bpf"},
+			owner: "apparmor",
+		},
+		{
+			data:  "capability,selinux,landlock,bpf",
+			names: []string{"capability", "selinux", "landlock", "bpf"},
+			owner: "selinux",
+		},
+		{
+			data:  "capability,smack,apparmor",
+			names: []string{"capability", "smack", "apparmor"},
+			owner: "smack",
+		},
+		{
+			data:  "capability,landlock,yama",
+			names: []string{"capability", "landlock", "yama"},
+		},
+		{
+			data: "",
+		},
+	} {
+		names := parseList(tc.data)
+		if !reflect.DeepEqual(names, tc.names) {
+			t.Errorf("%q: expected %q, got %q", tc.data, tc.names, names)
+		}
+		if owner := attrOwner(names); owner != tc.owner {
+			t.Errorf("%q: expected the attributes owner %q, got %q", tc.data, tc.owner, owner)
+		}
+	}
+}
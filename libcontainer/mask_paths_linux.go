@@ -0,0 +1,63 @@
+package libcontainer
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// MaskPaths masks the given paths in the running container, the same way as
+// the configured ones (see configs.Config.MaskPaths) are masked at its start:
+// a file is overmounted by /dev/null, and a directory by an empty read-only
+// tmpfs. The paths not existing in the container are ignored. This is meant
+// to mitigate newly discovered information leaks (such as a /proc file)
+// without restarting the container.
+//
+// The paths are added to the container configuration, and recorded in
+// State.RuntimeMaskPaths. The masks are done by a helper process which joins
+// the container namespaces, so the container must not be paused.
+func (c *Container) MaskPaths(paths []string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	masked := make(map[string]bool, len(c.config.MaskPaths))
+	for _, p := range c.config.MaskPaths {
+		masked[p] = true
+	}
+	var add []string
+	for _, p := range paths {
+		if !filepath.IsAbs(p) || filepath.Clean(p) != p || p == "/" {
+			return fmt.Errorf("invalid mask path %q: must be a clean absolute path other than /", p)
+		}
+		if !masked[p] {
+			masked[p] = true
+			add = append(add, p)
+		}
+	}
+	if len(add) == 0 {
+		return nil
+	}
+
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	switch status {
+	case Stopped:
+		return ErrNotRunning
+	case Paused:
+		return ErrPaused
+	}
+
+	process := &Process{maskPaths: add}
+	if err := c.start(process); err != nil {
+		return fmt.Errorf("unable to mask paths: %w", err)
+	}
+	if _, err := process.Wait(); err != nil {
+		return fmt.Errorf("unable to mask paths: %w", err)
+	}
+
+	c.config.MaskPaths = append(c.config.MaskPaths, add...)
+	c.runtimeMaskPaths = append(c.runtimeMaskPaths, add...)
+	_, err = c.updateState(nil)
+	return err
+}
@@ -0,0 +1,21 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestMaskPathsInvalid(t *testing.T) {
+	c := &Container{config: &configs.Config{MaskPaths: []string{"/proc/kcore"}}}
+	for _, p := range []string{"", "/", "proc/foo", "/proc/../foo", "/proc/foo/"} {
+		if err := c.MaskPaths([]string{p}); err == nil {
+			t.Errorf("%q: expected error, got nil", p)
+		}
+	}
+	// Nothing to do (so no need for a running container) if the paths are
+	// masked already.
+	if err := c.MaskPaths([]string{"/proc/kcore"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
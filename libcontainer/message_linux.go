@@ -3,6 +3,7 @@ package libcontainer
 import (
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/vishvananda/netlink/nl"
 	"golang.org/x/sys/unix"
@@ -11,19 +12,21 @@ import (
 // list of known message types we want to send to bootstrap program
 // The number is randomly chosen to not conflict with known netlink types
 const (
-	InitMsg          uint16 = 62000
-	CloneFlagsAttr   uint16 = 27281
-	NsPathsAttr      uint16 = 27282
-	UidmapAttr       uint16 = 27283
-	GidmapAttr       uint16 = 27284
-	SetgroupAttr     uint16 = 27285
-	OomScoreAdjAttr  uint16 = 27286
-	RootlessEUIDAttr uint16 = 27287
-	UidmapPathAttr   uint16 = 27288
-	GidmapPathAttr   uint16 = 27289
-	MountSourcesAttr uint16 = 27290
-	IdmapSourcesAttr uint16 = 27291
-	TimeOffsetsAttr  uint16 = 27292
+	InitMsg           uint16 = 62000
+	CloneFlagsAttr    uint16 = 27281
+	NsPathsAttr       uint16 = 27282
+	UidmapAttr        uint16 = 27283
+	GidmapAttr        uint16 = 27284
+	SetgroupAttr      uint16 = 27285
+	OomScoreAdjAttr   uint16 = 27286
+	RootlessEUIDAttr  uint16 = 27287
+	UidmapPathAttr    uint16 = 27288
+	GidmapPathAttr    uint16 = 27289
+	MountSourcesAttr  uint16 = 27290
+	IdmapSourcesAttr  uint16 = 27291
+	TimeOffsetsAttr   uint16 = 27292
+	UidmapTimeoutAttr uint16 = 27293
+	GidmapTimeoutAttr uint16 = 27294
 )
 
 type Int32msg struct {
@@ -97,3 +100,18 @@ func (msg *Boolmsg) Serialize() []byte {
 func (msg *Boolmsg) Len() int {
 	return unix.NLA_HDRLEN + 4 // alignment
 }
+
+// timeoutMsec converts a timeout for nsexec, which takes it in milliseconds
+// (with zero meaning no timeout).
+func timeoutMsec(d time.Duration) uint32 {
+	switch ms := d.Milliseconds(); {
+	case d <= 0:
+		return 0
+	case ms == 0:
+		return 1
+	case ms > math.MaxUint32:
+		return math.MaxUint32
+	default:
+		return uint32(ms)
+	}
+}
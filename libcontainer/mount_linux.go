@@ -4,6 +4,7 @@ import (
 	"io/fs"
 	"strconv"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
 
@@ -91,6 +92,133 @@ func mountViaFDs(source string, srcFD *int, target, dstFD, fstype string, flags
 	return nil
 }
 
+// bindMountDetached bind-mounts source onto target (or dstFD, if non-empty),
+// using the new mount API if it is available.
+//
+// With the new mount API, the source is first cloned into a detached mount
+// tree (open_tree(2) with OPEN_TREE_CLONE), which belongs to an anonymous
+// mount namespace and so is not visible anywhere. The mount is then fully
+// set up while still detached (the MS_RDONLY, MS_NOSUID, MS_NODEV and
+// MS_NOEXEC flags are applied to its top mount by mount_setattr(2)), and is
+// only then attached to the target by move_mount(2). If we fail in between,
+// the detached tree is freed once its file descriptor is closed, leaving no
+// mount behind. The attachment itself happens in the mount namespace of
+// runc init, which is the container's own, so no mount ever appears in the
+// host mount namespace, and all of them are gone with runc init if the
+// container creation fails.
+//
+// mount(2) ignores all flags other than MS_REC for a non-remount MS_BIND, so
+// the callers still remount the bind mount with the flags, which is a no-op
+// if they are already applied. If the kernel does not support the new mount
+// API (open_tree(2) or move_mount(2) fail with ENOSYS or EINVAL),
+// bindMountDetached falls back to the classic mount(2) bind mount.
+func bindMountDetached(source string, srcFD *int, target, dstFD string, flags uintptr) error {
+	src := source
+	if srcFD != nil {
+		src = "/proc/self/fd/" + strconv.Itoa(*srcFD)
+	}
+	dst := target
+	if dstFD != "" {
+		dst = dstFD
+	}
+	treeFlags := uint(unix.OPEN_TREE_CLONE | unix.OPEN_TREE_CLOEXEC)
+	if flags&unix.MS_REC != 0 {
+		treeFlags |= unix.AT_RECURSIVE
+	}
+	fd, err := unix.OpenTree(unix.AT_FDCWD, src, treeFlags)
+	if err != nil {
+		if err != unix.ENOSYS && err != unix.EINVAL {
+			return &mountError{
+				op:     "open_tree",
+				source: source,
+				srcFD:  srcFD,
+				target: target,
+				dstFD:  dstFD,
+				flags:  flags,
+				err:    err,
+			}
+		}
+		logrus.Debugf("open_tree %s: %v (falling back to mount(2))", source, err)
+		return mountViaFDs(source, srcFD, target, dstFD, "bind", flags|unix.MS_BIND, "")
+	}
+	defer unix.Close(fd)
+	if attr := bindMountAttr(flags); attr.Attr_set != 0 {
+		// Best effort: the flags are applied by the caller's remount
+		// anyway, for example on kernels without mount_setattr(2). Like
+		// that remount, this only applies to the top mount, not to the
+		// submounts of a recursive bind mount (which is what the rro
+		// option is for).
+		if err := unix.MountSetattr(fd, "", unix.AT_EMPTY_PATH, attr); err != nil {
+			logrus.Debugf("mount_setattr %s: %v", source, err)
+		}
+	}
+	if err := unix.MoveMount(fd, "", unix.AT_FDCWD, dst, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		if err == unix.ENOSYS || err == unix.EINVAL {
+			logrus.Debugf("move_mount %s: %v (falling back to mount(2))", source, err)
+			return mountViaFDs(source, srcFD, target, dstFD, "bind", flags|unix.MS_BIND, "")
+		}
+		return &mountError{
+			op:     "move_mount",
+			source: source,
+			srcFD:  srcFD,
+			target: target,
+			dstFD:  dstFD,
+			flags:  flags,
+			err:    err,
+		}
+	}
+	return nil
+}
+
+// bindMountAttr returns the mount_setattr(2) attributes corresponding to the
+// mount(2) flags of a bind mount.
+func bindMountAttr(flags uintptr) *unix.MountAttr {
+	attr := &unix.MountAttr{}
+	for _, f := range []struct {
+		flag uintptr
+		attr uint64
+	}{
+		{unix.MS_RDONLY, unix.MOUNT_ATTR_RDONLY},
+		{unix.MS_NOSUID, unix.MOUNT_ATTR_NOSUID},
+		{unix.MS_NODEV, unix.MOUNT_ATTR_NODEV},
+		{unix.MS_NOEXEC, unix.MOUNT_ATTR_NOEXEC},
+	} {
+		if flags&f.flag != 0 {
+			attr.Attr_set |= f.attr
+		}
+	}
+	return attr
+}
+
+// moveMountFd mounts srcFD, a mount source passed as a file descriptor (see
+// configs.Mount.SourceFd), onto target (or dstFD, if non-empty). If srcFD
+// is a detached mount tree, it is attached as is by move_mount(2). Otherwise
+// (move_mount(2) fails with EINVAL for a file descriptor which is not the
+// root of a detached mount, or of a mount in the current mount namespace),
+// it is bind mounted by bindMountDetached.
+func moveMountFd(source string, srcFD int, target, dstFD string, flags uintptr) error {
+	dst := target
+	if dstFD != "" {
+		dst = dstFD
+	}
+	err := unix.MoveMount(srcFD, "", unix.AT_FDCWD, dst, unix.MOVE_MOUNT_F_EMPTY_PATH)
+	if err == nil {
+		return nil
+	}
+	if err != unix.EINVAL && err != unix.ENOSYS {
+		return &mountError{
+			op:     "move_mount",
+			source: source,
+			srcFD:  &srcFD,
+			target: target,
+			dstFD:  dstFD,
+			flags:  flags,
+			err:    err,
+		}
+	}
+	return bindMountDetached(source, &srcFD, target, dstFD, flags)
+}
+
 // unmount is a simple unix.Unmount wrapper.
 func unmount(target string, flags int) error {
 	err := unix.Unmount(target, flags)
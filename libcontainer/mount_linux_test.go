@@ -0,0 +1,100 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestBindMountDetached(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	src := t.TempDir()
+	dst := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "file"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bindMountDetached(src, nil, dst, "", unix.MS_BIND|unix.MS_REC); err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Unmount(dst, unix.MNT_DETACH) //nolint:errcheck
+
+	data, err := os.ReadFile(filepath.Join(dst, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "data" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+}
+
+func TestBindMountDetachedReadonly(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	src := t.TempDir()
+	dst := t.TempDir()
+	if err := bindMountDetached(src, nil, dst, "", unix.MS_BIND|unix.MS_RDONLY|unix.MS_NOSUID); err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Unmount(dst, unix.MNT_DETACH) //nolint:errcheck
+
+	// Unless the kernel lacks mount_setattr(2), the mount is attached
+	// read-only, with no remount.
+	var st unix.Statfs_t
+	if err := unix.Statfs(dst, &st); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.MountSetattr(-1, dst, 0, &unix.MountAttr{}); err == unix.ENOSYS {
+		t.Skip("mount_setattr not supported")
+	}
+	if st.Flags&unix.ST_RDONLY == 0 || st.Flags&unix.ST_NOSUID == 0 {
+		t.Fatalf("expected the mount to be read-only and nosuid, got flags %#x", st.Flags)
+	}
+}
+
+func TestBindMountDetachedRecursiveReadonly(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	src := t.TempDir()
+	dst := t.TempDir()
+	sub := filepath.Join(src, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Mount("tmpfs", sub, "tmpfs", 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Unmount(sub, unix.MNT_DETACH) //nolint:errcheck
+
+	// rbind,ro: only the top mount is read-only, as with mount(2).
+	if err := bindMountDetached(src, nil, dst, "", unix.MS_BIND|unix.MS_REC|unix.MS_RDONLY); err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Unmount(dst, unix.MNT_DETACH|unix.MNT_FORCE) //nolint:errcheck
+
+	if err := unix.MountSetattr(-1, dst, 0, &unix.MountAttr{}); err == unix.ENOSYS {
+		t.Skip("mount_setattr not supported")
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(dst, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Flags&unix.ST_RDONLY == 0 {
+		t.Errorf("expected the top mount to be read-only, got flags %#x", st.Flags)
+	}
+	if err := unix.Statfs(filepath.Join(dst, "sub"), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Flags&unix.ST_RDONLY != 0 {
+		t.Errorf("expected the submount to be read-write, got flags %#x", st.Flags)
+	}
+}
@@ -0,0 +1,74 @@
+package libcontainer
+
+import (
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// Namespace origins, see NamespaceState.
+const (
+	// NamespaceCreated is a namespace created by runc for the container.
+	NamespaceCreated = "created"
+	// NamespaceJoined is an existing namespace joined by the container,
+	// from a path (or a /proc/self/fd/N path for an fd).
+	NamespaceJoined = "joined"
+	// NamespaceInherited is a namespace not in the configuration, that
+	// the container shares with runc.
+	NamespaceInherited = "inherited"
+)
+
+// NamespaceState describes a namespace of the container init process.
+type NamespaceState struct {
+	// Type is the namespace type, such as "NEWNET".
+	Type configs.NamespaceType `json:"type"`
+	// Origin is how the container got the namespace: NamespaceCreated,
+	// NamespaceJoined, or NamespaceInherited.
+	Origin string `json:"origin"`
+	// Path is the path the namespace was joined from (NamespaceJoined
+	// only).
+	Path string `json:"path,omitempty"`
+	// Inode is the inode number of the namespace (as shown by
+	// readlink /proc/<pid>/ns/<type>), 0 if it can not be read.
+	Inode uint64 `json:"inode,omitempty"`
+	// Host tells whether the namespace is the one of PID 1, as seen by
+	// runc.
+	Host bool `json:"host,omitempty"`
+}
+
+// namespaceStates returns the state of the namespaces of the init process
+// pid, for the namespace types supported by the kernel.
+func (c *Container) namespaceStates(pid int) []NamespaceState {
+	var states []NamespaceState
+	for _, nsType := range configs.NamespaceTypes() {
+		if !configs.IsNamespaceSupported(nsType) {
+			continue
+		}
+		s := NamespaceState{Type: nsType, Origin: NamespaceInherited}
+		if c.config.Namespaces.Contains(nsType) {
+			s.Path = c.config.Namespaces.PathOf(nsType)
+			if s.Path == "" {
+				s.Origin = NamespaceCreated
+			} else {
+				s.Origin = NamespaceJoined
+			}
+		}
+		ns := configs.Namespace{Type: nsType}
+		s.Inode = namespaceInode(ns.GetPath(pid))
+		if s.Inode != 0 {
+			s.Host = s.Inode == namespaceInode(ns.GetPath(1))
+		}
+		states = append(states, s)
+	}
+	return states
+}
+
+// namespaceInode returns the inode number of the namespace file at path,
+// or 0 if it can not be read.
+func namespaceInode(path string) uint64 {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0
+	}
+	return st.Ino
+}
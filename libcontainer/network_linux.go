@@ -2,20 +2,45 @@ package libcontainer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/opencontainers/runc/libcontainer/cni"
 	"github.com/opencontainers/runc/libcontainer/configs"
-	"github.com/opencontainers/runc/types"
+	"github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
 )
 
 var strategies = map[string]networkStrategy{
 	"loopback": &loopback{},
+	"netdev":   &netdev{},
+	"macvlan":  &subInterface{kind: "macvlan"},
+	"ipvlan":   &subInterface{kind: "ipvlan"},
 }
 
+var (
+	macvlanModes = map[string]netlink.MacvlanMode{
+		"":         netlink.MACVLAN_MODE_BRIDGE,
+		"bridge":   netlink.MACVLAN_MODE_BRIDGE,
+		"private":  netlink.MACVLAN_MODE_PRIVATE,
+		"vepa":     netlink.MACVLAN_MODE_VEPA,
+		"passthru": netlink.MACVLAN_MODE_PASSTHRU,
+	}
+	ipvlanModes = map[string]netlink.IPVlanMode{
+		"":    netlink.IPVLAN_MODE_L2,
+		"l2":  netlink.IPVLAN_MODE_L2,
+		"l3":  netlink.IPVLAN_MODE_L3,
+		"l3s": netlink.IPVLAN_MODE_L3S,
+	}
+)
+
 // networkStrategy represents a specific network configuration for
 // a container's networking stack
 type networkStrategy interface {
@@ -23,6 +48,130 @@ type networkStrategy interface {
 	initialize(*network) error
 	detach(*configs.Network) error
 	attach(*configs.Network) error
+	// destroy cleans up after the network interface once the container is
+	// destroyed, or its creation is rolled back. netnsPath is the path to
+	// the (pinned) container network namespace, see pinNetns.
+	destroy(n *configs.Network, netnsPath string) error
+}
+
+// netnsPinFilename is the name of the file in the container state directory
+// the container network namespace is bind-mounted to, to keep it (and the
+// host interfaces moved into it) around until the container is destroyed.
+const netnsPinFilename = "netns"
+
+// needsNetnsPin tells whether the container network namespace is to be
+// pinned, i.e. whether some network strategy has anything to clean up.
+func needsNetnsPin(config *configs.Config) bool {
+	for _, n := range config.Networks {
+		if n.Type != "loopback" {
+			return true
+		}
+	}
+	return false
+}
+
+// pinNetns bind-mounts the network namespace of the process to path.
+func pinNetns(pid int, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDONLY, 0o400)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	src := "/proc/" + strconv.Itoa(pid) + "/ns/net"
+	if err := unix.Mount(src, path, "", unix.MS_BIND, ""); err != nil {
+		return &os.PathError{Op: "bind-mount " + src, Path: path, Err: err}
+	}
+	return nil
+}
+
+// teardownNetwork cleans up the container network interfaces, flushes the
+// conntrack entries of the container addresses, and unpins the container
+// network namespace. Errors returning the interfaces are fatal (so that
+// the container can be deleted again to retry), while the conntrack flush
+// is best effort.
+func teardownNetwork(c *Container) error {
+	nsPath := filepath.Join(c.stateDir, netnsPinFilename)
+	for _, n := range c.config.Networks {
+		strategy, err := getStrategy(n.Type)
+		if err != nil {
+			return err
+		}
+		if err := strategy.destroy(n, nsPath); err != nil {
+			return fmt.Errorf("unable to clean up network interface %s: %w", n.Name, err)
+		}
+	}
+	if !c.config.RootlessEUID {
+		if ips := containerIPs(c); len(ips) > 0 {
+			if err := flushConntrack(ips); err != nil {
+				logrus.WithError(err).Warn("unable to flush conntrack entries")
+			}
+		}
+	}
+	return unpinNetns(nsPath)
+}
+
+// unpinNetns unmounts the network namespace pinned by pinNetns.
+func unpinNetns(path string) error {
+	if err := unix.Unmount(path, unix.MNT_DETACH); err != nil && err != unix.EINVAL && err != unix.ENOENT {
+		return &os.PathError{Op: "unmount", Path: path, Err: err}
+	}
+	return nil
+}
+
+// containerIPs returns the (non-loopback) container IP addresses known to
+// runc, from the container network configuration and the CNI result.
+func containerIPs(c *Container) []net.IP {
+	var addrs []string
+	for _, n := range c.config.Networks {
+		addrs = append(addrs, n.Address, n.IPv6Address)
+	}
+	if sn := c.config.StaticNetwork; sn != nil {
+		for _, a := range sn.Addresses {
+			addrs = append(addrs, a.Address)
+		}
+	}
+	addrs = append(addrs, cni.IPs(c.cniResult)...)
+
+	var ips []net.IP
+	for _, a := range addrs {
+		ip, _, err := net.ParseCIDR(a)
+		if err != nil {
+			ip = net.ParseIP(a)
+		}
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+// ipConntrackFilter matches the conntrack flows from or to any of the IPs.
+type ipConntrackFilter []net.IP
+
+func (f ipConntrackFilter) MatchConntrackFlow(flow *netlink.ConntrackFlow) bool {
+	for _, ip := range f {
+		if flow.Forward.SrcIP.Equal(ip) || flow.Forward.DstIP.Equal(ip) ||
+			flow.Reverse.SrcIP.Equal(ip) || flow.Reverse.DstIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// flushConntrack deletes the host conntrack entries of the given IPs, so
+// that stale NAT entries do not affect a container later reusing them.
+func flushConntrack(ips []net.IP) error {
+	for _, family := range []netlink.InetFamily{unix.AF_INET, unix.AF_INET6} {
+		n, err := netlink.ConntrackDeleteFilter(netlink.ConntrackTable, family, ipConntrackFilter(ips))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			logrus.Debugf("flushed %d conntrack entries", n)
+		}
+	}
+	return nil
 }
 
 // getStrategy returns the specific network strategy for the
@@ -37,8 +186,8 @@ func getStrategy(tpe string) (networkStrategy, error) {
 }
 
 // Returns the network statistics for the network interfaces represented by the NetworkRuntimeInfo.
-func getNetworkInterfaceStats(interfaceName string) (*types.NetworkInterface, error) {
-	out := &types.NetworkInterface{Name: interfaceName}
+func getNetworkInterfaceStats(interfaceName string) (*NetworkInterface, error) {
+	out := &NetworkInterface{Name: interfaceName}
 	// This can happen if the network runtime information is missing - possible if the
 	// container was created by an old version of libcontainer.
 	if interfaceName == "" {
@@ -101,3 +250,275 @@ func (l *loopback) attach(n *configs.Network) (err error) {
 func (l *loopback) detach(n *configs.Network) (err error) {
 	return nil
 }
+
+func (l *loopback) destroy(n *configs.Network, netnsPath string) error {
+	return nil
+}
+
+// netdev is a network strategy that moves an existing host network
+// interface (for example, an SR-IOV VF) into the container, and returns it
+// to the host when the container is destroyed.
+//
+// HostInterfaceName is the name of the interface on the host, and Name
+// (if set) is the name to rename it to in the container.
+type netdev struct{}
+
+func (d *netdev) create(n *network, nspid int) error {
+	link, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetDown(link); err != nil {
+		return err
+	}
+	return netlink.LinkSetNsPid(link, nspid)
+}
+
+func (d *netdev) initialize(n *network) error {
+	link, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	if n.Name != "" && n.Name != n.HostInterfaceName {
+		if err := netlink.LinkSetName(link, n.Name); err != nil {
+			return err
+		}
+	}
+	return configureLink(link, &n.Network)
+}
+
+// configureLink sets the MAC address, MTU, addresses and gateways from the
+// network configuration on the link, and brings it up.
+func configureLink(link netlink.Link, n *configs.Network) error {
+	if n.MacAddress != "" {
+		mac, err := net.ParseMAC(n.MacAddress)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetHardwareAddr(link, mac); err != nil {
+			return err
+		}
+	}
+	if n.Mtu != 0 {
+		if err := netlink.LinkSetMTU(link, n.Mtu); err != nil {
+			return err
+		}
+	}
+	for _, a := range []string{n.Address, n.IPv6Address} {
+		if a == "" {
+			continue
+		}
+		addr, err := netlink.ParseAddr(a)
+		if err != nil {
+			return err
+		}
+		if err := netlink.AddrAdd(link, addr); err != nil {
+			return err
+		}
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return err
+	}
+	for _, gw := range []string{n.Gateway, n.IPv6Gateway} {
+		if gw == "" {
+			continue
+		}
+		if err := netlink.RouteAdd(&netlink.Route{
+			Scope:     netlink.SCOPE_UNIVERSE,
+			LinkIndex: link.Attrs().Index,
+			Gw:        net.ParseIP(gw),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *netdev) attach(n *configs.Network) error {
+	return nil
+}
+
+func (d *netdev) detach(n *configs.Network) error {
+	return nil
+}
+
+// destroy moves the interface back to the host network namespace (the one
+// runc is running in), restoring its host name.
+func (d *netdev) destroy(n *configs.Network, netnsPath string) error {
+	name := n.Name
+	if name == "" {
+		name = n.HostInterfaceName
+	}
+	notPinned := func() error {
+		// The kernel returned the interface to the initial network
+		// namespace when the container one was gone, under its
+		// container name.
+		if name != n.HostInterfaceName {
+			logrus.Warnf("container network namespace is gone, interface %s may have been returned to the host as %s", n.HostInterfaceName, name)
+		}
+		return nil
+	}
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return notPinned()
+		}
+		return err
+	}
+	defer ns.Close()
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		// EINVAL means netnsPath is not a namespace (no longer mounted).
+		if errors.Is(err, unix.EINVAL) {
+			return notPinned()
+		}
+		return err
+	}
+	defer h.Delete()
+	link, err := h.LinkByName(name)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			// Already returned (or not moved in yet).
+			return nil
+		}
+		return err
+	}
+	if err := h.LinkSetDown(link); err != nil {
+		return err
+	}
+	// Rename before moving, so it can't clash with a host interface.
+	if name != n.HostInterfaceName {
+		if err := h.LinkSetName(link, n.HostInterfaceName); err != nil {
+			return err
+		}
+	}
+	host, err := os.Open("/proc/self/ns/net")
+	if err != nil {
+		return err
+	}
+	defer host.Close()
+	return h.LinkSetNsFd(link, int(host.Fd()))
+}
+
+// subInterface is a network strategy that creates a macvlan or ipvlan
+// interface on a host (parent) interface, right in the container network
+// namespace.
+type subInterface struct {
+	kind string
+}
+
+func (s *subInterface) create(n *network, nspid int) error {
+	parent, err := netlink.LinkByName(n.Parent)
+	if err != nil {
+		return fmt.Errorf("%s parent: %w", s.kind, err)
+	}
+	attrs := netlink.LinkAttrs{
+		Name:        n.Name,
+		ParentIndex: parent.Attrs().Index,
+		Namespace:   netlink.NsPid(nspid),
+	}
+	var link netlink.Link
+	switch s.kind {
+	case "macvlan":
+		link = &netlink.Macvlan{LinkAttrs: attrs, Mode: macvlanModes[n.Mode]}
+	case "ipvlan":
+		link = &netlink.IPVlan{LinkAttrs: attrs, Mode: ipvlanModes[n.Mode]}
+	}
+	if err := netlink.LinkAdd(link); err != nil {
+		if errors.Is(err, unix.EBUSY) {
+			// A parent can't have both macvlan and ipvlan interfaces.
+			return fmt.Errorf("unable to create %s interface on %s (is it used by another kind of sub-interfaces?): %w", s.kind, n.Parent, err)
+		}
+		return fmt.Errorf("unable to create %s interface on %s: %w", s.kind, n.Parent, err)
+	}
+	return nil
+}
+
+func (s *subInterface) initialize(n *network) error {
+	link, err := netlink.LinkByName(n.Name)
+	if err != nil {
+		return err
+	}
+	return configureLink(link, &n.Network)
+}
+
+func (s *subInterface) attach(n *configs.Network) error {
+	return nil
+}
+
+func (s *subInterface) detach(n *configs.Network) error {
+	return nil
+}
+
+// destroy removes the interface from the pinned container network
+// namespace. If the namespace is gone, so is the interface.
+func (s *subInterface) destroy(n *configs.Network, netnsPath string) error {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer ns.Close()
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		if errors.Is(err, unix.EINVAL) {
+			return nil
+		}
+		return err
+	}
+	defer h.Delete()
+	link, err := h.LinkByName(n.Name)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	}
+	return h.LinkDel(link)
+}
+
+// checkParentConflicts checks that the macvlan or ipvlan interface does not
+// conflict with the ones of the other containers (in the same root) on the
+// same parent interface: a parent can only have one kind of sub-interfaces,
+// and their addresses must be unique.
+func checkParentConflicts(root, id string, n *configs.Network) error {
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, d := range dirs {
+		if !d.IsDir() || d.Name() == id {
+			continue
+		}
+		state, err := readStateFile(filepath.Join(root, d.Name(), stateFilename))
+		if err != nil {
+			continue
+		}
+		for _, other := range state.Config.Networks {
+			if other.Parent != n.Parent || (other.Type != "macvlan" && other.Type != "ipvlan") {
+				continue
+			}
+			if other.Type != n.Type {
+				return fmt.Errorf("parent interface %s is used for %s by container %s", n.Parent, other.Type, d.Name())
+			}
+			if sameIP(other.Address, n.Address) || sameIP(other.IPv6Address, n.IPv6Address) {
+				return fmt.Errorf("address of %s interface on %s is used by container %s", n.Type, n.Parent, d.Name())
+			}
+			if n.MacAddress != "" && strings.EqualFold(other.MacAddress, n.MacAddress) {
+				return fmt.Errorf("MAC address %s on %s is used by container %s", n.MacAddress, n.Parent, d.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// sameIP tells whether the two addresses (in the CIDR form) have the same IP.
+func sameIP(a, b string) bool {
+	ipA, _, errA := net.ParseCIDR(a)
+	ipB, _, errB := net.ParseCIDR(b)
+	return errA == nil && errB == nil && ipA.Equal(ipB)
+}
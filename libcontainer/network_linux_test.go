@@ -0,0 +1,104 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/vishvananda/netlink"
+)
+
+func TestContainerIPs(t *testing.T) {
+	c := &Container{
+		config: &configs.Config{
+			Networks: []*configs.Network{
+				{Type: "loopback"},
+				{Type: "netdev", Address: "10.0.0.2/24", IPv6Address: "fe80::2/64"},
+			},
+			StaticNetwork: &configs.StaticNetwork{
+				Addresses: []*configs.StaticAddress{
+					{InterfaceName: "lo", Address: "127.0.0.2/8"},
+					{InterfaceName: "eth1", Address: "192.168.1.10/24"},
+				},
+			},
+		},
+		cniResult: json.RawMessage(`{"ips": [{"address": "fd00::2/64"}]}`),
+	}
+	expected := []net.IP{
+		net.ParseIP("10.0.0.2"),
+		net.ParseIP("192.168.1.10"),
+		net.ParseIP("fd00::2"),
+	}
+	if ips := containerIPs(c); !reflect.DeepEqual(ips, expected) {
+		t.Errorf("expected %v, got %v", expected, ips)
+	}
+}
+
+func TestIPConntrackFilter(t *testing.T) {
+	f := ipConntrackFilter{net.ParseIP("10.0.0.2")}
+	flow := func(origSrc, origDst, replySrc, replyDst string) *netlink.ConntrackFlow {
+		fl := &netlink.ConntrackFlow{}
+		fl.Forward.SrcIP = net.ParseIP(origSrc)
+		fl.Forward.DstIP = net.ParseIP(origDst)
+		fl.Reverse.SrcIP = net.ParseIP(replySrc)
+		fl.Reverse.DstIP = net.ParseIP(replyDst)
+		return fl
+	}
+	for _, tc := range []struct {
+		flow  *netlink.ConntrackFlow
+		match bool
+	}{
+		{flow("10.0.0.2", "1.1.1.1", "1.1.1.1", "192.168.0.1"), true},
+		// Port-forwarded (DNAT) connection to the container.
+		{flow("1.1.1.1", "192.168.0.1", "10.0.0.2", "1.1.1.1"), true},
+		{flow("10.0.0.3", "1.1.1.1", "1.1.1.1", "10.0.0.3"), false},
+	} {
+		if m := f.MatchConntrackFlow(tc.flow); m != tc.match {
+			t.Errorf("%s: expected match %v, got %v", tc.flow, tc.match, m)
+		}
+	}
+}
+
+func TestCheckParentConflicts(t *testing.T) {
+	root := t.TempDir()
+	for id, networks := range map[string][]*configs.Network{
+		"a": {{Type: "macvlan", Parent: "eth0", Name: "eth0", Address: "192.168.1.10/24", MacAddress: "02:42:ac:11:00:02"}},
+		"b": {{Type: "ipvlan", Parent: "eth1", Name: "eth0", IPv6Address: "fd00::10/64"}},
+	} {
+		state := &State{BaseState: BaseState{ID: id, Config: configs.Config{Networks: networks}}}
+		if err := os.Mkdir(filepath.Join(root, id), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeJSONAtomic(filepath.Join(root, id), stateFilename, "state-", state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		n     *configs.Network
+		isErr bool
+	}{
+		{n: &configs.Network{Type: "macvlan", Parent: "eth0", Address: "192.168.1.11/24"}},
+		{n: &configs.Network{Type: "macvlan", Parent: "eth0", Address: "192.168.1.10/16"}, isErr: true},
+		{n: &configs.Network{Type: "macvlan", Parent: "eth0", MacAddress: "02:42:AC:11:00:02"}, isErr: true},
+		{n: &configs.Network{Type: "ipvlan", Parent: "eth0"}, isErr: true},
+		{n: &configs.Network{Type: "ipvlan", Parent: "eth1", IPv6Address: "fd00::10/64"}, isErr: true},
+		{n: &configs.Network{Type: "macvlan", Parent: "eth2", Address: "192.168.1.10/24"}},
+	} {
+		err := checkParentConflicts(root, "new", tc.n)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.n)
+		} else if !tc.isErr && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tc.n, err)
+		}
+	}
+
+	// A container does not conflict with itself.
+	if err := checkParentConflicts(root, "a", &configs.Network{Type: "macvlan", Parent: "eth0", Address: "192.168.1.10/24"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	helperNiceMu sync.Mutex
+	// helperNice is the niceness runc had before SetHelperNice, which
+	// runc init restores, or nil.
+	helperNice *int
+)
+
+// SetHelperNice sets the niceness of runc itself (see setpriority(2)) to
+// nice, so that it competes less (or more) with the workloads for the CPU.
+// runc init inherits it for the duration of the container setup, and then
+// restores the niceness runc had before, so that the container processes
+// do not inherit it (unless lowering the niceness is not permitted, as it
+// usually is not for rootless containers).
+func SetHelperNice(nice int) error {
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("invalid niceness %d: must be between -20 and 19", nice)
+	}
+	helperNiceMu.Lock()
+	defer helperNiceMu.Unlock()
+	if helperNice == nil {
+		orig, err := Nice(0)
+		if err != nil {
+			return err
+		}
+		helperNice = &orig
+	}
+	// The niceness is a per thread attribute on Linux, so set it for all
+	// the threads; the threads created later inherit it.
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+		err = unix.Setpriority(unix.PRIO_PROCESS, tid, nice)
+		if err != nil && !errors.Is(err, unix.ESRCH) {
+			return os.NewSyscallError("setpriority", err)
+		}
+	}
+	return nil
+}
+
+// Nice returns the niceness of the thread tid (0 for the calling thread).
+func Nice(tid int) (int, error) {
+	// The raw getpriority(2) syscall returns 20 - niceness.
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, tid)
+	if err != nil {
+		return 0, os.NewSyscallError("getpriority", err)
+	}
+	return 20 - prio, nil
+}
+
+// getHelperNice returns the niceness for runc init to restore, or nil.
+func getHelperNice() *int {
+	helperNiceMu.Lock()
+	defer helperNiceMu.Unlock()
+	return helperNice
+}
+
+// restoreNice sets the niceness of runc init back to nice, the one runc
+// had before SetHelperNice. This is best effort, as lowering the niceness
+// requires CAP_SYS_NICE.
+func restoreNice(nice *int) {
+	if nice == nil {
+		return
+	}
+	_ = unix.Setpriority(unix.PRIO_PROCESS, 0, *nice)
+}
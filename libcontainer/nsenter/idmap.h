@@ -13,6 +13,11 @@
 #define MOUNT_ATTR_IDMAP 0x00100000
 #endif
 
+/*
+ * The fallback syscall numbers below are the unified ones (since Linux 5.1),
+ * used by x86, arm64, riscv64, ppc, s390 and the other asm-generic architectures.
+ * Among the architectures supported by runc, only MIPS has an offset.
+ */
 #ifndef __NR_mount_setattr
 	#if defined _MIPS_SIM
 		#if _MIPS_SIM == _MIPS_SIM_ABI32	/* o32 */
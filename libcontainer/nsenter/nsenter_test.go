@@ -187,6 +187,63 @@ func TestNsenterChildLogging(t *testing.T) {
 	reapChildren(t, parent)
 }
 
+func TestNsenterInvalidUidMap(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+		t.Skip("requires userns")
+	}
+	args := []string{"nsenter-exec"}
+	parent, child := newPipe(t)
+	logread, logwrite := newPipe(t)
+
+	cmd := &exec.Cmd{
+		Path:       os.Args[0],
+		Args:       args,
+		ExtraFiles: []*os.File{child, logwrite},
+		Env:        []string{"_LIBCONTAINER_INITPIPE=3", "_LIBCONTAINER_LOGPIPE=4"},
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("nsenter failed to start: %v", err)
+	}
+	child.Close()
+	logwrite.Close()
+
+	r := nl.NewNetlinkRequest(int(libcontainer.InitMsg), 0)
+	r.AddData(&libcontainer.Int32msg{
+		Type:  libcontainer.CloneFlagsAttr,
+		Value: uint32(unix.CLONE_NEWUSER),
+	})
+	// Overlapping ranges are rejected by the kernel with EINVAL.
+	r.AddData(&libcontainer.Bytemsg{
+		Type:  libcontainer.UidmapAttr,
+		Value: []byte("0 0 10\n5 100 10\n"),
+	})
+	if _, err := io.Copy(parent, bytes.NewReader(r.Serialize())); err != nil {
+		t.Fatal(err)
+	}
+
+	initWaiter(t, parent)
+	logs, err := io.ReadAll(logread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Fatal("nsenter exits with a zero exit status")
+	}
+	for _, s := range []string{
+		`failed to write '0 0 10,5 100 10' to /proc/`,
+		`/uid_map (likely cause: the ranges overlap`,
+		`: Invalid argument`,
+	} {
+		if !bytes.Contains(logs, []byte(s)) {
+			t.Errorf("expected %q in the logs, got %s", s, logs)
+		}
+	}
+}
+
 func init() {
 	if strings.HasPrefix(os.Args[0], "nsenter-") {
 		os.Exit(0)
@@ -15,6 +15,7 @@
 #include <stdlib.h>
 #include <stdbool.h>
 #include <string.h>
+#include <time.h>
 #include <unistd.h>
 
 #include <sys/ioctl.h>
@@ -98,6 +99,8 @@ struct nlconfig_t {
 	size_t uidmappath_len;
 	char *gidmappath;
 	size_t gidmappath_len;
+	uint32_t uidmappath_timeout;	/* in milliseconds, 0 for none */
+	uint32_t gidmappath_timeout;	/* in milliseconds, 0 for none */
 
 	/* Mount sources opened outside the container userns. */
 	char *mountsources;
@@ -129,6 +132,8 @@ struct nlconfig_t {
 #define MOUNT_SOURCES_ATTR	27290
 #define IDMAP_SOURCES_ATTR	27291
 #define TIMENSOFFSET_ATTR	27292
+#define UIDMAPTIMEOUT_ATTR	27293
+#define GIDMAPTIMEOUT_ATTR	27294
 
 /*
  * Use the raw syscall for versions of glibc which don't include a function for
@@ -211,14 +216,93 @@ static void update_setgroups(int pid, enum policy_t setgroup)
 		 * If the kernel is too old to support /proc/pid/setgroups,
 		 * open(2) or write(2) will return ENOENT. This is fine.
 		 */
+		if (errno == EPERM)
+			bail("failed to write '%s' to /proc/%d/setgroups (likely cause: "
+			     "setgroups can not be allowed once denied, nor changed once gid_map is written)",
+			     policy, pid);
 		if (errno != ENOENT)
 			bail("failed to write '%s' to /proc/%d/setgroups", policy, pid);
 	}
 }
 
-static int try_mapping_tool(const char *app, int pid, char *map, size_t map_len)
+/* Describes the uid or gid mapping files and tools, for the error messages. */
+struct idmap_kind {
+	const char *file;	/* uid_map or gid_map */
+	const char *tool;	/* newuidmap or newgidmap */
+	const char *subid;	/* /etc/subuid or /etc/subgid */
+};
+
+static const struct idmap_kind uid_kind = { "uid_map", "newuidmap", "/etc/subuid" };
+static const struct idmap_kind gid_kind = { "gid_map", "newgidmap", "/etc/subgid" };
+
+/*
+ * Copies the mapping to buf with the newlines replaced by commas, so that it
+ * fits on a single log line.
+ */
+static char *format_idmap(const char *map, size_t map_len, char *buf, size_t size)
+{
+	size_t i, n = 0;
+
+	for (i = 0; i < map_len && map[i] != '\0' && n + 1 < size; i++) {
+		if (map[i] != '\n')
+			buf[n++] = map[i];
+		else if (i + 1 < map_len && map[i + 1] != '\0')
+			buf[n++] = ',';
+	}
+	buf[n] = '\0';
+	return buf;
+}
+
+/*
+ * Returns the likely causes of a failure to write an id mapping file with
+ * the given errno, or NULL if there is nothing to add to the error.
+ */
+static const char *idmap_hint(const struct idmap_kind *kind, int err, bool rootless)
+{
+	static char hint[512];
+
+	switch (err) {
+	case EPERM:
+		if (rootless)
+			snprintf(hint, sizeof(hint),
+				 "an unprivileged user can only map its own id; mapping more ids needs %s "
+				 "(from the uidmap or shadow-utils package) in $PATH and an entry for the user in %s",
+				 kind->tool, kind->subid);
+		else
+			snprintf(hint, sizeof(hint),
+				 "runc lacks CAP_SET%cID over the user namespace, or the host ids are not "
+				 "mapped in the user namespace runc is running in (see /proc/self/%s)",
+				 kind->file[0] == 'u' ? 'U' : 'G', kind->file);
+		return hint;
+	case EINVAL:
+		return "the ranges overlap, there are too many of them (340 since Linux 4.15, 5 before), "
+		    "or the mapping was already written";
+	case ENOENT:
+	case ESRCH:
+		return "the process has exited";
+	}
+	return NULL;
+}
+
+/* Returns the time elapsed since start, in milliseconds. */
+static uint64_t elapsed_ms(const struct timespec *start)
+{
+	struct timespec now;
+
+	if (clock_gettime(CLOCK_MONOTONIC, &now) < 0)
+		bail("failed to get the time");
+	return (now.tv_sec - start->tv_sec) * 1000 + (now.tv_nsec - start->tv_nsec) / 1000000;
+}
+
+/*
+ * Runs the mapping tool, and returns its exit status. If the timeout (in
+ * milliseconds) is not zero and the tool runs for longer, it is killed, and
+ * we bail out.
+ */
+static int try_mapping_tool(const char *app, int pid, char *map, size_t map_len, uint32_t timeout)
 {
 	int child;
+	struct timespec start;
 
 	/*
 	 * If @app is NULL, execve will segfault. Just check it here and bail (if
@@ -229,6 +313,9 @@ static int try_mapping_tool(const char *app, int pid, char *map, size_t map_len)
 	if (!app)
 		bail("mapping tool not present");
 
+	if (clock_gettime(CLOCK_MONOTONIC, &start) < 0)
+		bail("failed to get the time");
+
 	child = fork();
 	if (child < 0)
 		bail("failed to fork");
@@ -264,51 +351,77 @@ static int try_mapping_tool(const char *app, int pid, char *map, size_t map_len)
 		}
 
 		execve(app, argv, envp);
-		bail("failed to execv");
+		bail("failed to execv %s", app);
 	} else {
-		int status;
+		int status, ret;
+		const struct timespec poll = { .tv_nsec = 10 * 1000000 };
 
 		while (true) {
-			if (waitpid(child, &status, 0) < 0) {
+			ret = waitpid(child, &status, timeout ? WNOHANG : 0);
+			if (ret < 0) {
 				if (errno == EINTR)
 					continue;
 				bail("failed to waitpid");
 			}
-			if (WIFEXITED(status) || WIFSIGNALED(status))
+			if (ret == 0) {
+				if (elapsed_ms(&start) >= timeout) {
+					kill(child, SIGKILL);
+					waitpid(child, NULL, 0);
+					errno = ETIMEDOUT;
+					bail("%s did not finish in %ums (hung?)", app, timeout);
+				}
+				nanosleep(&poll, NULL);
+				continue;
+			}
+			if (WIFEXITED(status))
 				return WEXITSTATUS(status);
+			if (WIFSIGNALED(status))
+				return 128 + WTERMSIG(status);
 		}
 	}
 
 	return -1;
 }
 
-static void update_uidmap(const char *path, int pid, char *map, size_t map_len)
+/*
+ * Writes the mapping to /proc/<pid>/{uid,gid}_map, or, if this is not
+ * permitted and the path of the mapping tool is set (for rootless
+ * containers), uses the tool. The errors include the mapping, the errno, and
+ * the likely causes of the failure.
+ */
+static void update_idmap(const struct idmap_kind *kind, const char *path, uint32_t timeout, bool rootless, int pid,
+			 char *map, size_t map_len)
 {
+	char buf[1024];
+	const char *hint;
+	int err, ret;
+
 	if (map == NULL || map_len == 0)
 		return;
 
-	write_log(DEBUG, "update /proc/%d/uid_map to '%s'", pid, map);
-	if (write_file(map, map_len, "/proc/%d/uid_map", pid) < 0) {
-		if (errno != EPERM)
-			bail("failed to update /proc/%d/uid_map", pid);
-		write_log(DEBUG, "update /proc/%d/uid_map got -EPERM (trying %s)", pid, path);
-		if (try_mapping_tool(path, pid, map, map_len))
-			bail("failed to use newuid map on %d", pid);
-	}
-}
-
-static void update_gidmap(const char *path, int pid, char *map, size_t map_len)
-{
-	if (map == NULL || map_len == 0)
+	format_idmap(map, map_len, buf, sizeof(buf));
+	write_log(DEBUG, "update /proc/%d/%s to '%s'", pid, kind->file, buf);
+	if (write_file(map, map_len, "/proc/%d/%s", pid, kind->file) == 0)
 		return;
 
-	write_log(DEBUG, "update /proc/%d/gid_map to '%s'", pid, map);
-	if (write_file(map, map_len, "/proc/%d/gid_map", pid) < 0) {
-		if (errno != EPERM)
-			bail("failed to update /proc/%d/gid_map", pid);
-		write_log(DEBUG, "update /proc/%d/gid_map got -EPERM (trying %s)", pid, path);
-		if (try_mapping_tool(path, pid, map, map_len))
-			bail("failed to use newgid map on %d", pid);
+	err = errno;
+	if (err != EPERM || path == NULL) {
+		hint = idmap_hint(kind, err, rootless);
+		errno = err;
+		if (hint)
+			bail("failed to write '%s' to /proc/%d/%s (likely cause: %s)", buf, pid, kind->file, hint);
+		bail("failed to write '%s' to /proc/%d/%s", buf, pid, kind->file);
+	}
+
+	write_log(DEBUG, "update /proc/%d/%s got -EPERM (trying %s)", pid, kind->file, path);
+	ret = try_mapping_tool(path, pid, map, map_len, timeout);
+	if (ret) {
+		/* Report the original error rather than the one of waitpid. */
+		errno = err;
+		bail("failed to write '%s' to /proc/%d/%s, and %s failed with exit status %d "
+		     "(likely cause: no entry for the user in %s covering the requested ids, "
+		     "or %s is not setuid root nor has CAP_SET%cID file capability)",
+		     buf, pid, kind->file, path, ret, kind->subid, path, kind->file[0] == 'u' ? 'U' : 'G');
 	}
 }
 
@@ -458,6 +571,12 @@ static void nl_parse(int fd, struct nlconfig_t *config)
 			config->timensoffset = current;
 			config->timensoffset_len = payload_len;
 			break;
+		case UIDMAPTIMEOUT_ATTR:
+			config->uidmappath_timeout = readint32(current);
+			break;
+		case GIDMAPTIMEOUT_ATTR:
+			config->gidmappath_timeout = readint32(current);
+			break;
 		default:
 			bail("unknown netlink message type %d", nlattr->nla_type);
 		}
@@ -655,6 +774,37 @@ void send_mountsources(int sockfd, pid_t child, char *mountsources, size_t mount
 		bail("failed to close container mount namespace fd %d", container_mntns_fd);
 }
 
+/*
+ * Returns the likely causes of a failure to create a user namespace with the
+ * given errno, or NULL if there is nothing to add to the error. errno is
+ * preserved.
+ */
+static const char *userns_unshare_hint(int err)
+{
+	char buf[32] = { 0 };
+	int fd;
+
+	switch (err) {
+	case ENOSPC:
+		fd = open("/proc/sys/user/max_user_namespaces", O_RDONLY | O_CLOEXEC);
+		if (fd >= 0) {
+			if (read(fd, buf, sizeof(buf) - 1) < 0)
+				buf[0] = '\0';
+			close(fd);
+		}
+		errno = err;
+		if (!strcmp(buf, "0\n"))
+			return "user namespaces are disabled by the user.max_user_namespaces sysctl being 0";
+		return "the user.max_user_namespaces limit is reached, or the namespaces are nested more than 32 levels deep";
+	case EPERM:
+		return "unprivileged user namespaces are disabled by the kernel.unprivileged_userns_clone sysctl, "
+		    "or runc is in a chroot";
+	case EUSERS:
+		return "the user namespaces are nested more than 32 levels deep";
+	}
+	return NULL;
+}
+
 void try_unshare(int flags, const char *msg)
 {
 	write_log(DEBUG, "unshare %s", msg);
@@ -671,6 +821,11 @@ void try_unshare(int flags, const char *msg)
 		if (errno != EINVAL)
 			break;
 	}
+	if (flags & CLONE_NEWUSER) {
+		const char *hint = userns_unshare_hint(errno);
+		if (hint)
+			bail("failed to unshare %s (likely cause: %s)", msg, hint);
+	}
 	bail("failed to unshare %s", msg);
 }
 
@@ -944,8 +1099,10 @@ void nsexec(void)
 						update_setgroups(stage1_pid, SETGROUPS_DENY);
 
 					/* Set up mappings. */
-					update_uidmap(config.uidmappath, stage1_pid, config.uidmap, config.uidmap_len);
-					update_gidmap(config.gidmappath, stage1_pid, config.gidmap, config.gidmap_len);
+					update_idmap(&uid_kind, config.uidmappath, config.uidmappath_timeout,
+						     config.is_rootless_euid, stage1_pid, config.uidmap, config.uidmap_len);
+					update_idmap(&gid_kind, config.gidmappath, config.gidmappath_timeout,
+						     config.is_rootless_euid, stage1_pid, config.gidmap, config.gidmap_len);
 
 					s = SYNC_USERMAP_ACK;
 					if (write(syncfd, &s, sizeof(s)) != sizeof(s)) {
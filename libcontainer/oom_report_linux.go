@@ -0,0 +1,189 @@
+package libcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// oomReportsDir is the directory in the container state directory
+	// where OOM reports are saved.
+	oomReportsDir = "oom"
+	// maxOOMReports is the number of most recent OOM reports kept.
+	maxOOMReports = 10
+	// maxOOMKernelLog is the number of most recent OOM-related kernel log
+	// lines saved in an OOM report.
+	maxOOMKernelLog = 50
+)
+
+// OOMReport is a snapshot of the container memory usage, taken after an
+// OOM kill, for post-mortem debugging.
+type OOMReport struct {
+	Time time.Time `json:"time"`
+	// MemoryStat is the contents of the memory.stat cgroup file.
+	MemoryStat map[string]uint64 `json:"memory_stat,omitempty"`
+	// MemoryPeak is the maximum memory usage of the cgroup, if known
+	// (memory.peak for cgroup v2, memory.max_usage_in_bytes for v1).
+	MemoryPeak uint64 `json:"memory_peak,omitempty"`
+	// Processes are the container processes using the most memory,
+	// sorted by their resident set size.
+	Processes []OOMProcess `json:"processes"`
+	// KernelLog are the most recent OOM-related kernel log lines. Reading
+	// the kernel log may require the CAP_SYSLOG capability.
+	KernelLog []string `json:"kernel_log,omitempty"`
+	// Path is where the report was saved.
+	Path string `json:"path,omitempty"`
+}
+
+// OOMProcess describes a process in an [OOMReport].
+type OOMProcess struct {
+	Pid  int    `json:"pid"`
+	Comm string `json:"comm"`
+	// RSS is the resident set size of the process, in bytes.
+	RSS uint64 `json:"rss"`
+}
+
+// CaptureOOMReport takes an OOM report (with the top processes by RSS),
+// and saves it to the container state directory, removing the older
+// reports in excess of the 10 most recent ones. It is meant to be called
+// upon an OOM notification (see [Container.NotifyOOM]). The parts of the
+// report which can not be obtained are omitted.
+func (c *Container) CaptureOOMReport(top int) (*OOMReport, error) {
+	r := &OOMReport{Time: time.Now().UTC()}
+	if stats, err := c.cgroupManager.GetStats(); err == nil {
+		r.MemoryStat = stats.MemoryStats.Stats
+		r.MemoryPeak = stats.MemoryStats.Usage.MaxUsage
+	}
+	pids, err := c.Processes()
+	if err != nil {
+		return nil, err
+	}
+	r.Processes = topRSSProcesses(pids, top)
+	r.KernelLog = readOOMKernelLog()
+
+	dir := filepath.Join(c.stateDir, oomReportsDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	name := "oom-" + r.Time.Format("20060102T150405.000000000Z") + ".json"
+	r.Path = filepath.Join(dir, name)
+	if err := writeJSONAtomic(dir, name, ".tmp-", r); err != nil {
+		return nil, fmt.Errorf("unable to save oom report: %w", err)
+	}
+	pruneOOMReports(dir)
+	return r, nil
+}
+
+func pruneOOMReports(dir string) {
+	matches, _ := filepath.Glob(filepath.Join(dir, "oom-*.json"))
+	// The names sort chronologically.
+	sort.Strings(matches)
+	for len(matches) > maxOOMReports {
+		_ = os.Remove(matches[0])
+		matches = matches[1:]
+	}
+}
+
+// topRSSProcesses returns up to top processes (or all of them, if top is
+// not positive) from pids, sorted by their RSS, in descending order.
+func topRSSProcesses(pids []int, top int) []OOMProcess {
+	procs := make([]OOMProcess, 0, len(pids))
+	for _, pid := range pids {
+		data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/status")
+		if err != nil {
+			continue
+		}
+		p := parseProcStatusRSS(data)
+		p.Pid = pid
+		procs = append(procs, p)
+	}
+	sort.SliceStable(procs, func(i, j int) bool { return procs[i].RSS > procs[j].RSS })
+	if top > 0 && len(procs) > top {
+		procs = procs[:top]
+	}
+	return procs
+}
+
+// parseProcStatusRSS gets the process name and RSS from the contents of
+// /proc/<pid>/status.
+func parseProcStatusRSS(data []byte) OOMProcess {
+	var p OOMProcess
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch k {
+		case "Name":
+			p.Comm = v
+		case "VmRSS":
+			kb, _ := strconv.ParseUint(strings.TrimSuffix(v, " kB"), 10, 64)
+			p.RSS = kb * 1024
+		}
+	}
+	return p
+}
+
+// readOOMKernelLog returns the most recent OOM-related lines from the
+// kernel log buffer, read from /dev/kmsg.
+func readOOMKernelLog() []string {
+	f, err := os.OpenFile("/dev/kmsg", os.O_RDONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	// Each read returns a single record; the buffer must fit it.
+	buf := make([]byte, 8192)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			// EPIPE means some records were overwritten, which
+			// is fine; EAGAIN means there are no more records.
+			if errors.Is(err, unix.EPIPE) {
+				continue
+			}
+			break
+		}
+		if msg, ok := oomKmsgMessage(buf[:n]); ok {
+			lines = append(lines, msg)
+			if len(lines) > maxOOMKernelLog {
+				lines = lines[1:]
+			}
+		}
+	}
+	return lines
+}
+
+// oomKmsgMessage extracts the message from a /dev/kmsg record (see
+// Documentation/ABI/testing/dev-kmsg in the kernel source), and returns
+// whether it is related to an OOM kill.
+func oomKmsgMessage(record []byte) (string, bool) {
+	_, msg, ok := bytes.Cut(record, []byte{';'})
+	if !ok {
+		return "", false
+	}
+	// Continuation lines (dictionary) start with a space.
+	if i := bytes.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	m := string(msg)
+	for _, s := range []string{"invoked oom-killer", "oom-kill:", "Out of memory", "Memory cgroup out of memory", "Killed process"} {
+		if strings.Contains(m, s) {
+			return m, true
+		}
+	}
+	return "", false
+}
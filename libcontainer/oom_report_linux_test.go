@@ -0,0 +1,63 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProcStatusRSS(t *testing.T) {
+	status := "Name:\tstress\nUmask:\t0022\nState:\tR (running)\nVmPeak:\t  123456 kB\nVmRSS:\t   20480 kB\nThreads:\t1\n"
+	p := parseProcStatusRSS([]byte(status))
+	if p.Comm != "stress" || p.RSS != 20480*1024 {
+		t.Errorf("unexpected result: %+v", p)
+	}
+	// Kernel threads have no VmRSS.
+	if p := parseProcStatusRSS([]byte("Name:\tkthreadd\n")); p.RSS != 0 {
+		t.Errorf("expected zero RSS, got %+v", p)
+	}
+}
+
+func TestTopRSSProcesses(t *testing.T) {
+	procs := topRSSProcesses([]int{os.Getpid(), 1 << 30}, 5)
+	if len(procs) != 1 || procs[0].Pid != os.Getpid() || procs[0].RSS == 0 {
+		t.Errorf("unexpected result: %+v", procs)
+	}
+}
+
+func TestOOMKmsgMessage(t *testing.T) {
+	for _, tc := range []struct {
+		record, msg string
+		ok          bool
+	}{
+		{"6,1234,5678,-;stress invoked oom-killer: gfp_mask=0xcc0(GFP_KERNEL), order=0, oom_score_adj=0\n", "stress invoked oom-killer: gfp_mask=0xcc0(GFP_KERNEL), order=0, oom_score_adj=0", true},
+		{"3,1235,5679,-;Memory cgroup out of memory: Killed process 42 (stress) total-vm:1024kB\n SUBSYSTEM=mem\n", "Memory cgroup out of memory: Killed process 42 (stress) total-vm:1024kB", true},
+		{"6,1236,5680,-;oom-kill:constraint=CONSTRAINT_MEMCG,task=stress,pid=42,uid=0\n", "oom-kill:constraint=CONSTRAINT_MEMCG,task=stress,pid=42,uid=0", true},
+		{"6,1237,5681,-;eth0: link up\n", "", false},
+		{"garbage", "", false},
+	} {
+		msg, ok := oomKmsgMessage([]byte(tc.record))
+		if msg != tc.msg || ok != tc.ok {
+			t.Errorf("%q: expected (%q, %v), got (%q, %v)", tc.record, tc.msg, tc.ok, msg, ok)
+		}
+	}
+}
+
+func TestPruneOOMReports(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < maxOOMReports+3; i++ {
+		name := fmt.Sprintf("oom-20260101T0000%02d.000000000Z.json", i)
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneOOMReports(dir)
+	matches, _ := filepath.Glob(filepath.Join(dir, "oom-*.json"))
+	if len(matches) != maxOOMReports {
+		t.Fatalf("expected %d reports, got %d", maxOOMReports, len(matches))
+	}
+	if filepath.Base(matches[0]) != "oom-20260101T000003.000000000Z.json" {
+		t.Errorf("expected the oldest reports to be removed, got %v", matches)
+	}
+}
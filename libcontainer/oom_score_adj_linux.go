@@ -0,0 +1,64 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// deferOomScoreAdj reports whether the container oom_score_adj is to be set
+// by runc once the container setup is done, rather than by runc init at its
+// very start. In the former case, runc init inherits the oom_score_adj of
+// runc (see [ReadOomScoreAdj]) for the duration of the setup, so that it is
+// not protected from (or exposed to) the OOM killer as the container
+// process is.
+//
+// This is only done for the container init of a non-rootless container, as
+// lowering oom_score_adj requires CAP_SYS_RESOURCE in the initial user
+// namespace.
+func deferOomScoreAdj(config *configs.Config, it initType) bool {
+	return it == initStandard && config.OomScoreAdj != nil && !config.RootlessEUID
+}
+
+// ReadOomScoreAdj returns the oom_score_adj of the process with the given
+// pid (0 for the current process).
+func ReadOomScoreAdj(pid int) (int, error) {
+	data, err := os.ReadFile(oomScoreAdjPath(pid))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// WriteOomScoreAdj sets the oom_score_adj of the process with the given pid
+// (0 for the current process).
+func WriteOomScoreAdj(pid, value int) error {
+	if value < -1000 || value > 1000 {
+		return fmt.Errorf("invalid oom_score_adj %d: must be between -1000 and 1000", value)
+	}
+	return os.WriteFile(oomScoreAdjPath(pid), []byte(strconv.Itoa(value)), 0)
+}
+
+func oomScoreAdjPath(pid int) string {
+	if pid == 0 {
+		return "/proc/self/oom_score_adj"
+	}
+	return "/proc/" + strconv.Itoa(pid) + "/oom_score_adj"
+}
+
+// OomScoreAdj returns the oom_score_adj of the container init process.
+func (c *Container) OomScoreAdj() (int, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return 0, err
+	}
+	if status == Stopped {
+		return 0, ErrNotRunning
+	}
+	return ReadOomScoreAdj(c.initProcess.pid())
+}
@@ -0,0 +1,50 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestDeferOomScoreAdj(t *testing.T) {
+	adj := -500
+	testCases := []struct {
+		config   configs.Config
+		it       initType
+		expected bool
+	}{
+		{config: configs.Config{}, it: initStandard},
+		{config: configs.Config{OomScoreAdj: &adj}, it: initStandard, expected: true},
+		{config: configs.Config{OomScoreAdj: &adj}, it: initSetns},
+		{config: configs.Config{OomScoreAdj: &adj, RootlessEUID: true}, it: initStandard},
+	}
+	for _, tc := range testCases {
+		if got := deferOomScoreAdj(&tc.config, tc.it); got != tc.expected {
+			t.Errorf("oom_score_adj %v, rootless %v, %s: expected %v, got %v", tc.config.OomScoreAdj, tc.config.RootlessEUID, tc.it, tc.expected, got)
+		}
+	}
+}
+
+func TestOomScoreAdjSelf(t *testing.T) {
+	adj, err := ReadOomScoreAdj(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Raising oom_score_adj does not require any privileges.
+	if adj < 1000 {
+		if err := WriteOomScoreAdj(0, adj+1); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = WriteOomScoreAdj(0, adj) })
+		got, err := ReadOomScoreAdj(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != adj+1 {
+			t.Fatalf("expected %d, got %d", adj+1, got)
+		}
+	}
+	if err := WriteOomScoreAdj(0, 1001); err == nil {
+		t.Fatal("expected error for out of range value, got nil")
+	}
+}
@@ -0,0 +1,119 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+const (
+	peaksFilename  = "peaks.json"
+	peaksTmpPrefix = ".peaks-"
+)
+
+// ResourcePeaks are the high-watermarks of the container resource usage
+// over its lifetime. The peak memory usage is tracked by the kernel, while
+// the pressure peaks are the maximum values observed by
+// [Container.UpdatePeaks].
+type ResourcePeaks struct {
+	// Memory is the peak memory usage, in bytes (memory.peak for
+	// cgroup v2, memory.max_usage_in_bytes for v1).
+	Memory uint64 `json:"memory,omitempty"`
+	// Swap is the peak swap usage, in bytes (cgroup v2 only, since
+	// Linux 6.5).
+	Swap uint64 `json:"swap,omitempty"`
+	// CPUPressure, MemoryPressure and IOPressure are the maximum observed
+	// 10 second averages of the PSI metrics (cgroup v2 only).
+	CPUPressure    *PressurePeak `json:"cpu_pressure,omitempty"`
+	MemoryPressure *PressurePeak `json:"memory_pressure,omitempty"`
+	IOPressure     *PressurePeak `json:"io_pressure,omitempty"`
+	// Updated is the time of the last update.
+	Updated time.Time `json:"updated"`
+}
+
+// PressurePeak is the maximum observed pressure, in percents.
+type PressurePeak struct {
+	Some float64 `json:"some"`
+	Full float64 `json:"full"`
+}
+
+// UpdatePeaks samples the container resource usage, updates the peaks
+// saved in the container state directory, and returns them. If the
+// container cgroup no longer exists, the saved peaks are returned.
+//
+// The more often this is called, the more accurate the pressure peaks are;
+// runc calls it from runc state, runc events (at every interval), and
+// runc delete.
+func (c *Container) UpdatePeaks() (*ResourcePeaks, error) {
+	peaks := &ResourcePeaks{}
+	path := filepath.Join(c.stateDir, peaksFilename)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, peaks); err != nil {
+			logrus.Warnf("ignoring invalid %s: %v", path, err)
+			peaks = &ResourcePeaks{}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	stats, err := c.cgroupManager.GetStats()
+	if err != nil {
+		if err = c.ignoreCgroupError(err); err != nil {
+			return nil, err
+		}
+		return peaks, nil
+	}
+	mergePeaks(peaks, stats)
+	peaks.Updated = time.Now().UTC()
+	if err := writeJSONAtomic(c.stateDir, peaksFilename, peaksTmpPrefix, peaks); err != nil {
+		// Do not fail the caller (which may not be able to write to
+		// the state directory), the peaks are just not persisted.
+		logrus.Debugf("unable to save resource peaks: %v", err)
+	}
+	return peaks, nil
+}
+
+// mergePeaks updates p with the values from stats.
+func mergePeaks(p *ResourcePeaks, stats *cgroups.Stats) {
+	mem := stats.MemoryStats
+	// memory.peak is not available before Linux 5.19, so the current
+	// usage is the best we can do.
+	p.Memory = maxUint64(p.Memory, mem.Usage.MaxUsage, mem.Usage.Usage)
+	p.Swap = maxUint64(p.Swap, mem.SwapOnlyUsage.MaxUsage, mem.SwapOnlyUsage.Usage)
+	p.CPUPressure = mergePressurePeak(p.CPUPressure, stats.CpuStats.PSI)
+	p.MemoryPressure = mergePressurePeak(p.MemoryPressure, mem.PSI)
+	p.IOPressure = mergePressurePeak(p.IOPressure, stats.BlkioStats.PSI)
+}
+
+func mergePressurePeak(p *PressurePeak, psi *cgroups.PSIStats) *PressurePeak {
+	if psi == nil {
+		return p
+	}
+	if p == nil {
+		p = &PressurePeak{}
+	}
+	if psi.Some.Avg10 > p.Some {
+		p.Some = psi.Some.Avg10
+	}
+	if psi.Full.Avg10 > p.Full {
+		p.Full = psi.Full.Avg10
+	}
+	return p
+}
+
+func maxUint64(vals ...uint64) uint64 {
+	var m uint64
+	for _, v := range vals {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,42 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+func TestMergePeaks(t *testing.T) {
+	p := &ResourcePeaks{}
+	stats := &cgroups.Stats{}
+	stats.MemoryStats.Usage = cgroups.MemoryData{Usage: 100, MaxUsage: 200}
+	stats.MemoryStats.PSI = &cgroups.PSIStats{Some: cgroups.PSIData{Avg10: 5}, Full: cgroups.PSIData{Avg10: 1}}
+	stats.CpuStats.PSI = &cgroups.PSIStats{Some: cgroups.PSIData{Avg10: 30}}
+	mergePeaks(p, stats)
+
+	// A later sample with lower values must not lower the peaks.
+	stats.MemoryStats.Usage = cgroups.MemoryData{Usage: 150}
+	stats.MemoryStats.PSI = &cgroups.PSIStats{Some: cgroups.PSIData{Avg10: 2}, Full: cgroups.PSIData{Avg10: 3}}
+	stats.CpuStats.PSI = nil
+	mergePeaks(p, stats)
+
+	if p.Memory != 200 {
+		t.Errorf("expected memory peak 200, got %d", p.Memory)
+	}
+	if p.MemoryPressure == nil || *p.MemoryPressure != (PressurePeak{Some: 5, Full: 3}) {
+		t.Errorf("unexpected memory pressure peak %+v", p.MemoryPressure)
+	}
+	if p.CPUPressure == nil || *p.CPUPressure != (PressurePeak{Some: 30}) {
+		t.Errorf("unexpected cpu pressure peak %+v", p.CPUPressure)
+	}
+	if p.IOPressure != nil {
+		t.Errorf("expected no io pressure peak, got %+v", p.IOPressure)
+	}
+
+	// Without memory.peak, the usage is used.
+	p = &ResourcePeaks{}
+	mergePeaks(p, &cgroups.Stats{MemoryStats: cgroups.MemoryStats{Usage: cgroups.MemoryData{Usage: 42}}})
+	if p.Memory != 42 {
+		t.Errorf("expected memory peak 42, got %d", p.Memory)
+	}
+}
@@ -0,0 +1,46 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+// signalPid sends s to the process pid, provided it is still the one which
+// started at startTime (as in /proc/<pid>/stat). A process file descriptor
+// is used if the kernel supports it (Linux 5.3 or later), so that the signal
+// can never be delivered to an unrelated process which reused the PID once
+// the expected one has exited. Otherwise, kill(2) is used, which leaves a
+// small window between the check and the signal.
+func signalPid(pid int, startTime uint64, s unix.Signal) error {
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return ErrNotRunning
+		}
+		if !errors.Is(err, unix.ENOSYS) {
+			return os.NewSyscallError("pidfd_open", err)
+		}
+		pidfd = -1
+	} else {
+		defer unix.Close(pidfd)
+	}
+	// With a pidfd, the process can not be replaced by another one
+	// between this check and the signal.
+	stat, err := system.Stat(pid)
+	if err != nil || stat.StartTime != startTime {
+		return ErrNotRunning
+	}
+	if pidfd == -1 {
+		err = unix.Kill(pid, s)
+	} else {
+		err = unix.PidfdSendSignal(pidfd, s, nil, 0)
+	}
+	if errors.Is(err, unix.ESRCH) {
+		return ErrNotRunning
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+package libcontainer
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+func TestSignalPid(t *testing.T) {
+	cmd := exec.Command("sleep", "100")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	pid := cmd.Process.Pid
+	stat, err := system.Stat(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := signalPid(pid, stat.StartTime+1, unix.SIGKILL); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("expected ErrNotRunning for a mismatching start time, got %v", err)
+	}
+	if err := signalPid(pid, stat.StartTime, unix.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	_ = cmd.Wait()
+	if ws := cmd.ProcessState.Sys().(syscall.WaitStatus); ws.Signal() != unix.SIGKILL {
+		t.Fatalf("expected the process to be killed, got %v", cmd.ProcessState)
+	}
+	if err := signalPid(pid, stat.StartTime, unix.SIGKILL); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("expected ErrNotRunning for an exited process, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package libcontainer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups/fscommon"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+// pidsStartResources returns the resources to set for the container cgroup
+// when the container is created. If config.PidsStartLimit is set, this is a
+// copy of r with the pids limit lowered to the start limit. Otherwise, r is
+// returned.
+func pidsStartResources(config *configs.Config, r *configs.Resources) *configs.Resources {
+	l := config.PidsStartLimit
+	if l == nil || r == nil || (r.PidsLimit > 0 && r.PidsLimit <= l.Limit) {
+		return r
+	}
+	lowered := *r
+	lowered.PidsLimit = l.Limit
+	return &lowered
+}
+
+// pidsMaxEvents returns the number of times the pids limit of the container
+// cgroup was hit.
+func (c *Container) pidsMaxEvents() (uint64, error) {
+	path := c.cgroupManager.Path("pids")
+	if path == "" {
+		return 0, nil
+	}
+	return fscommon.GetValueByKey(path, "pids.events", "max")
+}
+
+// settlePids waits for the container process to reach a steady state, and
+// raises the pids limit from the start one (see [configs.PidsStartLimit]).
+// If the start limit is hit meanwhile, the limit is not raised, and
+// ErrPidsStartLimit is returned.
+func (c *Container) settlePids() error {
+	l := c.config.PidsStartLimit
+	if l == nil {
+		return nil
+	}
+	start, err := c.pidsMaxEvents()
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(l.Settle)
+	for {
+		events, err := c.pidsMaxEvents()
+		if err != nil {
+			return err
+		}
+		if events > start {
+			return fmt.Errorf("%w: %d within %s of the container start", ErrPidsStartLimit, l.Limit, l.Settle)
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		if wait > 100*time.Millisecond {
+			wait = 100 * time.Millisecond
+		}
+		time.Sleep(wait)
+	}
+
+	// Nothing to do if the container is already gone.
+	stat, err := system.Stat(c.initProcess.pid())
+	if err != nil || stat.State == system.Zombie {
+		return nil
+	}
+	resources, err := cpusetResources(c.config, c.config.Cgroups.Resources)
+	if err != nil {
+		return err
+	}
+	raised := *resources
+	if raised.PidsLimit == 0 {
+		// Zero means "not set", so the limit has to be removed explicitly.
+		raised.PidsLimit = -1
+	}
+	if err := c.cgroupManager.Set(&raised); err != nil {
+		return fmt.Errorf("unable to raise the pids start limit: %w", err)
+	}
+	logrus.Debugf("pids limit raised from the start limit %d", l.Limit)
+	return nil
+}
@@ -0,0 +1,33 @@
+package libcontainer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestPidsStartResources(t *testing.T) {
+	start := &configs.PidsStartLimit{Limit: 32, Settle: time.Second}
+	testCases := []struct {
+		start    *configs.PidsStartLimit
+		limit    int64
+		expected int64
+	}{
+		{start: nil, limit: 1024, expected: 1024},
+		{start: start, limit: 0, expected: 32},
+		{start: start, limit: -1, expected: 32},
+		{start: start, limit: 1024, expected: 32},
+		{start: start, limit: 16, expected: 16},
+	}
+	for _, tc := range testCases {
+		r := &configs.Resources{PidsLimit: tc.limit}
+		got := pidsStartResources(&configs.Config{PidsStartLimit: tc.start}, r)
+		if got.PidsLimit != tc.expected {
+			t.Errorf("start %+v, limit %d: expected %d, got %d", tc.start, tc.limit, tc.expected, got.PidsLimit)
+		}
+		if r.PidsLimit != tc.limit {
+			t.Errorf("start %+v, limit %d: original resources modified", tc.start, tc.limit)
+		}
+	}
+}
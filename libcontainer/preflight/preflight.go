@@ -0,0 +1,80 @@
+// Package preflight checks the kernel settings a container configuration
+// relies upon, and provides the remediation hints for the failed checks.
+package preflight
+
+import (
+	"errors"
+	"strings"
+)
+
+// Result is the outcome of a single preflight check.
+type Result struct {
+	// Name is the setting which is checked, such as a sysctl name
+	// (user.max_user_namespaces) or a cgroup controller (cgroup.memory).
+	Name string `json:"name"`
+	// OK is whether the setting allows the container to be run.
+	OK bool `json:"ok"`
+	// Value is the current value of the setting, if known.
+	Value string `json:"value,omitempty"`
+	// Message describes why the check failed.
+	Message string `json:"message,omitempty"`
+	// Remediation is how to fix the failed check.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// Failed returns the failed checks from results.
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if !r.OK {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Error is an error annotated with the failed preflight checks, which are
+// the likely causes of it.
+type Error struct {
+	Err    error
+	Failed []Result
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	for _, r := range e.Failed {
+		b.WriteString("\n  ")
+		b.WriteString(r.Name)
+		if r.Value != "" {
+			b.WriteString(" = " + r.Value)
+		}
+		b.WriteString(": " + r.Message)
+		if r.Remediation != "" {
+			b.WriteString("; to fix: " + r.Remediation)
+		}
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Annotate returns err annotated with the failed checks from results, or
+// err itself if there are no failed checks (or err is nil or is already
+// annotated).
+func Annotate(err error, results []Result) error {
+	if err == nil {
+		return nil
+	}
+	var pe *Error
+	if errors.As(err, &pe) {
+		return err
+	}
+	failed := Failed(results)
+	if len(failed) == 0 {
+		return err
+	}
+	return &Error{Err: err, Failed: failed}
+}
@@ -0,0 +1,199 @@
+package preflight
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/apparmor"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// These can be changed by unit tests.
+var (
+	procSys         = "/proc/sys"
+	procCgroups     = "/proc/cgroups"
+	procSelfStatus  = "/proc/self/status"
+	cgroupRoot      = "/sys/fs/cgroup"
+	isCgroup2       = cgroups.IsCgroup2UnifiedMode
+	apparmorEnabled = apparmor.IsEnabled
+)
+
+// Run runs the checks relevant to config, and returns their results. The
+// settings which do not exist on this kernel are not checked.
+func Run(config *configs.Config) []Result {
+	var results []Result
+	add := func(r *Result) {
+		if r != nil {
+			results = append(results, *r)
+		}
+	}
+
+	if config.Namespaces.Contains(configs.NEWUSER) && config.Namespaces.PathOf(configs.NEWUSER) == "" {
+		add(checkSysctl("user.max_user_namespaces", func(v string) bool { return v != "0" },
+			"user namespaces are disabled",
+			"sysctl -w user.max_user_namespaces=15000, and persist it in /etc/sysctl.d"))
+		if config.RootlessEUID {
+			// Debian and older Ubuntu kernels.
+			add(checkSysctl("kernel.unprivileged_userns_clone", func(v string) bool { return v != "0" },
+				"unprivileged user namespaces are disabled",
+				"sysctl -w kernel.unprivileged_userns_clone=1, and persist it in /etc/sysctl.d"))
+			// Ubuntu 23.10 and later.
+			add(checkSysctl("kernel.apparmor_restrict_unprivileged_userns", func(v string) bool { return v != "1" },
+				"unprivileged user namespaces have no capabilities unless allowed by the AppArmor profile",
+				"load an AppArmor profile allowing userns for runc, or sysctl -w kernel.apparmor_restrict_unprivileged_userns=0"))
+		}
+	}
+	// RHEL 7 kernels.
+	add(checkSysctl("fs.may_detach_mounts", func(v string) bool { return v != "0" },
+		"mounts leaking into other mount namespaces may make the container removal fail with EBUSY",
+		"sysctl -w fs.may_detach_mounts=1, and persist it in /etc/sysctl.d"))
+
+	if config.Cgroups != nil && config.Cgroups.Resources != nil {
+		results = append(results, checkControllers(config.Cgroups.Resources)...)
+	}
+	if config.Seccomp != nil {
+		add(checkSeccomp())
+	}
+	if config.AppArmorProfile != "" {
+		add(checkAppArmor())
+	}
+	return results
+}
+
+// checkSysctl checks the value of the sysctl with ok, returning nil if the
+// sysctl does not exist.
+func checkSysctl(name string, ok func(string) bool, message, remediation string) *Result {
+	data, err := os.ReadFile(filepath.Join(procSys, strings.ReplaceAll(name, ".", "/")))
+	if err != nil {
+		return nil
+	}
+	r := &Result{Name: name, Value: strings.TrimSpace(string(data))}
+	if r.OK = ok(r.Value); !r.OK {
+		r.Message = message
+		r.Remediation = remediation
+	}
+	return r
+}
+
+// requiredControllers returns the cgroup controllers needed to apply r.
+func requiredControllers(r *configs.Resources, v2 bool) []string {
+	need := make(map[string]struct{})
+	if r.Memory != 0 || r.MemoryReservation != 0 || r.MemorySwap != 0 || r.SwapMax != nil || r.MemorySwappiness != nil || r.OomKillDisable {
+		need["memory"] = struct{}{}
+	}
+	if r.CpuShares != 0 || r.CpuWeight != 0 || r.CpuQuota != 0 || r.CpuPeriod != 0 || r.CpuBurst != nil || r.CPUIdle != nil || r.CpuRtRuntime != 0 || r.CpuRtPeriod != 0 {
+		need["cpu"] = struct{}{}
+	}
+	if r.CpusetCpus != "" || r.CpusetMems != "" {
+		need["cpuset"] = struct{}{}
+	}
+	if r.PidsLimit != 0 {
+		need["pids"] = struct{}{}
+	}
+	if r.BlkioWeight != 0 || r.BlkioLeafWeight != 0 || len(r.BlkioWeightDevice) > 0 ||
+		len(r.BlkioThrottleReadBpsDevice) > 0 || len(r.BlkioThrottleWriteBpsDevice) > 0 ||
+		len(r.BlkioThrottleReadIOPSDevice) > 0 || len(r.BlkioThrottleWriteIOPSDevice) > 0 {
+		if v2 {
+			need["io"] = struct{}{}
+		} else {
+			need["blkio"] = struct{}{}
+		}
+	}
+	if len(r.HugetlbLimit) > 0 {
+		need["hugetlb"] = struct{}{}
+	}
+	if len(r.Rdma) > 0 {
+		need["rdma"] = struct{}{}
+	}
+	names := make([]string, 0, len(need))
+	for n := range need {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkControllers checks that the cgroup controllers needed to apply r
+// are available.
+func checkControllers(r *configs.Resources) []Result {
+	v2 := isCgroup2()
+	need := requiredControllers(r, v2)
+	if len(need) == 0 {
+		return nil
+	}
+	var (
+		avail       map[string]bool
+		remediation string
+	)
+	if v2 {
+		data, err := os.ReadFile(filepath.Join(cgroupRoot, "cgroup.controllers"))
+		if err != nil {
+			return nil
+		}
+		avail = make(map[string]bool)
+		for _, c := range strings.Fields(string(data)) {
+			avail[c] = true
+		}
+		remediation = "make sure the controller is enabled in the kernel (some distributions need the cgroup_enable=<controller> boot parameter) and delegated to the container cgroup parent (cgroup.subtree_control; Delegate= for systemd units)"
+	} else {
+		data, err := os.ReadFile(procCgroups)
+		if err != nil {
+			return nil
+		}
+		avail = parseProcCgroups(data)
+		remediation = "enable the controller in the kernel, or boot with the cgroup_enable=<controller> parameter"
+	}
+	results := make([]Result, 0, len(need))
+	for _, c := range need {
+		res := Result{Name: "cgroup." + c, OK: avail[c]}
+		if !res.OK {
+			res.Message = "the " + c + " controller, needed for the configured resource limits, is not available"
+			res.Remediation = remediation
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// parseProcCgroups returns the enabled cgroup v1 controllers from the
+// contents of /proc/cgroups.
+func parseProcCgroups(data []byte) map[string]bool {
+	avail := make(map[string]bool)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		// #subsys_name hierarchy num_cgroups enabled
+		f := strings.Fields(s.Text())
+		if len(f) != 4 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		avail[f[0]] = f[3] == "1"
+	}
+	return avail
+}
+
+func checkSeccomp() *Result {
+	data, err := os.ReadFile(procSelfStatus)
+	if err != nil {
+		return nil
+	}
+	r := &Result{Name: "seccomp", OK: bytes.Contains(data, []byte("\nSeccomp:"))}
+	if !r.OK {
+		r.Message = "the kernel is built without seccomp support, which the configuration requires"
+		r.Remediation = "use a kernel built with CONFIG_SECCOMP and CONFIG_SECCOMP_FILTER, or remove the seccomp profile"
+	}
+	return r
+}
+
+func checkAppArmor() *Result {
+	r := &Result{Name: "apparmor", OK: apparmorEnabled()}
+	if !r.OK {
+		r.Message = "AppArmor is not enabled, but the configuration sets an AppArmor profile"
+		r.Remediation = "enable AppArmor (apparmor=1 security=apparmor boot parameters), or remove the AppArmor profile"
+	}
+	return r
+}
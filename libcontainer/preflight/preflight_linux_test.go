@@ -0,0 +1,127 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func setupTest(t *testing.T, v2 bool, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	oldSys, oldCgroups, oldStatus, oldRoot, oldV2, oldAA := procSys, procCgroups, procSelfStatus, cgroupRoot, isCgroup2, apparmorEnabled
+	t.Cleanup(func() {
+		procSys, procCgroups, procSelfStatus, cgroupRoot, isCgroup2, apparmorEnabled = oldSys, oldCgroups, oldStatus, oldRoot, oldV2, oldAA
+	})
+	procSys = filepath.Join(dir, "sys")
+	procCgroups = filepath.Join(dir, "cgroups")
+	procSelfStatus = filepath.Join(dir, "status")
+	cgroupRoot = filepath.Join(dir, "cgroup")
+	isCgroup2 = func() bool { return v2 }
+	apparmorEnabled = func() bool { return false }
+}
+
+func failedNames(results []Result) []string {
+	var names []string
+	for _, r := range Failed(results) {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestRunUserns(t *testing.T) {
+	setupTest(t, true, map[string]string{
+		"sys/user/max_user_namespaces":                     "0\n",
+		"sys/kernel/unprivileged_userns_clone":             "0\n",
+		"sys/kernel/apparmor_restrict_unprivileged_userns": "0\n",
+		"sys/fs/may_detach_mounts":                         "1\n",
+		"cgroup/cgroup.controllers":                        "cpu memory pids\n",
+		"status":                                           "Name:\tfoo\nSeccomp:\t0\n",
+	})
+	config := &configs.Config{
+		Namespaces:   configs.Namespaces{{Type: configs.NEWUSER}},
+		RootlessEUID: true,
+	}
+	results := Run(config)
+	got := strings.Join(failedNames(results), ",")
+	if got != "user.max_user_namespaces,kernel.unprivileged_userns_clone" {
+		t.Fatalf("unexpected failed checks: %s (all: %+v)", got, results)
+	}
+	for _, r := range Failed(results) {
+		if r.Value != "0" || r.Message == "" || r.Remediation == "" {
+			t.Errorf("incomplete result: %+v", r)
+		}
+	}
+
+	// Joining an existing userns needs no new namespaces.
+	config.Namespaces = configs.Namespaces{{Type: configs.NEWUSER, Path: "/proc/1/ns/user"}}
+	if failed := failedNames(Run(config)); len(failed) != 0 {
+		t.Fatalf("unexpected failed checks: %v", failed)
+	}
+}
+
+func TestRunControllersV2(t *testing.T) {
+	setupTest(t, true, map[string]string{
+		"cgroup/cgroup.controllers": "cpu memory pids\n",
+	})
+	config := &configs.Config{
+		Cgroups: &configs.Cgroup{Resources: &configs.Resources{
+			Memory:      1 << 20,
+			PidsLimit:   10,
+			CpusetCpus:  "0",
+			BlkioWeight: 100,
+		}},
+	}
+	got := strings.Join(failedNames(Run(config)), ",")
+	if got != "cgroup.cpuset,cgroup.io" {
+		t.Fatalf("unexpected failed checks: %s", got)
+	}
+}
+
+func TestRunControllersV1(t *testing.T) {
+	setupTest(t, false, map[string]string{
+		"cgroups": "#subsys_name\thierarchy\tnum_cgroups\tenabled\ncpu\t2\t1\t1\nmemory\t0\t1\t0\nblkio\t3\t1\t1\n",
+	})
+	config := &configs.Config{
+		Cgroups: &configs.Cgroup{Resources: &configs.Resources{
+			Memory:      1 << 20,
+			CpuShares:   512,
+			BlkioWeight: 100,
+		}},
+	}
+	got := strings.Join(failedNames(Run(config)), ",")
+	if got != "cgroup.memory" {
+		t.Fatalf("unexpected failed checks: %s", got)
+	}
+}
+
+func TestRunSeccompAppArmor(t *testing.T) {
+	setupTest(t, true, map[string]string{
+		"status": "Name:\tfoo\nNoNewPrivs:\t0\n",
+	})
+	config := &configs.Config{
+		Seccomp:         &configs.Seccomp{},
+		AppArmorProfile: "foo",
+	}
+	got := strings.Join(failedNames(Run(config)), ",")
+	if got != "seccomp,apparmor" {
+		t.Fatalf("unexpected failed checks: %s", got)
+	}
+}
@@ -0,0 +1,28 @@
+package preflight
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	if Annotate(nil, []Result{{Name: "foo"}}) != nil {
+		t.Fatal("expected nil")
+	}
+	base := errors.New("base error")
+	if err := Annotate(base, []Result{{Name: "foo", OK: true}}); err != base {
+		t.Fatalf("expected the error unchanged, got %v", err)
+	}
+	err := Annotate(base, []Result{{Name: "user.max_user_namespaces", Value: "0", Message: "disabled", Remediation: "enable it"}})
+	if !errors.Is(err, base) {
+		t.Fatal("expected the error to wrap the base error")
+	}
+	expected := "base error\n  user.max_user_namespaces = 0: disabled; to fix: enable it"
+	if err.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, err.Error())
+	}
+	// Annotating twice has no effect.
+	if err2 := Annotate(err, []Result{{Name: "bar"}}); err2 != err {
+		t.Fatalf("expected the error unchanged, got %v", err2)
+	}
+}
@@ -49,9 +49,17 @@ type Process struct {
 	// ExtraFiles specifies additional open files to be inherited by the container
 	ExtraFiles []*os.File
 
+	// Secrets are injected into the process, without being written to the
+	// container filesystem (see Secret).
+	Secrets []*Secret
+
 	// open handles to cloned binaries -- see dmz.ClonedBinary for more details
 	clonedExes []*os.File
 
+	// maskPaths, if set, are the paths for the process to mask, instead of
+	// executing Args (see Container.MaskPaths).
+	maskPaths []string
+
 	// Initial sizings for the console
 	ConsoleWidth  uint16
 	ConsoleHeight uint16
@@ -72,11 +80,16 @@ type Process struct {
 
 	// Rlimits specifies the resource limits, such as max open files, to set in the container
 	// If Rlimits are not set, the container will inherit rlimits from the parent process
+	// The container limits of the same types take precedence over these.
 	Rlimits []configs.Rlimit
 
 	// ConsoleSocket provides the masterfd console.
 	ConsoleSocket *os.File
 
+	// ConsoleSocketVersion is the version of the protocol used over
+	// ConsoleSocket (see ConsoleSocketVersion). 0 means ConsoleSocketV1.
+	ConsoleSocketVersion int
+
 	// PidfdSocket provides process file descriptor of it own.
 	PidfdSocket *os.File
 
@@ -99,9 +112,56 @@ type Process struct {
 	// For cgroup v2, the only key allowed is "".
 	SubCgroupPaths map[string]string
 
+	// SubCgroupCreate creates the SubCgroupPaths sub-cgroups which do not
+	// exist. See [Container.DestroySubCgroups] to remove them.
+	SubCgroupCreate bool
+
+	// SubCgroupSettings are the cgroup files (such as "memory.max") to
+	// write in the SubCgroupPaths sub-cgroups, with their values.
+	SubCgroupSettings map[string]string
+
+	// CgroupPaths, if set, are the cgroups to run the process in instead
+	// of the container ones, such as those of another container (see
+	// [Container.CgroupPaths]), for the process resources to be accounted
+	// against (and limited by) them. It can not be used with SubCgroupPaths,
+	// nor for the init process.
+	CgroupPaths map[string]string
+
 	Scheduler *configs.Scheduler
 }
 
+// SecretType is the way a secret is injected into a process.
+type SecretType string
+
+const (
+	// SecretKeyring adds the secret as a "user" key, named after the
+	// secret, to the session keyring of the container (see keyrings(7)).
+	// This requires a new session keyring (i.e. configs.Config.NoNewKeyring
+	// must not be set).
+	SecretKeyring SecretType = "keyring"
+	// SecretMemfd writes the secret to a sealed anonymous file (see
+	// memfd_create(2)), and sets the Env variable of the process to its
+	// path (/proc/self/fd/N).
+	SecretMemfd SecretType = "memfd"
+)
+
+// Secret is a secret to inject into a process. It is read from File by
+// runc init, so it is never written to the container filesystem, nor even
+// read by the runc process.
+type Secret struct {
+	Name string     `json:"name"`
+	Type SecretType `json:"type"`
+	// Env is the environment variable to set to the secret path, for
+	// SecretMemfd.
+	Env string `json:"env,omitempty"`
+	// File is where the secret is read from. It is not closed by
+	// libcontainer.
+	File *os.File `json:"-"`
+
+	// memfd is the memfd secret fd, in runc init.
+	memfd int
+}
+
 // Wait waits for the process to exit.
 // Wait releases any resources associated with the Process
 func (p Process) Wait() (*os.ProcessState, error) {
@@ -10,14 +10,20 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/opencontainers/runc/libcontainer/binfmt"
+	"github.com/opencontainers/runc/libcontainer/bpflsm"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
+	"github.com/opencontainers/runc/libcontainer/cni"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
 	"github.com/opencontainers/runc/libcontainer/logs"
+	"github.com/opencontainers/runc/libcontainer/quota"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -118,11 +124,55 @@ func (p *setnsProcess) signal(sig os.Signal) error {
 	return unix.Kill(p.pid(), s)
 }
 
+// startCmd starts cmd, and returns the started command. If cmd is to be
+// started right in a cgroup (UseCgroupFD), and this fails because
+// CLONE_INTO_CGROUP is not supported (before Linux 5.7), it is started
+// without it, in which case the caller is to move the process into the
+// cgroup. As an exec.Cmd can only be started once, a copy of cmd is
+// started then.
+func startCmd(cmd *exec.Cmd) (*exec.Cmd, error) {
+	err := cmd.Start()
+	if err == nil || cmd.SysProcAttr == nil || !cmd.SysProcAttr.UseCgroupFD {
+		return cmd, err
+	}
+	logrus.Debugf("starting %s with CLONE_INTO_CGROUP failed: %v; retrying without", cmd.Path, err)
+	attr := *cmd.SysProcAttr
+	attr.UseCgroupFD = false
+	attr.CgroupFD = 0
+	retry := &exec.Cmd{
+		Path:        cmd.Path,
+		Args:        cmd.Args,
+		Env:         cmd.Env,
+		Dir:         cmd.Dir,
+		Stdin:       cmd.Stdin,
+		Stdout:      cmd.Stdout,
+		Stderr:      cmd.Stderr,
+		ExtraFiles:  cmd.ExtraFiles,
+		SysProcAttr: &attr,
+	}
+	return retry, retry.Start()
+}
+
 func (p *setnsProcess) start() (retErr error) {
 	defer p.comm.closeParent()
 	// get the "before" value of oom kill count
 	oom, _ := p.manager.OOMKillCount()
-	err := p.cmd.Start()
+	hk, err := newHousekeeping(p.config.Config, p.config.ContainerID)
+	if err != nil {
+		return err
+	}
+	defer hk.remove()
+	if hk.enabled() {
+		// Do the setns part in the housekeeping cgroup rather than in
+		// the cgroup of the runc caller. The process is moved to the
+		// container cgroup below, before it runs the Go part of runc
+		// init.
+		p.cmd.SysProcAttr.UseCgroupFD = true
+		p.cmd.SysProcAttr.CgroupFD = int(hk.dir.Fd())
+	}
+	// If CLONE_INTO_CGROUP is not supported, the process is moved into
+	// the housekeeping cgroup by housekeeping.join below.
+	p.cmd, err = startCmd(p.cmd)
 	// close the child-side of the pipes (controlled by child)
 	p.comm.closeChild()
 	if err != nil {
@@ -147,6 +197,11 @@ func (p *setnsProcess) start() (retErr error) {
 		}
 	}()
 
+	if hk.enabled() && !p.cmd.SysProcAttr.UseCgroupFD {
+		if err := hk.join(p.pid()); err != nil {
+			return fmt.Errorf("unable to join housekeeping cgroup: %w", err)
+		}
+	}
 	if p.bootstrapData != nil {
 		if _, err := io.Copy(p.comm.initSockParent, p.bootstrapData); err != nil {
 			return fmt.Errorf("error copying bootstrap data to pipe: %w", err)
@@ -159,6 +214,11 @@ func (p *setnsProcess) start() (retErr error) {
 	if err := p.execSetns(); err != nil {
 		return fmt.Errorf("error executing setns process: %w", err)
 	}
+	if p.process.SubCgroupCreate || len(p.process.SubCgroupSettings) > 0 {
+		if err := setupSubCgroups(p.cgroupPaths, p.process.SubCgroupCreate, p.process.SubCgroupSettings); err != nil {
+			return err
+		}
+	}
 	for _, path := range p.cgroupPaths {
 		if err := cgroups.WriteCgroupProc(path, p.pid()); err != nil && !p.rootlessCgroups {
 			// On cgroup v2 + nesting + domain controllers, WriteCgroupProc may fail with EBUSY.
@@ -182,6 +242,9 @@ func (p *setnsProcess) start() (retErr error) {
 			}
 		}
 	}
+	if cost, err := hk.cost(); err == nil {
+		logrus.Debugf("exec setup cost: %+v", *cost)
+	}
 	if p.intelRdtPath != "" {
 		// if Intel RDT "resource control" filesystem path exists
 		_, err := os.Stat(p.intelRdtPath)
@@ -403,8 +466,26 @@ func (p *initProcess) waitForChildExit(childPid int) error {
 
 func (p *initProcess) start() (retErr error) {
 	defer p.comm.closeParent()
+	if b := p.config.Config.Binfmt; b != nil && !b.Private {
+		if err := binfmt.CheckRequired(binfmt.Dir, b.Require, p.config.Config.Rootfs); err != nil {
+			return err
+		}
+	}
+	hk, err := newHousekeeping(p.config.Config, p.container.id)
+	if err != nil {
+		return err
+	}
+	defer hk.remove()
+	if hk.enabled() {
+		// Start runc init in the housekeeping cgroup rather than in
+		// the container cgroup.
+		p.cmd.SysProcAttr.UseCgroupFD = true
+		p.cmd.SysProcAttr.CgroupFD = int(hk.dir.Fd())
+	}
 	/*启动命令*/
-	err := p.cmd.Start()
+	// If CLONE_INTO_CGROUP is not supported, the process is moved into
+	// the cgroup by manager.Apply (or housekeeping.join) below.
+	p.cmd, err = startCmd(p.cmd)
 	p.process.ops = p
 	// close the child-side of the pipes (controlled by child)
 	p.comm.closeChild()
@@ -450,20 +531,67 @@ func (p *initProcess) start() (retErr error) {
 			if p.intelRdtManager != nil {
 				_ = p.intelRdtManager.Destroy()
 			}
+			// Undo whatever is left.
+			if err := p.container.journal.rollback(); err != nil {
+				logrus.WithError(err).Warn("unable to roll back container creation")
+			}
 		}
 	}()
 
+	j := p.container.journal
+	// An adopted cgroup is not created by us, and is not to be removed (or
+	// have its processes killed) if the creation fails.
+	if !p.config.Config.Cgroups.Adopted {
+		if err := j.record(journalEntry{Type: journalCgroup, Cgroup: p.config.Config.Cgroups}); err != nil {
+			return fmt.Errorf("unable to record cgroup creation: %w", err)
+		}
+	}
 	// Do this before syncing with child so that no children can escape the
 	// cgroup. We don't need to worry about not doing this and not being root
 	// because we'd be using the rootless cgroup manager in that case.
-	if err := p.manager.Apply(p.pid()); err != nil {
-		return fmt.Errorf("unable to apply cgroup configuration: %w", err)
+	//
+	// With a housekeeping cgroup, runc init (along with its children) is
+	// only moved to the container cgroup once the setup is done (see
+	// procReady below), so the cgroup is only created here.
+	if hk.enabled() {
+		if !p.cmd.SysProcAttr.UseCgroupFD {
+			if err := hk.join(p.pid()); err != nil {
+				return fmt.Errorf("unable to join housekeeping cgroup: %w", err)
+			}
+		}
+		if err := p.manager.Apply(-1); err != nil {
+			return classify(fmt.Errorf("unable to apply cgroup configuration: %w", err), ErrCgroupApply)
+		}
+	} else if err := p.manager.Apply(p.pid()); err != nil {
+		return classify(fmt.Errorf("unable to apply cgroup configuration: %w", err), ErrCgroupApply)
 	}
 	if p.intelRdtManager != nil {
+		if rdt := p.config.Config.IntelRdt; rdt != nil && rdt.ClosID == "" {
+			if err := j.record(journalEntry{Type: journalIntelRdt, Path: p.intelRdtManager.GetPath()}); err != nil {
+				return fmt.Errorf("unable to record Intel RDT group creation: %w", err)
+			}
+		}
 		if err := p.intelRdtManager.Apply(p.pid()); err != nil {
 			return fmt.Errorf("unable to apply Intel RDT configuration: %w", err)
 		}
 	}
+	if q := p.config.Config.DiskQuota; q != nil {
+		if err := j.record(journalEntry{Type: journalDiskQuota, DiskQuota: q}); err != nil {
+			return fmt.Errorf("unable to record disk quota: %w", err)
+		}
+		if err := quota.Set(q); err != nil {
+			return fmt.Errorf("unable to set disk quota: %w", err)
+		}
+	}
+	if b := p.config.Config.BPFLSM; b != nil {
+		path := p.manager.Path("")
+		if err := j.record(journalEntry{Type: journalBPFLSM, Path: path, BPFLSM: b}); err != nil {
+			return fmt.Errorf("unable to record bpf lsm programs: %w", err)
+		}
+		if err := bpflsm.Attach(path, b.Programs); err != nil {
+			return err
+		}
+	}
 	if _, err := io.Copy(p.comm.initSockParent, p.bootstrapData); err != nil {
 		return fmt.Errorf("can't copy bootstrap data to pipe: %w", err)
 	}
@@ -549,6 +677,24 @@ func (p *initProcess) start() (retErr error) {
 			}
 		case procReady:
 			seenProcReady = true
+			cost, err := hk.leave(p.manager.Path(""))
+			if err != nil {
+				return fmt.Errorf("unable to leave housekeeping cgroup: %w", err)
+			}
+			p.container.setupCost = cost
+			logrus.Debugf("container setup cost: %+v", *cost)
+			if adj, err := ReadOomScoreAdj(p.pid()); err != nil {
+				logrus.Debugf("unable to read runc init oom_score_adj: %v", err)
+			} else {
+				p.container.helperOomScoreAdj = &adj
+			}
+			// Like rlimits, this has to be done here as runc init lacks
+			// the permissions to lower oom_score_adj (if needed) itself.
+			if deferOomScoreAdj(p.config.Config, initStandard) {
+				if err := WriteOomScoreAdj(p.pid(), *p.config.Config.OomScoreAdj); err != nil {
+					return fmt.Errorf("error setting oom_score_adj for ready process: %w", err)
+				}
+			}
 			// set rlimits, this has to be done here because we lose permissions
 			// to raise the limits once we enter a user-namespace
 			if err := setupRlimits(p.config.Rlimits, p.pid()); err != nil {
@@ -582,14 +728,27 @@ func (p *initProcess) start() (retErr error) {
 			}
 		case procHooks:
 			// Setup cgroup before prestart hook, so that the prestart hook could apply cgroup permissions.
-			if err := p.manager.Set(p.config.Config.Cgroups.Resources); err != nil {
+			resources, err := cpusetResources(p.config.Config, p.config.Config.Cgroups.Resources)
+			if err != nil {
 				return fmt.Errorf("error setting cgroup config for procHooks process: %w", err)
 			}
+			// The resources of an adopted cgroup are set by its owner.
+			if !p.config.Config.Cgroups.Adopted {
+				if err := p.manager.Set(pidsStartResources(p.config.Config, resources)); err != nil {
+					return fmt.Errorf("error setting cgroup config for procHooks process: %w", err)
+				}
+			}
 			if p.intelRdtManager != nil {
 				if err := p.intelRdtManager.Set(p.config.Config); err != nil {
 					return fmt.Errorf("error setting Intel RDT config for procHooks process: %w", err)
 				}
 			}
+			if p.config.Config.CNI != nil {
+				if err := p.setupCNI(); err != nil {
+					return fmt.Errorf("error setting up CNI network: %w", err)
+				}
+			}
+			var res *configs.HookResult
 			if len(p.config.Config.Hooks) != 0 {
 				s, err := p.container.currentOCIState()
 				if err != nil {
@@ -603,12 +762,23 @@ func (p *initProcess) start() (retErr error) {
 				if err := hooks.Run(configs.Prestart, s); err != nil {
 					return err
 				}
-				if err := hooks.Run(configs.CreateRuntime, s); err != nil {
+				res, err = hooks.RunWithResult(configs.CreateRuntime, s)
+				if err != nil {
 					return err
 				}
 			}
 			// Sync with child.
-			if err := writeSync(p.comm.syncSockParent, procHooksDone); err != nil {
+			if res == nil {
+				if err := writeSync(p.comm.syncSockParent, procHooksDone); err != nil {
+					return err
+				}
+				return nil
+			}
+			// The annotations are saved in the container state, while the
+			// mounts and the environment are applied by the child.
+			p.config.Config.Labels = addAnnotationLabels(p.config.Config.Labels, res.Annotations)
+			res.Annotations = nil
+			if err := writeSyncArg(p.comm.syncSockParent, procHooksDone, res); err != nil {
 				return err
 			}
 		default:
@@ -626,7 +796,9 @@ func (p *initProcess) start() (retErr error) {
 	if ierr != nil {
 		return fmt.Errorf("error during container init: %w", ierr)
 	}
-	return nil
+	// The container state is saved by now, so the rest of the cleanup
+	// is up to the container's destroy.
+	return j.commit()
 }
 
 func (p *initProcess) wait() (*os.ProcessState, error) {
@@ -662,6 +834,15 @@ func (p *initProcess) updateSpecState() error {
 
 /*发送配置给子进程*/
 func (p *initProcess) createNetworkInterfaces() error {
+	nsPath := filepath.Join(p.container.stateDir, netnsPinFilename)
+	if needsNetnsPin(p.config.Config) {
+		if err := p.container.journal.record(journalEntry{Type: journalNetnsPin, Path: nsPath}); err != nil {
+			return err
+		}
+		if err := pinNetns(p.pid(), nsPath); err != nil {
+			return fmt.Errorf("unable to pin network namespace: %w", err)
+		}
+	}
 	for _, config := range p.config.Config.Networks {
 		strategy, err := getStrategy(config.Type)
 		if err != nil {
@@ -670,6 +851,14 @@ func (p *initProcess) createNetworkInterfaces() error {
 		n := &network{
 			Network: *config,
 		}
+		if n.Type == "macvlan" || n.Type == "ipvlan" {
+			if err := checkParentConflicts(filepath.Dir(p.container.stateDir), p.container.ID(), config); err != nil {
+				return err
+			}
+		}
+		if err := p.container.journal.record(journalEntry{Type: journalNetwork, Network: config, Path: nsPath}); err != nil {
+			return err
+		}
 		if err := strategy.create(n, p.pid()); err != nil {
 			return err
 		}
@@ -821,3 +1010,48 @@ func initWaiter(r io.Reader) chan error {
 
 	return ch
 }
+
+// addAnnotationLabels adds (or replaces) the given annotations to the
+// container labels, in which annotations are stored in the key=value form.
+func addAnnotationLabels(labels []string, annotations map[string]string) []string {
+	if len(annotations) == 0 {
+		return labels
+	}
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		label := k + "=" + annotations[k]
+		replaced := false
+		for i, l := range labels {
+			if strings.HasPrefix(l, k+"=") {
+				labels[i] = label
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// setupCNI adds the container to its CNI network, saving the result to be
+// stored in the container state.
+func (p *initProcess) setupCNI() error {
+	conf := p.config.Config.CNI
+	id := p.container.ID()
+	if err := p.container.journal.record(journalEntry{Type: journalCNI, Name: id, CNI: conf}); err != nil {
+		return err
+	}
+	netns := "/proc/" + strconv.Itoa(p.pid()) + "/ns/net"
+	res, err := cni.Add(conf, id, netns)
+	if err != nil {
+		return err
+	}
+	p.container.cniResult = res
+	return nil
+}
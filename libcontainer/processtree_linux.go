@@ -0,0 +1,168 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+// ProcessInfo describes a process in the container, as seen from the host.
+type ProcessInfo struct {
+	Pid  int    `json:"pid"`
+	PPid int    `json:"ppid"`
+	Comm string `json:"comm"`
+	// Cgroup is the cgroup path of the process (for cgroup v1, the path
+	// in the pids controller hierarchy).
+	Cgroup string `json:"cgroup"`
+	// Namespaces maps the namespace names (as in /proc/<pid>/ns) to the
+	// namespace inode numbers.
+	Namespaces map[string]uint64 `json:"namespaces"`
+	// StartTime is the process start time, in clock ticks after system boot.
+	StartTime uint64 `json:"start_time"`
+	// Children are the child processes which are in the container.
+	Children []*ProcessInfo `json:"children,omitempty"`
+}
+
+// ProcessTree returns the processes in the container, arranged as a forest:
+// the returned processes are the ones whose parent is not in the container
+// (usually, the container init, and the processes run by "runc exec"), with
+// their descendants in Children. Processes which exit while the tree is
+// being built are omitted.
+func (c *Container) ProcessTree() ([]*ProcessInfo, error) {
+	pids, err := c.Processes()
+	if err != nil {
+		return nil, err
+	}
+	procs := make([]*ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		p, err := getProcessInfo(pid)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ESRCH) {
+				continue
+			}
+			return nil, err
+		}
+		procs = append(procs, p)
+	}
+	return buildProcessTree(procs), nil
+}
+
+func getProcessInfo(pid int) (*ProcessInfo, error) {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return nil, err
+	}
+	dir := "/proc/" + strconv.Itoa(pid)
+	cg, err := cgroups.ParseCgroupFile(dir + "/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	p := &ProcessInfo{
+		Pid:        pid,
+		PPid:       stat.PPid,
+		Comm:       stat.Name,
+		Namespaces: make(map[string]uint64),
+		StartTime:  stat.StartTime,
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		p.Cgroup = cg[""]
+	} else {
+		p.Cgroup = cg["pids"]
+	}
+	for _, t := range configs.NamespaceTypes() {
+		name := configs.NsName(t)
+		var st unix.Stat_t
+		if err := unix.Stat(dir+"/ns/"+name, &st); err != nil {
+			if errors.Is(err, unix.ENOENT) {
+				// Either the process is gone, or the kernel does
+				// not support this namespace type.
+				if _, err := os.Stat(dir); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, &os.PathError{Op: "stat", Path: dir + "/ns/" + name, Err: err}
+		}
+		p.Namespaces[name] = st.Ino
+	}
+	// Make sure the PID was not reused in the meantime.
+	if stat2, err := system.Stat(pid); err != nil {
+		return nil, err
+	} else if stat2.StartTime != stat.StartTime {
+		return nil, fmt.Errorf("process %d: %w", pid, os.ErrNotExist)
+	}
+	return p, nil
+}
+
+// buildProcessTree links the processes to their parents, and returns the
+// ones without a parent in procs. Siblings are sorted by their start time
+// (and PID).
+func buildProcessTree(procs []*ProcessInfo) []*ProcessInfo {
+	sort.Slice(procs, func(i, j int) bool {
+		if procs[i].StartTime != procs[j].StartTime {
+			return procs[i].StartTime < procs[j].StartTime
+		}
+		return procs[i].Pid < procs[j].Pid
+	})
+	byPid := make(map[int]*ProcessInfo, len(procs))
+	for _, p := range procs {
+		byPid[p.Pid] = p
+	}
+	var roots []*ProcessInfo
+	for _, p := range procs {
+		if parent, ok := byPid[p.PPid]; ok && parent != p {
+			parent.Children = append(parent.Children, p)
+		} else {
+			roots = append(roots, p)
+		}
+	}
+	return roots
+}
+
+// ForeignProcesses returns the processes in the container cgroup which are
+// neither the container init nor exec sessions (see [Container.ExecSessions]),
+// nor descendants of those, along with their descendants. Such processes
+// were either moved into the container cgroup or entered the container
+// by other means than libcontainer, which may indicate a process leak or
+// injection.
+func (c *Container) ForeignProcesses() ([]*ProcessInfo, error) {
+	tree, err := c.ProcessTree()
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := c.ExecSessions()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int]uint64, len(sessions)+1)
+	c.m.Lock()
+	if c.hasInit() {
+		known[c.initProcess.pid()] = c.initProcessStartTime
+	}
+	c.m.Unlock()
+	for _, s := range sessions {
+		known[s.Pid] = s.StartTime
+	}
+	return foreignProcesses(tree, known), nil
+}
+
+// foreignProcesses returns the roots of tree which are not in known (a map
+// of PIDs to their start times).
+func foreignProcesses(tree []*ProcessInfo, known map[int]uint64) []*ProcessInfo {
+	var foreign []*ProcessInfo
+	for _, p := range tree {
+		if st, ok := known[p.Pid]; ok && st == p.StartTime {
+			continue
+		}
+		foreign = append(foreign, p)
+	}
+	return foreign
+}
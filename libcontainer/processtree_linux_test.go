@@ -0,0 +1,68 @@
+package libcontainer
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBuildProcessTree(t *testing.T) {
+	procs := []*ProcessInfo{
+		{Pid: 30, PPid: 10, StartTime: 3},
+		{Pid: 10, PPid: 1, StartTime: 1},
+		{Pid: 25, PPid: 10, StartTime: 3},
+		{Pid: 20, PPid: 10, StartTime: 2},
+		{Pid: 40, PPid: 5, StartTime: 4},
+		{Pid: 50, PPid: 40, StartTime: 5},
+	}
+	roots := buildProcessTree(procs)
+	if len(roots) != 2 || roots[0].Pid != 10 || roots[1].Pid != 40 {
+		t.Fatalf("unexpected roots: %+v", roots)
+	}
+	var children []int
+	for _, c := range roots[0].Children {
+		children = append(children, c.Pid)
+		if len(c.Children) != 0 {
+			t.Errorf("process %d: unexpected children %+v", c.Pid, c.Children)
+		}
+	}
+	if len(children) != 3 || children[0] != 20 || children[1] != 25 || children[2] != 30 {
+		t.Errorf("unexpected children of 10: %v", children)
+	}
+	if len(roots[1].Children) != 1 || roots[1].Children[0].Pid != 50 {
+		t.Errorf("unexpected children of 40: %+v", roots[1].Children)
+	}
+}
+
+func TestGetProcessInfo(t *testing.T) {
+	p, err := getProcessInfo(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PPid != os.Getppid() {
+		t.Errorf("expected ppid %d, got %d", os.Getppid(), p.PPid)
+	}
+	if p.Namespaces["mnt"] == 0 || p.Namespaces["pid"] == 0 {
+		t.Errorf("missing namespace inodes: %v", p.Namespaces)
+	}
+	if p.StartTime == 0 {
+		t.Error("expected non-zero start time")
+	}
+}
+
+func TestForeignProcesses(t *testing.T) {
+	tree := buildProcessTree([]*ProcessInfo{
+		{Pid: 1, PPid: 0, StartTime: 10}, // init
+		{Pid: 2, PPid: 1, StartTime: 11},
+		{Pid: 3, PPid: 0, StartTime: 12}, // exec session
+		{Pid: 4, PPid: 0, StartTime: 13}, // reused PID of an exec session
+		{Pid: 5, PPid: 0, StartTime: 14}, // foreign
+		{Pid: 6, PPid: 5, StartTime: 15},
+	})
+	foreign := foreignProcesses(tree, map[int]uint64{1: 10, 3: 12, 4: 7})
+	if len(foreign) != 2 || foreign[0].Pid != 4 || foreign[1].Pid != 5 {
+		t.Fatalf("unexpected foreign processes: %+v", foreign)
+	}
+	if len(foreign[1].Children) != 1 || foreign[1].Children[0].Pid != 6 {
+		t.Errorf("expected descendants of foreign processes, got %+v", foreign[1].Children)
+	}
+}
@@ -0,0 +1,209 @@
+package libcontainer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moby/sys/mountinfo"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+const propagationFlags = unix.MS_SHARED | unix.MS_SLAVE | unix.MS_PRIVATE | unix.MS_UNBINDABLE
+
+// propagation is the propagation type of a mount, as shown by the optional
+// fields of /proc/self/mountinfo.
+type propagation struct {
+	shared, slave, unbindable bool
+}
+
+func parsePropagation(optional string) propagation {
+	var p propagation
+	for _, opt := range strings.Fields(optional) {
+		switch {
+		case strings.HasPrefix(opt, "shared:"):
+			p.shared = true
+		case strings.HasPrefix(opt, "master:"):
+			p.slave = true
+		case opt == "unbindable":
+			p.unbindable = true
+		}
+	}
+	return p
+}
+
+func (p propagation) String() string {
+	var s []string
+	if p.shared {
+		s = append(s, "shared")
+	}
+	if p.slave {
+		s = append(s, "slave")
+	}
+	if p.unbindable {
+		s = append(s, "unbindable")
+	}
+	if len(s) == 0 {
+		return "private"
+	}
+	return strings.Join(s, ",")
+}
+
+// matches reports whether p is the result of the propagation flag (one of
+// MS_SHARED, MS_SLAVE, MS_PRIVATE and MS_UNBINDABLE).
+func (p propagation) matches(flag int) bool {
+	switch flag {
+	case unix.MS_SHARED:
+		return p.shared
+	case unix.MS_SLAVE:
+		// Note that making a private mount a slave leaves it private,
+		// as it has no peers to be a slave of.
+		return p.slave && !p.shared
+	case unix.MS_PRIVATE:
+		return !p.shared && !p.slave && !p.unbindable
+	case unix.MS_UNBINDABLE:
+		return p.unbindable
+	}
+	return true
+}
+
+// lastPropagationFlag returns the last propagation type flag set by flags,
+// which is the propagation requested for the mount, along with the flag to
+// set it (with MS_REC, if requested).
+func lastPropagationFlag(flags []int) (int, int) {
+	for i := len(flags) - 1; i >= 0; i-- {
+		if t := flags[i] & propagationFlags; t != 0 {
+			return t, flags[i]
+		}
+	}
+	return 0, 0
+}
+
+type propagationCheck struct {
+	dest        string
+	want, apply int
+}
+
+// checkPropagation checks, once the rootfs is set up (and pivot_root is
+// done), that the propagation of / and of the mounts is the one requested,
+// and repairs it or fails, depending on config.PropagationCheck. It also
+// logs the propagation tree, at the debug level.
+func checkPropagation(config *configs.Config) error {
+	mode := config.PropagationCheck
+	if mode == "" {
+		mode = configs.PropagationCheckWarn
+	}
+	if mode == configs.PropagationCheckIgnore && !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return nil
+	}
+	mounts, err := mountinfo.GetMounts(nil)
+	if err != nil {
+		// Most probably, /proc is not mounted in the container.
+		logrus.Debugf("unable to check mount propagation: %v", err)
+		return nil
+	}
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
+		logrus.Debugf("mount propagation tree:\n%s", propagationTree(mounts))
+	}
+	if mode == configs.PropagationCheckIgnore {
+		return nil
+	}
+
+	var checks []propagationCheck
+	if t := config.RootPropagation & propagationFlags; t != 0 {
+		checks = append(checks, propagationCheck{dest: "/", want: t, apply: config.RootPropagation})
+	}
+	for _, m := range config.Mounts {
+		if t, flag := lastPropagationFlag(m.PropagationFlags); t != 0 {
+			checks = append(checks, propagationCheck{dest: filepath.Clean(m.Destination), want: t, apply: flag})
+		}
+	}
+	for _, c := range checks {
+		got, ok := mountPropagation(mounts, c.dest)
+		if !ok || got.matches(c.want) {
+			continue
+		}
+		msg := fmt.Sprintf("mount %s propagation is %s, not %s as requested", c.dest, got, propagation{
+			shared:     c.want == unix.MS_SHARED,
+			slave:      c.want == unix.MS_SLAVE,
+			unbindable: c.want == unix.MS_UNBINDABLE,
+		})
+		if mode == configs.PropagationCheckWarn {
+			logrus.Warn(msg)
+			continue
+		}
+		if err := mount("", c.dest, "", uintptr(c.apply), ""); err != nil {
+			msg += fmt.Sprintf(" (repair failed: %v)", err)
+		} else if got, err = currentPropagation(c.dest); err != nil {
+			msg += fmt.Sprintf(" (repair failed: %v)", err)
+		} else if got.matches(c.want) {
+			logrus.Debugf("%s (repaired)", msg)
+			continue
+		} else {
+			msg += fmt.Sprintf(" (still %s after repair)", got)
+		}
+		if mode == configs.PropagationCheckStrict {
+			return fmt.Errorf("propagation check failed: %s", msg)
+		}
+		logrus.Warn(msg)
+	}
+	return nil
+}
+
+// mountPropagation returns the propagation of the topmost mount at dest.
+func mountPropagation(mounts []*mountinfo.Info, dest string) (propagation, bool) {
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if mounts[i].Mountpoint == dest {
+			return parsePropagation(mounts[i].Optional), true
+		}
+	}
+	return propagation{}, false
+}
+
+func currentPropagation(dest string) (propagation, error) {
+	mounts, err := mountinfo.GetMounts(mountinfo.SingleEntryFilter(dest))
+	if err != nil {
+		return propagation{}, err
+	}
+	p, ok := mountPropagation(mounts, dest)
+	if !ok {
+		return propagation{}, fmt.Errorf("%s is not a mount point", dest)
+	}
+	return p, nil
+}
+
+// propagationTree formats the mounts as a tree, with their propagation.
+func propagationTree(mounts []*mountinfo.Info) string {
+	ids := make(map[int]bool, len(mounts))
+	children := make(map[int][]*mountinfo.Info)
+	for _, m := range mounts {
+		ids[m.ID] = true
+	}
+	var roots []*mountinfo.Info
+	for _, m := range mounts {
+		if ids[m.Parent] && m.Parent != m.ID {
+			children[m.Parent] = append(children[m.Parent], m)
+		} else {
+			roots = append(roots, m)
+		}
+	}
+	var b strings.Builder
+	var walk func(ms []*mountinfo.Info, depth int)
+	walk = func(ms []*mountinfo.Info, depth int) {
+		sort.SliceStable(ms, func(i, j int) bool { return ms[i].Mountpoint < ms[j].Mountpoint })
+		for _, m := range ms {
+			fmt.Fprintf(&b, "%s%s %s", strings.Repeat("  ", depth), m.Mountpoint, parsePropagation(m.Optional))
+			if m.Optional != "" {
+				fmt.Fprintf(&b, " [%s]", m.Optional)
+			}
+			b.WriteByte('\n')
+			walk(children[m.ID], depth+1)
+		}
+	}
+	walk(roots, 0)
+	return strings.TrimSuffix(b.String(), "\n")
+}
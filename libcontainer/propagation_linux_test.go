@@ -0,0 +1,67 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/moby/sys/mountinfo"
+	"golang.org/x/sys/unix"
+)
+
+func TestPropagationMatches(t *testing.T) {
+	testCases := []struct {
+		optional string
+		flag     int
+		expected bool
+	}{
+		{optional: "shared:1", flag: unix.MS_SHARED, expected: true},
+		{optional: "shared:1 master:2", flag: unix.MS_SHARED, expected: true},
+		{optional: "", flag: unix.MS_SHARED},
+		{optional: "master:2", flag: unix.MS_SLAVE, expected: true},
+		{optional: "shared:1 master:2", flag: unix.MS_SLAVE},
+		// A private mount made a slave is still private.
+		{optional: "", flag: unix.MS_SLAVE},
+		{optional: "", flag: unix.MS_PRIVATE, expected: true},
+		{optional: "master:2", flag: unix.MS_PRIVATE},
+		{optional: "unbindable", flag: unix.MS_UNBINDABLE, expected: true},
+		{optional: "unbindable", flag: unix.MS_PRIVATE},
+	}
+	for _, tc := range testCases {
+		p := parsePropagation(tc.optional)
+		if got := p.matches(tc.flag); got != tc.expected {
+			t.Errorf("%q (%s), flag %#x: expected %v, got %v", tc.optional, p, tc.flag, tc.expected, got)
+		}
+	}
+}
+
+func TestLastPropagationFlag(t *testing.T) {
+	want, apply := lastPropagationFlag([]int{unix.MS_SLAVE | unix.MS_REC, unix.MS_SHARED, unix.MS_NOSUID})
+	if want != unix.MS_SHARED || apply != unix.MS_SHARED {
+		t.Errorf("expected MS_SHARED, got %#x, %#x", want, apply)
+	}
+	want, apply = lastPropagationFlag([]int{unix.MS_PRIVATE | unix.MS_REC})
+	if want != unix.MS_PRIVATE || apply != unix.MS_PRIVATE|unix.MS_REC {
+		t.Errorf("expected MS_PRIVATE, got %#x, %#x", want, apply)
+	}
+	if want, _ := lastPropagationFlag(nil); want != 0 {
+		t.Errorf("expected 0, got %#x", want)
+	}
+}
+
+func TestPropagationTree(t *testing.T) {
+	mounts := []*mountinfo.Info{
+		{ID: 10, Parent: 1, Mountpoint: "/", Optional: "master:1"},
+		{ID: 12, Parent: 10, Mountpoint: "/proc"},
+		{ID: 11, Parent: 10, Mountpoint: "/dev", Optional: "shared:5"},
+		{ID: 13, Parent: 11, Mountpoint: "/dev/pts"},
+	}
+	expected := `/ slave [master:1]
+  /dev shared [shared:5]
+    /dev/pts private
+  /proc private`
+	if got := propagationTree(mounts); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if p, ok := mountPropagation(mounts, "/dev"); !ok || !p.shared {
+		t.Errorf("expected /dev to be shared, got %s, %v", p, ok)
+	}
+}
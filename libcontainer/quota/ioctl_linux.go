@@ -0,0 +1,10 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le && !ppc64 && !ppc64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le,!ppc64,!ppc64le
+
+package quota
+
+// The fsxattr ioctls, not in x/sys/unix yet.
+const (
+	fsIocFsgetxattr = 0x801c581f // FS_IOC_FSGETXATTR
+	fsIocFssetxattr = 0x401c5820 // FS_IOC_FSSETXATTR
+)
@@ -0,0 +1,12 @@
+//go:build linux && (mips || mipsle || mips64 || mips64le || ppc64 || ppc64le)
+// +build linux
+// +build mips mipsle mips64 mips64le ppc64 ppc64le
+
+package quota
+
+// The fsxattr ioctls, not in x/sys/unix yet (mips and powerpc have their
+// own ioctl direction bits).
+const (
+	fsIocFsgetxattr = 0x401c581f // FS_IOC_FSGETXATTR
+	fsIocFssetxattr = 0x801c5820 // FS_IOC_FSSETXATTR
+)
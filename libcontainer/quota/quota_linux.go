@@ -0,0 +1,258 @@
+// Package quota manages the project quotas (supported by ext4 and xfs)
+// limiting the disk usage of a container writable layer.
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/moby/sys/mountinfo"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// quotactl(2) commands and flags, not in x/sys/unix yet.
+const (
+	qGetQuota  = 0x800007 // Q_GETQUOTA
+	qSetQuota  = 0x800008 // Q_SETQUOTA
+	prjQuota   = 2        // PRJQUOTA
+	qifBlimits = 1        // QIF_BLIMITS
+	qifIlimits = 4        // QIF_ILIMITS
+
+	// The block limits are in units of QIF_DQBLKSIZE bytes.
+	dqBlkSize = 1024
+
+	fsXflagProjinherit = 0x200 // FS_XFLAG_PROJINHERIT
+)
+
+// dqblk is struct if_dqblk.
+type dqblk struct {
+	bhardlimit uint64
+	bsoftlimit uint64
+	curspace   uint64
+	ihardlimit uint64
+	isoftlimit uint64
+	curinodes  uint64
+	btime      uint64
+	itime      uint64
+	valid      uint32
+	_          uint32
+}
+
+// fsxattr is struct fsxattr.
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	_          [8]byte
+}
+
+// Usage is the disk usage of a directory with a project quota.
+type Usage struct {
+	// Size is the disk space used, in bytes.
+	Size uint64 `json:"size"`
+	// SizeLimit is the disk space limit, in bytes (0 means no limit).
+	SizeLimit uint64 `json:"size_limit,omitempty"`
+	// Inodes is the number of inodes used.
+	Inodes uint64 `json:"inodes"`
+	// InodesLimit is the maximum number of inodes (0 means no limit).
+	InodesLimit uint64 `json:"inodes_limit,omitempty"`
+}
+
+// ErrProjectInUse is returned by Set when the project ID is already used on
+// the filesystem by other files, such as the ones of another container whose
+// ID has the same default project ID (see ProjectID).
+var ErrProjectInUse = errors.New("project ID already in use")
+
+// ProjectID returns the default project ID for the container with the
+// given ID. The IDs are taken from [1<<30, 1<<31), to stay clear of the
+// small ones usually assigned by hand in /etc/projid. As they are hashed
+// from the container IDs, two containers may get the same one, which Set
+// detects (see ErrProjectInUse).
+func ProjectID(id string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	return 1<<30 + h.Sum32()%(1<<30)
+}
+
+// Set sets the project ID of q.Path and of the directories and regular
+// files in it, with the project ID inheritance flag on the directories,
+// and sets the quota limits of the project. Unless q.Path already has the
+// project ID, it is an error (ErrProjectInUse) if the project is already
+// used on the filesystem, so that two containers never share a quota.
+func Set(q *configs.DiskQuota) error {
+	dir, err := os.Open(q.Path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	var attr fsxattr
+	if err := ioctl(int(dir.Fd()), fsIocFsgetxattr, &attr); err != nil {
+		return &os.PathError{Op: "FS_IOC_FSGETXATTR", Path: q.Path, Err: fsError(err)}
+	}
+	var dq dqblk
+	if err := quotactl(dir, qGetQuota, q.ProjectID, &dq); err != nil {
+		return err
+	}
+	if projectInUse(attr.projid, q.ProjectID, &dq) {
+		return fmt.Errorf("%w: project %d is already used on the filesystem of %s (%d inodes); set another project ID", ErrProjectInUse, q.ProjectID, q.Path, dq.curinodes)
+	}
+	if err := setLimits(dir, q.ProjectID, q.Size, q.Inodes); err != nil {
+		return err
+	}
+	return filepath.WalkDir(q.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		return setProject(path, q.ProjectID, d.IsDir())
+	})
+}
+
+// Remove removes the quota limits of q (the project ID is left as is on
+// the files). It is not an error if q.Path no longer exists.
+func Remove(q *configs.DiskQuota) error {
+	dir, err := os.Open(q.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer dir.Close()
+	return setLimits(dir, q.ProjectID, 0, 0)
+}
+
+// GetUsage returns the disk usage, and the limits, of q.
+func GetUsage(q *configs.DiskQuota) (*Usage, error) {
+	dir, err := os.Open(q.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	var dq dqblk
+	if err := quotactl(dir, qGetQuota, q.ProjectID, &dq); err != nil {
+		return nil, err
+	}
+	return &Usage{
+		Size:        dq.curspace,
+		SizeLimit:   dq.bhardlimit * dqBlkSize,
+		Inodes:      dq.curinodes,
+		InodesLimit: dq.ihardlimit,
+	}, nil
+}
+
+// projectInUse returns whether projectID, with the quota dq, is used by
+// other files than the ones of a directory whose project ID is pathProjectID.
+func projectInUse(pathProjectID, projectID uint32, dq *dqblk) bool {
+	if pathProjectID == projectID {
+		// The directory is already in the project, such as when the
+		// container is run again on the same root filesystem.
+		return false
+	}
+	return dq.curinodes > 0 || dq.curspace > 0 || dq.bhardlimit > 0 || dq.ihardlimit > 0
+}
+
+func setLimits(dir *os.File, projectID uint32, size int64, inodes uint64) error {
+	// Round the size up to the quota block size.
+	blocks := (uint64(size) + dqBlkSize - 1) / dqBlkSize
+	dq := dqblk{
+		bhardlimit: blocks,
+		bsoftlimit: blocks,
+		ihardlimit: inodes,
+		isoftlimit: inodes,
+		valid:      qifBlimits | qifIlimits,
+	}
+	return quotactl(dir, qSetQuota, projectID, &dq)
+}
+
+func setProject(path string, projectID uint32, isDir bool) error {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+	var attr fsxattr
+	if err := ioctl(fd, fsIocFsgetxattr, &attr); err != nil {
+		return &os.PathError{Op: "FS_IOC_FSGETXATTR", Path: path, Err: fsError(err)}
+	}
+	attr.projid = projectID
+	if isDir {
+		attr.xflags |= fsXflagProjinherit
+	}
+	if err := ioctl(fd, fsIocFssetxattr, &attr); err != nil {
+		return &os.PathError{Op: "FS_IOC_FSSETXATTR", Path: path, Err: fsError(err)}
+	}
+	return nil
+}
+
+func ioctl(fd int, req uint, attr *fsxattr) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(unsafe.Pointer(attr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func quotactl(dir *os.File, cmd int, projectID uint32, dq *dqblk) error {
+	cmd = cmd<<8 | prjQuota
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL_FD, dir.Fd(), uintptr(cmd), uintptr(projectID), uintptr(unsafe.Pointer(dq)), 0, 0)
+	if errno == unix.ENOSYS {
+		// Before Linux 5.14, quotactl needs the filesystem device.
+		dev, err := fsDevice(dir)
+		if err != nil {
+			return err
+		}
+		p, err := unix.BytePtrFromString(dev)
+		if err != nil {
+			return err
+		}
+		_, _, errno = unix.Syscall6(unix.SYS_QUOTACTL, uintptr(cmd), uintptr(unsafe.Pointer(p)), uintptr(projectID), uintptr(unsafe.Pointer(dq)), 0, 0)
+	}
+	if errno != 0 {
+		return fmt.Errorf("quotactl %s: %w", dir.Name(), fsError(errno))
+	}
+	return nil
+}
+
+// fsDevice returns the device of the filesystem dir is on.
+func fsDevice(dir *os.File) (string, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(dir.Fd()), &st); err != nil {
+		return "", &os.PathError{Op: "fstat", Path: dir.Name(), Err: err}
+	}
+	devNumber := uint64(st.Dev) //nolint:unconvert // Dev is uint32 on e.g. MIPS.
+	major, minor := int(unix.Major(devNumber)), int(unix.Minor(devNumber))
+	mounts, err := mountinfo.GetMounts(func(m *mountinfo.Info) (bool, bool) {
+		return m.Major != major || m.Minor != minor, false
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(mounts) == 0 {
+		return "", fmt.Errorf("no mount found for %s", dir.Name())
+	}
+	return mounts[0].Source, nil
+}
+
+// fsError makes the errors returned when project quotas are not usable more
+// telling.
+func fsError(err error) error {
+	switch {
+	case errors.Is(err, unix.ESRCH):
+		return fmt.Errorf("%w (project quotas are not enabled; mount the filesystem with the prjquota option)", err)
+	case errors.Is(err, unix.ENOTTY), errors.Is(err, unix.EOPNOTSUPP), errors.Is(err, unix.EINVAL):
+		return fmt.Errorf("%w (project quotas are not supported by the filesystem)", err)
+	}
+	return err
+}
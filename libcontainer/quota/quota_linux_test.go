@@ -0,0 +1,63 @@
+package quota
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestStructSizes(t *testing.T) {
+	if s := unsafe.Sizeof(fsxattr{}); s != 28 {
+		t.Errorf("unexpected struct fsxattr size %d", s)
+	}
+	if s := unsafe.Sizeof(dqblk{}); s != 72 {
+		t.Errorf("unexpected struct if_dqblk size %d", s)
+	}
+}
+
+func TestProjectID(t *testing.T) {
+	ids := make(map[uint32]string)
+	for _, name := range []string{"a", "b", "mycontainer", "mycontainer2", ""} {
+		id := ProjectID(name)
+		if id < 1<<30 || id >= 1<<31 {
+			t.Errorf("%q: project ID %d out of range", name, id)
+		}
+		if id != ProjectID(name) {
+			t.Errorf("%q: project ID is not stable", name)
+		}
+		if other, ok := ids[id]; ok {
+			t.Errorf("%q and %q have the same project ID %d", name, other, id)
+		}
+		ids[id] = name
+	}
+}
+
+func TestProjectInUse(t *testing.T) {
+	for _, tc := range []struct {
+		pathID   uint32
+		dq       dqblk
+		expected bool
+	}{
+		// An unused project.
+		{pathID: 0, dq: dqblk{}, expected: false},
+		// The directory is already in the project.
+		{pathID: 1 << 30, dq: dqblk{curinodes: 10, bhardlimit: 1024}, expected: false},
+		// Files of another directory are in the project.
+		{pathID: 0, dq: dqblk{curinodes: 10, curspace: 4096}, expected: true},
+		// The project has limits, but no files yet.
+		{pathID: 0, dq: dqblk{ihardlimit: 100}, expected: true},
+		{pathID: 7, dq: dqblk{bhardlimit: 1024}, expected: true},
+	} {
+		if got := projectInUse(tc.pathID, 1<<30, &tc.dq); got != tc.expected {
+			t.Errorf("path project %d, quota %+v: expected %v, got %v", tc.pathID, tc.dq, tc.expected, got)
+		}
+	}
+}
+
+func TestRemoveNonexistent(t *testing.T) {
+	q := &configs.DiskQuota{Path: t.TempDir() + "/nonexistent", ProjectID: ProjectID("test"), Size: 1 << 20}
+	if err := Remove(q); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"os/exec"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/opencontainers/runc/libcontainer/system"
 )
 
@@ -108,11 +110,11 @@ func (p *nonChildProcess) startTime() (uint64, error) {
 }
 
 func (p *nonChildProcess) signal(s os.Signal) error {
-	proc, err := os.FindProcess(p.processPid)
-	if err != nil {
-		return err
+	sig, ok := s.(unix.Signal)
+	if !ok {
+		return errors.New("os: unsupported signal type")
 	}
-	return proc.Signal(s)
+	return signalPid(p.processPid, p.processStartTime, sig)
 }
 
 func (p *nonChildProcess) externalDescriptors() []string {
@@ -0,0 +1,74 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// SetRlimits sets the resource limits of the running container init
+// process, and of all the container processes if all is set. The limits
+// are also recorded in the container configuration, for the processes
+// executed later (by runc exec) to get them, replacing the ones of the
+// same types.
+func (c *Container) SetRlimits(rlimits []configs.Rlimit, all bool) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for _, rl := range rlimits {
+		if rl.Soft > rl.Hard {
+			return fmt.Errorf("rlimit type %d: soft limit %d is greater than hard limit %d", rl.Type, rl.Soft, rl.Hard)
+		}
+	}
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	if err := setupRlimits(rlimits, c.initProcess.pid()); err != nil {
+		return fmt.Errorf("container init: %w", err)
+	}
+	if all {
+		pids, err := c.cgroupManager.GetAllPids()
+		if err != nil {
+			return err
+		}
+		for _, pid := range pids {
+			if pid == c.initProcess.pid() {
+				continue
+			}
+			// The process may have exited in the meantime.
+			if err := setupRlimits(rlimits, pid); err != nil && !errors.Is(err, unix.ESRCH) {
+				return fmt.Errorf("process %d: %w", pid, err)
+			}
+		}
+	}
+
+	c.config.Rlimits = mergeRlimits(c.config.Rlimits, rlimits)
+	_, err = c.updateState(nil)
+	return err
+}
+
+// mergeRlimits returns the limits of base, replaced by the ones of override
+// of the same types, followed by the other ones of override.
+func mergeRlimits(base, override []configs.Rlimit) []configs.Rlimit {
+	merged := append([]configs.Rlimit{}, base...)
+	for _, rl := range override {
+		replaced := false
+		for i := range merged {
+			if merged[i].Type == rl.Type {
+				merged[i] = rl
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, rl)
+		}
+	}
+	return merged
+}
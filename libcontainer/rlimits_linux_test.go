@@ -0,0 +1,35 @@
+package libcontainer
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestMergeRlimits(t *testing.T) {
+	base := []configs.Rlimit{
+		{Type: unix.RLIMIT_NOFILE, Soft: 1024, Hard: 1024},
+		{Type: unix.RLIMIT_CORE, Soft: 0, Hard: 0},
+	}
+	override := []configs.Rlimit{
+		{Type: unix.RLIMIT_NOFILE, Soft: 4096, Hard: 8192},
+		{Type: unix.RLIMIT_NPROC, Soft: 100, Hard: 100},
+	}
+	expected := []configs.Rlimit{
+		{Type: unix.RLIMIT_NOFILE, Soft: 4096, Hard: 8192},
+		{Type: unix.RLIMIT_CORE, Soft: 0, Hard: 0},
+		{Type: unix.RLIMIT_NPROC, Soft: 100, Hard: 100},
+	}
+	if got := mergeRlimits(base, override); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if base[0].Soft != 1024 {
+		t.Error("the base limits were modified")
+	}
+	if got := mergeRlimits(nil, nil); len(got) != 0 {
+		t.Errorf("expected no limits, got %+v", got)
+	}
+}
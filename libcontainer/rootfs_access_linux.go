@@ -0,0 +1,288 @@
+package libcontainer
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// RootFS gives access to a container root filesystem from the host, without
+// entering the container namespaces. All paths are resolved as if the
+// container root was "/" (i.e. absolute symlinks and ".." components can not
+// escape it), so it is safe to use even if the container is not trusted.
+//
+// RootFS requires openat2(2) (Linux 5.6+). Its methods are similar to the
+// ones from the os package, and [RootFS.FS] provides an [fs.FS] view.
+type RootFS struct {
+	dir *os.File
+}
+
+// RootFS returns a [RootFS] for the root filesystem of the running container,
+// as seen by its init process (i.e. with the container mounts). The caller
+// must close it after use.
+func (c *Container) RootFS() (*RootFS, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.rootFS()
+}
+
+// rootFS is RootFS, for the callers holding c.m.
+func (c *Container) rootFS() (*RootFS, error) {
+	if !c.hasInit() {
+		return nil, ErrNotRunning
+	}
+	root, err := OpenRootFS("/proc/" + strconv.Itoa(c.initProcess.pid()) + "/root")
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the PID was not reused while opening it.
+	if !c.hasInit() {
+		_ = root.Close()
+		return nil, ErrNotRunning
+	}
+	return root, nil
+}
+
+// OpenRootFS returns a [RootFS] anchored at the given directory.
+func OpenRootFS(dir string) (*RootFS, error) {
+	fd, err := unix.Openat2(-1, dir, &unix.OpenHow{
+		Flags: unix.O_DIRECTORY | unix.O_PATH | unix.O_CLOEXEC,
+	})
+	if err != nil {
+		return nil, &os.PathError{Op: "openat2", Path: dir, Err: err}
+	}
+	return &RootFS{dir: os.NewFile(uintptr(fd), dir)}, nil
+}
+
+// Close releases the root directory file descriptor.
+func (r *RootFS) Close() error {
+	return r.dir.Close()
+}
+
+// OpenFile is like [os.OpenFile], with name resolved in the container root.
+// Magic links (such as /proc/self/exe) are not followed. Use [RootFS.ReadDir]
+// rather than the ReadDir method of the returned file to list directories,
+// as the Info method of the entries from the latter resolves paths on the
+// host.
+func (r *RootFS) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	fd, err := unix.Openat2(int(r.dir.Fd()), name, &unix.OpenHow{
+		Flags:   uint64(flag) | unix.O_CLOEXEC,
+		Mode:    uint64(perm.Perm()),
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	if err != nil {
+		return nil, &os.PathError{Op: "openat2", Path: name, Err: err}
+	}
+	return os.NewFile(uintptr(fd), filepath.Join("/", name)), nil
+}
+
+// Stat returns the file information for the named file, following symlinks.
+func (r *RootFS) Stat(name string) (os.FileInfo, error) {
+	return r.stat(name, 0)
+}
+
+// Lstat is like [RootFS.Stat], except that the last path component is not
+// followed if it is a symlink.
+func (r *RootFS) Lstat(name string) (os.FileInfo, error) {
+	return r.stat(name, unix.O_NOFOLLOW)
+}
+
+func (r *RootFS) stat(name string, flag int) (os.FileInfo, error) {
+	f, err := r.OpenFile(name, unix.O_PATH|flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// Readlink returns the destination of the named symlink. Note it is not
+// resolved in the container root.
+func (r *RootFS) Readlink(name string) (string, error) {
+	f, err := r.OpenFile(name, unix.O_PATH|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, unix.PathMax)
+	n, err := unix.Readlinkat(int(f.Fd()), "", buf)
+	if err != nil {
+		return "", &os.PathError{Op: "readlinkat", Path: name, Err: err}
+	}
+	return string(buf[:n]), nil
+}
+
+// ReadDir reads the named directory, returning its entries sorted by name.
+func (r *RootFS) ReadDir(name string) ([]os.DirEntry, error) {
+	f, err := r.OpenFile(name, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := r.readDir(f, name, -1)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, err
+}
+
+// readDir is like f.ReadDir, but the returned entries use r to get
+// the file information.
+func (r *RootFS) readDir(f *os.File, name string, n int) ([]os.DirEntry, error) {
+	entries, err := f.ReadDir(n)
+	for i, e := range entries {
+		entries[i] = &dirEntry{DirEntry: e, r: r, path: filepath.Join(name, e.Name())}
+	}
+	return entries, err
+}
+
+type dirEntry struct {
+	fs.DirEntry
+	r    *RootFS
+	path string
+}
+
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	return e.r.Lstat(e.path)
+}
+
+// ReadFile reads the whole named file.
+func (r *RootFS) ReadFile(name string) ([]byte, error) {
+	f, err := r.OpenFile(name, unix.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, &os.PathError{Op: "read", Path: name, Err: errors.New("not a regular file")}
+	}
+	return io.ReadAll(f)
+}
+
+// WriteFile writes data to the named file, creating it (with perm) if
+// necessary, and truncating it otherwise.
+func (r *RootFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := r.OpenFile(name, unix.O_WRONLY|unix.O_CREAT|unix.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// parent opens the parent directory of name, returning it along with the
+// last path component.
+func (r *RootFS) parent(op, name string) (*os.File, string, error) {
+	dir, base := filepath.Split(filepath.Clean(filepath.Join("/", name)))
+	if base == "" || base == "/" {
+		return nil, "", &os.PathError{Op: op, Path: name, Err: unix.EINVAL}
+	}
+	d, err := r.OpenFile(dir, unix.O_PATH|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	return d, base, nil
+}
+
+// Mkdir creates the named directory with the given permissions (before
+// umask).
+func (r *RootFS) Mkdir(name string, perm os.FileMode) error {
+	d, base, err := r.parent("mkdir", name)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := unix.Mkdirat(int(d.Fd()), base, uint32(perm.Perm())); err != nil {
+		return &os.PathError{Op: "mkdirat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Remove removes the named file or (empty) directory. If the last path
+// component is a symlink, the symlink itself is removed.
+func (r *RootFS) Remove(name string) error {
+	d, base, err := r.parent("remove", name)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	err = unix.Unlinkat(int(d.Fd()), base, 0)
+	if errors.Is(err, unix.EISDIR) {
+		err = unix.Unlinkat(int(d.Fd()), base, unix.AT_REMOVEDIR)
+	}
+	if err != nil {
+		return &os.PathError{Op: "unlinkat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// FS returns an [fs.FS] view of r. As required by [fs.FS], it only accepts
+// unrooted, slash-separated and clean paths.
+func (r *RootFS) FS() fs.FS {
+	return rootFSView{r: r}
+}
+
+type rootFSView struct {
+	r *RootFS
+}
+
+func (v rootFSView) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := v.r.OpenFile(name, unix.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &rootFile{File: f, r: v.r, name: name}, nil
+}
+
+func (v rootFSView) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return v.r.ReadDir(name)
+}
+
+func (v rootFSView) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	return v.r.Stat(name)
+}
+
+func (v rootFSView) Lstat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrInvalid}
+	}
+	return v.r.Lstat(name)
+}
+
+func (v rootFSView) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return v.r.Readlink(name)
+}
+
+// rootFile is a file opened by rootFSView.
+type rootFile struct {
+	*os.File
+	r    *RootFS
+	name string
+}
+
+func (f *rootFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	return f.r.readDir(f.File, f.name, n)
+}
@@ -0,0 +1,141 @@
+package libcontainer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/sys/unix"
+)
+
+func openTestRootFS(t *testing.T) (*RootFS, string) {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		filepath.Join(dir, "secret"):        "host",
+		filepath.Join(root, "secret"):       "container",
+		filepath.Join(root, "etc", "hosts"): "127.0.0.1 localhost\n",
+	} {
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Both links would point to dir/secret if followed on the host.
+	if err := os.Symlink(filepath.Join(dir, "secret"), filepath.Join(root, "abs")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../secret", filepath.Join(root, "etc", "rel")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenRootFS(root)
+	if errors.Is(err, unix.ENOSYS) {
+		t.Skip("openat2 not available")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return r, root
+}
+
+func TestRootFSResolveInRoot(t *testing.T) {
+	r, _ := openTestRootFS(t)
+
+	data, err := r.ReadFile("etc/rel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "container" {
+		t.Errorf("relative symlink escaped the root: got %q", data)
+	}
+	data, err = r.ReadFile("/../../secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "container" {
+		t.Errorf("dot-dot escaped the root: got %q", data)
+	}
+	// The absolute link target does not exist inside the root.
+	if _, err := r.ReadFile("abs"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected ENOENT for absolute symlink, got %v", err)
+	}
+	fi, err := r.Lstat("abs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected a symlink, got mode %v", fi.Mode())
+	}
+	if dst, err := r.Readlink("etc/rel"); err != nil || dst != "../../secret" {
+		t.Errorf("unexpected link destination %q (err: %v)", dst, err)
+	}
+}
+
+func TestRootFSModify(t *testing.T) {
+	r, root := openTestRootFS(t)
+
+	if err := r.Mkdir("/etc/rel/../../new", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.WriteFile("new/file", []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "new", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "data" {
+		t.Errorf("unexpected data %q", data)
+	}
+	entries, err := r.ReadDir("/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "file" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+
+	// Removing a symlink must not remove its target.
+	if err := r.Remove("abs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "secret")); err != nil {
+		t.Error(err)
+	}
+	if err := r.Remove("new"); !errors.Is(err, unix.ENOTEMPTY) {
+		t.Errorf("expected ENOTEMPTY, got %v", err)
+	}
+	if err := r.Remove("new/file"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remove("new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remove("/"); err == nil {
+		t.Error("expected an error removing the root, got nil")
+	}
+}
+
+func TestRootFSFS(t *testing.T) {
+	r, _ := openTestRootFS(t)
+
+	fsys := r.FS()
+	if _, err := fsys.Open("/etc/hosts"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a rooted path, got %v", err)
+	}
+	// fstest does not like dangling symlinks.
+	if err := r.Remove("abs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "etc/hosts", "secret"); err != nil {
+		t.Fatal(err)
+	}
+}
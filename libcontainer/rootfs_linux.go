@@ -16,6 +16,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/devices"
 	"github.com/opencontainers/runc/libcontainer/userns"
 	"github.com/opencontainers/runc/libcontainer/utils"
@@ -34,6 +35,13 @@ type mountConfig struct {
 	cgroup2Path     string
 	rootlessCgroups bool
 	cgroupns        bool
+	systemdInit     bool
+	// freshSysfs is set to mount sysfs in place of a bind mount of the
+	// host /sys (see configs.Config.KeepSysBind).
+	freshSysfs bool
+	// allowedSources restricts the bind mount sources (see
+	// configs.Config.AllowedMountSources).
+	allowedSources []string
 }
 
 // mountEntry contains mount data specific to a mount point.
@@ -49,6 +57,56 @@ func (m *mountEntry) src() string {
 	return m.Source
 }
 
+// applyHookResult sets up the mounts and the environment requested by the
+// createRuntime hooks. Like the other mounts, the bind mount sources are
+// checked against the allowed mount sources by mountToRootfs.
+func applyHookResult(mountConfig *mountConfig, config *configs.Config, res *configs.HookResult) error {
+	for _, m := range res.ToMounts() {
+		if err := mountToRootfs(mountConfig, mountEntry{Mount: m}); err != nil {
+			return fmt.Errorf("error mounting %q to rootfs at %q (requested by hook): %w", m.Source, m.Destination, err)
+		}
+		config.Mounts = append(config.Mounts, m)
+	}
+	for _, e := range res.Env {
+		k, v, _ := strings.Cut(e, "=")
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeResolvConf writes /etc/resolv.conf in the container root filesystem
+// from the static network configuration.
+func writeResolvConf(rootfs string, n *configs.StaticNetwork) error {
+	var b strings.Builder
+	b.WriteString("# Generated by runc.\n")
+	for _, ns := range n.Nameservers {
+		b.WriteString("nameserver " + ns + "\n")
+	}
+	if len(n.Search) > 0 {
+		b.WriteString("search " + strings.Join(n.Search, " ") + "\n")
+	}
+	etc, err := securejoin.SecureJoin(rootfs, "/etc")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(etc, 0o755); err != nil {
+		return err
+	}
+	// Make sure not to follow a symlink out of the rootfs.
+	path, err := securejoin.SecureJoin(rootfs, "/etc/resolv.conf")
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Lstat(path); err == nil && !fi.Mode().IsRegular() {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
 // needsSetupDev returns true if /dev needs to be set up.
 func needsSetupDev(config *configs.Config) bool {
 	for _, m := range config.Mounts {
@@ -82,6 +140,9 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig, mountFds mountFds) (er
 		cgroup2Path:     iConfig.Cgroup2Path,
 		rootlessCgroups: iConfig.RootlessCgroups,
 		cgroupns:        config.Namespaces.Contains(configs.NEWCGROUP),
+		systemdInit:     config.SystemdInit,
+		freshSysfs:      config.Namespaces.IsPrivate(configs.NEWNET) && !config.KeepSysBind,
+		allowedSources:  config.AllowedMountSources,
 	}
 	for i, m := range config.Mounts {
 		entry := mountEntry{Mount: m}
@@ -99,9 +160,28 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig, mountFds mountFds) (er
 			entry.srcFD = &mountFds.idmapFds[i]
 		}
 
+		if m.IsSourceFd() {
+			fd, err := m.SourceFd()
+			if err != nil {
+				return err
+			}
+			if fd < 3 || fd >= 3+iConfig.PassedFilesCount {
+				return fmt.Errorf("mount source %s: file descriptor not passed to the container (see --preserve-fds)", m.Source)
+			}
+			entry.srcFD = &fd
+		}
+
 		if err := mountToRootfs(mountConfig, entry); err != nil {
 			return fmt.Errorf("error mounting %q to rootfs at %q: %w", m.Source, m.Destination, err)
 		}
+		// The caller passed the file descriptor to be mounted, not to be
+		// used by the container process, which could escape the container
+		// through it.
+		if m.IsSourceFd() {
+			if err := unix.Close(*entry.srcFD); err != nil {
+				return fmt.Errorf("unable to close mount source %s: %w", m.Source, err)
+			}
+		}
 	}
 
 	setupDev := needsSetupDev(config)
@@ -117,14 +197,26 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig, mountFds mountFds) (er
 		}
 	}
 
+	if config.StaticNetwork != nil && config.StaticNetwork.ResolvConf {
+		if err := writeResolvConf(config.Rootfs, config.StaticNetwork); err != nil {
+			return fmt.Errorf("error writing resolv.conf: %w", err)
+		}
+	}
+
 	// Signal the parent to run the pre-start hooks.
 	// The hooks are run after the mounts are setup, but before we switch to the new
 	// root, so that the old root is still available in the hooks for any mount
 	// manipulations.
 	// Note that iConfig.Cwd is not guaranteed to exist here.
-	if err := syncParentHooks(pipe); err != nil {
+	res, err := syncParentHooks(pipe)
+	if err != nil {
 		return err
 	}
+	if res != nil {
+		if err := applyHookResult(mountConfig, config, res); err != nil {
+			return err
+		}
+	}
 
 	// The reason these operations are done here rather than in finalizeRootfs
 	// is because the console-handling code gets quite sticky if we have to set
@@ -176,6 +268,10 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig, mountFds mountFds) (er
 // finalizeRootfs sets anything to ro if necessary. You must call
 // prepareRootfs first.
 func finalizeRootfs(config *configs.Config) (err error) {
+	if err := checkPropagation(config); err != nil {
+		return err
+	}
+
 	// All tmpfs mounts and /dev were previously mounted as rw
 	// by mountPropagate. Remount them read-only as requested.
 	for _, m := range config.Mounts {
@@ -255,12 +351,18 @@ func mountCgroupV1(m *configs.Mount, c *mountConfig) error {
 	if err != nil {
 		return err
 	}
-	var merged []string
+	var (
+		merged     []string
+		hasSystemd bool
+	)
 	for _, b := range binds {
 		ss := filepath.Base(b.Destination)
 		if strings.Contains(ss, ",") {
 			merged = append(merged, ss)
 		}
+		if ss == "systemd" {
+			hasSystemd = true
+		}
 	}
 	tmpfs := &configs.Mount{
 		Source:           "tmpfs",
@@ -304,6 +406,14 @@ func mountCgroupV1(m *configs.Mount, c *mountConfig) error {
 			}
 		}
 	}
+	if c.systemdInit && !hasSystemd {
+		// systemd running as the container init requires the name=systemd
+		// hierarchy, which the host does not have (e.g. it is not running
+		// systemd itself). Mount a new instance of it.
+		if err := mountSystemdHierarchy(m, c); err != nil {
+			return err
+		}
+	}
 	for _, mc := range merged {
 		for _, ss := range strings.Split(mc, ",") {
 			// symlink(2) is very dumb, it will just shove the path into
@@ -317,6 +427,22 @@ func mountCgroupV1(m *configs.Mount, c *mountConfig) error {
 	return nil
 }
 
+// mountSystemdHierarchy mounts the named "name=systemd" cgroup v1 hierarchy
+// under the cgroup mount destination.
+func mountSystemdHierarchy(m *configs.Mount, c *mountConfig) error {
+	dest := filepath.Join(m.Destination, "systemd")
+	if err := os.MkdirAll(filepath.Join(c.root, dest), 0o755); err != nil {
+		return err
+	}
+	return utils.WithProcfd(c.root, dest, func(dstFD string) error {
+		flags := defaultMountFlags
+		if m.Flags&unix.MS_RDONLY != 0 {
+			flags |= unix.MS_RDONLY
+		}
+		return mountViaFDs("systemd", nil, dest, dstFD, "cgroup", uintptr(flags), "none,"+cgroups.CgroupNamePrefix+"systemd")
+	})
+}
+
 func mountCgroupV2(m *configs.Mount, c *mountConfig) error {
 	dest, err := securejoin.SecureJoin(c.root, m.Destination)
 	if err != nil {
@@ -452,9 +578,80 @@ func statfsToMountFlags(st unix.Statfs_t) int {
 	return flags
 }
 
+// isHostSysBind tells whether m is a bind mount of the host /sys on /sys,
+// such as the one used for rootless containers (see specconv.ToRootless).
+func isHostSysBind(m *configs.Mount) bool {
+	return m.IsBind() && !m.IsIDMapped() &&
+		utils.CleanPath(m.Source) == "/sys" && utils.CleanPath(m.Destination) == "/sys"
+}
+
+// mountFreshSysfs mounts sysfs in place of the bind mount of the host /sys
+// m, so that /sys/class/net shows the network devices of the container
+// network namespace rather than the host ones. The mount flags of m (such
+// as MS_RDONLY) are kept.
+func mountFreshSysfs(c *mountConfig, m mountEntry) error {
+	sysfs := *m.Mount
+	sysfs.Source = "sysfs"
+	sysfs.Device = "sysfs"
+	sysfs.Flags &^= unix.MS_BIND | unix.MS_REC
+	sysfs.ClearedFlags = 0
+	sysfs.Extensions = 0
+	return mountToRootfs(c, mountEntry{Mount: &sysfs})
+}
+
+// openMountSource opens the source of the bind mount m (unless it is
+// already open), and checks that it is allowed (see
+// configs.Config.AllowedMountSources). The opened file is the one mounted,
+// so that the source can not be replaced (by a symbolic link, say) after the
+// check. The returned function closes the file.
+func openMountSource(c *mountConfig, m *mountEntry) (func(), error) {
+	closeSrc := func() {}
+	if len(c.allowedSources) == 0 || !m.IsBind() {
+		return closeSrc, nil
+	}
+	// An idmapped mount source is a detached mount tree, whose path can
+	// not be checked. These are rejected by the config validation.
+	if m.IsIDMapped() {
+		return nil, fmt.Errorf("mount source %s: idmapped mounts are not allowed with allowed mount sources set", m.Source)
+	}
+	if m.srcFD == nil {
+		fd, err := unix.Open(m.Source, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: m.Source, Err: err}
+		}
+		m.srcFD = &fd
+		closeSrc = func() { _ = unix.Close(fd) }
+	}
+	source, err := os.Readlink(m.src())
+	if err == nil {
+		err = validate.CheckMountSource(c.allowedSources, source)
+	}
+	if err != nil {
+		closeSrc()
+		return nil, fmt.Errorf("mount source %s: %w", m.Source, err)
+	}
+	return closeSrc, nil
+}
+
 func mountToRootfs(c *mountConfig, m mountEntry) error {
 	rootfs := c.root
 
+	closeSrc, err := openMountSource(c, &m)
+	if err != nil {
+		return err
+	}
+	defer closeSrc()
+
+	if c.freshSysfs && isHostSysBind(m.Mount) {
+		err := mountFreshSysfs(c, m)
+		if err == nil || !errors.Is(err, unix.EPERM) {
+			return err
+		}
+		// Such as a network namespace not owned by the container user
+		// namespace; keep the bind mount.
+		logrus.Debugf("unable to mount a fresh sysfs, bind mounting /sys instead: %v", err)
+	}
+
 	// procfs and sysfs are special because we need to ensure they are actually
 	// mounted on a specific path in a container without any funny business.
 	switch m.Device {
@@ -662,6 +859,9 @@ func mountToRootfs(c *mountConfig, m mountEntry) error {
 			return mountCgroupV2(m.Mount, c)
 		}
 		return mountCgroupV1(m.Mount, c)
+	case "9p":
+		m.Data = default9pData(m.Data)
+		fallthrough
 	default:
 		if err := checkProcMount(rootfs, dest, m.Source); err != nil {
 			return err
@@ -673,6 +873,29 @@ func mountToRootfs(c *mountConfig, m mountEntry) error {
 	}
 }
 
+// default9pData returns the 9p mount data with the defaults for a mount of
+// a directory shared by the VM host (the source being its tag) added: the
+// virtio transport, and the 9P2000.L protocol (without which the file
+// ownership and modes are not those of the host).
+func default9pData(data string) string {
+	var trans, version bool
+	for _, o := range strings.Split(data, ",") {
+		trans = trans || strings.HasPrefix(o, "trans=")
+		version = version || strings.HasPrefix(o, "version=")
+	}
+	var opts []string
+	if data != "" {
+		opts = append(opts, data)
+	}
+	if !trans {
+		opts = append(opts, "trans=virtio")
+	}
+	if !version {
+		opts = append(opts, "version=9p2000.L")
+	}
+	return strings.Join(opts, ",")
+}
+
 func getCgroupMounts(m *configs.Mount) ([]*configs.Mount, error) {
 	mounts, err := cgroups.GetCgroupMounts(false)
 	if err != nil {
@@ -981,7 +1204,7 @@ func prepareRoot(config *configs.Config) error {
 		return err
 	}
 
-	return mount(config.Rootfs, config.Rootfs, "bind", unix.MS_BIND|unix.MS_REC, "")
+	return bindMountDetached(config.Rootfs, nil, config.Rootfs, "", unix.MS_BIND|unix.MS_REC)
 }
 
 func setReadonly() error {
@@ -1252,6 +1475,12 @@ func mountPropagate(m mountEntry, rootfs string, mountLabel string) error {
 	// inside the container with WithProcfd() -- mounting through a procfd
 	// mounts on the target.
 	if err := utils.WithProcfd(rootfs, m.Destination, func(dstFD string) error {
+		if m.Device == "bind" {
+			if m.IsSourceFd() {
+				return moveMountFd(m.Source, *m.srcFD, m.Destination, dstFD, uintptr(flags))
+			}
+			return bindMountDetached(m.Source, m.srcFD, m.Destination, dstFD, uintptr(flags))
+		}
 		return mountViaFDs(m.Source, m.srcFD, m.Destination, dstFD, m.Device, uintptr(flags), data)
 	}); err != nil {
 		return err
@@ -1,8 +1,12 @@
 package libcontainer
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
@@ -46,6 +50,29 @@ func TestCheckMountDestNsLastPid(t *testing.T) {
 	}
 }
 
+func TestIsHostSysBind(t *testing.T) {
+	for _, tc := range []struct {
+		m        configs.Mount
+		expected bool
+	}{
+		{
+			m:        configs.Mount{Source: "/sys", Destination: "/sys", Device: "bind", Flags: unix.MS_BIND | unix.MS_REC | unix.MS_RDONLY},
+			expected: true,
+		},
+		{
+			m:        configs.Mount{Source: "/sys/", Destination: "//sys", Device: "bind", Flags: unix.MS_BIND},
+			expected: true,
+		},
+		{m: configs.Mount{Source: "sysfs", Destination: "/sys", Device: "sysfs"}},
+		{m: configs.Mount{Source: "/sys/fs/cgroup", Destination: "/sys", Device: "bind", Flags: unix.MS_BIND}},
+		{m: configs.Mount{Source: "/sys", Destination: "/host/sys", Device: "bind", Flags: unix.MS_BIND}},
+	} {
+		if got := isHostSysBind(&tc.m); got != tc.expected {
+			t.Errorf("%+v: expected %v, got %v", tc.m, tc.expected, got)
+		}
+	}
+}
+
 func TestNeedsSetupDev(t *testing.T) {
 	config := &configs.Config{
 		Mounts: []*configs.Mount{
@@ -105,3 +132,57 @@ func TestNeedsSetupDevStrangeSourceDest(t *testing.T) {
 		t.Fatal("expected needsSetupDev to be true, got false")
 	}
 }
+
+func TestDefault9pData(t *testing.T) {
+	for _, tc := range []struct{ in, out string }{
+		{"", "trans=virtio,version=9p2000.L"},
+		{"msize=262144", "msize=262144,trans=virtio,version=9p2000.L"},
+		{"trans=virtio,version=9p2000.u", "trans=virtio,version=9p2000.u"},
+		{"trans=tcp,port=564", "trans=tcp,port=564,version=9p2000.L"},
+	} {
+		if out := default9pData(tc.in); out != tc.out {
+			t.Errorf("default9pData(%q): expected %q, got %q", tc.in, tc.out, out)
+		}
+	}
+}
+
+func TestOpenMountSource(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	other := filepath.Join(dir, "other")
+	for _, d := range []string{allowed, other} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &mountConfig{allowedSources: []string{allowed}}
+	bind := func(source string) mountEntry {
+		return mountEntry{Mount: &configs.Mount{Source: source, Destination: "/mnt", Device: "bind", Flags: unix.MS_BIND}}
+	}
+
+	m := bind(allowed)
+	closeSrc, err := openMountSource(c, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.srcFD == nil {
+		t.Fatal("expected the source to be opened")
+	}
+	closeSrc()
+
+	// The source, checked by the config validation, was replaced by a
+	// symbolic link out of the allowed directory in the meantime.
+	source := filepath.Join(allowed, "source")
+	if err := os.Symlink(other, source); err != nil {
+		t.Fatal(err)
+	}
+	m = bind(source)
+	if _, err := openMountSource(c, &m); err == nil {
+		t.Error("expected an error")
+	}
+
+	m = bind(other)
+	if _, err := openMountSource(&mountConfig{}, &m); err != nil || m.srcFD != nil {
+		t.Errorf("expected no check without allowed sources, got %v", err)
+	}
+}
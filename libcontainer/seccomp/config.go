@@ -77,7 +77,7 @@ var archs = map[string]string{
 }
 
 // KnownArchs returns the list of the known archs.
-// Used by `runc features`.
+// See SupportedArchs for the ones which can be used.
 func KnownArchs() []string {
 	var res []string
 	for k := range archs {
@@ -258,16 +258,22 @@ func findLastSyscalls(config *configs.Seccomp) (lastSyscallMap, error) {
 
 		// Find the largest syscall in the filter for this architecture.
 		var largestSyscall libseccomp.ScmpSyscall
+		var unknown []string
 		for _, rule := range config.Syscalls {
 			sysno, err := libseccomp.GetSyscallFromNameByArch(rule.Name, arch)
 			if err != nil {
-				// Ignore unknown syscalls.
+				// Ignore unknown syscalls (such as the legacy ones which
+				// the newer architectures, like riscv64, do not have).
+				unknown = append(unknown, rule.Name)
 				continue
 			}
 			if sysno > largestSyscall {
 				largestSyscall = sysno
 			}
 		}
+		if len(unknown) > 0 {
+			logrus.Debugf("seccomp: syscalls unknown to libseccomp for arch %s, which get -ENOSYS if the kernel has them: %v", ociArch, unknown)
+		}
 		if largestSyscall != 0 {
 			lastSyscalls[nativeArch][arch] = largestSyscall
 		} else {
@@ -691,18 +697,34 @@ func sysSeccompSetFilter(flags uint, filter []unix.SockFilter) (fd int, err erro
 // default libseccomp default action behaviour, and loads the patched filter
 // into the kernel for the current process.
 func PatchAndLoad(config *configs.Seccomp, filter *libseccomp.ScmpFilter) (*os.File, error) {
+	fprog, seccompFlags, noNewPrivs, err := Patch(config, filter)
+	if err != nil {
+		return nil, err
+	}
+	return Load(fprog, seccompFlags, noNewPrivs)
+}
+
+// Patch is the first half of PatchAndLoad: it returns the patched filter,
+// along with the seccomp(2) flags and the no_new_privs bit to load it with,
+// so that it can be loaded later (and more than once) by Load.
+func Patch(config *configs.Seccomp, filter *libseccomp.ScmpFilter) (fprog []unix.SockFilter, seccompFlags uint, noNewPrivs bool, err error) {
 	// Generate a patched filter.
-	fprog, err := enosysPatchFilter(config, filter)
+	fprog, err = enosysPatchFilter(config, filter)
 	if err != nil {
-		return nil, fmt.Errorf("error patching filter: %w", err)
+		return nil, 0, false, fmt.Errorf("error patching filter: %w", err)
 	}
 
 	// Get the set of libseccomp flags set.
-	seccompFlags, noNewPrivs, err := filterFlags(config, filter)
+	seccompFlags, noNewPrivs, err = filterFlags(config, filter)
 	if err != nil {
-		return nil, fmt.Errorf("unable to fetch seccomp filter flags: %w", err)
+		return nil, 0, false, fmt.Errorf("unable to fetch seccomp filter flags: %w", err)
 	}
+	return fprog, seccompFlags, noNewPrivs, nil
+}
 
+// Load is the second half of PatchAndLoad: it loads a filter returned by
+// Patch into the kernel for the current process.
+func Load(fprog []unix.SockFilter, seccompFlags uint, noNewPrivs bool) (*os.File, error) {
 	// Set no_new_privs if it was requested, though in runc we handle
 	// no_new_privs separately so warn if we hit this path.
 	if noNewPrivs {
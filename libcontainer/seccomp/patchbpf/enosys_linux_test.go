@@ -87,7 +87,8 @@ func fakeConfig(defaultAction configs.Action, explicitSyscalls []string, arches
 	return &config
 }
 
-// List copied from <libcontainer/seccomp/config.go>.
+// List copied from <libcontainer/seccomp/config.go>, less the ones in
+// versionedTestArches.
 var testArches = []string{
 	"x86",
 	"amd64",
@@ -107,6 +108,57 @@ var testArches = []string{
 	"s390x",
 }
 
+// versionedTestArches are the architectures added after libseccomp 2.4, along
+// with the first libseccomp version supporting them (see archVersions in
+// <libcontainer/seccomp/seccomp_linux.go>). They are added to testArches if
+// both libseccomp and libseccomp-golang support them.
+var versionedTestArches = []struct {
+	arch                string
+	major, minor, micro uint
+}{
+	{"riscv64", 2, 5, 0},
+}
+
+func versionedArchSupported(arch string, major, minor, micro uint) bool {
+	if _, err := libseccomp.GetArchFromString(arch); err != nil {
+		return false
+	}
+	haveMajor, haveMinor, haveMicro := libseccomp.GetLibraryVersion()
+	if haveMajor != major {
+		return haveMajor > major
+	}
+	if haveMinor != minor {
+		return haveMinor > minor
+	}
+	return haveMicro >= micro
+}
+
+func init() {
+	for _, a := range versionedTestArches {
+		if versionedArchSupported(a.arch, a.major, a.minor, a.micro) {
+			testArches = append(testArches, a.arch)
+		}
+	}
+}
+
+func TestArchToNativeVersioned(t *testing.T) {
+	for _, a := range versionedTestArches {
+		a := a
+		t.Run("arch="+a.arch, func(t *testing.T) {
+			if !versionedArchSupported(a.arch, a.major, a.minor, a.micro) {
+				t.Skipf("%s requires libseccomp %d.%d.%d and a libseccomp-golang knowing about it", a.arch, a.major, a.minor, a.micro)
+			}
+			scmpArch, err := libseccomp.GetArchFromString(a.arch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := archToNative(scmpArch); err != nil {
+				t.Fatalf("libseccomp supports %s, but the enosys stub does not: %v", a.arch, err)
+			}
+		})
+	}
+}
+
 func testEnosysStub(t *testing.T, defaultAction configs.Action, arches []string) {
 	explicitSyscalls := []string{
 		"setns",
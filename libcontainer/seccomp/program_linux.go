@@ -0,0 +1,16 @@
+package seccomp
+
+import "golang.org/x/sys/unix"
+
+// Program is a seccomp filter compiled by Compile, to be installed by
+// LoadProgram. It is used to compile a filter once in the runtime, and
+// install it into many containers.
+type Program struct {
+	// Filter is the BPF program.
+	Filter []unix.SockFilter `json:"filter"`
+	// Flags are the seccomp(2) flags to install the filter with.
+	Flags uint `json:"flags,omitempty"`
+	// NoNewPrivs tells whether to set no_new_privs before installing the
+	// filter.
+	NoNewPrivs bool `json:"no_new_privs,omitempty"`
+}
@@ -0,0 +1,24 @@
+//go:build linux && (!cgo || !seccomp)
+// +build linux
+// +build !cgo !seccomp
+
+package seccomp
+
+import (
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// Compile does nothing because seccomp is not supported.
+func Compile(config *configs.Seccomp) (*Program, error) {
+	if config != nil {
+		return nil, ErrSeccompNotEnabled
+	}
+	return nil, nil
+}
+
+// LoadProgram does nothing because seccomp is not supported.
+func LoadProgram(_ *Program) (*os.File, error) {
+	return nil, ErrSeccompNotEnabled
+}
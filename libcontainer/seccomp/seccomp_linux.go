@@ -31,6 +31,46 @@ const (
 // specified in config. Returns the seccomp file descriptor if any of the
 // filters include a SCMP_ACT_NOTIFY action.
 func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
+	filter, err := newFilter(config)
+	if err != nil {
+		return nil, err
+	}
+	seccompFd, err := patchbpf.PatchAndLoad(config, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error loading seccomp filter into kernel: %w", err)
+	}
+	return seccompFd, nil
+}
+
+// Compile compiles the seccomp filters specified in config, the same as
+// InitSeccomp does, but returns them as a program to be loaded (possibly
+// into many processes) by LoadProgram, rather than installing them.
+func Compile(config *configs.Seccomp) (*Program, error) {
+	filter, err := newFilter(config)
+	if err != nil {
+		return nil, err
+	}
+	defer filter.Release()
+	fprog, flags, noNewPrivs, err := patchbpf.Patch(config, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{Filter: fprog, Flags: flags, NoNewPrivs: noNewPrivs}, nil
+}
+
+// LoadProgram installs the seccomp filters compiled by Compile. Returns the
+// seccomp file descriptor if any of the filters include a SCMP_ACT_NOTIFY
+// action.
+func LoadProgram(p *Program) (*os.File, error) {
+	seccompFd, err := patchbpf.Load(p.Filter, p.Flags, p.NoNewPrivs)
+	if err != nil {
+		return nil, fmt.Errorf("error loading seccomp filter into kernel: %w", err)
+	}
+	return seccompFd, nil
+}
+
+// newFilter creates the libseccomp filter specified in config.
+func newFilter(config *configs.Seccomp) (*libseccomp.ScmpFilter, error) {
 	if config == nil {
 		return nil, errors.New("cannot initialize Seccomp - nil config passed")
 	}
@@ -78,7 +118,7 @@ func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
 
 	// Add extra architectures
 	for _, arch := range config.Architectures {
-		scmpArch, err := libseccomp.GetArchFromString(arch)
+		scmpArch, err := getArch(arch)
 		if err != nil {
 			return nil, fmt.Errorf("error validating Seccomp architecture: %w", err)
 		}
@@ -124,11 +164,7 @@ func InitSeccomp(config *configs.Seccomp) (*os.File, error) {
 		}
 	}
 
-	seccompFd, err := patchbpf.PatchAndLoad(config, filter)
-	if err != nil {
-		return nil, fmt.Errorf("error loading seccomp filter into kernel: %w", err)
-	}
-	return seccompFd, nil
+	return filter, nil
 }
 
 type unknownFlagError struct {
@@ -341,6 +377,42 @@ func matchCall(filter *libseccomp.ScmpFilter, call *configs.Syscall, defAct libs
 	return nil
 }
 
+// archVersions are the first libseccomp versions supporting the
+// architectures added after libseccomp 2.4.
+var archVersions = map[string][3]uint{
+	"riscv64": {2, 5, 0},
+}
+
+// getArch converts an arch (as returned by ConvertStringToArch) to the
+// libseccomp one, checking that both libseccomp-golang and libseccomp
+// support it.
+func getArch(arch string) (libseccomp.ScmpArch, error) {
+	scmpArch, err := libseccomp.GetArchFromString(arch)
+	if err != nil {
+		return scmpArch, fmt.Errorf("architecture %s is not supported by the libseccomp-golang version runc was built with: %w", arch, err)
+	}
+	if v, ok := archVersions[arch]; ok {
+		major, minor, micro := Version()
+		if major < v[0] || (major == v[0] && (minor < v[1] || (minor == v[1] && micro < v[2]))) {
+			return scmpArch, fmt.Errorf("architecture %s requires libseccomp %d.%d.%d or later (have %d.%d.%d)", arch, v[0], v[1], v[2], major, minor, micro)
+		}
+	}
+	return scmpArch, nil
+}
+
+// SupportedArchs returns the list of the known archs which are supported by
+// libseccomp (and by libseccomp-golang).
+// Used by `runc features`.
+func SupportedArchs() []string {
+	var res []string
+	for _, a := range KnownArchs() {
+		if _, err := getArch(archs[a]); err == nil {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
 // Version returns major, minor, and micro.
 func Version() (uint, uint, uint) {
 	return libseccomp.GetLibraryVersion()
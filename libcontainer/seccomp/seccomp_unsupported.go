@@ -26,6 +26,11 @@ func FlagSupported(_ specs.LinuxSeccompFlag) error {
 	return ErrSeccompNotEnabled
 }
 
+// SupportedArchs returns nil because seccomp is not supported.
+func SupportedArchs() []string {
+	return nil
+}
+
 // Version returns major, minor, and micro.
 func Version() (uint, uint, uint) {
 	return 0, 0, 0
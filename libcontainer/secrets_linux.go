@@ -0,0 +1,110 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkSecrets checks the secrets to inject into a process.
+func (c *Container) checkSecrets(secrets []*Secret) error {
+	names := make(map[string]bool, len(secrets))
+	for _, s := range secrets {
+		if s.Name == "" || strings.IndexByte(s.Name, 0) >= 0 {
+			return fmt.Errorf("invalid secret name %q", s.Name)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("secret %s: duplicate name", s.Name)
+		}
+		names[s.Name] = true
+		if s.File == nil {
+			return fmt.Errorf("secret %s: no file to read the secret from", s.Name)
+		}
+		switch s.Type {
+		case SecretKeyring:
+			// Without a new session keyring, the key would be added to
+			// the keyring of the runc caller.
+			if c.config.NoNewKeyring {
+				return fmt.Errorf("secret %s: keyring secrets require a new session keyring", s.Name)
+			}
+		case SecretMemfd:
+			if s.Env == "" || strings.ContainsAny(s.Env, "=\x00") {
+				return fmt.Errorf("secret %s: invalid environment variable name %q", s.Name, s.Env)
+			}
+		default:
+			return fmt.Errorf("secret %s: unknown type %q", s.Name, s.Type)
+		}
+	}
+	return nil
+}
+
+// injectSecrets reads the secrets and injects them into the current process
+// (runc init), which has joined the container session keyring already.
+func injectSecrets(secrets []*Secret) error {
+	for _, s := range secrets {
+		if err := injectSecret(s); err != nil {
+			return fmt.Errorf("unable to inject secret %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+func injectSecret(s *Secret) error {
+	data, err := io.ReadAll(s.File)
+	_ = s.File.Close()
+	if err != nil {
+		return err
+	}
+	// Do not leave the secret around in the runc init memory.
+	defer func() {
+		for i := range data {
+			data[i] = 0
+		}
+	}()
+
+	switch s.Type {
+	case SecretKeyring:
+		_, err = unix.AddKey("user", s.Name, data, unix.KEY_SPEC_SESSION_KEYRING)
+		return err
+	case SecretMemfd:
+		// The fd is made inheritable by finalizeNamespace, as the fds
+		// above the passed ones are made close-on-exec there.
+		fd, err := unix.MemfdCreate(s.Name, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+		if err != nil {
+			return os.NewSyscallError("memfd_create", err)
+		}
+		s.memfd = fd
+		for b := data; len(b) > 0; {
+			n, err := unix.Write(fd, b)
+			if err != nil {
+				return os.NewSyscallError("write", err)
+			}
+			b = b[n:]
+		}
+		// The secret can not be changed by the container process.
+		const seals = unix.F_SEAL_SEAL | unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE
+		if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, seals); err != nil {
+			return os.NewSyscallError("fcntl(F_ADD_SEALS)", err)
+		}
+		return os.Setenv(s.Env, "/proc/self/fd/"+strconv.Itoa(fd))
+	}
+	return errors.New("unknown secret type " + string(s.Type))
+}
+
+// inheritSecrets makes the memfd secrets inherited by the container process.
+func inheritSecrets(secrets []*Secret) error {
+	for _, s := range secrets {
+		if s.Type != SecretMemfd || s.memfd == 0 {
+			continue
+		}
+		if _, err := unix.FcntlInt(uintptr(s.memfd), unix.F_SETFD, 0); err != nil {
+			return fmt.Errorf("unable to pass secret %s: %w", s.Name, os.NewSyscallError("fcntl(F_SETFD)", err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package libcontainer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestCheckSecrets(t *testing.T) {
+	f, err := os.Open("/dev/null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c := &Container{config: &configs.Config{}}
+	ok := [][]*Secret{
+		nil,
+		{{Name: "a", Type: SecretKeyring, File: f}, {Name: "b", Type: SecretMemfd, Env: "B", File: f}},
+	}
+	for _, secrets := range ok {
+		if err := c.checkSecrets(secrets); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}
+	bad := [][]*Secret{
+		{{Name: "", Type: SecretKeyring, File: f}},
+		{{Name: "a", Type: SecretKeyring}},
+		{{Name: "a", Type: "tmpfs", File: f}},
+		{{Name: "a", Type: SecretMemfd, File: f}},
+		{{Name: "a", Type: SecretMemfd, Env: "A=B", File: f}},
+		{{Name: "a", Type: SecretKeyring, File: f}, {Name: "a", Type: SecretMemfd, Env: "A", File: f}},
+	}
+	for _, secrets := range bad {
+		if err := c.checkSecrets(secrets); err == nil {
+			t.Errorf("%+v: expected error, got nil", secrets[0])
+		}
+	}
+
+	c.config.NoNewKeyring = true
+	if err := c.checkSecrets(ok[1]); err == nil {
+		t.Error("keyring secret with NoNewKeyring: expected error, got nil")
+	}
+}
+
+func TestInjectMemfdSecret(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString("s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	const env = "TEST_INJECT_MEMFD_SECRET"
+	defer os.Unsetenv(env)
+	s := &Secret{Name: "test", Type: SecretMemfd, Env: env, File: r}
+	if err := injectSecrets([]*Secret{s}); err != nil {
+		t.Fatal(err)
+	}
+	defer os.NewFile(uintptr(s.memfd), "memfd").Close()
+
+	path := os.Getenv(env)
+	if !strings.HasPrefix(path, "/proc/self/fd/") {
+		t.Fatalf("expected %s to be a /proc/self/fd path, got %q", env, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "s3cr3t" {
+		t.Fatalf("expected secret %q, got %q", "s3cr3t", data)
+	}
+	// The secret is sealed.
+	if err := os.WriteFile(path, []byte("changed"), 0); err == nil {
+		t.Fatal("expected error writing the sealed secret, got nil")
+	}
+}
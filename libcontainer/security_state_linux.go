@@ -0,0 +1,184 @@
+package libcontainer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/selinux/go-selinux"
+
+	"github.com/opencontainers/runc/libcontainer/apparmor"
+	"github.com/opencontainers/runc/libcontainer/capabilities"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// The seccomp modes, as reported in SecurityState.Seccomp.
+const (
+	SeccompDisabled = "disabled"
+	SeccompStrict   = "strict"
+	SeccompFilter   = "filter"
+)
+
+// SecurityState is the security state of the container init process, as
+// seen by the kernel (rather than as requested by the configuration).
+type SecurityState struct {
+	// Capabilities are the capability sets of the process.
+	Capabilities *configs.Capabilities `json:"capabilities"`
+	// NoNewPrivileges is the no_new_privs flag of the process.
+	NoNewPrivileges bool `json:"no_new_privileges"`
+	// Seccomp is the seccomp mode of the process (SeccompDisabled,
+	// SeccompStrict or SeccompFilter).
+	Seccomp string `json:"seccomp"`
+	// SeccompFilters is the number of seccomp filters attached to the
+	// process, or -1 if unknown (Linux < 5.9).
+	SeccompFilters int `json:"seccomp_filters"`
+	// AppArmorProfile is the AppArmor profile confining the process, if
+	// AppArmor is enabled.
+	AppArmorProfile string `json:"apparmor_profile,omitempty"`
+	// SELinuxLabel is the SELinux label of the process, if SELinux is
+	// enabled.
+	SELinuxLabel string `json:"selinux_label,omitempty"`
+	// NotYetApplied is set for a created container, whose init process
+	// has not executed the container process yet, so the protections
+	// requested by the container configuration are not all in effect yet
+	// (and Drift is not set).
+	NotYetApplied bool `json:"not_yet_applied,omitempty"`
+	// Drift lists the protections requested by the container
+	// configuration, but not in effect.
+	Drift []string `json:"drift,omitempty"`
+}
+
+// SecurityState returns the security state of the container init process,
+// and checks it against the container configuration (unless the container
+// is only created).
+func (c *Container) SecurityState() (*SecurityState, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if !c.hasInit() {
+		return nil, ErrNotRunning
+	}
+	pid := c.initProcess.pid()
+	s, err := readSecurityState(pid)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the PID was not reused while reading its state.
+	status, err := c.currentStatus()
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case Stopped:
+		return nil, ErrNotRunning
+	case Created:
+		// The seccomp filter, capabilities and LSM labels may only be
+		// applied when the container process is executed.
+		s.NotYetApplied = true
+	default:
+		s.Drift = securityDrift(c.config, s)
+	}
+	return s, nil
+}
+
+func readSecurityState(pid int) (*SecurityState, error) {
+	s := &SecurityState{SeccompFilters: -1}
+	if err := readStatusSecurity(pid, s); err != nil {
+		return nil, err
+	}
+	var err error
+	if s.Capabilities, err = capabilities.Current(pid); err != nil {
+		return nil, fmt.Errorf("unable to get capabilities: %w", err)
+	}
+	if apparmor.IsEnabled() {
+		if s.AppArmorProfile, err = apparmor.ProcessProfile(pid); err != nil {
+			return nil, fmt.Errorf("unable to get AppArmor profile: %w", err)
+		}
+	}
+	if selinux.GetEnabled() {
+		if s.SELinuxLabel, err = selinux.PidLabel(pid); err != nil {
+			return nil, fmt.Errorf("unable to get SELinux label: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// readStatusSecurity fills the seccomp and no_new_privs fields of s from
+// /proc/<pid>/status.
+func readStatusSecurity(pid int, s *SecurityState) error {
+	f, err := os.Open("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "NoNewPrivs":
+			s.NoNewPrivileges = value == "1"
+		case "Seccomp":
+			switch value {
+			case "0":
+				s.Seccomp = SeccompDisabled
+			case "1":
+				s.Seccomp = SeccompStrict
+			case "2":
+				s.Seccomp = SeccompFilter
+			default:
+				s.Seccomp = value
+			}
+		case "Seccomp_filters":
+			if n, err := strconv.Atoi(value); err == nil {
+				s.SeccompFilters = n
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if s.Seccomp == "" {
+		// Kernel built without CONFIG_SECCOMP.
+		s.Seccomp = SeccompDisabled
+	}
+	return nil
+}
+
+// securityDrift returns the protections requested by config, but not in
+// effect according to s. The container process may drop capabilities, or
+// add seccomp filters, itself, so only the missing restrictions are
+// reported.
+func securityDrift(config *configs.Config, s *SecurityState) []string {
+	var drift []string
+	if config.Capabilities != nil {
+		// The bounding set is not changed by execve, unlike the other
+		// sets, so this is the one which can be checked.
+		allowed := make(map[string]bool, len(config.Capabilities.Bounding))
+		for _, c := range config.Capabilities.Bounding {
+			allowed[c] = true
+		}
+		for _, c := range s.Capabilities.Bounding {
+			if !allowed[c] {
+				drift = append(drift, fmt.Sprintf("capability %s is in the bounding set, but not in the configured one", c))
+			}
+		}
+	}
+	if config.NoNewPrivileges && !s.NoNewPrivileges {
+		drift = append(drift, "no_new_privs is requested, but not set")
+	}
+	if config.Seccomp != nil && s.Seccomp != SeccompFilter {
+		drift = append(drift, "seccomp filter is requested, but seccomp mode is "+s.Seccomp)
+	}
+	if config.AppArmorProfile != "" && apparmor.IsEnabled() && s.AppArmorProfile != config.AppArmorProfile {
+		drift = append(drift, fmt.Sprintf("AppArmor profile %s is requested, but the process is confined by %s", config.AppArmorProfile, s.AppArmorProfile))
+	}
+	if config.ProcessLabel != "" && selinux.GetEnabled() && s.SELinuxLabel != config.ProcessLabel {
+		drift = append(drift, fmt.Sprintf("SELinux label %s is requested, but the process label is %s", config.ProcessLabel, s.SELinuxLabel))
+	}
+	return drift
+}
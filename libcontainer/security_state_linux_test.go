@@ -0,0 +1,59 @@
+package libcontainer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestReadStatusSecurity(t *testing.T) {
+	s := &SecurityState{SeccompFilters: -1}
+	if err := readStatusSecurity(os.Getpid(), s); err != nil {
+		t.Fatal(err)
+	}
+	switch s.Seccomp {
+	case SeccompDisabled, SeccompStrict, SeccompFilter:
+	default:
+		t.Errorf("unexpected seccomp mode %q", s.Seccomp)
+	}
+	if s.Seccomp == SeccompDisabled && s.SeccompFilters > 0 {
+		t.Errorf("seccomp is disabled, but %d filters are reported", s.SeccompFilters)
+	}
+}
+
+func TestSecurityDrift(t *testing.T) {
+	state := &SecurityState{
+		Capabilities: &configs.Capabilities{
+			Bounding: []string{"CAP_CHOWN", "CAP_KILL", "CAP_SYS_ADMIN"},
+		},
+		Seccomp:        SeccompDisabled,
+		SeccompFilters: 0,
+	}
+	config := &configs.Config{
+		Capabilities: &configs.Capabilities{
+			Bounding: []string{"CAP_CHOWN", "CAP_KILL", "CAP_NET_RAW"},
+		},
+		NoNewPrivileges: true,
+		Seccomp:         &configs.Seccomp{DefaultAction: configs.Allow},
+	}
+	drift := securityDrift(config, state)
+	if len(drift) != 3 {
+		t.Fatalf("expected 3 drifts, got %q", drift)
+	}
+	for i, want := range []string{"CAP_SYS_ADMIN", "no_new_privs", "seccomp"} {
+		if !strings.Contains(drift[i], want) {
+			t.Errorf("expected drift %d to be about %s, got %q", i, want, drift[i])
+		}
+	}
+
+	// Dropped capabilities and extra restrictions are not a drift.
+	state.Capabilities.Bounding = []string{"CAP_CHOWN"}
+	state.NoNewPrivileges = true
+	state.Seccomp = SeccompFilter
+	state.SeccompFilters = 2
+	if drift := securityDrift(config, state); len(drift) != 0 {
+		t.Errorf("expected no drift, got %q", drift)
+	}
+}
@@ -13,7 +13,6 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer/apparmor"
 	"github.com/opencontainers/runc/libcontainer/keys"
-	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 )
 
@@ -33,6 +32,9 @@ func (l *linuxSetnsInit) getSessionRingName() string {
 }
 
 func (l *linuxSetnsInit) Init() error {
+	if len(l.config.MaskPaths) > 0 {
+		return l.maskPaths()
+	}
 	if !l.config.Config.NoNewKeyring {
 		if err := selinux.SetKeyLabel(l.config.ProcessLabel); err != nil {
 			return err
@@ -49,6 +51,9 @@ func (l *linuxSetnsInit) Init() error {
 			}
 		}
 	}
+	if err := injectSecrets(l.config.Secrets); err != nil {
+		return err
+	}
 	if l.config.CreateConsole {
 		if err := setupConsole(l.consoleSocket, l.config, false); err != nil {
 			return err
@@ -85,7 +90,7 @@ func (l *linuxSetnsInit) Init() error {
 	// do this before dropping capabilities; otherwise do it as late as possible
 	// just before execve so as few syscalls take place after it as possible.
 	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
+		seccompFd, err := initSeccomp(l.config)
 		if err != nil {
 			return err
 		}
@@ -107,20 +112,20 @@ func (l *linuxSetnsInit) Init() error {
 	// Check for the arg early to make sure it exists.
 	name, err := exec.LookPath(l.config.Args[0])
 	if err != nil {
-		return err
+		return classify(err, ErrExec)
 	}
 	// exec.LookPath in Go < 1.20 might return no error for an executable
 	// residing on a file system mounted with noexec flag, so perform this
 	// extra check now while we can still return a proper error.
 	// TODO: remove this once go < 1.20 is not supported.
 	if err := eaccess(name); err != nil {
-		return &os.PathError{Op: "eaccess", Path: name, Err: err}
+		return classify(&os.PathError{Op: "eaccess", Path: name, Err: err}, ErrExec)
 	}
 	// Set seccomp as close to execve as possible, so as few syscalls take
 	// place afterward (reducing the amount of syscalls that users need to
 	// enable in their seccomp profiles).
 	if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
+		seccompFd, err := initSeccomp(l.config)
 		if err != nil {
 			return fmt.Errorf("unable to init seccomp: %w", err)
 		}
@@ -141,3 +146,16 @@ func (l *linuxSetnsInit) Init() error {
 	}
 	return system.Exec(name, l.config.Args, os.Environ())
 }
+
+// maskPaths masks the paths in the container mount namespace, and exits,
+// for Container.MaskPaths.
+func (l *linuxSetnsInit) maskPaths() error {
+	for _, path := range l.config.MaskPaths {
+		if err := maskPath(path, l.config.Config.MountLabel); err != nil {
+			return fmt.Errorf("can't mask path %s: %w", path, err)
+		}
+	}
+	logrus.Debugf("setns_init: masked %v", l.config.MaskPaths)
+	os.Exit(0)
+	return nil
+}
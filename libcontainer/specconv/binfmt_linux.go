@@ -0,0 +1,51 @@
+package specconv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// BinfmtPrivateAnnotation, if "true", mounts a binfmt_misc instance private
+// to the container user namespace, BinfmtRegisterAnnotation is a newline
+// separated list of registration strings to write to it, and
+// BinfmtRequireAnnotation is a comma separated list of binfmt_misc entries
+// (of the private instance, or of the host one) the container requires
+// (see configs.Binfmt).
+const (
+	BinfmtPrivateAnnotation  = "org.opencontainers.runc.binfmt.private"
+	BinfmtRegisterAnnotation = "org.opencontainers.runc.binfmt.register"
+	BinfmtRequireAnnotation  = "org.opencontainers.runc.binfmt.require"
+)
+
+func createBinfmt(spec *specs.Spec) (*configs.Binfmt, error) {
+	b := &configs.Binfmt{}
+	if v, ok := spec.Annotations[BinfmtPrivateAnnotation]; ok {
+		switch v {
+		case "true":
+			b.Private = true
+		case "false":
+		default:
+			return nil, fmt.Errorf("invalid %s annotation value %q: must be true or false", BinfmtPrivateAnnotation, v)
+		}
+	}
+	if v := spec.Annotations[BinfmtRegisterAnnotation]; v != "" {
+		for _, r := range strings.Split(v, "\n") {
+			if r = strings.TrimSpace(r); r != "" {
+				b.Register = append(b.Register, r)
+			}
+		}
+	}
+	if v := spec.Annotations[BinfmtRequireAnnotation]; v != "" {
+		for _, name := range strings.Split(v, ",") {
+			b.Require = append(b.Require, strings.TrimSpace(name))
+		}
+	}
+	if !b.Private && b.Register == nil && b.Require == nil {
+		return nil, nil
+	}
+	return b, nil
+}
@@ -0,0 +1,120 @@
+package specconv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// The block IO device annotations set the per-device weight and throttling
+// limits, like linux.resources.blockIO does, but with the devices given by
+// their host path rather than by their major:minor numbers, which may change
+// (such as when an NVMe disk is replaced). The devices are resolved each
+// time the limits are set (including by runc update).
+//
+// Each value is a comma separated list of PATH:VALUE (see
+// ParseWeightDevice and ParseThrottleDevice).
+const (
+	BlkioWeightDeviceAnnotation    = "org.opencontainers.runc.blkio.weight-device"
+	BlkioReadBpsDeviceAnnotation   = "org.opencontainers.runc.blkio.read-bps-device"
+	BlkioWriteBpsDeviceAnnotation  = "org.opencontainers.runc.blkio.write-bps-device"
+	BlkioReadIOPSDeviceAnnotation  = "org.opencontainers.runc.blkio.read-iops-device"
+	BlkioWriteIOPSDeviceAnnotation = "org.opencontainers.runc.blkio.write-iops-device"
+)
+
+func cutDevicePath(s string) (string, string, error) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid device %q: must be PATH:VALUE", s)
+	}
+	path, val := s[:i], s[i+1:]
+	if !filepath.IsAbs(path) {
+		return "", "", fmt.Errorf("invalid device %q: path must be absolute", s)
+	}
+	return path, val, nil
+}
+
+// ParseWeightDevice parses a PATH:WEIGHT device weight, with the weight
+// from 10 to 1000.
+func ParseWeightDevice(s string) (*configs.WeightDevice, error) {
+	path, val, err := cutDevicePath(s)
+	if err != nil {
+		return nil, err
+	}
+	weight, err := strconv.ParseUint(val, 10, 16)
+	if err != nil || weight < 10 || weight > 1000 {
+		return nil, fmt.Errorf("invalid device weight %q: must be from 10 to 1000", s)
+	}
+	wd := configs.NewWeightDevice(0, 0, uint16(weight), 0)
+	wd.Path = path
+	return wd, nil
+}
+
+// ParseThrottleDevice parses a PATH:RATE device limit. If bytes is true,
+// the rate is in bytes per second, with an optional unit (such as "10m"),
+// otherwise it is in IO operations per second.
+func ParseThrottleDevice(s string, bytes bool) (*configs.ThrottleDevice, error) {
+	path, val, err := cutDevicePath(s)
+	if err != nil {
+		return nil, err
+	}
+	var rate uint64
+	if bytes {
+		var n int64
+		n, err = units.RAMInBytes(val)
+		rate = uint64(n)
+		if n < 0 {
+			err = strconv.ErrRange
+		}
+	} else {
+		rate, err = strconv.ParseUint(val, 10, 64)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid device rate %q: %w", s, err)
+	}
+	td := configs.NewThrottleDevice(0, 0, rate)
+	td.Path = path
+	return td, nil
+}
+
+func createBlkioDevices(spec *specs.Spec, r *configs.Resources) error {
+	list := func(annotation string) []string {
+		v := spec.Annotations[annotation]
+		if v == "" {
+			return nil
+		}
+		return strings.Split(v, ",")
+	}
+	for _, s := range list(BlkioWeightDeviceAnnotation) {
+		wd, err := ParseWeightDevice(s)
+		if err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", BlkioWeightDeviceAnnotation, err)
+		}
+		r.BlkioWeightDevice = append(r.BlkioWeightDevice, wd)
+	}
+	for _, t := range []struct {
+		annotation string
+		bytes      bool
+		dest       *[]*configs.ThrottleDevice
+	}{
+		{BlkioReadBpsDeviceAnnotation, true, &r.BlkioThrottleReadBpsDevice},
+		{BlkioWriteBpsDeviceAnnotation, true, &r.BlkioThrottleWriteBpsDevice},
+		{BlkioReadIOPSDeviceAnnotation, false, &r.BlkioThrottleReadIOPSDevice},
+		{BlkioWriteIOPSDeviceAnnotation, false, &r.BlkioThrottleWriteIOPSDevice},
+	} {
+		for _, s := range list(t.annotation) {
+			td, err := ParseThrottleDevice(s, t.bytes)
+			if err != nil {
+				return fmt.Errorf("invalid %s annotation: %w", t.annotation, err)
+			}
+			*t.dest = append(*t.dest, td)
+		}
+	}
+	return nil
+}
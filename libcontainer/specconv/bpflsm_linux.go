@@ -0,0 +1,25 @@
+package specconv
+
+import (
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// BPFLSMAnnotation is a comma separated list of the paths of pinned BPF LSM
+// programs to attach to the container cgroup (see configs.BPFLSM).
+const BPFLSMAnnotation = "org.opencontainers.runc.bpf-lsm.programs"
+
+func createBPFLSM(spec *specs.Spec) *configs.BPFLSM {
+	v := spec.Annotations[BPFLSMAnnotation]
+	if v == "" {
+		return nil
+	}
+	b := &configs.BPFLSM{}
+	for _, path := range strings.Split(v, ",") {
+		b.Programs = append(b.Programs, strings.TrimSpace(path))
+	}
+	return b
+}
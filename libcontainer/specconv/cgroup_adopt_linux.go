@@ -0,0 +1,81 @@
+package specconv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	libcontainerUtils "github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// CgroupAdoptAnnotation makes runc use an existing cgroup, owned by an
+// external manager, which runc never creates, configures or removes (see
+// configs.Cgroup.Adopted). The value is either "true", to adopt the cgroup
+// from linux.cgroupsPath, or "fd:N", to adopt the cgroup v2 directory
+// opened as file descriptor N (inherited by runc create or run).
+const CgroupAdoptAnnotation = "org.opencontainers.runc.cgroup-adopt"
+
+const cgroup2Mountpoint = "/sys/fs/cgroup"
+
+// adoptedCgroup returns the path of the cgroup to adopt (relative to the
+// cgroup mountpoint), or an empty string if no cgroup is to be adopted, and
+// its directory fd, if it was given by fd (or 0).
+func adoptedCgroup(spec *specs.Spec) (string, int, error) {
+	v := spec.Annotations[CgroupAdoptAnnotation]
+	switch {
+	case v == "" || v == "false":
+		return "", 0, nil
+	case v == "true":
+		if spec.Linux == nil || spec.Linux.CgroupsPath == "" {
+			return "", 0, fmt.Errorf("%s annotation requires linux.cgroupsPath", CgroupAdoptAnnotation)
+		}
+		if strings.Contains(spec.Linux.CgroupsPath, ":") {
+			return "", 0, fmt.Errorf("%s annotation requires linux.cgroupsPath to be a path, got %q", CgroupAdoptAnnotation, spec.Linux.CgroupsPath)
+		}
+		return libcontainerUtils.CleanPath(spec.Linux.CgroupsPath), 0, nil
+	case strings.HasPrefix(v, "fd:"):
+		fd, err := strconv.Atoi(v[3:])
+		if err != nil || fd <= 0 {
+			return "", 0, fmt.Errorf("invalid %s annotation value %q", CgroupAdoptAnnotation, v)
+		}
+		path, err := cgroupPathFromFd(fd)
+		return path, fd, err
+	default:
+		return "", 0, fmt.Errorf("invalid %s annotation value %q", CgroupAdoptAnnotation, v)
+	}
+}
+
+// cgroupPathFromFd returns the path of the cgroup v2 directory opened as fd,
+// relative to the cgroup mountpoint. If the cgroup is not visible in the
+// current mount namespace, the path is only used to identify the cgroup in
+// the container state, as all the operations use the fd.
+func cgroupPathFromFd(fd int) (string, error) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return "", errors.New("adopting a cgroup by file descriptor requires cgroup v2")
+	}
+	var st unix.Statfs_t
+	if err := unix.Fstatfs(fd, &st); err != nil {
+		return "", &os.PathError{Op: "fstatfs", Path: "fd " + strconv.Itoa(fd), Err: err}
+	}
+	if st.Type != unix.CGROUP2_SUPER_MAGIC {
+		return "", fmt.Errorf("fd %d is not a cgroup v2 directory", fd)
+	}
+	path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(fd))
+	if err != nil {
+		return "", err
+	}
+	// The cgroupfs may be mounted elsewhere (or not at all) in the caller's
+	// mount namespace, in which case the path is relative to its root.
+	rel, err := filepath.Rel(cgroup2Mountpoint, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = path
+	}
+	return libcontainerUtils.CleanPath("/" + rel), nil
+}
@@ -0,0 +1,33 @@
+package specconv
+
+import (
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// The CNI annotations make runc attach the container to a CNI network,
+// running the CNI plugins itself (rather than from a hook). Only the
+// network name is required; the other annotations default to the usual
+// CNI locations (see the cni package).
+const (
+	CNINetworkAnnotation = "org.opencontainers.runc.cni.network"
+	CNIConfDirAnnotation = "org.opencontainers.runc.cni.conf-dir"
+	CNIBinDirAnnotation  = "org.opencontainers.runc.cni.bin-dir"
+	CNIIfNameAnnotation  = "org.opencontainers.runc.cni.ifname"
+)
+
+// createCNIConfig returns the CNI configuration requested by the spec
+// annotations, or nil if the container is not to be attached to a CNI
+// network.
+func createCNIConfig(spec *specs.Spec) *configs.CNI {
+	network := spec.Annotations[CNINetworkAnnotation]
+	if network == "" {
+		return nil
+	}
+	return &configs.CNI{
+		Network: network,
+		ConfDir: spec.Annotations[CNIConfDirAnnotation],
+		BinDir:  spec.Annotations[CNIBinDirAnnotation],
+		IfName:  spec.Annotations[CNIIfNameAnnotation],
+	}
+}
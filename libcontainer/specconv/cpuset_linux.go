@@ -0,0 +1,49 @@
+package specconv
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// CpusetAdjustAnnotation is the annotation which, when set to "true", allows
+// some of the CPUs from linux.resources.cpu.cpus to be offline, rather than
+// failing the container creation. See configs.Config.CpusetAdjust.
+const CpusetAdjustAnnotation = "org.opencontainers.runc.cpuset-adjust"
+
+// CpusetAllocAnnotation is the number of exclusive CPUs for runc to allocate
+// to the container (see configs.CpusetAlloc), from the CPUs listed in the
+// file named by CpusetPoolAnnotation (or from all online CPUs, if not set).
+// The CPUs from a single NUMA node are preferred.
+const (
+	CpusetAllocAnnotation = "org.opencontainers.runc.cpuset-alloc"
+	CpusetPoolAnnotation  = "org.opencontainers.runc.cpuset-pool"
+)
+
+func isCpusetAdjust(spec *specs.Spec) (bool, error) {
+	switch v := spec.Annotations[CpusetAdjustAnnotation]; v {
+	case "", "false":
+		return false, nil
+	case "true":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid %s annotation value %q", CpusetAdjustAnnotation, v)
+	}
+}
+
+func createCpusetAlloc(spec *specs.Spec) (*configs.CpusetAlloc, error) {
+	v, ok := spec.Annotations[CpusetAllocAnnotation]
+	if !ok {
+		if _, ok := spec.Annotations[CpusetPoolAnnotation]; ok {
+			return nil, fmt.Errorf("%s annotation requires %s", CpusetPoolAnnotation, CpusetAllocAnnotation)
+		}
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation value %q: %w", CpusetAllocAnnotation, v, err)
+	}
+	return &configs.CpusetAlloc{CPUs: n, Pool: spec.Annotations[CpusetPoolAnnotation]}, nil
+}
@@ -0,0 +1,75 @@
+package specconv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/docker/go-units"
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/quota"
+)
+
+// DiskQuotaAnnotation is the disk space limit of the container writable
+// layer (a size such as "10g"), and DiskQuotaInodesAnnotation is its
+// maximum number of inodes, enforced by a project quota (see
+// configs.DiskQuota). The filesystem (ext4 or xfs) must be mounted with
+// the prjquota option.
+//
+// DiskQuotaPathAnnotation is the host path of the writable layer, such as
+// the upper directory of an overlayfs rootfs (the default is the rootfs),
+// and DiskQuotaProjectAnnotation is the project ID (the default is derived
+// from the container ID, see quota.ProjectID).
+const (
+	DiskQuotaAnnotation        = "org.opencontainers.runc.disk-quota"
+	DiskQuotaInodesAnnotation  = "org.opencontainers.runc.disk-quota-inodes"
+	DiskQuotaPathAnnotation    = "org.opencontainers.runc.disk-quota-path"
+	DiskQuotaProjectAnnotation = "org.opencontainers.runc.disk-quota-project"
+)
+
+func createDiskQuota(spec *specs.Spec, rootfs, id string) (*configs.DiskQuota, error) {
+	size, hasSize := spec.Annotations[DiskQuotaAnnotation]
+	inodes, hasInodes := spec.Annotations[DiskQuotaInodesAnnotation]
+	if !hasSize && !hasInodes {
+		for _, a := range []string{DiskQuotaPathAnnotation, DiskQuotaProjectAnnotation} {
+			if _, ok := spec.Annotations[a]; ok {
+				return nil, fmt.Errorf("%s annotation requires %s or %s", a, DiskQuotaAnnotation, DiskQuotaInodesAnnotation)
+			}
+		}
+		return nil, nil
+	}
+	q := &configs.DiskQuota{
+		Path:      rootfs,
+		ProjectID: quota.ProjectID(id),
+	}
+	if hasSize {
+		n, err := units.RAMInBytes(size)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %s annotation value %q", DiskQuotaAnnotation, size)
+		}
+		q.Size = n
+	}
+	if hasInodes {
+		n, err := strconv.ParseUint(inodes, 10, 64)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid %s annotation value %q", DiskQuotaInodesAnnotation, inodes)
+		}
+		q.Inodes = n
+	}
+	if v, ok := spec.Annotations[DiskQuotaPathAnnotation]; ok {
+		if !filepath.IsAbs(v) {
+			return nil, fmt.Errorf("invalid %s annotation value %q: must be an absolute path", DiskQuotaPathAnnotation, v)
+		}
+		q.Path = filepath.Clean(v)
+	}
+	if v, ok := spec.Annotations[DiskQuotaProjectAnnotation]; ok {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid %s annotation value %q", DiskQuotaProjectAnnotation, v)
+		}
+		q.ProjectID = uint32(n)
+	}
+	return q, nil
+}
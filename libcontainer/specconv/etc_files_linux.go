@@ -0,0 +1,71 @@
+package specconv
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// EtcFilesAnnotation selects (in JSON) the /etc files runc generates for
+// the container, and bind mounts over the ones of the root filesystem,
+// leaving it untouched. For example:
+//
+//	{
+//		"hostname": true,
+//		"hosts": true,
+//		"resolvConf": true,
+//		"extraHosts": [{"address": "192.168.1.20", "names": ["db", "db.example.com"]}],
+//		"nameservers": ["192.168.1.1"],
+//		"search": ["example.com"],
+//		"options": ["ndots:2"]
+//	}
+//
+// /etc/hostname contains the container hostname (from the spec), /etc/hosts
+// the localhost entries, the container hostname and the extra hosts, and
+// /etc/resolv.conf the given nameservers, search domains and options (the
+// host ones being used if no nameservers are given).
+const EtcFilesAnnotation = "org.opencontainers.runc.etc-files"
+
+type etcFiles struct {
+	Hostname   bool `json:"hostname"`
+	Hosts      bool `json:"hosts"`
+	ResolvConf bool `json:"resolvConf"`
+	ExtraHosts []struct {
+		Address string   `json:"address"`
+		Names   []string `json:"names"`
+	} `json:"extraHosts"`
+	Nameservers []string `json:"nameservers"`
+	Search      []string `json:"search"`
+	Options     []string `json:"options"`
+}
+
+// setupEtcFiles sets config.EtcFiles according to the EtcFilesAnnotation
+// annotation, if set.
+func setupEtcFiles(spec *specs.Spec, config *configs.Config) error {
+	v, ok := spec.Annotations[EtcFilesAnnotation]
+	if !ok {
+		return nil
+	}
+	var ef etcFiles
+	if err := json.Unmarshal([]byte(v), &ef); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", EtcFilesAnnotation, err)
+	}
+	e := &configs.EtcFiles{
+		Hostname:    ef.Hostname,
+		Hosts:       ef.Hosts,
+		ResolvConf:  ef.ResolvConf,
+		Nameservers: ef.Nameservers,
+		Search:      ef.Search,
+		Options:     ef.Options,
+	}
+	for _, h := range ef.ExtraHosts {
+		e.ExtraHosts = append(e.ExtraHosts, &configs.EtcHost{
+			Address: h.Address,
+			Names:   h.Names,
+		})
+	}
+	config.EtcFiles = e
+	return nil
+}
@@ -206,7 +206,11 @@ func ToRootless(spec *specs.Spec) {
 	// Fix up mounts.
 	var mounts []specs.Mount
 	for _, mount := range spec.Mounts {
-		// Replace the /sys mount with an rbind.
+		// Replace the /sys mount with an rbind, as sysfs can not be
+		// mounted without a network namespace owned by the container
+		// user namespace. If the container does get its own network
+		// namespace, a fresh sysfs is mounted instead (see
+		// configs.Config.KeepSysBind).
 		if filepath.Clean(mount.Destination) == "/sys" {
 			mounts = append(mounts, specs.Mount{
 				Source:      "/sys",
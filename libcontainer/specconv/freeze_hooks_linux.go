@@ -0,0 +1,61 @@
+package specconv
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// FreezeHooksAnnotation holds the hooks (in JSON) run around the freezing of
+// the container, when it is paused or checkpointed. For example:
+//
+//	{
+//		"preFreeze": [{"path": "/usr/bin/db-flush", "timeout": 30, "inContainer": true}],
+//		"postThaw": [{"path": "/usr/local/bin/reconnect", "args": ["reconnect", "db"]}]
+//	}
+//
+// The hooks have the same fields as the OCI hooks, plus inContainer to run
+// them in the container rather than on the host. The preFreeze hooks are
+// run before the container is frozen, the failure of one of them failing
+// the pause or checkpoint. The postThaw hooks are run once the container
+// is running again, their failures only being logged.
+const FreezeHooksAnnotation = "org.opencontainers.runc.freeze-hooks"
+
+type freezeHook struct {
+	specs.Hook
+	InContainer bool `json:"inContainer"`
+}
+
+type freezeHooks struct {
+	PreFreeze []freezeHook `json:"preFreeze"`
+	PostThaw  []freezeHook `json:"postThaw"`
+}
+
+// createFreezeHooks adds the PreFreeze and PostThaw hooks to config.Hooks
+// according to the FreezeHooksAnnotation annotation, if set.
+func createFreezeHooks(spec *specs.Spec, config *configs.Config) error {
+	v, ok := spec.Annotations[FreezeHooksAnnotation]
+	if !ok {
+		return nil
+	}
+	var fh freezeHooks
+	if err := json.Unmarshal([]byte(v), &fh); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", FreezeHooksAnnotation, err)
+	}
+	for name, hooks := range map[configs.HookName][]freezeHook{
+		configs.PreFreeze: fh.PreFreeze,
+		configs.PostThaw:  fh.PostThaw,
+	} {
+		for _, h := range hooks {
+			if h.Path == "" {
+				return fmt.Errorf("invalid %s annotation: %s hook with no path", FreezeHooksAnnotation, name)
+			}
+			cmd := createCommandHook(h.Hook)
+			cmd.InContainer = h.InContainer
+			config.Hooks[name] = append(config.Hooks[name], configs.NewCommandHook(cmd))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package specconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// NetdevsAnnotation is a comma-separated list of host network interfaces
+// (for example, SR-IOV VFs) to move into the container network namespace,
+// each optionally followed by "=" and the name for it in the container:
+//
+//	"ens1f0v1=eth1,ens1f0v2"
+//
+// The interfaces are returned to the host (under their original names)
+// when the container is deleted.
+const NetdevsAnnotation = "org.opencontainers.runc.netdevs"
+
+// createNetdevs returns the network configurations for the host interfaces
+// listed in the NetdevsAnnotation annotation.
+func createNetdevs(spec *specs.Spec) ([]*configs.Network, error) {
+	v := spec.Annotations[NetdevsAnnotation]
+	if v == "" {
+		return nil, nil
+	}
+	var networks []*configs.Network
+	for _, dev := range strings.Split(v, ",") {
+		host, name, _ := strings.Cut(strings.TrimSpace(dev), "=")
+		if host == "" {
+			return nil, fmt.Errorf("invalid %s annotation value %q", NetdevsAnnotation, v)
+		}
+		networks = append(networks, &configs.Network{
+			Type:              "netdev",
+			HostInterfaceName: host,
+			Name:              name,
+		})
+	}
+	return networks, nil
+}
+
+// InterfacesAnnotation holds a JSON list of macvlan or ipvlan interfaces to
+// create in the container network namespace, on top of host (parent)
+// interfaces. For example:
+//
+//	[{"type": "macvlan", "parent": "eth0", "mode": "bridge", "name": "eth0",
+//	  "address": "192.168.1.10/24", "gateway": "192.168.1.1"}]
+const InterfacesAnnotation = "org.opencontainers.runc.interfaces"
+
+type specInterface struct {
+	Type        string `json:"type"`
+	Parent      string `json:"parent"`
+	Mode        string `json:"mode"`
+	Name        string `json:"name"`
+	MacAddress  string `json:"mac"`
+	Mtu         int    `json:"mtu"`
+	Address     string `json:"address"`
+	Gateway     string `json:"gateway"`
+	IPv6Address string `json:"ipv6Address"`
+	IPv6Gateway string `json:"ipv6Gateway"`
+}
+
+// createInterfaces returns the network configurations for the interfaces
+// listed in the InterfacesAnnotation annotation.
+func createInterfaces(spec *specs.Spec) ([]*configs.Network, error) {
+	v, ok := spec.Annotations[InterfacesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var ifaces []specInterface
+	if err := json.Unmarshal([]byte(v), &ifaces); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", InterfacesAnnotation, err)
+	}
+	networks := make([]*configs.Network, 0, len(ifaces))
+	for _, i := range ifaces {
+		if i.Type != "macvlan" && i.Type != "ipvlan" {
+			return nil, fmt.Errorf("invalid %s annotation: unsupported interface type %q", InterfacesAnnotation, i.Type)
+		}
+		networks = append(networks, &configs.Network{
+			Type:        i.Type,
+			Parent:      i.Parent,
+			Mode:        i.Mode,
+			Name:        i.Name,
+			MacAddress:  i.MacAddress,
+			Mtu:         i.Mtu,
+			Address:     i.Address,
+			Gateway:     i.Gateway,
+			IPv6Address: i.IPv6Address,
+			IPv6Gateway: i.IPv6Gateway,
+		})
+	}
+	return networks, nil
+}
@@ -0,0 +1,45 @@
+package specconv
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// PidsStartLimitAnnotation is a lower pids limit for the container to start
+// with (see configs.PidsStartLimit). The limit is raised to the one from
+// linux.resources.pids.limit (or removed) after the container process has
+// been running for PidsStartSettleAnnotation (a duration such as "500ms",
+// defaults to DefaultPidsStartSettle).
+const (
+	PidsStartLimitAnnotation  = "org.opencontainers.runc.pids-start-limit"
+	PidsStartSettleAnnotation = "org.opencontainers.runc.pids-start-settle"
+)
+
+// DefaultPidsStartSettle is the default value of PidsStartSettleAnnotation.
+const DefaultPidsStartSettle = time.Second
+
+func createPidsStartLimit(spec *specs.Spec) (*configs.PidsStartLimit, error) {
+	v, ok := spec.Annotations[PidsStartLimitAnnotation]
+	if !ok {
+		if _, ok := spec.Annotations[PidsStartSettleAnnotation]; ok {
+			return nil, fmt.Errorf("%s annotation requires %s", PidsStartSettleAnnotation, PidsStartLimitAnnotation)
+		}
+		return nil, nil
+	}
+	limit, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation value %q: %w", PidsStartLimitAnnotation, v, err)
+	}
+	settle := DefaultPidsStartSettle
+	if v, ok := spec.Annotations[PidsStartSettleAnnotation]; ok {
+		settle, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation value %q: %w", PidsStartSettleAnnotation, v, err)
+		}
+	}
+	return &configs.PidsStartLimit{Limit: limit, Settle: settle}, nil
+}
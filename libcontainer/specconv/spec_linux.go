@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +27,38 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// StartContainerHooksAnnotation controls how the startContainer hooks are
+// run. They are always run inside the container (in its namespaces, with
+// the container's root filesystem as the root directory). By default, they
+// are run with the container's seccomp filter, capabilities and user applied
+// (the same as the container process). If the annotation is set to
+// "unconfined", they are run before those restrictions are applied, as
+// the container's root user with the full set of capabilities.
+const StartContainerHooksAnnotation = "org.opencontainers.runc.start-container-hooks"
+
+// HousekeepingCgroupAnnotation is the path of the cgroup (relative to the
+// cgroup v2 mount point) for runc to do the container setup in, so that
+// the setup cost is not charged to the container. See
+// configs.Config.HousekeepingCgroup.
+const HousekeepingCgroupAnnotation = "org.opencontainers.runc.housekeeping-cgroup"
+
+// PropagationCheckAnnotation sets what to do if the propagation of the
+// rootfs or of a mount is not the requested one once the container rootfs
+// is set up: "ignore", "warn" (the default), "repair", or "strict". See
+// configs.Config.PropagationCheck.
+const PropagationCheckAnnotation = "org.opencontainers.runc.propagation-check"
+
+// ConfigDriftCheckAnnotation sets what to do if the bundle config.json was
+// changed since the container was created, when it is started or a process
+// is executed in it: "ignore", "warn" (the default), or "strict". See
+// configs.Config.ConfigDriftCheck.
+const ConfigDriftCheckAnnotation = "org.opencontainers.runc.config-drift-check"
+
+// KeepSysBindAnnotation, when set to "true", keeps a bind mount of the host
+// /sys on /sys as is, even if the container has its own network namespace.
+// See configs.Config.KeepSysBind.
+const KeepSysBindAnnotation = "org.opencontainers.runc.keep-sys-bind"
+
 var (
 	initMapsOnce            sync.Once
 	namespaceMapping        map[specs.LinuxNamespaceType]configs.NamespaceType
@@ -314,6 +347,16 @@ type CreateOpts struct {
 	Spec             *specs.Spec
 	RootlessEUID     bool
 	RootlessCgroups  bool
+	// CgroupFd, if non-zero, is the open file descriptor of an existing
+	// cgroup v2 directory to use as the container cgroup.
+	CgroupFd int
+	// UsernsFd, if non-zero, is the open file descriptor of an existing
+	// user namespace for the container to join. It must stay open until
+	// the container is started.
+	UsernsFd int
+	// AllowedMountSources, if not empty, restricts the bind mount sources
+	// (see configs.Config.AllowedMountSources).
+	AllowedMountSources []string
 }
 
 // getwd is a wrapper similar to os.Getwd, except it always gets
@@ -366,7 +409,38 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		NoNewKeyring:    opts.NoNewKeyring,
 		RootlessEUID:    opts.RootlessEUID,
 		RootlessCgroups: opts.RootlessCgroups,
+		SystemdInit:     IsSystemdInit(spec),
+
+		AllowedMountSources: opts.AllowedMountSources,
+	}
+	switch v := spec.Annotations[StartContainerHooksAnnotation]; v {
+	case "", "confined":
+	case "unconfined":
+		config.UnconfinedStartHooks = true
+	default:
+		return nil, fmt.Errorf("invalid %s annotation value %q", StartContainerHooksAnnotation, v)
+	}
+	config.CNI = createCNIConfig(spec)
+	if config.CpusetAdjust, err = isCpusetAdjust(spec); err != nil {
+		return nil, err
 	}
+	if config.CpusetAlloc, err = createCpusetAlloc(spec); err != nil {
+		return nil, err
+	}
+	config.HousekeepingCgroup = spec.Annotations[HousekeepingCgroupAnnotation]
+	config.PropagationCheck = spec.Annotations[PropagationCheckAnnotation]
+	config.ConfigDriftCheck = spec.Annotations[ConfigDriftCheckAnnotation]
+	config.KeepSysBind = spec.Annotations[KeepSysBindAnnotation] == "true"
+	if config.PidsStartLimit, err = createPidsStartLimit(spec); err != nil {
+		return nil, err
+	}
+	if config.DiskQuota, err = createDiskQuota(spec, config.Rootfs, opts.CgroupName); err != nil {
+		return nil, err
+	}
+	if config.Binfmt, err = createBinfmt(spec); err != nil {
+		return nil, err
+	}
+	config.BPFLSM = createBPFLSM(spec)
 
 	/*填充config.Mounts*/
 	for _, m := range spec.Mounts {
@@ -389,6 +463,9 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 	}
 
 	config.Cgroups = c
+	if err := createTmpfsSizes(spec, config); err != nil {
+		return nil, err
+	}
 	// set linux-specific config
 	if spec.Linux != nil {
 		initMaps()
@@ -420,8 +497,23 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 				},
 			}
 		}
+		netdevs, err := createNetdevs(spec)
+		if err != nil {
+			return nil, err
+		}
+		config.Networks = append(config.Networks, netdevs...)
+		ifaces, err := createInterfaces(spec)
+		if err != nil {
+			return nil, err
+		}
+		config.Networks = append(config.Networks, ifaces...)
+		if opts.UsernsFd != 0 {
+			if err := setUsernsFd(config, opts.UsernsFd); err != nil {
+				return nil, err
+			}
+		}
 		if config.Namespaces.Contains(configs.NEWUSER) {
-			if err := setupUserNamespace(spec, config); err != nil {
+			if err := setupUserNamespace(spec, config, opts.UsernsFd != 0); err != nil {
 				return nil, err
 			}
 		}
@@ -430,6 +522,12 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		config.MountLabel = spec.Linux.MountLabel
 		config.Sysctl = spec.Linux.Sysctl
 		config.TimeOffsets = spec.Linux.TimeOffsets
+		if err := setupStaticNetwork(spec, config); err != nil {
+			return nil, err
+		}
+		if err := setupEtcFiles(spec, config); err != nil {
+			return nil, err
+		}
 		if spec.Linux.Seccomp != nil {
 			seccomp, err := SetupSeccomp(spec.Linux.Seccomp)
 			if err != nil {
@@ -520,6 +618,9 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		}
 	}
 	createHooks(spec, config)
+	if err := createFreezeHooks(spec, config); err != nil {
+		return nil, err
+	}
 	config.Version = specs.Version
 	return config, nil
 }
@@ -556,7 +657,7 @@ func createLibcontainerMount(cwd string, m specs.Mount) (*configs.Mount, error)
 		// bind-mounts -- so we set it to "bind" because rootfs_linux.go
 		// (incorrectly) relies on this for some checks.
 		mnt.Device = "bind"
-		if !filepath.IsAbs(mnt.Source) {
+		if !filepath.IsAbs(mnt.Source) && !mnt.IsSourceFd() {
 			mnt.Source = filepath.Join(cwd, m.Source)
 		}
 	}
@@ -685,6 +786,25 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 		Resources: &configs.Resources{},
 	}
 
+	dirPath, dirFd, err := adoptedCgroup(spec)
+	if err != nil {
+		return nil, err
+	}
+	if dirPath != "" {
+		// An adopted cgroup is never managed by systemd.
+		useSystemdCgroup = false
+		c.Systemd = false
+		c.Adopted = true
+	} else if opts.CgroupFd != 0 {
+		if useSystemdCgroup {
+			return nil, errors.New("cgroup directory fd can not be used with systemd cgroup driver")
+		}
+		dirFd = opts.CgroupFd
+		if dirPath, err = cgroupPathFromFd(dirFd); err != nil {
+			return nil, err
+		}
+	}
+
 	if useSystemdCgroup {
 		sp, err := initSystemdProps(spec)
 		if err != nil {
@@ -723,6 +843,11 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 		}
 		c.Path = myCgroupPath
 	}
+	if dirPath != "" {
+		c.Name = ""
+		c.Path = dirPath
+		c.DirFd = dirFd
+	}
 
 	// In rootless containers, any attempt to make cgroup changes is likely to fail.
 	// libcontainer will validate this but ignores the error.
@@ -730,34 +855,14 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 		r := spec.Linux.Resources
 		if r != nil {
 			for i, d := range r.Devices {
-				var (
-					t     = "a"
-					major = int64(-1)
-					minor = int64(-1)
-				)
-				if d.Type != "" {
-					t = d.Type
-				}
-				if d.Major != nil {
-					major = *d.Major
-				}
-				if d.Minor != nil {
-					minor = *d.Minor
-				}
 				if d.Access == "" {
 					return nil, fmt.Errorf("device access at %d field cannot be empty", i)
 				}
-				dt, err := stringToCgroupDeviceRune(t)
+				rule, err := CreateDeviceRule(d)
 				if err != nil {
 					return nil, err
 				}
-				c.Resources.Devices = append(c.Resources.Devices, &devices.Rule{
-					Type:        dt,
-					Major:       major,
-					Minor:       minor,
-					Permissions: devices.Permissions(d.Access),
-					Allow:       d.Allow,
-				})
+				c.Resources.Devices = append(c.Resources.Devices, rule)
 			}
 			if r.Memory != nil {
 				if r.Memory.Limit != nil {
@@ -886,6 +991,16 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 		}
 	}
 
+	swapMax, err := createSwapMax(spec)
+	if err != nil {
+		return nil, err
+	}
+	c.Resources.SwapMax = swapMax
+
+	if err := createBlkioDevices(spec, c.Resources); err != nil {
+		return nil, err
+	}
+
 	// Append the default allowed devices to the end of the list.
 	for _, device := range defaultDevs {
 		c.Resources.Devices = append(c.Resources.Devices, &device.Rule)
@@ -893,6 +1008,82 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 	return c, nil
 }
 
+// CreateDeviceRule converts a device cgroup rule of the spec.
+func CreateDeviceRule(d specs.LinuxDeviceCgroup) (*devices.Rule, error) {
+	var (
+		t     = "a"
+		major = int64(-1)
+		minor = int64(-1)
+	)
+	if d.Type != "" {
+		t = d.Type
+	}
+	if d.Major != nil {
+		major = *d.Major
+	}
+	if d.Minor != nil {
+		minor = *d.Minor
+	}
+	dt, err := stringToCgroupDeviceRune(t)
+	if err != nil {
+		return nil, err
+	}
+	return &devices.Rule{
+		Type:        dt,
+		Major:       major,
+		Minor:       minor,
+		Permissions: devices.Permissions(d.Access),
+		Allow:       d.Allow,
+	}, nil
+}
+
+// ParseDeviceRule parses a device cgroup rule in the cgroup v1
+// devices.allow format: "TYPE MAJOR:MINOR ACCESS" (such as "c 10:200 rwm",
+// where the major and minor numbers can be "*"), or "a" for all devices.
+func ParseDeviceRule(s string, allow bool) (*devices.Rule, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 1 && fields[0] == "a" {
+		fields = []string{"a", "*:*", "rwm"}
+	}
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid device rule %q: must be TYPE MAJOR:MINOR ACCESS", s)
+	}
+	dt, err := stringToCgroupDeviceRune(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid device rule %q: %w", s, err)
+	}
+	rule := &devices.Rule{
+		Type:        dt,
+		Permissions: devices.Permissions(fields[2]),
+		Allow:       allow,
+	}
+	majorStr, minorStr, ok := strings.Cut(fields[1], ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid device rule %q: must be TYPE MAJOR:MINOR ACCESS", s)
+	}
+	for _, n := range []struct {
+		val  string
+		dest *int64
+	}{
+		{majorStr, &rule.Major},
+		{minorStr, &rule.Minor},
+	} {
+		if n.val == "*" {
+			*n.dest = devices.Wildcard
+			continue
+		}
+		if *n.dest, err = strconv.ParseInt(n.val, 10, 64); err != nil || *n.dest < 0 {
+			return nil, fmt.Errorf("invalid device rule %q: bad device number %q", s, n.val)
+		}
+	}
+	if strings.Trim(fields[2], "rwm") != "" {
+		return nil, fmt.Errorf("invalid device rule %q: access must be a combination of r, w and m", s)
+	}
+	// Normalize the access, such as "mr" to "rm".
+	rule.Permissions = rule.Permissions.Union("")
+	return rule, nil
+}
+
 func stringToCgroupDeviceRune(s string) (devices.Type, error) {
 	switch s {
 	case "a":
@@ -976,7 +1167,30 @@ next:
 	return dedupedAllowDevs, nil
 }
 
-func setupUserNamespace(spec *specs.Spec, config *configs.Config) error {
+// setUsernsFd makes the container join the user namespace opened as fd.
+// Rather than a /proc/<pid>/ns/user path, which may refer to a different
+// namespace by the time it is opened, the namespace is referred to by the
+// fd of the current process, so it can not be swapped.
+func setUsernsFd(config *configs.Config, fd int) error {
+	nsType, err := unix.IoctlRetInt(fd, unix.NS_GET_NSTYPE)
+	if err != nil {
+		return fmt.Errorf("userns fd %d: %w", fd, err)
+	}
+	if nsType != unix.CLONE_NEWUSER {
+		return fmt.Errorf("userns fd %d is not a user namespace", fd)
+	}
+	if config.Namespaces.PathOf(configs.NEWUSER) != "" {
+		return errors.New("userns fd can not be used with a user namespace path")
+	}
+	config.Namespaces.Remove(configs.NEWUSER)
+	config.Namespaces.Add(configs.NEWUSER, "/proc/"+strconv.Itoa(os.Getpid())+"/fd/"+strconv.Itoa(fd))
+	return nil
+}
+
+// setupUserNamespace sets the user namespace mappings. If the namespace is
+// joined by fd (byFd), the mappings from the spec, if any, must be a subset
+// of the namespace mappings.
+func setupUserNamespace(spec *specs.Spec, config *configs.Config, byFd bool) error {
 	if spec.Linux != nil {
 		config.UIDMappings = toConfigIDMap(spec.Linux.UIDMappings)
 		config.GIDMappings = toConfigIDMap(spec.Linux.GIDMappings)
@@ -989,9 +1203,16 @@ func setupUserNamespace(spec *specs.Spec, config *configs.Config) error {
 		if err != nil {
 			return fmt.Errorf("failed to cache mappings for userns: %w", err)
 		}
-		// We cannot allow uid or gid mappings to be set if we are also asked
-		// to join a userns.
-		if config.UIDMappings != nil || config.GIDMappings != nil {
+		if byFd {
+			if !userns.IsSubsetMapping(config.UIDMappings, uidMap) ||
+				!userns.IsSubsetMapping(config.GIDMappings, gidMap) {
+				return fmt.Errorf("requested mappings (uid %v, gid %v) are not provided by the user namespace (uid %v, gid %v)",
+					config.UIDMappings, config.GIDMappings, uidMap, gidMap)
+			}
+		} else if config.UIDMappings != nil || config.GIDMappings != nil {
+			// We cannot allow uid or gid mappings to be set if we are also
+			// asked to join a userns.
+			//
 			// FIXME: It turns out that containerd and CRIO pass both a userns
 			// path and the mappings of the namespace in the same config.json.
 			// Such a configuration is technically not valid, but we used to
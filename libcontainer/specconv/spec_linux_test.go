@@ -2,13 +2,21 @@ package specconv
 
 import (
 	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	dbus "github.com/godbus/dbus/v5"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/opencontainers/runc/libcontainer/quota"
+	"github.com/opencontainers/runc/libcontainer/userns"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
@@ -560,6 +568,42 @@ func TestSpecconvExampleValidate(t *testing.T) {
 	}
 }
 
+func TestStartContainerHooksAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		value      string
+		unconfined bool
+		isErr      bool
+	}{
+		{value: "", unconfined: false},
+		{value: "confined", unconfined: false},
+		{value: "unconfined", unconfined: true},
+		{value: "yes", isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		if tc.value != "" {
+			spec.Annotations = map[string]string{StartContainerHooksAnnotation: tc.value}
+		}
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+			continue
+		}
+		if config.UnconfinedStartHooks != tc.unconfined {
+			t.Errorf("%q: expected UnconfinedStartHooks to be %v", tc.value, tc.unconfined)
+		}
+	}
+}
+
 func TestSpecconvNoLinuxSection(t *testing.T) {
 	spec := Example()
 	spec.Root.Path = "/"
@@ -669,6 +713,33 @@ func TestNonZeroEUIDCompatibleSpecconvValidate(t *testing.T) {
 	}
 }
 
+func TestKeepSysBindAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected bool
+	}{
+		{value: "", expected: false},
+		{value: "false", expected: false},
+		{value: "true", expected: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		if tc.value != "" {
+			spec.Annotations = map[string]string{KeepSysBindAnnotation: tc.value}
+		}
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.KeepSysBind != tc.expected {
+			t.Errorf("%q: expected KeepSysBind %v, got %v", tc.value, tc.expected, config.KeepSysBind)
+		}
+	}
+}
+
 func TestInitSystemdProps(t *testing.T) {
 	type inT struct {
 		name, value string
@@ -956,3 +1027,828 @@ func TestCreateDevices(t *testing.T) {
 		t.Errorf("device /dev/ram0 not found in config devices; got %v", conf.Devices)
 	}
 }
+
+func TestCNIAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CNI != nil {
+		t.Errorf("expected no CNI config, got %+v", config.CNI)
+	}
+
+	spec.Annotations = map[string]string{
+		CNINetworkAnnotation: "test",
+		CNIBinDirAnnotation:  "/usr/libexec/cni",
+	}
+	config, err = CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.CNI{Network: "test", BinDir: "/usr/libexec/cni"}
+	if !reflect.DeepEqual(config.CNI, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.CNI)
+	}
+}
+
+func TestStaticNetworkAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Linux.Sysctl = map[string]string{"net.ipv4.ip_forward": "1"}
+	spec.Annotations = map[string]string{
+		StaticNetworkAnnotation: `{
+			"addresses": [{"interface": "eth0", "address": "192.168.1.10/24"}],
+			"routes": [{"destination": "default", "gateway": "192.168.1.1"}],
+			"nameservers": ["192.168.1.1"],
+			"sysctls": {"net.ipv4.ping_group_range": "0 0"},
+			"resolvConf": true
+		}`,
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.StaticNetwork{
+		Addresses:   []*configs.StaticAddress{{InterfaceName: "eth0", Address: "192.168.1.10/24"}},
+		Routes:      []*configs.StaticRoute{{Gateway: "192.168.1.1"}},
+		Nameservers: []string{"192.168.1.1"},
+		ResolvConf:  true,
+	}
+	if !reflect.DeepEqual(config.StaticNetwork, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.StaticNetwork)
+	}
+	expectedSysctl := map[string]string{"net.ipv4.ip_forward": "1", "net.ipv4.ping_group_range": "0 0"}
+	if !reflect.DeepEqual(config.Sysctl, expectedSysctl) {
+		t.Errorf("expected sysctls %v, got %v", expectedSysctl, config.Sysctl)
+	}
+	if len(spec.Linux.Sysctl) != 1 {
+		t.Errorf("spec sysctls modified: %v", spec.Linux.Sysctl)
+	}
+
+	spec.Annotations[StaticNetworkAnnotation] = "{"
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error for invalid annotation, got nil")
+	}
+}
+
+func TestFreezeHooksAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		FreezeHooksAnnotation: `{
+			"preFreeze": [{"path": "/usr/bin/db-flush", "timeout": 30, "inContainer": true}],
+			"postThaw": [{"path": "/usr/local/bin/reconnect", "args": ["reconnect", "db"]}]
+		}`,
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout := 30 * time.Second
+	expected := map[configs.HookName]configs.Command{
+		configs.PreFreeze: {Path: "/usr/bin/db-flush", Timeout: &timeout, InContainer: true},
+		configs.PostThaw:  {Path: "/usr/local/bin/reconnect", Args: []string{"reconnect", "db"}},
+	}
+	for name, cmd := range expected {
+		hooks := config.Hooks[name]
+		if len(hooks) != 1 {
+			t.Fatalf("expected 1 %s hook, got %d", name, len(hooks))
+		}
+		if !reflect.DeepEqual(hooks[0].(configs.CommandHook).Command, cmd) {
+			t.Errorf("expected %s hook %+v, got %+v", name, cmd, hooks[0])
+		}
+	}
+
+	for _, v := range []string{"{", `{"preFreeze": [{"args": ["x"]}]}`} {
+		spec.Annotations[FreezeHooksAnnotation] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+			t.Errorf("expected error for annotation %q, got nil", v)
+		}
+	}
+}
+
+func TestEtcFilesAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		EtcFilesAnnotation: `{
+			"hostname": true,
+			"hosts": true,
+			"extraHosts": [{"address": "192.168.1.20", "names": ["db"]}],
+			"search": ["example.com"]
+		}`,
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.EtcFiles{
+		Hostname:   true,
+		Hosts:      true,
+		ExtraHosts: []*configs.EtcHost{{Address: "192.168.1.20", Names: []string{"db"}}},
+		Search:     []string{"example.com"},
+	}
+	if !reflect.DeepEqual(config.EtcFiles, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.EtcFiles)
+	}
+
+	spec.Annotations[EtcFilesAnnotation] = `{"hosts": 1}`
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error for invalid annotation, got nil")
+	}
+}
+
+func TestNetdevsAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{NetdevsAnnotation: "ens1f0v1=eth1, ens1f0v2"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*configs.Network{
+		{Type: "loopback"},
+		{Type: "netdev", HostInterfaceName: "ens1f0v1", Name: "eth1"},
+		{Type: "netdev", HostInterfaceName: "ens1f0v2"},
+	}
+	if !reflect.DeepEqual(config.Networks, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.Networks)
+	}
+
+	spec.Annotations[NetdevsAnnotation] = "eth0,,eth1"
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestInterfacesAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		InterfacesAnnotation: `[{"type": "macvlan", "parent": "eth0", "mode": "bridge", "name": "eth0", "address": "192.168.1.10/24", "gateway": "192.168.1.1", "mtu": 1400}]`,
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*configs.Network{
+		{Type: "loopback"},
+		{Type: "macvlan", Parent: "eth0", Mode: "bridge", Name: "eth0", Address: "192.168.1.10/24", Gateway: "192.168.1.1", Mtu: 1400},
+	}
+	if !reflect.DeepEqual(config.Networks, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.Networks)
+	}
+
+	spec.Annotations[InterfacesAnnotation] = `[{"type": "veth", "name": "eth0"}]`
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestCpusetAdjustAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected bool
+		isErr    bool
+	}{
+		{value: "", expected: false},
+		{value: "true", expected: true},
+		{value: "false", expected: false},
+		{value: "yes", isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Annotations = map[string]string{CpusetAdjustAnnotation: tc.value}
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+		} else if config.CpusetAdjust != tc.expected {
+			t.Errorf("%q: expected %v, got %v", tc.value, tc.expected, config.CpusetAdjust)
+		}
+	}
+}
+
+func TestCpusetAllocAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		annotations map[string]string
+		expected    *configs.CpusetAlloc
+		isErr       bool
+	}{
+		{annotations: nil},
+		{
+			annotations: map[string]string{CpusetAllocAnnotation: "2"},
+			expected:    &configs.CpusetAlloc{CPUs: 2},
+		},
+		{
+			annotations: map[string]string{CpusetAllocAnnotation: "4", CpusetPoolAnnotation: "/etc/pool"},
+			expected:    &configs.CpusetAlloc{CPUs: 4, Pool: "/etc/pool"},
+		},
+		{annotations: map[string]string{CpusetAllocAnnotation: "two"}, isErr: true},
+		{annotations: map[string]string{CpusetPoolAnnotation: "/etc/pool"}, isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Annotations = tc.annotations
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+		} else if !reflect.DeepEqual(config.CpusetAlloc, tc.expected) {
+			t.Errorf("%v: expected %+v, got %+v", tc.annotations, tc.expected, config.CpusetAlloc)
+		}
+	}
+}
+
+func TestSwapMaxAnnotation(t *testing.T) {
+	i64ptr := func(v int64) *int64 { return &v }
+	for _, tc := range []struct {
+		value    string
+		swap     *int64
+		expected *int64
+		isErr    bool
+	}{
+		{value: "1048576", expected: i64ptr(1048576)},
+		{value: "0", expected: i64ptr(0)},
+		{value: "max", expected: i64ptr(-1)},
+		{value: "-1", expected: i64ptr(-1)},
+		{value: "-2", isErr: true},
+		{value: "1M", isErr: true},
+		{value: "1048576", swap: i64ptr(2097152), isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Annotations = map[string]string{SwapMaxAnnotation: tc.value}
+		spec.Linux.Resources.Memory = &specs.LinuxMemory{Swap: tc.swap}
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+		} else if !reflect.DeepEqual(config.Cgroups.Resources.SwapMax, tc.expected) {
+			t.Errorf("%q: expected %v, got %v", tc.value, *tc.expected, config.Cgroups.Resources.SwapMax)
+		}
+	}
+}
+
+func TestCgroupAdoptAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		value       string
+		cgroupsPath string
+		expected    string
+		isErr       bool
+	}{
+		{value: "", cgroupsPath: "/foo"},
+		{value: "true", cgroupsPath: "/system.slice/foo.service/bar", expected: "/system.slice/foo.service/bar"},
+		{value: "true", cgroupsPath: "/foo/../bar", expected: "/bar"},
+		{value: "true", isErr: true},
+		{value: "true", cgroupsPath: "system.slice:foo:bar", isErr: true},
+		{value: "fd:x", cgroupsPath: "/foo", isErr: true},
+		{value: "yes", cgroupsPath: "/foo", isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Linux.CgroupsPath = tc.cgroupsPath
+		spec.Annotations = map[string]string{CgroupAdoptAnnotation: tc.value}
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName:       "ContainerID",
+			UseSystemdCgroup: tc.expected != "",
+			Spec:             spec,
+		})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+			continue
+		}
+		if config.Cgroups.Adopted != (tc.expected != "") {
+			t.Errorf("%q: expected adopted %v, got %v", tc.value, tc.expected != "", config.Cgroups.Adopted)
+		}
+		if tc.expected != "" && (config.Cgroups.Path != tc.expected || config.Cgroups.Systemd) {
+			t.Errorf("%q: expected path %q (no systemd), got %q (systemd %v)", tc.value, tc.expected, config.Cgroups.Path, config.Cgroups.Systemd)
+		}
+	}
+}
+
+func TestCgroupAdoptAnnotationFd(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("requires cgroup v2")
+	}
+	dir, err := os.Open("/sys/fs/cgroup")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer dir.Close()
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{CgroupAdoptAnnotation: "fd:" + strconv.Itoa(int(dir.Fd()))}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.Cgroups.Adopted || config.Cgroups.Path != "/" {
+		t.Fatalf("expected adopted cgroup /, got %+v", config.Cgroups)
+	}
+
+	// Not a cgroup.
+	spec.Annotations[CgroupAdoptAnnotation] = "fd:" + strconv.Itoa(int(os.Stdin.Fd()))
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCreateCgroupConfigCgroupFd(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("requires cgroup v2")
+	}
+	dir, err := os.Open("/sys/fs/cgroup")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer dir.Close()
+	fd := int(dir.Fd())
+
+	spec := Example()
+	spec.Linux.CgroupsPath = "/ignored"
+	cg, err := CreateCgroupConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec, CgroupFd: fd}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cg.Path != "/" || cg.DirFd != fd || cg.Adopted {
+		t.Fatalf("expected path / and fd %d, got %+v", fd, cg)
+	}
+
+	if _, err := CreateCgroupConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec, CgroupFd: fd, UseSystemdCgroup: true}, nil); err == nil {
+		t.Fatal("expected error with systemd, got nil")
+	}
+}
+
+func TestUserNamespaceFd(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+	// A process in a new user namespace, to get the namespace fd from.
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  unix.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: 100000, Size: 65536}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: 200000, Size: 65536}},
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("Test requires userns: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	nsPath := "/proc/" + strconv.Itoa(cmd.Process.Pid) + "/ns/user"
+	ns, err := os.Open(nsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ns.Close()
+	fd := int(ns.Fd())
+	uidMap, gidMap, err := userns.GetUserNamespaceMappings(nsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSpec := func() *specs.Spec {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Linux.Namespaces = append(spec.Linux.Namespaces, specs.LinuxNamespace{Type: specs.UserNamespace})
+		spec.Linux.UIDMappings = []specs.LinuxIDMapping{
+			{ContainerID: uint32(uidMap[0].ContainerID), HostID: uint32(uidMap[0].HostID), Size: 1},
+		}
+		spec.Linux.GIDMappings = []specs.LinuxIDMapping{
+			{ContainerID: uint32(gidMap[0].ContainerID), HostID: uint32(gidMap[0].HostID), Size: 1},
+		}
+		return spec
+	}
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: newSpec(), UsernsFd: fd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "/proc/" + strconv.Itoa(os.Getpid()) + "/fd/" + strconv.Itoa(fd)
+	if path := config.Namespaces.PathOf(configs.NEWUSER); path != expected {
+		t.Errorf("expected userns path %q, got %q", expected, path)
+	}
+	if !userns.IsSameMapping(config.UIDMappings, uidMap) {
+		t.Errorf("expected uid mappings %v, got %v", uidMap, config.UIDMappings)
+	}
+
+	// Mappings not provided by the namespace.
+	spec := newSpec()
+	spec.Linux.UIDMappings[0].HostID++
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec, UsernsFd: fd}); err == nil {
+		t.Error("expected error for mappings not in the namespace, got nil")
+	}
+
+	// Both a path and an fd.
+	spec = newSpec()
+	spec.Linux.Namespaces[len(spec.Linux.Namespaces)-1].Path = "/proc/self/ns/user"
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec, UsernsFd: fd}); err == nil {
+		t.Error("expected error for userns path and fd, got nil")
+	}
+
+	// Not a user namespace.
+	netns, err := os.Open("/proc/self/ns/net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer netns.Close()
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: newSpec(), UsernsFd: int(netns.Fd())}); err == nil {
+		t.Error("expected error for a non-userns fd, got nil")
+	}
+}
+
+func TestPidsStartLimitAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		annotations map[string]string
+		expected    *configs.PidsStartLimit
+		isErr       bool
+	}{
+		{annotations: nil},
+		{
+			annotations: map[string]string{PidsStartLimitAnnotation: "64"},
+			expected:    &configs.PidsStartLimit{Limit: 64, Settle: DefaultPidsStartSettle},
+		},
+		{
+			annotations: map[string]string{PidsStartLimitAnnotation: "64", PidsStartSettleAnnotation: "250ms"},
+			expected:    &configs.PidsStartLimit{Limit: 64, Settle: 250 * time.Millisecond},
+		},
+		{annotations: map[string]string{PidsStartLimitAnnotation: "many"}, isErr: true},
+		{annotations: map[string]string{PidsStartLimitAnnotation: "64", PidsStartSettleAnnotation: "1"}, isErr: true},
+		{annotations: map[string]string{PidsStartSettleAnnotation: "1s"}, isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Annotations = tc.annotations
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+		} else if !reflect.DeepEqual(config.PidsStartLimit, tc.expected) {
+			t.Errorf("%v: expected %+v, got %+v", tc.annotations, tc.expected, config.PidsStartLimit)
+		}
+	}
+}
+
+func TestParseTmpfsSize(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		limit    int64
+		expected int64
+		isErr    bool
+	}{
+		{in: "1024", expected: 1024},
+		{in: "64m", expected: 64 << 20},
+		{in: "1g", limit: 1 << 30, expected: 1 << 30},
+		{in: "50%", limit: 1 << 30, expected: 1 << 29},
+		{in: "12.5%", limit: 1 << 30, expected: 1 << 27},
+		{in: "0", isErr: true},
+		{in: "-1m", isErr: true},
+		{in: "0%", limit: 1 << 30, isErr: true},
+		{in: "101%", limit: 1 << 30, isErr: true},
+		{in: "big", isErr: true},
+	} {
+		size, err := ParseTmpfsSize(tc.in, tc.limit)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %d", tc.in, size)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if size != tc.expected {
+			t.Errorf("%q: expected %d, got %d", tc.in, tc.expected, size)
+		}
+	}
+
+	// Without a memory limit, percentages are of the host memory.
+	if size, err := ParseTmpfsSize("100%", 0); err != nil || size <= 0 {
+		t.Errorf("100%% of the host memory: got %d, %v", size, err)
+	}
+}
+
+func TestTmpfsSizeAnnotations(t *testing.T) {
+	findMount := func(config *configs.Config, dest string) *configs.Mount {
+		for _, m := range config.Mounts {
+			if m.Destination == dest {
+				return m
+			}
+		}
+		return nil
+	}
+
+	spec := Example()
+	spec.Root.Path = "/"
+	limit := int64(1 << 30)
+	spec.Linux.Resources.Memory = &specs.LinuxMemory{Limit: &limit}
+	spec.Annotations = map[string]string{
+		ShmSizeAnnotation:   "25%",
+		TmpSizeAnnotation:   "128m",
+		TmpInodesAnnotation: "4096",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ShmSize != 1<<28 || config.TmpSize != 128<<20 || config.TmpInodes != 4096 {
+		t.Errorf("unexpected sizes: shm %d, tmp %d, tmp inodes %d", config.ShmSize, config.TmpSize, config.TmpInodes)
+	}
+	// The /dev/shm size option from the spec is replaced.
+	if m := findMount(config, "/dev/shm"); m == nil || m.Data != "mode=1777,size=268435456" {
+		t.Errorf("unexpected /dev/shm mount: %+v", m)
+	}
+	// A /tmp tmpfs is added.
+	if m := findMount(config, "/tmp"); m == nil || m.Device != "tmpfs" || m.Data != "mode=1777,size=134217728,nr_inodes=4096" {
+		t.Errorf("unexpected /tmp mount: %+v", m)
+	}
+
+	// /tmp is a bind mount, so its size can not be set.
+	spec = Example()
+	spec.Root.Path = "/"
+	spec.Mounts = append(spec.Mounts, specs.Mount{Destination: "/tmp", Type: "bind", Source: "/var/tmp", Options: []string{"rbind"}})
+	spec.Annotations = map[string]string{TmpSizeAnnotation: "128m"}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error for a bind mounted /tmp, got nil")
+	}
+
+	spec = Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{TmpInodesAnnotation: "0"}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error for zero inodes, got nil")
+	}
+}
+
+func TestParseBlkioDevices(t *testing.T) {
+	wd, err := ParseWeightDevice("/dev/disk/by-id/nvme-a:200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wd.Path != "/dev/disk/by-id/nvme-a" || wd.Weight != 200 {
+		t.Errorf("unexpected weight device: %+v", wd)
+	}
+	for _, s := range []string{"/dev/sda", "sda:200", "/dev/sda:5", "/dev/sda:2000", "/dev/sda:x"} {
+		if _, err := ParseWeightDevice(s); err == nil {
+			t.Errorf("%q: expected error, got nil", s)
+		}
+	}
+
+	for _, tc := range []struct {
+		in    string
+		bytes bool
+		rate  uint64
+		isErr bool
+	}{
+		{in: "/dev/sda:10m", bytes: true, rate: 10 << 20},
+		{in: "/dev/sda:1024", bytes: true, rate: 1024},
+		{in: "/dev/sda:1000", rate: 1000},
+		{in: "/dev/sda:10m", isErr: true},
+		{in: "/dev/sda:-1", bytes: true, isErr: true},
+		{in: "/dev/sda", isErr: true},
+		{in: "sda:1000", isErr: true},
+	} {
+		td, err := ParseThrottleDevice(tc.in, tc.bytes)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if td.Path != "/dev/sda" || td.Rate != tc.rate {
+			t.Errorf("%q: unexpected throttle device: %+v", tc.in, td)
+		}
+	}
+}
+
+func TestBlkioDeviceAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		BlkioWeightDeviceAnnotation:    "/dev/sda:100,/dev/sdb:200",
+		BlkioReadBpsDeviceAnnotation:   "/dev/sda:1m",
+		BlkioWriteIOPSDeviceAnnotation: "/dev/sdb:500",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := config.Cgroups.Resources
+	if len(r.BlkioWeightDevice) != 2 || r.BlkioWeightDevice[1].Path != "/dev/sdb" || r.BlkioWeightDevice[1].Weight != 200 {
+		t.Errorf("unexpected weight devices: %+v", r.BlkioWeightDevice)
+	}
+	if len(r.BlkioThrottleReadBpsDevice) != 1 || r.BlkioThrottleReadBpsDevice[0].Rate != 1<<20 {
+		t.Errorf("unexpected read bps devices: %+v", r.BlkioThrottleReadBpsDevice)
+	}
+	if len(r.BlkioThrottleWriteIOPSDevice) != 1 || r.BlkioThrottleWriteIOPSDevice[0].Path != "/dev/sdb" {
+		t.Errorf("unexpected write iops devices: %+v", r.BlkioThrottleWriteIOPSDevice)
+	}
+
+	spec.Annotations = map[string]string{BlkioReadIOPSDeviceAnnotation: "/dev/sda:fast"}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestDiskQuotaAnnotations(t *testing.T) {
+	for _, tc := range []struct {
+		annotations map[string]string
+		expected    *configs.DiskQuota
+		isErr       bool
+	}{
+		{annotations: nil},
+		{
+			annotations: map[string]string{DiskQuotaAnnotation: "10g"},
+			expected:    &configs.DiskQuota{Path: "/", ProjectID: quota.ProjectID("ContainerID"), Size: 10 << 30},
+		},
+		{
+			annotations: map[string]string{
+				DiskQuotaInodesAnnotation:  "10000",
+				DiskQuotaPathAnnotation:    "/var/lib/overlay/upper/",
+				DiskQuotaProjectAnnotation: "1234",
+			},
+			expected: &configs.DiskQuota{Path: "/var/lib/overlay/upper", ProjectID: 1234, Inodes: 10000},
+		},
+		{annotations: map[string]string{DiskQuotaAnnotation: "lots"}, isErr: true},
+		{annotations: map[string]string{DiskQuotaAnnotation: "0"}, isErr: true},
+		{annotations: map[string]string{DiskQuotaInodesAnnotation: "-1"}, isErr: true},
+		{annotations: map[string]string{DiskQuotaAnnotation: "1g", DiskQuotaPathAnnotation: "upper"}, isErr: true},
+		{annotations: map[string]string{DiskQuotaAnnotation: "1g", DiskQuotaProjectAnnotation: "0"}, isErr: true},
+		{annotations: map[string]string{DiskQuotaPathAnnotation: "/upper"}, isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Annotations = tc.annotations
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+		} else if !reflect.DeepEqual(config.DiskQuota, tc.expected) {
+			t.Errorf("%v: expected %+v, got %+v", tc.annotations, tc.expected, config.DiskQuota)
+		}
+	}
+}
+
+func TestBinfmtAnnotations(t *testing.T) {
+	for _, tc := range []struct {
+		annotations map[string]string
+		expected    *configs.Binfmt
+		isErr       bool
+	}{
+		{annotations: nil},
+		{annotations: map[string]string{BinfmtPrivateAnnotation: "false"}},
+		{
+			annotations: map[string]string{BinfmtRequireAnnotation: "qemu-aarch64, qemu-riscv64"},
+			expected:    &configs.Binfmt{Require: []string{"qemu-aarch64", "qemu-riscv64"}},
+		},
+		{
+			annotations: map[string]string{
+				BinfmtPrivateAnnotation:  "true",
+				BinfmtRegisterAnnotation: ":a:E::a::/bin/a:\n\n:b:E::b::/bin/b:F\n",
+			},
+			expected: &configs.Binfmt{Private: true, Register: []string{":a:E::a::/bin/a:", ":b:E::b::/bin/b:F"}},
+		},
+		{annotations: map[string]string{BinfmtPrivateAnnotation: "yes"}, isErr: true},
+	} {
+		spec := Example()
+		spec.Annotations = tc.annotations
+		b, err := createBinfmt(spec)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v: expected error, got nil", tc.annotations)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.annotations, err)
+		} else if !reflect.DeepEqual(b, tc.expected) {
+			t.Errorf("%v: expected %+v, got %+v", tc.annotations, tc.expected, b)
+		}
+	}
+}
+
+func TestBPFLSMAnnotation(t *testing.T) {
+	spec := Example()
+	if b := createBPFLSM(spec); b != nil {
+		t.Errorf("expected nil, got %+v", b)
+	}
+	spec.Annotations = map[string]string{BPFLSMAnnotation: "/sys/fs/bpf/a, /sys/fs/bpf/b"}
+	expected := &configs.BPFLSM{Programs: []string{"/sys/fs/bpf/a", "/sys/fs/bpf/b"}}
+	if b := createBPFLSM(spec); !reflect.DeepEqual(b, expected) {
+		t.Errorf("expected %+v, got %+v", expected, b)
+	}
+}
+
+func TestParseDeviceRule(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		allow    bool
+		expected *devices.Rule
+	}{
+		{in: "c 10:200 rwm", allow: true, expected: &devices.Rule{Type: devices.CharDevice, Major: 10, Minor: 200, Permissions: "rwm", Allow: true}},
+		{in: "b 8:* mr", expected: &devices.Rule{Type: devices.BlockDevice, Major: 8, Minor: devices.Wildcard, Permissions: "rm"}},
+		{in: "a", expected: &devices.Rule{Type: devices.WildcardDevice, Major: devices.Wildcard, Minor: devices.Wildcard, Permissions: "rwm"}},
+		{in: "c 10:200"},
+		{in: "p 1:2 r"},
+		{in: "c 10 r"},
+		{in: "c x:1 r"},
+		{in: "c -1:1 r"},
+		{in: "c 1:1 rx"},
+		{in: "c 1:1 "},
+	} {
+		rule, err := ParseDeviceRule(tc.in, tc.allow)
+		if tc.expected == nil {
+			if err == nil {
+				t.Errorf("%q: expected error, got %+v", tc.in, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if !reflect.DeepEqual(rule, tc.expected) {
+			t.Errorf("%q: expected %+v, got %+v", tc.in, tc.expected, rule)
+		}
+	}
+}
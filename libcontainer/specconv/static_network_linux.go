@@ -0,0 +1,92 @@
+package specconv
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// StaticNetworkAnnotation holds a static network configuration (in JSON)
+// for the network namespace created by runc, for simple setups where a full
+// CNI stack is not needed. For example:
+//
+//	{
+//		"addresses": [{"interface": "eth0", "address": "192.168.1.10/24"}],
+//		"routes": [{"gateway": "192.168.1.1"}],
+//		"nameservers": ["192.168.1.1"],
+//		"search": ["example.com"],
+//		"sysctls": {"net.ipv4.ping_group_range": "0 0"},
+//		"resolvConf": true
+//	}
+//
+// The sysctls are added to the ones from the spec (linux.sysctl), and
+// /etc/resolv.conf in the container root filesystem is only written if
+// resolvConf is true.
+const StaticNetworkAnnotation = "org.opencontainers.runc.static-network"
+
+type staticNetwork struct {
+	Addresses []struct {
+		Interface string `json:"interface"`
+		Address   string `json:"address"`
+	} `json:"addresses"`
+	Routes []struct {
+		Destination string `json:"destination"`
+		Gateway     string `json:"gateway"`
+		Interface   string `json:"interface"`
+	} `json:"routes"`
+	Nameservers []string          `json:"nameservers"`
+	Search      []string          `json:"search"`
+	Sysctls     map[string]string `json:"sysctls"`
+	ResolvConf  bool              `json:"resolvConf"`
+}
+
+// setupStaticNetwork sets config.StaticNetwork (and adds to config.Sysctl)
+// according to the StaticNetworkAnnotation annotation, if set.
+func setupStaticNetwork(spec *specs.Spec, config *configs.Config) error {
+	v, ok := spec.Annotations[StaticNetworkAnnotation]
+	if !ok {
+		return nil
+	}
+	var sn staticNetwork
+	if err := json.Unmarshal([]byte(v), &sn); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", StaticNetworkAnnotation, err)
+	}
+	n := &configs.StaticNetwork{
+		Nameservers: sn.Nameservers,
+		Search:      sn.Search,
+		ResolvConf:  sn.ResolvConf,
+	}
+	for _, a := range sn.Addresses {
+		n.Addresses = append(n.Addresses, &configs.StaticAddress{
+			InterfaceName: a.Interface,
+			Address:       a.Address,
+		})
+	}
+	for _, r := range sn.Routes {
+		dst := r.Destination
+		if dst == "default" {
+			dst = ""
+		}
+		n.Routes = append(n.Routes, &configs.StaticRoute{
+			Destination:   dst,
+			Gateway:       r.Gateway,
+			InterfaceName: r.Interface,
+		})
+	}
+	config.StaticNetwork = n
+
+	if len(sn.Sysctls) > 0 {
+		// Do not modify the spec's map.
+		sysctl := make(map[string]string, len(config.Sysctl)+len(sn.Sysctls))
+		for k, v := range config.Sysctl {
+			sysctl[k] = v
+		}
+		for k, v := range sn.Sysctls {
+			sysctl[k] = v
+		}
+		config.Sysctl = sysctl
+	}
+	return nil
+}
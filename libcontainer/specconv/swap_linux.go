@@ -0,0 +1,32 @@
+package specconv
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// SwapMaxAnnotation sets the swap usage limit, in bytes, independent of the
+// memory limit (see configs.Resources.SwapMax). The value "max" (or -1)
+// means unlimited swap, and 0 disables swap. It can not be used together
+// with linux.resources.memory.swap, which is the memory+swap limit.
+const SwapMaxAnnotation = "org.opencontainers.runc.swap-max"
+
+func createSwapMax(spec *specs.Spec) (*int64, error) {
+	v, ok := spec.Annotations[SwapMaxAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	if v == "max" {
+		v = "-1"
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < -1 {
+		return nil, fmt.Errorf("invalid %s annotation value %q", SwapMaxAnnotation, v)
+	}
+	if spec.Linux != nil && spec.Linux.Resources != nil && spec.Linux.Resources.Memory != nil && spec.Linux.Resources.Memory.Swap != nil {
+		return nil, fmt.Errorf("%s annotation can not be used together with linux.resources.memory.swap", SwapMaxAnnotation)
+	}
+	return &n, nil
+}
@@ -0,0 +1,100 @@
+package specconv
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// SystemdInitAnnotation is the annotation which, when set to "true", makes
+// runc set up the container so that systemd can run as its init process.
+// See SetupSystemdInit for the list of changes made to the spec.
+const SystemdInitAnnotation = "org.opencontainers.runc.systemd-init"
+
+// IsSystemdInit tells whether the spec requests the systemd init mode.
+func IsSystemdInit(spec *specs.Spec) bool {
+	return spec != nil && spec.Annotations[SystemdInitAnnotation] == "true"
+}
+
+// SetupSystemdInit modifies the spec to provide an environment systemd
+// expects to find when it is running as the container's PID 1:
+//
+//   - a private cgroup namespace (unless one is already configured), so that
+//     the container sees its own cgroup as the root of the hierarchy;
+//   - a read-write /sys/fs/cgroup mount, so systemd can manage its subtree;
+//   - tmpfs mounts on /run and /run/lock (unless the spec has them already);
+//   - the "container" environment variable, used by systemd to detect it is
+//     running inside a container.
+//
+// The stop signal (SIGRTMIN+3) and the name=systemd hierarchy on cgroup v1
+// are handled by runc itself, based on configs.Config.SystemdInit.
+func SetupSystemdInit(spec *specs.Spec) {
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+	hasCgroupNS := false
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == specs.CgroupNamespace {
+			hasCgroupNS = true
+			break
+		}
+	}
+	if !hasCgroupNS {
+		spec.Linux.Namespaces = append(spec.Linux.Namespaces, specs.LinuxNamespace{Type: specs.CgroupNamespace})
+	}
+
+	hasCgroupMount := false
+	mounted := make(map[string]bool)
+	for i := range spec.Mounts {
+		m := &spec.Mounts[i]
+		dest := filepath.Clean(m.Destination)
+		mounted[dest] = true
+		if dest != "/sys/fs/cgroup" {
+			continue
+		}
+		hasCgroupMount = true
+		opts := m.Options[:0]
+		for _, o := range m.Options {
+			if o != "ro" {
+				opts = append(opts, o)
+			}
+		}
+		m.Options = append(opts, "rw")
+	}
+	if !hasCgroupMount {
+		spec.Mounts = append(spec.Mounts, specs.Mount{
+			Destination: "/sys/fs/cgroup",
+			Type:        "cgroup",
+			Source:      "cgroup",
+			Options:     []string{"nosuid", "noexec", "nodev", "relatime", "rw"},
+		})
+	}
+	for _, m := range []specs.Mount{
+		{
+			Destination: "/run",
+			Type:        "tmpfs",
+			Source:      "tmpfs",
+			Options:     []string{"nosuid", "nodev", "strictatime", "mode=755", "size=65536k"},
+		},
+		{
+			Destination: "/run/lock",
+			Type:        "tmpfs",
+			Source:      "tmpfs",
+			Options:     []string{"nosuid", "nodev", "noexec", "mode=1777", "size=5120k"},
+		},
+	} {
+		if !mounted[m.Destination] {
+			spec.Mounts = append(spec.Mounts, m)
+		}
+	}
+
+	if spec.Process != nil {
+		for _, env := range spec.Process.Env {
+			if strings.HasPrefix(env, "container=") {
+				return
+			}
+		}
+		spec.Process.Env = append(spec.Process.Env, "container=oci")
+	}
+}
@@ -0,0 +1,88 @@
+package specconv
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+func TestSetupSystemdInit(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{SystemdInitAnnotation: "true"}
+	if !IsSystemdInit(spec) {
+		t.Fatal("expected IsSystemdInit to return true")
+	}
+
+	SetupSystemdInit(spec)
+	// Calling it twice should not result in duplicated mounts.
+	SetupSystemdInit(spec)
+
+	count := make(map[string]int)
+	for _, m := range spec.Mounts {
+		count[m.Destination]++
+	}
+	for _, dest := range []string{"/sys/fs/cgroup", "/run", "/run/lock"} {
+		if count[dest] != 1 {
+			t.Errorf("expected exactly one mount at %s, got %d", dest, count[dest])
+		}
+	}
+	envCount := 0
+	for _, env := range spec.Process.Env {
+		if env == "container=oci" {
+			envCount++
+		}
+	}
+	if envCount != 1 {
+		t.Errorf("expected container=oci to be set once, got %d", envCount)
+	}
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatalf("Couldn't create libcontainer config: %v", err)
+	}
+	if !config.SystemdInit {
+		t.Error("expected config.SystemdInit to be set")
+	}
+	if !config.Namespaces.Contains(configs.NEWCGROUP) {
+		t.Error("expected a cgroup namespace to be added")
+	}
+	for _, m := range config.Mounts {
+		if m.Destination == "/sys/fs/cgroup" && m.Flags&unix.MS_RDONLY != 0 {
+			t.Error("expected /sys/fs/cgroup to be mounted read-write")
+		}
+	}
+	if err := validate.Validate(config); err != nil {
+		t.Errorf("Expected specconv to produce valid container config: %v", err)
+	}
+}
+
+func TestSetupSystemdInitKeepsExistingMounts(t *testing.T) {
+	spec := Example()
+	spec.Mounts = append(spec.Mounts, specs.Mount{
+		Destination: "/run",
+		Type:        "bind",
+		Source:      "/somewhere",
+		Options:     []string{"rbind"},
+	})
+	spec.Process.Env = append(spec.Process.Env, "container=docker")
+
+	SetupSystemdInit(spec)
+
+	for _, m := range spec.Mounts {
+		if m.Destination == "/run" && m.Type != "bind" {
+			t.Errorf("existing /run mount was overridden: %+v", m)
+		}
+	}
+	for _, env := range spec.Process.Env {
+		if env == "container=oci" {
+			t.Error("existing container= environment variable was overridden")
+		}
+	}
+}
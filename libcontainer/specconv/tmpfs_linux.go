@@ -0,0 +1,120 @@
+package specconv
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// ShmSizeAnnotation is the size of /dev/shm, and TmpSizeAnnotation and
+// TmpInodesAnnotation are the size and the maximum number of inodes of a
+// tmpfs /tmp (see configs.Config.ShmSize). The sizes are in the
+// ParseTmpfsSize format.
+//
+// If there is no /dev/shm (or /tmp) mount in the spec, a tmpfs one is added.
+const (
+	ShmSizeAnnotation   = "org.opencontainers.runc.shm-size"
+	TmpSizeAnnotation   = "org.opencontainers.runc.tmp-size"
+	TmpInodesAnnotation = "org.opencontainers.runc.tmp-inodes"
+)
+
+// ParseTmpfsSize parses a tmpfs size, which is either a number of bytes
+// (with an optional unit, such as "1g"), or a percentage (such as "50%") of
+// the container memory limit, memoryLimit, or of the host memory if the
+// container has no memory limit (memoryLimit <= 0).
+func ParseTmpfsSize(s string, memoryLimit int64) (int64, error) {
+	pct, ok := strings.CutSuffix(s, "%")
+	if !ok {
+		size, err := units.RAMInBytes(s)
+		if err != nil || size <= 0 {
+			return 0, fmt.Errorf("invalid tmpfs size %q: must be a positive size, or a percentage", s)
+		}
+		return size, nil
+	}
+	p, err := strconv.ParseFloat(pct, 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("invalid tmpfs size %q: percentage must be in (0, 100]", s)
+	}
+	base := memoryLimit
+	if base <= 0 {
+		var si unix.Sysinfo_t
+		if err := unix.Sysinfo(&si); err != nil {
+			return 0, &os.SyscallError{Syscall: "sysinfo", Err: err}
+		}
+		base = int64(si.Totalram) * int64(si.Unit)
+	}
+	return int64(float64(base) * p / 100), nil
+}
+
+func createTmpfsSizes(spec *specs.Spec, config *configs.Config) error {
+	var memoryLimit int64
+	if config.Cgroups != nil && config.Cgroups.Resources != nil {
+		memoryLimit = config.Cgroups.Resources.Memory
+	}
+	if v, ok := spec.Annotations[ShmSizeAnnotation]; ok {
+		size, err := ParseTmpfsSize(v, memoryLimit)
+		if err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", ShmSizeAnnotation, err)
+		}
+		m, err := tmpfsMount(config, "/dev/shm", unix.MS_NOSUID|unix.MS_NOEXEC|unix.MS_NODEV)
+		if err != nil {
+			return err
+		}
+		m.SetDataOption("size", strconv.FormatInt(size, 10))
+		config.ShmSize = size
+	}
+
+	size, hasSize := spec.Annotations[TmpSizeAnnotation]
+	inodes, hasInodes := spec.Annotations[TmpInodesAnnotation]
+	if !hasSize && !hasInodes {
+		return nil
+	}
+	m, err := tmpfsMount(config, "/tmp", unix.MS_NOSUID|unix.MS_NODEV)
+	if err != nil {
+		return err
+	}
+	if hasSize {
+		if config.TmpSize, err = ParseTmpfsSize(size, memoryLimit); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", TmpSizeAnnotation, err)
+		}
+		m.SetDataOption("size", strconv.FormatInt(config.TmpSize, 10))
+	}
+	if hasInodes {
+		if config.TmpInodes, err = strconv.ParseUint(inodes, 10, 64); err != nil || config.TmpInodes == 0 {
+			return fmt.Errorf("invalid %s annotation value %q: must be a positive number", TmpInodesAnnotation, inodes)
+		}
+		m.SetDataOption("nr_inodes", inodes)
+	}
+	return nil
+}
+
+// tmpfsMount returns the tmpfs mount at dest, adding it (with flags) if
+// there is no mount there.
+func tmpfsMount(config *configs.Config, dest string, flags int) (*configs.Mount, error) {
+	for _, m := range config.Mounts {
+		if utils.CleanPath(m.Destination) != dest {
+			continue
+		}
+		if m.Device != "tmpfs" {
+			return nil, fmt.Errorf("%s is not a tmpfs mount, so its size can not be set", dest)
+		}
+		return m, nil
+	}
+	m := &configs.Mount{
+		Source:      "tmpfs",
+		Destination: dest,
+		Device:      "tmpfs",
+		Flags:       flags,
+		Data:        "mode=1777",
+	}
+	config.Mounts = append(config.Mounts, m)
+	return m, nil
+}
@@ -13,9 +13,9 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/opencontainers/runc/libcontainer/apparmor"
+	"github.com/opencontainers/runc/libcontainer/binfmt"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/keys"
-	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 )
 
@@ -77,6 +77,9 @@ func (l *linuxStandardInit) Init() error {
 			}
 		}
 	}
+	if err := injectSecrets(l.config.Secrets); err != nil {
+		return err
+	}
 
 	/*启动network*/
 	if err := setupNetwork(l.config); err != nil {
@@ -87,6 +90,9 @@ func (l *linuxStandardInit) Init() error {
 	if err := setupRoute(l.config.Config); err != nil {
 		return err
 	}
+	if err := setupStaticNetwork(l.config.Config); err != nil {
+		return err
+	}
 
 	// initialises the labeling system
 	selinux.GetEnabled()
@@ -130,6 +136,11 @@ func (l *linuxStandardInit) Init() error {
 			return err
 		}
 	}
+	if b := l.config.Config.Binfmt; b != nil && b.Private {
+		if err := binfmt.Setup(b); err != nil {
+			return err
+		}
+	}
 
 	if hostname := l.config.Config.Hostname; hostname != "" {
 		if err := unix.Sethostname([]byte(hostname)); err != nil {
@@ -186,67 +197,106 @@ func (l *linuxStandardInit) Init() error {
 		return fmt.Errorf("can't set process label: %w", err)
 	}
 	defer selinux.SetExecLabel("") //nolint: errcheck
-	// Without NoNewPrivileges seccomp is a privileged operation, so we need to
-	// do this before dropping capabilities; otherwise do it as late as possible
-	// just before execve so as few syscalls take place after it as possible.
-	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
-		if err != nil {
-			return err
-		}
+	// With unconfined startContainer hooks, the container's seccomp filter,
+	// user and capabilities are only applied after the hooks are run.
+	// Otherwise, this is done before waiting for the exec fifo, so the
+	// errors are reported by runc create.
+	deferConfine := l.config.Config.UnconfinedStartHooks && len(l.config.Config.Hooks[configs.StartContainer]) > 0
+	// dropPrivileges applies the container's user and capabilities (and,
+	// without NoNewPrivileges, the seccomp filter).
+	dropPrivileges := func() error {
+		// Without NoNewPrivileges seccomp is a privileged operation, so we need to
+		// do this before dropping capabilities; otherwise do it as late as possible
+		// just before execve so as few syscalls take place after it as possible.
+		if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
+			seccompFd, err := initSeccomp(l.config)
+			if err != nil {
+				return err
+			}
 
-		if err := syncParentSeccomp(l.pipe, seccompFd); err != nil {
+			if err := syncParentSeccomp(l.pipe, seccompFd); err != nil {
+				return err
+			}
+		}
+		if err := finalizeNamespace(l.config); err != nil {
 			return err
 		}
+		// finalizeNamespace can change user/group which clears the parent death
+		// signal, so we restore it here. After the exec fifo is opened, the
+		// parent is gone unless it is runc run, and the signal is only restored
+		// in the latter case (the parent is checked before waiting for the
+		// exec fifo, see below).
+		if deferConfine && unix.Getppid() != l.parentPid {
+			return nil
+		}
+		if err := pdeath.Restore(); err != nil {
+			return fmt.Errorf("can't restore pdeath signal: %w", err)
+		}
+		return nil
 	}
-	if err := finalizeNamespace(l.config); err != nil {
-		return err
+	// lookPath looks up the binary to execute, as the current user.
+	lookPath := func() (string, error) {
+		name, err := exec.LookPath(l.config.Args[0])
+		if err != nil {
+			return "", classify(err, ErrExec)
+		}
+		// exec.LookPath in Go < 1.20 might return no error for an executable
+		// residing on a file system mounted with noexec flag, so perform this
+		// extra check now while we can still return a proper error.
+		// TODO: remove this once go < 1.20 is not supported.
+		if err := eaccess(name); err != nil {
+			return "", classify(&os.PathError{Op: "eaccess", Path: name, Err: err}, ErrExec)
+		}
+		return name, nil
+	}
+	// confine applies the seccomp filter (with NoNewPrivileges) and the
+	// personality, as close to execve as possible.
+	confine := func() error {
+		// Set seccomp as close to execve as possible, so as few syscalls take
+		// place afterward (reducing the amount of syscalls that users need to
+		// enable in their seccomp profiles). However, this needs to be done
+		// before closing the pipe since we need it to pass the seccompFd to
+		// the parent.
+		if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
+			seccompFd, err := initSeccomp(l.config)
+			if err != nil {
+				return fmt.Errorf("unable to init seccomp: %w", err)
+			}
+
+			if err := syncParentSeccomp(l.pipe, seccompFd); err != nil {
+				return err
+			}
+		}
+
+		// Set personality if specified.
+		if l.config.Config.Personality != nil {
+			if err := setupPersonality(l.config.Config); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	// finalizeNamespace can change user/group which clears the parent death
-	// signal, so we restore it here.
-	if err := pdeath.Restore(); err != nil {
-		return fmt.Errorf("can't restore pdeath signal: %w", err)
+	if !deferConfine {
+		if err := dropPrivileges(); err != nil {
+			return err
+		}
 	}
 	// Compare the parent from the initial start of the init process and make
 	// sure that it did not change.  if the parent changes that means it died
 	// and we were reparented to something else so we should just kill ourself
-	// and not cause problems for someone else.
+	// and not cause problems for someone else. This has to be done before
+	// waiting for the exec fifo, as runc create exits after that.
 	if unix.Getppid() != l.parentPid {
 		return unix.Kill(unix.Getpid(), unix.SIGKILL)
 	}
 	// Check for the arg before waiting to make sure it exists and it is
 	// returned as a create time error.
-	name, err := exec.LookPath(l.config.Args[0])
+	name, err := lookPath()
 	if err != nil {
 		return err
 	}
-	// exec.LookPath in Go < 1.20 might return no error for an executable
-	// residing on a file system mounted with noexec flag, so perform this
-	// extra check now while we can still return a proper error.
-	// TODO: remove this once go < 1.20 is not supported.
-	if err := eaccess(name); err != nil {
-		return &os.PathError{Op: "eaccess", Path: name, Err: err}
-	}
-
-	// Set seccomp as close to execve as possible, so as few syscalls take
-	// place afterward (reducing the amount of syscalls that users need to
-	// enable in their seccomp profiles). However, this needs to be done
-	// before closing the pipe since we need it to pass the seccompFd to
-	// the parent.
-	if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
-		if err != nil {
-			return fmt.Errorf("unable to init seccomp: %w", err)
-		}
-
-		if err := syncParentSeccomp(l.pipe, seccompFd); err != nil {
-			return err
-		}
-	}
-
-	// Set personality if specified.
-	if l.config.Config.Personality != nil {
-		if err := setupPersonality(l.config.Config); err != nil {
+	if !deferConfine {
+		if err := confine(); err != nil {
 			return err
 		}
 	}
@@ -288,6 +338,20 @@ func (l *linuxStandardInit) Init() error {
 	if err := l.config.Config.Hooks.Run(configs.StartContainer, s); err != nil {
 		return err
 	}
+	if deferConfine {
+		if err := dropPrivileges(); err != nil {
+			return err
+		}
+		// The binary has been looked up as the container's root, so look it
+		// up again as the container user, who may not be permitted to
+		// execute it (or may find another one in $PATH).
+		if name, err = lookPath(); err != nil {
+			return err
+		}
+		if err := confine(); err != nil {
+			return err
+		}
+	}
 
 	if l.dmzExe != nil {
 		l.config.Args[0] = name
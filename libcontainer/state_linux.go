@@ -1,12 +1,17 @@
 package libcontainer
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/opencontainers/runc/libcontainer/bpflsm"
+	"github.com/opencontainers/runc/libcontainer/cni"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/quota"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
 
@@ -42,25 +47,64 @@ func destroy(c *Container) error {
 	//
 	// As the container without init process running is considered stopped,
 	// and destroy is supposed to remove all the container resources, we need
-	// to kill those processes here.
-	if !c.config.Namespaces.IsPrivate(configs.NEWPID) {
+	// to kill those processes here. An adopted cgroup may contain processes
+	// of its owner, which are not ours to kill.
+	if !c.config.Namespaces.IsPrivate(configs.NEWPID) && !c.config.Cgroups.Adopted {
 		_ = signalAllProcesses(c.cgroupManager, unix.SIGKILL)
 	}
+	// A failure to remove one of the container resources does not prevent
+	// the others from being removed. The errors are logged as they occur,
+	// and returned together.
+	var errs []error
+	fail := func(err error) {
+		logrus.Warn(err)
+		errs = append(errs, err)
+	}
+	if b := c.config.BPFLSM; b != nil {
+		// The programs go away with the cgroup, but an adopted cgroup
+		// is not removed.
+		if err := bpflsm.Detach(c.cgroupManager.Path(""), b.Programs); err != nil {
+			fail(fmt.Errorf("unable to detach container's bpf lsm programs: %w", err))
+		}
+	}
+	// The container state is kept if the cgroup can not be removed, so that
+	// the removal can be retried.
+	keepState := false
 	if err := c.cgroupManager.Destroy(); err != nil {
-		return fmt.Errorf("unable to remove container's cgroup: %w", err)
+		fail(fmt.Errorf("unable to remove container's cgroup: %w", err))
+		keepState = true
 	}
 	if c.intelRdtManager != nil {
 		if err := c.intelRdtManager.Destroy(); err != nil {
-			return fmt.Errorf("unable to remove container's IntelRDT group: %w", err)
+			fail(fmt.Errorf("unable to remove container's IntelRDT group: %w", err))
+		}
+	}
+	if c.config.DiskQuota != nil {
+		if err := quota.Remove(c.config.DiskQuota); err != nil {
+			fail(fmt.Errorf("unable to remove container's disk quota: %w", err))
 		}
 	}
+	if c.config.CNI != nil {
+		// The network namespace is gone with the container init.
+		if err := cni.Del(c.config.CNI, c.id, "", c.cniResult); err != nil {
+			fail(fmt.Errorf("unable to remove container from CNI network: %w", err))
+		}
+	}
+	if err := teardownNetwork(c); err != nil {
+		fail(err)
+	}
+	if keepState {
+		return errors.Join(errs...)
+	}
 	if err := os.RemoveAll(c.stateDir); err != nil {
-		return fmt.Errorf("unable to remove container state dir: %w", err)
+		fail(fmt.Errorf("unable to remove container state dir: %w", err))
 	}
 	c.initProcess = nil
-	err := runPoststopHooks(c)
+	if err := runPoststopHooks(c); err != nil {
+		errs = append(errs, err)
+	}
 	c.state = &stoppedState{c: c}
-	return err
+	return errors.Join(errs...)
 }
 
 func runPoststopHooks(c *Container) error {
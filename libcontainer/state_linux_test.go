@@ -2,8 +2,13 @@ package libcontainer
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
 var states = map[containerState]Status{
@@ -109,3 +114,34 @@ func TestCreatedStateTransition(t *testing.T) {
 		},
 	)
 }
+
+func TestDestroyContinuesOnError(t *testing.T) {
+	stateDir := filepath.Join(t.TempDir(), "test")
+	if err := os.Mkdir(stateDir, 0o711); err != nil {
+		t.Fatal(err)
+	}
+	c := &Container{
+		id:       "test",
+		stateDir: stateDir,
+		config: &configs.Config{
+			Namespaces: configs.Namespaces{{Type: configs.NEWPID}},
+			Cgroups:    &configs.Cgroup{},
+			// A temporary directory is not on a file system with project
+			// quotas enabled, so the quota can not be removed.
+			DiskQuota: &configs.DiskQuota{Path: t.TempDir(), ProjectID: 12345},
+		},
+		cgroupManager: &mockCgroupManager{},
+	}
+
+	err := destroy(c)
+	if err == nil || !strings.Contains(err.Error(), "disk quota") {
+		t.Fatalf("expected disk quota removal error, got %v", err)
+	}
+	// The rest of the container resources are removed nevertheless.
+	if _, err := os.Stat(stateDir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected state directory to be removed, got %v", err)
+	}
+	if c.state.status() != Stopped {
+		t.Fatalf("expected container to be stopped, got %s", c.state.status())
+	}
+}
@@ -3,11 +3,36 @@ package libcontainer
 import (
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
-	"github.com/opencontainers/runc/types"
+	"github.com/opencontainers/runc/libcontainer/quota"
 )
 
+// Stats are the statistics of a container, as returned by Container.Stats.
 type Stats struct {
-	Interfaces    []*types.NetworkInterface
+	Interfaces    []*NetworkInterface
 	CgroupStats   *cgroups.Stats
 	IntelRdtStats *intelrdt.Stats
+	DiskQuota     *quota.Usage
+	// FDs is only set by Container.StatsWithFDs.
+	FDs *FDStats
+}
+
+// NetworkInterface holds the statistics of a container network interface,
+// as read from /sys/class/net/<name>/statistics.
+//
+// It has the same fields and JSON encoding as the types.NetworkInterface
+// type that Stats.Interfaces used before libcontainer became a separate
+// module, so that the statistics serialized by older versions can still be
+// decoded.
+type NetworkInterface struct {
+	// Name is the name of the network interface.
+	Name string `json:"Name"`
+
+	RxBytes   uint64 `json:"RxBytes"`
+	RxPackets uint64 `json:"RxPackets"`
+	RxErrors  uint64 `json:"RxErrors"`
+	RxDropped uint64 `json:"RxDropped"`
+	TxBytes   uint64 `json:"TxBytes"`
+	TxPackets uint64 `json:"TxPackets"`
+	TxErrors  uint64 `json:"TxErrors"`
+	TxDropped uint64 `json:"TxDropped"`
 }
@@ -0,0 +1,136 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+// subCgroupPaths returns the paths of the sub-cgroups sub (see
+// Process.SubCgroupPaths) of the cgroups at paths (by controller).
+func subCgroupPaths(paths, sub map[string]string) (map[string]string, error) {
+	res := make(map[string]string, len(paths))
+	for k, v := range paths {
+		res[k] = v
+	}
+	join := func(base, add string) (string, error) {
+		subPath := path.Join(base, add)
+		if subPath != base && !strings.HasPrefix(subPath, base+"/") {
+			return "", fmt.Errorf("%s is not a sub cgroup path", add)
+		}
+		return subPath, nil
+	}
+	if add, ok := sub[""]; ok {
+		// cgroup v1: using the same path for all controllers.
+		// cgroup v2: the only possible way.
+		for k := range res {
+			subPath, err := join(res[k], add)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = subPath
+		}
+		return res, nil
+	}
+	// Per-controller paths.
+	for ctrl, add := range sub {
+		val, ok := res[ctrl]
+		if !ok {
+			return nil, fmt.Errorf("unknown controller %s in SubCgroupPaths", ctrl)
+		}
+		subPath, err := join(val, add)
+		if err != nil {
+			return nil, err
+		}
+		res[ctrl] = subPath
+	}
+	return res, nil
+}
+
+// setupSubCgroups creates the cgroups at paths (if create is set), and
+// writes the settings (cgroup file names, such as "memory.max", and their
+// values) to them.
+func setupSubCgroups(paths map[string]string, create bool, settings map[string]string) error {
+	if create {
+		for _, p := range paths {
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				return fmt.Errorf("unable to create sub-cgroup: %w", err)
+			}
+		}
+	}
+	for file, value := range settings {
+		dir, err := settingCgroupPath(paths, file)
+		if err != nil {
+			return err
+		}
+		if err := cgroups.WriteFile(dir, file, value); err != nil {
+			if errors.Is(err, os.ErrNotExist) && cgroups.IsCgroup2UnifiedMode() {
+				// The controller is not enabled in the parent
+				// cgroup, which can not have both processes and
+				// controllers enabled for its children.
+				return fmt.Errorf("unable to set %s in sub-cgroup: %w (is the controller enabled for the sub-cgroups of the container cgroup?)", file, err)
+			}
+			return fmt.Errorf("unable to set %s in sub-cgroup: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// settingCgroupPath returns the path of the cgroup, out of paths, having the
+// given cgroup file.
+func settingCgroupPath(paths map[string]string, file string) (string, error) {
+	if p, ok := paths[""]; ok {
+		return p, nil
+	}
+	ctrl, _, ok := strings.Cut(file, ".")
+	if !ok {
+		return "", fmt.Errorf("invalid cgroup file name %q", file)
+	}
+	p, ok := paths[ctrl]
+	if !ok {
+		return "", fmt.Errorf("cgroup file %s: no %s controller", file, ctrl)
+	}
+	return p, nil
+}
+
+// DestroySubCgroups kills the processes of the sub-cgroups sub (see
+// Process.SubCgroupPaths) of the container, and removes them. The container
+// cgroup itself, and its other sub-cgroups, are left untouched.
+func (c *Container) DestroySubCgroups(sub map[string]string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	base := c.cgroupManager.GetPaths()
+	paths, err := subCgroupPaths(base, sub)
+	if err != nil {
+		return err
+	}
+	for k, p := range paths {
+		if p == base[k] {
+			return errors.New("unable to destroy the container cgroup as a sub-cgroup")
+		}
+	}
+	for _, p := range paths {
+		pids, err := cgroups.GetAllPids(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		for _, pid := range pids {
+			_ = unix.Kill(pid, unix.SIGKILL)
+		}
+	}
+	for _, p := range paths {
+		if err := cgroups.RemovePath(p); err != nil {
+			return fmt.Errorf("unable to remove sub-cgroup: %w", err)
+		}
+	}
+	return nil
+}
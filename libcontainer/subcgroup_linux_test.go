@@ -0,0 +1,63 @@
+package libcontainer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubCgroupPaths(t *testing.T) {
+	v1 := map[string]string{"memory": "/sys/fs/cgroup/memory/ct", "pids": "/sys/fs/cgroup/pids/ct"}
+	v2 := map[string]string{"": "/sys/fs/cgroup/ct"}
+	for _, tc := range []struct {
+		paths, sub, expected map[string]string
+	}{
+		{
+			paths:    v2,
+			sub:      map[string]string{"": "aux"},
+			expected: map[string]string{"": "/sys/fs/cgroup/ct/aux"},
+		},
+		{
+			paths:    v1,
+			sub:      map[string]string{"": "a/b"},
+			expected: map[string]string{"memory": "/sys/fs/cgroup/memory/ct/a/b", "pids": "/sys/fs/cgroup/pids/ct/a/b"},
+		},
+		{
+			paths:    v1,
+			sub:      map[string]string{"memory": "aux"},
+			expected: map[string]string{"memory": "/sys/fs/cgroup/memory/ct/aux", "pids": "/sys/fs/cgroup/pids/ct"},
+		},
+		{paths: v2, sub: map[string]string{"": "../ct2"}},
+		{paths: v2, sub: map[string]string{"": "../ctx"}},
+		{paths: v1, sub: map[string]string{"cpu": "aux"}},
+	} {
+		paths, err := subCgroupPaths(tc.paths, tc.sub)
+		if tc.expected == nil {
+			if err == nil {
+				t.Errorf("%v: expected error, got %v", tc.sub, paths)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.sub, err)
+		} else if !reflect.DeepEqual(paths, tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.sub, tc.expected, paths)
+		}
+	}
+	// The original paths are left untouched.
+	if v2[""] != "/sys/fs/cgroup/ct" {
+		t.Errorf("paths modified: %v", v2)
+	}
+}
+
+func TestSettingCgroupPath(t *testing.T) {
+	v1 := map[string]string{"memory": "/m", "pids": "/p"}
+	if p, err := settingCgroupPath(v1, "memory.limit_in_bytes"); err != nil || p != "/m" {
+		t.Errorf("expected /m, got %q (%v)", p, err)
+	}
+	if _, err := settingCgroupPath(v1, "cpu.shares"); err == nil {
+		t.Error("expected error for a missing controller")
+	}
+	if p, err := settingCgroupPath(map[string]string{"": "/u"}, "cpu.max"); err != nil || p != "/u" {
+		t.Errorf("expected /u, got %q (%v)", p, err)
+	}
+}
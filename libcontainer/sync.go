@@ -74,12 +74,22 @@ func (s syncT) String() string {
 // as encoding/json can't unmarshal into error type.
 type initError struct {
 	Message string `json:"message,omitempty"`
+	// Exec is set if the container process could not be executed (see
+	// ErrExec).
+	Exec bool `json:"exec,omitempty"`
 }
 
 func (i initError) Error() string {
 	return i.Message
 }
 
+func (i initError) Unwrap() error {
+	if i.Exec {
+		return ErrExec
+	}
+	return nil
+}
+
 func doWriteSync(pipe *syncSocket, sync syncT) error {
 	sync.Flags &= ^syncFlagHasFd
 	if sync.File != nil {
@@ -60,6 +60,13 @@ type Stat_t struct {
 	// State is the state of the process.
 	State State
 
+	// PPid is the PID of the parent process.
+	PPid int
+
+	// UTime and STime are the user and system CPU time of the process, in
+	// clock ticks.
+	UTime, STime uint64
+
 	// StartTime is the number of clock ticks after system boot (since
 	// Linux 2.6).
 	StartTime uint64
@@ -83,6 +90,9 @@ func parseStat(data string) (stat Stat_t, err error) {
 	//  * field 2: process name. It is the only field enclosed into
 	//    parenthesis, as it can contain spaces (and parenthesis) inside.
 	//  * field 3: process state, a single character (%c)
+	//  * field 4: parent PID (%d)
+	//  * fields 14 and 15: user and system CPU time, long unsigned
+	//    integers (%lu).
 	//  * field 22: process start time, a long unsigned integer (%llu).
 
 	// 1. Look for the first '(' and the last ')' first, what's in between is Name.
@@ -105,22 +115,27 @@ func parseStat(data string) (stat Stat_t, err error) {
 	// 2. Remove fields 1 and 2 and a space after. State is right after.
 	data = data[last+2:]
 	stat.State = State(data[0])
-
-	// 3. StartTime is field 22, data is at field 3 now, so we need to skip 19 spaces.
-	skipSpaces := 22 - 3
-	for first = 0; skipSpaces > 0 && first < len(data); first++ {
-		if data[first] == ' ' {
-			skipSpaces--
+	if i := strings.IndexByte(data[2:], ' '); i > 0 {
+		stat.PPid, err = strconv.Atoi(data[2 : 2+i])
+		if err != nil {
+			return stat, fmt.Errorf("invalid stat data (bad ppid): %w", err)
 		}
 	}
-	// Now first points to StartTime; look for space right after.
-	i := strings.IndexByte(data[first:], ' ')
-	if i < 0 {
+
+	// 3. UTime, STime and StartTime are fields 14, 15 and 22, data is at
+	//    field 3 now. Split it up to StartTime and a space after.
+	fields := strings.SplitN(data, " ", 22-3+2)
+	if len(fields) < 22-3+2 {
 		return stat, fmt.Errorf("invalid stat data (too short): %q", data)
 	}
-	stat.StartTime, err = strconv.ParseUint(data[first:first+i], 10, 64)
-	if err != nil {
-		return stat, fmt.Errorf("invalid stat data (bad start time): %w", err)
+	for _, f := range []struct {
+		index int
+		val   *uint64
+		name  string
+	}{{14, &stat.UTime, "utime"}, {15, &stat.STime, "stime"}, {22, &stat.StartTime, "start time"}} {
+		if *f.val, err = strconv.ParseUint(fields[f.index-3], 10, 64); err != nil {
+			return stat, fmt.Errorf("invalid stat data (bad %s): %w", f.name, err)
+		}
 	}
 
 	return stat, nil
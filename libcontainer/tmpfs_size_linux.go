@@ -0,0 +1,190 @@
+package libcontainer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"unsafe"
+
+	"github.com/moby/sys/mountinfo"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// fsconfig(2) commands, not in x/sys/unix yet.
+const (
+	fsconfigSetString       = 1 // FSCONFIG_SET_STRING
+	fsconfigCmdReconfigure  = 7 // FSCONFIG_CMD_RECONFIGURE
+	fsconfigLogMessageLimit = 1024
+)
+
+func fsconfig(fd int, cmd uint, key, value string) error {
+	var k, v *byte
+	var err error
+	if key != "" {
+		if k, err = unix.BytePtrFromString(key); err != nil {
+			return err
+		}
+	}
+	if value != "" {
+		if v, err = unix.BytePtrFromString(value); err != nil {
+			return err
+		}
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_FSCONFIG, uintptr(fd), uintptr(cmd),
+		uintptr(unsafe.Pointer(k)), uintptr(unsafe.Pointer(v)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// fsContextLog returns the messages logged by the kernel to the filesystem
+// context fd, which usually tell why fsconfig failed.
+func fsContextLog(fd int) string {
+	var msgs [][]byte
+	buf := make([]byte, fsconfigLogMessageLimit)
+	for {
+		n, err := unix.Read(fd, buf)
+		if err != nil || n <= 0 {
+			break
+		}
+		msgs = append(msgs, bytes.TrimSpace(append([]byte(nil), buf[:n]...)))
+	}
+	return string(bytes.Join(msgs, []byte("; ")))
+}
+
+// SetTmpfsSize changes the size (in bytes) and the maximum number of inodes
+// of the container tmpfs mounted at dest (such as /dev/shm or /tmp), while
+// the container is running, and saves the new values in the container
+// configuration. Zero size or inodes means no change. The tmpfs can not be
+// made smaller than its current usage.
+//
+// This requires the new mount API (Linux 5.2+).
+func (c *Container) SetTmpfsSize(dest string, size int64, inodes uint64) error {
+	if size < 0 {
+		return fmt.Errorf("invalid tmpfs size %d", size)
+	}
+	if size == 0 && inodes == 0 {
+		return nil
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	dest = utils.CleanPath(dest)
+	var mnt *configs.Mount
+	for _, m := range c.config.Mounts {
+		if m.Device == "tmpfs" && utils.CleanPath(m.Destination) == dest {
+			mnt = m
+		}
+	}
+	if mnt == nil {
+		return fmt.Errorf("no tmpfs is mounted at %s in the container", dest)
+	}
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+
+	if err := c.reconfigureTmpfs(dest, size, inodes); err != nil {
+		return fmt.Errorf("unable to resize the %s tmpfs: %w", dest, err)
+	}
+
+	if size != 0 {
+		mnt.SetDataOption("size", strconv.FormatInt(size, 10))
+		switch dest {
+		case "/dev/shm":
+			c.config.ShmSize = size
+		case "/tmp":
+			c.config.TmpSize = size
+		}
+	}
+	if inodes != 0 {
+		mnt.SetDataOption("nr_inodes", strconv.FormatUint(inodes, 10))
+		if dest == "/tmp" {
+			c.config.TmpInodes = inodes
+		}
+	}
+	_, err = c.updateState(nil)
+	return err
+}
+
+func (c *Container) reconfigureTmpfs(dest string, size int64, inodes uint64) error {
+	root, err := c.rootFS()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	// Do not follow a symlink the container may have put in place of an
+	// unmounted tmpfs.
+	dir, err := root.OpenFile(dest, unix.O_PATH|unix.O_DIRECTORY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	// Make sure this is the tmpfs mounted at dest in the container mount
+	// namespace, and not some other mount the path resolves to.
+	var stx unix.Statx_t
+	if err := unix.Statx(int(dir.Fd()), "", unix.AT_EMPTY_PATH|unix.AT_SYMLINK_NOFOLLOW, unix.STATX_MNT_ID, &stx); err != nil {
+		return &os.PathError{Op: "statx", Path: dest, Err: err}
+	}
+	if stx.Mask&unix.STATX_MNT_ID == 0 {
+		return errors.New("unable to get the mount ID (kernel too old?)")
+	}
+	f, err := os.Open("/proc/" + strconv.Itoa(c.initProcess.pid()) + "/mountinfo")
+	if err != nil {
+		return err
+	}
+	mounts, err := mountinfo.GetMountsFromReader(f, nil)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+	var found bool
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if mounts[i].Mountpoint == dest {
+			found = mounts[i].ID == int(stx.Mnt_id) && mounts[i].FSType == "tmpfs"
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s is no longer a tmpfs mount point", dest)
+	}
+
+	fd, err := unix.Fspick(int(dir.Fd()), "", unix.FSPICK_EMPTY_PATH|unix.FSPICK_CLOEXEC)
+	if err != nil {
+		return os.NewSyscallError("fspick", err)
+	}
+	defer unix.Close(fd)
+	set := func(key, value string) error {
+		if err := fsconfig(fd, fsconfigSetString, key, value); err != nil {
+			return fmt.Errorf("fsconfig %s=%s: %w", key, value, err)
+		}
+		return nil
+	}
+	if size != 0 {
+		if err := set("size", strconv.FormatInt(size, 10)); err != nil {
+			return err
+		}
+	}
+	if inodes != 0 {
+		if err := set("nr_inodes", strconv.FormatUint(inodes, 10)); err != nil {
+			return err
+		}
+	}
+	if err := fsconfig(fd, fsconfigCmdReconfigure, "", ""); err != nil {
+		if msg := fsContextLog(fd); msg != "" {
+			return fmt.Errorf("fsconfig reconfigure: %w (%s)", err, msg)
+		}
+		return fmt.Errorf("fsconfig reconfigure: %w", err)
+	}
+	return nil
+}
@@ -121,10 +121,11 @@ func GetUserNamespaceMappings(nsPath string) (uidMap, gidMap []configs.IDMap, er
 	// just use the pid to read from /proc/<pid>/*id_map.
 	//
 	// Note that Sscanf doesn't consume the whole input, so we check for any
-	// trailing data with %c. That way, we can be sure the pattern matched
-	// /proc/$pid/ns/user _exactly_ iff n === 1.
+	// trailing data with %c. As n is also 1 if the input diverges from the
+	// pattern after the pid (like /proc/$pid/fd/$fd, which refers to a
+	// namespace of another process), the path is checked to match exactly.
 	if n, _ := fmt.Sscanf(nsPath, "/proc/%d/ns/user%c", &pid, &extra); n == 1 {
-		tryFastPath = pid > 0
+		tryFastPath = pid > 0 && nsPath == fmt.Sprintf("/proc/%d/ns/user", pid)
 	}
 
 	for _, mapType := range []struct {
@@ -184,3 +185,37 @@ func IsSameMapping(a, b []configs.IDMap) bool {
 	}
 	return true
 }
+
+// IsSubsetMapping returns whether every ID mapped by sub is also mapped by
+// full, to the same host ID. An empty sub is a subset of any mapping.
+func IsSubsetMapping(sub, full []configs.IDMap) bool {
+	for _, m := range sub {
+		if m.Size <= 0 {
+			return false
+		}
+		id, host, size := m.ContainerID, m.HostID, m.Size
+		// The range may span several (adjacent) ranges of full.
+		for size > 0 {
+			var found bool
+			for _, f := range full {
+				if id < f.ContainerID || id >= f.ContainerID+f.Size {
+					continue
+				}
+				if host != f.HostID+(id-f.ContainerID) {
+					return false
+				}
+				n := f.ContainerID + f.Size - id
+				if n > size {
+					n = size
+				}
+				id, host, size = id+n, host+n, size-n
+				found = true
+				break
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package userns
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestIsSubsetMapping(t *testing.T) {
+	full := []configs.IDMap{
+		{ContainerID: 0, HostID: 1000, Size: 1},
+		{ContainerID: 1, HostID: 100000, Size: 65536},
+		{ContainerID: 65537, HostID: 165536, Size: 1000},
+	}
+	for _, tc := range []struct {
+		sub    []configs.IDMap
+		subset bool
+	}{
+		{sub: nil, subset: true},
+		{sub: full, subset: true},
+		{sub: []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 1}}, subset: true},
+		{sub: []configs.IDMap{{ContainerID: 10, HostID: 100009, Size: 100}}, subset: true},
+		// Spans two adjacent ranges.
+		{sub: []configs.IDMap{{ContainerID: 65000, HostID: 164999, Size: 1000}}, subset: true},
+		// Spans two non-adjacent host ranges.
+		{sub: []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 2}}, subset: false},
+		// Different host ID.
+		{sub: []configs.IDMap{{ContainerID: 0, HostID: 1001, Size: 1}}, subset: false},
+		// Beyond the mapped range.
+		{sub: []configs.IDMap{{ContainerID: 66000, HostID: 165999, Size: 1000}}, subset: false},
+		{sub: []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 0}}, subset: false},
+	} {
+		if got := IsSubsetMapping(tc.sub, full); got != tc.subset {
+			t.Errorf("IsSubsetMapping(%v): expected %v, got %v", tc.sub, tc.subset, got)
+		}
+	}
+}
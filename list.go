@@ -5,12 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
 	"github.com/moby/sys/user"
 	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/urfave/cli"
 )
@@ -38,6 +42,112 @@ type containerState struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 	// The owner of the state directory (the owner of the container).
 	Owner string `json:"owner"`
+	// Labels is the user defined metadata set by runc label.
+	Labels map[string]string `json:"labels,omitempty"`
+	// IPs are the container IP addresses assigned by CNI, if any.
+	IPs []string `json:"ips,omitempty"`
+	// ForeignProcesses are the PIDs of the processes found in the
+	// container cgroup which do not belong to the container, if any
+	// (see runc audit).
+	ForeignProcesses []int `json:"foreign_processes,omitempty"`
+	// Peaks are the container resource usage high-watermarks.
+	Peaks *libcontainer.ResourcePeaks `json:"peaks,omitempty"`
+	// SetupCost is the cost of the container setup by runc.
+	SetupCost *libcontainer.SetupCost `json:"setup_cost,omitempty"`
+	// OomScoreAdj is the oom_score_adj of the container init process, and
+	// HelperOomScoreAdj is the one runc init had during the container
+	// setup.
+	OomScoreAdj       *int `json:"oom_score_adj,omitempty"`
+	HelperOomScoreAdj *int `json:"helper_oom_score_adj,omitempty"`
+	// RuntimeMaskPaths are the paths masked by runc update --mask-path.
+	RuntimeMaskPaths []string `json:"runtime_mask_paths,omitempty"`
+	// ExitReason is why the container was stopped by runc, if it was
+	// (such as "timeout", see runc run --timeout).
+	ExitReason string `json:"exit_reason,omitempty"`
+	// Security is the security state of the container init process, as
+	// seen by the kernel (runc state --security only).
+	Security *libcontainer.SecurityState `json:"security,omitempty"`
+	// CgroupPaths are the paths of the container cgroups, by controller
+	// (cgroup v1), or with an empty key (cgroup v2).
+	CgroupPaths map[string]string `json:"cgroup_paths,omitempty"`
+	// CgroupDriver is the cgroup driver managing the container cgroups,
+	// "systemd" or "cgroupfs".
+	CgroupDriver string `json:"cgroup_driver"`
+	// Frozen tells whether the container cgroup is frozen (that is, if
+	// the container is paused).
+	Frozen bool `json:"frozen"`
+	// Resources are the configured resource limits of the container.
+	Resources *containerResources `json:"resources,omitempty"`
+	// Rootless tells whether the container was created by an unprivileged
+	// user, and RootlessCgroups whether its cgroups are managed as such.
+	Rootless        bool `json:"rootless"`
+	RootlessCgroups bool `json:"rootless_cgroups"`
+	// Namespaces tells, for each namespace of the container, whether it
+	// was created by runc, joined, or inherited from runc, with its inode
+	// (runc state only).
+	Namespaces []libcontainer.NamespaceState `json:"namespaces,omitempty"`
+	// ConfigHash is the digest of the bundle config.json the container was
+	// created from, and BundleConfigHash the one of the current bundle
+	// config.json, if it is still there (runc state only).
+	ConfigHash       string `json:"config_hash,omitempty"`
+	BundleConfigHash string `json:"bundle_config_hash,omitempty"`
+	// CgroupV2Unavailable lists the cgroup v2 only features which are not
+	// available on the host, with the fallback used instead (runc state
+	// only).
+	CgroupV2Unavailable []cgroups.V2Feature `json:"cgroup_v2_unavailable,omitempty"`
+}
+
+// containerResources are the configured resource limits of a container
+// (see runc update), unset ones being omitted. The fields are the ones of
+// configs.Resources.
+type containerResources struct {
+	Memory            int64             `json:"memory,omitempty"`
+	MemoryReservation int64             `json:"memory_reservation,omitempty"`
+	MemorySwap        int64             `json:"memory_swap,omitempty"`
+	CPUShares         uint64            `json:"cpu_shares,omitempty"`
+	CPUWeight         uint64            `json:"cpu_weight,omitempty"`
+	CPUQuota          int64             `json:"cpu_quota,omitempty"`
+	CPUPeriod         uint64            `json:"cpu_period,omitempty"`
+	CPUBurst          *uint64           `json:"cpu_burst,omitempty"`
+	CpusetCpus        string            `json:"cpuset_cpus,omitempty"`
+	CpusetMems        string            `json:"cpuset_mems,omitempty"`
+	PidsLimit         int64             `json:"pids_limit,omitempty"`
+	BlkioWeight       uint16            `json:"blkio_weight,omitempty"`
+	Unified           map[string]string `json:"unified,omitempty"`
+}
+
+// setCgroupState sets the cgroup related fields of cs, and the rootless
+// mode, from the container state and status.
+func (cs *containerState) setCgroupState(state *libcontainer.State, status libcontainer.Status) {
+	cs.CgroupPaths = state.CgroupPaths
+	cs.CgroupDriver = "cgroupfs"
+	cs.Rootless = state.Config.RootlessEUID
+	cs.RootlessCgroups = state.Config.RootlessCgroups
+	cs.Frozen = status == libcontainer.Paused
+	cg := state.Config.Cgroups
+	if cg == nil {
+		return
+	}
+	if cg.Systemd {
+		cs.CgroupDriver = "systemd"
+	}
+	if r := cg.Resources; r != nil {
+		cs.Resources = &containerResources{
+			Memory:            r.Memory,
+			MemoryReservation: r.MemoryReservation,
+			MemorySwap:        r.MemorySwap,
+			CPUShares:         r.CpuShares,
+			CPUWeight:         r.CpuWeight,
+			CPUQuota:          r.CpuQuota,
+			CPUPeriod:         r.CpuPeriod,
+			CPUBurst:          r.CpuBurst,
+			CpusetCpus:        r.CpusetCpus,
+			CpusetMems:        r.CpusetMems,
+			PidsLimit:         r.PidsLimit,
+			BlkioWeight:       r.BlkioWeight,
+			Unified:           r.Unified,
+		}
+	}
 }
 
 var listCommand = cli.Command{
@@ -54,26 +164,68 @@ To list containers created via the default "--root":
 
 EXAMPLE 2:
 To list containers created using a non-default value for "--root":
-       # runc --root value list`,
+       # runc --root value list
+
+EXAMPLE 3:
+To print the ID and the PID of the running containers:
+       # runc list --filter status=running --format '{{.ID}} {{.InitProcessPid}}'`,
 	Flags: []cli.Flag{
 		cli.StringFlag{
 			Name:  "format, f",
 			Value: "table",
-			Usage: `select one of: ` + formatOptions,
+			Usage: `select one of: ` + formatOptions + `, or a Go template applied to each container`,
+		},
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "only list containers matching the filter (status, id, owner, label or annotation)=value (can be specified multiple times)",
 		},
 		cli.BoolFlag{
 			Name:  "quiet, q",
 			Usage: "display only container IDs",
 		},
+		cli.StringSliceFlag{
+			Name:  "label, l",
+			Usage: "only list containers having the label key=value (can be specified multiple times, all must match)",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 0, exactArgs); err != nil {
 			return err
 		}
+		filter, err := parseListFilter(context.StringSlice("filter"), context.StringSlice("label"))
+		if err != nil {
+			return err
+		}
+		var tmpl *template.Template
+		switch format := context.String("format"); format {
+		case "table", "json":
+		default:
+			if !strings.Contains(format, "{{") {
+				return errors.New("invalid format option")
+			}
+			tmpl, err = template.New("format").Funcs(template.FuncMap{
+				"json": func(v interface{}) (string, error) {
+					b, err := json.Marshal(v)
+					return string(b), err
+				},
+			}).Parse(format)
+			if err != nil {
+				return fmt.Errorf("invalid format template: %w", err)
+			}
+		}
 		s, err := getContainers(context)
 		if err != nil {
 			return err
 		}
+		if len(filter) > 0 {
+			var matched []containerState
+			for _, item := range s {
+				if filter.match(&item) {
+					matched = append(matched, item)
+				}
+			}
+			s = matched
+		}
 
 		if context.Bool("quiet") {
 			for _, item := range s {
@@ -82,6 +234,16 @@ To list containers created using a non-default value for "--root":
 			return nil
 		}
 
+		if tmpl != nil {
+			for _, item := range s {
+				if err := tmpl.Execute(os.Stdout, item); err != nil {
+					return err
+				}
+				fmt.Println()
+			}
+			return nil
+		}
+
 		switch context.String("format") {
 		case "table":
 			w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
@@ -102,13 +264,99 @@ To list containers created using a non-default value for "--root":
 			if err := json.NewEncoder(os.Stdout).Encode(s); err != nil {
 				return err
 			}
-		default:
-			return errors.New("invalid format option")
 		}
 		return nil
 	},
 }
 
+// listFilter are the runc list --filter and --label conditions. A container
+// matches if it meets all the conditions.
+type listFilter []listCondition
+
+// listCondition is met by a container matching any of the values for key.
+type listCondition struct {
+	key  string
+	vals []string
+}
+
+// parseListFilter parses the --filter values, grouped by key, and the
+// --label values, each of which is a condition of its own (so, unlike
+// with --filter label=..., all the labels must match).
+func parseListFilter(filters, labels []string) (listFilter, error) {
+	var filter listFilter
+	byKey := make(map[string]int)
+	for _, val := range filters {
+		k, v, ok := strings.Cut(val, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --filter %q: must be key=value", val)
+		}
+		switch k {
+		case "status":
+			switch v {
+			case "created", "running", "paused", "stopped":
+			default:
+				return nil, fmt.Errorf("invalid --filter %q: unknown status %q", val, v)
+			}
+		case "id", "owner":
+		case "label", "annotation":
+			// The value is either a key (which must be set) or key=value.
+			if name, _, _ := strings.Cut(v, "="); name == "" {
+				return nil, fmt.Errorf("invalid --filter %q: empty %s key", val, k)
+			}
+		default:
+			return nil, fmt.Errorf("invalid --filter %q: unknown key %q (must be status, id, owner, label or annotation)", val, k)
+		}
+		if i, ok := byKey[k]; ok {
+			filter[i].vals = append(filter[i].vals, v)
+			continue
+		}
+		byKey[k] = len(filter)
+		filter = append(filter, listCondition{key: k, vals: []string{v}})
+	}
+	for _, val := range labels {
+		if k, _, ok := strings.Cut(val, "="); !ok || k == "" {
+			return nil, fmt.Errorf("invalid --label %q: must be key=value", val)
+		}
+		filter = append(filter, listCondition{key: "label", vals: []string{val}})
+	}
+	return filter, nil
+}
+
+func (filter listFilter) match(c *containerState) bool {
+	for _, cond := range filter {
+		matched := false
+		for _, v := range cond.vals {
+			switch cond.key {
+			case "status":
+				matched = c.Status == v
+			case "id":
+				matched = c.ID == v
+			case "owner":
+				matched = c.Owner == v
+			case "label":
+				matched = matchKeyValue(c.Labels, v)
+			case "annotation":
+				matched = matchKeyValue(c.Annotations, v)
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchKeyValue reports whether m has the key kv is set to (if kv is just a
+// key), or the key=value pair kv.
+func matchKeyValue(m map[string]string, kv string) bool {
+	k, v, ok := strings.Cut(kv, "=")
+	val, found := m[k]
+	return found && (!ok || val == v)
+}
+
 func getContainers(context *cli.Context) ([]containerState, error) {
 	root := context.GlobalString("root")
 	list, err := os.ReadDir(root)
@@ -123,7 +371,7 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 	}
 	var s []containerState
 	for _, item := range list {
-		if !item.IsDir() {
+		if !item.IsDir() || item.Name() == libcontainer.LockDir || isTenantDir(filepath.Join(root, item.Name())) {
 			continue
 		}
 		st, err := item.Info()
@@ -161,7 +409,7 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 			pid = 0
 		}
 		bundle, annotations := utils.Annotations(state.Config.Labels)
-		s = append(s, containerState{
+		cs := containerState{
 			Version:        state.BaseState.Config.Version,
 			ID:             state.BaseState.ID,
 			InitProcessPid: pid,
@@ -171,7 +419,10 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 			Created:        state.BaseState.Created,
 			Annotations:    annotations,
 			Owner:          owner.Name,
-		})
+			Labels:         state.BaseState.Metadata,
+		}
+		cs.setCgroupState(state, containerStatus)
+		s = append(s, cs)
 	}
 	return s, nil
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestListFilter(t *testing.T) {
+	c := &containerState{
+		ID:          "c1",
+		Status:      "running",
+		Owner:       "root",
+		Labels:      map[string]string{"app": "web"},
+		Annotations: map[string]string{"org.example.tier": "front", "org.example.empty": ""},
+	}
+	for _, tc := range []struct {
+		filter  []string
+		matched bool
+	}{
+		{filter: nil, matched: true},
+		{filter: []string{"status=running"}, matched: true},
+		{filter: []string{"status=stopped"}, matched: false},
+		{filter: []string{"status=stopped", "status=running"}, matched: true},
+		{filter: []string{"status=running", "id=c2"}, matched: false},
+		{filter: []string{"id=c1", "owner=root"}, matched: true},
+		{filter: []string{"label=app=web"}, matched: true},
+		{filter: []string{"label=app=db"}, matched: false},
+		{filter: []string{"label=app"}, matched: true},
+		{filter: []string{"annotation=org.example.tier=front"}, matched: true},
+		{filter: []string{"annotation=org.example.empty"}, matched: true},
+		{filter: []string{"annotation=org.example.empty="}, matched: true},
+		{filter: []string{"annotation=org.example.missing"}, matched: false},
+	} {
+		filter, err := parseListFilter(tc.filter, nil)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.filter, err)
+			continue
+		}
+		if m := filter.match(c); m != tc.matched {
+			t.Errorf("%q: expected match %v, got %v", tc.filter, tc.matched, m)
+		}
+	}
+
+	for _, f := range []string{"status", "status=up", "name=c1", "label==x", "annotation="} {
+		if _, err := parseListFilter([]string{f}, nil); err == nil {
+			t.Errorf("%q: expected error, got nil", f)
+		}
+	}
+}
+
+func TestListFilterLabels(t *testing.T) {
+	c := &containerState{
+		ID:     "c1",
+		Status: "running",
+		Labels: map[string]string{"app": "web", "owner": "alice"},
+	}
+	for _, tc := range []struct {
+		filter, labels []string
+		matched        bool
+	}{
+		{labels: []string{"app=web"}, matched: true},
+		{labels: []string{"app=web", "owner=alice"}, matched: true},
+		// Unlike --filter label=..., all the --label values must match.
+		{labels: []string{"app=web", "app=db"}, matched: false},
+		{filter: []string{"label=app=web", "label=app=db"}, matched: true},
+		{filter: []string{"status=running"}, labels: []string{"owner=bob"}, matched: false},
+		{filter: []string{"label=app=db"}, labels: []string{"owner=alice"}, matched: false},
+	} {
+		filter, err := parseListFilter(tc.filter, tc.labels)
+		if err != nil {
+			t.Errorf("%q %q: unexpected error: %v", tc.filter, tc.labels, err)
+			continue
+		}
+		if m := filter.match(c); m != tc.matched {
+			t.Errorf("%q %q: expected match %v, got %v", tc.filter, tc.labels, tc.matched, m)
+		}
+	}
+
+	for _, l := range []string{"app", "=web"} {
+		if _, err := parseListFilter(nil, []string{l}); err == nil {
+			t.Errorf("%q: expected error, got nil", l)
+		}
+	}
+}
+
+func TestSetCgroupState(t *testing.T) {
+	burst := uint64(1000)
+	state := &libcontainer.State{
+		BaseState: libcontainer.BaseState{
+			Config: configs.Config{
+				RootlessEUID: true,
+				Cgroups: &configs.Cgroup{
+					Systemd: true,
+					Resources: &configs.Resources{
+						Memory:     1 << 20,
+						CpuQuota:   50000,
+						CpuPeriod:  100000,
+						CpuBurst:   &burst,
+						CpusetCpus: "0-1",
+						PidsLimit:  10,
+					},
+				},
+			},
+		},
+		CgroupPaths: map[string]string{"": "/sys/fs/cgroup/system.slice/runc-test.scope"},
+	}
+	var cs containerState
+	cs.setCgroupState(state, libcontainer.Paused)
+	expected := containerState{
+		CgroupPaths:  state.CgroupPaths,
+		CgroupDriver: "systemd",
+		Frozen:       true,
+		Resources: &containerResources{
+			Memory:     1 << 20,
+			CPUQuota:   50000,
+			CPUPeriod:  100000,
+			CPUBurst:   &burst,
+			CpusetCpus: "0-1",
+			PidsLimit:  10,
+		},
+		Rootless: true,
+	}
+	if !reflect.DeepEqual(cs, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cs)
+	}
+
+	cs = containerState{}
+	state.Config.Cgroups.Systemd = false
+	cs.setCgroupState(state, libcontainer.Running)
+	if cs.CgroupDriver != "cgroupfs" || cs.Frozen {
+		t.Errorf("unexpected driver %q or frozen %v", cs.CgroupDriver, cs.Frozen)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/extcmd"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runtime-spec/specs-go"
 
@@ -75,13 +77,7 @@ func main() {
 	app.Version = strings.Join(v, "\n")
 
 	/*指明默认root*/
-	root := "/run/runc"
-	xdgDirUsed := false
-	xdgRuntimeDir := os.Getenv("XDG_RUNTIME_DIR")
-	if xdgRuntimeDir != "" && shouldHonorXDGRuntimeDir() {
-		root = xdgRuntimeDir + "/runc"
-		xdgDirUsed = true
-	}
+	root, rootKind := defaultRootDir()
 
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
@@ -99,9 +95,19 @@ func main() {
 			Usage: "set the log format ('text' (default), or 'json')",
 		},
 		cli.StringFlag{
-			Name:  "root",
-			Value: root,
-			Usage: "root directory for storage of container state (this should be located in tmpfs)",
+			Name:   "root",
+			Value:  root,
+			EnvVar: "RUNC_ROOT",
+			Usage:  "root directory for storage of container state (this should be located in tmpfs)",
+		},
+		cli.StringFlag{
+			Name:  "root-mode",
+			Usage: "create the root directory with (or change it to) this octal mode, such as 1777 to share it between users",
+		},
+		cli.StringFlag{
+			Name:   "tenant",
+			EnvVar: "RUNC_TENANT",
+			Usage:  "keep the container state in the per-tenant subdirectory of the root directory, so that container IDs of different tenants do not collide",
 		},
 		cli.StringFlag{
 			Name:   "criu",
@@ -117,17 +123,63 @@ func main() {
 			Value: "auto",
 			Usage: "ignore cgroup permission errors ('true', 'false', or 'auto')",
 		},
+		cli.IntFlag{
+			Name:   "helper-oom-score-adj",
+			EnvVar: "RUNC_HELPER_OOM_SCORE_ADJ",
+			Usage:  "set the oom_score_adj of runc itself (and of runc init during the container setup), rather than inheriting it",
+		},
+		cli.IntFlag{
+			Name:   "helper-gomaxprocs",
+			EnvVar: "RUNC_HELPER_GOMAXPROCS",
+			Usage:  "limit the number of CPUs runc itself runs on at once (its GOMAXPROCS)",
+		},
+		cli.StringFlag{
+			Name:   "helper-memory-limit",
+			EnvVar: "RUNC_HELPER_MEMORY_LIMIT",
+			Usage:  "set a soft memory limit of runc itself (such as 32M), beyond which it collects garbage more aggressively",
+		},
+		cli.IntFlag{
+			Name:   "helper-nice",
+			EnvVar: "RUNC_HELPER_NICE",
+			Usage:  "set the niceness of runc itself (and of runc init during the container setup), rather than inheriting it",
+		},
+		cli.IntFlag{
+			Name:   "helper-max-parallel",
+			EnvVar: "RUNC_HELPER_MAX_PARALLEL",
+			Usage:  "limit the number of containers runc creates at once when creating several (default: its GOMAXPROCS)",
+		},
+		cli.StringSliceFlag{
+			Name:   "allowed-mount-source",
+			EnvVar: "RUNC_ALLOWED_MOUNT_SOURCES",
+			Usage:  "restrict the bind mount sources of the containers to this host directory and its subdirectories (can be specified multiple times)",
+		},
+		cli.BoolFlag{
+			Name:   "error-exit-codes",
+			EnvVar: "RUNC_ERROR_EXIT_CODES",
+			Usage:  "exit with a distinct status for each error code, rather than 1 (or 255 for exec)",
+		},
+		cli.StringSliceFlag{
+			Name:   "tool-timeout",
+			EnvVar: "RUNC_TOOL_TIMEOUT",
+			Usage:  "set the timeout for an external binary runc runs, as name=duration (e.g. newuidmap=5s), or the default one, as duration (0 means no timeout; can be specified multiple times)",
+		},
 	}
 	
 	/*定义支持的命令*/
 	app.Commands = []cli.Command{
+		auditCommand,
 		checkpointCommand,
+		cliSchemaCommand,
+		completionCommand,
 		createCommand,
 		deleteCommand,
+		doctorCommand,
 		eventsCommand,
 		execCommand,
 		killCommand,
+		labelCommand,
 		listCommand,
+		migrateCommand,
 		pauseCommand,
 		psCommand,
 		restoreCommand,
@@ -136,32 +188,50 @@ func main() {
 		specCommand,
 		startCommand,
 		stateCommand,
+		topCommand,
 		updateCommand,
+		validateCommand,
 		featuresCommand,
 	}
 	app.Before = func(context *cli.Context) error {
-		if !context.IsSet("root") && xdgDirUsed {
-			// According to the XDG specification, we need to set anything in
-			// XDG_RUNTIME_DIR to have a sticky bit if we don't want it to get
-			// auto-pruned.
-			if err := os.MkdirAll(root, 0o700); err != nil {
-				fmt.Fprintln(os.Stderr, "the path in $XDG_RUNTIME_DIR must be writable by the user")
-				fatal(err)
-			}
-			if err := os.Chmod(root, os.FileMode(0o700)|os.ModeSticky); err != nil {
-				fmt.Fprintln(os.Stderr, "you should check permission of the path in $XDG_RUNTIME_DIR")
-				fatal(err)
+		// Set up logging first, so that the root directory setup can log.
+		if err := configLogrus(context); err != nil {
+			return err
+		}
+		errorExitCodes = context.GlobalBool("error-exit-codes")
+		if !context.IsSet("root") {
+			if err := prepareRootDir(root, rootKind); err != nil {
+				return err
 			}
 		}
 		if err := reviseRootDir(context); err != nil {
 			return err
 		}
+		if err := applyRootMode(context); err != nil {
+			return err
+		}
+		if err := setupTenant(context); err != nil {
+			return err
+		}
+		for _, t := range context.StringSlice("tool-timeout") {
+			if err := extcmd.ParseTimeout(t); err != nil {
+				return err
+			}
+		}
+		if context.IsSet("helper-oom-score-adj") {
+			if err := libcontainer.WriteOomScoreAdj(0, context.Int("helper-oom-score-adj")); err != nil {
+				return fmt.Errorf("unable to set helper oom_score_adj: %w", err)
+			}
+		}
+		if err := applyHelperLimits(context); err != nil {
+			return err
+		}
 		// TODO: remove this in runc 1.3.0.
 		if context.IsSet("criu") {
 			fmt.Fprintln(os.Stderr, "WARNING: --criu ignored (criu binary from $PATH is used); do not use")
 		}
 
-		return configLogrus(context)
+		return nil
 	}
 
 	// If the command returns an error, cli takes upon itself to print
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	criu "github.com/checkpoint-restore/go-criu/v6/rpc"
+	"github.com/docker/go-units"
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+)
+
+var migrateCommand = cli.Command{
+	Name:  "migrate",
+	Usage: "migrate a running container to another host",
+	ArgsUsage: `<container-id> <destination>
+
+Where "<container-id>" is the name for the instance of the container to be
+migrated, and "<destination>" is the host to migrate it to, as passed to the
+--rsh command (such as [user@]host for ssh).`,
+	Description: `The migrate command moves a running container to another host, where runc
+and criu are installed, and the container bundle is available (at the same
+path, unless --remote-bundle is set).
+
+The container memory is first copied by a number of pre-dumps (see
+--pre-dumps), while it keeps running. The container is then checkpointed,
+the remaining images are copied, and the container is restored on the
+destination host. If the restore fails there, the container is restored
+locally. The images are copied over the --rsh command, and must not be
+needed after the migration.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "rsh",
+			Value: "ssh",
+			Usage: "`command` used to run commands on the destination host, which is given as its first argument",
+		},
+		cli.StringFlag{
+			Name:  "remote-runc",
+			Value: "runc",
+			Usage: "runc `command` on the destination host, including its global options, such as \"runc --root /run/runc\"",
+		},
+		cli.StringFlag{
+			Name:  "remote-bundle",
+			Usage: "`path` to the bundle on the destination host (default: the container bundle path)",
+		},
+		cli.StringFlag{
+			Name:  "image-path",
+			Usage: "`path` for saving the criu images locally (default: a temporary directory)",
+		},
+		cli.StringFlag{
+			Name:  "remote-image-path",
+			Usage: "`path` for saving the criu images on the destination host (default: /var/tmp/runc-migrate-<container-id>)",
+		},
+		cli.IntFlag{
+			Name:  "pre-dumps",
+			Value: 1,
+			Usage: "number of pre-dumps copying the container memory before it is checkpointed",
+		},
+		cli.BoolFlag{
+			Name:  "keep-images",
+			Usage: "do not remove the criu images once the migration is done",
+		},
+		cli.BoolFlag{Name: "tcp-established", Usage: "allow open tcp connections"},
+		cli.BoolFlag{Name: "ext-unix-sk", Usage: "allow external unix sockets"},
+		cli.BoolFlag{Name: "file-locks", Usage: "handle file locks, for safety"},
+		cli.StringFlag{Name: "manage-cgroups-mode", Value: "", Usage: "cgroups mode: soft|full|strict|ignore (default: soft)"},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 2, exactArgs); err != nil {
+			return err
+		}
+		m, err := newMigration(context)
+		if err != nil {
+			return err
+		}
+		return m.run(context)
+	},
+}
+
+// migration is a runc migrate run.
+type migration struct {
+	id, dest string
+	// rsh is the command (and its arguments) to run a command on dest.
+	rsh        []string
+	remoteRunc string
+	// bundle is the container bundle, and remoteBundle the one on the
+	// destination host.
+	bundle       string
+	remoteBundle string
+	remoteDir    string
+	localDir     string
+	// tempDir is set if localDir is a temporary directory, and
+	// remoteCreated if remoteDir was created by runc.
+	tempDir       bool
+	remoteCreated bool
+	// localEntries and remoteEntries are the entries of localDir and
+	// remoteDir written by runc, which are removed by cleanup.
+	localEntries  []string
+	remoteEntries []string
+	preDumps      int
+	keepImages    bool
+	// criuFlags are the runc restore flags matching the checkpoint ones.
+	criuFlags []string
+	cgMode    criu.CriuCgMode
+}
+
+func newMigration(context *cli.Context) (*migration, error) {
+	m := &migration{
+		id:         context.Args().First(),
+		dest:       context.Args().Get(1),
+		rsh:        strings.Fields(context.String("rsh")),
+		remoteRunc: context.String("remote-runc"),
+		remoteDir:  context.String("remote-image-path"),
+		localDir:   context.String("image-path"),
+		preDumps:   context.Int("pre-dumps"),
+		keepImages: context.Bool("keep-images"),
+	}
+	if len(m.rsh) == 0 {
+		return nil, errors.New("--rsh must not be empty")
+	}
+	if strings.TrimSpace(m.remoteRunc) == "" {
+		return nil, errors.New("--remote-runc must not be empty")
+	}
+	if m.preDumps < 0 {
+		return nil, errors.New("--pre-dumps must not be negative")
+	}
+	if m.remoteDir == "" {
+		m.remoteDir = "/var/tmp/runc-migrate-" + m.id
+	}
+	if !path.IsAbs(m.remoteDir) {
+		return nil, errors.New("--remote-image-path must be absolute")
+	}
+	for _, f := range []string{"tcp-established", "ext-unix-sk", "file-locks"} {
+		if context.Bool(f) {
+			m.criuFlags = append(m.criuFlags, "--"+f)
+		}
+	}
+	switch mode := context.String("manage-cgroups-mode"); mode {
+	case "":
+	case "soft", "full", "strict", "ignore":
+		m.cgMode = map[string]criu.CriuCgMode{
+			"soft":   criu.CriuCgMode_SOFT,
+			"full":   criu.CriuCgMode_FULL,
+			"strict": criu.CriuCgMode_STRICT,
+			"ignore": criu.CriuCgMode_IGNORE,
+		}[mode]
+		m.criuFlags = append(m.criuFlags, "--manage-cgroups-mode", mode)
+	default:
+		return nil, errors.New("Invalid manage-cgroups-mode value")
+	}
+	return m, nil
+}
+
+// progress reports the migration progress.
+func (m *migration) progress(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "migrate %s: %s\n", m.id, fmt.Sprintf(format, args...))
+}
+
+func (m *migration) run(context *cli.Context) error {
+	lock, err := lockContainer(context, "migrate")
+	if err != nil {
+		return err
+	}
+	locked := true
+	defer func() {
+		if locked {
+			lock.Unlock()
+		}
+	}()
+	container, err := getContainer(context)
+	if err != nil {
+		return err
+	}
+	status, err := container.Status()
+	if err != nil {
+		return err
+	}
+	if status != libcontainer.Running {
+		return fmt.Errorf("cannot migrate a container in %s state", status)
+	}
+	bundle, ok := utils.SearchLabels(container.Config().Labels, "bundle")
+	if !ok {
+		return errors.New("bundle not found in labels")
+	}
+	m.bundle = bundle
+	m.remoteBundle = context.String("remote-bundle")
+	if m.remoteBundle == "" {
+		m.remoteBundle = bundle
+	}
+
+	if m.localDir == "" {
+		if m.localDir, err = os.MkdirTemp("", "runc-migrate-"); err != nil {
+			return err
+		}
+		m.tempDir = true
+	} else if err := os.MkdirAll(m.localDir, 0o700); err != nil {
+		return err
+	}
+
+	// Check the destination before dumping anything. Nothing is written
+	// there if this fails, so only the local directory is cleaned up.
+	m.progress("checking %s", m.dest)
+	dir := shellQuote(m.remoteDir)
+	out, err := m.remoteOutput(shellQuoteCmd(m.remoteRunc) + " --version >/dev/null && " +
+		"if [ -d " + dir + " ]; then echo exists; else mkdir -p " + dir + " && echo created; fi")
+	if err != nil {
+		m.cleanup()
+		return err
+	}
+	m.remoteCreated = strings.TrimSpace(out) == "created"
+
+	// Until the container is checkpointed, it keeps running here, and
+	// a failure only requires to clean the images up.
+	parent := ""
+	for i := 1; i <= m.preDumps; i++ {
+		name := "pre-dump-" + strconv.Itoa(i)
+		m.progress("pre-dump %d/%d", i, m.preDumps)
+		if err := container.Checkpoint(m.criuOpts(name, parent, true)); err != nil {
+			m.cleanup()
+			return fmt.Errorf("pre-dump failed: %w", err)
+		}
+		if err := m.send(name); err != nil {
+			m.cleanup()
+			return err
+		}
+		parent = name
+	}
+	m.progress("dump")
+	if err := container.Checkpoint(m.criuOpts("dump", parent, false)); err != nil {
+		m.cleanup()
+		return fmt.Errorf("dump failed: %w", err)
+	}
+	if err := container.Destroy(); err != nil {
+		logrus.Warn(err)
+	}
+	// The container now only exists in the images; on failure, it is
+	// restored locally (which requires the container lock).
+	lock.Unlock()
+	locked = false
+
+	err = m.send("dump")
+	if err == nil {
+		m.progress("restoring on %s", m.dest)
+		m.remoteEntries = append(m.remoteEntries, "restore.log")
+		err = m.remote(m.remoteRestoreCmd(), nil)
+	}
+	if err != nil {
+		m.progress("migration failed, restoring locally")
+		if rerr := m.restoreLocally(context); rerr != nil {
+			// Keep the images, from which the container can
+			// still be restored.
+			return fmt.Errorf("%w (and unable to restore the container locally: %v; the images are in %s)", err, rerr, m.localDir)
+		}
+		m.cleanup()
+		return fmt.Errorf("migration failed, the container was restored locally: %w", err)
+	}
+	m.cleanup()
+	m.progress("done")
+	return nil
+}
+
+func (m *migration) criuOpts(name, parent string, preDump bool) *libcontainer.CriuOpts {
+	m.localEntries = append(m.localEntries, name)
+	opts := &libcontainer.CriuOpts{
+		ImagesDirectory:         filepath.Join(m.localDir, name),
+		PreDump:                 preDump,
+		TcpEstablished:          m.hasFlag("--tcp-established"),
+		ExternalUnixConnections: m.hasFlag("--ext-unix-sk"),
+		FileLocks:               m.hasFlag("--file-locks"),
+		ManageCgroupsMode:       m.cgMode,
+		// runc doesn't manage network devices and their configuration.
+		EmptyNs: unix.CLONE_NEWNET,
+	}
+	if parent != "" {
+		// Relative to ImagesDirectory, so that it is also valid
+		// on the destination host.
+		opts.ParentImage = "../" + parent
+	}
+	return opts
+}
+
+func (m *migration) hasFlag(flag string) bool {
+	for _, f := range m.criuFlags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// remote runs the shell command cmdline on the destination host, with the
+// given standard input.
+func (m *migration) remote(cmdline string, stdin io.Reader) error {
+	return m.runRemote(cmdline, stdin, nil)
+}
+
+// remoteOutput runs the shell command cmdline on the destination host, and
+// returns its standard output.
+func (m *migration) remoteOutput(cmdline string) (string, error) {
+	var stdout bytes.Buffer
+	err := m.runRemote(cmdline, nil, &stdout)
+	return stdout.String(), err
+}
+
+func (m *migration) runRemote(cmdline string, stdin io.Reader, stdout io.Writer) error {
+	args := append(append([]string{}, m.rsh[1:]...), m.dest, cmdline)
+	cmd := exec.Command(m.rsh[0], args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w: %s", m.dest, err, msg)
+		}
+		return fmt.Errorf("%s: %w", m.dest, err)
+	}
+	return nil
+}
+
+// send copies the local images directory name to the destination host.
+func (m *migration) send(name string) error {
+	pr, pw := io.Pipe()
+	cw := &countingWriter{w: pw}
+	go func() {
+		_ = pw.CloseWithError(writeTar(cw, filepath.Join(m.localDir, name)))
+	}()
+	m.remoteEntries = append(m.remoteEntries, name)
+	dir := shellQuote(path.Join(m.remoteDir, name))
+	err := m.remote("mkdir -p "+dir+" && tar -x -C "+dir, pr)
+	// Unblock the writer, if the command failed early.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return fmt.Errorf("unable to copy %s: %w", name, err)
+	}
+	m.progress("copied %s (%s)", name, units.HumanSize(float64(cw.n)))
+	return nil
+}
+
+// remoteRestoreCmd returns the shell command restoring the container on the
+// destination host. As the container is detached from the restoring runc,
+// the standard input and outputs are /dev/null, and the runc errors are
+// read from its log.
+func (m *migration) remoteRestoreCmd() string {
+	log := shellQuote(path.Join(m.remoteDir, "restore.log"))
+	args := []string{
+		"restore", "--detach",
+		"--image-path", path.Join(m.remoteDir, "dump"),
+		"--bundle", m.remoteBundle,
+	}
+	args = append(args, m.criuFlags...)
+	args = append(args, m.id)
+	for i := range args {
+		args[i] = shellQuote(args[i])
+	}
+	return shellQuoteCmd(m.remoteRunc) + " --log " + log + " " + strings.Join(args, " ") +
+		" </dev/null >/dev/null 2>&1 || { cat " + log + " >&2; exit 1; }"
+}
+
+// restoreLocally restores the container from the local images, using this
+// runc binary.
+func (m *migration) restoreLocally(context *cli.Context) error {
+	var args []string
+	if root := context.GlobalString("root"); root != "" {
+		args = append(args, "--root", root)
+	}
+	if criuPath := context.GlobalString("criu"); criuPath != "" {
+		args = append(args, "--criu", criuPath)
+	}
+	if context.GlobalBool("systemd-cgroup") {
+		args = append(args, "--systemd-cgroup")
+	}
+	args = append(args, "restore", "--detach",
+		"--image-path", filepath.Join(m.localDir, "dump"),
+		"--bundle", m.bundle)
+	args = append(args, m.criuFlags...)
+	args = append(args, m.id)
+	cmd := exec.Command("/proc/self/exe", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// cleanup removes the local and remote images, unless --keep-images is set.
+// Only the directories created by runc are removed as a whole; from the
+// existing ones, only the entries written by runc are removed.
+func (m *migration) cleanup() {
+	if m.keepImages {
+		return
+	}
+	if cmd := m.remoteCleanupCmd(); cmd != "" {
+		if err := m.remote(cmd, nil); err != nil {
+			logrus.Warnf("unable to remove the images on %s: %v", m.dest, err)
+		}
+	}
+	if m.tempDir {
+		_ = os.RemoveAll(m.localDir)
+		return
+	}
+	for _, name := range m.localEntries {
+		_ = os.RemoveAll(filepath.Join(m.localDir, name))
+	}
+}
+
+// remoteCleanupCmd returns the shell command removing the remote images (see
+// cleanup), or an empty string if there is nothing to remove.
+func (m *migration) remoteCleanupCmd() string {
+	if m.remoteCreated {
+		return "rm -rf " + shellQuote(m.remoteDir)
+	}
+	if len(m.remoteEntries) == 0 {
+		return ""
+	}
+	paths := make([]string, len(m.remoteEntries))
+	for i, name := range m.remoteEntries {
+		paths[i] = shellQuote(path.Join(m.remoteDir, name))
+	}
+	return "rm -rf " + strings.Join(paths, " ")
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeTar writes the contents of dir (regular files, directories and
+// symlinks, such as the criu "parent" one) as a tar archive to w.
+func writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		return writeTarEntry(tw, p, filepath.ToSlash(rel), fi)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// writeTarEntry writes the file p (a regular file, a directory or a
+// symlink), with the given name, to tw.
+func writeTarEntry(tw *tar.Writer, p, name string, fi os.FileInfo) error {
+	var link string
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		var err error
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	case fi.IsDir(), fi.Mode().IsRegular():
+	default:
+		return fmt.Errorf("%s: unsupported file type %s", p, fi.Mode().Type())
+	}
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellQuoteCmd quotes the words of cmd (such as --remote-runc) for a POSIX
+// shell.
+func shellQuoteCmd(cmd string) string {
+	words := strings.Fields(cmd)
+	for i := range words {
+		words[i] = shellQuote(words[i])
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	for _, tc := range []struct {
+		in, expected string
+	}{
+		{in: "", expected: "''"},
+		{in: "/var/tmp/a b", expected: "'/var/tmp/a b'"},
+		{in: "it's", expected: `'it'\''s'`},
+	} {
+		if got := shellQuote(tc.in); got != tc.expected {
+			t.Errorf("%q: expected %s, got %s", tc.in, tc.expected, got)
+		}
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(tc.in)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tc.in {
+			t.Errorf("%q: the shell got %q", tc.in, out)
+		}
+	}
+	if got := shellQuoteCmd(" runc  --root /run/a "); got != "'runc' '--root' '/run/a'" {
+		t.Errorf("unexpected quoted command %s", got)
+	}
+}
+
+func TestMigrationSend(t *testing.T) {
+	dir := t.TempDir()
+	// A fake rsh, running the command locally.
+	rsh := filepath.Join(dir, "rsh")
+	if err := os.WriteFile(rsh, []byte("#!/bin/sh\nshift\nexec sh -c \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(dir, "local")
+	remote := filepath.Join(dir, "remote")
+	if err := os.MkdirAll(filepath.Join(local, "dump", "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(local, "dump", "sub", "pages-1.img"), []byte("pages"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../pre-dump-1", filepath.Join(local, "dump", "parent")); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &migration{id: "test", dest: "host", rsh: []string{rsh}, localDir: local, remoteDir: remote}
+	if err := m.send("dump"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(remote, "dump", "sub", "pages-1.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "pages" {
+		t.Errorf("unexpected file contents %q", data)
+	}
+	if link, err := os.Readlink(filepath.Join(remote, "dump", "parent")); err != nil || link != "../pre-dump-1" {
+		t.Errorf("unexpected parent link %q (%v)", link, err)
+	}
+
+	m.rsh = []string{"false"}
+	if err := m.send("dump"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestMigrationCleanup(t *testing.T) {
+	dir := t.TempDir()
+	rsh := filepath.Join(dir, "rsh")
+	if err := os.WriteFile(rsh, []byte("#!/bin/sh\nshift\nexec sh -c \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(dir, "local")
+	remote := filepath.Join(dir, "remote")
+	for _, p := range []string{
+		filepath.Join(local, "dump"),
+		filepath.Join(local, "dump.old"),
+		filepath.Join(remote, "dump"),
+		filepath.Join(remote, "other"),
+	} {
+		if err := os.MkdirAll(p, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &migration{
+		id: "test", dest: "host", rsh: []string{rsh},
+		localDir: local, remoteDir: remote,
+		localEntries: []string{"dump"}, remoteEntries: []string{"dump", "restore.log"},
+	}
+	m.cleanup()
+	for _, p := range []string{filepath.Join(local, "dump"), filepath.Join(remote, "dump")} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s: expected to be removed, got %v", p, err)
+		}
+	}
+	for _, p := range []string{filepath.Join(local, "dump.old"), filepath.Join(remote, "other")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s: expected to be kept, got %v", p, err)
+		}
+	}
+
+	// Nothing is removed remotely if the pre-flight checks failed.
+	m = &migration{id: "test", dest: "host", rsh: []string{rsh}, localDir: local, remoteDir: remote}
+	m.cleanup()
+	if _, err := os.Stat(filepath.Join(remote, "other")); err != nil {
+		t.Errorf("expected the remote directory to be kept, got %v", err)
+	}
+
+	m.remoteCreated = true
+	m.cleanup()
+	if _, err := os.Stat(remote); !os.IsNotExist(err) {
+		t.Errorf("expected the remote directory to be removed, got %v", err)
+	}
+}
@@ -111,8 +111,7 @@ func (n *notifySocket) run(pid1 int) error {
 	if n.socket == nil {
 		return nil
 	}
-	notifySocketHostAddr := net.UnixAddr{Name: n.host, Net: "unixgram"}
-	client, err := net.DialUnix("unixgram", nil, &notifySocketHostAddr)
+	client, err := n.dialHost()
 	if err != nil {
 		return err
 	}
@@ -120,24 +119,17 @@ func (n *notifySocket) run(pid1 int) error {
 	ticker := time.NewTicker(time.Millisecond * 100)
 	defer ticker.Stop()
 
-	fileChan := make(chan []byte)
+	msgChan := make(chan *notifyMessage)
 	go func() {
 		for {
-			buf := make([]byte, 4096)
-			r, err := n.socket.Read(buf)
+			m, err := n.readMessage()
 			if err != nil {
 				return
 			}
-			got := buf[0:r]
-			// systemd-ready sends a single datagram with the state string as payload,
-			// so we don't need to worry about partial messages.
-			for _, line := range bytes.Split(got, []byte{'\n'}) {
-				if bytes.HasPrefix(got, []byte("READY=")) {
-					fileChan <- line
-					return
-				}
+			msgChan <- m
+			if m.ready() != nil {
+				return
 			}
-
 		}
 	}()
 
@@ -148,10 +140,133 @@ func (n *notifySocket) run(pid1 int) error {
 			if err != nil {
 				return nil
 			}
-		case b := <-fileChan:
-			return notifyHost(client, b, pid1)
+		case m := <-msgChan:
+			if err := forwardMessage(client, m); err != nil {
+				return err
+			}
+			if ready := m.ready(); ready != nil {
+				return notifyHost(client, ready, pid1)
+			}
+		}
+	}
+}
+
+// forwardStates forwards the messages of the container to the host, once
+// the container is ready, until the socket is closed.
+func (n *notifySocket) forwardStates() error {
+	client, err := n.dialHost()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	for {
+		m, err := n.readMessage()
+		if err != nil {
+			return nil
+		}
+		if err := forwardMessage(client, m); err != nil {
+			return err
+		}
+	}
+}
+
+func (n *notifySocket) dialHost() (*net.UnixConn, error) {
+	notifySocketHostAddr := net.UnixAddr{Name: n.host, Net: "unixgram"}
+	return net.DialUnix("unixgram", nil, &notifySocketHostAddr)
+}
+
+// notifyMessage is a datagram sent by the container to the notify socket.
+type notifyMessage struct {
+	// lines are the "VARIABLE=value" states.
+	lines [][]byte
+	// fds are the file descriptors sent along, such as the BARRIER=1 one.
+	fds []int
+}
+
+// readMessage reads a datagram from the notify socket.
+func (n *notifySocket) readMessage() (*notifyMessage, error) {
+	buf := make([]byte, 4096)
+	oob := make([]byte, unix.CmsgSpace(notifyMaxFds*4))
+	r, oobn, _, _, err := n.socket.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, err
+	}
+	// sd_notify sends a single datagram with the states as payload, so
+	// we don't need to worry about partial messages.
+	m := &notifyMessage{}
+	for _, line := range bytes.Split(buf[:r], []byte{'\n'}) {
+		if len(line) > 0 {
+			m.lines = append(m.lines, line)
+		}
+	}
+	scms, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return m, nil
+	}
+	for _, scm := range scms {
+		fds, err := unix.ParseUnixRights(&scm)
+		if err == nil {
+			m.fds = append(m.fds, fds...)
+		}
+	}
+	return m, nil
+}
+
+// notifyMaxFds is the maximum number of file descriptors received along with
+// a notify message; any other one is closed by the kernel.
+const notifyMaxFds = 16
+
+// ready returns the READY state of m, or nil if m has none.
+func (m *notifyMessage) ready() []byte {
+	for _, line := range m.lines {
+		if bytes.HasPrefix(line, []byte("READY=")) {
+			return line
+		}
+	}
+	return nil
+}
+
+// notifyForwardedStates are the prefixes of the states forwarded to the
+// host as they are. READY is forwarded along with MAINPID (see notifyHost),
+// and BARRIER is handled by the proxy itself.
+var notifyForwardedStates = [][]byte{
+	[]byte("STATUS="),
+	[]byte("EXTEND_TIMEOUT_USEC="),
+}
+
+// forwardMessage forwards the states of m, other than READY, to the host.
+// If m is a BARRIER=1 message, it waits for the host to have processed all
+// the states forwarded so far, before closing the fd of m (which is how the
+// container is told that the barrier is passed).
+func forwardMessage(client *net.UnixConn, m *notifyMessage) error {
+	defer func() {
+		for _, fd := range m.fds {
+			unix.Close(fd)
+		}
+	}()
+	var states [][]byte
+	barrier := false
+	for _, line := range m.lines {
+		if bytes.Equal(line, []byte("BARRIER=1")) {
+			barrier = true
+			continue
+		}
+		for _, prefix := range notifyForwardedStates {
+			if bytes.HasPrefix(line, prefix) {
+				states = append(states, line)
+				break
+			}
+		}
+	}
+	if len(states) > 0 {
+		if _, err := client.Write(append(bytes.Join(states, []byte{'\n'}), '\n')); err != nil {
+			return err
 		}
 	}
+	if barrier && len(m.fds) > 0 {
+		return sdNotifyBarrier(client)
+	}
+	return nil
 }
 
 // notifyHost tells the host (usually systemd) that the container reported READY.
@@ -2,8 +2,10 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net"
+	"os"
 	"testing"
 	"time"
 
@@ -118,3 +120,83 @@ func mustExtractFd(t *testing.T, buf []byte) int {
 
 	return fd
 }
+
+// TestNotifyForwardStates tests how runc forwards the container states, and
+// its barriers, to the host.
+func TestNotifyForwardStates(t *testing.T) {
+	dir := t.TempDir()
+	hostAddr := net.UnixAddr{Name: dir + "/host.sock", Net: "unixgram"}
+	server, err := net.ListenUnixgram("unixgram", &hostAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	n := &notifySocket{host: hostAddr.Name, socketPath: dir + "/notify.sock"}
+	if err := n.bindSocket(); err != nil {
+		t.Fatal(err)
+	}
+	forwardChan := make(chan error)
+	go func() {
+		forwardChan <- n.forwardStates()
+	}()
+
+	// mock a container process using the notify socket
+	container, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: n.socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer container.Close()
+	if _, err := container.Write([]byte("STATUS=starting\nFOO=bar\nEXTEND_TIMEOUT_USEC=5000000")); err != nil {
+		t.Fatal(err)
+	}
+	expectRead(t, server, "STATUS=starting\nEXTEND_TIMEOUT_USEC=5000000\n")
+
+	pipeR, pipeW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipeR.Close()
+	containerFd, err := container.File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer containerFd.Close()
+	if err := unix.Sendmsg(int(containerFd.Fd()), []byte("BARRIER=1"), unix.UnixRights(int(pipeW.Fd())), nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	pipeW.Close()
+
+	var msg, oob [1024]byte
+	m, oobn, _, _, err := server.ReadMsgUnix(msg[:], oob[:])
+	if err != nil {
+		t.Fatal("Failed to receive BARRIER message", err)
+	}
+	if !bytes.Equal(msg[:m], []byte("BARRIER=1")) {
+		t.Fatalf("Expected to receive 'BARRIER=1' but got '%s' instead.", msg[:m])
+	}
+	fd := mustExtractFd(t, oob[:oobn])
+
+	// The container barrier is only lifted once the host one is.
+	var buf [1]byte
+	if err := pipeR.SetReadDeadline(time.Now().Add(500 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pipeR.Read(buf[:]); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("container barrier lifted before the host one", err)
+	}
+	if err := unix.Close(fd); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipeR.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pipeR.Read(buf[:]); err != io.EOF { //nolint:errorlint // comparison with io.EOF is legit.
+		t.Fatal("container barrier not lifted", err)
+	}
+
+	n.Close()
+	if err := <-forwardChan; err != nil {
+		t.Fatal("forwardStates returned with error", err)
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
@@ -15,22 +19,14 @@ paused. `,
 	Description: `The pause command suspends all processes in the instance of the container.
 
 Use runc list to identify instances of containers and their current status.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all, a",
+			Usage: "pause all the running containers",
+		},
+	},
 	Action: func(context *cli.Context) error {
-		if err := checkArgs(context, 1, exactArgs); err != nil {
-			return err
-		}
-		rootlessCg, err := shouldUseRootlessCgroupManager(context)
-		if err != nil {
-			return err
-		}
-		if rootlessCg {
-			logrus.Warnf("runc pause may fail if you don't have the full access to cgroups")
-		}
-		container, err := getContainer(context)
-		if err != nil {
-			return err
-		}
-		return container.Pause()
+		return pauseOrResume(context, "pause")
 	},
 }
 
@@ -44,21 +40,82 @@ resumed.`,
 	Description: `The resume command resumes all processes in the instance of the container.
 
 Use runc list to identify instances of containers and their current status.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all, a",
+			Usage: "resume all the paused containers",
+		},
+	},
 	Action: func(context *cli.Context) error {
-		if err := checkArgs(context, 1, exactArgs); err != nil {
+		return pauseOrResume(context, "resume")
+	},
+}
+
+// pauseOrResume runs runc pause or resume (depending on operation), for the
+// container given as the argument or, with --all, for all the containers
+// which are running (or paused).
+func pauseOrResume(context *cli.Context, operation string) error {
+	all := context.Bool("all")
+	if all {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
 			return err
 		}
-		rootlessCg, err := shouldUseRootlessCgroupManager(context)
-		if err != nil {
-			return err
+	} else if err := checkArgs(context, 1, exactArgs); err != nil {
+		return err
+	}
+	rootlessCg, err := shouldUseRootlessCgroupManager(context)
+	if err != nil {
+		return err
+	}
+	if rootlessCg {
+		logrus.Warnf("runc %s may fail if you don't have the full access to cgroups", operation)
+	}
+	root := context.GlobalString("root")
+	if !all {
+		id := context.Args().First()
+		if id == "" {
+			return errEmptyID
 		}
-		if rootlessCg {
-			logrus.Warn("runc resume may fail if you don't have the full access to cgroups")
+		return pauseOrResumeContainer(root, id, operation)
+	}
+
+	from := libcontainer.Running
+	if operation == "resume" {
+		from = libcontainer.Paused
+	}
+	containers, err := getContainers(context)
+	if err != nil {
+		return err
+	}
+	var total, failed int
+	for _, c := range containers {
+		if c.Status != from.String() {
+			continue
 		}
-		container, err := getContainer(context)
-		if err != nil {
-			return err
+		total++
+		if err := pauseOrResumeContainer(root, c.ID, operation); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c.ID, err)
+			failed++
 		}
-		return container.Resume()
-	},
+	}
+	if failed > 0 {
+		return fmt.Errorf("unable to %s %d of %d containers", operation, failed, total)
+	}
+	return nil
+}
+
+func pauseOrResumeContainer(root, id, operation string) error {
+	lock, err := libcontainer.LockContainer(root, id, operation)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	container, err := libcontainer.Load(root, id)
+	if err != nil {
+		return err
+	}
+	if operation == "pause" {
+		return container.Pause()
+	}
+	return container.Resume()
 }
@@ -4,13 +4,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/moby/sys/user"
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/extcmd"
+	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
 )
 
 var psCommand = cli.Command{
@@ -23,6 +32,18 @@ var psCommand = cli.Command{
 			Value: "table",
 			Usage: `select one of: ` + formatOptions,
 		},
+		cli.StringFlag{
+			Name:  "columns, c",
+			Usage: "read the processes from /proc instead of running ps, and show the given comma separated columns (" + strings.Join(psColumnNames(), ", ") + ")",
+		},
+		cli.BoolFlag{
+			Name:  "tree",
+			Usage: "show the processes as a tree, along with their cgroups (ps options are not accepted)",
+		},
+		cli.BoolFlag{
+			Name:  "exec",
+			Usage: "show the exec session (or init) each process belongs to, and when it was started (ps options are not accepted)",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, minArgs); err != nil {
@@ -41,11 +62,78 @@ var psCommand = cli.Command{
 			return err
 		}
 
+		if context.Bool("exec") {
+			if context.NArg() > 1 {
+				return errors.New("ps options can not be used with --exec")
+			}
+			if context.IsSet("columns") || context.Bool("tree") {
+				return errors.New("--columns and --tree can not be used with --exec")
+			}
+			procs, err := execSessionProcs(container)
+			if err != nil {
+				return err
+			}
+			switch context.String("format") {
+			case "table":
+				return printExecSessionProcs(os.Stdout, procs)
+			case "json":
+				return json.NewEncoder(os.Stdout).Encode(procs)
+			default:
+				return errors.New("invalid format option")
+			}
+		}
+
+		if context.Bool("tree") {
+			if context.NArg() > 1 {
+				return errors.New("ps options can not be used with --tree")
+			}
+			if context.IsSet("columns") {
+				return errors.New("--columns can not be used with --tree")
+			}
+			tree, err := container.ProcessTree()
+			if err != nil {
+				return err
+			}
+			switch context.String("format") {
+			case "table":
+				return printProcessTree(os.Stdout, tree)
+			case "json":
+				return json.NewEncoder(os.Stdout).Encode(tree)
+			default:
+				return errors.New("invalid format option")
+			}
+		}
+
+		var columns []string
+		if context.IsSet("columns") {
+			if context.NArg() > 1 {
+				return errors.New("ps options can not be used with --columns")
+			}
+			if columns, err = parsePsColumns(context.String("columns")); err != nil {
+				return err
+			}
+		}
+
 		pids, err := container.Processes()
 		if err != nil {
 			return err
 		}
 
+		if columns != nil {
+			procs, err := readPsProcs(pids, columns)
+			if err != nil {
+				return err
+			}
+			switch context.String("format") {
+			case "table":
+				return printPsProcs(os.Stdout, columns, procs)
+			case "json":
+				return json.NewEncoder(os.Stdout).Encode(procs)
+			default:
+				return errors.New("invalid format option")
+			}
+		}
+
 		switch context.String("format") {
 		case "table":
 		case "json":
@@ -63,10 +151,9 @@ var psCommand = cli.Command{
 			psArgs = []string{"-ef"}
 		}
 
-		cmd := exec.Command("ps", psArgs...)
-		output, err := cmd.CombinedOutput()
+		output, err := extcmd.Command("ps", psArgs...).CombinedOutput()
 		if err != nil {
-			return fmt.Errorf("%w: %s", err, output)
+			return err
 		}
 
 		lines := strings.Split(string(output), "\n")
@@ -110,3 +197,250 @@ func getPidIndex(title string) (int, error) {
 
 	return pidIndex, errors.New("couldn't find PID field in ps output")
 }
+
+func printProcessTree(out io.Writer, tree []*libcontainer.ProcessInfo) error {
+	w := tabwriter.NewWriter(out, 6, 1, 3, ' ', 0)
+	fmt.Fprint(w, "PID\tPPID\tCGROUP\tCOMMAND\n")
+	var printLevel func(procs []*libcontainer.ProcessInfo, depth int)
+	printLevel = func(procs []*libcontainer.ProcessInfo, depth int) {
+		for _, p := range procs {
+			prefix := ""
+			if depth > 0 {
+				prefix = strings.Repeat("  ", depth-1) + "\\_ "
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s%s\n", p.Pid, p.PPid, p.Cgroup, prefix, p.Comm)
+			printLevel(p.Children, depth+1)
+		}
+	}
+	printLevel(tree, 0)
+	return w.Flush()
+}
+
+// psExecProc is a process shown by runc ps --exec.
+type psExecProc struct {
+	Pid  int    `json:"pid"`
+	PPid int    `json:"ppid"`
+	Comm string `json:"comm"`
+	// Session is the ID of the exec session the process belongs to (that
+	// is, of the process started by runc exec, or of its ancestor which
+	// was), "init" for the container init and its descendants, or "" for
+	// a foreign process (see runc audit).
+	Session string `json:"session"`
+	// Started is when the exec session was started (or, for init, when
+	// the container was created).
+	Started *time.Time `json:"started,omitempty"`
+}
+
+// execSessionProcs returns the container processes, along with the exec
+// session they belong to.
+func execSessionProcs(container *libcontainer.Container) ([]*psExecProc, error) {
+	tree, err := container.ProcessTree()
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := container.ExecSessions()
+	if err != nil {
+		return nil, err
+	}
+	state, err := container.State()
+	if err != nil {
+		return nil, err
+	}
+	var procs []*psExecProc
+	var add func(p *libcontainer.ProcessInfo, session string, started *time.Time)
+	add = func(p *libcontainer.ProcessInfo, session string, started *time.Time) {
+		procs = append(procs, &psExecProc{Pid: p.Pid, PPid: p.PPid, Comm: p.Comm, Session: session, Started: started})
+		for _, c := range p.Children {
+			add(c, session, started)
+		}
+	}
+	for _, p := range tree {
+		session, started := "", (*time.Time)(nil)
+		if p.Pid == state.InitProcessPid && p.StartTime == state.InitProcessStartTime {
+			session, started = "init", &state.Created
+		} else {
+			for _, s := range sessions {
+				if s.Pid == p.Pid && s.StartTime == p.StartTime {
+					session, started = s.ID, &s.Created
+					break
+				}
+			}
+		}
+		add(p, session, started)
+	}
+	return procs, nil
+}
+
+func printExecSessionProcs(out io.Writer, procs []*psExecProc) error {
+	w := tabwriter.NewWriter(out, 6, 1, 3, ' ', 0)
+	fmt.Fprint(w, "SESSION\tSTARTED\tPID\tPPID\tCOMMAND\n")
+	for _, p := range procs {
+		session, started := p.Session, "-"
+		if session == "" {
+			session = "-"
+		}
+		if p.Started != nil {
+			started = p.Started.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", session, started, p.Pid, p.PPid, p.Comm)
+	}
+	return w.Flush()
+}
+
+// psColumns are the runc ps --columns, by name.
+var psColumns = map[string]struct {
+	header string
+	value  func(pid int, stat *system.Stat_t) (interface{}, error)
+}{
+	"pid": {"PID", func(pid int, _ *system.Stat_t) (interface{}, error) {
+		return pid, nil
+	}},
+	"ppid": {"PPID", func(_ int, stat *system.Stat_t) (interface{}, error) {
+		return stat.PPid, nil
+	}},
+	"comm": {"COMMAND", func(_ int, stat *system.Stat_t) (interface{}, error) {
+		return stat.Name, nil
+	}},
+	"state": {"STATE", func(_ int, stat *system.Stat_t) (interface{}, error) {
+		return string(stat.State), nil
+	}},
+	"start_time": {"START", func(_ int, stat *system.Stat_t) (interface{}, error) {
+		return stat.StartTime, nil
+	}},
+	"uid": {"UID", func(pid int, _ *system.Stat_t) (interface{}, error) {
+		return procUID(pid)
+	}},
+	"user": {"USER", func(pid int, _ *system.Stat_t) (interface{}, error) {
+		uid, err := procUID(pid)
+		if err != nil {
+			return nil, err
+		}
+		if u, err := user.LookupUid(uid); err == nil {
+			return u.Name, nil
+		}
+		return strconv.Itoa(uid), nil
+	}},
+	"args": {"ARGS", func(pid int, stat *system.Stat_t) (interface{}, error) {
+		data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			// A kernel thread, or a zombie.
+			return []string{"[" + stat.Name + "]"}, nil
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00"), nil
+	}},
+	"cgroup": {"CGROUP", func(pid int, _ *system.Stat_t) (interface{}, error) {
+		cg, err := cgroups.ParseCgroupFile("/proc/" + strconv.Itoa(pid) + "/cgroup")
+		if err != nil {
+			return nil, err
+		}
+		if cgroups.IsCgroup2UnifiedMode() {
+			return cg[""], nil
+		}
+		return cg["pids"], nil
+	}},
+}
+
+func psColumnNames() []string {
+	names := make([]string, 0, len(psColumns))
+	for name := range psColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parsePsColumns parses the --columns value.
+func parsePsColumns(val string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := psColumns[name]; !ok {
+			return nil, fmt.Errorf("invalid --columns %q: unknown column %q (must be one of %s)", val, name, strings.Join(psColumnNames(), ", "))
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// readPsProcs reads the columns of the processes with the given PIDs from
+// /proc. The processes which exit in the meantime are omitted.
+func readPsProcs(pids []int, columns []string) ([]map[string]interface{}, error) {
+	procs := make([]map[string]interface{}, 0, len(pids))
+	for _, pid := range pids {
+		p, err := readPsProc(pid, columns)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ESRCH) {
+				continue
+			}
+			return nil, err
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+func readPsProc(pid int, columns []string) (map[string]interface{}, error) {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return nil, err
+	}
+	p := make(map[string]interface{}, len(columns))
+	for _, name := range columns {
+		if p[name], err = psColumns[name].value(pid, &stat); err != nil {
+			return nil, err
+		}
+	}
+	// Make sure the PID was not reused in the meantime.
+	if stat2, err := system.Stat(pid); err != nil {
+		return nil, err
+	} else if stat2.StartTime != stat.StartTime {
+		return nil, fmt.Errorf("process %d: %w", pid, os.ErrNotExist)
+	}
+	return p, nil
+}
+
+// procUID returns the effective UID of the process.
+func procUID(pid int) (int, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if val, ok := strings.CutPrefix(line, "Uid:"); ok {
+			ids := strings.Fields(val)
+			if len(ids) < 2 {
+				break
+			}
+			return strconv.Atoi(ids[1])
+		}
+	}
+	return 0, fmt.Errorf("process %d: no Uid in status", pid)
+}
+
+func printPsProcs(out io.Writer, columns []string, procs []map[string]interface{}) error {
+	w := tabwriter.NewWriter(out, 6, 1, 3, ' ', 0)
+	for i, name := range columns {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, psColumns[name].header)
+	}
+	fmt.Fprint(w, "\n")
+	for _, p := range procs {
+		for i, name := range columns {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			if args, ok := p[name].([]string); ok {
+				fmt.Fprint(w, strings.Join(args, " "))
+			} else {
+				fmt.Fprint(w, p[name])
+			}
+		}
+		fmt.Fprint(w, "\n")
+	}
+	return w.Flush()
+}
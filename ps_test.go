@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePsColumns(t *testing.T) {
+	columns, err := parsePsColumns("pid, ppid,comm,cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"pid", "ppid", "comm", "cgroup"}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected %q, got %q", expected, columns)
+	}
+	for _, val := range []string{"", "pid,", "pid,cmd", "PID"} {
+		if _, err := parsePsColumns(val); err == nil {
+			t.Errorf("%q: expected error, got nil", val)
+		}
+	}
+}
+
+func TestReadPsProc(t *testing.T) {
+	pid := os.Getpid()
+	p, err := readPsProc(pid, psColumnNames())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p["pid"] != pid {
+		t.Errorf("expected pid %d, got %v", pid, p["pid"])
+	}
+	if p["ppid"] != os.Getppid() {
+		t.Errorf("expected ppid %d, got %v", os.Getppid(), p["ppid"])
+	}
+	if p["uid"] != os.Geteuid() {
+		t.Errorf("expected uid %d, got %v", os.Geteuid(), p["uid"])
+	}
+	if args := p["args"].([]string); !reflect.DeepEqual(args, os.Args) {
+		t.Errorf("expected args %q, got %q", os.Args, args)
+	}
+	if comm := p["comm"].(string); !strings.HasPrefix(comm, "runc") {
+		t.Errorf("unexpected comm %q", comm)
+	}
+
+	var out strings.Builder
+	if err := printPsProcs(&out, []string{"pid", "args"}, []map[string]interface{}{p}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "PID") || !strings.Contains(lines[1], strings.Join(os.Args, " ")) {
+		t.Errorf("unexpected table:\n%s", out.String())
+	}
+}
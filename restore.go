@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"os"
 
+	"github.com/opencontainers/runc/libcontainer/extcmd"
 	"github.com/opencontainers/runc/libcontainer/userns"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -16,18 +18,36 @@ var restoreCommand = cli.Command{
 Where "<container-id>" is the name for the instance of the container to be
 restored.`,
 	Description: `Restores the saved state of the container instance that was previously saved
-using the runc checkpoint command.`,
+using the runc checkpoint command. The images compressed by runc checkpoint
+--compress are decompressed first.
+
+With --page-client, the memory pages are restored lazily, being fetched
+from the page server of runc checkpoint --lazy-pages --page-server on the
+source host; runc then returns once all the pages are transferred.
+
+With --image-stream, the images are read from a tar archive (possibly
+compressed with gzip, lz4 or zstd) instead, for example:
+
+    ssh src cat /var/lib/ckpt/checkpoint.tar.zst | runc restore --image-stream - ctr`,
 	Flags: []cli.Flag{
 		cli.StringFlag{
 			Name:  "console-socket",
 			Value: "",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal",
 		},
+		cli.IntFlag{
+			Name:  "console-socket-version",
+			Usage: "the `version` of the protocol used over the console socket (1 or 2, the default being 1)",
+		},
 		cli.StringFlag{
 			Name:  "image-path",
 			Value: "",
 			Usage: "path to criu image files for restoring",
 		},
+		cli.StringFlag{
+			Name:  "image-stream",
+			Usage: "read the criu image files from a tar archive at `path` (\"-\" for stdin) instead of --image-path",
+		},
 		cli.StringFlag{
 			Name:  "work-path",
 			Value: "",
@@ -88,6 +108,10 @@ using the runc checkpoint command.`,
 			Name:  "lazy-pages",
 			Usage: "use userfaultfd to lazily restore memory pages",
 		},
+		cli.StringFlag{
+			Name:  "page-client",
+			Usage: "lazily restore the memory pages from the page server at `ADDRESS:PORT`, running criu lazy-pages (implies --lazy-pages)",
+		},
 		cli.StringFlag{
 			Name:  "lsm-profile",
 			Value: "",
@@ -108,11 +132,52 @@ using the runc checkpoint command.`,
 			logrus.Warn("runc checkpoint is untested with rootless containers")
 		}
 
+		var cleanup func()
+		if stream := context.String("image-stream"); stream != "" {
+			if context.IsSet("image-path") {
+				return errors.New("--image-stream and --image-path can not be used together")
+			}
+			if context.Bool("lazy-pages") || context.IsSet("page-client") {
+				return errors.New("--image-stream can not be used with --lazy-pages or --page-client")
+			}
+			dir, err := extractImageStream(stream, context.String("work-path"))
+			if err != nil {
+				return err
+			}
+			cleanup = func() { _ = os.RemoveAll(dir) }
+			if err := context.Set("image-path", dir); err != nil {
+				cleanup()
+				return err
+			}
+		}
+
 		options, err := criuOptions(context)
+		if err == nil && cleanup == nil {
+			// The images may have been compressed by runc checkpoint --compress.
+			cleanup, err = decompressImages(options.ImagesDirectory)
+		}
 		if err != nil {
+			if cleanup != nil {
+				cleanup()
+			}
 			return err
 		}
+		var lazyPages *extcmd.Cmd
+		if addr := context.String("page-client"); addr != "" {
+			options.LazyPages = true
+			lazyPages, err = startLazyPages(addr, options.ImagesDirectory, options.WorkDirectory)
+			if err != nil {
+				cleanup()
+				return err
+			}
+		}
 		status, err := startContainer(context, CT_ACT_RESTORE, options)
+		if lazyPages != nil {
+			// The daemon reads the images until all the pages are
+			// transferred.
+			stopLazyPages(lazyPages, err == nil)
+		}
+		cleanup()
 		if err != nil {
 			return err
 		}
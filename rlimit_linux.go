@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/opencontainers/runc/libcontainer/configs"
 	"golang.org/x/sys/unix"
 )
 
@@ -32,3 +35,43 @@ func strToRlimit(key string) (int, error) {
 	}
 	return rl, nil
 }
+
+// parseRlimit parses a resource limit in the name=soft[:hard] form, such as
+// nofile=1024:4096, the name being case insensitive and with an optional
+// RLIMIT_ prefix. The limits can be "unlimited", and the hard limit
+// defaults to the soft one.
+func parseRlimit(s string) (configs.Rlimit, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" || value == "" {
+		return configs.Rlimit{}, fmt.Errorf("invalid rlimit %q: must be name=soft[:hard]", s)
+	}
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "RLIMIT_") {
+		name = "RLIMIT_" + name
+	}
+	typ, err := strToRlimit(name)
+	if err != nil {
+		return configs.Rlimit{}, err
+	}
+	parse := func(v string) (uint64, error) {
+		if v == "unlimited" {
+			return unix.RLIM_INFINITY, nil
+		}
+		return strconv.ParseUint(v, 10, 64)
+	}
+	soft, hard, ok := strings.Cut(value, ":")
+	if !ok {
+		hard = soft
+	}
+	rl := configs.Rlimit{Type: typ}
+	if rl.Soft, err = parse(soft); err != nil {
+		return configs.Rlimit{}, fmt.Errorf("invalid rlimit %q: %w", s, err)
+	}
+	if rl.Hard, err = parse(hard); err != nil {
+		return configs.Rlimit{}, fmt.Errorf("invalid rlimit %q: %w", s, err)
+	}
+	if rl.Soft > rl.Hard {
+		return configs.Rlimit{}, fmt.Errorf("invalid rlimit %q: soft limit greater than hard limit", s)
+	}
+	return rl, nil
+}
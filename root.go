@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// tenantMarker is the file created in a tenant directory (under --root),
+// so that runc list does not mistake it for a container.
+const tenantMarker = ".runc-tenant"
+
+// parseRootMode parses the --root-mode value, an octal mode such as 711
+// or 1777 (the sticky and setgid bits are allowed).
+func parseRootMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m&^0o3777 != 0 {
+		return 0, fmt.Errorf("invalid --root-mode %q: must be an octal mode of at most 3777", s)
+	}
+	mode := os.FileMode(m & 0o777)
+	if m&0o1000 != 0 {
+		mode |= os.ModeSticky
+	}
+	if m&0o2000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	return mode, nil
+}
+
+// applyRootMode makes sure the --root directory exists and has the mode
+// set by --root-mode. An existing directory is only changed if it is owned
+// by the current user.
+func applyRootMode(context *cli.Context) error {
+	if !context.GlobalIsSet("root-mode") {
+		return nil
+	}
+	mode, err := parseRootMode(context.GlobalString("root-mode"))
+	if err != nil {
+		return err
+	}
+	root := context.GlobalString("root")
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return err
+	}
+	fi, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	const modeMask = os.ModePerm | os.ModeSticky | os.ModeSetgid
+	if fi.Mode()&modeMask == mode {
+		return nil
+	}
+	// This cast is safe on Linux.
+	if uid := fi.Sys().(*syscall.Stat_t).Uid; int(uid) != os.Geteuid() {
+		return fmt.Errorf("root directory %s has mode %s (wanted %s) and is owned by another user (uid %d)", root, fi.Mode()&modeMask, mode, uid)
+	}
+	return os.Chmod(root, mode)
+}
+
+// setupTenant makes the --root directory point to the directory of the
+// tenant set by --tenant (creating it if needed), so that the container
+// IDs of different tenants sharing the same --root do not collide. The
+// tenant directory is private to the user who created it.
+func setupTenant(context *cli.Context) error {
+	tenant := context.GlobalString("tenant")
+	if tenant == "" {
+		return nil
+	}
+	if err := libcontainer.ValidateID(tenant); err != nil {
+		return fmt.Errorf("invalid tenant name: %w", err)
+	}
+	dir := filepath.Join(context.GlobalString("root"), tenant)
+	if err := os.MkdirAll(filepath.Dir(dir), 0o700); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0o700); err == nil {
+		f, err := os.OpenFile(filepath.Join(dir, tenantMarker), os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	} else if !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("tenant %s: %s is not a directory", tenant, dir)
+	}
+	// This cast is safe on Linux.
+	if uid := fi.Sys().(*syscall.Stat_t).Uid; int(uid) != os.Geteuid() {
+		return fmt.Errorf("tenant %s is owned by another user (uid %d)", tenant, uid)
+	}
+	if _, err := os.Stat(filepath.Join(dir, tenantMarker)); err != nil {
+		return fmt.Errorf("tenant %s: %s is not a tenant directory (a container with the same ID?)", tenant, dir)
+	}
+	return context.GlobalSet("root", dir)
+}
+
+// isTenantDir reports whether the directory (under --root) belongs to a
+// tenant rather than to a container. As the container state directories
+// are searchable by others, a directory which can't be searched is assumed
+// to be the tenant directory of another user.
+func isTenantDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, tenantMarker))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// The kinds of the default root directory, see defaultRootDir.
+const (
+	rootDirSystem  = iota // /run/runc
+	rootDirXDG            // $XDG_RUNTIME_DIR/runc
+	rootDirRunUser        // /run/user/$UID/runc
+	rootDirTmp            // $TMPDIR/runc-$UID
+)
+
+const (
+	// bootIDFilename is the file in the default root directory recording
+	// the boot ID of the system the directory was created on.
+	bootIDFilename = ".boot-id"
+	// staleRootSuffix is appended to the name of the default root
+	// directory found to be from a previous boot.
+	staleRootSuffix = ".stale"
+)
+
+// bootIDFile can be changed by unit tests.
+var bootIDFile = "/proc/sys/kernel/random/boot_id"
+
+// defaultRootDir returns the root directory to use when neither --root nor
+// $RUNC_ROOT is set, along with its kind. The first usable one of these is
+// used:
+//
+//  1. /run/runc, unless rootless (see shouldHonorXDGRuntimeDir);
+//  2. $XDG_RUNTIME_DIR/runc, if $XDG_RUNTIME_DIR is a directory owned by
+//     the current user;
+//  3. /run/user/$UID/runc, if /run/user/$UID is a directory owned by the
+//     current user (i.e. there is a logind session, but $XDG_RUNTIME_DIR
+//     is not set, like after su or sudo);
+//  4. $TMPDIR/runc-$UID, for non-root users (i.e. there is no logind
+//     session at all);
+//  5. /run/runc otherwise (i.e. for root in a user namespace).
+//
+// This function does not create anything, see prepareRootDir.
+func defaultRootDir() (string, int) {
+	if !shouldHonorXDGRuntimeDir() {
+		return "/run/runc", rootDirSystem
+	}
+	euid := os.Geteuid()
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+		err := checkOwnedDir(xdg, euid)
+		if err == nil {
+			return filepath.Join(xdg, "runc"), rootDirXDG
+		}
+		logrus.Debugf("ignoring $XDG_RUNTIME_DIR: %v", err)
+	}
+	dir := filepath.Join("/run/user", strconv.Itoa(euid))
+	if checkOwnedDir(dir, euid) == nil {
+		return filepath.Join(dir, "runc"), rootDirRunUser
+	}
+	if euid != 0 {
+		return filepath.Join(os.TempDir(), "runc-"+strconv.Itoa(euid)), rootDirTmp
+	}
+	return "/run/runc", rootDirSystem
+}
+
+// checkOwnedDir checks that dir is a directory owned by uid.
+func checkOwnedDir(dir string, uid int) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	// This cast is safe on Linux.
+	if owner := fi.Sys().(*syscall.Stat_t).Uid; int(owner) != uid {
+		return fmt.Errorf("%s is owned by uid %d, not %d", dir, owner, uid)
+	}
+	return nil
+}
+
+// prepareRootDir creates the default root directory (as returned by
+// defaultRootDir) if needed, with safe permissions, and moves it aside
+// if it is from a previous boot.
+func prepareRootDir(root string, kind int) error {
+	switch kind {
+	case rootDirXDG, rootDirRunUser:
+		// According to the XDG specification, we need to set anything in
+		// XDG_RUNTIME_DIR to have a sticky bit if we don't want it to get
+		// auto-pruned.
+		if err := os.MkdirAll(root, 0o700); err != nil {
+			return fmt.Errorf("the runtime directory %s must be writable by the user: %w", filepath.Dir(root), err)
+		}
+		if err := os.Chmod(root, os.FileMode(0o700)|os.ModeSticky); err != nil {
+			return fmt.Errorf("unable to set permissions of %s: %w", root, err)
+		}
+	case rootDirTmp:
+		// The temporary directory is shared with other users, so make sure
+		// the directory is not pre-created (or replaced by a symlink) by
+		// someone else.
+		if err := os.Mkdir(root, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		fi, err := os.Lstat(root)
+		if err != nil {
+			return err
+		}
+		// This cast is safe on Linux.
+		if !fi.IsDir() || int(fi.Sys().(*syscall.Stat_t).Uid) != os.Geteuid() || fi.Mode().Perm()&0o077 != 0 {
+			return fmt.Errorf("unsafe root directory %s (not a directory private to the user); set --root or $XDG_RUNTIME_DIR", root)
+		}
+	}
+	checkRootBootID(root)
+	return nil
+}
+
+// checkRootBootID compares the boot ID recorded in root to the current one.
+// If they differ, the root directory (and the state of the containers in
+// it) is from a previous boot, which can happen if it is not on tmpfs. As
+// the containers are all gone, the root directory is then moved aside,
+// replacing the one moved aside previously (if any). If the boot ID is not
+// yet recorded, it is.
+//
+// The root directory is locked during the check, so that concurrent runc
+// invocations do not step on each other. Errors are logged but otherwise
+// ignored, as this is merely a cleanup.
+func checkRootBootID(root string) {
+	data, err := os.ReadFile(bootIDFile)
+	if err != nil {
+		logrus.Debugf("unable to read boot ID: %v", err)
+		return
+	}
+	bootID := bytes.TrimSpace(data)
+
+	// Retry if the directory is moved aside by someone else while we
+	// are waiting for the lock.
+	for i := 0; i < 3; i++ {
+		done, err := checkRootBootIDLocked(root, bootID)
+		if err != nil {
+			logrus.Warnf("unable to check the boot ID of %s: %v", root, err)
+			return
+		}
+		if done {
+			return
+		}
+	}
+}
+
+func checkRootBootIDLocked(root string, bootID []byte) (bool, error) {
+	dir, err := os.Open(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	defer dir.Close()
+	if err := unix.Flock(int(dir.Fd()), unix.LOCK_EX); err != nil {
+		return false, &os.PathError{Op: "flock", Path: root, Err: err}
+	}
+	locked, err := dir.Stat()
+	if err != nil {
+		return false, err
+	}
+	current, err := os.Stat(root)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if current == nil || !os.SameFile(locked, current) {
+		return false, nil
+	}
+
+	path := filepath.Join(root, bootIDFilename)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(path, append(bootID, '\n'), 0o644); err != nil {
+			// Most probably, the directory is not writable by us.
+			logrus.Debugf("unable to record boot ID: %v", err)
+		}
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if bytes.Equal(bytes.TrimSpace(data), bootID) {
+		return true, nil
+	}
+
+	stale := root + staleRootSuffix
+	if err := os.RemoveAll(stale); err != nil {
+		return false, err
+	}
+	if err := os.Rename(root, stale); err != nil {
+		return false, err
+	}
+	logrus.Warnf("root directory %s is from a previous boot, moved to %s", root, stale)
+	if err := os.Mkdir(root, locked.Mode()&(os.ModePerm|os.ModeSticky|os.ModeSetgid)); err != nil {
+		return false, err
+	}
+	// Mkdir is subject to umask.
+	if err := os.Chmod(root, locked.Mode()&(os.ModePerm|os.ModeSticky|os.ModeSetgid)); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, append(bootID, '\n'), 0o644)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setBootID(t *testing.T, id string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "boot_id")
+	if err := os.WriteFile(file, []byte(id+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := bootIDFile
+	bootIDFile = file
+	t.Cleanup(func() { bootIDFile = saved })
+}
+
+func readBootID(t *testing.T, root string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, bootIDFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestCheckRootBootID(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "runc")
+
+	// Nothing is created if the root does not exist.
+	setBootID(t, "boot1")
+	checkRootBootID(root)
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Fatalf("expected %s not to exist, got %v", root, err)
+	}
+
+	// The boot ID is recorded.
+	if err := os.Mkdir(root, 0o711); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "ct1"), 0o711); err != nil {
+		t.Fatal(err)
+	}
+	checkRootBootID(root)
+	if id := readBootID(t, root); id != "boot1" {
+		t.Fatalf("expected boot ID boot1, got %q", id)
+	}
+	checkRootBootID(root)
+	if _, err := os.Stat(filepath.Join(root, "ct1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a reboot, the root is moved aside and recreated.
+	setBootID(t, "boot2")
+	checkRootBootID(root)
+	if id := readBootID(t, root); id != "boot2" {
+		t.Fatalf("expected boot ID boot2, got %q", id)
+	}
+	if _, err := os.Stat(filepath.Join(root, "ct1")); !os.IsNotExist(err) {
+		t.Fatalf("expected stale container to be moved, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root+staleRootSuffix, "ct1")); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o711 {
+		t.Fatalf("expected mode 0711, got %o", perm)
+	}
+}
+
+func TestPrepareRootDirTmp(t *testing.T) {
+	setBootID(t, "boot1")
+	root := filepath.Join(t.TempDir(), "runc-test")
+	if err := prepareRootDir(root, rootDirTmp); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o700 {
+		t.Fatalf("expected mode 0700, got %o", perm)
+	}
+
+	// A directory accessible by others is rejected.
+	if err := os.Chmod(root, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareRootDir(root, rootDirTmp); err == nil {
+		t.Fatal("expected an error for a directory writable by others")
+	}
+
+	// So is a symlink.
+	link := root + "-link"
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareRootDir(link, rootDirTmp); err == nil {
+		t.Fatal("expected an error for a symlink")
+	}
+}
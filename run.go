@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/urfave/cli"
 )
@@ -35,6 +39,10 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Value: "",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal",
 		},
+		cli.IntFlag{
+			Name:  "console-socket-version",
+			Usage: "the `version` of the protocol used over the console socket (1 or 2, the default being 1)",
+		},
 		cli.StringFlag{
 			Name:  "pidfd-socket",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the init process",
@@ -68,12 +76,67 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Name:  "preserve-fds",
 			Usage: "Pass N additional file descriptors to the container (stdio + $LISTEN_FDS + N in total)",
 		},
+		secretFlag,
+		configOverrideFlag,
+		configPatchFlag,
+		cli.IntFlag{
+			Name:  "cgroup-fd",
+			Usage: "use the existing cgroup v2 directory opened as file descriptor `N` as the container cgroup",
+		},
+		cli.IntFlag{
+			Name:  "userns-fd",
+			Usage: "join the user namespace opened as file descriptor `N`",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "stop the container (and exit with status 124) if it is still running after the given `duration` (such as 30m)",
+		},
+		cli.StringFlag{
+			Name:  "exit-status-file",
+			Usage: "atomically write the exit status of the container process (and whether the OOM killer killed a process) to this `path` in JSON once it exits, even with --detach",
+		},
+		cli.BoolFlag{
+			Name:  "control-socket",
+			Usage: "serve a control socket (control.sock in the container state directory) while the container runs, even with --detach",
+		},
+		cli.DurationFlag{
+			Name:  "timeout-grace",
+			Value: 10 * time.Second,
+			Usage: "the `duration` to wait after SIGTERM, once the --timeout deadline is exceeded, before sending SIGKILL",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		/*只容许一个参数*/
 		if err := checkArgs(context, 1, exactArgs); err != nil {
 			return err
 		}
+		if context.Duration("timeout") < 0 || context.Duration("timeout-grace") < 0 {
+			return errors.New("--timeout and --timeout-grace must not be negative")
+		}
+		if context.Duration("timeout") > 0 && context.Bool("detach") {
+			return errors.New("--timeout can not be used with --detach")
+		}
+		if p := context.String("exit-status-file"); p != "" {
+			// The path is relative to the current directory, not to
+			// the bundle.
+			p, err := filepath.Abs(p)
+			if err != nil {
+				return err
+			}
+			if err := context.Set("exit-status-file", p); err != nil {
+				return err
+			}
+		}
+		switch {
+		case strings.HasPrefix(os.Getenv(exitMonitorEnv), "monitor:"):
+			exitMonitor(context)
+		case exitMonitored(context):
+			status, err := runExitMonitored(context)
+			if err != nil {
+				return err
+			}
+			os.Exit(status)
+		}
 		/*对container执行run操作*/
 		status, err := startContainer(context, CT_ACT_RUN, nil)
 		if err == nil {
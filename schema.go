@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// cliSchema is a machine-readable description of the runc command line,
+// generated from the cli metadata.
+type cliSchema struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	GlobalFlags []flagSchema    `json:"global_flags"`
+	Commands    []commandSchema `json:"commands"`
+}
+
+type commandSchema struct {
+	Name    string       `json:"name"`
+	Aliases []string     `json:"aliases,omitempty"`
+	Usage   string       `json:"usage"`
+	Flags   []flagSchema `json:"flags"`
+	Args    argsSchema   `json:"args"`
+}
+
+type flagSchema struct {
+	// Names are the flag names, without the leading dashes.
+	Names      []string `json:"names"`
+	TakesValue bool     `json:"takes_value"`
+	Usage      string   `json:"usage"`
+}
+
+// argsSchema describes the positional arguments of a command.
+type argsSchema struct {
+	Min int `json:"min"`
+	// Max is the maximum number of arguments, or -1 if unlimited.
+	Max int `json:"max"`
+	// ContainerID is set if the first argument is a container ID.
+	ContainerID bool `json:"container_id"`
+}
+
+// commandArgs describes the positional arguments of runc commands,
+// which can not be obtained from the cli metadata. It must match
+// the checkArgs call of the command.
+var commandArgs = map[string]argsSchema{
+	"audit":      {Min: 1, Max: 1, ContainerID: true},
+	"checkpoint": {Min: 1, Max: 1, ContainerID: true},
+	"cli-schema": {Min: 0, Max: 0},
+	"completion": {Min: 1, Max: 1},
+	"create":     {Min: 1, Max: 1},
+	"delete":     {Min: 1, Max: 1, ContainerID: true},
+	"doctor":     {Min: 0, Max: 0},
+	"events":     {Min: 1, Max: 1, ContainerID: true},
+	"exec":       {Min: 1, Max: -1, ContainerID: true},
+	"features":   {Min: 0, Max: 0},
+	"kill":       {Min: 1, Max: 2, ContainerID: true},
+	"label":      {Min: 2, Max: -1, ContainerID: true},
+	"list":       {Min: 0, Max: 0},
+	"migrate":    {Min: 2, Max: 2, ContainerID: true},
+	"pause":      {Min: 1, Max: 1, ContainerID: true},
+	"ps":         {Min: 1, Max: -1, ContainerID: true},
+	"restore":    {Min: 1, Max: 1},
+	"resume":     {Min: 1, Max: 1, ContainerID: true},
+	"run":        {Min: 1, Max: 1},
+	"spec":       {Min: 0, Max: 0},
+	"start":      {Min: 1, Max: 1, ContainerID: true},
+	"state":      {Min: 1, Max: 1, ContainerID: true},
+	"top":        {Min: 1, Max: 1, ContainerID: true},
+	"update":     {Min: 1, Max: 1, ContainerID: true},
+	"validate":   {Min: 0, Max: 0},
+}
+
+var cliSchemaCommand = cli.Command{
+	Name:  "cli-schema",
+	Usage: "output a JSON description of runc commands and flags",
+	Description: `The cli-schema command outputs a machine-readable (JSON) description of
+runc commands, their flags and arguments, to be used by wrappers and tools
+which need to stay in sync with the runc command line.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(getCLISchema(context.App))
+	},
+}
+
+func getCLISchema(app *cli.App) *cliSchema {
+	s := &cliSchema{
+		Name:        app.Name,
+		Version:     version,
+		GlobalFlags: getFlagsSchema(app.VisibleFlags()),
+	}
+	for _, cmd := range app.VisibleCommands() {
+		args, ok := commandArgs[cmd.Name]
+		if !ok {
+			args = argsSchema{Min: 0, Max: -1}
+		}
+		s.Commands = append(s.Commands, commandSchema{
+			Name:    cmd.Name,
+			Aliases: cmd.Aliases,
+			Usage:   cmd.Usage,
+			Flags:   getFlagsSchema(cmd.VisibleFlags()),
+			Args:    args,
+		})
+	}
+	return s
+}
+
+func getFlagsSchema(flags []cli.Flag) []flagSchema {
+	s := make([]flagSchema, 0, len(flags))
+	for _, f := range flags {
+		fs := flagSchema{}
+		for _, name := range strings.Split(f.GetName(), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				fs.Names = append(fs.Names, name)
+			}
+		}
+		if df, ok := f.(cli.DocGenerationFlag); ok {
+			fs.TakesValue = df.TakesValue()
+			fs.Usage = df.GetUsage()
+		}
+		s = append(s, fs)
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+var secretFlag = cli.StringSliceFlag{
+	Name:  "secret",
+	Usage: "inject a secret into the container process, without writing it to the container filesystem. Format is name=<name>,src=<path>|fd:<N>[,type=keyring|memfd][,env=<var>]",
+}
+
+// parseSecrets parses the --secret options, and opens the secret sources.
+func parseSecrets(context *cli.Context) (_ []*libcontainer.Secret, retErr error) {
+	var secrets []*libcontainer.Secret
+	defer func() {
+		if retErr != nil {
+			closeSecrets(secrets)
+		}
+	}()
+	for _, opt := range context.StringSlice("secret") {
+		s := &libcontainer.Secret{Type: libcontainer.SecretKeyring}
+		var src string
+		for _, kv := range strings.Split(opt, ",") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid --secret %q: %q is not key=value", opt, kv)
+			}
+			switch k {
+			case "name":
+				s.Name = v
+			case "src":
+				src = v
+			case "type":
+				s.Type = libcontainer.SecretType(v)
+			case "env":
+				s.Env = v
+			default:
+				return nil, fmt.Errorf("invalid --secret %q: unknown key %q", opt, k)
+			}
+		}
+		if s.Name == "" || src == "" {
+			return nil, fmt.Errorf("invalid --secret %q: name and src are required", opt)
+		}
+		if s.Type == libcontainer.SecretMemfd && s.Env == "" {
+			s.Env = s.Name
+		}
+		f, err := openSecret(src)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", s.Name, err)
+		}
+		s.File = f
+		secrets = append(secrets, s)
+	}
+	return secrets, nil
+}
+
+// openSecret opens the secret source, which is either a path or an fd:N
+// file descriptor (such as a pipe the secret is written to by the runc
+// caller).
+func openSecret(src string) (*os.File, error) {
+	fdStr, ok := strings.CutPrefix(src, "fd:")
+	if !ok {
+		return os.Open(src)
+	}
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil || fd < 3 {
+		return nil, fmt.Errorf("invalid source %q: must be fd:N, N being 3 or more", src)
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0); err != nil {
+		return nil, fmt.Errorf("source %s: %w", src, os.NewSyscallError("fcntl", err))
+	}
+	return os.NewFile(uintptr(fd), src), nil
+}
+
+func closeSecrets(secrets []*libcontainer.Secret) {
+	for _, s := range secrets {
+		_ = s.File.Close()
+	}
+}
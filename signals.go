@@ -68,7 +68,7 @@ func (h *signalHandler) forward(process *libcontainer.Process, tty *tty, detach
 			return 0, nil
 		}
 		_ = h.notifySocket.run(os.Getpid())
-		go func() { _ = h.notifySocket.run(0) }()
+		go func() { _ = h.notifySocket.forwardStates() }()
 	}
 
 	// Perform the initial tty resize. Always ignore errors resizing because
@@ -64,8 +64,13 @@ generate a proper rootless spec file.
 
 Note that --rootless is not needed when you execute runc as the root in a user namespace
 created by an unprivileged user.
+
+The generated spec can also be customized by options, such as --args, --env or
+--mount, which are applied after --rootless. For example:
+
+    runc spec --args /hello --hostname hello --mount src=/data,dst=/data,opt=ro
 `,
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		cli.StringFlag{
 			Name:  "bundle, b",
 			Value: "",
@@ -75,7 +80,7 @@ created by an unprivileged user.
 			Name:  "rootless",
 			Usage: "generate a configuration for a rootless container",
 		},
-	},
+	}, specFlags...),
 	Action: func(context *cli.Context) error {
 		/*不接收参数*/
 		if err := checkArgs(context, 0, exactArgs); err != nil {
@@ -88,6 +93,9 @@ created by an unprivileged user.
 		if rootless {
 			specconv.ToRootless(spec)
 		}
+		if err := applySpecFlags(context, spec); err != nil {
+			return err
+		}
 
 		checkNoFile := func(name string) error {
 			_, err := os.Stat(name)
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/capabilities"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+)
+
+// specFlags are the runc spec options to customize the generated spec.
+var specFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "args",
+		Usage: `the command to run: space separated arguments, or a JSON array (such as '["sh", "-c", "echo hello"]')`,
+	},
+	cli.StringFlag{
+		Name:  "cwd",
+		Usage: "the working directory of the container process",
+	},
+	cli.StringSliceFlag{
+		Name:  "env",
+		Usage: "set an environment variable as KEY=VALUE, or KEY to pass its current value (can be specified multiple times)",
+	},
+	cli.StringFlag{
+		Name:  "hostname",
+		Usage: "the container hostname",
+	},
+	cli.StringSliceFlag{
+		Name:  "mount",
+		Usage: "add a mount, such as type=bind,src=/data,dst=/data,opt=ro (can be specified multiple times)",
+	},
+	cli.StringSliceFlag{
+		Name:  "cap-add",
+		Usage: "add a capability, or ALL (can be specified multiple times)",
+	},
+	cli.StringSliceFlag{
+		Name:  "cap-drop",
+		Usage: "drop a capability, or ALL (can be specified multiple times)",
+	},
+	cli.StringSliceFlag{
+		Name:  "ns",
+		Usage: "add a namespace (TYPE), join one (TYPE=PATH), or remove one (-TYPE) (can be specified multiple times)",
+	},
+}
+
+// applySpecFlags customizes spec according to the specFlags options.
+func applySpecFlags(context *cli.Context, spec *specs.Spec) error {
+	if val := context.String("args"); val != "" {
+		args, err := parseSpecArgs(val)
+		if err != nil {
+			return err
+		}
+		spec.Process.Args = args
+	}
+	if val := context.String("cwd"); val != "" {
+		if !filepath.IsAbs(val) {
+			return fmt.Errorf("invalid --cwd %q: must be an absolute path", val)
+		}
+		spec.Process.Cwd = val
+	}
+	for _, val := range context.StringSlice("env") {
+		if err := setSpecEnv(spec.Process, val); err != nil {
+			return err
+		}
+	}
+	if context.IsSet("hostname") {
+		spec.Hostname = context.String("hostname")
+	}
+	for _, val := range context.StringSlice("mount") {
+		m, err := parseSpecMount(val)
+		if err != nil {
+			return err
+		}
+		addSpecMount(spec, m)
+	}
+	for _, val := range context.StringSlice("cap-add") {
+		if err := addSpecCap(spec.Process, val); err != nil {
+			return err
+		}
+	}
+	for _, val := range context.StringSlice("cap-drop") {
+		if err := dropSpecCap(spec.Process, val); err != nil {
+			return err
+		}
+	}
+	for _, val := range context.StringSlice("ns") {
+		if err := setSpecNamespace(spec, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSpecArgs parses the --args value, which is either a JSON array, or
+// space separated arguments.
+func parseSpecArgs(val string) ([]string, error) {
+	var args []string
+	if strings.HasPrefix(strings.TrimSpace(val), "[") {
+		if err := json.Unmarshal([]byte(val), &args); err != nil {
+			return nil, fmt.Errorf("invalid --args %q: %w", val, err)
+		}
+	} else {
+		args = strings.Fields(val)
+	}
+	if len(args) == 0 || args[0] == "" {
+		return nil, fmt.Errorf("invalid --args %q: no command", val)
+	}
+	return args, nil
+}
+
+// setSpecEnv sets the environment variable from an --env value, replacing
+// the variable of the same name, if any.
+func setSpecEnv(p *specs.Process, val string) error {
+	key, _, ok := strings.Cut(val, "=")
+	if !ok {
+		v, found := os.LookupEnv(key)
+		if !found {
+			return fmt.Errorf("invalid --env %q: must be KEY=VALUE, or the name of a set variable", val)
+		}
+		val = key + "=" + v
+	}
+	if key == "" {
+		return fmt.Errorf("invalid --env %q: empty name", val)
+	}
+	for i, e := range p.Env {
+		if k, _, _ := strings.Cut(e, "="); k == key {
+			p.Env[i] = val
+			return nil
+		}
+	}
+	p.Env = append(p.Env, val)
+	return nil
+}
+
+// parseSpecMount parses a --mount value, a comma separated list of key=value
+// pairs: type, source (or src), destination (or dst, or target), and option
+// (or opt), which can be repeated. The type defaults to bind, and bind
+// mounts are recursive unless the bind option is given.
+func parseSpecMount(val string) (specs.Mount, error) {
+	var m specs.Mount
+	for _, kv := range strings.Split(val, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || v == "" {
+			return m, fmt.Errorf("invalid --mount %q: %q is not key=value", val, kv)
+		}
+		switch k {
+		case "type":
+			m.Type = v
+		case "source", "src":
+			m.Source = v
+		case "destination", "dst", "target":
+			m.Destination = v
+		case "option", "opt":
+			m.Options = append(m.Options, v)
+		default:
+			return m, fmt.Errorf("invalid --mount %q: unknown key %q", val, k)
+		}
+	}
+	if !filepath.IsAbs(m.Destination) {
+		return m, fmt.Errorf("invalid --mount %q: destination must be an absolute path", val)
+	}
+	if m.Type == "" {
+		m.Type = "bind"
+	}
+	if m.Type == "bind" {
+		if m.Source == "" {
+			return m, fmt.Errorf("invalid --mount %q: bind mount without a source", val)
+		}
+		var isBind bool
+		for _, o := range m.Options {
+			if o == "bind" || o == "rbind" {
+				isBind = true
+			}
+		}
+		if !isBind {
+			m.Options = append([]string{"rbind"}, m.Options...)
+		}
+	}
+	if m.Source == "" {
+		m.Source = m.Type
+	}
+	return m, nil
+}
+
+// addSpecMount adds m to the spec mounts, replacing the mount with the same
+// destination, if any.
+func addSpecMount(spec *specs.Spec, m specs.Mount) {
+	for i := range spec.Mounts {
+		if filepath.Clean(spec.Mounts[i].Destination) == filepath.Clean(m.Destination) {
+			spec.Mounts[i] = m
+			return
+		}
+	}
+	spec.Mounts = append(spec.Mounts, m)
+}
+
+// specCapName returns the canonical name of a --cap-add or --cap-drop
+// capability (such as CAP_NET_ADMIN for net_admin), or ALL.
+func specCapName(opt, val string) (string, error) {
+	name := strings.ToUpper(val)
+	if name == "ALL" {
+		return name, nil
+	}
+	if !strings.HasPrefix(name, "CAP_") {
+		name = "CAP_" + name
+	}
+	for _, c := range capabilities.KnownCapabilities() {
+		if c == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --%s %q: unknown capability", opt, val)
+}
+
+// addSpecCap adds a capability to the bounding, effective and permitted
+// sets of p.
+func addSpecCap(p *specs.Process, val string) error {
+	name, err := specCapName("cap-add", val)
+	if err != nil {
+		return err
+	}
+	names := []string{name}
+	if name == "ALL" {
+		names = capabilities.KnownCapabilities()
+	}
+	if p.Capabilities == nil {
+		p.Capabilities = &specs.LinuxCapabilities{}
+	}
+	c := p.Capabilities
+	for _, set := range []*[]string{&c.Bounding, &c.Effective, &c.Permitted} {
+		for _, n := range names {
+			if !hasCap(*set, n) {
+				*set = append(*set, n)
+			}
+		}
+	}
+	return nil
+}
+
+// dropSpecCap removes a capability from all the capability sets of p.
+func dropSpecCap(p *specs.Process, val string) error {
+	name, err := specCapName("cap-drop", val)
+	if err != nil {
+		return err
+	}
+	c := p.Capabilities
+	if c == nil {
+		return nil
+	}
+	for _, set := range []*[]string{&c.Bounding, &c.Effective, &c.Inheritable, &c.Permitted, &c.Ambient} {
+		kept := []string{}
+		for _, n := range *set {
+			if name != "ALL" && n != name {
+				kept = append(kept, n)
+			}
+		}
+		*set = kept
+	}
+	return nil
+}
+
+func hasCap(set []string, name string) bool {
+	for _, n := range set {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+var specNamespaceTypes = []specs.LinuxNamespaceType{
+	specs.PIDNamespace,
+	specs.NetworkNamespace,
+	specs.MountNamespace,
+	specs.IPCNamespace,
+	specs.UTSNamespace,
+	specs.UserNamespace,
+	specs.CgroupNamespace,
+	specs.TimeNamespace,
+}
+
+// setSpecNamespace applies an --ns value: TYPE adds a new namespace,
+// TYPE=PATH joins the namespace at PATH, and -TYPE removes the namespace.
+func setSpecNamespace(spec *specs.Spec, val string) error {
+	typ, path, _ := strings.Cut(val, "=")
+	typ, remove := strings.CutPrefix(typ, "-")
+	if remove && path != "" {
+		return fmt.Errorf("invalid --ns %q: can not remove a namespace with a path", val)
+	}
+	var known bool
+	for _, t := range specNamespaceTypes {
+		if string(t) == typ {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("invalid --ns %q: unknown namespace type %q", val, typ)
+	}
+	if path != "" && !filepath.IsAbs(path) {
+		return fmt.Errorf("invalid --ns %q: path must be absolute", val)
+	}
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+
+	var namespaces []specs.LinuxNamespace
+	for _, ns := range spec.Linux.Namespaces {
+		if string(ns.Type) != typ {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	if !remove {
+		if typ == string(specs.UserNamespace) && path == "" &&
+			(len(spec.Linux.UIDMappings) == 0 || len(spec.Linux.GIDMappings) == 0) {
+			return errors.New("invalid --ns user: a new user namespace requires uid and gid mappings (see --rootless)")
+		}
+		namespaces = append(namespaces, specs.LinuxNamespace{
+			Type: specs.LinuxNamespaceType(typ),
+			Path: path,
+		})
+	}
+	spec.Linux.Namespaces = namespaces
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+func TestParseSpecArgs(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected []string
+	}{
+		{in: "/hello", expected: []string{"/hello"}},
+		{in: " echo  hello world ", expected: []string{"echo", "hello", "world"}},
+		{in: `["sh", "-c", "echo hello"]`, expected: []string{"sh", "-c", "echo hello"}},
+		{in: "  "},
+		{in: "[]"},
+		{in: `[""]`},
+		{in: `["sh"`},
+	} {
+		args, err := parseSpecArgs(tc.in)
+		if tc.expected == nil {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", tc.in, args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.expected, args)
+		}
+	}
+}
+
+func TestSetSpecEnv(t *testing.T) {
+	t.Setenv("RUNC_SPEC_TEST", "from-host")
+	p := &specs.Process{Env: []string{"PATH=/bin", "TERM=xterm"}}
+	for _, val := range []string{"TERM=dumb", "FOO=a=b", "RUNC_SPEC_TEST"} {
+		if err := setSpecEnv(p, val); err != nil {
+			t.Fatalf("%q: %v", val, err)
+		}
+	}
+	expected := []string{"PATH=/bin", "TERM=dumb", "FOO=a=b", "RUNC_SPEC_TEST=from-host"}
+	if !reflect.DeepEqual(p.Env, expected) {
+		t.Errorf("expected %q, got %q", expected, p.Env)
+	}
+	for _, val := range []string{"=foo", "RUNC_SPEC_TEST_UNSET"} {
+		if err := setSpecEnv(p, val); err == nil {
+			t.Errorf("%q: expected error, got nil", val)
+		}
+	}
+}
+
+func TestParseSpecMount(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected *specs.Mount
+	}{
+		{
+			in:       "src=/data,dst=/data",
+			expected: &specs.Mount{Type: "bind", Source: "/data", Destination: "/data", Options: []string{"rbind"}},
+		},
+		{
+			in:       "type=bind,source=/data,target=/mnt,opt=bind,opt=ro",
+			expected: &specs.Mount{Type: "bind", Source: "/data", Destination: "/mnt", Options: []string{"bind", "ro"}},
+		},
+		{
+			in:       "type=tmpfs,destination=/run,option=size=1m",
+			expected: &specs.Mount{Type: "tmpfs", Source: "tmpfs", Destination: "/run", Options: []string{"size=1m"}},
+		},
+		{in: "dst=/data"},
+		{in: "src=/data,dst=data"},
+		{in: "type=tmpfs"},
+		{in: "type=tmpfs,dst=/run,ro"},
+		{in: "type=tmpfs,dst=/run,mode=1777"},
+	} {
+		m, err := parseSpecMount(tc.in)
+		if tc.expected == nil {
+			if err == nil {
+				t.Errorf("%q: expected error, got %+v", tc.in, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if !reflect.DeepEqual(&m, tc.expected) {
+			t.Errorf("%q: expected %+v, got %+v", tc.in, tc.expected, m)
+		}
+	}
+}
+
+func TestSpecCaps(t *testing.T) {
+	p := specconv.Example().Process
+	if err := addSpecCap(p, "net_admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dropSpecCap(p, "CAP_KILL"); err != nil {
+		t.Fatal(err)
+	}
+	c := p.Capabilities
+	for _, set := range [][]string{c.Bounding, c.Effective, c.Permitted} {
+		if !hasCap(set, "CAP_NET_ADMIN") || hasCap(set, "CAP_KILL") {
+			t.Errorf("unexpected capabilities %q", set)
+		}
+	}
+	if err := addSpecCap(p, "CAP_NO_SUCH"); err == nil {
+		t.Error("expected error for an unknown capability, got nil")
+	}
+	if err := dropSpecCap(p, "all"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Bounding)+len(c.Effective)+len(c.Permitted) != 0 {
+		t.Errorf("expected no capabilities, got %+v", c)
+	}
+}
+
+func TestSetSpecNamespace(t *testing.T) {
+	spec := specconv.Example()
+	for _, val := range []string{"network=/run/netns/test", "-ipc", "cgroup", "time"} {
+		if err := setSpecNamespace(spec, val); err != nil {
+			t.Fatalf("%q: %v", val, err)
+		}
+	}
+	expected := []specs.LinuxNamespace{
+		{Type: specs.PIDNamespace},
+		{Type: specs.UTSNamespace},
+		{Type: specs.MountNamespace},
+		{Type: specs.NetworkNamespace, Path: "/run/netns/test"},
+		{Type: specs.CgroupNamespace},
+		{Type: specs.TimeNamespace},
+	}
+	if !reflect.DeepEqual(spec.Linux.Namespaces, expected) {
+		t.Errorf("expected %+v, got %+v", expected, spec.Linux.Namespaces)
+	}
+	for _, val := range []string{"net", "-pid=/proc/1/ns/pid", "pid=proc/1/ns/pid", "user"} {
+		if err := setSpecNamespace(spec, val); err == nil {
+			t.Errorf("%q: expected error, got nil", val)
+		}
+	}
+}
@@ -22,6 +22,11 @@ your host.`,
 		if err := checkArgs(context, 1, exactArgs); err != nil {
 			return err
 		}
+		lock, err := lockContainer(context, "start")
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
 		container, err := getContainer(context)
 		if err != nil {
 			return err
@@ -32,6 +37,9 @@ your host.`,
 		}
 		switch status {
 		case libcontainer.Created:
+			if err := checkConfigDrift(container); err != nil {
+				return err
+			}
 			notifySocket, err := notifySocketStart(context, os.Getenv("NOTIFY_SOCKET"), container.ID())
 			if err != nil {
 				return err
@@ -39,16 +47,17 @@ your host.`,
 			if err := container.Exec(); err != nil {
 				return err
 			}
+			lock.Unlock()
 			if notifySocket != nil {
 				return notifySocket.waitForContainer(container)
 			}
 			return nil
 		case libcontainer.Stopped:
-			return errors.New("cannot start a container that has stopped")
+			return withCode(errCodeState, errors.New("cannot start a container that has stopped"))
 		case libcontainer.Running:
-			return errors.New("cannot start an already running container")
+			return withCode(errCodeState, errors.New("cannot start an already running container"))
 		default:
-			return fmt.Errorf("cannot start a container in the %s state", status)
+			return withCode(errCodeState, fmt.Errorf("cannot start a container in the %s state", status))
 		}
 	},
 }
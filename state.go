@@ -2,10 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/cni"
 	"github.com/opencontainers/runc/libcontainer/utils"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
@@ -17,6 +20,16 @@ var stateCommand = cli.Command{
 Where "<container-id>" is your name for the instance of the container.`,
 	Description: `The state command outputs current state information for the
 instance of a container.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "locks",
+			Usage: "also show the container lock holder, if any",
+		},
+		cli.BoolFlag{
+			Name:  "security",
+			Usage: "also show the effective security state (capabilities, seccomp, LSM labels) of the container init, and its drift from the configuration",
+		},
+	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
 			return err
@@ -47,8 +60,54 @@ instance of a container.`,
 			Rootfs:         state.BaseState.Config.Rootfs,
 			Created:        state.BaseState.Created,
 			Annotations:    annotations,
+			Labels:         state.BaseState.Metadata,
+			IPs:            cni.IPs(state.CNIResult),
+			SetupCost:      state.SetupCost,
+		}
+		cs.HelperOomScoreAdj = state.HelperOomScoreAdj
+		cs.RuntimeMaskPaths = state.RuntimeMaskPaths
+		cs.ExitReason = state.ExitReason
+		cs.Namespaces = state.Namespaces
+		cs.ConfigHash = state.Config.ConfigHash
+		cs.CgroupV2Unavailable = state.CgroupV2Unavailable
+		if cs.BundleConfigHash, err = bundleConfigHash(&state.Config); err != nil {
+			logrus.Warnf("unable to get the bundle config.json digest: %v", err)
+		}
+		cs.setCgroupState(state, containerStatus)
+		if containerStatus != libcontainer.Stopped {
+			if adj, err := container.OomScoreAdj(); err != nil {
+				logrus.Warnf("unable to get oom_score_adj: %v", err)
+			} else {
+				cs.OomScoreAdj = &adj
+			}
+			foreign, err := container.ForeignProcesses()
+			if err != nil {
+				logrus.Warnf("unable to check for foreign processes: %v", err)
+			}
+			for _, p := range foreign {
+				cs.ForeignProcesses = append(cs.ForeignProcesses, p.Pid)
+			}
+			if context.Bool("security") {
+				if cs.Security, err = container.SecurityState(); err != nil {
+					return fmt.Errorf("unable to get the security state: %w", err)
+				}
+			}
+		}
+		if cs.Peaks, err = container.UpdatePeaks(); err != nil {
+			logrus.Warnf("unable to get resource peaks: %v", err)
+		}
+		var v interface{} = cs
+		if context.Bool("locks") {
+			holder, err := libcontainer.ContainerLockHolder(context.GlobalString("root"), cs.ID)
+			if err != nil {
+				return err
+			}
+			v = struct {
+				containerState
+				Lock *libcontainer.LockHolder `json:"lock"`
+			}{cs, holder}
 		}
-		data, err := json.MarshalIndent(cs, "", "  ")
+		data, err := json.MarshalIndent(v, "", "  ")
 		if err != nil {
 			return err
 		}
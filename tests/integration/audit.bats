@@ -0,0 +1,49 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	requires root cgroups_v2
+	setup_busybox
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+}
+
+function teardown() {
+	[ -v FOREIGN_PID ] && kill -9 "$FOREIGN_PID"
+	teardown_bundle
+}
+
+@test "runc audit" {
+	runc exec -d test_busybox sleep 1000
+	[ "$status" -eq 0 ]
+
+	runc audit test_busybox
+	[ "$status" -eq 0 ]
+	[ "$output" = "" ]
+
+	runc audit -f json test_busybox
+	[ "$status" -eq 0 ]
+	[ "$output" = "[]" ]
+
+	# Move a host process into the container cgroup.
+	sleep 1000 &
+	FOREIGN_PID=$!
+	echo "$FOREIGN_PID" >"$(get_cgroup_path cgroup.procs)/cgroup.procs"
+
+	runc audit test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"$FOREIGN_PID"*"sleep"* ]]
+	[[ "$output" == *"1 foreign process(es)"* ]]
+
+	runc audit -f json test_busybox
+	[ "$status" -ne 0 ]
+	[[ "${lines[0]}" == *'"pid":'"$FOREIGN_PID"* ]]
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq -c '.foreign_processes' <<<"$output")" = "[$FOREIGN_PID]" ]
+}
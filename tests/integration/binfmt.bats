@@ -0,0 +1,51 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run [binfmt_misc required entry not registered]" {
+	update_config '.annotations += {"org.opencontainers.runc.binfmt.require": "runc-test-missing"}'
+	runc run test_binfmt
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"required binfmt_misc entry runc-test-missing is not registered"* ]]
+}
+
+@test "runc run [binfmt_misc registration without a private instance]" {
+	update_config '.annotations += {"org.opencontainers.runc.binfmt.register": ":runc-test:E::runctest::/bin/sh:"}'
+	runc run test_binfmt
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"require a private binfmt_misc instance"* ]]
+}
+
+@test "runc run [private binfmt_misc instance]" {
+	requires root
+	requires_kernel 6.7
+
+	update_config '.linux.namespaces += [{"type": "user"}]
+		| .linux.uidMappings += [{"hostID": 100000, "containerID": 0, "size": 65534}]
+		| .linux.gidMappings += [{"hostID": 100000, "containerID": 0, "size": 65534}]'
+	remap_rootfs
+	# Run the scripts with the .runctest extension with /bin/sh.
+	update_config '.annotations += {
+			"org.opencontainers.runc.binfmt.private": "true",
+			"org.opencontainers.runc.binfmt.register": ":runc-test:E::runctest::/bin/sh:",
+			"org.opencontainers.runc.binfmt.require": "runc-test"
+		}
+		| .root.readonly = false
+		| .process.args = ["sh", "-c", "echo \"echo binfmt works\" > /tmp/test.runctest && chmod +x /tmp/test.runctest && /tmp/test.runctest && cat /proc/sys/fs/binfmt_misc/runc-test"]'
+
+	runc run test_binfmt
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"binfmt works"* ]]
+	[[ "$output" == *"interpreter /bin/sh"* ]]
+
+	# The entry is not registered on the host.
+	[ ! -e /proc/sys/fs/binfmt_misc/runc-test ]
+}
@@ -187,6 +187,46 @@ function setup() {
 	[[ "$weights" == *"$major:$minor 444"* ]]
 }
 
+@test "runc run (per-device io limits by path)" {
+	requires root # to create a loop device
+
+	dd if=/dev/zero of=backing.img bs=4096 count=1
+	dev=$(losetup --find --show backing.img) || skip "unable to create a loop device"
+
+	set_cgroups_path
+
+	IFS=$' \t:' read -r major minor <<<"$(lsblk -nd -o MAJ:MIN "$dev")"
+	update_config '.annotations += {"org.opencontainers.runc.blkio.read-bps-device": "'"$dev"':1m"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_dev_limit
+	[ "$status" -eq 0 ]
+
+	if [ -v CGROUP_V2 ]; then
+		limits=$(get_cgroup_value io.max)
+		[[ "$limits" == *"$major:$minor rbps=1048576 "* ]]
+	else
+		limits=$(get_cgroup_value blkio.throttle.read_bps_device)
+		[[ "$limits" == *"$major:$minor 1048576"* ]]
+	fi
+
+	# The device path is resolved again on update.
+	runc update --device-write-iops "$dev:100" test_dev_limit
+	[ "$status" -eq 0 ]
+	if [ -v CGROUP_V2 ]; then
+		limits=$(get_cgroup_value io.max)
+		[[ "$limits" == *"$major:$minor rbps=1048576 wbps=max riops=max wiops=100"* ]]
+	else
+		limits=$(get_cgroup_value blkio.throttle.write_iops_device)
+		[[ "$limits" == *"$major:$minor 100"* ]]
+	fi
+
+	runc update --device-read-iops /dev/null:100 test_dev_limit
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"not a block device"* ]]
+
+	runc delete -f test_dev_limit
+	losetup -d "$dev"
+}
+
 @test "runc run (cpu.idle)" {
 	requires cgroups_cpu_idle
 	[ $EUID -ne 0 ] && requires rootless_cgroup
@@ -473,3 +513,70 @@ convert_hugetlb_size() {
 	# Cleanup.
 	rmdir "$FREEZER_DIR"
 }
+
+@test "runc run (adopted cgroup)" {
+	requires root cgroups_v2
+
+	set_cgroups_path
+	update_config '	  .annotations += {"org.opencontainers.runc.cgroup-adopt": "true"}
+			| .linux.resources.pids.limit = 42'
+
+	# The cgroup must exist.
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_adopt
+	[ "$status" -ne 0 ]
+
+	mkdir -p "$CGROUP_V2_PATH"
+	# A process of the cgroup owner.
+	sleep 100 &
+	local owner=$!
+	echo "$owner" >"$CGROUP_V2_PATH/cgroup.procs"
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_adopt
+	[ "$status" -eq 0 ]
+
+	pid=$(__runc state test_adopt | jq '.pid')
+	grep -qx "$pid" "$CGROUP_V2_PATH/cgroup.procs"
+	# Resources are not set.
+	if [ -e "$CGROUP_V2_PATH/pids.max" ]; then
+		[ "$(cat "$CGROUP_V2_PATH/pids.max")" = "max" ]
+	fi
+
+	# Nor can they be updated, nor can the cgroup be frozen.
+	runc update --pids-limit 10 test_adopt
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"adopted cgroup"* ]]
+	runc pause test_adopt
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"adopted cgroup"* ]]
+
+	runc delete --force test_adopt
+	[ "$status" -eq 0 ]
+
+	# Neither the cgroup nor the owner's process is gone.
+	[ -d "$CGROUP_V2_PATH" ]
+	kill -0 "$owner"
+
+	kill -9 "$owner"
+	wait "$owner" || true
+	rmdir "$CGROUP_V2_PATH"
+}
+
+@test "runc run --cgroup-fd" {
+	requires root cgroups_v2
+
+	set_cgroups_path
+	# The cgroupsPath is ignored.
+	update_config '.linux.cgroupsPath = "/runc-cgroups-integration-test/ignored"'
+	mkdir -p "$CGROUP_V2_PATH"
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" --cgroup-fd 5 test_cgroup_fd 5<"$CGROUP_V2_PATH"
+	[ "$status" -eq 0 ]
+
+	pid=$(__runc state test_cgroup_fd | jq '.pid')
+	grep -qx "$pid" "$CGROUP_V2_PATH/cgroup.procs"
+
+	runc delete --force test_cgroup_fd
+	[ "$status" -eq 0 ]
+	# The cgroup is removed, as it is not adopted.
+	[ ! -d "$CGROUP_V2_PATH" ]
+}
@@ -155,6 +155,71 @@ function simple_cr() {
 	simple_cr
 }
 
+@test "checkpoint --compress and restore" {
+	for method in zstd lz4 gzip; do
+		if ! command -v "$method" >/dev/null; then
+			continue
+		fi
+		runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+		[ "$status" -eq 0 ]
+
+		runc checkpoint --compress "$method" --work-path ./work-dir --image-path ./image-dir test_busybox
+		grep -B 5 Error ./work-dir/dump.log || true
+		[ "$status" -eq 0 ]
+		testcontainer test_busybox checkpointed
+
+		# Only the archive is left.
+		run ls ./image-dir
+		[[ "$output" == checkpoint.tar.* ]]
+
+		runc restore -d --work-path ./work-dir --image-path ./image-dir --console-socket "$CONSOLE_SOCKET" test_busybox
+		grep -B 5 Error ./work-dir/restore.log || true
+		[ "$status" -eq 0 ]
+		testcontainer test_busybox running
+
+		runc delete -f test_busybox
+		[ "$status" -eq 0 ]
+		rm -rf ./image-dir ./work-dir
+	done
+}
+
+@test "checkpoint and restore --image-stream" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --work-path ./work-dir --image-path ./image-dir test_busybox
+	grep -B 5 Error ./work-dir/dump.log || true
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox checkpointed
+
+	tar -C ./image-dir -cf ./images.tar .
+	runc restore -d --image-stream - --work-path ./work-dir --console-socket "$CONSOLE_SOCKET" test_busybox <./images.tar
+	grep -B 5 Error ./work-dir/restore.log || true
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	# The extracted images are removed.
+	run find ./work-dir -name 'runc-restore-images-*'
+	[ -z "$output" ]
+
+	runc restore --image-stream ./images.tar --image-path ./image-dir test_busybox_2
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"can not be used together"* ]]
+}
+
+@test "checkpoint --compress (bad value)" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --compress bzip2 test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unsupported --compress value"* ]]
+	testcontainer test_busybox running
+
+	runc checkpoint --compress zstd --pre-dump test_busybox
+	[ "$status" -ne 0 ]
+}
+
 @test "checkpoint --pre-dump (bad --parent-path)" {
 	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
 	[ "$status" -eq 0 ]
@@ -273,6 +338,53 @@ function simple_cr() {
 	check_pipes
 }
 
+@test "checkpoint --lazy-pages and restore --page-client" {
+	# check if lazy-pages is supported
+	if ! criu check --feature uffd-noncoop; then
+		skip "this criu does not support lazy migration"
+	fi
+
+	setup_pipes
+	runc_run_with_pipes test_busybox
+
+	mkdir image-dir
+	mkdir work-dir
+
+	exec {pipe}<> <(:)
+	# shellcheck disable=SC2094
+	exec {lazy_r}</proc/self/fd/$pipe {lazy_w}>/proc/self/fd/$pipe
+	exec {pipe}>&-
+
+	port=27278
+
+	__runc checkpoint \
+		--lazy-pages \
+		--page-server 0.0.0.0:${port} \
+		--status-fd ${lazy_w} \
+		--manage-cgroups-mode=ignore \
+		--work-path ./work-dir \
+		--image-path ./image-dir \
+		test_busybox &
+	cpt_pid=$!
+
+	out=$(timeout 2 dd if=/proc/self/fd/${lazy_r} bs=1 count=1 2>/dev/null | od)
+	exec {lazy_r}>&-
+	exec {lazy_w}>&-
+	# shellcheck disable=SC2116,SC2086
+	out=$(echo $out) # rm newlines
+	grep -B5 Error ./work-dir/dump.log || true
+	[ "$out" = "0000000 000000 0000001" ]
+
+	# No criu lazy-pages daemon is needed, runc runs it.
+	runc_restore_with_pipes ./image-dir test_busybox_restore \
+		--page-client 127.0.0.1:${port} \
+		--manage-cgroups-mode=ignore
+
+	wait $cpt_pid
+
+	check_pipes
+}
+
 @test "checkpoint and restore in external network namespace" {
 	# check if external_net_ns is supported; only with criu 3.10++
 	if ! criu check --feature external_net_ns; then
@@ -457,3 +569,57 @@ function simple_cr() {
 	pid=$(cat "pid")
 	grep -q "${REL_CGROUPS_PATH}$" "/proc/$pid/cgroup"
 }
+
+function setup_migrate() {
+	update_config '	  .process.terminal = false
+			| .process.args = ["sleep", "1000"]'
+	__runc run -d test_busybox </dev/null >/dev/null 2>&1
+	testcontainer test_busybox running
+
+	# Run the "remote" commands locally.
+	cat >rsh <<-'EOF'
+		#!/bin/sh
+		shift
+		exec sh -c "$1"
+	EOF
+	chmod +x rsh
+	mkdir root2
+}
+
+@test "runc migrate (to another runc root)" {
+	setup_migrate
+
+	runc migrate --pre-dumps 2 --rsh "$(pwd)/rsh" \
+		--remote-runc "$RUNC --root $(pwd)/root2" \
+		--remote-image-path "$(pwd)/remote" test_busybox localhost
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"pre-dump 2/2"* ]]
+
+	# The container is gone from the original root...
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+	[ ! -e remote ]
+
+	# ...and running in the other one.
+	run "$RUNC" --root "$(pwd)/root2" state test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *'"status": "running"'* ]]
+	"$RUNC" --root "$(pwd)/root2" delete --force test_busybox
+}
+
+@test "runc migrate (restored locally on failure)" {
+	setup_migrate
+	cat >badrunc <<-'EOF'
+		#!/bin/sh
+		[ "$1" = "--version" ]
+	EOF
+	chmod +x badrunc
+
+	runc migrate --pre-dumps 0 --rsh "$(pwd)/rsh" \
+		--remote-runc "$(pwd)/badrunc" \
+		--remote-image-path "$(pwd)/remote" test_busybox localhost
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"restored locally"* ]]
+
+	testcontainer test_busybox running
+}
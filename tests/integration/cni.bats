@@ -0,0 +1,62 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+
+	mkdir -p "$ROOT/cni/net.d" "$ROOT/cni/bin"
+	cat >"$ROOT/cni/net.d/10-test.conflist" <<EOF
+{"cniVersion": "1.0.0", "name": "test", "plugins": [{"type": "fake"}]}
+EOF
+	# A fake plugin, logging its invocations.
+	cat >"$ROOT/cni/bin/fake" <<EOF
+#!/bin/sh
+cat >/dev/null
+echo "\$CNI_COMMAND \$CNI_CONTAINERID" >>"$ROOT/cni/log"
+[ "\$CNI_COMMAND" = ADD ] && echo '{"cniVersion": "1.0.0", "ips": [{"address": "10.88.0.2/16"}]}'
+exit 0
+EOF
+	chmod +x "$ROOT/cni/bin/fake"
+
+	update_config '.annotations += {
+		"org.opencontainers.runc.cni.network": "test",
+		"org.opencontainers.runc.cni.conf-dir": "'"$ROOT/cni/net.d"'",
+		"org.opencontainers.runc.cni.bin-dir": "'"$ROOT/cni/bin"'"
+	}
+	| .process.args = ["/bin/sleep", "100"]'
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run [cni network]" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cni
+	[ "$status" -eq 0 ]
+
+	runc state test_cni
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq -r '.ips[0]')" = "10.88.0.2/16" ]
+	[ "$(cat "$ROOT/cni/log")" = "ADD test_cni" ]
+
+	runc delete -f test_cni
+	[ "$status" -eq 0 ]
+	[ "$(tail -n 1 "$ROOT/cni/log")" = "DEL test_cni" ]
+}
+
+@test "runc run [cni network not found]" {
+	update_config '.annotations["org.opencontainers.runc.cni.network"] = "missing"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cni
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"network \"missing\" not found"* ]]
+}
+
+@test "runc run [cni requires a new network namespace]" {
+	update_config '.linux.namespaces -= [{"type": "network"}]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_cni
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"a new network namespace is required"* ]]
+}
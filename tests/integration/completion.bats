@@ -0,0 +1,63 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	# These tests do not need a container.
+	requires root
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc completion bash" {
+	runc completion bash
+	[ "$status" -eq 0 ]
+	bash -n <<<"$output"
+
+	# The generated script completes command names.
+	script="$output"
+	run bash -c "$script"'
+		COMP_WORDS=(runc sta); COMP_CWORD=1; _runc; echo "${COMPREPLY[@]}"'
+	[ "$status" -eq 0 ]
+	[[ "$output" == *start* ]]
+	[[ "$output" == *state* ]]
+}
+
+@test "runc completion bash [container IDs]" {
+	setup_busybox
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc completion bash
+	[ "$status" -eq 0 ]
+	script="$output"
+	# The container IDs are listed from the --root given on the command line.
+	run bash -c "$script"'
+		COMP_WORDS=("$0" --root "$1" state test_); COMP_CWORD=4; _runc; echo "${COMPREPLY[@]}"' "$RUNC" "$ROOT/state"
+	[ "$status" -eq 0 ]
+	[ "$output" = "test_busybox" ]
+}
+
+@test "runc completion zsh|fish" {
+	runc completion zsh
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "#compdef runc" ]]
+
+	runc completion fish
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"complete -c runc"* ]]
+}
+
+@test "runc completion [unsupported shell]" {
+	runc completion tcsh
+	[ "$status" -ne 0 ]
+}
+
+@test "runc cli-schema" {
+	runc cli-schema
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r '.commands[] | select(.name == "kill") | .args.max' <<<"$output")" == "2" ]]
+	[[ "$(jq -r '.global_flags[] | select(.names[0] == "root") | .takes_value' <<<"$output")" == "true" ]]
+}
@@ -123,6 +123,48 @@ function test_runc_delete_host_pidns() {
 	[ "$status" -ne 0 ]
 }
 
+@test "runc delete --stop-signal --stop-timeout" {
+	update_config '.process.args = ["sh", "-c", "trap \"exit 0\" USR1; while true; do sleep 0.1; done"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	# The container exits on SIGUSR1, long before the timeout.
+	SECONDS=0
+	runc delete --stop-signal USR1 --stop-timeout 30s test_busybox
+	[ "$status" -eq 0 ]
+	[ "$SECONDS" -lt 20 ]
+
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+}
+
+@test "runc delete --stop-timeout [SIGKILL after timeout]" {
+	# The container init ignores SIGTERM.
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	runc delete --stop-timeout 1s test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+}
+
+@test "runc delete --stop-timeout [paused container]" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc pause test_busybox
+	[ "$status" -eq 0 ]
+	runc delete --stop-timeout 1s test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+}
+
 @test "runc delete --force ignore not exist" {
 	runc delete --force notexists
 	[ "$status" -eq 0 ]
@@ -270,3 +312,21 @@ EOF
 	# Expect "no such unit" exit code.
 	run -4 systemctl status $user "$SD_UNIT_NAME"
 }
+
+@test "runc delete --summary" {
+	requires root cgroups_v2
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq '.peaks.memory' <<<"$output")" -gt 0 ]
+
+	runc delete --force --summary test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '.type' <<<"$output")" = "summary" ]
+	[ "$(jq -r '.id' <<<"$output")" = "test_busybox" ]
+	[ "$(jq '.data.memory' <<<"$output")" -gt 0 ]
+}
@@ -0,0 +1,70 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	requires root
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+	if [ -v QUOTA_MNT ]; then
+		umount "$QUOTA_MNT"
+		losetup -d "$QUOTA_DEV" || true
+	fi
+}
+
+# Mount an ext4 filesystem with project quotas enabled, and set $QUOTA_MNT.
+function setup_quota_fs() {
+	command -v mkfs.ext4 >/dev/null || skip "mkfs.ext4 not found"
+	truncate -s 64M "$ROOT/quota.img"
+	mkfs.ext4 -q -O quota,project "$ROOT/quota.img" || skip "unable to create an ext4 filesystem with project quotas"
+	QUOTA_DEV=$(losetup --find --show "$ROOT/quota.img") || skip "unable to create a loop device"
+	QUOTA_MNT="$ROOT/quota"
+	mkdir -p "$QUOTA_MNT"
+	if ! mount -o prjquota "$QUOTA_DEV" "$QUOTA_MNT"; then
+		losetup -d "$QUOTA_DEV"
+		unset QUOTA_MNT
+		skip "project quotas not supported by the kernel"
+	fi
+	mkdir "$QUOTA_MNT/upper"
+}
+
+@test "runc run (disk quota)" {
+	setup_quota_fs
+
+	update_config '.annotations += {
+			"org.opencontainers.runc.disk-quota": "4m",
+			"org.opencontainers.runc.disk-quota-path": "'"$QUOTA_MNT/upper"'",
+			"org.opencontainers.runc.disk-quota-project": "4242"
+		}
+		| .process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_quota
+	[ "$status" -eq 0 ]
+
+	# Files created in the directory are accounted to the project.
+	dd if=/dev/zero of="$QUOTA_MNT/upper/file" bs=1M count=2
+	sync
+	runc events --stats test_quota
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq '.data.disk_quota.size_limit')" -eq $((4 << 20)) ]
+	[ "$(echo "$output" | jq '.data.disk_quota.size')" -ge $((2 << 20)) ]
+
+	# The limit is enforced.
+	run ! dd if=/dev/zero of="$QUOTA_MNT/upper/big" bs=1M count=8
+
+	runc delete -f test_quota
+	[ "$status" -eq 0 ]
+}
+
+@test "runc run (disk quota, not enabled)" {
+	update_config '.annotations += {"org.opencontainers.runc.disk-quota": "4m"}'
+	# The bundle is not on a filesystem with project quotas.
+	if findmnt -n -o OPTIONS --target "$(pwd)" | grep -q prjquota; then
+		skip "the bundle is on a filesystem with project quotas"
+	fi
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_quota
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unable to set disk quota"* ]]
+}
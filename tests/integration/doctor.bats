@@ -0,0 +1,27 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	requires root
+}
+
+@test "runc doctor" {
+	runc doctor
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"run"*"pass"* ]]
+	[[ "$output" != *"fail"* ]]
+}
+
+@test "runc doctor --format json" {
+	runc doctor --format json
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq -r '.[] | select(.name == "run") | .status')" = "pass" ]
+	[ "$(echo "$output" | jq '[.[] | select(.status == "fail")] | length')" -eq 0 ]
+}
+
+@test "runc doctor --format invalid" {
+	runc doctor --format invalid
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid format option"* ]]
+}
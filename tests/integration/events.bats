@@ -60,6 +60,36 @@ function test_events() {
 	[[ "${lines[0]}" == *"data"* ]]
 }
 
+@test "events --stats with several containers" {
+	requires root
+	init_cgroup_paths
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox2
+	[ "$status" -eq 0 ]
+
+	runc events --stats test_busybox test_busybox2 test_missing
+	[ "$status" -eq 0 ]
+	[ "$(jq length <<<"$output")" -eq 3 ]
+	[ "$(jq -r '.[0].type + " " + .[0].id' <<<"$output")" = "stats test_busybox" ]
+	[ "$(jq -r '.[1].type + " " + .[1].id' <<<"$output")" = "stats test_busybox2" ]
+	[ "$(jq -r '.[2].type + " " + .[2].id' <<<"$output")" = "error test_missing" ]
+	[ "$(jq '.[1].data.pids.current' <<<"$output")" -ge 1 ]
+
+	runc kill test_busybox2 KILL
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_busybox2 stopped
+
+	runc events --stats --all
+	[ "$status" -eq 0 ]
+	[ "$(jq length <<<"$output")" -eq 1 ]
+	[ "$(jq -r '.[0].id' <<<"$output")" = "test_busybox" ]
+
+	runc events --all test_busybox
+	[ "$status" -ne 0 ]
+}
+
 @test "events --stats with psi data" {
 	requires root cgroups_v2 psi
 	init_cgroup_paths
@@ -128,3 +158,84 @@ function test_events() {
 
 	grep -q '{"type":"oom","id":"test_busybox"}' events.log
 }
+
+@test "events --oom-report" {
+	requires root cgroups_swap
+	init_cgroup_paths
+
+	update_config '(.. | select(.resources? != null)) .resources.memory |= {"limit": 33554432, "swap": 33554432}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	(__runc events --oom-report --oom-report-top 1 test_busybox >events.log) &
+	(
+		retry 10 1 grep -q test_busybox events.log
+		# shellcheck disable=SC2016
+		__runc exec -d test_busybox sh -c 'test=$(dd if=/dev/urandom ibs=5120k)'
+		retry 30 1 grep -q oom-report events.log
+		__runc delete -f test_busybox
+	) &
+	wait
+
+	report=$(jq -c 'select(.type == "oom-report") | .data' events.log | head -1)
+	[ "$(jq '.processes | length' <<<"$report")" -eq 1 ]
+	[ "$(jq '.memory_stat | length' <<<"$report")" -gt 0 ]
+	[ "$(jq -r '.path' <<<"$report")" != "" ]
+}
+
+@test "events fds and --fd-threshold" {
+	requires root
+	init_cgroup_paths
+
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# The file descriptors are only accounted for with --fds.
+	runc events --stats test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq '.data.fds' <<<"${lines[0]}")" = "null" ]
+
+	runc events --stats --fds test_busybox
+	[ "$status" -eq 0 ]
+	fds=$(jq '.data.fds.fds' <<<"${lines[0]}")
+	[ "$fds" -ge 3 ]
+	[ "$(jq '.data.fds.processes' <<<"${lines[0]}")" -eq 1 ]
+
+	(__runc events --interval 500ms --fd-threshold "$((fds + 5))" test_busybox >events.log) &
+	(
+		retry 10 1 grep -q test_busybox events.log
+		# Run a process with 10 open descriptors.
+		__runc exec -d test_busybox sh -c 'exec 3</dev/null 4</dev/null 5</dev/null 6</dev/null 7</dev/null 8</dev/null 9</dev/null; sleep infinity'
+		retry 10 1 grep -q fd-threshold events.log
+		__runc delete -f test_busybox
+	) &
+	wait
+
+	event=$(jq -c 'select(.type == "fd-threshold") | .data' events.log | head -1)
+	[ "$(jq -r '.resource' <<<"$event")" = "fds" ]
+	[ "$(jq '.current' <<<"$event")" -gt "$((fds + 5))" ]
+	# The event is only sent once the threshold is crossed.
+	[ "$(grep -c fd-threshold events.log)" -eq 1 ]
+}
+
+@test "events --output with rotation" {
+	requires root
+
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	(__runc events --interval 100ms --output events.log --output-max-size 16K --output-max-files 2 test_busybox) &
+	retry 20 1 test -e events.log.2
+	__runc delete -f test_busybox
+	wait
+
+	[ ! -e events.log.3 ]
+	for f in events.log events.log.1 events.log.2; do
+		# Every line is a complete event.
+		jq -e '.type == "stats"' "$f"
+		[ "$(stat -c %s "$f")" -le 16384 ]
+	done
+}
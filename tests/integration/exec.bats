@@ -115,6 +115,127 @@ function teardown() {
 	[[ ${output} == *"RUNC_EXEC_TEST=true"* ]]
 }
 
+@test "runc exec --env-file" {
+	# run busybox detached
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	cat >env1 <<-EOF
+		# comment
+		ONE=1
+		TWO=from file
+
+		THREE=3
+	EOF
+	echo "THREE=4" >env2
+
+	runc exec --env-file env1 --env-file env2 --env TWO=2 test_busybox env
+	[ "$status" -eq 0 ]
+
+	[[ ${output} == *"ONE=1"* ]]
+	[[ ${output} == *"TWO=2"* ]]
+	[[ ${output} != *"TWO=from file"* ]]
+	[[ ${output} == *"THREE=4"* ]]
+	[[ ${output} != *"THREE=3"* ]]
+
+	runc exec --env-file nonexistent test_busybox true
+	[ "$status" -ne 0 ]
+}
+
+@test "runc exec --stdio-socket [invalid]" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec --stdio-socket "$CONSOLE_SOCKET" --tty test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cannot use stdio socket"* ]]
+
+	runc exec --stdio-socket "$CONSOLE_SOCKET" --detach test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cannot use stdio socket"* ]]
+
+	runc exec --stdio-socket ./nonexistent.sock test_busybox true
+	[ "$status" -ne 0 ]
+}
+
+@test "runc exec --process with ociVersion" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# Unknown fields are ignored...
+	echo '{"ociVersion": "1.0.0", "cwd": "/", "args": ["echo", "ok"], "unknownField": 1}' >process.json
+	runc exec --process process.json test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"ignoring unknown fields unknownField"* ]]
+	[[ "$output" == *"ok"* ]]
+
+	# ... unless they are from a newer version of the runtime spec.
+	echo '{"ociVersion": "1.999.0", "cwd": "/", "args": ["echo", "ok"], "unknownField": 1}' >process.json
+	runc exec --process process.json test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"fields unknownField, from ociVersion 1.999.0, are not supported"* ]]
+}
+
+@test "runc exec --process -" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec --process - test_busybox <<EOF
+{"cwd": "/", "args": ["sh", "-c", "echo \$SECRET"], "env": ["SECRET=from-stdin"]}
+EOF
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"from-stdin"* ]]
+
+	# The process gets an empty standard input.
+	runc exec --process - test_busybox <<EOF
+{"cwd": "/", "args": ["sh", "-c", "cat; echo done"], "env": ["PATH=/bin:/usr/bin"]}
+EOF
+	[ "$status" -eq 0 ]
+	[ "$output" = "done" ]
+
+	runc exec --process - test_busybox <<EOF
+{"terminal": true, "cwd": "/", "args": ["true"]}
+EOF
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"terminal can not be used"* ]]
+}
+
+@test "runc exec --console-socket-version [invalid]" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec --console-socket-version 2 test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cannot set console socket version without console socket"* ]]
+
+	runc exec -t -d --console-socket "$CONSOLE_SOCKET" --console-socket-version 99 test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unsupported console socket version 99"* ]]
+}
+
+@test "runc exec --env with state variables" {
+	# run busybox detached
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	pid=$(jq -r .pid <<<"$output")
+
+	# shellcheck disable=SC2016
+	runc exec --expand-state-vars --env 'TARGET=${CONTAINER_ID}/${INIT_PID}' --env 'OTHER=${HOME}' test_busybox env
+	[ "$status" -eq 0 ]
+
+	[[ ${output} == *"TARGET=test_busybox/$pid"* ]]
+	[[ ${output} == *'OTHER=${HOME}'* ]]
+
+	# Without --expand-state-vars, nothing is expanded.
+	# shellcheck disable=SC2016
+	runc exec --env 'TARGET=${CONTAINER_ID}' test_busybox env
+	[ "$status" -eq 0 ]
+	[[ ${output} == *'TARGET=${CONTAINER_ID}'* ]]
+}
+
 @test "runc exec --user" {
 	# --user can't work in rootless containers that don't have idmap.
 	[ $EUID -ne 0 ] && requires rootless_idmap
@@ -322,3 +443,76 @@ function check_exec_debug() {
 	runc exec --cgroup second test_busybox grep -w second /proc/self/cgroup
 	[ "$status" -eq 0 ]
 }
+
+@test "runc exec --cgroup-create --cgroup-set --cgroup-remove [v2]" {
+	requires root cgroups_v2
+
+	set_cgroups_path
+	set_cgroup_mount_writable
+
+	__runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	testcontainer test_busybox running
+
+	# Settings require --cgroup.
+	runc exec --cgroup-set pids.max=10 test_busybox true
+	[ "$status" -ne 0 ]
+
+	# The sub-cgroup does not exist.
+	runc exec --cgroup aux --cgroup-set pids.max=10 test_busybox true
+	[ "$status" -ne 0 ]
+
+	# Move init to a sub-cgroup, to enable the controllers for the
+	# sub-cgroups of the container cgroup.
+	runc exec test_busybox sh -euc "mkdir /sys/fs/cgroup/init \
+		&& echo 1 > /sys/fs/cgroup/init/cgroup.procs \
+		&& echo \$\$ > /sys/fs/cgroup/init/cgroup.procs \
+		&& echo +memory +pids > /sys/fs/cgroup/cgroup.subtree_control"
+	[ "$status" -eq 0 ]
+
+	runc exec --cgroup aux --cgroup-create --cgroup-set memory.max=32M --cgroup-set pids.max=10 --cgroup-remove \
+		test_busybox sh -euc 'grep "^0::/aux$" /proc/self/cgroup; cat /sys/fs/cgroup/aux/memory.max /sys/fs/cgroup/aux/pids.max'
+	[ "$status" -eq 0 ]
+	[[ "${lines[1]}" == "33554432" ]]
+	[[ "${lines[2]}" == "10" ]]
+
+	# The sub-cgroup is removed, init is left alone.
+	runc exec --cgroup init test_busybox sh -euc '! test -d /sys/fs/cgroup/aux && grep -w init /proc/1/cgroup'
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	# The container cgroup can not be removed.
+	runc exec --cgroup / --cgroup-remove test_busybox true
+	[ "$status" -ne 0 ]
+}
+
+@test "runc exec --cgroup-of" {
+	requires root
+
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_other
+	[ "$status" -eq 0 ]
+
+	runc exec --cgroup-of test_other test_busybox cat /proc/self/cgroup
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"/test_other"* ]]
+	[[ "$output" != *"/test_busybox"* ]]
+
+	# The process is accounted as one of test_other.
+	runc exec -d --cgroup-of test_other test_busybox sleep 1000
+	[ "$status" -eq 0 ]
+	runc ps test_other
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"sleep 1000"* ]]
+
+	runc exec --cgroup-of nonexistent test_busybox true
+	[ "$status" -ne 0 ]
+	runc exec --cgroup-of test_other --cgroup / test_busybox true
+	[ "$status" -ne 0 ]
+
+	runc delete -f test_other
+	[ "$status" -eq 0 ]
+	runc exec --cgroup-of test_other test_busybox true
+	[ "$status" -ne 0 ]
+}
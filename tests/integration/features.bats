@@ -0,0 +1,97 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function annotation() {
+	jq -r --arg key "$1" '.annotations[$key]' <<<"$output"
+}
+
+@test "runc features" {
+	runc features
+	[ "$status" -eq 0 ]
+
+	[ "$(jq -r '.ociVersionMax' <<<"$output")" != "null" ]
+	jq -e '.mountOptions | index("rro")' <<<"$output"
+	jq -e '.linux.mountExtensions.idmap.enabled' <<<"$output"
+	[ "$(annotation org.opencontainers.runc.checkpoint.enabled)" = "true" ]
+	[[ "$(annotation org.opencontainers.runc.console-socket.versions)" =~ ^[0-9]+(,[0-9]+)*$ ]]
+	[[ "$(annotation org.opencontainers.runc.state.version)" =~ ^[0-9]+$ ]]
+}
+
+@test "runc features [seccomp]" {
+	runc features
+	[ "$status" -eq 0 ]
+
+	if [ "$(jq -r '.linux.seccomp' <<<"$output")" = "null" ]; then
+		# Built without seccomp support.
+		[ "$(annotation io.github.seccomp.libseccomp.version)" = "null" ]
+		return
+	fi
+	[[ "$(annotation io.github.seccomp.libseccomp.version)" =~ ^[0-9]+\.[0-9]+\.[0-9]+$ ]]
+	jq -e '.linux.seccomp.actions | index("SCMP_ACT_ERRNO")' <<<"$output"
+	jq -e '.linux.seccomp.operators | index("SCMP_CMP_EQ")' <<<"$output"
+	jq -e '.linux.seccomp.archs | length > 0' <<<"$output"
+}
+
+@test "runc features [cgroup controllers]" {
+	init_cgroup_paths
+	local expected
+	if [ -v CGROUP_V2 ]; then
+		# Same as runc, assume the "pseudo" controllers are available.
+		expected=$(
+			cat /sys/fs/cgroup/cgroup.controllers
+			echo devices freezer
+		)
+	else
+		expected=$(awk '!/^#/ && $4 != 0 { print $1 }' /proc/cgroups)
+	fi
+	expected=$(tr ' ' '\n' <<<"$expected" | sed '/^$/d' | sort | paste -sd,)
+
+	runc features
+	[ "$status" -eq 0 ]
+	[ "$(annotation org.opencontainers.runc.cgroup.controllers)" = "$expected" ]
+}
+
+@test "runc features [criu version]" {
+	requires criu
+
+	runc features
+	[ "$status" -eq 0 ]
+	# "criu --version" prints the version without a zero sublevel, too.
+	[ "$(annotation org.opencontainers.runc.criu.version)" = "$(criu --version | awk '/^Version:/ { print $2 }')" ]
+}
+
+@test "runc features [no criu]" {
+	# Checkpointing is compiled in, but the CRIU version is only reported
+	# if criu can be run.
+	run env PATH=/nonexistent "$RUNC" features
+	[ "$status" -eq 0 ]
+	[ "$(annotation org.opencontainers.runc.checkpoint.enabled)" = "true" ]
+	[ "$(annotation org.opencontainers.runc.criu.version)" = "null" ]
+}
+
+@test "runc features [mount_setattr]" {
+	runc features
+	[ "$status" -eq 0 ]
+	if is_kernel_gte 5.12; then
+		[ "$(annotation org.opencontainers.runc.mount.setattr)" = "true" ]
+	else
+		[ "$(annotation org.opencontainers.runc.mount.setattr)" = "false" ]
+	fi
+}
+
+@test "runc features [helper annotations]" {
+	runc features
+	[ "$status" -eq 0 ]
+	[[ "$(annotation org.opencontainers.runc.helper.gomaxprocs)" =~ ^[0-9]+$ ]]
+	[[ "$(annotation org.opencontainers.runc.helper.memory-limit)" =~ ^([0-9]+|max)$ ]]
+	[[ "$(annotation org.opencontainers.runc.helper.nice)" =~ ^-?[0-9]+$ ]]
+	[[ "$(annotation org.opencontainers.runc.helper.max-parallel)" =~ ^[0-9]+$ ]]
+
+	RUNC_HELPER_MAX_PARALLEL=3 runc features
+	[ "$status" -eq 0 ]
+	[ "$(annotation org.opencontainers.runc.helper.max-parallel)" = "3" ]
+
+	runc --helper-max-parallel 0 features
+	[ "$status" -ne 0 ]
+}
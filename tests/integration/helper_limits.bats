@@ -0,0 +1,48 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+	update_config '.process.args = ["sleep", "infinity"]'
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc features [helper limits]" {
+	runc --helper-gomaxprocs 1 --helper-memory-limit 32M --helper-nice 10 features
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '.annotations["org.opencontainers.runc.helper.gomaxprocs"]' <<<"$output")" = "1" ]
+	[ "$(jq -r '.annotations["org.opencontainers.runc.helper.memory-limit"]' <<<"$output")" = "33554432" ]
+	[ "$(jq -r '.annotations["org.opencontainers.runc.helper.nice"]' <<<"$output")" = "10" ]
+
+	RUNC_HELPER_NICE=5 runc features
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '.annotations["org.opencontainers.runc.helper.nice"]' <<<"$output")" = "5" ]
+}
+
+@test "runc run --helper-nice" {
+	requires root
+
+	runc --helper-nice 10 run -d --console-socket "$CONSOLE_SOCKET" test_nice
+	[ "$status" -eq 0 ]
+
+	# The container process does not inherit the runc niceness.
+	pid=$(__runc state test_nice | jq .pid)
+	[ "$(awk '{print $19}' "/proc/$pid/stat")" -eq "$(nice)" ]
+}
+
+@test "runc --helper-* [invalid]" {
+	runc --helper-nice 20 list
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid niceness"* ]]
+
+	runc --helper-gomaxprocs 0 list
+	[ "$status" -ne 0 ]
+
+	RUNC_HELPER_MEMORY_LIMIT=foo runc list
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid --helper-memory-limit"* ]]
+}
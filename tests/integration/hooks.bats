@@ -42,3 +42,109 @@ function teardown() {
 		[[ "$output" == *"error running $hook hook #1:"* ]]
 	done
 }
+
+@test "runc run [startContainer hook runs inside the container]" {
+	# Only root can set a different user for the container process.
+	requires root
+
+	update_config '.root.readonly = false
+		| .process.user = {"uid": 1000, "gid": 1000}
+		| .process.args = ["/bin/sleep", "100"]
+		| .hooks |= {"startContainer": [{"path": "/bin/sh", "args": ["/bin/sh", "-c", "id -u > /dev/shm/hook-uid; test -e /.container-rootfs"]}]}'
+	touch rootfs/.container-rootfs
+
+	# By default, the hook is run as the container user.
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_hook
+	[ "$status" -eq 0 ]
+	runc exec test_hook cat /dev/shm/hook-uid
+	[ "$status" -eq 0 ]
+	[ "$output" = "1000" ]
+	runc delete -f test_hook
+	[ "$status" -eq 0 ]
+
+	# With unconfined start hooks, the hook is run as the container's root.
+	update_config '.annotations["org.opencontainers.runc.start-container-hooks"] = "unconfined"'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_hook
+	[ "$status" -eq 0 ]
+	runc exec test_hook cat /dev/shm/hook-uid
+	[ "$status" -eq 0 ]
+	[ "$output" = "0" ]
+}
+
+@test "runc run [unconfined startContainer hook, binary not executable by the user]" {
+	requires root
+
+	update_config '.root.readonly = false
+		| .annotations["org.opencontainers.runc.start-container-hooks"] = "unconfined"
+		| .process.user = {"uid": 1000, "gid": 1000}
+		| .process.args = ["/root-only"]
+		| .hooks |= {"startContainer": [{"path": "/bin/true"}]}'
+	cp rootfs/bin/true rootfs/root-only
+	chmod 0700 rootfs/root-only
+
+	# The binary is looked up again as the container user.
+	runc run test_hook
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"permission denied"* ]]
+}
+
+@test "runc create and start [startContainer hook, host pid namespace]" {
+	requires root
+
+	# runc create is gone by the time the hook is run by runc start, which
+	# must not be mistaken for the death of the parent of runc init.
+	update_config '.linux.namespaces -= [{"type": "pid"}]
+		| .annotations["org.opencontainers.runc.start-container-hooks"] = "unconfined"
+		| .process.args = ["/bin/sleep", "100"]
+		| .hooks |= {"startContainer": [{"path": "/bin/true"}]}'
+
+	runc create --console-socket "$CONSOLE_SOCKET" test_hook
+	[ "$status" -eq 0 ]
+	runc start test_hook
+	[ "$status" -eq 0 ]
+	testcontainer test_hook running
+}
+
+@test "runc run [createRuntime hook result]" {
+	mkdir -p "$ROOT/hook-src" rootfs/hook-dst
+	echo "from hook" >"$ROOT/hook-src/file"
+	cat >"$ROOT/hook.sh" <<EOF
+#!/bin/sh
+cat >/dev/null
+echo '{"hookResultVersion": "1.0.0", "mounts": [{"source": "$ROOT/hook-src", "destination": "/hook-dst", "options": ["rbind", "ro"]}], "env": ["FROM_HOOK=yes"], "annotations": {"hook.example": "done"}}'
+EOF
+	chmod +x "$ROOT/hook.sh"
+	update_config '.process.args = ["/bin/sleep", "100"]
+		| .hooks |= {"createRuntime": [{"path": "'"$ROOT/hook.sh"'"}]}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_hook
+	[ "$status" -eq 0 ]
+
+	runc state test_hook
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq -r '.annotations["hook.example"]')" = "done" ]
+
+	runc exec test_hook sh -c 'tr "\0" "\n" </proc/1/environ'
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"FROM_HOOK=yes"* ]]
+
+	runc exec test_hook cat /hook-dst/file
+	[ "$status" -eq 0 ]
+	[ "$output" = "from hook" ]
+
+	runc exec test_hook touch /hook-dst/new
+	[ "$status" -ne 0 ]
+}
+
+@test "runc create [invalid createRuntime hook result]" {
+	cat >"$ROOT/hook.sh" <<EOF
+#!/bin/sh
+echo '{"hookResultVersion": "1.0.0", "env": ["NOVALUE"]}'
+EOF
+	chmod +x "$ROOT/hook.sh"
+	update_config '.hooks |= {"createRuntime": [{"path": "'"$ROOT/hook.sh"'"}]}'
+
+	runc create --console-socket "$CONSOLE_SOCKET" test_hooks
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid result of createRuntime hook #0"* ]]
+}
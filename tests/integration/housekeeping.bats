@@ -0,0 +1,37 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	requires root cgroups_v2 no_systemd
+	setup_busybox
+	set_cgroups_path
+	HK="/runc-test-housekeeping-$$"
+	update_config '.annotations["org.opencontainers.runc.housekeeping-cgroup"] = "'"$HK"'"'
+}
+
+function teardown() {
+	teardown_bundle
+	[ -v HK ] && rmdir "/sys/fs/cgroup$HK" 2>/dev/null
+	unset HK
+}
+
+@test "runc run with housekeeping cgroup" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_hk
+	[ "$status" -eq 0 ]
+
+	# The container init is in the container cgroup.
+	runc exec test_hk cat /proc/1/cgroup
+	[ "$status" -eq 0 ]
+	[ "$output" = "0::/" ]
+	pid=$(__runc state test_hk | jq .pid)
+	[[ "$(cat "/proc/$pid/cgroup")" == *"$REL_CGROUPS_PATH" ]]
+
+	# The transient housekeeping sub-cgroups are removed.
+	[ -z "$(find "/sys/fs/cgroup$HK" -mindepth 1 -type d)" ]
+
+	runc state test_hk
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq '.setup_cost.wall_time_usec > 0')" = "true" ]
+	[ "$(echo "$output" | jq '.setup_cost.cpu_usec > 0')" = "true" ]
+}
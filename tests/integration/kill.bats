@@ -91,6 +91,35 @@ test_host_pidns_kill() {
 	[ "$status" -eq 0 ]
 }
 
+@test "kill --all-exec and --exec-session" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec -d test_busybox sleep 1000
+	[ "$status" -eq 0 ]
+	runc exec -d test_busybox sleep 2000
+	[ "$status" -eq 0 ]
+
+	runc ps --exec -f json test_busybox
+	[ "$status" -eq 0 ]
+	session=$(jq -r '.[] | select(.comm == "sleep") | .session' <<<"$output" | head -n 1)
+
+	runc kill --exec-session "$session" test_busybox KILL
+	[ "$status" -eq 0 ]
+	retry 10 0.5 eval '[ "$(__runc ps --exec -f json test_busybox | jq length)" -eq 2 ]'
+
+	runc kill --exec-session "$session" test_busybox KILL
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"exec session $session not found"* ]]
+
+	runc kill --all-exec test_busybox KILL
+	[ "$status" -eq 0 ]
+	retry 10 0.5 eval '[ "$(__runc ps --exec -f json test_busybox | jq length)" -eq 1 ]'
+
+	# The init process is left alone.
+	testcontainer test_busybox running
+}
+
 # This is roughly the same as TestPIDHostInitProcessWait in libcontainer/integration.
 # The differences are:
 #
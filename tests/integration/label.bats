@@ -0,0 +1,57 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc label" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_box1
+	[ "$status" -eq 0 ]
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_box2
+	[ "$status" -eq 0 ]
+
+	runc label test_box1 owner=alice tenant=a
+	[ "$status" -eq 0 ]
+	runc label test_box2 owner=bob
+	[ "$status" -eq 0 ]
+
+	runc state test_box1
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .labels.owner <<<"$output")" == "alice" ]]
+	[[ "$(jq -r .labels.tenant <<<"$output")" == "a" ]]
+
+	runc list -q --label owner=alice
+	[ "$status" -eq 0 ]
+	[ "$output" = "test_box1" ]
+
+	# Remove a label.
+	runc label test_box1 tenant-
+	[ "$status" -eq 0 ]
+	runc state test_box1
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .labels.tenant <<<"$output")" == "null" ]]
+
+	runc list -q --label owner=alice --label tenant=a
+	[ "$status" -eq 0 ]
+	[ "$output" = "" ]
+}
+
+@test "runc label [invalid]" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc label test_busybox
+	[ "$status" -ne 0 ]
+	runc label test_busybox =value
+	[ "$status" -ne 0 ]
+	runc label test_busybox key=
+	[ "$status" -ne 0 ]
+	runc label test_busybox key
+	[ "$status" -ne 0 ]
+}
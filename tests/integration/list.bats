@@ -52,3 +52,42 @@ function teardown() {
 	[[ "${lines[0]}" == *[,][\{]"\"ociVersion\""[:]"\""*[0-9][\.]*[0-9][\.]*[0-9]*"\""[,]"\"id\""[:]"\"test_box2\""[,]"\"pid\""[:]*[0-9][,]"\"status\""[:]*"\"running\""[,]"\"bundle\""[:]*$bundle*[,]"\"rootfs\""[:]"\""*"\""[,]"\"created\""[:]*[0-9]*[\}]* ]]
 	[[ "${lines[0]}" == *[,][\{]"\"ociVersion\""[:]"\""*[0-9][\.]*[0-9][\.]*[0-9]*"\""[,]"\"id\""[:]"\"test_box3\""[,]"\"pid\""[:]*[0-9][,]"\"status\""[:]*"\"running\""[,]"\"bundle\""[:]*$bundle*[,]"\"rootfs\""[:]"\""*"\""[,]"\"created\""[:]*[0-9]*[\}][\]] ]]
 }
+
+@test "list --filter --format" {
+	update_config '.annotations += {"org.example.tier": "front"}'
+	ROOT=$ALT_ROOT runc run -d --console-socket "$CONSOLE_SOCKET" test_box1
+	[ "$status" -eq 0 ]
+
+	update_config '.annotations["org.example.tier"] = "back"'
+	ROOT=$ALT_ROOT runc create --console-socket "$CONSOLE_SOCKET" test_box2
+	[ "$status" -eq 0 ]
+
+	ROOT=$ALT_ROOT runc list --filter status=running --format '{{.ID}}'
+	[ "$status" -eq 0 ]
+	[ "$output" = "test_box1" ]
+
+	ROOT=$ALT_ROOT runc list --filter status=running --filter status=created --format '{{.ID}} {{.Status}}'
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = "test_box1 running" ]
+	[ "${lines[1]}" = "test_box2 created" ]
+
+	ROOT=$ALT_ROOT runc list --filter annotation=org.example.tier=back --format '{{.ID}} {{index .Annotations "org.example.tier"}}'
+	[ "$status" -eq 0 ]
+	[ "$output" = "test_box2 back" ]
+
+	ROOT=$ALT_ROOT runc list --filter annotation=org.example.tier --filter id=test_box1 --format '{{json .Annotations}}'
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq -r '.["org.example.tier"]')" = "front" ]
+
+	ROOT=$ALT_ROOT runc list --filter status=paused -q
+	[ "$status" -eq 0 ]
+	[ "$output" = "" ]
+
+	ROOT=$ALT_ROOT runc list --filter foo=bar
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unknown key"* ]]
+
+	ROOT=$ALT_ROOT runc list --format '{{.ID'
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid format template"* ]]
+}
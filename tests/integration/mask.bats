@@ -75,3 +75,47 @@ function teardown() {
 	# so we merely check that it fails, and do not check the exact error
 	# message like for /proc above.
 }
+
+@test "mask paths [runc update --mask-path]" {
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	mkdir rootfs/leakdir
+	echo "Leaked information!" >rootfs/leakdir/file
+	echo "Leaked information!" >rootfs/leakfile
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox cat /leakfile
+	[ "$status" -eq 0 ]
+	[[ "$output" == "Leaked information!" ]]
+
+	runc update --mask-path /leakfile --mask-path /leakdir --mask-path /nonexistent test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox cat /leakfile
+	[ "$status" -eq 0 ]
+	[ -z "$output" ]
+
+	runc exec test_busybox ls /leakdir
+	[ "$status" -eq 0 ]
+	[ -z "$output" ]
+
+	runc exec test_busybox touch /leakdir/foo
+	[ "$status" -eq 1 ]
+	[[ "${output}" == *"Read-only file system"* ]]
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$(jq -c .runtime_mask_paths <<<"$output")" == '["/leakfile","/leakdir","/nonexistent"]' ]]
+
+	# Masking a path again is a no-op.
+	runc update --mask-path /leakfile test_busybox
+	[ "$status" -eq 0 ]
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$(jq -c .runtime_mask_paths <<<"$output")" == '["/leakfile","/leakdir","/nonexistent"]' ]]
+
+	runc update --mask-path relative/path test_busybox
+	[ "$status" -ne 0 ]
+}
@@ -55,6 +55,49 @@ function test_ro_cgroup_mount() {
 	[[ "${lines[0]}" == *'/tmp/bind/config.json'* ]]
 }
 
+@test "runc run [host /sys bind with own netns]" {
+	requires root
+	update_config '	  .mounts |= map(if .destination == "/sys" then {
+					source: "/sys",
+					destination: "/sys",
+					type: "none",
+					options: ["rbind", "nosuid", "noexec", "nodev", "ro"]
+				} else . end)
+			| .process.args |= ["ls", "/sys/class/net"]'
+
+	# A fresh sysfs only shows the container network devices.
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[ "$output" = "lo" ]
+
+	# Unless the bind mount is explicitly kept.
+	update_config '.annotations += {"org.opencontainers.runc.keep-sys-bind": "true"}'
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[ "$output" = "$(ls /sys/class/net)" ]
+}
+
+@test "runc run [bind mount from fd]" {
+	echo "from fd" >fd-source.txt
+	update_config '	  .mounts += [{
+					source: "fd:3",
+					destination: "/tmp/fd-source.txt",
+					options: ["bind", "ro"]
+				}]
+			| .process.args |= ["sh", "-c", "cat /tmp/fd-source.txt; test -e /proc/self/fd/3 || echo closed"]'
+
+	runc run --preserve-fds 1 test_busybox 3<fd-source.txt
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "from fd" ]]
+	# The source fd is not passed to the container process.
+	[[ "${lines[1]}" == "closed" ]]
+
+	# The fd must be passed.
+	runc run test_busybox 3<fd-source.txt
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"mount source fd:3: file descriptor not passed to the container"* ]]
+}
+
 # https://github.com/opencontainers/runc/issues/2246
 @test "runc run [ro tmpfs mount]" {
 	update_config '	  .mounts += [{
@@ -108,3 +151,52 @@ function test_ro_cgroup_mount() {
 	update_config '.linux.namespaces |= if index({"type": "cgroup"}) then . else . + [{"type": "cgroup"}] end'
 	test_ro_cgroup_mount
 }
+
+@test "runc run [rootfsPropagation check]" {
+	update_config '.linux.rootfsPropagation = "rshared"
+		| .annotations["org.opencontainers.runc.propagation-check"] = "strict"
+		| .process.args = ["sh", "-c", "grep -E \"^[0-9]+ [0-9]+ [0-9:]+ [^ ]+ / \" /proc/self/mountinfo"]'
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"shared:"* ]]
+
+	runc --debug run test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"mount propagation tree"* ]]
+}
+
+@test "runc run [mount propagation check]" {
+	requires root
+
+	# A slave of a private mount stays private.
+	mkdir -p private
+	mount --bind private private
+	mount --make-private private
+	update_config '.mounts += [{
+			source: "'"$PWD"'/private",
+			destination: "/mnt",
+			options: ["bind", "slave"]
+		}]
+		| .process.args = ["true"]'
+
+	runc run test_busybox
+	umount private
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"mount /mnt propagation is private, not slave as requested"* ]]
+
+	update_config '.annotations["org.opencontainers.runc.propagation-check"] = "ignore"'
+	mount --bind private private
+	mount --make-private private
+	runc run test_busybox
+	umount private
+	[ "$status" -eq 0 ]
+	[[ "$output" != *"propagation"* ]]
+
+	update_config '.annotations["org.opencontainers.runc.propagation-check"] = "strict"'
+	mount --bind private private
+	mount --make-private private
+	runc run test_busybox
+	umount private
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"propagation check failed: mount /mnt propagation is private, not slave as requested (still private after repair)"* ]]
+}
@@ -0,0 +1,142 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+	update_config '.process.args = ["/bin/sleep", "100"] | .root.readonly = false'
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run [static network]" {
+	update_config '.annotations["org.opencontainers.runc.static-network"] = "{
+		\"addresses\": [{\"interface\": \"lo\", \"address\": \"192.0.2.10/24\"}],
+		\"routes\": [{\"destination\": \"198.51.100.0/24\", \"gateway\": \"192.0.2.1\"}],
+		\"nameservers\": [\"192.0.2.53\"],
+		\"search\": [\"example.com\"],
+		\"resolvConf\": true
+	}"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_net
+	[ "$status" -eq 0 ]
+
+	runc exec test_net ip addr show dev lo
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"inet 192.0.2.10/24"* ]]
+
+	runc exec test_net ip route
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"198.51.100.0/24 via 192.0.2.1"* ]]
+
+	runc exec test_net cat /etc/resolv.conf
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"nameserver 192.0.2.53"* ]]
+	[[ "$output" == *"search example.com"* ]]
+}
+
+@test "runc run [static network requires a new network namespace]" {
+	update_config '.linux.namespaces -= [{"type": "network"}]
+		| .annotations["org.opencontainers.runc.static-network"] = "{\"addresses\": [{\"interface\": \"lo\", \"address\": \"192.0.2.10/24\"}]}"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_net
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"a new network namespace is required"* ]]
+}
+
+@test "runc run [generated etc files]" {
+	update_config '.root.readonly = true
+		| .hostname = "etc-test"
+		| .annotations["org.opencontainers.runc.etc-files"] = "{
+		\"hostname\": true,
+		\"hosts\": true,
+		\"resolvConf\": true,
+		\"extraHosts\": [{\"address\": \"192.0.2.20\", \"names\": [\"db\", \"db.example.com\"]}],
+		\"nameservers\": [\"192.0.2.53\"],
+		\"options\": [\"ndots:2\"]
+	}"'
+	# The root filesystem is not modified.
+	echo "old" >rootfs/etc/hostname
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_net
+	[ "$status" -eq 0 ]
+
+	runc exec test_net cat /etc/hostname
+	[ "$status" -eq 0 ]
+	[ "$output" = "etc-test" ]
+
+	runc exec test_net cat /etc/hosts
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"127.0.1.1"*"etc-test"* ]]
+	[[ "$output" == *"192.0.2.20"*"db db.example.com"* ]]
+
+	runc exec test_net cat /etc/resolv.conf
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"nameserver 192.0.2.53"* ]]
+	[[ "$output" == *"options ndots:2"* ]]
+
+	# The container can update the files, despite the read-only rootfs.
+	runc exec test_net sh -c 'echo "192.0.2.30 cache" >>/etc/hosts'
+	[ "$status" -eq 0 ]
+	grep -q "192.0.2.30 cache" "$ROOT/state/test_net/etc/hosts"
+
+	[ "$(cat rootfs/etc/hostname)" = "old" ]
+}
+
+@test "runc delete [netdev is returned to the host]" {
+	requires root
+
+	ip link add runc-test0 type dummy
+	update_config '.annotations["org.opencontainers.runc.netdevs"] = "runc-test0=eth1"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_net
+	[ "$status" -eq 0 ]
+	! ip link show runc-test0
+
+	runc exec test_net ip link show eth1
+	[ "$status" -eq 0 ]
+
+	# The interface must be returned even though the container init is
+	# killed (a dummy interface would be destroyed with the netns).
+	runc kill test_net KILL
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_net stopped
+
+	runc delete test_net
+	[ "$status" -eq 0 ]
+	ip link show runc-test0
+	ip link del runc-test0
+}
+
+@test "runc run [macvlan interface]" {
+	requires root
+
+	ip link add runc-par0 type dummy
+	ip link set runc-par0 up
+	update_config '.annotations["org.opencontainers.runc.interfaces"] = "[{
+		\"type\": \"macvlan\", \"parent\": \"runc-par0\", \"name\": \"eth0\",
+		\"address\": \"192.0.2.10/24\", \"gateway\": \"192.0.2.1\"
+	}]"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_net
+	[ "$status" -eq 0 ]
+
+	runc exec test_net ip addr show dev eth0
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"inet 192.0.2.10/24"* ]]
+
+	runc exec test_net ip route
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"default via 192.0.2.1"* ]]
+
+	# The same address on the same parent is a conflict.
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_net2
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"is used by container test_net"* ]]
+
+	runc delete -f test_net
+	[ "$status" -eq 0 ]
+	ip link del runc-par0
+}
@@ -0,0 +1,47 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+	update_config '.process.args = ["sleep", "infinity"]'
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run [oom_score_adj]" {
+	update_config '.process.oomScoreAdj = 200'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_oom
+	[ "$status" -eq 0 ]
+
+	runc exec test_oom cat /proc/self/oom_score_adj
+	[ "$status" -eq 0 ]
+	[ "$output" = "200" ]
+
+	runc state test_oom
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq .oom_score_adj)" = "200" ]
+}
+
+@test "runc run --helper-oom-score-adj" {
+	requires root
+
+	update_config '.process.oomScoreAdj = -200'
+
+	runc --helper-oom-score-adj 100 run -d --console-socket "$CONSOLE_SOCKET" test_oom
+	[ "$status" -eq 0 ]
+
+	runc state test_oom
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq .oom_score_adj)" = "-200" ]
+	[ "$(echo "$output" | jq .helper_oom_score_adj)" = "100" ]
+}
+
+@test "runc --helper-oom-score-adj [invalid]" {
+	RUNC_HELPER_OOM_SCORE_ADJ=1001 runc list
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid oom_score_adj"* ]]
+}
@@ -75,3 +75,66 @@ function teardown() {
 	runc state test_busybox
 	[ "$status" -ne 0 ]
 }
+
+@test "runc pause --all and resume --all" {
+	requires cgroups_freezer
+	if [ $EUID -ne 0 ]; then
+		requires rootless_cgroup
+	fi
+
+	for ct in ct1 ct2; do
+		[ $EUID -ne 0 ] && set_cgroups_path
+		runc run -d --console-socket "$CONSOLE_SOCKET" "$ct"
+		[ "$status" -eq 0 ]
+	done
+	# A container which is not running is skipped.
+	[ $EUID -ne 0 ] && set_cgroups_path
+	runc create --console-socket "$CONSOLE_SOCKET" ct3
+	[ "$status" -eq 0 ]
+
+	runc pause --all
+	[ "$status" -eq 0 ]
+	testcontainer ct1 paused
+	testcontainer ct2 paused
+	testcontainer ct3 created
+
+	# Container IDs can not be given with --all.
+	runc resume --all ct1
+	[ "$status" -ne 0 ]
+
+	runc resume --all
+	[ "$status" -eq 0 ]
+	testcontainer ct1 running
+	testcontainer ct2 running
+}
+
+@test "runc state [cgroup fields]" {
+	requires cgroups_freezer cgroups_pids
+	if [ $EUID -ne 0 ]; then
+		requires rootless_cgroup
+	fi
+	set_cgroups_path
+	update_config '.linux.resources.pids.limit = 42'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .frozen <<<"$output")" = "false" ]
+	[ "$(jq -r .resources.pids_limit <<<"$output")" = "42" ]
+	[ "$(jq -r '.cgroup_paths | length' <<<"$output")" -gt 0 ]
+	driver=cgroupfs
+	[ -v RUNC_USE_SYSTEMD ] && driver=systemd
+	[ "$(jq -r .cgroup_driver <<<"$output")" = "$driver" ]
+	[ "$(jq -r .rootless <<<"$output")" = "$([ $EUID -ne 0 ] && echo true || echo false)" ]
+
+	runc pause test_busybox
+	[ "$status" -eq 0 ]
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .frozen <<<"$output")" = "true" ]
+
+	runc resume test_busybox
+	[ "$status" -eq 0 ]
+}
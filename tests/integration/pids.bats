@@ -0,0 +1,61 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	requires root
+	setup_busybox
+	set_cgroups_path
+	update_config '.linux.resources.pids.limit = 1000
+		| .annotations["org.opencontainers.runc.pids-start-limit"] = "30"
+		| .annotations["org.opencontainers.runc.pids-start-settle"] = "500ms"'
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc create with pids start limit" {
+	runc create --console-socket "$CONSOLE_SOCKET" test_pids
+	[ "$status" -eq 0 ]
+	check_cgroup_value "pids.max" 30
+
+	runc start test_pids
+	[ "$status" -eq 0 ]
+	check_cgroup_value "pids.max" 1000
+}
+
+@test "runc run with pids start limit and no pids limit" {
+	update_config 'del(.linux.resources.pids)'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_pids
+	[ "$status" -eq 0 ]
+	check_cgroup_value "pids.max" max
+}
+
+@test "runc run with pids start limit hit" {
+	# Fork more processes than allowed by the start limit.
+	update_config '.process.args = ["sh", "-c", "for i in $(seq 100); do sleep 10 & done; wait"]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_pids
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"pids start limit reached"* ]]
+
+	# The container is removed.
+	runc state test_pids
+	[ "$status" -ne 0 ]
+}
+
+@test "runc events reports pids limit hits" {
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_pids
+	[ "$status" -eq 0 ]
+
+	runc update --pids-limit 20 test_pids
+	[ "$status" -eq 0 ]
+	runc exec test_pids sh -c 'for i in $(seq 40); do sleep 10 & done; wait'
+
+	runc events --stats test_pids
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq '.data.pids.max_events > 0')" = "true" ]
+}
@@ -51,3 +51,127 @@ function teardown() {
 	runc ps test_busybox
 	[ "$status" -eq 0 ]
 }
+
+@test "ps --tree" {
+	runc exec -d test_busybox sh -c 'sleep 1000 & sleep 1000; true'
+	[ "$status" -eq 0 ]
+	sleep 0.5
+
+	runc ps --tree test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" =~ PID\ +PPID\ +CGROUP\ +COMMAND ]]
+	[[ "$output" == *'\_ sleep'* ]]
+
+	runc ps --tree -f json test_busybox
+	[ "$status" -eq 0 ]
+	# The init and the exec-ed shell are the roots.
+	[ "$(jq length <<<"$output")" -eq 2 ]
+	[ "$(jq '.[1].children | length' <<<"$output")" -eq 2 ]
+	[ "$(jq '.[0].namespaces.pid == .[1].namespaces.pid' <<<"$output")" = "true" ]
+
+	runc ps --tree test_busybox -ef
+	[ "$status" -ne 0 ]
+}
+
+@test "ps --exec" {
+	runc exec -d test_busybox sh -c 'sleep 1000 & sleep 1000; true'
+	[ "$status" -eq 0 ]
+	sleep 0.5
+
+	runc ps --exec test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" =~ SESSION\ +STARTED\ +PID\ +PPID\ +COMMAND ]]
+	[[ "${lines[1]}" == "init "* ]]
+
+	runc ps --exec -f json test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq length <<<"$output")" -eq 4 ]
+	[ "$(jq -r '.[0].session' <<<"$output")" = "init" ]
+	# The exec-ed shell and its children are in the same session.
+	session=$(jq -r '.[1].session' <<<"$output")
+	[ "$session" != "init" ]
+	[ "$session" != "" ]
+	[ "$(jq --arg s "$session" '[.[] | select(.session == $s)] | length' <<<"$output")" -eq 3 ]
+	[ "$(jq -r '.[1].started' <<<"$output")" != "null" ]
+
+	runc ps --exec test_busybox -ef
+	[ "$status" -ne 0 ]
+}
+
+@test "ps --columns" {
+	runc ps --columns pid,ppid,comm,args,cgroup test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" =~ PID\ +PPID\ +COMMAND\ +ARGS\ +CGROUP ]]
+	[[ "${lines[1]}" == *"sh"* ]]
+
+	runc ps -f json --columns pid,comm,args,uid test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq length <<<"$output")" -eq 1 ]
+	[ "$(jq -r '.[0].comm' <<<"$output")" = "sh" ]
+	[ "$(jq -r '.[0].args[0]' <<<"$output")" = "sh" ]
+	[ "$(jq -r '.[0].uid' <<<"$output")" -eq "$EUID" ]
+	[ "$(jq '.[0] | keys | length' <<<"$output")" -eq 4 ]
+
+	runc ps --columns pid,bogus test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unknown column"* ]]
+
+	runc ps --columns pid test_busybox -ef
+	[ "$status" -ne 0 ]
+}
+
+@test "ps with a hung ps binary [--tool-timeout]" {
+	bin="$(mktemp -d "$BATS_RUN_TMPDIR/bin.XXXXXX")"
+	printf '#!/bin/sh\nsleep 100\n' >"$bin/ps"
+	chmod +x "$bin/ps"
+
+	PATH="$bin:$PATH" runc --tool-timeout ps=500ms ps test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"ps did not finish in 500ms"* ]]
+
+	runc --tool-timeout ps=5 ps test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid timeout"* ]]
+}
+
+@test "top -n 1" {
+	runc top --interval 100ms -n 1 test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == *"CPU: "*"MEM: "*"PIDS: "* ]]
+	[[ "$output" =~ PID\ +NSPID\ +PPID\ +S\ +%CPU\ +RSS\ +COMMAND ]]
+	[[ "$output" == *" sh"* ]]
+	# Not a terminal: the screen is not cleared.
+	[[ "$output" != *$'\033'* ]]
+}
+
+@test "top --once" {
+	runc top --interval 100ms --once -f json test_busybox
+	[ "$status" -eq 0 ]
+	[ "${#lines[@]}" -eq 1 ]
+	# The container init is PID 1 in the container PID namespace.
+	[[ "$(jq -r '.processes[] | select(.comm == "sh") | .ns_pid' <<<"${lines[0]}")" == "1" ]]
+	# With PSI enabled (see "requires psi").
+	if [ -v CGROUP_V2 ] && cat /sys/fs/cgroup/cpu.pressure &>/dev/null; then
+		[[ "$(jq -r '.psi.memory | has("some")' <<<"${lines[0]}")" == "true" ]]
+	fi
+
+	runc top --once -n 2 test_busybox
+	[ "$status" -ne 0 ]
+}
+
+@test "top -f json" {
+	runc top --interval 100ms -n 2 -f json test_busybox
+	[ "$status" -eq 0 ]
+	[ "${#lines[@]}" -eq 2 ]
+	[[ "$(jq -r '.processes[0].comm' <<<"${lines[1]}")" == "sh" ]]
+}
+
+@test "top exits when the container stops" {
+	runc kill test_busybox KILL
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_busybox stopped
+
+	runc top --interval 100ms test_busybox
+	[ "$status" -eq 0 ]
+	[ "$output" = "" ]
+}
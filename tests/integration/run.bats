@@ -34,6 +34,37 @@ function teardown() {
 	[ "$status" -ne 0 ]
 }
 
+@test "runc run --timeout" {
+	update_config '.process.args = ["sleep", "100"]'
+
+	runc run --keep --timeout 1s --timeout-grace 1s test_run_timeout
+	[ "$status" -eq 124 ]
+
+	testcontainer test_run_timeout stopped
+
+	runc state test_run_timeout
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r '.exit_reason' <<<"$output")" == "timeout" ]]
+
+	runc delete test_run_timeout
+}
+
+@test "runc run --timeout [not expired]" {
+	runc run --keep --timeout 1m test_run_timeout
+	[ "$status" -eq 0 ]
+
+	runc state test_run_timeout
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r '.exit_reason' <<<"$output")" == "null" ]]
+
+	runc delete test_run_timeout
+}
+
+@test "runc run --timeout --detach" {
+	runc run --detach --timeout 1s test_run_timeout
+	[ "$status" -ne 0 ]
+}
+
 @test "runc run --keep (check cgroup exists)" {
 	# for systemd driver, the unit's cgroup path will be auto removed if container's all processes exited
 	requires no_systemd
@@ -230,3 +261,69 @@ function teardown() {
 	grep -E '^monotonic\s+7881\s+2718281$' <<<"$output"
 	grep -E '^boottime\s+1337\s+3141519$' <<<"$output"
 }
+
+@test "runc run --config-override --config-patch" {
+	cat >"$ROOT/patch.json" <<-EOF
+		[{"op": "replace", "path": "/process/args", "value": ["/bin/echo", "patched"]}]
+	EOF
+	cp config.json config.json.orig
+
+	runc run --config-patch "$ROOT/patch.json" test_override
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"patched"* ]]
+
+	# The overrides are applied after the patches.
+	runc run --config-patch "$ROOT/patch.json" --config-override process.args.1=overridden \
+		--config-override hostname=override-host test_override
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"overridden"* ]]
+
+	runc run --config-override 'process.args=["hostname"]' --config-override hostname=override-host test_override
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"override-host"* ]]
+
+	# config.json is left as is.
+	cmp config.json config.json.orig
+
+	runc run --config-override process.args test_override
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"must be path=value"* ]]
+}
+
+@test "runc run --exit-status-file" {
+	update_config '.process.args = ["sh", "-c", "exit 3"]'
+	runc run --exit-status-file ./status.json test_exit
+	[ "$status" -eq 3 ]
+	[ "$(jq -c . ./status.json)" = '{"exit_code":3,"oom_killed":false}' ]
+}
+
+@test "runc run --detach --exit-status-file" {
+	update_config '.process.args = ["sleep", "1000"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" --exit-status-file ./status.json test_exit
+	[ "$status" -eq 0 ]
+	testcontainer test_exit running
+	[ ! -e ./status.json ]
+
+	runc kill test_exit KILL
+	[ "$status" -eq 0 ]
+	retry 10 1 [ -e ./status.json ]
+	[ "$(jq .exit_code ./status.json)" -eq 137 ]
+
+	runc delete test_exit
+	[ "$status" -eq 0 ]
+}
+
+@test "runc run --detach --control-socket" {
+	update_config '.process.args = ["sleep", "1000"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" --control-socket test_ctl
+	[ "$status" -eq 0 ]
+	testcontainer test_ctl running
+	[ -S "$ROOT/state/test_ctl/control.sock" ]
+
+	runc kill test_ctl KILL
+	[ "$status" -eq 0 ]
+	retry 10 1 [ ! -e "$ROOT/state/test_ctl/control.sock" ]
+
+	runc delete test_ctl
+	[ "$status" -eq 0 ]
+}
@@ -0,0 +1,68 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+	echo -n "s3cr3t" >secret.txt
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc run --secret type=memfd" {
+	update_config '.process.args = ["sh", "-c", "echo $DB_PASSWORD; cat $DB_PASSWORD; echo"]'
+
+	runc run --secret name=db,src=secret.txt,type=memfd,env=DB_PASSWORD test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "/proc/self/fd/"* ]]
+	[[ "${lines[1]}" == "s3cr3t" ]]
+}
+
+@test "runc run --secret type=memfd is sealed" {
+	update_config '.process.args = ["sh", "-c", "echo x >>$token || echo sealed"]'
+
+	runc run --secret name=token,src=secret.txt,type=memfd test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"sealed"* ]]
+}
+
+@test "runc run --secret from fd" {
+	update_config '.process.args = ["sh", "-c", "cat $token"]'
+
+	runc run --secret name=token,src=fd:5,type=memfd test_busybox 5< <(echo from-pipe)
+	[ "$status" -eq 0 ]
+	[[ "$output" == "from-pipe" ]]
+}
+
+@test "runc run --secret type=keyring" {
+	update_config '.process.args = ["grep", "-w", "db", "/proc/keys"]'
+
+	runc run --secret name=db,src=secret.txt test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *" user "*"db: 6"* ]]
+
+	runc run --no-new-keyring --secret name=db,src=secret.txt test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"keyring secrets require a new session keyring"* ]]
+}
+
+@test "runc exec --secret" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec --secret name=token,src=secret.txt,type=memfd test_busybox sh -c 'cat $token'
+	[ "$status" -eq 0 ]
+	[[ "$output" == "s3cr3t" ]]
+}
+
+@test "runc run --secret [invalid]" {
+	runc run --secret name=db test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"name and src are required"* ]]
+
+	runc run --secret name=db,src=secret.txt,type=tmpfs test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'unknown type "tmpfs"'* ]]
+}
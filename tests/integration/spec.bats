@@ -39,3 +39,41 @@ function teardown() {
 
 	./validate "$SCHEMA" config.json
 }
+
+@test "spec generation with options" {
+	rm config.json
+	local rootless=""
+	[ $EUID -ne 0 ] && rootless="--rootless"
+
+	mkdir -p "$ROOT/data"
+	echo "from the host" >"$ROOT/data/file"
+
+	runc spec $rootless \
+		--args '["sh", "-c", "echo $FOO; hostname; cat /data/file; pwd"]' \
+		--cwd /tmp \
+		--env FOO=bar \
+		--hostname spec-test \
+		--mount src="$ROOT/data",dst=/data,opt=ro \
+		--cap-drop ALL \
+		--ns -ipc
+	[ "$status" -eq 0 ]
+
+	[[ "$(jq -c .process.capabilities.bounding config.json)" == "[]" ]]
+	[[ "$(jq -r '.linux.namespaces[] | select(.type == "ipc") | .type' config.json)" == "" ]]
+	if [ -n "$rootless" ]; then
+		[[ "$(jq -r '.linux.namespaces[] | select(.type == "user") | .type' config.json)" == "user" ]]
+	fi
+
+	runc run test_hello
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" == "bar"* ]]
+	[[ "${lines[1]}" == "spec-test"* ]]
+	[[ "${lines[2]}" == "from the host"* ]]
+	[[ "${lines[3]}" == "/tmp"* ]]
+
+	# Invalid options are rejected, and no spec is written.
+	rm config.json
+	runc spec --ns no-such-ns
+	[ "$status" -ne 0 ]
+	[ ! -e config.json ]
+}
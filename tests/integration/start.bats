@@ -28,3 +28,32 @@ function teardown() {
 	runc state test_busybox
 	[ "$status" -ne 0 ]
 }
+
+@test "runc start with a changed config.json" {
+	runc create --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	update_config '.process.args = ["false"]'
+	run -0 __runc state test_busybox
+	[ "$(jq -r .config_hash <<<"$output")" != "$(jq -r .bundle_config_hash <<<"$output")" ]
+
+	# The default is to warn.
+	runc start test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"config.json was changed"* ]]
+}
+
+@test "runc start with a changed config.json (strict)" {
+	update_config '.annotations["org.opencontainers.runc.config-drift-check"] = "strict"'
+	runc create --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	run -0 __runc state test_busybox
+	[ "$(jq -r .config_hash <<<"$output")" = "$(jq -r .bundle_config_hash <<<"$output")" ]
+
+	update_config '.process.args = ["false"]'
+	runc start test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"config.json was changed"* ]]
+	testcontainer test_busybox created
+}
@@ -61,3 +61,67 @@ function teardown() {
 	# test state of busybox is back to running
 	testcontainer test_busybox running
 }
+
+@test "state --locks" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# Nobody holds the lock once runc run has returned.
+	runc state --locks test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .lock <<<"$output")" == "null" ]]
+	[[ "$(jq -r .status <<<"$output")" == "running" ]]
+
+	runc delete --force test_busybox
+	[ "$status" -eq 0 ]
+
+	# The lock file is removed together with the container.
+	[ ! -e "$ROOT/state/.locks/test_busybox" ]
+}
+
+@test "state --security" {
+	update_config '   .process.noNewPrivileges = true
+			| .process.capabilities.bounding = ["CAP_CHOWN", "CAP_KILL"]
+			| .linux.seccomp = {
+				"defaultAction":"SCMP_ACT_ALLOW",
+				"architectures":["SCMP_ARCH_X86","SCMP_ARCH_X32","SCMP_ARCH_X86_64","SCMP_ARCH_AARCH64","SCMP_ARCH_ARM"],
+				"syscalls":[{"names":["mkdir"], "action":"SCMP_ACT_ERRNO"}]
+			}'
+
+	runc create --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# The protections are not all applied before runc start.
+	runc state --security test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .security.not_yet_applied <<<"$output")" == "true" ]]
+	[[ "$(jq -r .security.drift <<<"$output")" == "null" ]]
+
+	runc start test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state --security test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .security.not_yet_applied <<<"$output")" == "null" ]]
+	[[ "$(jq -r .security.no_new_privileges <<<"$output")" == "true" ]]
+	[[ "$(jq -r .security.seccomp <<<"$output")" == "filter" ]]
+	[[ "$(jq -c .security.capabilities.bounding <<<"$output")" == '["CAP_CHOWN","CAP_KILL"]' ]]
+	[[ "$(jq -r .security.drift <<<"$output")" == "null" ]]
+
+	# Without --security, there is no security state.
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r .security <<<"$output")" == "null" ]]
+}
+
+@test "state of a non-existent container (error codes)" {
+	runc --log-format json state nonexistent
+	[ "$status" -eq 1 ]
+	[[ "$output" == *'"code":"container-not-found"'* ]]
+
+	runc --error-exit-codes state nonexistent
+	[ "$status" -eq 243 ]
+
+	RUNC_ERROR_EXIT_CODES=1 runc state
+	[ "$status" -eq 241 ]
+}
@@ -0,0 +1,78 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+	update_config '.process.args = ["/bin/sleep", "1d"]'
+}
+
+function teardown() {
+	local tenant ct
+	for tenant in tenant_a tenant_b; do
+		for ct in $(RUNC_TENANT=$tenant __runc list -q); do
+			RUNC_TENANT=$tenant __runc delete -f "$ct"
+		done
+	done
+	teardown_bundle
+}
+
+@test "runc --tenant allows the same ID in different tenants" {
+	runc --tenant tenant_a run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc --tenant tenant_b run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	[ -d "$ROOT/state/tenant_a/test_busybox" ]
+	[ -d "$ROOT/state/tenant_b/test_busybox" ]
+
+	RUNC_TENANT=tenant_a runc state test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"running"* ]]
+
+	# The tenant directories are not listed as containers.
+	runc list -q
+	[ "$status" -eq 0 ]
+	[ "$output" = "" ]
+
+	runc --tenant tenant_a list -q
+	[ "$status" -eq 0 ]
+	[ "$output" = "test_busybox" ]
+
+	runc --tenant tenant_a delete -f test_busybox
+	[ "$status" -eq 0 ]
+
+	runc --tenant tenant_b state test_busybox
+	[ "$status" -eq 0 ]
+}
+
+@test "runc --tenant with invalid name" {
+	runc --tenant ../tenant list
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid tenant name"* ]]
+}
+
+@test "runc --root-mode" {
+	runc --root-mode 1777 list
+	[ "$status" -eq 0 ]
+	[ "$(stat -c %a "$ROOT/state")" = "1777" ]
+
+	runc --root-mode 711 list
+	[ "$status" -eq 0 ]
+	[ "$(stat -c %a "$ROOT/state")" = "711" ]
+
+	runc --root-mode 7777 list
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid --root-mode"* ]]
+}
+
+@test "runc run with invalid container ID" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" "$(printf 'x%.0s' {1..256})"
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"longer than 255"* ]]
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" "a:b"
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"is not allowed"* ]]
+}
@@ -255,6 +255,21 @@ EOF
 			check_cgroup_value "$MEM_SWAP" $((80 * 1024 * 1024))
 			check_systemd_value "$SD_MEM_SWAP" $((80 * 1024 * 1024))
 		fi
+
+		# Set the swap limit on its own.
+		runc update test_update --memory 60M --swap-max 10M
+		[ "$status" -eq 0 ]
+
+		if [ -v CGROUP_V2 ]; then
+			check_cgroup_value "$MEM_SWAP" $((10 * 1024 * 1024))
+			check_systemd_value "$SD_MEM_SWAP" $((10 * 1024 * 1024))
+		else
+			check_cgroup_value "$MEM_SWAP" $((70 * 1024 * 1024))
+			check_systemd_value "$SD_MEM_SWAP" $((70 * 1024 * 1024))
+		fi
+
+		runc update test_update --swap-max 10M --memory-swap 80M
+		[ "$status" -ne 0 ]
 	fi
 }
 
@@ -269,10 +284,10 @@ EOF
 	check_cpu_quota 500000 1000000 "500ms"
 	check_cpu_shares 100
 
-	# update cpu period
+	# update cpu period (the quota is rescaled to keep the limit)
 	runc update test_update --cpu-period 900000
 	[ "$status" -eq 0 ]
-	check_cpu_quota 500000 900000 "560ms"
+	check_cpu_quota 450000 900000 "500ms"
 
 	# update cpu quota
 	runc update test_update --cpu-quota 600000
@@ -340,11 +355,15 @@ EOF
 	[ "$status" -eq 0 ]
 	check_cpu_burst 0
 
-	runc update test_update --cpu-period 900000 --cpu-burst 500000
+	runc update test_update --cpu-period 1000000 --cpu-burst 500000
 	[ "$status" -eq 0 ]
 	check_cpu_burst 500000
 
-	runc update test_update --cpu-period 900000 --cpu-burst 0
+	# The burst can not be greater than the quota.
+	runc update test_update --cpu-burst 600000
+	[ "$status" -ne 0 ]
+
+	runc update test_update --cpu-period 1000000 --cpu-burst 0
 	[ "$status" -eq 0 ]
 	check_cpu_burst 0
 }
@@ -393,6 +412,42 @@ EOF
 	check_cpu_quota -1 50000 "infinity"
 }
 
+@test "update cpu period rescales the quota" {
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+	check_cpu_quota 500000 1000000 "500ms"
+
+	runc update --cpu-period 100000 test_update
+	[ "$status" -eq 0 ]
+	check_cpu_quota 50000 100000 "500ms"
+
+	# A short period is allowed, with a warning.
+	runc update --cpu-period 5000 test_update
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"cpu period 5000us is short"* ]]
+	check_cpu_quota 2500 5000 "500ms"
+
+	# The limit can not be kept with such a period.
+	runc update --cpu-period 1000 test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"set --cpu-quota as well"* ]]
+	check_cpu_quota 2500 5000 "500ms"
+
+	# Out of the kernel bounds.
+	runc update --cpu-period 2000000 --cpu-quota 1000000 test_update
+	[ "$status" -ne 0 ]
+	runc update --cpu-period 100000 --cpu-quota 500 test_update
+	[ "$status" -ne 0 ]
+	check_cpu_quota 2500 5000 "500ms"
+
+	# No rescaling if both are set.
+	runc update --cpu-period 100000 --cpu-quota 20000 test_update
+	[ "$status" -eq 0 ]
+	check_cpu_quota 20000 100000 "200ms"
+}
+
 @test "update cpu quota with no previous period/quota set" {
 	[ $EUID -ne 0 ] && requires rootless_cgroup
 
@@ -793,12 +848,12 @@ EOF
 	cat "$CONTAINER_OUTPUT"
 	[ "$status" -eq 0 ]
 
-	# Trigger an update. This update doesn't actually change the device rules,
-	# but it will trigger the devices cgroup code to reapply the current rules.
-	# We trigger the update a few times to make sure we hit the race.
+	# Trigger an update. This update doesn't actually change the device access
+	# (it denies an already denied device), but it will trigger the devices
+	# cgroup code to reapply the current rules. We trigger the update a few
+	# times to make sure we hit the race.
 	for _ in {1..30}; do
-		# TODO: Update "runc update" so we can change the device rules.
-		runc update --pids-limit 30 test_update
+		runc update --pids-limit 30 --device-deny 'c 1:11 rwm' test_update
 		[ "$status" -eq 0 ]
 	done
 
@@ -810,6 +865,46 @@ EOF
 	[ -z "$(<"$CONTAINER_OUTPUT")" ]
 }
 
+@test "update --device-allow --device-deny" {
+	requires root
+
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc exec test_update head -c 1 /dev/zero
+	[ "$status" -eq 0 ]
+
+	runc update --device-deny 'c 1:5 rwm' test_update
+	[ "$status" -eq 0 ]
+	runc exec test_update head -c 1 /dev/zero
+	[ "$status" -ne 0 ]
+	# Other devices are still allowed.
+	runc exec test_update head -c 1 /dev/urandom
+	[ "$status" -eq 0 ]
+
+	runc update --device-allow 'c 1:5 rwm' test_update
+	[ "$status" -eq 0 ]
+	runc exec test_update head -c 1 /dev/zero
+	[ "$status" -eq 0 ]
+
+	# The same, with the resources JSON.
+	runc update -r - test_update <<<'{"devices": [{"allow": false, "type": "c", "major": 1, "minor": 5, "access": "rwm"}]}'
+	[ "$status" -eq 0 ]
+	runc exec test_update head -c 1 /dev/zero
+	[ "$status" -ne 0 ]
+
+	# The rules are kept in the container state.
+	runc update --pids-limit 100 test_update
+	[ "$status" -eq 0 ]
+	runc exec test_update head -c 1 /dev/zero
+	[ "$status" -ne 0 ]
+
+	runc update --device-allow 'c 1:5' test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid value for device-allow"* ]]
+}
+
 @test "update paused container" {
 	requires cgroups_freezer
 	[ $EUID -ne 0 ] && requires rootless_cgroup
@@ -873,3 +968,102 @@ EOF
 	runc update test_update --memory 1024
 	wait_for_container 10 1 test_update stopped
 }
+
+@test "update --shm-size --tmp-size --tmp-inodes" {
+	requires root
+	requires_kernel 5.2
+
+	update_config '.annotations += {
+		"org.opencontainers.runc.shm-size": "8m",
+		"org.opencontainers.runc.tmp-size": "50%",
+		"org.opencontainers.runc.tmp-inodes": "1000"
+	}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	# 50% of the 32M memory limit.
+	runc exec test_update df -k /dev/shm /tmp
+	[ "$status" -eq 0 ]
+	[[ "$(awk '$6 == "/dev/shm" {print $2}' <<<"$output")" == 8192 ]]
+	[[ "$(awk '$6 == "/tmp" {print $2}' <<<"$output")" == 16384 ]]
+
+	runc update --shm-size 16m --tmp-size 25% --tmp-inodes 2000 test_update
+	[ "$status" -eq 0 ]
+
+	runc exec test_update df -k /dev/shm /tmp
+	[ "$status" -eq 0 ]
+	[[ "$(awk '$6 == "/dev/shm" {print $2}' <<<"$output")" == 16384 ]]
+	[[ "$(awk '$6 == "/tmp" {print $2}' <<<"$output")" == 8192 ]]
+	runc exec test_update grep -E '^\S+ /tmp tmpfs .*nr_inodes=2000' /proc/mounts
+	[ "$status" -eq 0 ]
+
+	# The tmpfs can not be shrunk below its usage.
+	runc exec test_update dd if=/dev/zero of=/dev/shm/file bs=1M count=4
+	[ "$status" -eq 0 ]
+	runc update --shm-size 1m test_update
+	[ "$status" -ne 0 ]
+	runc exec test_update df -k /dev/shm
+	[ "$status" -eq 0 ]
+	[[ "$(awk '$6 == "/dev/shm" {print $2}' <<<"$output")" == 16384 ]]
+}
+
+@test "update --rlimit" {
+	requires root
+
+	update_config '.process.rlimits = [{"type": "RLIMIT_NOFILE", "soft": 1024, "hard": 1024}]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	# A process started before the update.
+	runc exec -d test_update sleep 1h
+	[ "$status" -eq 0 ]
+
+	runc update --rlimit nofile=4096:8192 --rlimit-all test_update
+	[ "$status" -eq 0 ]
+
+	# The init process, and the other processes with --rlimit-all.
+	run -0 grep -h "Max open files" /proc/"$(__runc state test_update | jq .pid)"/limits
+	[[ "$output" == *" 4096 "*" 8192 "* ]]
+	pid=$(pgrep -f "^sleep 1h$")
+	run -0 grep -h "Max open files" /proc/"$pid"/limits
+	[[ "$output" == *" 4096 "*" 8192 "* ]]
+
+	# The new processes get the updated limits.
+	runc exec test_update sh -c 'ulimit -n; ulimit -Hn'
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = "4096" ]
+	[ "${lines[1]}" = "8192" ]
+
+	runc update --rlimit nofile=8192:4096 test_update
+	[ "$status" -ne 0 ]
+}
+
+@test "update --systemd-persistent" {
+	requires root systemd
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc update --pids-limit 30 --systemd-persistent test_update
+	[ "$status" -eq 0 ]
+	check_systemd_value "TasksMax" 30
+	run -0 grep -rl "TasksMax=30" "/etc/systemd/system.control/$SD_UNIT_NAME.d"
+
+	# The drop-in is removed with the container, not to apply to a later
+	# unit with the same name.
+	runc delete -f test_update
+	[ "$status" -eq 0 ]
+	[ ! -e "/etc/systemd/system.control/$SD_UNIT_NAME.d" ]
+}
+
+@test "update --systemd-persistent [no systemd]" {
+	requires no_systemd
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc update --pids-limit 30 --systemd-persistent test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"requires the systemd cgroup driver"* ]]
+}
@@ -164,3 +164,70 @@ function teardown() {
 		grep -E '^\s+0\s+'$EUID'\s+1$' <<<"$output"
 	fi
 }
+
+@test "userns join other container userns [--userns-fd]" {
+	requires root
+
+	# Create a detached container with the id-mapping we want.
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" target_userns
+	[ "$status" -eq 0 ]
+	target_pid="$(__runc state target_userns | jq .pid)"
+
+	# Mappings not provided by the namespace are rejected.
+	update_config '.linux.uidMappings = [{"hostID": 300000, "containerID": 0, "size": 1000}]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" --userns-fd 5 in_userns 5<"/proc/$target_pid/ns/user"
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"not provided by the user namespace"* ]]
+
+	# A subset of the namespace mappings is fine.
+	update_config '.linux.uidMappings = [{"hostID": 100000, "containerID": 0, "size": 1000}]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" --userns-fd 5 in_userns 5<"/proc/$target_pid/ns/user"
+	[ "$status" -eq 0 ]
+
+	runc exec in_userns cat /proc/self/uid_map
+	[ "$status" -eq 0 ]
+	grep -E '^\s+0\s+100000\s+65534$' <<<"$output"
+
+	runc exec in_userns readlink /proc/self/ns/user
+	[ "$status" -eq 0 ]
+	[ "$output" = "$(readlink "/proc/$target_pid/ns/user")" ]
+}
+
+@test "userns exec with many gid mappings and unmapped groups" {
+	requires root
+
+	# More than 5 ranges, with gids 1001 and 6000 not mapped.
+	update_config ' .linux.gidMappings = [
+		{"hostID": 200000, "containerID": 0, "size": 1000},
+		{"hostID": 201000, "containerID": 1000, "size": 1},
+		{"hostID": 202000, "containerID": 2000, "size": 1},
+		{"hostID": 203000, "containerID": 3000, "size": 1},
+		{"hostID": 204000, "containerID": 4000, "size": 1},
+		{"hostID": 205000, "containerID": 5000, "size": 1},
+		{"hostID": 265534, "containerID": 65534, "size": 1}]'
+	echo "tester:x:1000:1000::/:/bin/sh" >>rootfs/etc/passwd
+	cat >>rootfs/etc/group <<EOF
+tester:x:1000:
+g1001:x:1001:tester
+g2000:x:2000:tester
+g5000:x:5000:tester
+EOF
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# The unmapped group (1001) is skipped.
+	runc exec --user 1000:1000 test_busybox id -G
+	[ "$status" -eq 0 ]
+	[ "$output" = "1000 2000 5000" ]
+
+	runc exec --user 1000:1000 --additional-gids 4000 test_busybox id -G
+	[ "$status" -eq 0 ]
+	[ "$output" = "1000 2000 5000 4000" ]
+
+	# An unmapped additional group is an error.
+	runc exec --user 1000:1000 --additional-gids 6000 test_busybox id -G
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"not mapped in the user namespace"* ]]
+}
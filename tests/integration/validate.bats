@@ -0,0 +1,54 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc validate" {
+	runc validate
+	[ "$status" -eq 0 ]
+	[ -z "$output" ]
+
+	runc validate --format json
+	[ "$status" -eq 0 ]
+	[ "$output" = "[]" ]
+}
+
+@test "runc validate --bundle" {
+	cd /
+	runc validate --bundle "$ROOT/bundle"
+	[ "$status" -eq 0 ]
+}
+
+@test "runc validate (schema errors)" {
+	update_config '.process.args = [] | .process.termnal = true'
+	runc validate --format json
+	[ "$status" -ne 0 ]
+	[ "$(echo "${lines[0]}" | jq -r '.[] | select(.level == "error") | .message')" = "process: args must not be empty" ]
+	[ "$(echo "${lines[0]}" | jq -r '.[] | select(.level == "warning") | .message')" = "unknown field process.termnal" ]
+
+	update_config '.process.args = ["sh"] | .linux.namespaces += [{"type": "pidns"}]'
+	runc validate
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'unknown type "pidns"'* ]]
+}
+
+@test "runc validate (unknown capability)" {
+	update_config '.process.capabilities.bounding += ["CAP_NET_ADMN"]'
+	runc validate
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"caps"*"CAP_NET_ADMN"* ]]
+}
+
+@test "runc validate (config error)" {
+	update_config '.root.path = "nonexistent"'
+	runc validate
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"config"*"rootfs"* ]]
+}
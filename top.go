@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containerd/console"
+	"github.com/docker/go-units"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+var topCommand = cli.Command{
+	Name:  "top",
+	Usage: "display the processes of a container and their resource usage, periodically",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+	Description: `The top command displays, every --interval, the container processes
+along with their CPU and memory usage, and the totals and pressure stall
+information of the container cgroup, until the container stops. On a
+terminal, the table is refreshed in place.
+
+The CPU usage is the percentage of one CPU used during the last interval,
+so that it can exceed 100% for a container using several CPUs.`,
+	Flags: []cli.Flag{
+		cli.DurationFlag{Name: "interval", Value: 2 * time.Second, Usage: "the `duration` between two updates"},
+		cli.IntFlag{Name: "iterations, n", Usage: "exit after the given number of updates (0 for no limit)"},
+		cli.BoolFlag{Name: "once", Usage: "display a single update (after --interval), without refreshing the screen"},
+		cli.StringFlag{Name: "format, f", Value: "table", Usage: `select one of: ` + formatOptions},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		interval := context.Duration("interval")
+		if interval <= 0 {
+			return errors.New("--interval must be greater than 0")
+		}
+		iterations := context.Int("iterations")
+		if iterations < 0 {
+			return errors.New("--iterations must not be negative")
+		}
+		once := context.Bool("once")
+		if once {
+			if context.IsSet("iterations") {
+				return errors.New("--once and --iterations can not be used together")
+			}
+			iterations = 1
+		}
+		var print func(io.Writer, *topSample) error
+		switch context.String("format") {
+		case "table":
+			print = printTopSample
+			// Refresh the screen if the output is a terminal.
+			if _, err := console.ConsoleFromFile(os.Stdout); err == nil && !once {
+				print = func(w io.Writer, s *topSample) error {
+					// Move the cursor home and clear the screen.
+					if _, err := io.WriteString(w, "\033[H\033[2J"); err != nil {
+						return err
+					}
+					return printTopSample(w, s)
+				}
+			}
+		case "json":
+			print = func(w io.Writer, s *topSample) error {
+				return json.NewEncoder(w).Encode(s)
+			}
+		default:
+			return errors.New("invalid format option")
+		}
+		rootlessCg, err := shouldUseRootlessCgroupManager(context)
+		if err != nil {
+			return err
+		}
+		if rootlessCg {
+			logrus.Warn("runc top may fail if you don't have the full access to cgroups")
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+
+		status, err := container.Status()
+		if err != nil {
+			return err
+		}
+		if status == libcontainer.Stopped {
+			return nil
+		}
+		s := &topSampler{container: container}
+		// The first sample is only the base of the CPU usage.
+		if _, err := s.sample(); err != nil {
+			return err
+		}
+		for i := 0; iterations == 0 || i < iterations; i++ {
+			time.Sleep(interval)
+			status, err := container.Status()
+			if err != nil {
+				return err
+			}
+			if status == libcontainer.Stopped {
+				return nil
+			}
+			sample, err := s.sample()
+			if err != nil {
+				return err
+			}
+			if err := print(os.Stdout, sample); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// topSample is what runc top displays at each interval.
+type topSample struct {
+	Time time.Time `json:"time"`
+	// CPU is the percentage of one CPU used by the container cgroup
+	// since the previous sample.
+	CPU         float64 `json:"cpu_percent"`
+	MemoryUsage uint64  `json:"memory_usage"`
+	// MemoryLimit and PidsLimit are 0 if unlimited.
+	MemoryLimit uint64 `json:"memory_limit,omitempty"`
+	Pids        uint64 `json:"pids"`
+	PidsLimit   uint64 `json:"pids_limit,omitempty"`
+	// PSI is the pressure stall information of the container cgroup
+	// (cgroup v2 only).
+	PSI       *topPSI      `json:"psi,omitempty"`
+	Processes []topProcess `json:"processes"`
+}
+
+// topPSI is the pressure stall information of the container cgroup, by
+// resource.
+type topPSI struct {
+	CPU    *cgroups.PSIStats `json:"cpu,omitempty"`
+	Memory *cgroups.PSIStats `json:"memory,omitempty"`
+	IO     *cgroups.PSIStats `json:"io,omitempty"`
+}
+
+// topProcess is a container process, as displayed by runc top.
+type topProcess struct {
+	Pid int `json:"pid"`
+	// NsPid is the PID in the container PID namespace, 0 if unknown.
+	NsPid int    `json:"ns_pid,omitempty"`
+	PPid  int    `json:"ppid"`
+	State string `json:"state"`
+	// CPU is the percentage of one CPU used by the process since the
+	// previous sample, or since it started.
+	CPU float64 `json:"cpu_percent"`
+	// RSS is the resident set size, in bytes.
+	RSS  uint64 `json:"rss"`
+	Comm string `json:"comm"`
+}
+
+// procKey identifies a process, even if its PID is reused.
+type procKey struct {
+	pid       int
+	startTime uint64
+}
+
+// clockTicks is the number of clock ticks per second in /proc files, which
+// is always 100 on Linux (USER_HZ).
+const clockTicks = 100
+
+// topSampler samples the resource usage of the container, and of its
+// processes, computing the CPU usage from the previous sample.
+type topSampler struct {
+	container *libcontainer.Container
+	last      time.Time
+	lastCPU   uint64
+	lastTicks map[procKey]uint64
+}
+
+func (s *topSampler) sample() (*topSample, error) {
+	now := time.Now()
+	stats, err := s.container.Stats()
+	if err != nil {
+		return nil, err
+	}
+	pids, err := s.container.Processes()
+	if err != nil {
+		return nil, err
+	}
+	elapsed := now.Sub(s.last).Seconds()
+	sample := &topSample{Time: now}
+	if cg := stats.CgroupStats; cg != nil {
+		usage := cg.CpuStats.CpuUsage.TotalUsage
+		if !s.last.IsZero() && usage >= s.lastCPU {
+			sample.CPU = float64(usage-s.lastCPU) / 1e9 / elapsed * 100
+		}
+		s.lastCPU = usage
+		sample.MemoryUsage = cg.MemoryStats.Usage.Usage
+		if limit := cg.MemoryStats.Usage.Limit; limit < 1<<62 {
+			sample.MemoryLimit = limit
+		}
+		sample.Pids = cg.PidsStats.Current
+		sample.PidsLimit = cg.PidsStats.Limit
+		if psi := (topPSI{CPU: cg.CpuStats.PSI, Memory: cg.MemoryStats.PSI, IO: cg.BlkioStats.PSI}); psi != (topPSI{}) {
+			sample.PSI = &psi
+		}
+	}
+
+	ticks := make(map[procKey]uint64, len(pids))
+	for _, pid := range pids {
+		st, err := system.Stat(pid)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ESRCH) {
+				continue
+			}
+			return nil, err
+		}
+		key := procKey{pid: pid, startTime: st.StartTime}
+		cpuTicks := st.UTime + st.STime
+		ticks[key] = cpuTicks
+		status := readProcStatus(pid)
+		p := topProcess{
+			Pid:   pid,
+			NsPid: status.nsPid,
+			PPid:  st.PPid,
+			State: string(st.State),
+			RSS:   status.rss,
+			Comm:  st.Name,
+		}
+		if last, ok := s.lastTicks[key]; ok && cpuTicks >= last {
+			p.CPU = float64(cpuTicks-last) / clockTicks / elapsed * 100
+		} else if !s.last.IsZero() {
+			// A new process: its CPU time is from the interval.
+			p.CPU = float64(cpuTicks) / clockTicks / elapsed * 100
+		}
+		sample.Processes = append(sample.Processes, p)
+	}
+	sort.SliceStable(sample.Processes, func(i, j int) bool {
+		a, b := sample.Processes[i], sample.Processes[j]
+		if a.CPU != b.CPU {
+			return a.CPU > b.CPU
+		}
+		return a.Pid < b.Pid
+	})
+	s.last = now
+	s.lastTicks = ticks
+	return sample, nil
+}
+
+// procStatus is what runc top reads from /proc/<pid>/status.
+type procStatus struct {
+	// nsPid is the PID of the process in its PID namespace (the container
+	// one), or 0 if unknown.
+	nsPid int
+	// rss is the resident set size, in bytes, or 0 if unknown (such as
+	// for a zombie).
+	rss uint64
+}
+
+// readProcStatus reads the status of the process pid, returning the zero
+// value if it can not be read.
+func readProcStatus(pid int) procStatus {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return procStatus{}
+	}
+	return parseProcStatus(string(data))
+}
+
+// parseProcStatus parses the contents of /proc/<pid>/status (see proc(5)):
+// the innermost PID of the NSpid line, and the VmRSS line.
+func parseProcStatus(status string) procStatus {
+	var st procStatus
+	for _, line := range strings.Split(status, "\n") {
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(val)
+		if len(fields) == 0 {
+			continue
+		}
+		switch key {
+		case "NSpid":
+			st.nsPid, _ = strconv.Atoi(fields[len(fields)-1])
+		case "VmRSS":
+			if kb, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+				st.rss = kb * 1024
+			}
+		}
+	}
+	return st
+}
+
+func printTopSample(out io.Writer, s *topSample) error {
+	mem := units.BytesSize(float64(s.MemoryUsage))
+	if s.MemoryLimit != 0 {
+		mem += " / " + units.BytesSize(float64(s.MemoryLimit))
+	}
+	pids := strconv.FormatUint(s.Pids, 10)
+	if s.PidsLimit != 0 {
+		pids += " / " + strconv.FormatUint(s.PidsLimit, 10)
+	}
+	fmt.Fprintf(out, "%s  CPU: %.1f%%  MEM: %s  PIDS: %s\n", s.Time.Format(time.TimeOnly), s.CPU, mem, pids)
+	if psi := s.PSI; psi != nil {
+		// The share of the last 10 seconds some tasks were stalled.
+		avg10 := func(p *cgroups.PSIStats) string {
+			if p == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f%%", p.Some.Avg10)
+		}
+		fmt.Fprintf(out, "PRESSURE (avg10)  CPU: %s  MEM: %s  IO: %s\n", avg10(psi.CPU), avg10(psi.Memory), avg10(psi.IO))
+	}
+	w := tabwriter.NewWriter(out, 6, 1, 3, ' ', 0)
+	fmt.Fprint(w, "PID\tNSPID\tPPID\tS\t%CPU\tRSS\tCOMMAND\n")
+	for _, p := range s.Processes {
+		nsPid := "-"
+		if p.NsPid != 0 {
+			nsPid = strconv.Itoa(p.NsPid)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%.1f\t%s\t%s\n", p.Pid, nsPid, p.PPid, p.State, p.CPU, units.BytesSize(float64(p.RSS)), p.Comm)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseProcStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status   string
+		expected procStatus
+	}{
+		{status: "Name:\tsleep\nPid:\t1234\nNSpid:\t1234\t1\nNStgid:\t1234\t1\nVmRSS:\t    1280 kB\n", expected: procStatus{nsPid: 1, rss: 1280 * 1024}},
+		{status: "Name:\tsleep\nNSpid:\t1234\n", expected: procStatus{nsPid: 1234}},
+		{status: "Name:\tsleep\nPid:\t1234\n"},
+		{status: "NSpid:\nVmRSS:\n"},
+	} {
+		if st := parseProcStatus(tc.status); st != tc.expected {
+			t.Errorf("%q: expected %+v, got %+v", tc.status, tc.expected, st)
+		}
+	}
+
+	st := readProcStatus(os.Getpid())
+	if st.nsPid == 0 {
+		t.Error("expected the PID of the test in its namespace, got 0")
+	}
+	if st.rss == 0 {
+		t.Error("expected the RSS of the test, got 0")
+	}
+}
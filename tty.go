@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/signal"
 	"sync"
@@ -21,6 +23,9 @@ type tty struct {
 	postStart   []io.Closer
 	wg          sync.WaitGroup
 	consoleC    chan error
+	// exitStatus is where the process exit status is sent (see
+	// setupSocketIO), if set.
+	exitStatus io.Writer
 }
 
 func (t *tty) copyIO(w io.Writer, r io.ReadCloser) {
@@ -63,6 +68,76 @@ func setupProcessPipes(p *libcontainer.Process, rootuid, rootgid int) (*tty, err
 	return t, nil
 }
 
+// setupSocketIO sets up the process stdio as socketpairs, and sends their
+// other ends to the AF_UNIX socket at sockpath: first the process stdin and
+// stdout (as "stdio"), then its stderr (as "stderr"). As runc keeps no copy
+// of them, the caller gets EOF as soon as the container processes are done
+// with their output, and can half-close the process stdin with shutdown(2).
+// Once the process has exited, its exit status is sent over the connection
+// to sockpath (see sendExitStatus).
+func setupSocketIO(p *libcontainer.Process, sockpath string) (_ *tty, Err error) {
+	conn, err := net.Dial("unix", sockpath)
+	if err != nil {
+		return nil, err
+	}
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		conn.Close()
+		return nil, errors.New("casting to UnixConn failed")
+	}
+	t := &tty{
+		closers:    []io.Closer{uc},
+		exitStatus: uc,
+	}
+	defer func() {
+		if Err != nil {
+			t.Close()
+		}
+	}()
+	socket, err := uc.File()
+	if err != nil {
+		return nil, err
+	}
+	defer socket.Close()
+
+	stdio, stdioChild, err := utils.NewSockPair("stdio")
+	if err != nil {
+		return nil, err
+	}
+	defer stdio.Close()
+	t.postStart = append(t.postStart, stdioChild)
+	stderr, stderrChild, err := utils.NewSockPair("stderr")
+	if err != nil {
+		return nil, err
+	}
+	defer stderr.Close()
+	t.postStart = append(t.postStart, stderrChild)
+
+	for _, f := range []struct {
+		name string
+		file *os.File
+	}{{"stdio", stdio}, {"stderr", stderr}} {
+		if err := utils.SendRawFd(socket, f.name, f.file.Fd()); err != nil {
+			return nil, fmt.Errorf("unable to send %s to the stdio socket: %w", f.name, err)
+		}
+	}
+	p.Stdin = stdioChild
+	p.Stdout = stdioChild
+	p.Stderr = stderrChild
+	return t, nil
+}
+
+// sendExitStatus sends the process exit status, as a JSON object such as
+// {"exit_status":0}, to the socket set up by setupSocketIO, if any.
+func (t *tty) sendExitStatus(status int) error {
+	if t.exitStatus == nil {
+		return nil
+	}
+	return json.NewEncoder(t.exitStatus).Encode(struct {
+		ExitStatus int `json:"exit_status"`
+	}{status})
+}
+
 func inheritStdio(process *libcontainer.Process) {
 	process.Stdin = os.Stdin
 	process.Stdout = os.Stdout
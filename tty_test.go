@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+func TestSetupSocketIO(t *testing.T) {
+	sockpath := filepath.Join(t.TempDir(), "stdio.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: sockpath, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p := &libcontainer.Process{}
+	tty, err := setupSocketIO(p, sockpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tty.Close()
+
+	conn, err := l.AcceptUnix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	socket, err := conn.File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+	stdio, err := utils.RecvFile(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdio.Close()
+	stderr, err := utils.RecvFile(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	if stdio.Name() != "stdio" || stderr.Name() != "stderr" {
+		t.Fatalf("expected stdio and stderr, got %s and %s", stdio.Name(), stderr.Name())
+	}
+
+	// Act as the process: echo stdin to stdout until EOF, then exit.
+	in, out := p.Stdin.(*os.File), p.Stdout.(*os.File)
+	if _, err := stdio.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Shutdown(int(stdio.Fd()), unix.SHUT_WR); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := out.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	tty.ClosePostStart()
+
+	data, err = io.ReadAll(stdio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("expected hello, got %q", data)
+	}
+	if data, err = io.ReadAll(stderr); err != nil || len(data) != 0 {
+		t.Errorf("expected an empty stderr, got %q (%v)", data, err)
+	}
+
+	if err := tty.sendExitStatus(3); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != `{"exit_status":3}`+"\n" {
+		t.Errorf("unexpected exit status message %q", line)
+	}
+}
@@ -22,6 +22,38 @@ type Stats struct {
 	Hugetlb           map[string]Hugetlb  `json:"hugetlb"`
 	IntelRdt          IntelRdt            `json:"intel_rdt"`
 	NetworkInterfaces []*NetworkInterface `json:"network_interfaces"`
+	DiskQuota         *DiskQuota          `json:"disk_quota,omitempty"`
+	FDs               *FDs                `json:"fds,omitempty"`
+}
+
+// FDs are the totals of the file descriptors and sockets opened by the
+// container processes.
+type FDs struct {
+	FDs       uint64 `json:"fds"`
+	Sockets   uint64 `json:"sockets"`
+	Unix      uint64 `json:"unix"`
+	TCP       uint64 `json:"tcp"`
+	UDP       uint64 `json:"udp"`
+	Processes int    `json:"processes"`
+	Sampled   bool   `json:"sampled,omitempty"`
+}
+
+// FDThreshold is the data of an fd-threshold event, sent when the number
+// of file descriptors (Resource "fds") or sockets (Resource "sockets")
+// opened by the container processes goes over the threshold.
+type FDThreshold struct {
+	Resource  string `json:"resource"`
+	Current   uint64 `json:"current"`
+	Threshold uint64 `json:"threshold"`
+}
+
+// DiskQuota is the disk usage of the container writable layer, and its
+// project quota limits.
+type DiskQuota struct {
+	Size        uint64 `json:"size"`
+	SizeLimit   uint64 `json:"size_limit,omitempty"`
+	Inodes      uint64 `json:"inodes"`
+	InodesLimit uint64 `json:"inodes_limit,omitempty"`
 }
 
 type PSIData = cgroups.PSIData
@@ -51,11 +83,25 @@ type Blkio struct {
 	IoTimeRecursive         []BlkioEntry `json:"ioTimeRecursive,omitempty"`
 	SectorsRecursive        []BlkioEntry `json:"sectorsRecursive,omitempty"`
 	PSI                     *PSIStats    `json:"psi,omitempty"`
+	// Devices are the per-device IO stats, keyed by the device name (such
+	// as nvme0n1), or by major:minor if the name is not known.
+	Devices map[string]BlkioDevice `json:"devices,omitempty"`
+}
+
+type BlkioDevice struct {
+	Major      uint64 `json:"major"`
+	Minor      uint64 `json:"minor"`
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+	ReadIOs    uint64 `json:"read_ios"`
+	WriteIOs   uint64 `json:"write_ios"`
 }
 
 type Pids struct {
 	Current uint64 `json:"current,omitempty"`
 	Limit   uint64 `json:"limit,omitempty"`
+	// MaxEvents is the number of times a fork failed due to the limit.
+	MaxEvents uint64 `json:"max_events,omitempty"`
 }
 
 type Throttling struct {
@@ -92,6 +138,8 @@ type CPUSet struct {
 	MemoryPressure        uint64   `json:"memory_pressure"`
 	SchedLoadBalance      uint64   `json:"sched_load_balance"`
 	SchedRelaxDomainLevel int64    `json:"sched_relax_domain_level"`
+	EffectiveCPUs         []uint16 `json:"effective_cpus,omitempty"`
+	EffectiveMems         []uint16 `json:"effective_mems,omitempty"`
 }
 
 type MemoryEntry struct {
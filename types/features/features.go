@@ -22,4 +22,61 @@ const (
 	// AnnotationLibseccompVersion is the version of libseccomp, e.g., "2.5.1".
 	// Note that the runtime MAY support seccomp even when this annotation is not present.
 	AnnotationLibseccompVersion = "io.github.seccomp.libseccomp.version"
+
+	// AnnotationSystemdVersion is the version of the systemd instance used by the systemd cgroup driver, e.g., "252".
+	// It is not present if systemd is not running, or its version can not be detected.
+	AnnotationSystemdVersion = "org.opencontainers.runc.systemd.version"
+
+	// AnnotationSystemdFeatures is a comma-separated list of the version-dependent systemd unit properties
+	// (and property values) used by the systemd cgroup driver which the systemd instance supports,
+	// e.g., "CPUQuotaPeriodUSec,AllowedCPUs".
+	AnnotationSystemdFeatures = "org.opencontainers.runc.systemd.features"
+
+	// AnnotationLSMStack is a comma-separated list of the active Linux Security Modules, in the order
+	// they are called, e.g., "lockdown,capability,landlock,yama,apparmor,bpf".
+	// It is not present if the list can not be read (such as when securityfs is not mounted).
+	AnnotationLSMStack = "org.opencontainers.runc.lsm.stack"
+
+	// AnnotationStateVersion is the version of the container state format written by runc, e.g., "1".
+	// Containers whose state requires a newer version can not be managed by this runc.
+	AnnotationStateVersion = "org.opencontainers.runc.state.version"
+
+	// AnnotationConsoleSocketVersions is a comma-separated list of the console socket protocol versions
+	// which can be selected with --console-socket-version, e.g., "1,2".
+	AnnotationConsoleSocketVersions = "org.opencontainers.runc.console-socket.versions"
+
+	// AnnotationCgroupControllers is a comma-separated list of the cgroup controllers available on the host,
+	// e.g., "cpu,io,memory,pids". It is not present if the list can not be read.
+	AnnotationCgroupControllers = "org.opencontainers.runc.cgroup.controllers"
+
+	// AnnotationCriuVersion is the version of the CRIU binary used for checkpoint/restore, e.g., "3.19" or "3.17.1".
+	// It is not present if CRIU is not installed or its version can not be detected, in which case
+	// checkpointing fails even though AnnotationRuncCheckpointEnabled is "true".
+	AnnotationCriuVersion = "org.opencontainers.runc.criu.version"
+
+	// AnnotationMountSetattr is set to "true" if the kernel supports mount_setattr(2) (Linux 5.12+),
+	// which is needed by the recursive mount options ("rro", "rnosuid", etc.) and by idmapped mounts,
+	// and to "false" otherwise.
+	AnnotationMountSetattr = "org.opencontainers.runc.mount.setattr"
+
+	// AnnotationCgroupV2Unavailable is a comma-separated list of the cgroup v2 only features used by runc which are
+	// not available on the host (such as on a cgroup v1 host), e.g., "psi,memory.low,io.weight,cgroup.kill".
+	// It is empty if they are all available. The fallbacks used instead are shown in the "cgroup_v2_unavailable"
+	// field of `runc state`.
+	AnnotationCgroupV2Unavailable = "org.opencontainers.runc.cgroup.v2-unavailable"
+
+	// AnnotationHelperGOMAXPROCS is the effective GOMAXPROCS of runc itself (see the --helper-gomaxprocs option),
+	// e.g., "1".
+	AnnotationHelperGOMAXPROCS = "org.opencontainers.runc.helper.gomaxprocs"
+
+	// AnnotationHelperMemoryLimit is the effective soft memory limit of runc itself, in bytes (see the
+	// --helper-memory-limit option), or "max" if there is none.
+	AnnotationHelperMemoryLimit = "org.opencontainers.runc.helper.memory-limit"
+
+	// AnnotationHelperNice is the effective niceness of runc itself (see the --helper-nice option), e.g., "10".
+	AnnotationHelperNice = "org.opencontainers.runc.helper.nice"
+
+	// AnnotationHelperMaxParallel is the effective number of containers runc creates at once when creating several
+	// of them (see the --helper-max-parallel option), e.g., "4".
+	AnnotationHelperMaxParallel = "org.opencontainers.runc.helper.max-parallel"
 )
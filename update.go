@@ -11,8 +11,11 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/docker/go-units"
+	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/devices"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
 )
@@ -54,9 +57,20 @@ The accepted format is as follow (unchanged values can be omitted):
   },
   "blockIO": {
     "weight": 0
-  }
+  },
+  "devices": [
+    {
+      "allow": true,
+      "type": "c",
+      "major": 10,
+      "minor": 200,
+      "access": "rwm"
+    }
+  ]
 }
 
+The device rules are added to the container ones.
+
 Note: if data is to be read from a file or the standard input, all
 other options are ignored.
 `,
@@ -66,6 +80,34 @@ other options are ignored.
 			Name:  "blkio-weight",
 			Usage: "Specifies per cgroup weight, range is from 10 to 1000",
 		},
+		cli.StringSliceFlag{
+			Name:  "blkio-weight-device",
+			Usage: "Block IO weight of a device, as PATH:WEIGHT (can be specified multiple times)",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-read-bps",
+			Usage: "Read rate limit of a device, as PATH:RATE, in bytes per second, such as /dev/sda:10m (can be specified multiple times)",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-write-bps",
+			Usage: "Write rate limit of a device, as PATH:RATE, in bytes per second (can be specified multiple times)",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-read-iops",
+			Usage: "Read rate limit of a device, as PATH:RATE, in IO operations per second (can be specified multiple times)",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-write-iops",
+			Usage: "Write rate limit of a device, as PATH:RATE, in IO operations per second (can be specified multiple times)",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-allow",
+			Usage: "Allow access to devices, with a device cgroup rule such as 'c 10:200 rwm' (can be specified multiple times)",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-deny",
+			Usage: "Deny access to devices, with a device cgroup rule such as 'b 8:* w' (can be specified multiple times)",
+		},
 		cli.StringFlag{
 			Name:  "cpu-period",
 			Usage: "CPU CFS period to be used for hardcapping (in usecs). 0 to use system default",
@@ -124,10 +166,42 @@ other options are ignored.
 			Name:  "memory-swap",
 			Usage: "Total memory usage (memory + swap); set '-1' to enable unlimited swap",
 		},
+		cli.StringFlag{
+			Name:  "swap-max",
+			Usage: "Swap usage limit, independent of the memory limit; set '-1' to enable unlimited swap, '0' to disable swap",
+		},
 		cli.IntFlag{
 			Name:  "pids-limit",
 			Usage: "Maximum number of pids allowed in the container",
 		},
+		cli.StringFlag{
+			Name:  "shm-size",
+			Usage: "Size of the /dev/shm tmpfs (such as 64m, or 50% of the memory limit)",
+		},
+		cli.StringFlag{
+			Name:  "tmp-size",
+			Usage: "Size of the /tmp tmpfs (such as 1g, or 50% of the memory limit)",
+		},
+		cli.Uint64Flag{
+			Name:  "tmp-inodes",
+			Usage: "Maximum number of inodes of the /tmp tmpfs",
+		},
+		cli.StringSliceFlag{
+			Name:  "mask-path",
+			Usage: "Mask the path in the running container (can be specified multiple times)",
+		},
+		cli.StringSliceFlag{
+			Name:  "rlimit",
+			Usage: "Set a resource limit of the container init process, as name=soft[:hard], such as nofile=1048576:1048576 (can be specified multiple times)",
+		},
+		cli.BoolFlag{
+			Name:  "rlimit-all",
+			Usage: "Also set the --rlimit limits of all the other container processes",
+		},
+		cli.BoolFlag{
+			Name:  "systemd-persistent",
+			Usage: "With the systemd cgroup driver, set the container unit properties persistently, rather than at runtime only",
+		},
 		cli.StringFlag{
 			Name:  "l3-cache-schema",
 			Usage: "The string of Intel RDT/CAT L3 cache schema",
@@ -141,10 +215,23 @@ other options are ignored.
 		if err := checkArgs(context, 1, exactArgs); err != nil {
 			return err
 		}
+		lock, err := lockContainer(context, "update")
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
 		container, err := getContainer(context)
 		if err != nil {
 			return err
 		}
+		var rlimits []configs.Rlimit
+		for _, val := range context.StringSlice("rlimit") {
+			rl, err := parseRlimit(val)
+			if err != nil {
+				return err
+			}
+			rlimits = append(rlimits, rl)
+		}
 
 		r := specs.LinuxResources{
 			Memory: &specs.LinuxMemory{
@@ -174,6 +261,10 @@ other options are ignored.
 		}
 
 		config := container.Config()
+		var (
+			swapMax     *int64
+			deviceRules []*devices.Rule
+		)
 
 		if in := context.String("resources"); in != "" {
 			var (
@@ -194,6 +285,16 @@ other options are ignored.
 			if err != nil {
 				return err
 			}
+			for i, d := range r.Devices {
+				if d.Access == "" {
+					return fmt.Errorf("device access at %d field cannot be empty", i)
+				}
+				rule, err := specconv.CreateDeviceRule(d)
+				if err != nil {
+					return err
+				}
+				deviceRules = append(deviceRules, rule)
+			}
 		} else {
 			if val := context.Int("blkio-weight"); val != 0 {
 				r.BlockIO.Weight = u16Ptr(uint16(val))
@@ -270,6 +371,34 @@ other options are ignored.
 			}
 
 			r.Pids.Limit = int64(context.Int("pids-limit"))
+
+			if val := context.String("swap-max"); val != "" {
+				v := int64(-1)
+				if val != "-1" {
+					v, err = units.RAMInBytes(val)
+					if err != nil {
+						return fmt.Errorf("invalid value for swap-max: %w", err)
+					}
+				}
+				if *r.Memory.Swap != 0 {
+					return errors.New("--swap-max and --memory-swap can not be used together")
+				}
+				swapMax = &v
+			}
+
+			if err := updateBlkioDevices(context, config.Cgroups.Resources); err != nil {
+				return err
+			}
+
+			for _, opt := range []string{"device-allow", "device-deny"} {
+				for _, val := range context.StringSlice(opt) {
+					rule, err := specconv.ParseDeviceRule(val, opt == "device-allow")
+					if err != nil {
+						return fmt.Errorf("invalid value for %s: %w", opt, err)
+					}
+					deviceRules = append(deviceRules, rule)
+				}
+			}
 		}
 
 		if *r.Memory.Kernel != 0 || *r.Memory.KernelTCP != 0 {
@@ -289,7 +418,9 @@ other options are ignored.
 		//
 		// Here in update, previously set values are available from config.
 		// If only one of {quota,period} is set and the other is not, leave
-		// the unset parameter at the old value (don't overwrite config).
+		// the unset parameter at the old value (don't overwrite config),
+		// except that the quota is rescaled to the new period, so that the
+		// CPU limit stays the same.
 		p, q := *r.CPU.Period, *r.CPU.Quota
 		if (p == 0 && q == 0) || (p != 0 && q != 0) {
 			// both values are either set or unset (0)
@@ -298,8 +429,17 @@ other options are ignored.
 		} else {
 			// one is set and the other is not
 			if p != 0 {
-				// set new period, leave quota at old value
+				// set new period, rescale the old quota
+				old := config.Cgroups.Resources
+				quota := cgroups.RescaleCPUQuota(old.CpuQuota, old.CpuPeriod, p)
+				if quota > 0 && quota < cgroups.CPUQuotaMin {
+					return fmt.Errorf("the cpu limit can not be kept with cpu period %d, as the quota would be %d, below the %d minimum; set --cpu-quota as well", p, quota, cgroups.CPUQuotaMin)
+				}
+				if quota != old.CpuQuota {
+					logrus.Infof("cpu quota rescaled from %d to %d for cpu period %d", old.CpuQuota, quota, p)
+				}
 				config.Cgroups.Resources.CpuPeriod = p
+				config.Cgroups.Resources.CpuQuota = quota
 			} else if q != 0 {
 				// set new quota, leave period at old value
 				config.Cgroups.Resources.CpuQuota = q
@@ -307,6 +447,12 @@ other options are ignored.
 		}
 
 		config.Cgroups.Resources.CpuBurst = r.CPU.Burst
+		if err := cgroups.CheckCPUBandwidth(config.Cgroups.Resources); err != nil {
+			return err
+		}
+		if p := config.Cgroups.Resources.CpuPeriod; p != 0 && p < cgroups.CPUPeriodShort {
+			logrus.Warnf("cpu period %dus is short: the container may be throttled very often, with a high scheduling overhead", p)
+		}
 		config.Cgroups.Resources.CpuShares = *r.CPU.Shares
 		// CpuWeight is used for cgroupv2 and should be converted
 		config.Cgroups.Resources.CpuWeight = cgroups.ConvertCPUSharesToCgroupV2Value(*r.CPU.Shares)
@@ -318,9 +464,22 @@ other options are ignored.
 		config.Cgroups.Resources.CPUIdle = r.CPU.Idle
 		config.Cgroups.Resources.MemoryReservation = *r.Memory.Reservation
 		config.Cgroups.Resources.MemorySwap = *r.Memory.Swap
+		// The swap limit and the memory+swap limit are mutually exclusive,
+		// the one being set replaces the other.
+		if swapMax != nil {
+			config.Cgroups.Resources.SwapMax = swapMax
+		} else if *r.Memory.Swap != 0 {
+			config.Cgroups.Resources.SwapMax = nil
+		}
 		config.Cgroups.Resources.MemoryCheckBeforeUpdate = *r.Memory.CheckBeforeUpdate
 		config.Cgroups.Resources.PidsLimit = r.Pids.Limit
 		config.Cgroups.Resources.Unified = r.Unified
+		if context.Bool("systemd-persistent") {
+			if !config.Cgroups.Systemd {
+				return errors.New("--systemd-persistent requires the systemd cgroup driver")
+			}
+			config.Cgroups.SystemdPersistent = true
+		}
 
 		// Update Intel RDT
 		l3CacheSchema := context.String("l3-cache-schema")
@@ -353,13 +512,112 @@ other options are ignored.
 			config.IntelRdt.MemBwSchema = memBwSchema
 		}
 
-		// XXX(kolyshkin@): currently "runc update" is unable to change
-		// device configuration, so add this to skip device update.
+		// Unless the device rules are updated, skip the device update.
 		// This helps in case an extra plugin (nvidia GPU) applies some
 		// configuration on top of what runc does.
 		// Note this field is not saved into container's state.json.
-		config.Cgroups.SkipDevices = true
+		if len(deviceRules) > 0 {
+			config.Cgroups.Resources.Devices = updateDeviceRules(config.Cgroups.Resources.Devices, deviceRules)
+		} else {
+			config.Cgroups.SkipDevices = true
+		}
 
-		return container.Set(config)
+		if err := container.Set(config); err != nil {
+			return err
+		}
+		if err := updateTmpfsSizes(context, container, config.Cgroups.Resources.Memory); err != nil {
+			return err
+		}
+		if paths := context.StringSlice("mask-path"); len(paths) > 0 {
+			if err := container.MaskPaths(paths); err != nil {
+				return err
+			}
+		}
+		if len(rlimits) > 0 {
+			return container.SetRlimits(rlimits, context.Bool("rlimit-all"))
+		}
+		return nil
 	},
 }
+
+// updateTmpfsSizes resizes the /dev/shm and /tmp tmpfs mounts of the
+// container, as requested by the --shm-size, --tmp-size and --tmp-inodes
+// options. The sizes in percent are relative to memoryLimit.
+func updateTmpfsSizes(context *cli.Context, container *libcontainer.Container, memoryLimit int64) error {
+	if val := context.String("shm-size"); val != "" {
+		size, err := specconv.ParseTmpfsSize(val, memoryLimit)
+		if err != nil {
+			return fmt.Errorf("invalid value for shm-size: %w", err)
+		}
+		if err := container.SetTmpfsSize("/dev/shm", size, 0); err != nil {
+			return err
+		}
+	}
+	var size int64
+	if val := context.String("tmp-size"); val != "" {
+		var err error
+		if size, err = specconv.ParseTmpfsSize(val, memoryLimit); err != nil {
+			return fmt.Errorf("invalid value for tmp-size: %w", err)
+		}
+	}
+	return container.SetTmpfsSize("/tmp", size, context.Uint64("tmp-inodes"))
+}
+
+// updateDeviceRules appends the device cgroup rules add to rules, removing
+// the rules for the same devices and access, which they override.
+func updateDeviceRules(rules, add []*devices.Rule) []*devices.Rule {
+	for _, a := range add {
+		var kept []*devices.Rule
+		for _, r := range rules {
+			if r.Type != a.Type || r.Major != a.Major || r.Minor != a.Minor || r.Permissions != a.Permissions {
+				kept = append(kept, r)
+			}
+		}
+		rules = append(kept, a)
+	}
+	return rules
+}
+
+// updateBlkioDevices sets the per-device block IO weights and limits from
+// the --blkio-weight-device and --device-* options, replacing the ones set
+// for the same device path.
+func updateBlkioDevices(context *cli.Context, r *configs.Resources) error {
+	for _, val := range context.StringSlice("blkio-weight-device") {
+		wd, err := specconv.ParseWeightDevice(val)
+		if err != nil {
+			return fmt.Errorf("invalid value for blkio-weight-device: %w", err)
+		}
+		var kept []*configs.WeightDevice
+		for _, d := range r.BlkioWeightDevice {
+			if d.Path != wd.Path {
+				kept = append(kept, d)
+			}
+		}
+		r.BlkioWeightDevice = append(kept, wd)
+	}
+	for _, t := range []struct {
+		opt   string
+		bytes bool
+		dest  *[]*configs.ThrottleDevice
+	}{
+		{"device-read-bps", true, &r.BlkioThrottleReadBpsDevice},
+		{"device-write-bps", true, &r.BlkioThrottleWriteBpsDevice},
+		{"device-read-iops", false, &r.BlkioThrottleReadIOPSDevice},
+		{"device-write-iops", false, &r.BlkioThrottleWriteIOPSDevice},
+	} {
+		for _, val := range context.StringSlice(t.opt) {
+			td, err := specconv.ParseThrottleDevice(val, t.bytes)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %w", t.opt, err)
+			}
+			var kept []*configs.ThrottleDevice
+			for _, d := range *t.dest {
+				if d.Path != td.Path {
+					kept = append(kept, d)
+				}
+			}
+			*t.dest = append(kept, td)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/devices"
+	"golang.org/x/sys/unix"
+)
+
+func TestUpdateDeviceRules(t *testing.T) {
+	rules := []*devices.Rule{
+		{Type: devices.WildcardDevice, Major: devices.Wildcard, Minor: devices.Wildcard, Permissions: "rwm", Allow: false},
+		{Type: devices.CharDevice, Major: 1, Minor: 5, Permissions: "rwm", Allow: true},
+		{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+	}
+	rules = updateDeviceRules(rules, []*devices.Rule{
+		{Type: devices.CharDevice, Major: 1, Minor: 5, Permissions: "rwm", Allow: false},
+		{Type: devices.CharDevice, Major: 10, Minor: 200, Permissions: "rw", Allow: true},
+	})
+	expected := []*devices.Rule{
+		{Type: devices.WildcardDevice, Major: devices.Wildcard, Minor: devices.Wildcard, Permissions: "rwm", Allow: false},
+		{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+		{Type: devices.CharDevice, Major: 1, Minor: 5, Permissions: "rwm", Allow: false},
+		{Type: devices.CharDevice, Major: 10, Minor: 200, Permissions: "rw", Allow: true},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		for i, r := range rules {
+			t.Logf("%d: %+v", i, r)
+		}
+		t.Error("unexpected rules")
+	}
+}
+
+func TestParseRlimit(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected configs.Rlimit
+		isErr    bool
+	}{
+		{in: "nofile=1048576:1048576", expected: configs.Rlimit{Type: unix.RLIMIT_NOFILE, Soft: 1048576, Hard: 1048576}},
+		{in: "NOFILE=1024:4096", expected: configs.Rlimit{Type: unix.RLIMIT_NOFILE, Soft: 1024, Hard: 4096}},
+		{in: "RLIMIT_CORE=0", expected: configs.Rlimit{Type: unix.RLIMIT_CORE}},
+		{in: "memlock=unlimited", expected: configs.Rlimit{Type: unix.RLIMIT_MEMLOCK, Soft: unix.RLIM_INFINITY, Hard: unix.RLIM_INFINITY}},
+		{in: "nproc=100:unlimited", expected: configs.Rlimit{Type: unix.RLIMIT_NPROC, Soft: 100, Hard: unix.RLIM_INFINITY}},
+		{in: "nofile=4096:1024", isErr: true},
+		{in: "nofile", isErr: true},
+		{in: "nofile=", isErr: true},
+		{in: "nofile=-1", isErr: true},
+		{in: "files=10", isErr: true},
+	}
+	for _, tc := range testCases {
+		rl, err := parseRlimit(tc.in)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if rl != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.in, tc.expected, rl)
+		}
+	}
+}
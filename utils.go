@@ -42,7 +42,7 @@ func checkArgs(context *cli.Context, expected, checkType int) error {
 	if err != nil {
 		fmt.Printf("Incorrect Usage.\n\n")
 		_ = cli.ShowCommandHelp(context, cmdName)
-		return err
+		return withCode(errCodeUsage, err)
 	}
 	return nil
 }
@@ -59,17 +59,30 @@ func fatal(err error) {
 }
 
 func fatalWithCode(err error, ret int) {
+	code := errorCodeOf(err)
 	// Make sure the error is written to the logger.
-	logrus.Error(err)
+	if _, ok := logrus.StandardLogger().Formatter.(*logrus.JSONFormatter); ok {
+		logrus.WithField("code", code.name).Error(err)
+	} else {
+		logrus.Error(err)
+	}
 	if !logrusToStderr() {
 		fmt.Fprintln(os.Stderr, err)
 	}
 
+	if errorExitCodes {
+		ret = code.exit
+	}
 	os.Exit(ret)
 }
 
 // setupSpec performs initial setup based on the cli.Context for the container
 func setupSpec(context *cli.Context) (*specs.Spec, error) {
+	// The patch files are relative to the current directory.
+	overrides, err := readConfigOverrides(context)
+	if err != nil {
+		return nil, err
+	}
 	bundle := context.String("bundle")
 	if bundle != "" {
 		/*如果bundle有值，则划换工作目录到bundle指定的位置*/
@@ -78,9 +91,17 @@ func setupSpec(context *cli.Context) (*specs.Spec, error) {
 		}
 	}
 	/*加载config.json，获得spec对象*/
+	if overrides != nil {
+		data, err := os.ReadFile(specConfig)
+		if err != nil {
+			return nil, withCode(errCodeBundleInvalid, err)
+		}
+		spec, err := overrides.apply(data)
+		return spec, withCode(errCodeBundleInvalid, err)
+	}
 	spec, err := loadSpec(specConfig)
 	if err != nil {
-		return nil, err
+		return nil, withCode(errCodeBundleInvalid, err)
 	}
 	return spec, nil
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/coreos/go-systemd/v22/activation"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/preflight"
 	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runc/libcontainer/system/kernelversion"
 	"github.com/opencontainers/runc/libcontainer/utils"
@@ -35,6 +37,17 @@ func getContainer(context *cli.Context) (*libcontainer.Container, error) {
 	return libcontainer.Load(root, id)
 }
 
+// lockContainer takes the lock on the container specified by the first
+// argument, so no other runc instance can modify it concurrently. The lock
+// is to be taken before the container is loaded (or created).
+func lockContainer(context *cli.Context, operation string) (*libcontainer.ContainerLock, error) {
+	id := context.Args().First()
+	if id == "" {
+		return nil, errEmptyID
+	}
+	return libcontainer.LockContainer(context.GlobalString("root"), id, operation)
+}
+
 func getDefaultImagePath() string {
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -169,6 +182,18 @@ func createContainer(context *cli.Context, id string/*container id*/, spec *spec
 	if err != nil {
 		return nil, err
 	}
+	var cgroupFd int
+	if context.IsSet("cgroup-fd") {
+		if cgroupFd = context.Int("cgroup-fd"); cgroupFd <= 2 {
+			return nil, fmt.Errorf("invalid --cgroup-fd value: %d", cgroupFd)
+		}
+	}
+	var usernsFd int
+	if context.IsSet("userns-fd") {
+		if usernsFd = context.Int("userns-fd"); usernsFd <= 2 {
+			return nil, fmt.Errorf("invalid --userns-fd value: %d", usernsFd)
+		}
+	}
 	
 	/*生成config对象*/
 	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
@@ -179,14 +204,44 @@ func createContainer(context *cli.Context, id string/*container id*/, spec *spec
 		Spec:             spec,
 		RootlessEUID:     os.Geteuid() != 0,
 		RootlessCgroups:  rootlessCg,
+		CgroupFd:         cgroupFd,
+		UsernsFd:         usernsFd,
+
+		AllowedMountSources: context.GlobalStringSlice("allowed-mount-source"),
 	})
 	if err != nil {
+		return nil, withCode(errCodeBundleInvalid, err)
+	}
+	// The current directory is the bundle (see setupSpec).
+	if config.ConfigHash, err = configHash(specConfig); err != nil {
 		return nil, err
 	}
 
 	/*通过factory_linux.go的Create函数，生成container对象*/
 	root := context.GlobalString("root")
-	return libcontainer.Create(root, id, config)
+	container, err := libcontainer.Create(root, id, config)
+	if err != nil {
+		return nil, annotatePreflight(err, config)
+	}
+	return container, nil
+}
+
+// annotatePreflight annotates err with the failed preflight checks for
+// config, if it is an error they can explain (see preflightExplains).
+func annotatePreflight(err error, config *configs.Config) error {
+	if !preflightExplains(err) {
+		return err
+	}
+	return preflight.Annotate(err, preflight.Run(config))
+}
+
+// preflightExplains reports whether err is of a class the failed preflight
+// checks can explain, such as a cgroup or an invalid configuration error
+// (but not, for example, a container ID or lock error).
+func preflightExplains(err error) bool {
+	return errors.Is(err, libcontainer.ErrCgroupApply) ||
+		errors.Is(err, libcontainer.ErrExec) ||
+		errors.Is(err, libcontainer.ErrInvalidConfig)
 }
 
 type runner struct {
@@ -198,12 +253,28 @@ type runner struct {
 	preserveFDs     int
 	pidFile         string
 	consoleSocket   string
+	consoleVersion  int
 	pidfdSocket     string
+	stdioSocket     string
 	container       *libcontainer.Container
 	action          CtAct
 	notifySocket    *notifySocket
 	criuOpts        *libcontainer.CriuOpts
 	subCgroupPaths  map[string]string
+	subCgroupCreate bool
+	subCgroupSet    map[string]string
+	cgroupPaths     map[string]string
+	timeout         time.Duration
+	timeoutGrace    time.Duration
+	exitStatusFile  string
+	controlSocket   bool
+	secrets         []*libcontainer.Secret
+	// lock is the container lock, held until the process is started.
+	lock *libcontainer.ContainerLock
+	// cgroupOfLock is the lock of the container whose cgroup the process
+	// is run in (exec --cgroup-of), held for as long as lock is.
+	cgroupOfLock *libcontainer.ContainerLock
+	root         string
 }
 
 /*负责运行指定的container*/
@@ -214,6 +285,7 @@ func (r *runner) run(config *specs.Process) (int, error) {
 		if err != nil {
 			r.destroy()
 		}
+		r.unlock()
 	}()
 	
 	/*检查terminal配置*/
@@ -230,6 +302,9 @@ func (r *runner) run(config *specs.Process) (int, error) {
 	// Populate the fields that come from runner.
 	process.Init = r.init
 	process.SubCgroupPaths = r.subCgroupPaths
+	process.SubCgroupCreate = r.subCgroupCreate
+	process.SubCgroupSettings = r.subCgroupSet
+	process.CgroupPaths = r.cgroupPaths
 	if len(r.listenFDs) > 0 {
 		process.Env = append(process.Env, "LISTEN_FDS="+strconv.Itoa(len(r.listenFDs)), "LISTEN_PID=1")
 		process.ExtraFiles = append(process.ExtraFiles, r.listenFDs...)
@@ -242,6 +317,12 @@ func (r *runner) run(config *specs.Process) (int, error) {
 		}
 		process.ExtraFiles = append(process.ExtraFiles, os.NewFile(uintptr(i), "PreserveFD:"+strconv.Itoa(i)))
 	}
+	for _, s := range r.secrets {
+		if fd := int(s.File.Fd()); fd >= baseFd && fd < baseFd+r.preserveFDs {
+			return -1, fmt.Errorf("secret %s: fd %d is also passed to the container by --preserve-fds", s.Name, fd)
+		}
+	}
+	process.Secrets = r.secrets
 	rootuid, err := r.container.Config().HostRootUID()
 	if err != nil {
 		return -1, err
@@ -255,7 +336,13 @@ func (r *runner) run(config *specs.Process) (int, error) {
 	// with detaching containers, and then we get a tty after the container has
 	// started.
 	handler := newSignalHandler(r.enableSubreaper, r.notifySocket)
-	tty, err := setupIO(process, rootuid, rootgid, config.Terminal, detach, r.consoleSocket)
+	var tty *tty
+	if r.stdioSocket != "" {
+		tty, err = setupSocketIO(process, r.stdioSocket)
+	} else {
+		tty, err = setupIO(process, rootuid, rootgid, config.Terminal, detach, r.consoleSocket)
+		process.ConsoleSocketVersion = r.consoleVersion
+	}
 	if err != nil {
 		return -1, err
 	}
@@ -284,6 +371,8 @@ func (r *runner) run(config *specs.Process) (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	// The container state is saved; the rest does not need the lock.
+	r.unlock()
 	if err = tty.waitConsole(); err != nil {
 		r.terminate(process)
 		return -1, err
@@ -295,13 +384,36 @@ func (r *runner) run(config *specs.Process) (int, error) {
 			return -1, err
 		}
 	}
+	if r.controlSocket && !detach {
+		cs, err := startControlSocket(r.root, r.container, tty)
+		if err != nil {
+			r.terminate(process)
+			return -1, err
+		}
+		defer cs.close()
+	}
+	var dl *deadline
+	if r.timeout > 0 && !detach {
+		dl = startDeadline(r.container, r.timeout, r.timeoutGrace)
+	}
 	status, err := handler.forward(process, tty, detach)
+	if dl != nil && dl.stop() && err == nil {
+		status = timeoutExitStatus
+	}
 	if err != nil {
 		r.terminate(process)
+	} else if err := tty.sendExitStatus(status); err != nil {
+		logrus.Warnf("unable to send the exit status to the stdio socket: %v", err)
 	}
 	if detach {
 		return 0, nil
 	}
+	if err == nil && r.exitStatusFile != "" {
+		// Before the container cgroup is removed.
+		if err := writeExitStatus(r.exitStatusFile, r.container, status); err != nil {
+			logrus.Warn(err)
+		}
+	}
 	if err == nil {
 		r.destroy()
 	}
@@ -309,11 +421,32 @@ func (r *runner) run(config *specs.Process) (int, error) {
 }
 
 func (r *runner) destroy() {
-	if r.shouldDestroy {
-		if err := r.container.Destroy(); err != nil {
+	if !r.shouldDestroy {
+		return
+	}
+	lock := r.lock
+	if lock == nil {
+		var err error
+		lock, err = libcontainer.LockContainer(r.root, r.container.ID(), "delete")
+		if err != nil {
 			logrus.Warn(err)
 		}
+		defer lock.Unlock()
+	}
+	if err := r.container.Destroy(); err != nil {
+		logrus.Warn(err)
+		return
 	}
+	if err := lock.Remove(); err != nil {
+		logrus.Warn(err)
+	}
+}
+
+func (r *runner) unlock() {
+	r.lock.Unlock()
+	r.lock = nil
+	r.cgroupOfLock.Unlock()
+	r.cgroupOfLock = nil
 }
 
 func (r *runner) terminate(p *libcontainer.Process) {
@@ -333,6 +466,17 @@ func (r *runner) checkTerminal(config *specs.Process) error {
 		/*consoleSocket配置情况下，terminal/detach为false时报错*/
 		return errors.New("cannot use console socket if runc will not detach or allocate tty")
 	}
+	if r.consoleVersion != 0 {
+		if r.consoleSocket == "" {
+			return errors.New("cannot set console socket version without console socket")
+		}
+		if r.consoleVersion < libcontainer.ConsoleSocketV1 || r.consoleVersion > libcontainer.ConsoleSocketVersion {
+			return fmt.Errorf("unsupported console socket version %d (supported: %d to %d)", r.consoleVersion, libcontainer.ConsoleSocketV1, libcontainer.ConsoleSocketVersion)
+		}
+	}
+	if r.stdioSocket != "" && (detach || config.Terminal) {
+		return errors.New("cannot use stdio socket if runc will detach or allocate tty")
+	}
 	return nil
 }
 
@@ -384,15 +528,35 @@ func startContainer(context *cli.Context, action CtAct/*要执行的操作*/, cr
 		return -1, errEmptyID
 	}
 
+	if specconv.IsSystemdInit(spec) {
+		specconv.SetupSystemdInit(spec)
+	}
+
 	/*构造notifySocket对象*/
 	notifySocket := newNotifySocket(context, os.Getenv("NOTIFY_SOCKET"), id)
 	if notifySocket != nil {
 		notifySocket.setupSpec(spec)
 	}
 
+	// The secrets are read by runc init.
+	secrets, err := parseSecrets(context)
+	if err != nil {
+		return -1, err
+	}
+	defer closeSecrets(secrets)
+
+	lock, err := lockContainer(context, "create")
+	if err != nil {
+		return -1, err
+	}
+	defer lock.Unlock()
+
 	/*针对$id,创建container对象*/
 	container, err := createContainer(context, id, spec)
 	if err != nil {
+		if !errors.Is(err, libcontainer.ErrExist) {
+			_ = lock.Remove()
+		}
 		return -1, err
 	}
 
@@ -422,15 +586,28 @@ func startContainer(context *cli.Context, action CtAct/*要执行的操作*/, cr
 		listenFDs:       listenFDs,
 		notifySocket:    notifySocket,
 		consoleSocket:   context.String("console-socket"),
+		consoleVersion:  context.Int("console-socket-version"),
 		pidfdSocket:     context.String("pidfd-socket"),
 		detach:          context.Bool("detach"),
 		pidFile:         context.String("pid-file"),
 		preserveFDs:     context.Int("preserve-fds"),
+		secrets:         secrets,
 		action:          action,
 		criuOpts:        criuOpts,
+		timeout:         context.Duration("timeout"),
+		timeoutGrace:    context.Duration("timeout-grace"),
+		exitStatusFile:  context.String("exit-status-file"),
+		controlSocket:   context.Bool("control-socket"),
 		init:            true,
+		lock:            lock,
+		root:            context.GlobalString("root"),
 	}
-	return r.run(spec.Process)
+	status, err := r.run(spec.Process)
+	if err != nil {
+		config := container.Config()
+		err = annotatePreflight(err, &config)
+	}
+	return status, err
 }
 
 func setupPidfdSocket(process *libcontainer.Process, sockpath string) (_clean func(), _ error) {
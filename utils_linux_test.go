@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+func TestPreflightExplains(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected bool
+	}{
+		{err: fmt.Errorf("unable to apply cgroup configuration: %w", libcontainer.ErrCgroupApply), expected: true},
+		{err: fmt.Errorf("exec: %w", libcontainer.ErrExec), expected: true},
+		{err: fmt.Errorf("bad config: %w", libcontainer.ErrInvalidConfig), expected: true},
+		{err: libcontainer.ErrInvalidID},
+		{err: libcontainer.ErrExist},
+		{err: errors.New("unable to lock the container: resource temporarily unavailable")},
+		{err: nil},
+	} {
+		if got := preflightExplains(tc.err); got != tc.expected {
+			t.Errorf("%v: expected %v, got %v", tc.err, tc.expected, got)
+		}
+	}
+}
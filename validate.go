@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer/capabilities"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
+	"github.com/opencontainers/runc/libcontainer/preflight"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+const (
+	validateError   = "error"
+	validateWarning = "warning"
+)
+
+var validateCommand = cli.Command{
+	Name:  "validate",
+	Usage: "check a bundle configuration before creating a container",
+	Description: `The validate command loads the config.json of a bundle, and checks it, without
+creating a container:
+
+ * schema   against the OCI runtime spec: the JSON types, the required
+            fields and values, and the unknown fields (likely misspelled,
+            reported as warnings);
+ * caps     the capability names, which must be known to runc and to the
+            kernel (runc would otherwise ignore them);
+ * config   the container configuration, as runc create does;
+ * kernel   the kernel settings and cgroup controllers the configuration
+            relies upon.
+
+The global --systemd-cgroup and --rootless options are honored. The command
+fails if any error is found.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "bundle, b",
+			Value: "",
+			Usage: `path to the root of the bundle directory, defaults to the current directory`,
+		},
+		cli.StringFlag{
+			Name:  "format, f",
+			Value: "table",
+			Usage: `select one of: ` + formatOptions,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		format := context.String("format")
+		if format != "table" && format != "json" {
+			return errors.New("invalid format option")
+		}
+		if bundle := context.String("bundle"); bundle != "" {
+			if err := os.Chdir(bundle); err != nil {
+				return err
+			}
+		}
+		data, err := os.ReadFile(specConfig)
+		if err != nil {
+			return err
+		}
+		problems := validateBundle(context, data)
+
+		if format == "json" {
+			if problems == nil {
+				problems = []validateProblem{}
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(problems); err != nil {
+				return err
+			}
+		} else if len(problems) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', 0)
+			fmt.Fprint(w, "LEVEL\tCHECK\tMESSAGE\n")
+			for _, p := range problems {
+				msg := p.Message
+				if p.Remediation != "" {
+					msg += "; to fix: " + p.Remediation
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", p.Level, p.Check, msg)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		var errs int
+		for _, p := range problems {
+			if p.Level == validateError {
+				errs++
+			}
+		}
+		if errs > 0 {
+			return fmt.Errorf("%s: %d error(s) found", specConfig, errs)
+		}
+		return nil
+	},
+}
+
+// validateProblem is a problem found by runc validate.
+type validateProblem struct {
+	Check       string `json:"check"`
+	Level       string `json:"level"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// validateBundle checks the config.json contents, data, and returns the
+// problems found. The environment is only checked if the spec itself is
+// valid.
+func validateBundle(context *cli.Context, data []byte) []validateProblem {
+	spec, problems := specSchemaProblems(data)
+	for _, p := range problems {
+		if p.Level == validateError {
+			return problems
+		}
+	}
+	add := func(check, msg, remediation string) {
+		problems = append(problems, validateProblem{
+			Check:       check,
+			Level:       validateError,
+			Message:     msg,
+			Remediation: remediation,
+		})
+	}
+
+	if spec.Process.Capabilities != nil {
+		caps := spec.Process.Capabilities
+		for _, c := range capabilities.Unavailable(&configs.Capabilities{
+			Bounding:    caps.Bounding,
+			Effective:   caps.Effective,
+			Inheritable: caps.Inheritable,
+			Permitted:   caps.Permitted,
+			Ambient:     caps.Ambient,
+		}) {
+			add("caps", "capability "+c+" is unknown, or not supported by the kernel",
+				"check the name (runc features lists the known ones), or remove it")
+		}
+	}
+
+	rootlessCg, err := shouldUseRootlessCgroupManager(context)
+	if err != nil {
+		add("config", err.Error(), "")
+		return problems
+	}
+	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
+		CgroupName:       "validate",
+		UseSystemdCgroup: context.GlobalBool("systemd-cgroup"),
+		Spec:             spec,
+		RootlessEUID:     os.Geteuid() != 0,
+		RootlessCgroups:  rootlessCg,
+
+		AllowedMountSources: context.GlobalStringSlice("allowed-mount-source"),
+	})
+	if err != nil {
+		add("config", err.Error(), "")
+		return problems
+	}
+	if err := validate.Validate(config); err != nil {
+		add("config", err.Error(), "")
+	}
+	for _, r := range preflight.Failed(preflight.Run(config)) {
+		msg := r.Name
+		if r.Value != "" {
+			msg += " = " + r.Value
+		}
+		add("kernel", msg+": "+r.Message, r.Remediation)
+	}
+	return problems
+}
+
+// specSchemaProblems decodes the spec from data, and checks it against the
+// OCI runtime spec schema (and the runc requirements, such as a root path).
+func specSchemaProblems(data []byte) (*specs.Spec, []validateProblem) {
+	var problems []validateProblem
+	add := func(level, msg string) {
+		problems = append(problems, validateProblem{Check: "schema", Level: level, Message: msg})
+	}
+
+	var spec *specs.Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		add(validateError, "invalid JSON: "+err.Error())
+		return nil, problems
+	}
+	if spec == nil {
+		add(validateError, "config cannot be null")
+		return nil, problems
+	}
+	var raw interface{}
+	_ = json.Unmarshal(data, &raw)
+	for _, f := range unknownSpecFields("", raw, reflect.TypeOf(spec)) {
+		add(validateWarning, "unknown field "+f)
+	}
+
+	if spec.Version == "" {
+		add(validateError, "ociVersion is required")
+	} else if major, _, _ := strings.Cut(spec.Version, "."); major != fmt.Sprint(specs.VersionMajor) {
+		add(validateWarning, fmt.Sprintf("ociVersion %s does not match the supported version %s", spec.Version, specs.Version))
+	}
+	if spec.Root == nil || spec.Root.Path == "" {
+		add(validateError, "root.path is required")
+	}
+	if err := validateProcessSpec(spec.Process); err != nil {
+		add(validateError, "process: "+err.Error())
+	} else {
+		seen := make(map[string]bool)
+		for _, rl := range spec.Process.Rlimits {
+			if _, err := strToRlimit(rl.Type); err != nil {
+				add(validateError, "process.rlimits: unknown type "+rl.Type)
+			}
+			if seen[rl.Type] {
+				add(validateError, "process.rlimits: duplicate type "+rl.Type)
+			}
+			seen[rl.Type] = true
+			if rl.Soft > rl.Hard {
+				add(validateError, fmt.Sprintf("process.rlimits: %s soft limit %d is greater than the hard limit %d", rl.Type, rl.Soft, rl.Hard))
+			}
+		}
+	}
+	for i, m := range spec.Mounts {
+		if m.Destination == "" {
+			add(validateError, fmt.Sprintf("mounts[%d]: destination is required", i))
+		}
+	}
+	if spec.Hooks != nil {
+		for _, h := range []struct {
+			name  string
+			hooks []specs.Hook
+		}{
+			{"prestart", spec.Hooks.Prestart}, //nolint:staticcheck // Prestart is deprecated, but still supported.
+			{"createRuntime", spec.Hooks.CreateRuntime},
+			{"createContainer", spec.Hooks.CreateContainer},
+			{"startContainer", spec.Hooks.StartContainer},
+			{"poststart", spec.Hooks.Poststart},
+			{"poststop", spec.Hooks.Poststop},
+		} {
+			for i, hook := range h.hooks {
+				if !filepath.IsAbs(hook.Path) {
+					add(validateError, fmt.Sprintf("hooks.%s[%d]: path %q must be absolute", h.name, i, hook.Path))
+				}
+			}
+		}
+	}
+	if spec.Linux != nil {
+		seen := make(map[specs.LinuxNamespaceType]bool)
+		for _, ns := range spec.Linux.Namespaces {
+			known := false
+			for _, t := range specNamespaceTypes {
+				if ns.Type == t {
+					known = true
+				}
+			}
+			if !known {
+				add(validateError, fmt.Sprintf("linux.namespaces: unknown type %q", ns.Type))
+			}
+			if seen[ns.Type] {
+				add(validateError, fmt.Sprintf("linux.namespaces: duplicate type %q", ns.Type))
+			}
+			seen[ns.Type] = true
+		}
+		for _, m := range []struct {
+			name     string
+			mappings []specs.LinuxIDMapping
+		}{
+			{"uidMappings", spec.Linux.UIDMappings},
+			{"gidMappings", spec.Linux.GIDMappings},
+		} {
+			for i, idmap := range m.mappings {
+				if idmap.Size == 0 {
+					add(validateError, fmt.Sprintf("linux.%s[%d]: size must not be 0", m.name, i))
+				}
+			}
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Level == validateError && problems[j].Level != validateError
+	})
+	return spec, problems
+}
+
+// unknownSpecFields returns the paths of the fields of the decoded JSON
+// value v which are not known to the type t, sorted.
+func unknownSpecFields(path string, v interface{}, t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	var unknown []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for name, fv := range v {
+				ft, ok := fields[name]
+				if !ok {
+					unknown = append(unknown, join(name))
+					continue
+				}
+				unknown = append(unknown, unknownSpecFields(join(name), fv, ft)...)
+			}
+		case reflect.Map:
+			for name, fv := range v {
+				unknown = append(unknown, unknownSpecFields(join(name), fv, t.Elem())...)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, e := range v {
+				unknown = append(unknown, unknownSpecFields(fmt.Sprintf("%s[%d]", path, i), e, t.Elem())...)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// jsonFields returns the types of the fields of the struct type t, by their
+// JSON name.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			for name, t := range jsonFields(ft) {
+				fields[name] = t
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+func TestUnknownSpecFields(t *testing.T) {
+	data := `{
+		"ociVersion": "1.0.2",
+		"process": {"termnal": true, "args": ["sh"], "rlimits": [{"type": "RLIMIT_NOFILE", "hrad": 1}]},
+		"annotations": {"any.key": "value"},
+		"linux": {"resources": {"unified": {"memory.max": "1G"}}, "seccmp": {}},
+		"Hostname": "upper case"
+	}`
+	var raw interface{}
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		t.Fatal(err)
+	}
+	unknown := unknownSpecFields("", raw, reflect.TypeOf(&specs.Spec{}))
+	expected := []string{"Hostname", "linux.seccmp", "process.rlimits[0].hrad", "process.termnal"}
+	if !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected %q, got %q", expected, unknown)
+	}
+}
+
+func TestSpecSchemaProblems(t *testing.T) {
+	spec := specconv.Example()
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, problems := specSchemaProblems(data); len(problems) != 0 {
+		t.Errorf("unexpected problems for the example spec: %+v", problems)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*specs.Spec)
+	}{
+		{"no ociVersion", func(s *specs.Spec) { s.Version = "" }},
+		{"no root", func(s *specs.Spec) { s.Root = nil }},
+		{"relative cwd", func(s *specs.Spec) { s.Process.Cwd = "tmp" }},
+		{"no args", func(s *specs.Spec) { s.Process.Args = nil }},
+		{"unknown rlimit", func(s *specs.Spec) {
+			s.Process.Rlimits = append(s.Process.Rlimits, specs.POSIXRlimit{Type: "RLIMIT_FOO"})
+		}},
+		{"duplicate rlimit", func(s *specs.Spec) { s.Process.Rlimits = append(s.Process.Rlimits, s.Process.Rlimits[0]) }},
+		{"soft rlimit above hard", func(s *specs.Spec) { s.Process.Rlimits[0].Soft = s.Process.Rlimits[0].Hard + 1 }},
+		{"no mount destination", func(s *specs.Spec) { s.Mounts[0].Destination = "" }},
+		{"relative hook path", func(s *specs.Spec) {
+			s.Hooks = &specs.Hooks{Poststop: []specs.Hook{{Path: "hook.sh"}}}
+		}},
+		{"unknown namespace", func(s *specs.Spec) {
+			s.Linux.Namespaces = append(s.Linux.Namespaces, specs.LinuxNamespace{Type: "pidns"})
+		}},
+		{"duplicate namespace", func(s *specs.Spec) {
+			s.Linux.Namespaces = append(s.Linux.Namespaces, s.Linux.Namespaces[0])
+		}},
+		{"empty id mapping", func(s *specs.Spec) {
+			s.Linux.UIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: 1000}}
+		}},
+	} {
+		spec := specconv.Example()
+		tc.modify(spec)
+		data, err := json.Marshal(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, problems := specSchemaProblems(data)
+		if len(problems) != 1 || problems[0].Level != validateError {
+			t.Errorf("%s: expected an error, got %+v", tc.name, problems)
+		}
+	}
+
+	if _, problems := specSchemaProblems([]byte(`{"process": {"args": "sh"}}`)); len(problems) != 1 || problems[0].Level != validateError {
+		t.Errorf("expected a JSON type error, got %+v", problems)
+	}
+}